@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// pollAlarmInputs polls the wired alarm inputs of a device once and emits an
+// alarm_input event for every input that changed since prev, returning the
+// new states. Inputs belong to the device, so their events are reported on
+// its camera with the lowest channel.
+func (p *Plugin) pollAlarmInputs(ctx context.Context, client *reolink.Client, prev []bool) []bool {
+	cameras := p.camerasOf(client)
+	if len(cameras) == 0 || allPowerSaving(cameras) {
+		return prev
+	}
+	info, err := client.GetDeviceInfo(ctx)
+	if err != nil || info.AlarmInputs == 0 {
+		return prev
+	}
+
+	inputs, err := client.GetAlarmInputs(ctx)
+	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, reolink.ErrCircuitOpen) && !errors.Is(err, reolink.ErrNotSupported) {
+			log.Printf("Alarm input poll failed for %s: %v", client.Host(), err)
+		}
+		return prev
+	}
+
+	sort.Slice(cameras, func(i, j int) bool { return cameras[i].Channel() < cameras[j].Channel() })
+	cur := make([]bool, info.AlarmInputs)
+	for _, input := range inputs {
+		if input.Port < 0 || input.Port >= len(cur) {
+			continue
+		}
+		cur[input.Port] = input.Active
+	}
+	for port, active := range cur {
+		if was := port < len(prev) && prev[port]; was == active {
+			continue
+		}
+		state := EventEnd
+		if active {
+			state = EventStart
+		}
+		p.emitEvent(Event{
+			Type:     EventAlarmInput,
+			State:    state,
+			CameraID: cameras[0].ID(),
+			Data:     map[string]interface{}{"input": port},
+		})
+	}
+	return cur
+}
+
+// GetAlarmInputs returns the state of the wired alarm inputs of the device a
+// camera belongs to
+func (p *Plugin) GetAlarmInputs(ctx context.Context, cameraID string) ([]reolink.AlarmInput, error) {
+	var inputs []reolink.AlarmInput
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		info, err := cam.client.GetDeviceInfo(ctx)
+		if err != nil {
+			return err
+		}
+		if info.AlarmInputs == 0 {
+			return fmt.Errorf("device of camera %s has no alarm inputs", cameraID)
+		}
+		inputs, err = cam.client.GetAlarmInputs(ctx)
+		return err
+	})
+	return inputs, err
+}
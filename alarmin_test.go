@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_PollAlarmInputs(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "password", AlarmInputs: 2})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	client := newTestClient(server)
+	plugin := NewPlugin()
+	plugin.cameras["nvr_ch1"] = NewCamera("nvr_ch1", "Garden", "RLC-510A", "localhost", 1, client)
+	plugin.cameras["nvr_ch0"] = NewCamera("nvr_ch0", "Gate", "RLC-510A", "localhost", 0, client)
+	ctx := context.Background()
+
+	inputs := plugin.pollAlarmInputs(ctx, client, nil)
+	if len(inputs) != 2 || len(plugin.GetEvents(0, "", 0).Events) != 0 {
+		t.Fatalf("Expected idle inputs without events, got %v", inputs)
+	}
+
+	sim.SetAlarmInput(1, true)
+	inputs = plugin.pollAlarmInputs(ctx, client, inputs)
+	inputs = plugin.pollAlarmInputs(ctx, client, inputs)
+	sim.SetAlarmInput(1, false)
+	plugin.pollAlarmInputs(ctx, client, inputs)
+
+	events := plugin.GetEvents(0, "", 0).Events
+	if len(events) != 2 || events[0].State != EventStart || events[1].State != EventEnd {
+		t.Fatalf("Expected a start and an end, got %+v", events)
+	}
+	for _, ev := range events {
+		if ev.Type != EventAlarmInput || ev.CameraID != "nvr_ch0" || ev.Data["input"] != 1 {
+			t.Errorf("Unexpected event %+v", ev)
+		}
+	}
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "get_alarm_inputs", Params: []byte(`{"camera_id": "nvr_ch1"}`)})
+	if resp.Error != nil {
+		t.Fatalf("get_alarm_inputs failed: %s", resp.Error.Message)
+	}
+	if got := resp.Result.([]reolink.AlarmInput); len(got) != 2 || got[1].Active {
+		t.Errorf("Unexpected alarm inputs: %+v", got)
+	}
+}
+
+func TestPlugin_AlarmInputs_NoInputs(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Password: "password"}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	plugin := NewPlugin()
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Drive", "RLC-811A", "localhost", 0, client)
+
+	if inputs := plugin.pollAlarmInputs(context.Background(), client, nil); inputs != nil {
+		t.Errorf("Expected no inputs to be polled, got %v", inputs)
+	}
+	if _, err := plugin.GetAlarmInputs(context.Background(), "cam_1"); err == nil || !strings.Contains(err.Error(), "no alarm inputs") {
+		t.Errorf("Expected a missing alarm inputs error, got %v", err)
+	}
+}
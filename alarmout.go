@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// maxAlarmPulse is the longest pulse an alarm output can be triggered for
+const maxAlarmPulse = 10 * time.Minute
+
+// AlarmOutputRequest is one alarm_output operation
+type AlarmOutputRequest struct {
+	CameraID   string `json:"camera_id"`
+	Action     string `json:"action"`                // "status", "trigger" or "clear"
+	Port       int    `json:"port"`                  // For "trigger" and "clear", from 0
+	DurationMs int    `json:"duration_ms,omitempty"` // For "trigger"; latched until "clear" if 0
+}
+
+// alarmPulseKey identifies an alarm output relay of a device
+type alarmPulseKey struct {
+	client *reolink.Client
+	port   int
+}
+
+// alarmPulse is a triggered output waiting to be released
+type alarmPulse struct {
+	cancel context.CancelFunc
+}
+
+// AlarmOutput reads, triggers or clears the alarm output relays of the device
+// a camera belongs to, usually an NVR, and returns the state of all its
+// outputs. A trigger with a duration releases the relay again when it ends;
+// triggering or clearing the port again replaces the pending release.
+func (p *Plugin) AlarmOutput(ctx context.Context, req AlarmOutputRequest) ([]reolink.AlarmOutput, error) {
+	switch req.Action {
+	case "status", "clear":
+	case "trigger":
+		pulse := time.Duration(req.DurationMs) * time.Millisecond
+		if req.DurationMs < 0 || pulse > maxAlarmPulse {
+			return nil, fmt.Errorf("duration_ms must be between 0 and %d", maxAlarmPulse.Milliseconds())
+		}
+	default:
+		return nil, fmt.Errorf("unknown alarm output action: %s (must be status, trigger, or clear)", req.Action)
+	}
+
+	var outputs []reolink.AlarmOutput
+	err := p.onCamera(ctx, req.CameraID, func(ctx context.Context, cam *Camera) (err error) {
+		info, err := cam.client.GetDeviceInfo(ctx)
+		if err != nil {
+			return err
+		}
+		if info.AlarmOutputs == 0 {
+			return fmt.Errorf("device of camera %s has no alarm outputs", req.CameraID)
+		}
+
+		if req.Action != "status" {
+			if req.Port < 0 || req.Port >= info.AlarmOutputs {
+				return fmt.Errorf("alarm output not found: %d (device has %d)", req.Port, info.AlarmOutputs)
+			}
+			key := alarmPulseKey{client: cam.client, port: req.Port}
+			p.mu.Lock()
+			p.cancelAlarmPulseLocked(key)
+			p.mu.Unlock()
+
+			active := req.Action == "trigger"
+			if err := cam.client.SetAlarmOutput(ctx, req.Port, active); err != nil {
+				return err
+			}
+			if active && req.DurationMs > 0 {
+				p.mu.Lock()
+				p.startAlarmPulseLocked(key, time.Duration(req.DurationMs)*time.Millisecond)
+				p.mu.Unlock()
+			}
+			log.Printf("Alarm output %d of %s: %s %dms", req.Port, cam.client.Host(), req.Action, req.DurationMs)
+		}
+
+		outputs, err = cam.client.GetAlarmOutputs(ctx)
+		return err
+	})
+	return outputs, err
+}
+
+func (p *Plugin) startAlarmPulseLocked(key alarmPulseKey, d time.Duration) {
+	parent := p.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	pulse := &alarmPulse{cancel: cancel}
+	p.alarmPulses[key] = pulse
+	goGuarded(ctx, "alarm output pulse", func() {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		err := p.workerFor(key.client).do(ctx, func(ctx context.Context) error {
+			// A trigger or clear may have replaced the pulse while queued
+			p.mu.Lock()
+			current := p.alarmPulses[key] == pulse
+			if current {
+				delete(p.alarmPulses, key)
+			}
+			p.mu.Unlock()
+			if !current {
+				return nil
+			}
+			return key.client.SetAlarmOutput(ctx, key.port, false)
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to release alarm output %d of %s: %v", key.port, key.client.Host(), err)
+		}
+	})
+}
+
+func (p *Plugin) cancelAlarmPulseLocked(key alarmPulseKey) {
+	if pulse, ok := p.alarmPulses[key]; ok {
+		pulse.cancel()
+		delete(p.alarmPulses, key)
+	}
+}
+
+// releaseAlarmPulses releases outputs whose pulse has not ended yet, so a
+// shutdown does not leave a gate strike or siren energized. Latched outputs
+// are left as they are.
+func (p *Plugin) releaseAlarmPulses(ctx context.Context) {
+	p.mu.Lock()
+	keys := make([]alarmPulseKey, 0, len(p.alarmPulses))
+	for key := range p.alarmPulses {
+		keys = append(keys, key)
+		p.cancelAlarmPulseLocked(key)
+	}
+	p.mu.Unlock()
+
+	for _, key := range keys {
+		releaseCtx, cancel := context.WithTimeout(ctx, key.client.GetTimeouts().Request)
+		err := p.workerFor(key.client).do(releaseCtx, func(ctx context.Context) error {
+			return key.client.SetAlarmOutput(ctx, key.port, false)
+		})
+		cancel()
+		if err != nil {
+			log.Printf("Failed to release alarm output %d of %s: %v", key.port, key.client.Host(), err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_AlarmOutput(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "password", AlarmOutputs: 2})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	plugin := NewPlugin()
+	plugin.cameras["nvr_ch1"] = NewCamera("nvr_ch1", "Gate", "RLC-510A", "localhost", 1, newTestClient(server))
+	ctx := context.Background()
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "alarm_output", Params: []byte(
+		`{"camera_id": "nvr_ch1", "action": "trigger", "port": 1}`)})
+	if resp.Error != nil {
+		t.Fatalf("alarm_output failed: %s", resp.Error.Message)
+	}
+	outputs := resp.Result.([]reolink.AlarmOutput)
+	if len(outputs) != 2 || !outputs[1].Active || outputs[0].Active || !sim.AlarmOutput(1) {
+		t.Errorf("Expected port 1 to be latched, got %+v", outputs)
+	}
+
+	// A pulse releases the relay by itself
+	if _, err := plugin.AlarmOutput(ctx, AlarmOutputRequest{CameraID: "nvr_ch1", Action: "trigger", Port: 0, DurationMs: 20}); err != nil {
+		t.Fatalf("Pulse failed: %v", err)
+	}
+	if !sim.AlarmOutput(0) {
+		t.Error("Expected port 0 to be active during the pulse")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for sim.AlarmOutput(0) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sim.AlarmOutput(0) {
+		t.Error("Expected port 0 to be released after the pulse")
+	}
+
+	// Clearing replaces a pending release
+	if _, err := plugin.AlarmOutput(ctx, AlarmOutputRequest{CameraID: "nvr_ch1", Action: "trigger", Port: 0, DurationMs: 60000}); err != nil {
+		t.Fatalf("Pulse failed: %v", err)
+	}
+	outputs, err := plugin.AlarmOutput(ctx, AlarmOutputRequest{CameraID: "nvr_ch1", Action: "clear", Port: 0})
+	if err != nil || outputs[0].Active || len(plugin.alarmPulses) != 0 {
+		t.Errorf("Expected port 0 to be cleared without a pending pulse, got %+v, %v", outputs, err)
+	}
+
+	errTests := []struct {
+		req  AlarmOutputRequest
+		want string
+	}{
+		{AlarmOutputRequest{CameraID: "nvr_ch1", Action: "trigger", Port: 2}, "not found"},
+		{AlarmOutputRequest{CameraID: "nvr_ch1", Action: "trigger", DurationMs: -1}, "duration_ms"},
+		{AlarmOutputRequest{CameraID: "nvr_ch1", Action: "open"}, "unknown alarm output action"},
+	}
+	for _, tt := range errTests {
+		if _, err := plugin.AlarmOutput(ctx, tt.req); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected %q error, got %v", tt.req, tt.want, err)
+		}
+	}
+}
+
+func TestPlugin_AlarmOutput_ReleasedOnShutdown(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Password: "password", AlarmOutputs: 1})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	plugin := NewPlugin()
+	plugin.cameras["nvr_ch0"] = NewCamera("nvr_ch0", "Gate", "RLN8-410", "localhost", 0, newTestClient(server))
+
+	if _, err := plugin.AlarmOutput(context.Background(), AlarmOutputRequest{CameraID: "nvr_ch0", Action: "trigger", DurationMs: 60000}); err != nil {
+		t.Fatalf("Pulse failed: %v", err)
+	}
+	if err := plugin.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if sim.AlarmOutput(0) {
+		t.Error("Expected the pulse to be released on shutdown")
+	}
+}
+
+func TestPlugin_AlarmOutput_NoOutputs(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Password: "password"}))
+	defer server.Close()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Drive", "RLC-811A", "localhost", 0, newTestClient(server))
+
+	if _, err := plugin.AlarmOutput(context.Background(), AlarmOutputRequest{CameraID: "cam_1", Action: "status"}); err == nil || !strings.Contains(err.Error(), "no alarm outputs") {
+		t.Errorf("Expected a missing alarm outputs error, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// analyticsRetention is how long hourly event counts are kept
+const analyticsRetention = 7 * 24 * time.Hour
+
+// defaultSummaryHours is the summary window when get_event_summary gets no hours
+const defaultSummaryHours = 24
+
+// eventAnalytics aggregates event counts per camera, hour and event type
+type eventAnalytics struct {
+	mu     sync.Mutex
+	counts map[string]map[time.Time]map[string]int // camera ID -> hour -> type -> count
+}
+
+func newEventAnalytics() *eventAnalytics {
+	return &eventAnalytics{counts: make(map[string]map[time.Time]map[string]int)}
+}
+
+// record counts an event. Only event starts are counted so that an event
+// with a start and an end is counted once; test events are not counted.
+func (a *eventAnalytics) record(ev Event) {
+	if ev.State != EventStart || ev.Data["test"] == true {
+		return
+	}
+
+	hour := ev.Timestamp.UTC().Truncate(time.Hour)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	hours, ok := a.counts[ev.CameraID]
+	if !ok {
+		hours = make(map[time.Time]map[string]int)
+		a.counts[ev.CameraID] = hours
+	}
+	types, ok := hours[hour]
+	if !ok {
+		types = make(map[string]int)
+		hours[hour] = types
+	}
+	types[ev.Type]++
+
+	a.expireLocked(hours, hour)
+}
+
+// expireLocked drops hours older than the retention period
+func (a *eventAnalytics) expireLocked(hours map[time.Time]map[string]int, now time.Time) {
+	cutoff := now.Add(-analyticsRetention)
+	for hour := range hours {
+		if hour.Before(cutoff) {
+			delete(hours, hour)
+		}
+	}
+}
+
+// forget drops the counts of a removed camera
+func (a *eventAnalytics) forget(cameraID string) {
+	a.mu.Lock()
+	delete(a.counts, cameraID)
+	a.mu.Unlock()
+}
+
+// HourlyEventCount is the number of events of each type in one hour
+type HourlyEventCount struct {
+	Hour   time.Time      `json:"hour"`
+	Counts map[string]int `json:"counts"`
+}
+
+// CameraEventSummary is the event activity of one camera
+type CameraEventSummary struct {
+	CameraID string             `json:"camera_id"`
+	Totals   map[string]int     `json:"totals"`
+	Hours    []HourlyEventCount `json:"hours"` // oldest first, hours without events omitted
+}
+
+// summary returns the event counts of the hours since from, ordered by camera ID
+func (a *eventAnalytics) summary(cameraID string, from time.Time) []CameraEventSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	from = from.UTC().Truncate(time.Hour)
+	result := []CameraEventSummary{}
+	for id, hours := range a.counts {
+		if cameraID != "" && id != cameraID {
+			continue
+		}
+
+		summary := CameraEventSummary{CameraID: id, Totals: make(map[string]int), Hours: []HourlyEventCount{}}
+		for hour, types := range hours {
+			if hour.Before(from) {
+				continue
+			}
+			counts := make(map[string]int, len(types))
+			for eventType, n := range types {
+				counts[eventType] = n
+				summary.Totals[eventType] += n
+			}
+			summary.Hours = append(summary.Hours, HourlyEventCount{Hour: hour, Counts: counts})
+		}
+		sort.Slice(summary.Hours, func(i, j int) bool {
+			return summary.Hours[i].Hour.Before(summary.Hours[j].Hour)
+		})
+		result = append(result, summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CameraID < result[j].CameraID })
+	return result
+}
+
+// EventSummaryResult is the result of get_event_summary
+type EventSummaryResult struct {
+	From    time.Time            `json:"from"`
+	Cameras []CameraEventSummary `json:"cameras"`
+}
+
+// GetEventSummary returns hourly event counts per camera and type for the
+// last hours hours, for activity heatmaps
+func (p *Plugin) GetEventSummary(cameraID string, hours int) (*EventSummaryResult, error) {
+	if cameraID != "" {
+		if _, err := p.lookupCamera(cameraID); err != nil {
+			return nil, err
+		}
+	}
+	if hours <= 0 {
+		hours = defaultSummaryHours
+	}
+	if maxHours := int(analyticsRetention / time.Hour); hours > maxHours {
+		hours = maxHours
+	}
+
+	// The current hour counts as one of the hours
+	from := time.Now().UTC().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+	return &EventSummaryResult{
+		From:    from,
+		Cameras: p.analytics.summary(cameraID, from),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventAnalytics_Record(t *testing.T) {
+	a := newEventAnalytics()
+	hour := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	a.record(Event{Type: EventMotion, State: EventStart, CameraID: "cam1", Timestamp: hour.Add(5 * time.Minute)})
+	a.record(Event{Type: EventMotion, State: EventEnd, CameraID: "cam1", Timestamp: hour.Add(6 * time.Minute)})
+	a.record(Event{Type: EventMotion, State: EventStart, CameraID: "cam1", Timestamp: hour.Add(50 * time.Minute)})
+	a.record(Event{Type: EventPerson, State: EventStart, CameraID: "cam1", Timestamp: hour.Add(70 * time.Minute)})
+	a.record(Event{Type: EventVehicle, State: EventStart, CameraID: "cam2", Timestamp: hour})
+
+	summary := a.summary("", hour)
+	if len(summary) != 2 || summary[0].CameraID != "cam1" {
+		t.Fatalf("Expected summaries for cam1 and cam2, got %+v", summary)
+	}
+
+	cam1 := summary[0]
+	if cam1.Totals[EventMotion] != 2 || cam1.Totals[EventPerson] != 1 {
+		t.Errorf("Unexpected totals %v", cam1.Totals)
+	}
+	if len(cam1.Hours) != 2 || !cam1.Hours[0].Hour.Equal(hour) {
+		t.Fatalf("Expected 2 hours starting at %v, got %+v", hour, cam1.Hours)
+	}
+	if cam1.Hours[0].Counts[EventMotion] != 2 || cam1.Hours[1].Counts[EventPerson] != 1 {
+		t.Errorf("Unexpected hourly counts %+v", cam1.Hours)
+	}
+
+	if got := a.summary("cam2", hour.Add(time.Hour)); len(got) != 1 || len(got[0].Hours) != 0 {
+		t.Errorf("Expected no cam2 hours after the window start, got %+v", got)
+	}
+}
+
+func TestEventAnalytics_Retention(t *testing.T) {
+	a := newEventAnalytics()
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	a.record(Event{Type: EventMotion, State: EventStart, CameraID: "cam1", Timestamp: old})
+	a.record(Event{Type: EventMotion, State: EventStart, CameraID: "cam1", Timestamp: old.Add(analyticsRetention + time.Hour)})
+
+	summary := a.summary("cam1", old)
+	if len(summary) != 1 || len(summary[0].Hours) != 1 || summary[0].Totals[EventMotion] != 1 {
+		t.Errorf("Expected the expired hour to be dropped, got %+v", summary)
+	}
+}
+
+func TestPlugin_GetEventSummary(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.cameras["cam1"] = NewCamera("cam1", "Front", "RLC-810A", "localhost", 0, nil)
+
+	plugin.emitEvent(Event{Type: EventPerson, State: EventStart, CameraID: "cam1"})
+	plugin.emitEvent(Event{Type: EventPerson, State: EventEnd, CameraID: "cam1"})
+
+	result, err := plugin.GetEventSummary("cam1", 0)
+	if err != nil {
+		t.Fatalf("GetEventSummary failed: %v", err)
+	}
+	if len(result.Cameras) != 1 || result.Cameras[0].Totals[EventPerson] != 1 {
+		t.Errorf("Unexpected summary %+v", result)
+	}
+	if want := time.Now().UTC().Truncate(time.Hour).Add(-23 * time.Hour); !result.From.Equal(want) {
+		t.Errorf("Expected window to start at %v, got %v", want, result.From)
+	}
+
+	if _, err := plugin.GetEventSummary("missing", 0); err == nil {
+		t.Error("Expected error for unknown camera")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// ListAudioClips returns the audio clips installed on a camera
+func (p *Plugin) ListAudioClips(ctx context.Context, cameraID string) ([]reolink.AudioClip, error) {
+	var clips []reolink.AudioClip
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		clips, err = cam.client.GetAudioClips(ctx, cam.Channel())
+		return err
+	})
+	return clips, err
+}
+
+// UploadAudioClip uploads an audio clip to a camera, either from base64 data
+// or from a completed chunked transfer
+func (p *Plugin) UploadAudioClip(ctx context.Context, cameraID, name, data, transferID string) error {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return err
+	}
+
+	var raw []byte
+	if transferID != "" {
+		var transferName string
+		transferName, raw, err = p.transfers.Take(transferID)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = transferName
+		}
+	} else {
+		raw, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return fmt.Errorf("invalid clip data: %w", err)
+		}
+	}
+
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+		return cam.client.UploadAudioClip(ctx, cam.Channel(), name, raw)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Uploaded audio clip %s (%d bytes) to camera %s", name, len(raw), cameraID)
+	return nil
+}
+
+// SelectAudioClip selects the clip played by a camera's audio alarm
+func (p *Plugin) SelectAudioClip(ctx context.Context, cameraID string, id int) error {
+	return p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		return cam.client.SelectAudioClip(ctx, cam.Channel(), id)
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// maxBackfillWindow is the longest outage searched for missed events
+const maxBackfillWindow = 24 * time.Hour
+
+// beginOutage records when an offline camera was last seen
+func (c *Camera) beginOutage(lastSeen time.Time) {
+	c.mu.Lock()
+	c.outageStart = lastSeen
+	c.mu.Unlock()
+}
+
+// endOutage returns and clears the start of the camera's outage, zero if
+// none was recorded
+func (c *Camera) endOutage() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	start := c.outageStart
+	c.outageStart = time.Time{}
+	return start
+}
+
+// backfillLater runs the event backfill of a camera that came back with
+// the long-running commands of its device, as searching the recordings may
+// take a while; without a worker it runs right away
+func (p *Plugin) backfillLater(ctx context.Context, cam *Camera) {
+	w := p.workerFor(cam.client)
+	if w == nil {
+		p.backfillEvents(ctx, cam)
+		return
+	}
+	goGuarded(ctx, "event backfill of "+cam.ID(), func() {
+		_ = w.do(longRunning(ctx), func(ctx context.Context) error {
+			p.backfillEvents(ctx, cam)
+			return nil
+		})
+	})
+}
+
+// backfillEvents emits the events a camera recorded while it was offline,
+// found through the recordings on its storage. They are motion events
+// marked "historical" in their data, timestamped when the recording started
+// and ended, so the host's timeline has no gap.
+func (p *Plugin) backfillEvents(ctx context.Context, cam *Camera) {
+	from := cam.endOutage()
+	if from.IsZero() || cam.client == nil {
+		return
+	}
+	to := time.Now()
+	if to.Sub(from) > maxBackfillWindow {
+		from = to.Add(-maxBackfillWindow)
+	}
+
+	recordings, err := cam.client.SearchRecordings(ctx, cam.Channel(), "main", from, to)
+	if errors.Is(err, reolink.ErrNotSupported) {
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Event backfill failed for %s: %v", cam.ID(), err)
+		}
+		return
+	}
+
+	count := 0
+	for _, rec := range recordings {
+		// Recordings already running before the outage were seen live
+		if rec.Start.Before(from) {
+			continue
+		}
+		data := func() map[string]interface{} {
+			return map[string]interface{}{"historical": true, "recording": rec.Name}
+		}
+		p.emitEvent(Event{Type: EventMotion, State: EventStart, CameraID: cam.ID(), Timestamp: rec.Start, Data: data()})
+		p.emitEvent(Event{Type: EventMotion, State: EventEnd, CameraID: cam.ID(), Timestamp: rec.End, Data: data()})
+		count++
+	}
+	if count > 0 {
+		log.Printf("Backfilled %d events of camera %s from %s", count, cam.ID(), from.Format(time.RFC3339))
+	}
+}
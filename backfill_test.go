@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_BackfillEvents(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "password", Location: time.FixedZone("UTC-5", -5*3600)})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	client := newTestClient(server)
+	plugin := NewPlugin()
+	cam := NewCamera("cam_1", "Yard", "RLC-810A", "localhost", 0, client)
+	plugin.cameras["cam_1"] = cam
+
+	// Seen 10 minutes ago, then offline
+	outage := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	cam.online = false
+	cam.beginOutage(outage)
+
+	missed := reolinksim.Recording{Start: outage.Add(2 * time.Minute), End: outage.Add(3 * time.Minute), Name: "missed.mp4"}
+	sim.AddRecording(reolinksim.Recording{Start: outage.Add(-time.Minute), End: outage.Add(time.Minute), Name: "seen.mp4"})
+	sim.AddRecording(missed)
+
+	plugin.checkConnectivity(context.Background(), time.Minute)
+	if !cam.IsOnline() {
+		t.Fatal("Expected the camera to be back online")
+	}
+
+	events := plugin.GetEvents(0, "cam_1", 0).Events
+	if len(events) != 2 {
+		t.Fatalf("Expected a start and an end event, got %+v", events)
+	}
+	start, end := events[0], events[1]
+	if start.Type != EventMotion || start.State != EventStart || !start.Timestamp.Equal(missed.Start) {
+		t.Errorf("Unexpected start event %+v", start)
+	}
+	if end.State != EventEnd || !end.Timestamp.Equal(missed.End) {
+		t.Errorf("Unexpected end event %+v", end)
+	}
+	if start.Data["historical"] != true || start.Data["recording"] != "missed.mp4" {
+		t.Errorf("Expected the event to be marked historical, got %v", start.Data)
+	}
+
+	// The outage is only backfilled once
+	cam.online = false
+	plugin.checkConnectivity(context.Background(), time.Minute)
+	if n := len(plugin.GetEvents(0, "cam_1", 0).Events); n != 2 {
+		t.Errorf("Expected no further events, got %d", n)
+	}
+}
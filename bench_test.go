@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// benchCameras is the number of simulated cameras the benchmarks run against,
+// the channels of one NVR
+const benchCameras = 64
+
+// newBenchPlugin initializes a plugin with a simulated NVR of benchCameras
+// channels. Background event polling is off so benchmarks measure only the
+// work they drive.
+func newBenchPlugin(b *testing.B, snapshot []byte) (*Plugin, *reolinksim.Server, string) {
+	b.Helper()
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN36", Channels: benchCameras, Password: "secret", Snapshot: snapshot})
+	server := httptest.NewServer(sim)
+	b.Cleanup(server.Close)
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		b.Fatalf("Initialize failed: %v", err)
+	}
+	b.Cleanup(func() { plugin.Shutdown(context.Background()) })
+	if n := len(plugin.ListCameras()); n != benchCameras {
+		b.Fatalf("Expected %d cameras, got %d", benchCameras, n)
+	}
+	return plugin, sim, host
+}
+
+func benchRequest(b *testing.B, method string, params interface{}) JSONRPCRequest {
+	b.Helper()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return JSONRPCRequest{JSONRPC: "2.0", ID: float64(1), Method: method, Params: raw}
+}
+
+// benchJPEG encodes a noisy 1080p frame, close to the size of a real snapshot
+func benchJPEG(b *testing.B) []byte {
+	b.Helper()
+	img := image.NewGray(image.Rect(0, 0, 1920, 1080))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = byte(seed >> 24)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkHandleRequest measures dispatching requests that are answered
+// from plugin state, without device I/O
+func BenchmarkHandleRequest(b *testing.B) {
+	plugin, _, host := newBenchPlugin(b, nil)
+	for i := 0; i < maxQueuedEvents; i++ {
+		plugin.emitEvent(Event{Type: EventMotion, State: EventStart, CameraID: fmt.Sprintf("%s_ch%d", host, i%benchCameras)})
+	}
+	camID := host + "_ch7"
+
+	requests := []struct {
+		name string
+		req  JSONRPCRequest
+	}{
+		{"health", benchRequest(b, "health", nil)},
+		{"list_cameras", benchRequest(b, "list_cameras", nil)},
+		{"get_camera", benchRequest(b, "get_camera", map[string]interface{}{"camera_id": camID})},
+		{"get_events", benchRequest(b, "get_events", map[string]interface{}{"since": maxQueuedEvents - 10})},
+		{"unknown", benchRequest(b, "no_such_method", nil)},
+	}
+	for _, r := range requests {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if resp := plugin.HandleRequest(r.req); resp.Error != nil && r.name != "unknown" {
+					b.Fatalf("%s failed: %s", r.name, resp.Error.Message)
+				}
+			}
+		})
+	}
+
+	// Concurrent requests for different cameras show contention on shared
+	// plugin state
+	b.Run("get_camera_parallel", func(b *testing.B) {
+		reqs := make([]JSONRPCRequest, benchCameras)
+		for i := range reqs {
+			reqs[i] = benchRequest(b, "get_camera", map[string]interface{}{"camera_id": fmt.Sprintf("%s_ch%d", host, i)})
+		}
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				if resp := plugin.HandleRequest(reqs[i%benchCameras]); resp.Error != nil {
+					b.Errorf("get_camera failed: %s", resp.Error.Message)
+					return
+				}
+			}
+		})
+	})
+}
+
+// BenchmarkSnapshot measures get_snapshot from fetching the JPEG to writing
+// the base64 response line, and the encoding alone
+func BenchmarkSnapshot(b *testing.B) {
+	frame := benchJPEG(b)
+	plugin, _, host := newBenchPlugin(b, frame)
+
+	b.Run("get_snapshot", func(b *testing.B) {
+		req := benchRequest(b, "get_snapshot", map[string]interface{}{"camera_id": host + "_ch0"})
+		b.SetBytes(int64(len(frame)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			plugin.respond(req, false)
+		}
+	})
+
+	b.Run("get_snapshot_parallel", func(b *testing.B) {
+		reqs := make([]JSONRPCRequest, benchCameras)
+		for i := range reqs {
+			reqs[i] = benchRequest(b, "get_snapshot", map[string]interface{}{"camera_id": fmt.Sprintf("%s_ch%d", host, i)})
+		}
+		b.SetBytes(int64(len(frame)))
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				plugin.respond(reqs[i%benchCameras], false)
+			}
+		})
+	})
+
+	b.Run("encode", func(b *testing.B) {
+		b.SetBytes(int64(len(frame)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			resp := JSONRPCResponse{JSONRPC: "2.0", ID: float64(1), Result: base64.StdEncoding.EncodeToString(frame)}
+			if err := plugin.writeMessage(resp); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkEventPolling measures one poll of every camera of the NVR, with
+// and without state changes, and hosts reading the event queue
+func BenchmarkEventPolling(b *testing.B) {
+	plugin, sim, host := newBenchPlugin(b, nil)
+	cam, err := plugin.lookupCamera(host + "_ch0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	b.Run("poll_idle", func(b *testing.B) {
+		states := make(map[string]reolink.EventState)
+		plugin.pollDeviceEvents(ctx, cam.client, states)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			plugin.pollDeviceEvents(ctx, cam.client, states)
+		}
+	})
+
+	b.Run("poll_changing", func(b *testing.B) {
+		states := make(map[string]reolink.EventState)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			for ch := 0; ch < benchCameras; ch++ {
+				sim.SetMotion(ch, i%2 == 0)
+			}
+			b.StartTimer()
+			plugin.pollDeviceEvents(ctx, cam.client, states)
+		}
+	})
+
+	for i := 0; i < maxQueuedEvents; i++ {
+		plugin.emitEvent(Event{Type: EventMotion, State: EventStart, CameraID: fmt.Sprintf("%s_ch%d", host, i%benchCameras)})
+	}
+	last := plugin.events.lastSeq()
+	queries := []struct {
+		name     string
+		since    uint64
+		cameraID string
+		limit    int
+	}{
+		{"get_events_all", 0, "", 0},
+		{"get_events_camera", 0, host + "_ch7", 0},
+		{"get_events_limit", 0, "", 50},
+		{"get_events_caught_up", last, "", 0},
+	}
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				plugin.GetEvents(q.since, q.cameraID, q.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Event burst limits. Frames are kept in memory, so counts are small and only
+// the bursts of the most recent events are kept.
+const (
+	minBurstInterval = 250 * time.Millisecond
+	maxBurstFrames   = 10
+	maxPreFrames     = 5
+	maxStoredBursts  = 20
+)
+
+// errNoEventMedia is returned for events without captured snapshots
+var errNoEventMedia = errors.New("no media for event")
+
+// EventBurstConfig captures a short burst of snapshots when an event starts,
+// and optionally keeps the last few snapshots before it
+type EventBurstConfig struct {
+	Count      int `json:"count"`       // snapshots from the event start on, 3 by default
+	IntervalMs int `json:"interval_ms"` // time between snapshots, 1000 by default
+
+	// PreCount snapshots from before the event are kept by snapshotting the
+	// listed Cameras every interval all the time, so it requires Cameras
+	PreCount int `json:"pre_count,omitempty"`
+
+	// EventTypes and Cameras limit the events that trigger a burst; all
+	// events trigger one if empty
+	EventTypes []string `json:"event_types,omitempty"`
+	Cameras    []string `json:"cameras,omitempty"`
+}
+
+// validate checks the config and fills in the defaults
+func (cfg *EventBurstConfig) validate() error {
+	if cfg.Count == 0 {
+		cfg.Count = 3
+	}
+	if cfg.IntervalMs == 0 {
+		cfg.IntervalMs = 1000
+	}
+	if cfg.Count < 1 || cfg.Count > maxBurstFrames {
+		return fmt.Errorf("count must be between 1 and %d", maxBurstFrames)
+	}
+	if time.Duration(cfg.IntervalMs)*time.Millisecond < minBurstInterval {
+		return fmt.Errorf("interval_ms must be at least %d", minBurstInterval.Milliseconds())
+	}
+	if cfg.PreCount < 0 || cfg.PreCount > maxPreFrames {
+		return fmt.Errorf("pre_count must be between 0 and %d", maxPreFrames)
+	}
+	if cfg.PreCount > 0 && len(cfg.Cameras) == 0 {
+		return fmt.Errorf("pre_count requires cameras, as it snapshots them continuously")
+	}
+	return nil
+}
+
+// triggers reports whether ev starts a burst. Events recovered from
+// recordings are in the past, so there is nothing to capture for them.
+func (cfg *EventBurstConfig) triggers(ev Event) bool {
+	if ev.State != EventStart || ev.CameraID == "" || ev.Data["historical"] == true {
+		return false
+	}
+	if len(cfg.EventTypes) > 0 && !contains(cfg.EventTypes, ev.Type) {
+		return false
+	}
+	return len(cfg.Cameras) == 0 || contains(cfg.Cameras, ev.CameraID)
+}
+
+func (cfg *EventBurstConfig) interval() time.Duration {
+	return time.Duration(cfg.IntervalMs) * time.Millisecond
+}
+
+// burstFrame is a captured snapshot
+type burstFrame struct {
+	capturedAt time.Time
+	data       []byte
+}
+
+// eventBurst is the snapshots captured for the events of a camera that
+// started while it ran
+type eventBurst struct {
+	cameraID string
+	done     chan struct{} // closed when the capture has finished
+
+	mu     sync.Mutex
+	events map[string]time.Time // event ID to event time
+	frames []burstFrame
+}
+
+func (b *eventBurst) add(frame burstFrame) {
+	b.mu.Lock()
+	b.frames = append(b.frames, frame)
+	b.mu.Unlock()
+}
+
+func (b *eventBurst) complete() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// eventBursts holds the burst configuration, the pre-event snapshots of
+// each camera and the captured bursts
+type eventBursts struct {
+	mu     sync.Mutex
+	config *EventBurstConfig // nil when disabled
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	pre     map[string][]burstFrame // latest snapshots by camera ID
+	active  map[string]*eventBurst  // capturing bursts by camera ID
+	byEvent map[string]*eventBurst
+	order   []*eventBurst // oldest first, at most maxStoredBursts
+}
+
+// configured returns the burst config, or nil if bursts are disabled
+func (b *eventBursts) configured() *EventBurstConfig {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
+// storeLocked keeps burst, dropping the oldest beyond maxStoredBursts
+func (b *eventBursts) storeLocked(burst *eventBurst) {
+	b.order = append(b.order, burst)
+	for len(b.order) > maxStoredBursts {
+		old := b.order[0]
+		b.order = b.order[1:]
+		old.mu.Lock()
+		for id := range old.events {
+			delete(b.byEvent, id)
+		}
+		old.mu.Unlock()
+	}
+}
+
+// configureEventBursts replaces the burst configuration, stopping pre-event
+// capture and dropping the captured bursts. A nil config disables bursts.
+func (p *Plugin) configureEventBursts(cfg *EventBurstConfig) {
+	b := &p.bursts
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+	b.config = cfg
+	b.pre = make(map[string][]burstFrame)
+	b.active = make(map[string]*eventBurst)
+	b.byEvent = make(map[string]*eventBurst)
+	b.order = nil
+	if cfg == nil {
+		return
+	}
+
+	parent := p.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	b.ctx, b.cancel = context.WithCancel(parent)
+	if cfg.PreCount > 0 {
+		for _, cameraID := range cfg.Cameras {
+			ctx := b.ctx
+			goGuarded(ctx, "pre-event snapshots of "+cameraID, func() { p.runPreEventCapture(ctx, cameraID, cfg) })
+		}
+	}
+	log.Printf("Capturing %d snapshots every %dms on events", cfg.Count, cfg.IntervalMs)
+}
+
+// forget drops the pre-event snapshots and captured bursts of a removed
+// camera
+func (b *eventBursts) forget(cameraID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pre, cameraID)
+	delete(b.active, cameraID)
+	kept := b.order[:0]
+	for _, burst := range b.order {
+		if burst.cameraID != cameraID {
+			kept = append(kept, burst)
+			continue
+		}
+		burst.mu.Lock()
+		for id := range burst.events {
+			delete(b.byEvent, id)
+		}
+		burst.mu.Unlock()
+	}
+	b.order = kept
+}
+
+// runPreEventCapture keeps the last PreCount snapshots of a camera until ctx
+// is canceled. Failures are skipped; the camera may not be connected yet.
+func (p *Plugin) runPreEventCapture(ctx context.Context, cameraID string, cfg *EventBurstConfig) {
+	ticker := time.NewTicker(cfg.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := p.captureBurstFrame(ctx, cameraID)
+		if err != nil {
+			continue
+		}
+		b := &p.bursts
+		b.mu.Lock()
+		if ctx.Err() == nil {
+			frames := append(b.pre[cameraID], burstFrame{capturedAt: time.Now(), data: data})
+			if len(frames) > cfg.PreCount {
+				frames = frames[len(frames)-cfg.PreCount:]
+			}
+			b.pre[cameraID] = frames
+		}
+		b.mu.Unlock()
+	}
+}
+
+// startEventBurst attaches ev to the running burst of its camera, or starts
+// a new one with the camera's pre-event snapshots
+func (p *Plugin) startEventBurst(ev Event) {
+	b := &p.bursts
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.config == nil {
+		return
+	}
+
+	if burst := b.active[ev.CameraID]; burst != nil {
+		burst.mu.Lock()
+		burst.events[ev.ID] = ev.Timestamp
+		burst.mu.Unlock()
+		b.byEvent[ev.ID] = burst
+		return
+	}
+
+	burst := &eventBurst{
+		cameraID: ev.CameraID,
+		done:     make(chan struct{}),
+		events:   map[string]time.Time{ev.ID: ev.Timestamp},
+	}
+	// Snapshots from before failures of the pre-event capture are stale, and
+	// one taken since the event happened is not from before it
+	oldest := time.Now().Add(-time.Duration(b.config.PreCount+1) * b.config.interval())
+	for _, frame := range b.pre[ev.CameraID] {
+		if frame.capturedAt.After(oldest) && !frame.capturedAt.After(ev.Timestamp) {
+			burst.frames = append(burst.frames, frame)
+		}
+	}
+	b.active[ev.CameraID] = burst
+	b.byEvent[ev.ID] = burst
+	b.storeLocked(burst)
+
+	ctx, cfg := b.ctx, b.config
+	go runGuarded("event burst of "+ev.CameraID, func() { p.runEventBurst(ctx, burst, cfg) })
+}
+
+// runEventBurst captures the burst's snapshots
+func (p *Plugin) runEventBurst(ctx context.Context, burst *eventBurst, cfg *EventBurstConfig) {
+	defer func() {
+		b := &p.bursts
+		b.mu.Lock()
+		if b.active[burst.cameraID] == burst {
+			delete(b.active, burst.cameraID)
+		}
+		b.mu.Unlock()
+		close(burst.done)
+	}()
+
+	for shot := 0; shot < cfg.Count; shot++ {
+		if shot > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(cfg.interval()):
+			}
+		}
+		data, err := p.captureBurstFrame(ctx, burst.cameraID)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Event snapshot of %s failed: %v", burst.cameraID, err)
+			}
+			continue
+		}
+		burst.add(burstFrame{capturedAt: time.Now(), data: data})
+	}
+}
+
+// captureBurstFrame takes a snapshot of a camera through its device worker
+func (p *Plugin) captureBurstFrame(ctx context.Context, cameraID string) ([]byte, error) {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cam.client.GetTimeouts().Snapshot)
+	defer cancel()
+
+	var data []byte
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) (err error) {
+		data, err = cam.client.GetSnapshot(ctx, cam.Channel())
+		return err
+	})
+	return data, err
+}
+
+// EventMedia is the result of get_event_media
+type EventMedia struct {
+	EventID  string       `json:"event_id"`
+	CameraID string       `json:"camera_id"`
+	Complete bool         `json:"complete"` // false while snapshots are still being taken
+	Frames   []EventFrame `json:"frames"`
+}
+
+// EventFrame is a snapshot taken around an event
+type EventFrame struct {
+	CapturedAt time.Time `json:"captured_at"`
+	OffsetMs   int64     `json:"offset_ms"` // from the event time; negative before it
+	Image      string    `json:"image"`     // base64 JPEG
+}
+
+// GetEventMedia returns the snapshots captured for an event, waiting up to
+// wait (at most maxEventWait) for the burst to complete
+func (p *Plugin) GetEventMedia(ctx context.Context, eventID string, wait time.Duration) (*EventMedia, error) {
+	b := &p.bursts
+	b.mu.Lock()
+	burst := b.byEvent[eventID]
+	b.mu.Unlock()
+	if burst == nil {
+		return nil, fmt.Errorf("%w %s", errNoEventMedia, eventID)
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(min(wait, maxEventWait))
+		defer timer.Stop()
+		select {
+		case <-burst.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+
+	complete := burst.complete()
+	burst.mu.Lock()
+	defer burst.mu.Unlock()
+	at := burst.events[eventID]
+	media := &EventMedia{
+		EventID:  eventID,
+		CameraID: burst.cameraID,
+		Complete: complete,
+		Frames:   make([]EventFrame, 0, len(burst.frames)),
+	}
+	for _, frame := range burst.frames {
+		media.Frames = append(media.Frames, EventFrame{
+			CapturedAt: frame.capturedAt.UTC(),
+			OffsetMs:   frame.capturedAt.Sub(at).Milliseconds(),
+			Image:      base64.StdEncoding.EncodeToString(frame.data),
+		})
+	}
+	return media, nil
+}
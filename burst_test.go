@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestParseEventBurst(t *testing.T) {
+	burst, err := parseEventBurst(map[string]interface{}{})
+	if err != nil || burst != nil {
+		t.Fatalf("Expected bursts to be off by default, got %+v, %v", burst, err)
+	}
+
+	burst, err = parseEventBurst(map[string]interface{}{"event_burst": map[string]interface{}{}})
+	if err != nil || burst.Count != 3 || burst.IntervalMs != 1000 || burst.PreCount != 0 {
+		t.Errorf("Unexpected defaults %+v, %v", burst, err)
+	}
+
+	invalid := []map[string]interface{}{
+		{"count": float64(11)},
+		{"count": float64(-1)},
+		{"interval_ms": float64(100)},
+		{"pre_count": float64(2)},
+		{"pre_count": float64(6), "cameras": []interface{}{"cam_1"}},
+		{"count": "three"},
+	}
+	for _, cfg := range invalid {
+		var cfgErr *ConfigError
+		if _, err := parseEventBurst(map[string]interface{}{"event_burst": cfg}); !errors.As(err, &cfgErr) {
+			t.Errorf("Expected a config error for %v, got %v", cfg, err)
+		}
+	}
+}
+
+func TestEventBurstConfig_Triggers(t *testing.T) {
+	cfg := EventBurstConfig{EventTypes: []string{EventPerson}, Cameras: []string{"cam_1"}}
+	tests := []struct {
+		ev   Event
+		want bool
+	}{
+		{Event{Type: EventPerson, State: EventStart, CameraID: "cam_1"}, true},
+		{Event{Type: EventPerson, State: EventEnd, CameraID: "cam_1"}, false},
+		{Event{Type: EventMotion, State: EventStart, CameraID: "cam_1"}, false},
+		{Event{Type: EventPerson, State: EventStart, CameraID: "cam_2"}, false},
+		{Event{Type: EventPerson, State: EventStart, CameraID: "cam_1", Data: map[string]interface{}{"historical": true}}, false},
+	}
+	for _, tt := range tests {
+		if got := cfg.triggers(tt.ev); got != tt.want {
+			t.Errorf("triggers(%+v) = %v, want %v", tt.ev, got, tt.want)
+		}
+	}
+}
+
+func TestPlugin_EventBurst(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", Snapshot: []byte("\xff\xd8 frame \xff\xd9")})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+	cameraID := host + "_ch0"
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"event_burst": map[string]interface{}{
+			"count": float64(2), "interval_ms": float64(250), "pre_count": float64(1),
+			"cameras": []interface{}{cameraID},
+		},
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	// Wait for a pre-event snapshot
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		plugin.bursts.mu.Lock()
+		ready := len(plugin.bursts.pre[cameraID]) > 0
+		plugin.bursts.mu.Unlock()
+		if ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("No pre-event snapshot was taken")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	ctx := context.Background()
+	first, err := plugin.EmitTestEvent(ctx, TestEventRequest{CameraID: cameraID, Type: EventMotion, State: EventStart})
+	if err != nil {
+		t.Fatalf("EmitTestEvent failed: %v", err)
+	}
+	second, err := plugin.EmitTestEvent(ctx, TestEventRequest{CameraID: cameraID, Type: EventPerson, State: EventStart})
+	if err != nil {
+		t.Fatalf("EmitTestEvent failed: %v", err)
+	}
+	if first[0].Data["media"] != true || second[0].Data["media"] != true {
+		t.Fatalf("Expected events with media, got %+v and %+v", first[0], second[0])
+	}
+
+	media, err := plugin.GetEventMedia(ctx, first[0].ID, 5*time.Second)
+	if err != nil {
+		t.Fatalf("GetEventMedia failed: %v", err)
+	}
+	if !media.Complete || media.CameraID != cameraID || len(media.Frames) != 3 {
+		t.Fatalf("Expected a complete burst of 3 frames, got %+v", media)
+	}
+	// The pre-event frame may be less than a millisecond old, so its
+	// truncated offset can be 0
+	if !media.Frames[0].CapturedAt.Before(first[0].Timestamp) || media.Frames[2].OffsetMs <= 0 || media.Frames[1].Image == "" {
+		t.Errorf("Expected a frame before and after the event, got %+v", media.Frames)
+	}
+
+	// An event starting during the burst shares its snapshots
+	shared, err := plugin.GetEventMedia(ctx, second[0].ID, 0)
+	if err != nil || len(shared.Frames) != 3 || shared.EventID != second[0].ID {
+		t.Errorf("Expected the second event to share the burst, got %+v, %v", shared, err)
+	}
+
+	// Ends do not trigger bursts
+	ended, err := plugin.EmitTestEvent(ctx, TestEventRequest{CameraID: cameraID, Type: EventMotion, State: EventEnd})
+	if err != nil {
+		t.Fatalf("EmitTestEvent failed: %v", err)
+	}
+	if _, err := plugin.GetEventMedia(ctx, ended[0].ID, 0); !errors.Is(err, errNoEventMedia) {
+		t.Errorf("Expected no media for an end, got %v", err)
+	}
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "get_event_media", Params: []byte(`{"event_id": "` + first[0].ID + `"}`)})
+	if resp.Error != nil || len(resp.Result.(*EventMedia).Frames) != 3 {
+		t.Errorf("Unexpected get_event_media response: %+v", resp)
+	}
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "get_event_media", Params: []byte(`{"event_id": "x", "wait_ms": -1}`)})
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("Expected invalid params, got %+v", resp.Error)
+	}
+}
+
+func TestEventBursts_Retention(t *testing.T) {
+	var b eventBursts
+	b.byEvent = make(map[string]*eventBurst)
+	var first *eventBurst
+	for i := 0; i <= maxStoredBursts; i++ {
+		id := string(rune('a' + i))
+		burst := &eventBurst{events: map[string]time.Time{id: time.Now()}}
+		if first == nil {
+			first = burst
+		}
+		b.byEvent[id] = burst
+		b.storeLocked(burst)
+	}
+	if len(b.order) != maxStoredBursts || b.order[0] == first {
+		t.Errorf("Expected the oldest burst to be dropped, have %d", len(b.order))
+	}
+	if _, ok := b.byEvent["a"]; ok || len(b.byEvent) != maxStoredBursts {
+		t.Errorf("Expected the oldest event to be forgotten, have %d", len(b.byEvent))
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
 )
 
 // Camera represents a Reolink camera instance
@@ -16,19 +18,38 @@ type Camera struct {
 	host     string
 	channel  int
 	protocol string // "rtsp" (default), "hls", or "rtmp"
-	client   *Client
+	client   *reolink.Client
 
-	ability   *Ability
-	encConfig *EncoderConfig
+	ability   *reolink.Ability
+	encConfig *reolink.EncoderConfig
 
 	online   bool
 	lastSeen time.Time
 
+	// outageStart is when the camera was last seen before it went offline;
+	// zero while online
+	outageStart time.Time
+
+	// powerSaving stops periodic traffic that would keep a battery camera awake
+	powerSaving bool
+
+	// lensMode is the viewer configuration of Duo and Fisheye cameras
+	lensMode *LensMode
+
+	// orientation is the ISP rotation and mirroring, once read
+	orientation *Orientation
+
+	// overrides force capabilities on or off where detection gets them wrong
+	overrides map[string]bool
+
+	// aiSupport is the AI detection reported by the device, nil until probed
+	aiSupport *reolink.AISupport
+
 	mu sync.RWMutex
 }
 
 // NewCamera creates a new Reolink camera instance
-func NewCamera(id, name, model, host string, channel int, client *Client) *Camera {
+func NewCamera(id, name, model, host string, channel int, client *reolink.Client) *Camera {
 	return &Camera{
 		id:       id,
 		name:     name,
@@ -39,6 +60,33 @@ func NewCamera(id, name, model, host string, channel int, client *Client) *Camer
 		client:   client,
 		online:   true,
 		lastSeen: time.Now(),
+		lensMode: defaultLensMode(model),
+	}
+}
+
+// relocated returns the camera on another address of its device, keeping its
+// ID and settings
+func (c *Camera) relocated(host string, client *reolink.Client) *Camera {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Camera{
+		id:       c.id,
+		name:     c.name,
+		model:    c.model,
+		host:     host,
+		channel:  c.channel,
+		protocol: c.protocol,
+		client:   client,
+		online:   true,
+		lastSeen: time.Now(),
+
+		ability:     c.ability,
+		encConfig:   c.encConfig,
+		powerSaving: c.powerSaving,
+		lensMode:    c.lensMode,
+		orientation: c.orientation,
+		overrides:   c.overrides,
+		aiSupport:   c.aiSupport,
 	}
 }
 
@@ -62,26 +110,50 @@ func (c *Camera) Protocol() string {
 	return c.protocol
 }
 
-func (c *Camera) ID() string      { return c.id }
-func (c *Camera) Name() string    { return c.name }
-func (c *Camera) Model() string   { return c.model }
-func (c *Camera) Host() string    { return c.host }
-func (c *Camera) Channel() int    { return c.channel }
+func (c *Camera) ID() string    { return c.id }
+func (c *Camera) Model() string { return c.model }
+func (c *Camera) Host() string  { return c.host }
+func (c *Camera) Channel() int  { return c.channel }
+
+func (c *Camera) Name() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.name
+}
+
+// SetName updates the display name of the camera
+func (c *Camera) SetName(name string) {
+	c.mu.Lock()
+	c.name = name
+	c.mu.Unlock()
+}
 
 // DeviceType returns the type of device (camera, doorbell, nvr, battery)
 func (c *Camera) DeviceType() string {
-	if isDoorbellModel(c.model) {
-		return "doorbell"
-	}
-	if isBatteryModel(c.model) {
-		return "battery"
-	}
+	c.mu.RLock()
+	doorbell := c.isDoorbell()
+	battery := c.overridden(CapBattery, isBatteryModel(c.model))
+	c.mu.RUnlock()
+
 	// Check if it's an NVR based on channel count
+	var nvr bool
 	if c.client != nil {
 		info := c.client.GetCachedDeviceInfo()
-		if info != nil && info.ChannelCount > 1 {
-			return "nvr"
-		}
+		nvr = info != nil && info.ChannelCount > 1
+	}
+	return deviceTypeOf(doorbell, battery, nvr)
+}
+
+// deviceTypeOf returns the device type cameras report, doorbells and
+// battery cameras taking precedence over the NVR they may be attached to
+func deviceTypeOf(doorbell, battery, nvr bool) string {
+	switch {
+	case doorbell:
+		return "doorbell"
+	case battery:
+		return "battery"
+	case nvr:
+		return "nvr"
 	}
 	return "camera"
 }
@@ -92,52 +164,115 @@ func (c *Camera) IsOnline() bool {
 	return c.online
 }
 
+// LastSeen returns when the device last answered, falling back to the time
+// the camera was added if no request has succeeded yet
 func (c *Camera) LastSeen() time.Time {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.lastSeen
+	lastSeen := c.lastSeen
+	c.mu.RUnlock()
+
+	if c.client != nil {
+		if seen := c.client.LastSeen(); seen.After(lastSeen) {
+			return seen
+		}
+	}
+	return lastSeen
 }
 
-func (c *Camera) SetAbility(ability *Ability) {
+// setOnline updates the online state and reports whether it changed
+func (c *Camera) setOnline(online bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed := c.online != online
+	c.online = online
+	return changed
+}
+
+func (c *Camera) SetAbility(ability *reolink.Ability) {
 	c.mu.Lock()
 	c.ability = ability
 	c.mu.Unlock()
 }
 
-func (c *Camera) SetEncoderConfig(cfg *EncoderConfig) {
+// Ability returns the cached device ability with the capability overrides
+// applied, or nil if not probed
+func (c *Camera) Ability() *reolink.Ability {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ability == nil {
+		return nil
+	}
+	ability := c.effectiveAbility()
+	return &ability
+}
+
+// enableAIDetection marks face and/or package detection as supported once the
+// camera reports them in its AI state
+func (c *Camera) enableAIDetection(face, pkg bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ability := reolink.Ability{}
+	if c.ability != nil {
+		ability = *c.ability
+	}
+	if (!face || ability.FaceDetection) && (!pkg || ability.PackageDetection) {
+		return
+	}
+	ability.FaceDetection = ability.FaceDetection || face
+	ability.PackageDetection = ability.PackageDetection || pkg
+	c.ability = &ability
+}
+
+func (c *Camera) SetEncoderConfig(cfg *reolink.EncoderConfig) {
 	c.mu.Lock()
 	c.encConfig = cfg
 	c.mu.Unlock()
 }
 
+// EncoderConfig returns the cached encoder settings, or nil if not fetched yet
+func (c *Camera) EncoderConfig() *reolink.EncoderConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.encConfig
+}
+
 func (c *Camera) Capabilities() []string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	caps := []string{"video", "snapshot"}
 
-	if c.ability != nil {
-		if c.ability.PTZ || c.ability.PanTilt {
-			caps = append(caps, "ptz")
-		}
-		if c.ability.TwoWayAudio {
-			caps = append(caps, "two_way_audio")
-		}
-		if c.ability.AudioAlarm {
-			caps = append(caps, "audio")
-		}
+	ability := c.effectiveAbility()
+	if ability.PTZ || ability.PanTilt {
+		caps = append(caps, CapPTZ)
+	}
+	if ability.TwoWayAudio {
+		caps = append(caps, CapTwoWayAudio)
+	}
+	if ability.AudioAlarm {
+		caps = append(caps, CapAudio)
+	}
+	if ability.Floodlight {
+		caps = append(caps, CapLight)
+	}
+	if ability.FaceDetection {
+		caps = append(caps, CapFaceDetection)
+	}
+	if ability.PackageDetection {
+		caps = append(caps, CapPackageDetection)
 	}
 
 	// Detect from model
 	model := c.model
-	if isDoorbellModel(model) {
-		caps = append(caps, "doorbell")
+	if c.isDoorbell() {
+		caps = append(caps, CapDoorbell)
 	}
-	if isBatteryModel(model) {
-		caps = append(caps, "battery")
+	if c.overridden(CapBattery, isBatteryModel(model)) {
+		caps = append(caps, CapBattery)
 	}
-	if hasAIDetection(model) {
-		caps = append(caps, "ai_detection", "motion")
+	if len(c.aiTypes()) > 0 {
+		caps = append(caps, CapAIDetection, "motion")
 	}
 
 	return caps
@@ -153,11 +288,11 @@ func (c *Camera) StreamURL(quality string) string {
 
 func (c *Camera) SnapshotURL() string {
 	return fmt.Sprintf("http://%s:%d/cgi-bin/api.cgi?cmd=Snap&channel=%d",
-		c.host, c.client.port, c.channel)
+		c.client.ActiveHost(), c.client.Port(), c.channel)
 }
 
 func (c *Camera) PTZControl(ctx context.Context, cmd PTZCommand) error {
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	switch cmd.Action {
 	case "pan":
@@ -183,17 +318,57 @@ func (c *Camera) PTZControl(ctx context.Context, cmd PTZCommand) error {
 	case "preset":
 		ptzCmd.Operation = "ToPos"
 		ptzCmd.Preset = cmd.Preset
+	case "area_zoom":
+		return c.areaZoom(ctx, cmd.Area)
 	default:
 		return fmt.Errorf("unknown PTZ action: %s", cmd.Action)
 	}
 
+	// Speeds are scaled to the range of the model
+	speeds := reolink.LookupModel(c.model).PTZSpeed
 	if cmd.Speed > 0 {
-		ptzCmd.Speed = int(cmd.Speed * 64)
+		ptzCmd.Speed = int(cmd.Speed * float64(speeds.Max))
 	}
+	ptzCmd.Speed = min(max(ptzCmd.Speed, speeds.Min), speeds.Max)
 
 	return c.client.PTZControl(ctx, c.channel, ptzCmd)
 }
 
+// areaZoom centers and zooms the camera on a normalized image rectangle
+func (c *Camera) areaZoom(ctx context.Context, area *PTZArea) error {
+	if area == nil {
+		return fmt.Errorf("area is required for area_zoom")
+	}
+	if area.Width <= 0 || area.Height <= 0 || area.X < 0 || area.Y < 0 ||
+		area.X+area.Width > 1 || area.Y+area.Height > 1 {
+		return fmt.Errorf("area must be a non-empty rectangle within 0..1")
+	}
+	if ability := c.Ability(); ability != nil && !ability.PTZ {
+		return fmt.Errorf("camera %s does not support PTZ zoom", c.id)
+	}
+
+	// The camera expects the rectangle in main stream pixel coordinates
+	c.mu.RLock()
+	encConfig := c.encConfig
+	c.mu.RUnlock()
+	if encConfig == nil || encConfig.MainStream.Width == 0 {
+		cfg, err := c.client.GetEncoderConfig(ctx, c.channel)
+		if err != nil {
+			return fmt.Errorf("failed to get stream resolution: %w", err)
+		}
+		c.SetEncoderConfig(cfg)
+		encConfig = cfg
+	}
+	width := float64(encConfig.MainStream.Width)
+	height := float64(encConfig.MainStream.Height)
+	if width == 0 || height == 0 {
+		return fmt.Errorf("unknown stream resolution for camera %s", c.id)
+	}
+
+	return c.client.PTZAreaZoom(ctx, c.channel,
+		int(area.X*width), int(area.Y*height), int(area.Width*width), int(area.Height*height))
+}
+
 func (c *Camera) GetSnapshot(ctx context.Context) (string, error) {
 	data, err := c.client.GetSnapshot(ctx, c.channel)
 	if err != nil {
@@ -231,6 +406,42 @@ func (c *Camera) GetPTZPresets(ctx context.Context) ([]CameraPreset, error) {
 	return result, nil
 }
 
+// GetPerformance fetches current load statistics from the device
+func (c *Camera) GetPerformance(ctx context.Context) (*reolink.Performance, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("camera %s has no client", c.id)
+	}
+	return c.client.GetPerformance(ctx)
+}
+
+// Storage returns the cached storage info of the device, if any
+func (c *Camera) Storage() []reolink.HddInfo {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.GetCachedHddInfo()
+}
+
+// MAC returns the device's MAC address, or "" if it has not been read
+func (c *Camera) MAC() string {
+	if c.client == nil {
+		return ""
+	}
+	if link := c.client.GetCachedLocalLink(); link != nil {
+		return link.MAC
+	}
+	return ""
+}
+
+// Network returns the device's network settings, or nil if they have not
+// been read
+func (c *Camera) Network() *reolink.LocalLink {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.GetCachedLocalLink()
+}
+
 // CameraDeviceInfo represents device information
 type CameraDeviceInfo struct {
 	Model           string
@@ -262,32 +473,13 @@ func (c *Camera) GetDeviceInfo() *CameraDeviceInfo {
 
 // Helper functions for model detection
 func isDoorbellModel(model string) bool {
-	return containsIgnoreCase(model, "doorbell")
+	return reolink.LookupModel(model).Doorbell
 }
 
 func isBatteryModel(model string) bool {
-	keywords := []string{"argus", "lumus", "go", "battery"}
-	for _, kw := range keywords {
-		if containsIgnoreCase(model, kw) {
-			return true
-		}
-	}
-	return false
+	return reolink.LookupModel(model).Battery
 }
 
 func hasAIDetection(model string) bool {
-	noAI := []string{"rlc-410", "rlc-420", "e1 zoom", "c1 pro"}
-	for _, m := range noAI {
-		if containsIgnoreCase(model, m) {
-			return false
-		}
-	}
-	return true
-}
-
-func containsIgnoreCase(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr ||
-		len(s) > 0 && len(substr) > 0 &&
-		(s[0]|0x20 == substr[0]|0x20) && containsIgnoreCase(s[1:], substr[1:]) ||
-		len(s) > 0 && containsIgnoreCase(s[1:], substr))
+	return reolink.LookupModel(model).AI
 }
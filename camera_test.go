@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
 )
 
 func TestNewCamera(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	if camera == nil {
@@ -34,7 +39,7 @@ func TestNewCamera(t *testing.T) {
 }
 
 func TestCamera_Getters(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	if camera.ID() != "cam_1" {
@@ -55,7 +60,7 @@ func TestCamera_Getters(t *testing.T) {
 }
 
 func TestCamera_IsOnline(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	if !camera.IsOnline() {
@@ -69,7 +74,7 @@ func TestCamera_IsOnline(t *testing.T) {
 }
 
 func TestCamera_LastSeen(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	lastSeen := camera.LastSeen()
@@ -83,10 +88,10 @@ func TestCamera_LastSeen(t *testing.T) {
 }
 
 func TestCamera_SetAbility(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
-	ability := &Ability{
+	ability := &reolink.Ability{
 		PTZ:         true,
 		PanTilt:     true,
 		TwoWayAudio: true,
@@ -103,11 +108,11 @@ func TestCamera_SetAbility(t *testing.T) {
 }
 
 func TestCamera_SetEncoderConfig(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
-	cfg := &EncoderConfig{
-		MainStream: StreamConfig{
+	cfg := &reolink.EncoderConfig{
+		MainStream: reolink.StreamConfig{
 			Width:     1920,
 			Height:    1080,
 			FrameRate: 30,
@@ -125,7 +130,7 @@ func TestCamera_SetEncoderConfig(t *testing.T) {
 }
 
 func TestCamera_Capabilities_Basic(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	caps := camera.Capabilities()
@@ -151,10 +156,10 @@ func TestCamera_Capabilities_Basic(t *testing.T) {
 }
 
 func TestCamera_Capabilities_WithAbility(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
-	ability := &Ability{
+	ability := &reolink.Ability{
 		PTZ:         true,
 		TwoWayAudio: true,
 		AudioAlarm:  true,
@@ -190,7 +195,7 @@ func TestCamera_Capabilities_WithAbility(t *testing.T) {
 }
 
 func TestCamera_Capabilities_Doorbell(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "Reolink Video Doorbell", "192.168.1.100", 0, client)
 
 	caps := camera.Capabilities()
@@ -208,7 +213,7 @@ func TestCamera_Capabilities_Doorbell(t *testing.T) {
 }
 
 func TestCamera_Capabilities_Battery(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Backyard", "Argus 3 Pro", "192.168.1.100", 0, client)
 
 	caps := camera.Capabilities()
@@ -226,7 +231,7 @@ func TestCamera_Capabilities_Battery(t *testing.T) {
 }
 
 func TestCamera_Capabilities_AIDetection(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	caps := camera.Capabilities()
@@ -251,7 +256,7 @@ func TestCamera_Capabilities_AIDetection(t *testing.T) {
 }
 
 func TestCamera_Capabilities_NoAIDetection(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Old Camera", "RLC-410", "192.168.1.100", 0, client)
 
 	caps := camera.Capabilities()
@@ -269,7 +274,7 @@ func TestCamera_Capabilities_NoAIDetection(t *testing.T) {
 }
 
 func TestCamera_StreamURL(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	// Default protocol is now RTSP
@@ -292,7 +297,7 @@ func TestCamera_StreamURL(t *testing.T) {
 }
 
 func TestCamera_StreamURL_RTSP(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 	camera.SetProtocol("rtsp")
 
@@ -308,7 +313,7 @@ func TestCamera_StreamURL_RTSP(t *testing.T) {
 }
 
 func TestCamera_Protocol(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	// Default should be RTSP
@@ -330,7 +335,7 @@ func TestCamera_Protocol(t *testing.T) {
 }
 
 func TestCamera_SnapshotURL(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	camera := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	url := camera.SnapshotURL()
@@ -342,14 +347,14 @@ func TestCamera_SnapshotURL(t *testing.T) {
 }
 
 func TestCamera_PTZControl_Pan(t *testing.T) {
-	_ = NewClient("192.168.1.100", 80, "admin", "password")
+	_ = reolink.NewClient("192.168.1.100", 80, "admin", "password")
 
 	// Test PTZ command construction
 	cmd := PTZCommand{Action: "pan", Direction: -1}
 
 	// We can't actually test the HTTP call without a mock server,
 	// but we can verify the command parsing logic
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	switch cmd.Action {
 	case "pan":
@@ -367,7 +372,7 @@ func TestCamera_PTZControl_Pan(t *testing.T) {
 
 func TestCamera_PTZControl_Tilt(t *testing.T) {
 	cmd := PTZCommand{Action: "tilt", Direction: 1}
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	switch cmd.Action {
 	case "tilt":
@@ -385,7 +390,7 @@ func TestCamera_PTZControl_Tilt(t *testing.T) {
 
 func TestCamera_PTZControl_Zoom(t *testing.T) {
 	cmd := PTZCommand{Action: "zoom", Direction: 1}
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	switch cmd.Action {
 	case "zoom":
@@ -403,7 +408,7 @@ func TestCamera_PTZControl_Zoom(t *testing.T) {
 
 func TestCamera_PTZControl_Stop(t *testing.T) {
 	cmd := PTZCommand{Action: "stop"}
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	switch cmd.Action {
 	case "stop":
@@ -417,7 +422,7 @@ func TestCamera_PTZControl_Stop(t *testing.T) {
 
 func TestCamera_PTZControl_Preset(t *testing.T) {
 	cmd := PTZCommand{Action: "preset", Preset: "home"}
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	switch cmd.Action {
 	case "preset":
@@ -435,7 +440,7 @@ func TestCamera_PTZControl_Preset(t *testing.T) {
 
 func TestCamera_PTZControl_CustomSpeed(t *testing.T) {
 	cmd := PTZCommand{Action: "pan", Direction: 1, Speed: 0.5}
-	ptzCmd := PTZCmd{Speed: 30}
+	ptzCmd := reolink.PTZCmd{Speed: 30}
 
 	if cmd.Speed > 0 {
 		ptzCmd.Speed = int(cmd.Speed * 64)
@@ -447,7 +452,7 @@ func TestCamera_PTZControl_CustomSpeed(t *testing.T) {
 }
 
 func TestCamera_PTZControl_UnknownAction(t *testing.T) {
-	client := NewClient("192.168.1.100", 80, "admin", "password")
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
 	cam := NewCamera("cam_1", "Front Door", "RLC-810A", "192.168.1.100", 0, client)
 
 	cmd := PTZCommand{Action: "unknown_action"}
@@ -524,32 +529,6 @@ func TestHasAIDetection(t *testing.T) {
 	}
 }
 
-func TestContainsIgnoreCase(t *testing.T) {
-	tests := []struct {
-		s        string
-		substr   string
-		expected bool
-	}{
-		{"Hello World", "hello", true},
-		{"Hello World", "WORLD", true},
-		{"Hello World", "lo Wo", true},
-		{"Hello World", "xyz", false},
-		{"", "", true},
-		{"Hello", "", true},
-		{"", "hello", false},
-		{"A", "a", true},
-		{"a", "A", true},
-	}
-
-	for _, tt := range tests {
-		result := containsIgnoreCase(tt.s, tt.substr)
-		if result != tt.expected {
-			t.Errorf("containsIgnoreCase(%q, %q) = %v, expected %v",
-				tt.s, tt.substr, result, tt.expected)
-		}
-	}
-}
-
 func TestPTZCommand(t *testing.T) {
 	cmd := PTZCommand{
 		Action:    "pan",
@@ -568,3 +547,77 @@ func TestPTZCommand(t *testing.T) {
 		t.Errorf("Expected speed 0.5, got %f", cmd.Speed)
 	}
 }
+
+func TestCamera_PTZControl_AreaZoom(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "PtzCtrl", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+	cam := NewCamera("cam_1", "Yard", "TrackMix PoE", "localhost", 0, client)
+	cam.SetAbility(&reolink.Ability{PTZ: true})
+	cam.SetEncoderConfig(&reolink.EncoderConfig{MainStream: reolink.StreamConfig{Width: 2000, Height: 1000}})
+
+	cmd := PTZCommand{Action: "area_zoom", Area: &PTZArea{X: 0.25, Y: 0.5, Width: 0.1, Height: 0.2}}
+	if err := cam.PTZControl(context.Background(), cmd); err != nil {
+		t.Fatalf("PTZControl failed: %v", err)
+	}
+
+	if len(received) != 1 || received[0].Param["op"] != "3DPosition" {
+		t.Fatalf("Unexpected command: %+v", received)
+	}
+	rect, _ := received[0].Param["rect"].(map[string]interface{})
+	if rect["x"] != float64(500) || rect["y"] != float64(500) || rect["width"] != float64(200) || rect["height"] != float64(200) {
+		t.Errorf("Unexpected rect: %v", rect)
+	}
+}
+
+func TestCamera_PTZControl_AreaZoom_Invalid(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	cam := NewCamera("cam_1", "Yard", "TrackMix PoE", "192.168.1.100", 0, client)
+	ctx := context.Background()
+
+	if err := cam.PTZControl(ctx, PTZCommand{Action: "area_zoom"}); err == nil {
+		t.Error("Expected error for missing area")
+	}
+	area := &PTZArea{X: 0.9, Y: 0.1, Width: 0.2, Height: 0.2}
+	if err := cam.PTZControl(ctx, PTZCommand{Action: "area_zoom", Area: area}); err == nil {
+		t.Error("Expected error for area outside the image")
+	}
+
+	cam.SetAbility(&reolink.Ability{PanTilt: true})
+	area = &PTZArea{X: 0.1, Y: 0.1, Width: 0.2, Height: 0.2}
+	if err := cam.PTZControl(ctx, PTZCommand{Action: "area_zoom", Area: area}); err == nil {
+		t.Error("Expected error for camera without zoom")
+	}
+}
+
+func TestCamera_EnableAIDetection(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	camera := NewCamera("cam_1", "Porch", "RLC-811A", "192.168.1.100", 0, client)
+	camera.SetAbility(&reolink.Ability{PTZ: true})
+
+	camera.enableAIDetection(false, true)
+
+	ability := camera.Ability()
+	if !ability.PTZ || !ability.PackageDetection || ability.FaceDetection {
+		t.Errorf("Unexpected ability %+v", ability)
+	}
+
+	hasPackage := false
+	for _, cap := range camera.Capabilities() {
+		if cap == "face_detection" {
+			t.Error("Should not have 'face_detection' capability")
+		}
+		if cap == "package_detection" {
+			hasPackage = true
+		}
+	}
+	if !hasPackage {
+		t.Error("Should have 'package_detection' capability")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// capabilitiesVersion is bumped whenever the structure of CapabilitySet
+// changes, so hosts can tell which fields to expect
+const capabilitiesVersion = 2
+
+// Capabilities that can be forced on or off by capability_overrides, named
+// as in the capabilities list of a camera
+const (
+	CapPTZ              = "ptz"
+	CapTwoWayAudio      = "two_way_audio"
+	CapAudio            = "audio" // siren / audio alarm
+	CapLight            = "light"
+	CapFaceDetection    = "face_detection"
+	CapPackageDetection = "package_detection"
+	CapDoorbell         = "doorbell"
+	CapBattery          = "battery"
+	CapAIDetection      = "ai_detection" // person, vehicle and animal detection
+)
+
+// overridableCapabilities lists the valid keys of capability_overrides
+var overridableCapabilities = map[string]bool{
+	CapPTZ: true, CapTwoWayAudio: true, CapAudio: true, CapLight: true,
+	CapFaceDetection: true, CapPackageDetection: true,
+	CapDoorbell: true, CapBattery: true, CapAIDetection: true,
+}
+
+// validateCapabilityOverrides returns the unknown keys of overrides, sorted
+func validateCapabilityOverrides(overrides map[string]bool) []string {
+	var unknown []string
+	for name := range overrides {
+		if !overridableCapabilities[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// SetCapabilityOverrides forces capabilities on or off, for models whose
+// probed ability or model database entry is wrong
+func (c *Camera) SetCapabilityOverrides(overrides map[string]bool) {
+	c.mu.Lock()
+	c.overrides = overrides
+	c.mu.Unlock()
+}
+
+// overridden returns the configured value of a capability, or detected if it
+// is not overridden. Callers hold c.mu.
+func (c *Camera) overridden(name string, detected bool) bool {
+	if v, ok := c.overrides[name]; ok {
+		return v
+	}
+	return detected
+}
+
+// effectiveAbility returns the probed ability, zero if not probed, with the
+// overrides applied. Callers hold c.mu.
+func (c *Camera) effectiveAbility() reolink.Ability {
+	var ability reolink.Ability
+	if c.ability != nil {
+		ability = *c.ability
+	}
+	if ptz, ok := c.overrides[CapPTZ]; ok && ptz != (ability.PTZ || ability.PanTilt) {
+		// Forced PTZ is assumed to include zoom
+		ability.PTZ, ability.PanTilt = ptz, false
+	}
+	ability.TwoWayAudio = c.overridden(CapTwoWayAudio, ability.TwoWayAudio)
+	ability.AudioAlarm = c.overridden(CapAudio, ability.AudioAlarm)
+	ability.Floodlight = c.overridden(CapLight, ability.Floodlight)
+	ability.FaceDetection = c.overridden(CapFaceDetection, ability.FaceDetection)
+	ability.PackageDetection = c.overridden(CapPackageDetection, ability.PackageDetection)
+	ability.Doorbell = c.isDoorbell()
+	return ability
+}
+
+// isDoorbell reports whether the camera is a doorbell, by its visitor
+// abilities or else its model name. Callers hold c.mu.
+func (c *Camera) isDoorbell() bool {
+	detected := (c.ability != nil && c.ability.IsDoorbellChannel(c.channel)) || isDoorbellModel(c.model)
+	return c.overridden(CapDoorbell, detected)
+}
+
+// SetAISupport records the AI detection reported by the device, which
+// takes precedence over the model database
+func (c *Camera) SetAISupport(support *reolink.AISupport) {
+	c.mu.Lock()
+	c.aiSupport = support
+	c.mu.Unlock()
+	if support != nil {
+		c.enableAIDetection(support.Face, support.Package)
+	}
+}
+
+// aiTypes returns the person, vehicle and animal detection of the camera:
+// as probed, else all three if the model database lists AI detection.
+// Callers hold c.mu.
+func (c *Camera) aiTypes() []string {
+	all := []string{EventPerson, EventVehicle, EventAnimal}
+	if v, ok := c.overrides[CapAIDetection]; ok {
+		if v {
+			return all
+		}
+		return nil
+	}
+	if s := c.aiSupport; s != nil {
+		var types []string
+		for i, supported := range []bool{s.Person, s.Vehicle, s.Animal} {
+			if supported {
+				types = append(types, all[i])
+			}
+		}
+		return types
+	}
+	if hasAIDetection(c.model) {
+		return all
+	}
+	return nil
+}
+
+// CapabilitySet is the structured form of a camera's capabilities, detailed
+// enough for the host to render feature-specific controls
+type CapabilitySet struct {
+	Version  int             `json:"version"`
+	Video    bool            `json:"video"`
+	Snapshot bool            `json:"snapshot"`
+	PTZ      *PTZCapability  `json:"ptz,omitempty"`
+	Audio    AudioCapability `json:"audio"`
+	Light    bool            `json:"light"`
+	Siren    bool            `json:"siren"`
+	Battery  bool            `json:"battery"`
+	Doorbell bool            `json:"doorbell"`
+	AI       []string        `json:"ai"` // detection event types, empty without AI
+	Lens     *LensCapability `json:"lens,omitempty"`
+}
+
+// PTZCapability describes which PTZ movements a camera supports
+type PTZCapability struct {
+	Pan     bool `json:"pan"`
+	Tilt    bool `json:"tilt"`
+	Zoom    bool `json:"zoom"`
+	Presets bool `json:"presets"`
+}
+
+// AudioCapability describes audio input (microphone) and output (speaker)
+type AudioCapability struct {
+	In  bool `json:"in"`
+	Out bool `json:"out"`
+}
+
+// CapabilitySet returns the camera's structured capabilities
+func (c *Camera) CapabilitySet() *CapabilitySet {
+	c.mu.RLock()
+	ability := c.effectiveAbility()
+	battery := c.overridden(CapBattery, isBatteryModel(c.model))
+	doorbell := c.isDoorbell()
+	ai := c.aiTypes()
+	c.mu.RUnlock()
+
+	set := &CapabilitySet{
+		Version:  capabilitiesVersion,
+		Video:    true,
+		Snapshot: true,
+		Battery:  battery,
+		Doorbell: doorbell,
+		AI:       []string{},
+		Lens:     lensCapability(c.model),
+	}
+
+	if ability.PTZ || ability.PanTilt {
+		set.PTZ = &PTZCapability{
+			Pan:     true,
+			Tilt:    true,
+			Zoom:    ability.PTZ, // pan-tilt-only cameras have no optical zoom
+			Presets: true,
+		}
+	}
+	// Cameras with a siren or talk-back have a microphone
+	set.Audio.In = ability.AudioAlarm || ability.TwoWayAudio
+	set.Audio.Out = ability.TwoWayAudio
+	set.Light = ability.Floodlight
+	set.Siren = ability.AudioAlarm
+
+	set.AI = append(set.AI, ai...)
+	if ability.FaceDetection {
+		set.AI = append(set.AI, EventFace)
+	}
+	if ability.PackageDetection {
+		set.AI = append(set.AI, EventPackage)
+	}
+
+	return set
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestCamera_CapabilitySet(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	camera := NewCamera("cam_1", "Drive", "RLC-823A", "192.168.1.100", 0, client)
+	camera.SetAbility(&reolink.Ability{PTZ: true, AudioAlarm: true, Floodlight: true, PackageDetection: true})
+
+	set := camera.CapabilitySet()
+	if set.Version != capabilitiesVersion || !set.Video || !set.Snapshot {
+		t.Errorf("Unexpected base capabilities %+v", set)
+	}
+	if set.PTZ == nil || !set.PTZ.Pan || !set.PTZ.Tilt || !set.PTZ.Zoom || !set.PTZ.Presets {
+		t.Errorf("Expected full PTZ, got %+v", set.PTZ)
+	}
+	if !set.Audio.In || set.Audio.Out {
+		t.Errorf("Expected audio in only, got %+v", set.Audio)
+	}
+	if !set.Light || !set.Siren || set.Battery || set.Doorbell {
+		t.Errorf("Unexpected feature flags %+v", set)
+	}
+	want := []string{EventPerson, EventVehicle, EventAnimal, EventPackage}
+	if !reflect.DeepEqual(set.AI, want) {
+		t.Errorf("Expected AI types %v, got %v", want, set.AI)
+	}
+}
+
+func TestCamera_CapabilitySet_PanTiltOnly(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	camera := NewCamera("cam_1", "Nursery", "E1 Zoom", "192.168.1.100", 0, client)
+	camera.SetAbility(&reolink.Ability{PanTilt: true, TwoWayAudio: true})
+
+	set := camera.CapabilitySet()
+	if set.PTZ == nil || set.PTZ.Zoom || !set.PTZ.Pan {
+		t.Errorf("Expected pan/tilt without zoom, got %+v", set.PTZ)
+	}
+	if !set.Audio.In || !set.Audio.Out {
+		t.Errorf("Expected two-way audio, got %+v", set.Audio)
+	}
+	if len(set.AI) != 0 {
+		t.Errorf("Expected no AI types, got %v", set.AI)
+	}
+}
+
+func TestCamera_CapabilitySet_NoAbility(t *testing.T) {
+	camera := NewCamera("cam_1", "Door", "Reolink Video Doorbell", "192.168.1.100", 0, nil)
+
+	set := camera.CapabilitySet()
+	if set.PTZ != nil || set.Audio.In || set.Light {
+		t.Errorf("Expected no probed features, got %+v", set)
+	}
+	if !set.Doorbell {
+		t.Error("Expected doorbell from model name")
+	}
+}
+
+func TestCamera_CapabilityOverrides(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	camera := NewCamera("cam_1", "Yard", "RLC-810A", "192.168.1.100", 0, client)
+	camera.SetAbility(&reolink.Ability{PanTilt: true, Floodlight: true})
+	camera.SetCapabilityOverrides(map[string]bool{CapAIDetection: false, CapLight: false, CapTwoWayAudio: true, CapBattery: true})
+
+	set := camera.CapabilitySet()
+	if len(set.AI) != 0 || set.Light || !set.Audio.Out || !set.Battery {
+		t.Errorf("Expected overrides to apply, got %+v", set)
+	}
+	if set.PTZ == nil || set.PTZ.Zoom {
+		t.Errorf("Expected detected pan/tilt to be kept, got %+v", set.PTZ)
+	}
+	want := []string{"video", "snapshot", CapPTZ, CapTwoWayAudio, CapBattery}
+	if caps := camera.Capabilities(); !reflect.DeepEqual(caps, want) {
+		t.Errorf("Expected capabilities %v, got %v", want, caps)
+	}
+	if ability := camera.Ability(); ability.Floodlight || !ability.TwoWayAudio {
+		t.Errorf("Expected Ability to apply overrides, got %+v", ability)
+	}
+	if camera.DeviceType() != "battery" {
+		t.Errorf("Expected battery device type, got %s", camera.DeviceType())
+	}
+
+	// Forcing PTZ on a camera without any
+	camera.SetAbility(&reolink.Ability{})
+	camera.SetCapabilityOverrides(map[string]bool{CapPTZ: true})
+	if set := camera.CapabilitySet(); set.PTZ == nil || !set.PTZ.Zoom {
+		t.Errorf("Expected forced PTZ, got %+v", set.PTZ)
+	}
+}
+
+func TestCamera_DoorbellFromAbility(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	camera := NewCamera("cam_1", "Porch", "D340P", "192.168.1.100", 0, client)
+	camera.SetAbility(&reolink.Ability{TwoWayAudio: true, Doorbell: true})
+
+	if !camera.CapabilitySet().Doorbell || camera.DeviceType() != "doorbell" {
+		t.Errorf("Expected a doorbell from its abilities, got %+v", camera.CapabilitySet())
+	}
+	if !containsString(camera.Capabilities(), CapDoorbell) {
+		t.Errorf("Expected the doorbell capability, got %v", camera.Capabilities())
+	}
+
+	camera.SetCapabilityOverrides(map[string]bool{CapDoorbell: false})
+	if camera.CapabilitySet().Doorbell {
+		t.Error("Expected the override to win over the abilities")
+	}
+}
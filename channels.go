@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// SyncChannelNames refreshes camera names from the channel names configured
+// on their NVRs and returns the number of cameras renamed. If cameraID is set
+// only that camera's device is synced.
+func (p *Plugin) SyncChannelNames(ctx context.Context, cameraID string) (int, error) {
+	var cameras []*Camera
+	if cameraID != "" {
+		cam, err := p.lookupCamera(cameraID)
+		if err != nil {
+			return 0, err
+		}
+		cameras = append(cameras, cam)
+	} else {
+		p.mu.RLock()
+		for _, cam := range p.cameras {
+			cameras = append(cameras, cam)
+		}
+		p.mu.RUnlock()
+	}
+
+	names := make(map[*reolink.Client]map[int]string)
+	renamed := 0
+	for _, cam := range cameras {
+		if cam.client == nil {
+			continue
+		}
+		if info := cam.client.GetCachedDeviceInfo(); info == nil || info.ChannelCount <= 1 {
+			continue // standalone cameras have no channel names
+		}
+
+		deviceNames, ok := names[cam.client]
+		if !ok {
+			client := cam.client
+			err := p.workerFor(client).do(ctx, func(ctx context.Context) (err error) {
+				deviceNames, err = client.GetChannelNames(ctx)
+				return err
+			})
+			if err != nil {
+				return renamed, fmt.Errorf("failed to get channel names from %s: %w", cam.Host(), err)
+			}
+			names[cam.client] = deviceNames
+		}
+
+		if name := deviceNames[cam.Channel()]; name != "" && name != cam.Name() {
+			log.Printf("Renaming camera %s from %q to %q", cam.ID(), cam.Name(), name)
+			cam.SetName(name)
+			renamed++
+		}
+	}
+	return renamed, nil
+}
+
+// RenameCamera sets a camera's display name, optionally pushing it to the
+// device so the NVR channel name matches
+func (p *Plugin) RenameCamera(ctx context.Context, cameraID, name string, push bool) error {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if push {
+		err := p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+			return cam.client.SetChannelName(ctx, cam.Channel(), name)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set name on device: %w", err)
+		}
+	}
+
+	cam.SetName(name)
+	log.Printf("Renamed camera %s to %q", cameraID, name)
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPlugin_SyncChannelNames(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetDevInfo": map[string]interface{}{
+			"DevInfo": map[string]interface{}{"model": "RLN8-410", "channelNum": float64(8)},
+		},
+		"GetChannelstatus": map[string]interface{}{
+			"count": float64(2),
+			"status": []interface{}{
+				map[string]interface{}{"channel": float64(0), "name": "Driveway", "online": float64(1)},
+				map[string]interface{}{"channel": float64(1), "name": "", "online": float64(0)},
+			},
+		},
+	})
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+	if _, err := client.GetDeviceInfo(context.Background()); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+
+	plugin := NewPlugin()
+	plugin.cameras["nvr_ch0"] = NewCamera("nvr_ch0", "NVR Ch1", "RLN8-410", "nvr", 0, client)
+	plugin.cameras["nvr_ch1"] = NewCamera("nvr_ch1", "NVR Ch2", "RLN8-410", "nvr", 1, client)
+
+	renamed, err := plugin.SyncChannelNames(context.Background(), "")
+	if err != nil {
+		t.Fatalf("SyncChannelNames failed: %v", err)
+	}
+	if renamed != 1 {
+		t.Errorf("Expected 1 camera renamed, got %d", renamed)
+	}
+	if name := plugin.cameras["nvr_ch0"].Name(); name != "Driveway" {
+		t.Errorf("Expected 'Driveway', got '%s'", name)
+	}
+	// Unnamed channels keep their generated name
+	if name := plugin.cameras["nvr_ch1"].Name(); name != "NVR Ch2" {
+		t.Errorf("Expected 'NVR Ch2', got '%s'", name)
+	}
+}
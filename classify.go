@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// ClassifyModelRequest is a classify_model request
+type ClassifyModelRequest struct {
+	Model string `json:"model"`
+	// Channels is the channel count the device reports, if known; devices
+	// with more than one are NVRs whatever their model
+	Channels int `json:"channels,omitempty"`
+}
+
+// ModelClassification is how the plugin classifies a model name, and why
+type ModelClassification struct {
+	Model        string                    `json:"model"`
+	DeviceType   string                    `json:"device_type"` // as cameras report it: "doorbell", "battery", "nvr" or "camera"
+	Capabilities reolink.ModelCapabilities `json:"capabilities"`
+	Matches      []reolink.ModelMatch      `json:"matches"` // model database entries that applied, in order
+	Reasons      []string                  `json:"reasons"`
+}
+
+// ClassifyModel runs the model detection for a model name without a device,
+// to check what the model database and overrides make of it
+func ClassifyModel(req ClassifyModelRequest) (*ModelClassification, error) {
+	if strings.TrimSpace(req.Model) == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	if req.Channels < 0 {
+		return nil, fmt.Errorf("channels must not be negative")
+	}
+
+	caps, matches := reolink.ExplainModel(req.Model)
+	result := &ModelClassification{
+		Model:        req.Model,
+		Capabilities: caps,
+		Matches:      matches,
+		Reasons:      []string{},
+	}
+	if result.Matches == nil {
+		result.Matches = []reolink.ModelMatch{}
+	}
+	for _, m := range matches {
+		source := "built-in"
+		if m.Override {
+			source = "override"
+		}
+		result.Reasons = append(result.Reasons,
+			fmt.Sprintf("matches %q (%s): %s", m.Match, source, describeModelEntry(m.ModelInfo)))
+	}
+
+	nvr := req.Channels > 1 || caps.NVR || caps.Hub
+	result.DeviceType = deviceTypeOf(caps.Doorbell, caps.Battery, nvr)
+	var why string
+	switch {
+	case caps.Doorbell:
+		why = "the model is a doorbell"
+	case caps.Battery:
+		why = "the model is battery powered"
+	case req.Channels > 1:
+		why = fmt.Sprintf("the device has %d channels", req.Channels)
+	case caps.Hub:
+		why = "the model is a Home Hub"
+	case caps.NVR:
+		why = "the model is an NVR"
+	default:
+		why = "the model is not a doorbell, battery camera or NVR"
+	}
+	result.Reasons = append(result.Reasons, fmt.Sprintf("device type %s: %s", result.DeviceType, why))
+	return result, nil
+}
+
+// describeModelEntry lists the fields a model database entry sets
+func describeModelEntry(e reolink.ModelInfo) string {
+	var fields []string
+	if e.Type != "" {
+		fields = append(fields, "type="+e.Type)
+	}
+	for _, f := range []struct {
+		name string
+		v    *bool
+	}{{"nvr", e.NVR}, {"hub", e.Hub}, {"doorbell", e.Doorbell}, {"battery", e.Battery}, {"ai", e.AI}} {
+		if f.v != nil {
+			fields = append(fields, fmt.Sprintf("%s=%t", f.name, *f.v))
+		}
+	}
+	if e.Lens != "" {
+		fields = append(fields, "lens="+e.Lens)
+	}
+	if r := e.PTZSpeed; r != nil {
+		fields = append(fields, fmt.Sprintf("ptz_speed=%d-%d", r.Min, r.Max))
+	}
+	if e.RTSPPrefix != "" {
+		fields = append(fields, "rtsp_prefix="+e.RTSPPrefix)
+	}
+	if len(fields) == 0 {
+		return "no fields"
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestClassifyModel(t *testing.T) {
+	defer reolink.SetModelOverrides(nil)
+	if err := reolink.SetModelOverrides([]reolink.ModelInfo{{Match: "cx410", Type: "camera"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		req        ClassifyModelRequest
+		deviceType string
+		reason     string
+	}{
+		{ClassifyModelRequest{Model: "RLC-810A"}, "camera", "not a doorbell"},
+		{ClassifyModelRequest{Model: "RLC-810A", Channels: 8}, "nvr", "has 8 channels"},
+		{ClassifyModelRequest{Model: "rln16-410"}, "nvr", `matches "rln" (built-in): type=nvr, nvr=true`},
+		{ClassifyModelRequest{Model: "Reolink Home Hub"}, "nvr", "a Home Hub"},
+		{ClassifyModelRequest{Model: "Reolink Video Doorbell PoE"}, "doorbell", "is a doorbell"},
+		{ClassifyModelRequest{Model: "ARGUS 3 PRO"}, "battery", "battery powered"},
+		{ClassifyModelRequest{Model: "CX410"}, "camera", `matches "cx410" (override): type=camera`},
+	}
+	for _, tt := range tests {
+		got, err := ClassifyModel(tt.req)
+		if err != nil {
+			t.Fatalf("ClassifyModel(%+v) failed: %v", tt.req, err)
+		}
+		if got.DeviceType != tt.deviceType || !strings.Contains(strings.Join(got.Reasons, "\n"), tt.reason) {
+			t.Errorf("ClassifyModel(%+v) = %s %q, want %s with %q", tt.req, got.DeviceType, got.Reasons, tt.deviceType, tt.reason)
+		}
+		if got.Capabilities != reolink.LookupModel(tt.req.Model) {
+			t.Errorf("Unexpected capabilities for %s: %+v", tt.req.Model, got.Capabilities)
+		}
+	}
+}
+
+func TestHandleRequest_ClassifyModel(t *testing.T) {
+	plugin := NewPlugin()
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "classify_model", Params: json.RawMessage(`{"model": "RLN8-410"}`)})
+	if resp.Error != nil {
+		t.Fatalf("classify_model failed: %s", resp.Error.Message)
+	}
+	if c := resp.Result.(*ModelClassification); c.DeviceType != "nvr" || !c.Capabilities.NVR || len(c.Matches) != 2 {
+		t.Errorf("Unexpected classification: %+v", c)
+	}
+
+	for _, params := range []string{`{}`, `{"model": " "}`, `{"model": "RLC-810A", "channels": -1}`} {
+		resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "classify_model", Params: json.RawMessage(params)})
+		if resp.Error == nil || resp.Error.Code != -32603 {
+			t.Errorf("Expected an error for %s, got %+v", params, resp.Error)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// The plugin binary doubles as a diagnostic tool: "reolink-plugin <command>"
+// talks to a device directly, without the JSON-RPC loop, so connectivity can
+// be debugged from a shell.
+
+// cliCommands maps subcommand names to their implementations
+var cliCommands = map[string]func(ctx context.Context, args []string, stdout io.Writer) error{
+	"probe":    cliProbe,
+	"snapshot": cliSnapshot,
+	"discover": cliDiscover,
+	"ptz":      cliPTZ,
+	"record":   cliRecord,
+}
+
+// maxDiscoverHosts bounds the size of a discover scan
+const maxDiscoverHosts = 1024
+
+// isCLICommand reports whether arg names a diagnostic subcommand
+func isCLICommand(arg string) bool {
+	_, ok := cliCommands[arg]
+	return ok
+}
+
+// runCLI runs a diagnostic subcommand and returns the process exit code
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !isCLICommand(args[0]) {
+		fmt.Fprintln(stderr, "usage: reolink-plugin <probe|snapshot|discover|ptz|record> [flags]")
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := cliCommands[args[0]](ctx, args[1:], stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// cliDevice holds the connection flags shared by the subcommands
+type cliDevice struct {
+	host     string
+	port     int
+	username string
+	password string
+	timeout  time.Duration
+}
+
+// newCLIFlags returns a flag set with the shared connection flags. The
+// password defaults to $REOLINK_PASSWORD so it stays out of shell history.
+func newCLIFlags(name string) (*flag.FlagSet, *cliDevice) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dev := &cliDevice{}
+	fs.StringVar(&dev.host, "host", "", "device IP address or hostname")
+	fs.IntVar(&dev.port, "port", 80, "HTTP port")
+	fs.StringVar(&dev.username, "user", "admin", "login username")
+	fs.StringVar(&dev.password, "password", os.Getenv("REOLINK_PASSWORD"), "login password (default $REOLINK_PASSWORD)")
+	fs.DurationVar(&dev.timeout, "timeout", 30*time.Second, "overall timeout")
+	return fs, dev
+}
+
+// connect logs in to the device
+func (d *cliDevice) connect(ctx context.Context) (*reolink.Client, error) {
+	if d.host == "" {
+		return nil, fmt.Errorf("-host is required")
+	}
+	return d.login(ctx, reolink.NewClient(d.host, d.port, d.username, d.password))
+}
+
+// login logs client in to the device
+func (d *cliDevice) login(ctx context.Context, client *reolink.Client) (*reolink.Client, error) {
+	if err := client.Login(ctx); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	return client, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// cliProbe prints everything the plugin can detect about a device
+func cliProbe(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, dev := newCLIFlags("probe")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dev.timeout)
+	defer cancel()
+
+	client, err := dev.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close(ctx)
+
+	result, err := client.ProbeCamera(ctx)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, result)
+}
+
+// cliSnapshot saves a snapshot of one channel to a file, or to stdout with -out -
+func cliSnapshot(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, dev := newCLIFlags("snapshot")
+	channel := fs.Int("channel", 0, "channel number (0-based)")
+	out := fs.String("out", "snapshot.jpg", "output file, or - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dev.timeout)
+	defer cancel()
+
+	client, err := dev.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close(ctx)
+
+	if *out == "-" {
+		_, err := client.StreamSnapshot(ctx, *channel, stdout)
+		return err
+	}
+
+	var size int64
+	var snapErr error
+	err = writeFileAtomicFrom(*out, 0o644, func(w io.Writer) error {
+		size, snapErr = client.StreamSnapshot(ctx, *channel, w)
+		return snapErr
+	})
+	if snapErr != nil {
+		return snapErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote %s (%d bytes)\n", *out, size)
+	return nil
+}
+
+// cliPTZ sends one PTZ command. Movements run for -duration and are then stopped.
+func cliPTZ(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, dev := newCLIFlags("ptz")
+	channel := fs.Int("channel", 0, "channel number (0-based)")
+	cmd := PTZCommand{}
+	fs.StringVar(&cmd.Action, "action", "", "pan, tilt, zoom, stop or preset")
+	fs.Float64Var(&cmd.Direction, "direction", 1, "movement direction: negative for left/down/zoom out")
+	fs.Float64Var(&cmd.Speed, "speed", 0, "movement speed from 0 to 1 (camera default if 0)")
+	fs.StringVar(&cmd.Preset, "preset", "", "preset ID for -action preset")
+	duration := fs.Duration("duration", time.Second, "how long to move before stopping")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch cmd.Action {
+	case "pan", "tilt", "zoom", "stop", "preset":
+	default:
+		return fmt.Errorf("-action must be pan, tilt, zoom, stop or preset")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dev.timeout+*duration)
+	defer cancel()
+
+	client, err := dev.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close(ctx)
+
+	cam := NewCamera("cli", dev.host, "", dev.host, *channel, client)
+	if err := cam.PTZControl(ctx, cmd); err != nil {
+		return err
+	}
+
+	switch cmd.Action {
+	case "pan", "tilt", "zoom":
+		select {
+		case <-time.After(*duration):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := cam.PTZControl(ctx, PTZCommand{Action: "stop"}); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(stdout, "OK")
+	return nil
+}
+
+// cliRecord exercises the read-only API of a device and saves the sanitized
+// exchanges as a test fixture
+func cliRecord(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, dev := newCLIFlags("record")
+	out := fs.String("out", "", "fixture file to write")
+	note := fs.String("note", "", "free-form note stored in the fixture, e.g. the hardware revision")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if dev.host == "" {
+		return fmt.Errorf("-host is required")
+	}
+	if *out == "" {
+		return fmt.Errorf("-out is required")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dev.timeout)
+	defer cancel()
+
+	client := reolink.NewClient(dev.host, dev.port, dev.username, dev.password)
+	rec := client.StartRecording()
+	if _, err := dev.login(ctx, client); err != nil {
+		return err
+	}
+
+	probe, err := client.ProbeCamera(ctx)
+	if err != nil {
+		return err
+	}
+	// Optional commands; unsupported ones are recorded as errors too
+	_, _ = client.GetLocalLink(ctx)
+	_, _ = client.GetNetPort(ctx)
+	if probe.IsNVR {
+		_, _ = client.GetChannelNames(ctx)
+	}
+	for ch := 0; ch < probe.ChannelCount; ch++ {
+		_, _ = client.GetEventState(ctx, ch)
+		if probe.HasPTZ {
+			_, _ = client.GetPTZPresets(ctx, ch)
+		}
+		_, _ = client.GetSnapshot(ctx, ch)
+	}
+	_ = client.Logout(ctx)
+
+	fixture := rec.Fixture(client.GetCachedDeviceInfo())
+	fixture.Note = *note
+	if err := fixture.Save(*out); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Recorded %d exchanges with %s to %s\n", len(fixture.Exchanges), fixture.Model, *out)
+	return nil
+}
+
+// cliDiscover scans a subnet for devices answering the Reolink HTTP API. With
+// a password, each device found is logged in to for its model and name.
+func cliDiscover(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, dev := newCLIFlags("discover")
+	subnet := fs.String("subnet", "", "IPv4 subnet to scan in CIDR notation, e.g. 192.168.1.0/24")
+	hostTimeout := fs.Duration("host-timeout", time.Second, "timeout per address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	hosts, err := subnetHosts(*subnet)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dev.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	found := []DiscoveredCamera{}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 64)
+	for _, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if cam, ok := discoverHost(ctx, host, dev, *hostTimeout); ok {
+				mu.Lock()
+				found = append(found, cam)
+				mu.Unlock()
+			}
+		}(host)
+	}
+	wg.Wait()
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Host < found[j].Host })
+	return writeJSON(stdout, found)
+}
+
+// discoverHost checks whether host answers the Reolink API and, given a
+// password, fetches its device info
+func discoverHost(ctx context.Context, host string, dev *cliDevice, timeout time.Duration) (DiscoveredCamera, bool) {
+	client := reolink.NewClient(host, dev.port, dev.username, dev.password)
+	client.SetTimeouts(reolink.Timeouts{Request: timeout, Login: 2 * timeout, Snapshot: timeout})
+
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Any well-formed API response, even "please login first", identifies a device
+	if err := client.Ping(probeCtx); err != nil {
+		return DiscoveredCamera{}, false
+	}
+
+	cam := DiscoveredCamera{
+		ID:           host,
+		Manufacturer: "Reolink",
+		Host:         host,
+		Port:         dev.port,
+		Capabilities: []string{},
+	}
+	if dev.password == "" {
+		return cam, true
+	}
+
+	loginCtx, cancel := context.WithTimeout(ctx, 4*timeout)
+	defer cancel()
+	if err := client.Login(loginCtx); err != nil {
+		return cam, true
+	}
+	defer client.Close(loginCtx)
+	if info, err := client.GetDeviceInfo(loginCtx); err == nil {
+		cam.Name = info.Name
+		cam.Model = info.Model
+		cam.Channels = info.ChannelCount
+		cam.Serial = info.Serial
+		cam.FirmwareVersion = info.FirmwareVersion
+	}
+	return cam, true
+}
+
+// subnetHosts lists the host addresses of an IPv4 subnet
+func subnetHosts(cidr string) ([]string, error) {
+	if cidr == "" {
+		return nil, fmt.Errorf("-subnet is required")
+	}
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 subnet %q", cidr)
+	}
+	ones, bits := ipNet.Mask.Size()
+	size := 1 << uint(bits-ones)
+	if size > maxDiscoverHosts {
+		return nil, fmt.Errorf("subnet %s is too large, at most %d addresses can be scanned", cidr, maxDiscoverHosts)
+	}
+
+	base := ipNet.IP.To4()
+	start := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	var hosts []string
+	for i := 0; i < size; i++ {
+		// Skip the network and broadcast addresses of regular subnets
+		if size > 2 && (i == 0 || i == size-1) {
+			continue
+		}
+		n := start + uint32(i)
+		hosts = append(hosts, net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).String())
+	}
+	return hosts, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// newCLITestServer fakes a device that accepts token logins and snapshots
+func newCLITestServer(t *testing.T, jpeg []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cmd") == "Snap" {
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(jpeg)
+			return
+		}
+		// Reject basic auth so Login falls back to the token API
+		if r.URL.Query().Get("user") != "" {
+			_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "GetDevInfo", Code: 1, Error: &apiErrorDetail{RspCode: -7}}})
+			return
+		}
+
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		if len(cmds) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if cmds[0].Cmd == "Login" {
+			_ = json.NewEncoder(w).Encode([]apiResponse{{
+				Cmd:   "Login",
+				Code:  0,
+				Value: map[string]interface{}{"Token": map[string]interface{}{"name": "cli", "leaseTime": float64(3600)}},
+			}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: cmds[0].Cmd, Code: 0, Value: map[string]interface{}{}}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunCLI_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "usage") {
+		t.Errorf("Expected usage message, got %q", stderr.String())
+	}
+
+	if isCLICommand("-pprof") {
+		t.Error("Flags should not be treated as subcommands")
+	}
+}
+
+func TestRunCLI_MissingHost(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"probe", "-password", "secret"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "-host is required") {
+		t.Errorf("Expected missing host error, got %q", stderr.String())
+	}
+}
+
+func TestRunCLI_Snapshot(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 'j', 'p', 'e', 'g'}
+	server := newCLITestServer(t, jpeg)
+	host, port := serverHostPort(server)
+	out := filepath.Join(t.TempDir(), "snap.jpg")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"snapshot", "-host", host, "-port", strconv.Itoa(port), "-password", "secret", "-out", out}
+	if code := runCLI(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil || !bytes.Equal(data, jpeg) {
+		t.Errorf("Unexpected snapshot contents: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "8 bytes") {
+		t.Errorf("Expected size in output, got %q", stdout.String())
+	}
+}
+
+func TestRunCLI_PTZInvalidAction(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"ptz", "-host", "127.0.0.1", "-action", "spin"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "-action must be") {
+		t.Errorf("Expected action error, got %q", stderr.String())
+	}
+}
+
+func TestSubnetHosts(t *testing.T) {
+	hosts, err := subnetHosts("192.168.1.0/30")
+	if err != nil {
+		t.Fatalf("subnetHosts failed: %v", err)
+	}
+	if len(hosts) != 2 || hosts[0] != "192.168.1.1" || hosts[1] != "192.168.1.2" {
+		t.Errorf("Unexpected hosts: %v", hosts)
+	}
+
+	if hosts, _ := subnetHosts("10.0.0.5/32"); len(hosts) != 1 || hosts[0] != "10.0.0.5" {
+		t.Errorf("Expected single host, got %v", hosts)
+	}
+
+	for _, cidr := range []string{"", "not-a-subnet", "fe80::/120", "10.0.0.0/16"} {
+		if _, err := subnetHosts(cidr); err == nil {
+			t.Errorf("Expected error for %q", cidr)
+		}
+	}
+}
+
+func TestDiscoverHost(t *testing.T) {
+	server := newCLITestServer(t, nil)
+	host, port := serverHostPort(server)
+
+	dev := &cliDevice{port: port, username: "admin"}
+	cam, ok := discoverHost(context.Background(), host, dev, reolink.DefaultTimeouts.Request)
+	if !ok {
+		t.Fatal("Expected device to be discovered")
+	}
+	if cam.Host != host || cam.Port != port || cam.Manufacturer != "Reolink" {
+		t.Errorf("Unexpected discovered camera: %+v", cam)
+	}
+
+	// Nothing listens on a closed server
+	server.Close()
+	if _, ok := discoverHost(context.Background(), host, dev, reolink.DefaultTimeouts.Request); ok {
+		t.Error("Expected no device on a closed port")
+	}
+}
+
+func TestRecord_RoundTrip(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", PTZ: true, AI: true, TokenOnly: true})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+	out := filepath.Join(t.TempDir(), "fixture.json")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"record", "-host", host, "-port", strconv.Itoa(port), "-password", "secret", "-out", out}
+	if code := runCLI(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("record failed with %d: %s", code, stderr.String())
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	for _, secret := range []string{"secret", reolinksim.DefaultCamera.Serial, reolinksim.DefaultCamera.MAC} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("Fixture contains %q", secret)
+		}
+	}
+
+	live := reolink.NewClient(host, port, "admin", "secret")
+	want, err := live.ProbeCamera(context.Background())
+	if err != nil {
+		t.Fatalf("Live ProbeCamera failed: %v", err)
+	}
+
+	fixture, err := reolink.LoadFixture(out)
+	if err != nil {
+		t.Fatalf("LoadFixture failed: %v", err)
+	}
+	if fixture.Model != reolinksim.DefaultCamera.Model {
+		t.Errorf("Expected model %s, got %s", reolinksim.DefaultCamera.Model, fixture.Model)
+	}
+	// Stream URLs embed the client's credentials, so replay with the same ones
+	replayed := reolink.NewClient(host, port, "admin", "secret")
+	replayed.SetTransport(reolink.NewReplayTransport(fixture))
+	got, err := replayed.ProbeCamera(context.Background())
+	if err != nil {
+		t.Fatalf("Replayed ProbeCamera failed: %v", err)
+	}
+	// Only the serial and network details differ, as they are redacted
+	want.Serial = "REDACTED"
+	if got.Network == nil || got.Network.Gateway != "REDACTED" || got.MAC == want.MAC {
+		t.Errorf("Expected redacted network details, got %s %+v", got.MAC, got.Network)
+	}
+	want.MAC, want.Network = got.MAC, got.Network
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Replay differs from live device:\ngot  %+v\nwant %+v", got, want)
+	}
+}
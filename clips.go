@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"log"
+	"path"
+)
+
+// DownloadClip streams a recording from a camera to the host as a chunked transfer
+func (p *Plugin) DownloadClip(ctx context.Context, cameraID, source string) (*TransferEnd, error) {
+	var result *TransferEnd
+	err := p.onCamera(longRunning(ctx), cameraID, func(ctx context.Context, cam *Camera) error {
+		body, size, err := cam.client.DownloadClip(ctx, source)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		result, err = p.sendTransfer(path.Base(source), "video/mp4", size, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Downloaded clip %s (%d bytes) from camera %s", source, result.Size, cameraID)
+	return result, nil
+}
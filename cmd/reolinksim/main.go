@@ -0,0 +1,108 @@
+// Command reolinksim serves a fleet of simulated Reolink devices, one per
+// port, to run the plugin or a host integration against without hardware.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func main() {
+	host := flag.String("host", "127.0.0.1", "address to listen on")
+	port := flag.Int("port", 8080, "port of the first device; further devices use the following ports")
+	count := flag.Int("count", 1, "number of devices")
+	model := flag.String("model", reolinksim.DefaultCamera.Model, "device model")
+	channels := flag.Int("channels", 1, "channels per device, more than 1 for an NVR")
+	user := flag.String("user", "admin", "login username")
+	password := flag.String("password", "", "login password")
+	noPTZ := flag.Bool("no-ptz", false, "simulate fixed cameras without PTZ")
+	tokenOnly := flag.Bool("token-only", false, "reject credentials in the URL, like newer firmware")
+	legacyEvents := flag.Bool("legacy-events", false, "reject GetEvents, like older firmware")
+	motionEvery := flag.Duration("motion-interval", 0, "toggle motion and person detection on every channel at this interval")
+	flag.Parse()
+
+	if *count < 1 {
+		log.Fatal("-count must be at least 1")
+	}
+
+	var sims []*reolinksim.Server
+	var servers []*http.Server
+	for i := 0; i < *count; i++ {
+		sim := reolinksim.New(reolinksim.Camera{
+			Model:        *model,
+			Name:         fmt.Sprintf("Sim %d", i+1),
+			Serial:       fmt.Sprintf("SIM%08d", i+1),
+			MAC:          fmt.Sprintf("ec:71:db:00:%02x:%02x", (i+1)>>8&0xff, (i+1)&0xff),
+			Channels:     *channels,
+			Username:     *user,
+			Password:     *password,
+			PTZ:          !*noPTZ,
+			AI:           true,
+			TokenOnly:    *tokenOnly,
+			LegacyEvents: *legacyEvents,
+		})
+		addr := net.JoinHostPort(*host, strconv.Itoa(*port+i))
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", addr, err)
+		}
+
+		srv := &http.Server{Handler: sim, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Device on %s stopped: %v", addr, err)
+			}
+		}()
+		log.Printf("Simulating %s \"Sim %d\" on http://%s", *model, i+1, addr)
+
+		sims = append(sims, sim)
+		servers = append(servers, srv)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *motionEvery > 0 {
+		go toggleMotion(ctx, sims, *channels, *motionEvery)
+	}
+
+	<-ctx.Done()
+	for _, srv := range servers {
+		_ = srv.Close()
+	}
+}
+
+// toggleMotion flips motion and person detection on every channel so hosts
+// receive a steady stream of events
+func toggleMotion(ctx context.Context, sims []*reolinksim.Server, channels int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	active := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		active = !active
+		for _, sim := range sims {
+			for ch := 0; ch < channels; ch++ {
+				sim.SetMotion(ch, active)
+				sim.SetAI(ch, reolinksim.AIPerson, active)
+			}
+		}
+	}
+}
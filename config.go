@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// FieldError is a validation error for a single config field
+type FieldError struct {
+	Field   string `json:"field"` // e.g. "devices[1].username"
+	Message string `json:"message"`
+}
+
+// ConfigError aggregates every validation error found in the plugin config
+type ConfigError struct {
+	Errors []FieldError
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// add records a validation error for field
+func (e *ConfigError) add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// deviceOnlyFields are device settings that cannot be inherited from defaults
+var deviceOnlyFields = []string{"host", "name", "channels"}
+
+// parseConfig decodes and validates the device list. Devices inherit the
+// settings of the top-level "defaults" block and may override any of them.
+// All problems are reported together in a *ConfigError rather than stopping
+// at the first.
+func (p *Plugin) parseConfig(config map[string]interface{}) error {
+	p.devices = nil
+
+	cfgErr := &ConfigError{}
+
+	defaults, ok := config["defaults"].(map[string]interface{})
+	if raw, set := config["defaults"]; set && raw != nil && !ok {
+		cfgErr.add("defaults", "must be an object")
+		return cfgErr
+	}
+	if defaults != nil {
+		for _, key := range deviceOnlyFields {
+			if _, set := defaults[key]; set {
+				cfgErr.add("defaults."+key, "cannot be set in defaults")
+			}
+		}
+		decodeDevice(cfgErr, "defaults", defaults)
+		if len(cfgErr.Errors) > 0 {
+			return cfgErr
+		}
+	}
+
+	raw, ok := config["devices"]
+	if !ok || raw == nil {
+		return nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		cfgErr.add("devices", "must be an array")
+		return cfgErr
+	}
+
+	hosts := make(map[string]int)
+	for i, entry := range entries {
+		field := fmt.Sprintf("devices[%d]", i)
+
+		// Decode each device on its own so one bad entry does not hide the others
+		deviceMap, ok := entry.(map[string]interface{})
+		if !ok {
+			cfgErr.add(field, "must be an object")
+			continue
+		}
+		device, ok := decodeDevice(cfgErr, field, mergeObjects(defaults, deviceMap))
+		if !ok {
+			continue
+		}
+
+		// Entries without a host are placeholders, e.g. from the settings UI
+		if device.Host == "" {
+			continue
+		}
+
+		if prev, dup := hosts[device.Host]; dup {
+			cfgErr.add(field+".host", "duplicate of devices[%d]", prev)
+			continue
+		}
+		hosts[device.Host] = i
+		for j, host := range device.FallbackHosts {
+			hostField := fmt.Sprintf("%s.fallback_hosts[%d]", field, j)
+			if prev, dup := hosts[host]; dup && prev != i {
+				cfgErr.add(hostField, "duplicate of devices[%d]", prev)
+			} else if host == "" || host == device.Host {
+				cfgErr.add(hostField, "must be another address of the device")
+			} else {
+				hosts[host] = i
+			}
+		}
+
+		validateDevice(cfgErr, field, device)
+		p.devices = append(p.devices, device)
+	}
+
+	if len(cfgErr.Errors) > 0 {
+		p.devices = nil
+		return cfgErr
+	}
+	return nil
+}
+
+// parseModelOverrides reads the "model_overrides" section of the plugin
+// config: entries added to the model database after the embedded ones
+func parseModelOverrides(config map[string]interface{}) ([]reolink.ModelInfo, error) {
+	raw, ok := config["model_overrides"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	cfgErr := &ConfigError{}
+	list, ok := raw.([]interface{})
+	if !ok {
+		cfgErr.add("model_overrides", "must be an array")
+		return nil, cfgErr
+	}
+
+	var entries []reolink.ModelInfo
+	for i, item := range list {
+		field := fmt.Sprintf("model_overrides[%d]", i)
+		var entry reolink.ModelInfo
+		data, err := json.Marshal(item)
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				cfgErr.add(field+"."+typeErr.Field, "must be %s", jsonTypeName(typeErr.Type))
+			} else {
+				cfgErr.add(field, "must be an object")
+			}
+			continue
+		}
+		if err := entry.Validate(); err != nil {
+			cfgErr.add(field, "%v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(cfgErr.Errors) > 0 {
+		return nil, cfgErr
+	}
+	return entries, nil
+}
+
+// parseLocale reads the locale of error details, e.g. "de" or "de-DE"
+func parseLocale(config map[string]interface{}) (string, error) {
+	raw, ok := config["locale"]
+	if !ok || raw == nil {
+		return defaultLocale, nil
+	}
+	cfgErr := &ConfigError{}
+	s, ok := raw.(string)
+	if !ok {
+		cfgErr.add("locale", "must be a string")
+		return "", cfgErr
+	}
+	if s == "" {
+		return defaultLocale, nil
+	}
+	locale, ok := normalizeLocale(s)
+	if !ok {
+		cfgErr.add("locale", "unsupported locale %q (must be one of %s)", s, strings.Join(supportedLocales(), ", "))
+		return "", cfgErr
+	}
+	return locale, nil
+}
+
+// parseNamespace reads "plugin_id" and "camera_id_prefix", which set the
+// IDs the plugin reports so several instances can share a host
+func parseNamespace(config map[string]interface{}) (namespace, error) {
+	ns := namespace{PluginID: defaultPluginID}
+	cfgErr := &ConfigError{}
+	if raw, ok := config["plugin_id"]; ok && raw != nil {
+		if s, ok := raw.(string); !ok {
+			cfgErr.add("plugin_id", "must be a string")
+		} else if s != "" {
+			if !validIDChars(s) {
+				cfgErr.add("plugin_id", "may only contain letters, digits, '-', '_' and '.'")
+			}
+			ns.PluginID = s
+		}
+	}
+	if raw, ok := config["camera_id_prefix"]; ok && raw != nil {
+		if s, ok := raw.(string); !ok {
+			cfgErr.add("camera_id_prefix", "must be a string")
+		} else if !validIDChars(s) {
+			cfgErr.add("camera_id_prefix", "may only contain letters, digits, '-', '_' and '.'")
+		} else {
+			ns.CameraPrefix = s
+		}
+	}
+	if len(cfgErr.Errors) > 0 {
+		return namespace{}, cfgErr
+	}
+	return ns, nil
+}
+
+// parseSnapshotSinks reads the "snapshot_sinks" section of the plugin config
+func parseSnapshotSinks(config map[string]interface{}) ([]SnapshotSinkConfig, error) {
+	raw, ok := config["snapshot_sinks"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	cfgErr := &ConfigError{}
+	list, ok := raw.([]interface{})
+	if !ok {
+		cfgErr.add("snapshot_sinks", "must be an array")
+		return nil, cfgErr
+	}
+
+	var sinks []SnapshotSinkConfig
+	for i, item := range list {
+		field := fmt.Sprintf("snapshot_sinks[%d]", i)
+		var sink SnapshotSinkConfig
+		data, err := json.Marshal(item)
+		if err == nil {
+			err = json.Unmarshal(data, &sink)
+		}
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				cfgErr.add(field+"."+typeErr.Field, "must be %s", jsonTypeName(typeErr.Type))
+			} else {
+				cfgErr.add(field, "must be an object")
+			}
+			continue
+		}
+		if err := sink.validate(); err != nil {
+			cfgErr.add(field, "%v", err)
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(cfgErr.Errors) > 0 {
+		return nil, cfgErr
+	}
+	return sinks, nil
+}
+
+// parseWebhooks reads the "webhooks" section of the plugin config
+func parseWebhooks(config map[string]interface{}) ([]WebhookConfig, error) {
+	raw, ok := config["webhooks"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	cfgErr := &ConfigError{}
+	list, ok := raw.([]interface{})
+	if !ok {
+		cfgErr.add("webhooks", "must be an array")
+		return nil, cfgErr
+	}
+
+	var hooks []WebhookConfig
+	for i, item := range list {
+		field := fmt.Sprintf("webhooks[%d]", i)
+		var hook WebhookConfig
+		data, err := json.Marshal(item)
+		if err == nil {
+			err = json.Unmarshal(data, &hook)
+		}
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				cfgErr.add(field+"."+typeErr.Field, "must be %s", jsonTypeName(typeErr.Type))
+			} else {
+				cfgErr.add(field, "must be an object")
+			}
+			continue
+		}
+		if err := hook.validate(); err != nil {
+			cfgErr.add(field, "%v", err)
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	if len(cfgErr.Errors) > 0 {
+		return nil, cfgErr
+	}
+	return hooks, nil
+}
+
+// parseDiscoverySubnets reads the "discovery_subnets" section of the plugin
+// config: IPv4 subnets searched for devices that changed address
+func parseDiscoverySubnets(config map[string]interface{}) ([]string, error) {
+	raw, ok := config["discovery_subnets"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	cfgErr := &ConfigError{}
+	list, ok := raw.([]interface{})
+	if !ok {
+		cfgErr.add("discovery_subnets", "must be an array")
+		return nil, cfgErr
+	}
+
+	var subnets []string
+	for i, item := range list {
+		field := fmt.Sprintf("discovery_subnets[%d]", i)
+		cidr, ok := item.(string)
+		switch {
+		case !ok:
+			cfgErr.add(field, "must be a string")
+		case cidr == "":
+			cfgErr.add(field, "must be an IPv4 subnet, e.g. 192.168.1.0/24")
+		case contains(subnets, cidr):
+			cfgErr.add(field, "duplicate subnet %s", cidr)
+		default:
+			if _, err := subnetHosts(cidr); err != nil {
+				cfgErr.add(field, "%v", err)
+				continue
+			}
+			subnets = append(subnets, cidr)
+		}
+	}
+	if len(cfgErr.Errors) > 0 {
+		return nil, cfgErr
+	}
+	return subnets, nil
+}
+
+// parseEventBurst reads the "event_burst" section of the plugin config; nil
+// disables bursts
+func parseEventBurst(config map[string]interface{}) (*EventBurstConfig, error) {
+	raw, ok := config["event_burst"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	cfgErr := &ConfigError{}
+	var burst EventBurstConfig
+	data, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &burst)
+	}
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			cfgErr.add("event_burst."+typeErr.Field, "must be %s", jsonTypeName(typeErr.Type))
+		} else {
+			cfgErr.add("event_burst", "must be an object")
+		}
+		return nil, cfgErr
+	}
+	if err := burst.validate(); err != nil {
+		cfgErr.add("event_burst", "%v", err)
+		return nil, cfgErr
+	}
+	return &burst, nil
+}
+
+// decodeDevice decodes a device object, recording type errors under field
+func decodeDevice(cfgErr *ConfigError, field string, data map[string]interface{}) (DeviceConfig, bool) {
+	var device DeviceConfig
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(raw, &device)
+	}
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			cfgErr.add(field+"."+typeErr.Field, "must be %s", jsonTypeName(typeErr.Type))
+		} else {
+			cfgErr.add(field, "must be an object")
+		}
+		return device, false
+	}
+	return device, true
+}
+
+// mergeObjects returns base overlaid with override. Nested objects are merged
+// key by key, so a device can override a single timeout of the defaults.
+func mergeObjects(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		baseObj, baseIsObj := merged[k].(map[string]interface{})
+		overrideObj, overrideIsObj := v.(map[string]interface{})
+		if baseIsObj && overrideIsObj {
+			merged[k] = mergeObjects(baseObj, overrideObj)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// validateDevice checks the fields of one decoded device
+func validateDevice(cfgErr *ConfigError, field string, device DeviceConfig) {
+	if device.Username == "" {
+		cfgErr.add(field+".username", "is required")
+	}
+	if device.Port < 0 || device.Port > 65535 {
+		cfgErr.add(field+".port", "must be between 1 and 65535, got %d", device.Port)
+	}
+	switch device.Protocol {
+	case "", "rtsp", "rtmp", "hls":
+	default:
+		cfgErr.add(field+".protocol", "must be rtsp, rtmp or hls, got %q", device.Protocol)
+	}
+	for j, ch := range device.Channels {
+		if ch < 0 {
+			cfgErr.add(fmt.Sprintf("%s.channels[%d]", field, j), "must not be negative")
+		}
+	}
+	if device.Proxy != "" {
+		if _, err := reolink.ParseProxyURL(device.Proxy); err != nil {
+			cfgErr.add(field+".proxy", "%v", err)
+		}
+	}
+	if o := device.Snapshot; o != nil {
+		if err := o.Validate(); err != nil {
+			cfgErr.add(field+".snapshot", "%v", err)
+		}
+	}
+	if t := device.StreamTemplates; t != nil {
+		if err := t.Validate(); err != nil {
+			cfgErr.add(field+".stream_templates", "%v", err)
+		}
+	}
+	if device.StreamUsername != "" && device.StreamUsername == device.Username {
+		cfgErr.add(field+".stream_username", "must differ from username")
+	}
+	if device.StreamPassword != "" && device.StreamUsername == "" {
+		cfgErr.add(field+".stream_password", "requires stream_username")
+	}
+	if unknown := validateCapabilityOverrides(device.CapabilityOverrides); len(unknown) > 0 {
+		cfgErr.add(field+".capability_overrides", "unknown capabilities: %s", strings.Join(unknown, ", "))
+	}
+	if device.MaxConcurrent < 0 {
+		cfgErr.add(field+".max_concurrent", "must not be negative")
+	}
+	if r := device.Retry; r != nil && (r.MaxAttempts < 0 || r.BaseDelayMs < 0 || r.MaxDelayMs < 0) {
+		cfgErr.add(field+".retry", "values must not be negative")
+	}
+	if t := device.Timeouts; t != nil && (t.RequestMs < 0 || t.LoginMs < 0 || t.SnapshotMs < 0) {
+		cfgErr.add(field+".timeouts", "values must not be negative")
+	}
+}
+
+// jsonTypeName describes the Go type a JSON value failed to decode into
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
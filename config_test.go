@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPlugin_ParseConfig_Typed(t *testing.T) {
+	plugin := NewPlugin()
+
+	config := map[string]interface{}{
+		"devices": []interface{}{
+			map[string]interface{}{
+				"host":           "192.168.1.100",
+				"username":       "admin",
+				"password":       "password",
+				"channels":       []interface{}{float64(0), float64(2)},
+				"protocol":       "rtmp",
+				"max_concurrent": float64(1),
+			},
+		},
+	}
+
+	if err := plugin.parseConfig(config); err != nil {
+		t.Fatalf("parseConfig failed: %v", err)
+	}
+
+	device := plugin.devices[0]
+	if len(device.Channels) != 2 || device.Channels[1] != 2 {
+		t.Errorf("Expected channels [0 2], got %v", device.Channels)
+	}
+	if device.Protocol != "rtmp" || device.MaxConcurrent != 1 {
+		t.Errorf("Unexpected device options: %+v", device)
+	}
+}
+
+func TestPlugin_ParseConfig_ValidationErrors(t *testing.T) {
+	plugin := NewPlugin()
+
+	config := map[string]interface{}{
+		"devices": []interface{}{
+			map[string]interface{}{"host": "192.168.1.100", "username": "admin", "port": float64(70000)},
+			map[string]interface{}{"host": "192.168.1.101", "password": "secret"},
+			map[string]interface{}{"host": "192.168.1.100", "username": "admin"},
+			map[string]interface{}{"host": "192.168.1.102", "username": "admin", "port": "eighty"},
+			map[string]interface{}{"host": "192.168.1.103", "username": "admin", "protocol": "webrtc"},
+			map[string]interface{}{"host": "192.168.1.104", "username": "admin", "proxy": "ftp://jump:21"},
+			map[string]interface{}{"host": "192.168.1.105", "username": "admin", "stream_username": "admin"},
+			map[string]interface{}{"host": "192.168.1.106", "username": "admin", "capability_overrides": map[string]interface{}{"zoom": true}},
+			map[string]interface{}{"host": "192.168.1.107", "username": "admin", "stream_templates": map[string]interface{}{"rtsp": "rtsp://{host}/{path}"}},
+		},
+	}
+
+	err := plugin.parseConfig(config)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Expected a ConfigError, got %v", err)
+	}
+
+	want := map[string]bool{
+		"devices[0].port":                 true,
+		"devices[1].username":             true,
+		"devices[2].host":                 true,
+		"devices[3].port":                 true,
+		"devices[4].protocol":             true,
+		"devices[5].proxy":                true,
+		"devices[6].stream_username":      true,
+		"devices[7].capability_overrides": true,
+		"devices[8].stream_templates":     true,
+	}
+	for _, fe := range cfgErr.Errors {
+		if !want[fe.Field] {
+			t.Errorf("Unexpected error for %s: %s", fe.Field, fe.Message)
+		}
+		delete(want, fe.Field)
+	}
+	for field := range want {
+		t.Errorf("Missing error for %s", field)
+	}
+	if len(plugin.devices) != 0 {
+		t.Errorf("Expected no devices from an invalid config, got %d", len(plugin.devices))
+	}
+}
+
+func TestPlugin_ParseConfig_NotAnArray(t *testing.T) {
+	plugin := NewPlugin()
+
+	err := plugin.parseConfig(map[string]interface{}{"devices": "192.168.1.100"})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Errors[0].Field != "devices" {
+		t.Errorf("Expected a devices error, got %v", err)
+	}
+}
+
+func TestPlugin_HandleRequest_Initialize_InvalidConfig(t *testing.T) {
+	plugin := NewPlugin()
+	defer func() { _ = plugin.Shutdown(context.Background()) }()
+
+	resp := plugin.HandleRequest(JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params:  []byte(`{"devices": [{"host": "192.168.1.100"}]}`),
+	})
+
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("Expected an invalid params error, got %+v", resp.Error)
+	}
+	fields, ok := resp.Error.Data.([]FieldError)
+	if !ok || len(fields) != 1 || fields[0].Field != "devices[0].username" {
+		t.Errorf("Expected the field errors as data, got %+v", resp.Error.Data)
+	}
+}
+
+func TestPlugin_ParseConfig_Defaults(t *testing.T) {
+	plugin := NewPlugin()
+
+	config := map[string]interface{}{
+		"defaults": map[string]interface{}{
+			"username": "admin",
+			"password": "shared",
+			"protocol": "rtmp",
+			"timeouts": map[string]interface{}{"request_ms": float64(30000), "login_ms": float64(30000)},
+			"tls":      map[string]interface{}{"https": true},
+		},
+		"devices": []interface{}{
+			map[string]interface{}{"host": "192.168.1.100"},
+			map[string]interface{}{
+				"host":     "192.168.1.101",
+				"password": "other",
+				"timeouts": map[string]interface{}{"request_ms": float64(5000)},
+			},
+		},
+	}
+
+	if err := plugin.parseConfig(config); err != nil {
+		t.Fatalf("parseConfig failed: %v", err)
+	}
+
+	d1, d2 := plugin.devices[0], plugin.devices[1]
+	if d1.Username != "admin" || d1.Password != "shared" || d1.Protocol != "rtmp" {
+		t.Errorf("Expected device to inherit defaults, got %+v", d1)
+	}
+	if d1.TLS == nil || !d1.TLS.HTTPS {
+		t.Errorf("Expected inherited TLS settings, got %+v", d1.TLS)
+	}
+	if d2.Password != "other" || d2.Username != "admin" {
+		t.Errorf("Expected password override with inherited username, got %+v", d2)
+	}
+	// Nested settings are merged per field
+	if d2.Timeouts.RequestMs != 5000 || d2.Timeouts.LoginMs != 30000 {
+		t.Errorf("Expected request_ms override with inherited login_ms, got %+v", d2.Timeouts)
+	}
+}
+
+func TestPlugin_ParseConfig_InvalidDefaults(t *testing.T) {
+	plugin := NewPlugin()
+
+	config := map[string]interface{}{
+		"defaults": map[string]interface{}{"host": "192.168.1.100", "port": "eighty"},
+		"devices":  []interface{}{map[string]interface{}{"host": "192.168.1.101", "username": "admin"}},
+	}
+
+	err := plugin.parseConfig(config)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 2 {
+		t.Fatalf("Expected errors for defaults.host and defaults.port, got %v", err)
+	}
+	if cfgErr.Errors[0].Field != "defaults.host" || cfgErr.Errors[1].Field != "defaults.port" {
+		t.Errorf("Unexpected errors: %+v", cfgErr.Errors)
+	}
+}
+
+func TestParseModelOverrides(t *testing.T) {
+	config := map[string]interface{}{
+		"model_overrides": []interface{}{
+			map[string]interface{}{"match": "RLC-1240A", "ai": false, "ptz_speed": map[string]interface{}{"min": 1, "max": 10}},
+			map[string]interface{}{"match": "", "lens": "dual"},
+			map[string]interface{}{"match": "E1", "lens": "triple"},
+			map[string]interface{}{"match": "E1", "nvr": "yes"},
+		},
+	}
+
+	_, err := parseModelOverrides(config)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 3 {
+		t.Fatalf("Expected 3 errors, got %v", err)
+	}
+	for i, field := range []string{"model_overrides[1]", "model_overrides[2]", "model_overrides[3].nvr"} {
+		if cfgErr.Errors[i].Field != field {
+			t.Errorf("Expected an error for %s, got %+v", field, cfgErr.Errors[i])
+		}
+	}
+
+	entries, err := parseModelOverrides(map[string]interface{}{"model_overrides": config["model_overrides"].([]interface{})[:1]})
+	if err != nil || len(entries) != 1 || entries[0].PTZSpeed == nil || entries[0].PTZSpeed.Max != 10 {
+		t.Errorf("Unexpected overrides: %+v, %v", entries, err)
+	}
+}
+
+func TestParseSnapshotSinks(t *testing.T) {
+	dir := t.TempDir()
+	config := map[string]interface{}{
+		"snapshot_sinks": []interface{}{
+			map[string]interface{}{"camera_id": "cam1", "interval_ms": 60000, "dir": dir},
+			map[string]interface{}{"camera_id": "cam1", "interval_ms": 60000},
+			map[string]interface{}{"camera_id": "cam1", "interval_ms": 60000, "mqtt": map[string]interface{}{"broker": "http://broker", "topic": "cams/1"}},
+			map[string]interface{}{"camera_id": "cam1", "interval_ms": "60s", "dir": dir},
+		},
+	}
+
+	_, err := parseSnapshotSinks(config)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 3 {
+		t.Fatalf("Expected 3 errors, got %v", err)
+	}
+	for i, field := range []string{"snapshot_sinks[1]", "snapshot_sinks[2]", "snapshot_sinks[3].interval_ms"} {
+		if cfgErr.Errors[i].Field != field {
+			t.Errorf("Expected an error for %s, got %+v", field, cfgErr.Errors[i])
+		}
+	}
+
+	sinks, err := parseSnapshotSinks(map[string]interface{}{"snapshot_sinks": config["snapshot_sinks"].([]interface{})[:1]})
+	if err != nil || len(sinks) != 1 || sinks[0].Filename != "cam1.jpg" {
+		t.Errorf("Unexpected sinks: %+v, %v", sinks, err)
+	}
+}
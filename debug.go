@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// pprofAddr validates a pprof listen address. Profiles expose internals, so
+// only loopback addresses are accepted; a bare ":port" binds to 127.0.0.1.
+func pprofAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid pprof address %q: %w", addr, err)
+	}
+	host, ok := loopbackHost(host)
+	if !ok {
+		return "", fmt.Errorf("pprof address %q must be on localhost", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// loopbackHost reports whether host is a loopback address, mapping an empty
+// host to 127.0.0.1
+func loopbackHost(host string) (string, bool) {
+	switch host {
+	case "":
+		return "127.0.0.1", true
+	case "localhost":
+		return host, true
+	}
+	ip := net.ParseIP(host)
+	return host, ip != nil && ip.IsLoopback()
+}
+
+// startPprof serves net/http/pprof on a loopback address until Shutdown
+func (p *Plugin) startPprof(addr string) error {
+	addr, err := pprofAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pprof != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for pprof: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	p.pprof = &http.Server{Addr: listener.Addr().String(), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("pprof server stopped: %v", err)
+		}
+	}(p.pprof)
+
+	log.Printf("Serving pprof on http://%s/debug/pprof/", p.pprof.Addr)
+	return nil
+}
+
+// stopPprof closes the pprof server, if running
+func (p *Plugin) stopPprof() {
+	p.mu.Lock()
+	srv := p.pprof
+	p.pprof = nil
+	p.mu.Unlock()
+
+	if srv != nil {
+		_ = srv.Close()
+	}
+}
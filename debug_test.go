@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPprofAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{"localhost:6060", "localhost:6060", false},
+		{"127.0.0.1:6060", "127.0.0.1:6060", false},
+		{"[::1]:6060", "[::1]:6060", false},
+		{":6060", "127.0.0.1:6060", false},
+		{"0.0.0.0:6060", "", true},
+		{"192.168.1.10:6060", "", true},
+		{"6060", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := pprofAddr(tt.addr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("pprofAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("pprofAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPlugin_StartPprof(t *testing.T) {
+	plugin := NewPlugin()
+	if err := plugin.startPprof("127.0.0.1:0"); err != nil {
+		t.Fatalf("startPprof failed: %v", err)
+	}
+	defer plugin.stopPprof()
+
+	resp, err := http.Get("http://" + plugin.pprof.Addr + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatalf("Failed to fetch goroutine profile: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %s", resp.Status)
+	}
+
+	plugin.stopPprof()
+	if plugin.pprof != nil {
+		t.Error("Expected pprof server to be stopped")
+	}
+}
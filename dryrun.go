@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// DryRunResult is returned in place of an operation's result when it runs as
+// a dry run: the request was checked, but nothing was sent to the device
+type DryRunResult struct {
+	DryRun   bool     `json:"dry_run"`
+	CameraID string   `json:"camera_id"`
+	Changes  []string `json:"changes"` // what the operation would do, in order
+}
+
+func newDryRunResult(cameraID string) *DryRunResult {
+	return &DryRunResult{DryRun: true, CameraID: cameraID, Changes: []string{}}
+}
+
+// dryRun reports whether an operation runs as a dry run, because the request
+// asks for it or the plugin config sets dry_run
+func (p *Plugin) dryRun(requested bool) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return requested || p.dryRunAll
+}
+
+// describeStream formats the set fields of a stream config, e.g.
+// "2560x1440, 25 fps, 4096 kbps"
+func describeStream(cfg reolink.StreamConfig) string {
+	var parts []string
+	if cfg.Width > 0 && cfg.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", cfg.Width, cfg.Height))
+	}
+	if cfg.FrameRate > 0 {
+		parts = append(parts, fmt.Sprintf("%d fps", cfg.FrameRate))
+	}
+	if cfg.BitRate > 0 {
+		parts = append(parts, fmt.Sprintf("%d kbps", cfg.BitRate))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPlugin_DryRun(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	if err := plugin.Initialize(context.Background(), map[string]interface{}{"dry_run": true, "allow_raw_commands": true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Drive", "RLC-811A", "localhost", 0, newTestClient(server))
+
+	payload := []byte("firmware image")
+	sum := sha256.Sum256(payload)
+	begin, err := plugin.transfers.Begin("IPC_523.pak", int64(len(payload)), hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	_ = plugin.transfers.Chunk(TransferChunk{TransferID: begin.TransferID, Data: base64.StdEncoding.EncodeToString(payload)})
+	if _, err := plugin.transfers.End(begin.TransferID, ""); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+
+	// The configured dry run applies without dry_run in the request
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "upgrade_firmware", Params: []byte(
+		`{"camera_id": "cam_1", "transfer_id": "` + begin.TransferID + `"}`)})
+	if resp.Error != nil {
+		t.Fatalf("upgrade_firmware failed: %s", resp.Error.Message)
+	}
+	plan := resp.Result.(*DryRunResult)
+	if !plan.DryRun || len(plan.Changes) != 2 || !strings.Contains(plan.Changes[1], "IPC_523.pak (14 bytes)") {
+		t.Errorf("Unexpected firmware plan: %+v", plan)
+	}
+	if _, _, err := plugin.transfers.Peek(begin.TransferID); err != nil {
+		t.Errorf("Expected the transfer to be kept, got %v", err)
+	}
+
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "raw_command", Params: []byte(
+		`{"camera_id": "cam_1", "commands": [{"cmd": "Reboot"}, {"cmd": "FormatHdd", "param": {"HddInfo": [{"id": 0}]}}]}`)})
+	if resp.Error != nil {
+		t.Fatalf("raw_command failed: %s", resp.Error.Message)
+	}
+	plan = resp.Result.(*DryRunResult)
+	want := []string{`Reboot (action 0): {}`, `FormatHdd (action 0): {"HddInfo":[{"id":0}]}`}
+	if len(plan.Changes) != 2 || plan.Changes[0] != want[0] || plan.Changes[1] != want[1] {
+		t.Errorf("Expected %q, got %q", want, plan.Changes)
+	}
+
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected nothing to be sent to the device, got %d requests", n)
+	}
+
+	errTests := []struct {
+		method, params, want string
+	}{
+		{"upgrade_firmware", `{"camera_id": "cam_1", "transfer_id": "missing"}`, "transfer not found"},
+		{"raw_command", `{"camera_id": "cam_1", "commands": [{"param": {}}]}`, "has no cmd"},
+		{"raw_command", `{"camera_id": "missing", "commands": [{"cmd": "Reboot"}]}`, "not found"},
+	}
+	for _, tt := range errTests {
+		resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 3, Method: tt.method, Params: []byte(tt.params)})
+		if resp.Error == nil || !strings.Contains(resp.Error.Message, tt.want) {
+			t.Errorf("%s %s: expected %q error, got %+v", tt.method, tt.params, tt.want, resp)
+		}
+	}
+}
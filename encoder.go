@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// binningModes are the binning modes offered on models that support binning
+var binningModes = []string{"off", "on", "auto"}
+
+// EncoderSettings are a camera's stream settings together with the values
+// they can be set to
+type EncoderSettings struct {
+	Current      *reolink.EncoderConfig  `json:"current"`
+	Options      *reolink.EncoderOptions `json:"options"`
+	Binning      string                  `json:"binning,omitempty"`       // only on models with binning
+	BinningModes []string                `json:"binning_modes,omitempty"` // only on models with binning
+}
+
+// EncoderSettingsRequest changes the stream settings and/or binning mode of a
+// camera. Zero fields are left unchanged.
+type EncoderSettingsRequest struct {
+	CameraID  string `json:"camera_id"`
+	Stream    string `json:"stream,omitempty"` // "main" (default) or "sub"
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	FrameRate int    `json:"frame_rate,omitempty"`
+	BitRate   int    `json:"bit_rate,omitempty"` // kbps
+	Binning   string `json:"binning,omitempty"`  // "off", "on" or "auto"
+	DryRun    bool   `json:"dry_run,omitempty"`  // only check and report the changes
+}
+
+// readEncoderSettings reads the current settings, the GetEnc range and the
+// binning mode of a camera
+func (c *Camera) readEncoderSettings(ctx context.Context) (*EncoderSettings, error) {
+	current, err := c.client.GetEncoderConfig(ctx, c.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoder config: %w", err)
+	}
+	c.SetEncoderConfig(current)
+
+	options, err := c.client.GetEncoderOptions(ctx, c.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoder options: %w", err)
+	}
+
+	isp, err := c.client.GetImageSettings(ctx, c.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image settings: %w", err)
+	}
+
+	settings := &EncoderSettings{Current: current, Options: options, Binning: isp.Binning}
+	if isp.Binning != "" {
+		settings.BinningModes = binningModes
+	}
+	return settings, nil
+}
+
+// GetEncoderSettings returns a camera's stream settings and what they can be
+// set to
+func (p *Plugin) GetEncoderSettings(ctx context.Context, cameraID string) (*EncoderSettings, error) {
+	var settings *EncoderSettings
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		settings, err = cam.readEncoderSettings(ctx)
+		return err
+	})
+	return settings, err
+}
+
+// SetEncoderSettings changes a camera's stream settings and binning mode after
+// checking the combination against the device's options: with binning on, the
+// main stream must be at most half the sensor resolution, so turning binning
+// on may need a lower resolution in the same request. Changed streams are
+// announced with camera.updated.
+func (p *Plugin) SetEncoderSettings(ctx context.Context, req EncoderSettingsRequest) (*EncoderSettings, error) {
+	return p.setEncoderSettings(ctx, req, nil)
+}
+
+// PlanEncoderSettings checks an encoder settings change like
+// SetEncoderSettings and reports the commands it would send, without
+// changing anything
+func (p *Plugin) PlanEncoderSettings(ctx context.Context, req EncoderSettingsRequest) (*DryRunResult, error) {
+	plan := newDryRunResult(req.CameraID)
+	if _, err := p.setEncoderSettings(ctx, req, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// setEncoderSettings applies an encoder settings change, or only records it
+// in plan if one is given
+func (p *Plugin) setEncoderSettings(ctx context.Context, req EncoderSettingsRequest, plan *DryRunResult) (*EncoderSettings, error) {
+	if req.Stream == "" {
+		req.Stream = "main"
+	}
+	cfg := reolink.StreamConfig{Width: req.Width, Height: req.Height, FrameRate: req.FrameRate, BitRate: req.BitRate}
+	if (cfg.Width == 0) != (cfg.Height == 0) {
+		return nil, fmt.Errorf("width and height must be set together")
+	}
+	if cfg == (reolink.StreamConfig{}) && req.Binning == "" {
+		return nil, fmt.Errorf("no encoder settings to apply")
+	}
+
+	var settings *EncoderSettings
+	var changed *Camera
+	err := p.onCamera(ctx, req.CameraID, func(ctx context.Context, cam *Camera) error {
+		before, err := cam.readEncoderSettings(ctx)
+		if err != nil {
+			return err
+		}
+
+		binning := before.Binning
+		if req.Binning != "" {
+			if before.Binning == "" {
+				return fmt.Errorf("camera %s does not support binning", req.CameraID)
+			}
+			if !contains(binningModes, req.Binning) {
+				return fmt.Errorf("invalid binning: %s (must be off, on, or auto)", req.Binning)
+			}
+			binning = req.Binning
+		}
+
+		if err := before.Options.Validate(req.Stream, cfg, binning); err != nil {
+			return err
+		}
+		// A binning change must also suit the main stream if it stays as is
+		if binning != before.Binning && (req.Stream != "main" || cfg.Width == 0) {
+			main := before.Current.MainStream
+			if err := before.Options.Validate("main", reolink.StreamConfig{Width: main.Width, Height: main.Height}, binning); err != nil {
+				return err
+			}
+		}
+
+		if plan != nil {
+			if binning != before.Binning {
+				plan.Changes = append(plan.Changes, fmt.Sprintf("SetIsp: binning %s -> %s", before.Binning, binning))
+			}
+			if cfg != (reolink.StreamConfig{}) {
+				current := before.Current.MainStream
+				if req.Stream == "sub" {
+					current = before.Current.SubStream
+				}
+				plan.Changes = append(plan.Changes, fmt.Sprintf("SetEnc: %s stream %s -> %s", req.Stream, describeStream(current), describeStream(cfg)))
+			}
+			settings = before
+			return nil
+		}
+
+		if binning != before.Binning {
+			if err := cam.client.SetImageSettings(ctx, cam.Channel(), reolink.ImageSettings{Binning: binning}); err != nil {
+				return err
+			}
+			log.Printf("Set binning of camera %s to %s", req.CameraID, binning)
+		}
+		if cfg != (reolink.StreamConfig{}) {
+			if err := cam.client.SetStreamConfig(ctx, cam.Channel(), req.Stream, cfg); err != nil {
+				return err
+			}
+			log.Printf("Updated %s stream of camera %s: %+v", req.Stream, req.CameraID, cfg)
+		}
+
+		settings, err = cam.readEncoderSettings(ctx)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(settings.Current, before.Current) {
+			changed = cam
+		}
+		return nil
+	})
+	if changed != nil {
+		p.notifyCameraUpdated(changed)
+	}
+	return settings, err
+}
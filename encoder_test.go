@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_EncoderSettings_Binning(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Model: "RLC-1212A", Password: "password", Binning: true}))
+	defer server.Close()
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Yard", "RLC-1212A", "localhost", 0, client)
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "get_encoder_settings", Params: []byte(`{"camera_id": "cam_1"}`)})
+	if resp.Error != nil {
+		t.Fatalf("get_encoder_settings failed: %s", resp.Error.Message)
+	}
+	settings := resp.Result.(*EncoderSettings)
+	if settings.Binning != "off" || len(settings.BinningModes) != 3 || len(settings.Options.MainStream) != 2 {
+		t.Errorf("Unexpected encoder settings: %+v", settings)
+	}
+
+	// Binning cannot be turned on at full resolution
+	_, err := plugin.SetEncoderSettings(context.Background(), EncoderSettingsRequest{CameraID: "cam_1", Binning: "on"})
+	if err == nil || !strings.Contains(err.Error(), "binning limits") {
+		t.Fatalf("Expected a binning resolution error, got %v", err)
+	}
+	if msgs := readLifecycle(t, &out); len(msgs) != 0 {
+		t.Errorf("Expected no change to be announced, got %+v", msgs)
+	}
+
+	// A dry run reports the change without making it
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "set_encoder_settings", Params: []byte(
+		`{"camera_id": "cam_1", "width": 2256, "height": 1256, "frame_rate": 30, "binning": "on", "dry_run": true}`)})
+	if resp.Error != nil {
+		t.Fatalf("Dry run failed: %s", resp.Error.Message)
+	}
+	want := []string{"SetIsp: binning off -> on", "SetEnc: main stream 4512x2512, 20 fps, 4096 kbps -> 2256x1256, 30 fps"}
+	if plan := resp.Result.(*DryRunResult); !reflect.DeepEqual(plan.Changes, want) {
+		t.Errorf("Expected %q, got %q", want, plan.Changes)
+	}
+	if settings, _ := plugin.GetEncoderSettings(context.Background(), "cam_1"); settings.Binning != "off" || settings.Current.MainStream.Width != 4512 {
+		t.Errorf("Expected the dry run to leave the camera unchanged, got %+v", settings)
+	}
+
+	settings, err = plugin.SetEncoderSettings(context.Background(), EncoderSettingsRequest{
+		CameraID: "cam_1", Width: 2256, Height: 1256, FrameRate: 30, Binning: "on",
+	})
+	if err != nil {
+		t.Fatalf("SetEncoderSettings failed: %v", err)
+	}
+	if settings.Binning != "on" || settings.Current.MainStream.Width != 2256 {
+		t.Errorf("Expected binning at 2256x1256, got %s at %+v", settings.Binning, settings.Current.MainStream)
+	}
+	msgs := readLifecycle(t, &out)
+	if len(msgs) != 1 || msgs[0].Method != NotifyCameraUpdated || msgs[0].Params.Camera.Encoder.MainStream.Width != 2256 {
+		t.Errorf("Expected camera.updated with the new resolution, got %+v", msgs)
+	}
+
+	errTests := []struct {
+		req  EncoderSettingsRequest
+		want string
+	}{
+		{EncoderSettingsRequest{CameraID: "cam_1", Width: 2256, Height: 1256, FrameRate: 25}, "frame rate 25"},
+		{EncoderSettingsRequest{CameraID: "cam_1", Width: 2256}, "set together"},
+		{EncoderSettingsRequest{CameraID: "cam_1", Binning: "night"}, "invalid binning"},
+		{EncoderSettingsRequest{CameraID: "cam_1"}, "no encoder settings"},
+	}
+	for _, tt := range errTests {
+		if _, err := plugin.SetEncoderSettings(context.Background(), tt.req); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected %q error, got %v", tt.req, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// defaultLocale is the language of error details unless configured otherwise
+const defaultLocale = "en"
+
+// ErrorDetail is the data of an internal error the plugin recognizes: what
+// went wrong and what the user can do about it, for hosts to show instead of
+// the technical message
+type ErrorDetail struct {
+	Kind    string `json:"kind"` // stable identifier, e.g. "max_sessions"
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"` // how to fix it
+	Locale  string `json:"locale"`
+}
+
+// errorText is the message and hint of a catalog entry in one language
+type errorText struct {
+	message, hint string
+}
+
+// errorEntry is a class of failures in the error catalog
+type errorEntry struct {
+	kind  string
+	match func(err error) bool
+	text  map[string]errorText // by locale; every entry has defaultLocale
+}
+
+// errorIs matches errors wrapping target
+func errorIs(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// errorCatalog lists the failures with user-facing messages, most specific
+// first, as an error can match several entries (e.g. a missing backchannel
+// is also an unsupported command)
+var errorCatalog = []errorEntry{
+	{"max_sessions", errorIs(reolink.ErrMaxSessions), map[string]errorText{
+		"en": {"The camera has reached its maximum number of sessions", "Log out other clients connected to the camera (apps, browsers, other NVR software) or wait for their sessions to expire"},
+		"de": {"Die Kamera hat die maximale Anzahl an Sitzungen erreicht", "Andere mit der Kamera verbundene Clients (Apps, Browser, andere NVR-Software) abmelden oder warten, bis ihre Sitzungen ablaufen"},
+		"fr": {"La caméra a atteint son nombre maximal de sessions", "Déconnectez les autres clients connectés à la caméra (applications, navigateurs, autres logiciels NVR) ou attendez l'expiration de leurs sessions"},
+		"es": {"La cámara ha alcanzado su número máximo de sesiones", "Cierre la sesión de otros clientes conectados a la cámara (aplicaciones, navegadores, otro software NVR) o espere a que caduquen sus sesiones"},
+	}},
+	{"account_locked", errorIs(reolink.ErrAccountLocked), map[string]errorText{
+		"en": {"The camera account is locked after too many failed logins", "Wait for the lockout to expire or reboot the camera, then check the configured password"},
+		"de": {"Das Kamerakonto ist nach zu vielen fehlgeschlagenen Anmeldungen gesperrt", "Das Ende der Sperre abwarten oder die Kamera neu starten und dann das konfigurierte Passwort prüfen"},
+		"fr": {"Le compte de la caméra est verrouillé après trop d'échecs de connexion", "Attendez la fin du verrouillage ou redémarrez la caméra, puis vérifiez le mot de passe configuré"},
+		"es": {"La cuenta de la cámara está bloqueada tras demasiados inicios de sesión fallidos", "Espere a que termine el bloqueo o reinicie la cámara y compruebe la contraseña configurada"},
+	}},
+	{"auth_failed", errorIs(reolink.ErrAuthFailed), map[string]errorText{
+		"en": {"The camera rejected the username or password", "Check the credentials configured for the camera"},
+		"de": {"Die Kamera hat Benutzername oder Passwort abgelehnt", "Die für die Kamera konfigurierten Zugangsdaten prüfen"},
+		"fr": {"La caméra a refusé le nom d'utilisateur ou le mot de passe", "Vérifiez les identifiants configurés pour la caméra"},
+		"es": {"La cámara rechazó el usuario o la contraseña", "Compruebe las credenciales configuradas para la cámara"},
+	}},
+	{"session_expired", errorIs(reolink.ErrTokenInvalid), map[string]errorText{
+		"en": {"The camera session expired", "Retry the request; the plugin logs in again automatically"},
+		"de": {"Die Sitzung bei der Kamera ist abgelaufen", "Die Anfrage wiederholen; das Plugin meldet sich automatisch neu an"},
+		"fr": {"La session avec la caméra a expiré", "Réessayez la requête ; le plugin se reconnecte automatiquement"},
+		"es": {"La sesión con la cámara ha caducado", "Repita la solicitud; el plugin vuelve a iniciar sesión automáticamente"},
+	}},
+	{"permission_denied", errorIs(reolink.ErrPermissionDenied), map[string]errorText{
+		"en": {"The camera account is not allowed to do this", "Use an administrator account for the camera"},
+		"de": {"Das Kamerakonto hat dafür keine Berechtigung", "Ein Administratorkonto für die Kamera verwenden"},
+		"fr": {"Le compte de la caméra n'a pas l'autorisation de faire cela", "Utilisez un compte administrateur pour la caméra"},
+		"es": {"La cuenta de la cámara no tiene permiso para hacer esto", "Use una cuenta de administrador para la cámara"},
+	}},
+	{"camera_unreachable", errorIs(reolink.ErrCircuitOpen), map[string]errorText{
+		"en": {"The camera is unreachable after repeated failures", "Check that the camera is powered and on the network; the plugin keeps retrying in the background"},
+		"de": {"Die Kamera ist nach wiederholten Fehlern nicht erreichbar", "Prüfen, ob die Kamera eingeschaltet und im Netzwerk ist; das Plugin versucht es im Hintergrund weiter"},
+		"fr": {"La caméra est injoignable après des échecs répétés", "Vérifiez que la caméra est alimentée et connectée au réseau ; le plugin continue de réessayer en arrière-plan"},
+		"es": {"La cámara no está accesible tras fallos repetidos", "Compruebe que la cámara está encendida y en la red; el plugin sigue reintentando en segundo plano"},
+	}},
+	{"snapshot_too_large", errorIs(reolink.ErrSnapshotTooLarge), map[string]errorText{
+		"en": {"The snapshot is larger than the configured limit", "Raise max_snapshot_bytes or lower the snapshot size or quality with set_snapshot_options"},
+		"de": {"Der Schnappschuss ist größer als das konfigurierte Limit", "max_snapshot_bytes erhöhen oder Größe bzw. Qualität des Schnappschusses mit set_snapshot_options verringern"},
+		"fr": {"L'instantané dépasse la limite configurée", "Augmentez max_snapshot_bytes ou réduisez la taille ou la qualité de l'instantané avec set_snapshot_options"},
+		"es": {"La instantánea supera el límite configurado", "Aumente max_snapshot_bytes o reduzca el tamaño o la calidad de la instantánea con set_snapshot_options"},
+	}},
+	{"no_backchannel", errorIs(reolink.ErrNoBackchannel), map[string]errorText{
+		"en": {"The camera offers no audio backchannel for two-way audio", "Two-way audio needs a camera with a speaker and firmware that supports the RTSP backchannel"},
+		"de": {"Die Kamera bietet keinen Audio-Rückkanal für Gegensprechen", "Gegensprechen erfordert eine Kamera mit Lautsprecher und einer Firmware, die den RTSP-Rückkanal unterstützt"},
+		"fr": {"La caméra n'offre pas de canal audio de retour pour l'audio bidirectionnel", "L'audio bidirectionnel nécessite une caméra avec haut-parleur et un firmware prenant en charge le canal de retour RTSP"},
+		"es": {"La cámara no ofrece canal de retorno de audio para audio bidireccional", "El audio bidireccional requiere una cámara con altavoz y un firmware compatible con el canal de retorno RTSP"},
+	}},
+	{"not_supported", errorIs(reolink.ErrNotSupported), map[string]errorText{
+		"en": {"The camera does not support this feature", "Check for a firmware update; some features need newer models or firmware"},
+		"de": {"Die Kamera unterstützt diese Funktion nicht", "Nach einem Firmware-Update suchen; manche Funktionen erfordern neuere Modelle oder Firmware"},
+		"fr": {"La caméra ne prend pas en charge cette fonction", "Recherchez une mise à jour du firmware ; certaines fonctions nécessitent des modèles ou un firmware plus récents"},
+		"es": {"La cámara no admite esta función", "Busque una actualización de firmware; algunas funciones requieren modelos o firmware más recientes"},
+	}},
+	{"device_busy", errorIs(reolink.ErrDeviceBusy), map[string]errorText{
+		"en": {"The camera is busy", "Try again in a few seconds"},
+		"de": {"Die Kamera ist beschäftigt", "In einigen Sekunden erneut versuchen"},
+		"fr": {"La caméra est occupée", "Réessayez dans quelques secondes"},
+		"es": {"La cámara está ocupada", "Inténtelo de nuevo en unos segundos"},
+	}},
+	{"invalid_parameter", errorIs(reolink.ErrInvalidParameter), map[string]errorText{
+		"en": {"The camera rejected a value of the request", "Check the values against what the camera supports, e.g. with get_ability"},
+		"de": {"Die Kamera hat einen Wert der Anfrage abgelehnt", "Die Werte mit den Fähigkeiten der Kamera abgleichen, z. B. mit get_ability"},
+		"fr": {"La caméra a refusé une valeur de la requête", "Comparez les valeurs avec ce que la caméra prend en charge, par exemple avec get_ability"},
+		"es": {"La cámara rechazó un valor de la solicitud", "Compare los valores con lo que admite la cámara, por ejemplo con get_ability"},
+	}},
+	{"device_failure", errorIs(reolink.ErrDeviceFailure), map[string]errorText{
+		"en": {"The camera reported an internal failure", "Try again, and reboot the camera if the problem persists"},
+		"de": {"Die Kamera hat einen internen Fehler gemeldet", "Erneut versuchen und die Kamera neu starten, falls das Problem bleibt"},
+		"fr": {"La caméra a signalé une défaillance interne", "Réessayez, et redémarrez la caméra si le problème persiste"},
+		"es": {"La cámara informó de un fallo interno", "Inténtelo de nuevo y reinicie la cámara si el problema persiste"},
+	}},
+	{"read_only", errorIs(errReadOnly), map[string]errorText{
+		"en": {"The plugin is in read-only mode", "Turn off read_only in the plugin config to change camera settings"},
+		"de": {"Das Plugin ist im Nur-Lese-Modus", "read_only in der Plugin-Konfiguration deaktivieren, um Kameraeinstellungen zu ändern"},
+		"fr": {"Le plugin est en mode lecture seule", "Désactivez read_only dans la configuration du plugin pour modifier les réglages des caméras"},
+		"es": {"El plugin está en modo de solo lectura", "Desactive read_only en la configuración del plugin para cambiar los ajustes de las cámaras"},
+	}},
+	{"raw_commands_disabled", errorIs(errRawCommandsDisabled), map[string]errorText{
+		"en": {"Raw camera commands are disabled", "Set allow_raw_commands in the plugin config"},
+		"de": {"Direkte Kamerabefehle sind deaktiviert", "allow_raw_commands in der Plugin-Konfiguration setzen"},
+		"fr": {"Les commandes brutes de la caméra sont désactivées", "Activez allow_raw_commands dans la configuration du plugin"},
+		"es": {"Los comandos directos de la cámara están desactivados", "Active allow_raw_commands en la configuración del plugin"},
+	}},
+	{"camera_not_found", errorIs(errCameraNotFound), map[string]errorText{
+		"en": {"The camera is not managed by the plugin", "Check the camera ID with list_cameras, or add the camera first"},
+		"de": {"Die Kamera wird nicht vom Plugin verwaltet", "Die Kamera-ID mit list_cameras prüfen oder die Kamera zuerst hinzufügen"},
+		"fr": {"La caméra n'est pas gérée par le plugin", "Vérifiez l'identifiant de la caméra avec list_cameras, ou ajoutez d'abord la caméra"},
+		"es": {"La cámara no está gestionada por el plugin", "Compruebe el ID de la cámara con list_cameras o añada primero la cámara"},
+	}},
+	{"connection_refused", errorIs(syscall.ECONNREFUSED), map[string]errorText{
+		"en": {"The camera refused the connection", "Check the port, and that the HTTP or HTTPS service is enabled on the camera"},
+		"de": {"Die Kamera hat die Verbindung abgelehnt", "Den Port prüfen und ob der HTTP- oder HTTPS-Dienst auf der Kamera aktiviert ist"},
+		"fr": {"La caméra a refusé la connexion", "Vérifiez le port, et que le service HTTP ou HTTPS est activé sur la caméra"},
+		"es": {"La cámara rechazó la conexión", "Compruebe el puerto y que el servicio HTTP o HTTPS esté activado en la cámara"},
+	}},
+	{"timeout", isTimeout, map[string]errorText{
+		"en": {"The camera did not answer in time", "Check the network connection to the camera, or raise the device timeouts for slow links"},
+		"de": {"Die Kamera hat nicht rechtzeitig geantwortet", "Die Netzwerkverbindung zur Kamera prüfen oder die Geräte-Timeouts für langsame Verbindungen erhöhen"},
+		"fr": {"La caméra n'a pas répondu à temps", "Vérifiez la connexion réseau vers la caméra, ou augmentez les délais d'attente de l'appareil pour les liaisons lentes"},
+		"es": {"La cámara no respondió a tiempo", "Compruebe la conexión de red con la cámara o aumente los tiempos de espera del dispositivo para enlaces lentos"},
+	}},
+	{"network_unreachable", isNetworkError, map[string]errorText{
+		"en": {"The camera could not be reached", "Check the camera's address and the network connection to it"},
+		"de": {"Die Kamera konnte nicht erreicht werden", "Die Adresse der Kamera und die Netzwerkverbindung zu ihr prüfen"},
+		"fr": {"La caméra n'a pas pu être jointe", "Vérifiez l'adresse de la caméra et la connexion réseau vers elle"},
+		"es": {"No se pudo contactar con la cámara", "Compruebe la dirección de la cámara y la conexión de red con ella"},
+	}},
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func isNetworkError(err error) bool {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr)
+}
+
+// supportedLocales returns the locales every catalog entry is translated to
+func supportedLocales() []string {
+	var locales []string
+	for locale := range errorCatalog[0].text {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// normalizeLocale reduces a locale such as "de-DE" or "de_DE.UTF-8" to its
+// language, and reports whether the catalog has it
+func normalizeLocale(locale string) (string, bool) {
+	lang := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(lang, "-_."); i >= 0 {
+		lang = lang[:i]
+	}
+	_, ok := errorCatalog[0].text[lang]
+	return lang, ok
+}
+
+// explainError returns the catalog's description of err in locale, or nil
+// if err is not a known failure
+func explainError(err error, locale string) *ErrorDetail {
+	for _, entry := range errorCatalog {
+		if !entry.match(err) {
+			continue
+		}
+		text, ok := entry.text[locale]
+		if !ok {
+			locale = defaultLocale
+			text = entry.text[locale]
+		}
+		return &ErrorDetail{Kind: entry.kind, Message: text.message, Hint: text.hint, Locale: locale}
+	}
+	return nil
+}
+
+// internalError is the JSON-RPC error for a failed method, carrying the
+// catalog's user-facing description in its data when there is one
+func (p *Plugin) internalError(err error) *JSONRPCError {
+	return &JSONRPCError{Code: -32603, Message: err.Error(), Data: p.errorData(err)}
+}
+
+// errorData returns the catalog's description of err in the configured
+// locale as JSON-RPC error data, or nil
+func (p *Plugin) errorData(err error) interface{} {
+	p.mu.RLock()
+	locale := p.locale
+	p.mu.RUnlock()
+
+	if detail := explainError(err, locale); detail != nil {
+		return detail
+	}
+	return nil
+}
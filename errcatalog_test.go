@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestErrorCatalog_Complete(t *testing.T) {
+	locales := supportedLocales()
+	if len(locales) < 2 || locales[0] != "de" {
+		t.Fatalf("Unexpected locales: %v", locales)
+	}
+	kinds := make(map[string]bool)
+	for _, entry := range errorCatalog {
+		if kinds[entry.kind] {
+			t.Errorf("Duplicate kind %s", entry.kind)
+		}
+		kinds[entry.kind] = true
+		if len(entry.text) != len(locales) {
+			t.Errorf("%s has %d translations, want %d", entry.kind, len(entry.text), len(locales))
+		}
+		for _, locale := range locales {
+			if text := entry.text[locale]; text.message == "" || text.hint == "" {
+				t.Errorf("%s lacks a %s message or hint", entry.kind, locale)
+			}
+		}
+	}
+}
+
+func TestExplainError(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connect: %w", syscall.ECONNREFUSED)}
+	tests := []struct {
+		err  error
+		kind string
+	}{
+		{fmt.Errorf("login failed: %w", &reolink.APIError{Cmd: "Login", RspCode: -5}), "max_sessions"},
+		{&reolink.APIError{Cmd: "Login", RspCode: -7}, "auth_failed"},
+		{&reolink.APIError{Cmd: "GetAutoFocus", RspCode: -9}, "not_supported"},
+		{fmt.Errorf("talk: %w", reolink.ErrNoBackchannel), "no_backchannel"},
+		{fmt.Errorf("%w: cam1", errCameraNotFound), "camera_not_found"},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), "timeout"},
+		{fmt.Errorf("request failed: %w", refused), "connection_refused"},
+		{&net.DNSError{Err: "no such host", Name: "cam.local"}, "network_unreachable"},
+		{errReadOnly, "read_only"},
+	}
+	for _, tt := range tests {
+		detail := explainError(tt.err, "en")
+		if detail == nil || detail.Kind != tt.kind || detail.Hint == "" {
+			t.Errorf("explainError(%v) = %+v, want kind %s", tt.err, detail, tt.kind)
+		}
+	}
+
+	if detail := explainError(errors.New("something else"), "en"); detail != nil {
+		t.Errorf("Expected no detail for an unknown error, got %+v", detail)
+	}
+
+	de := explainError(reolink.ErrDeviceBusy, "de")
+	if de.Locale != "de" || de.Message != "Die Kamera ist beschäftigt" {
+		t.Errorf("Unexpected German detail: %+v", de)
+	}
+	if fallback := explainError(reolink.ErrDeviceBusy, "xx"); fallback.Locale != defaultLocale || fallback.Message != "The camera is busy" {
+		t.Errorf("Expected the English fallback, got %+v", fallback)
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	for value, want := range map[interface{}]string{nil: "en", "": "en", "de": "de", "fr-FR": "fr", "es_ES.UTF-8": "es", " DE ": "de"} {
+		config := map[string]interface{}{}
+		if value != nil {
+			config["locale"] = value
+		}
+		if got, err := parseLocale(config); err != nil || got != want {
+			t.Errorf("parseLocale(%v) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []interface{}{"klingon", 42} {
+		var cfgErr *ConfigError
+		if _, err := parseLocale(map[string]interface{}{"locale": value}); !errors.As(err, &cfgErr) || cfgErr.Errors[0].Field != "locale" {
+			t.Errorf("Expected a config error for %v, got %v", value, err)
+		}
+	}
+}
+
+func TestHandleRequest_ErrorDetail(t *testing.T) {
+	// A device that has run out of sessions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "Login", Code: 1, Error: &apiErrorDetail{RspCode: -5, Detail: "max session"}}})
+	}))
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	if err := plugin.Initialize(context.Background(), map[string]interface{}{"locale": "fr-CA"}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	params, _ := json.Marshal(map[string]interface{}{"host": host, "port": port, "username": "admin", "password": "secret"})
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "probe_camera", Params: params})
+	if resp.Error == nil {
+		t.Fatal("Expected probe_camera to fail")
+	}
+	detail, ok := resp.Error.Data.(*ErrorDetail)
+	if !ok || detail.Kind != "max_sessions" || detail.Locale != "fr" || detail.Message != "La caméra a atteint son nombre maximal de sessions" {
+		t.Errorf("Unexpected error data: %+v (%s)", resp.Error.Data, resp.Error.Message)
+	}
+
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "get_snapshot", Params: json.RawMessage(`{"camera_id": "missing"}`)})
+	if detail, ok := resp.Error.Data.(*ErrorDetail); !ok || detail.Kind != "camera_not_found" {
+		t.Errorf("Unexpected error data: %+v", resp.Error.Data)
+	}
+
+	// Errors outside the catalog carry no data
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 3, Method: "classify_model", Params: json.RawMessage(`{}`)})
+	if resp.Error == nil || resp.Error.Data != nil {
+		t.Errorf("Expected an error without data, got %+v", resp.Error)
+	}
+}
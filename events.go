@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventMotion   = "motion"
+	EventPerson   = "person"
+	EventVehicle  = "vehicle"
+	EventAnimal   = "animal"
+	EventFace     = "face"
+	EventPackage  = "package"
+	EventDoorbell = "doorbell" // visitor pressed the doorbell button
+
+	EventAlarmInput = "alarm_input" // wired sensor on an alarm input
+)
+
+// Event states. Detection events have a start and an end; doorbell presses
+// are instantaneous and only have a start.
+const (
+	EventStart = "start"
+	EventEnd   = "end"
+)
+
+// maxQueuedEvents is how many recent events are kept for get_events
+const maxQueuedEvents = 500
+
+// maxEventWait is the longest a get_events request may wait for an event
+const maxEventWait = 60 * time.Second
+
+// Event is a camera event delivered to the host as an "event" notification
+// and kept in a queue for get_events
+type Event struct {
+	ID        string                 `json:"id"`
+	Seq       uint64                 `json:"seq"`
+	Type      string                 `json:"type"`
+	State     string                 `json:"state"`
+	CameraID  string                 `json:"camera_id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// eventQueue is a bounded queue of recent events ordered by sequence number
+type eventQueue struct {
+	mu      sync.Mutex
+	events  []Event
+	nextSeq uint64
+
+	// added is closed when the next event is pushed, waking waiters
+	added chan struct{}
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{nextSeq: 1}
+}
+
+// push assigns the event its sequence number and ID and stores it
+func (q *eventQueue) push(ev Event) Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ev.Seq = q.nextSeq
+	q.nextSeq++
+	if ev.ID == "" {
+		ev.ID = fmt.Sprintf("%s-%d", ev.CameraID, ev.Seq)
+	}
+
+	q.events = append(q.events, ev)
+	if len(q.events) > maxQueuedEvents {
+		q.events = q.events[len(q.events)-maxQueuedEvents:]
+	}
+	if q.added != nil {
+		close(q.added)
+		q.added = nil
+	}
+	return ev
+}
+
+// since returns up to limit events with a sequence number above seq,
+// optionally filtered by camera
+func (q *eventQueue) since(seq uint64, cameraID string, limit int) []Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.sinceLocked(seq, cameraID, limit)
+}
+
+// wait returns the events since seq, waiting until there are some, ctx is
+// canceled or timeout elapses
+func (q *eventQueue) wait(ctx context.Context, seq uint64, cameraID string, limit int, timeout time.Duration) []Event {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		q.mu.Lock()
+		result := q.sinceLocked(seq, cameraID, limit)
+		if len(result) > 0 {
+			q.mu.Unlock()
+			return result
+		}
+		if q.added == nil {
+			q.added = make(chan struct{})
+		}
+		added := q.added
+		q.mu.Unlock()
+
+		select {
+		case <-added:
+		case <-timer.C:
+			return result
+		case <-ctx.Done():
+			return result
+		}
+	}
+}
+
+func (q *eventQueue) sinceLocked(seq uint64, cameraID string, limit int) []Event {
+	result := []Event{}
+	for _, ev := range q.events {
+		if ev.Seq <= seq || (cameraID != "" && ev.CameraID != cameraID) {
+			continue
+		}
+		result = append(result, ev)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// lastSeq returns the sequence number of the most recent event
+func (q *eventQueue) lastSeq() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.nextSeq - 1
+}
+
+// emitEvent queues an event and sends it to the host as a notification
+func (p *Plugin) emitEvent(ev Event) Event {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+	// Snapshots around the event are fetched with get_event_media
+	burst := false
+	if cfg := p.bursts.configured(); cfg != nil && cfg.triggers(ev) {
+		burst = true
+		if ev.Data == nil {
+			ev.Data = make(map[string]interface{})
+		}
+		ev.Data["media"] = true
+	}
+	ev = p.events.push(ev)
+	p.analytics.record(ev)
+	if burst {
+		p.startEventBurst(ev)
+	}
+
+	// Hosts that do not read notifications can still use get_events
+	_ = p.notify("event", ev)
+	p.forwardEvent(ev)
+	return ev
+}
+
+// EventsResult is the result of get_events
+type EventsResult struct {
+	Events []Event `json:"events"`
+	Last   uint64  `json:"last"` // pass as "since" to receive only newer events
+}
+
+// GetEvents returns queued events newer than since
+func (p *Plugin) GetEvents(since uint64, cameraID string, limit int) EventsResult {
+	events := p.events.since(since, cameraID, limit)
+	last := p.events.lastSeq()
+	if limit > 0 && len(events) == limit {
+		last = events[len(events)-1].Seq
+	}
+	return EventsResult{Events: events, Last: last}
+}
+
+// WaitEvents is GetEvents that waits up to wait (at most maxEventWait) for
+// an event when there are none newer than since, for hosts that cannot
+// receive notifications
+func (p *Plugin) WaitEvents(ctx context.Context, since uint64, cameraID string, limit int, wait time.Duration) EventsResult {
+	if wait > 0 {
+		p.events.wait(ctx, since, cameraID, limit, min(wait, maxEventWait))
+	}
+	return p.GetEvents(since, cameraID, limit)
+}
+
+// TestEventRequest is an emit_test_event request
+type TestEventRequest struct {
+	CameraID string `json:"camera_id"`
+	Type     string `json:"type"`            // a detection event type or "doorbell"
+	State    string `json:"state,omitempty"` // "start" or "end"; both if empty
+}
+
+// EmitTestEvent synthesizes an event for a camera, marked with "test" in its
+// data, so the host's alert handling can be checked end to end. Without a
+// state a detection event is emitted as a start followed by an end. A test
+// doorbell press carries a live snapshot like a real one. Test events are
+// not counted in the event summary.
+func (p *Plugin) EmitTestEvent(ctx context.Context, req TestEventRequest) ([]Event, error) {
+	cam, err := p.lookupCamera(req.CameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	states := []string{EventStart, EventEnd}
+	switch req.State {
+	case "":
+	case EventStart, EventEnd:
+		states = []string{req.State}
+	default:
+		return nil, fmt.Errorf("invalid event state: %s (must be start or end)", req.State)
+	}
+
+	if req.Type == EventDoorbell {
+		if req.State == EventEnd {
+			return nil, fmt.Errorf("doorbell events have no end")
+		}
+		var ev Event
+		err := p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+			ev = p.visitorEvent(ctx, cam, time.Now())
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		ev.Data["test"] = true
+		return []Event{p.emitEvent(ev)}, nil
+	}
+
+	if !contains(detectionEvents, req.Type) {
+		return nil, fmt.Errorf("invalid event type: %s (must be motion, person, vehicle, animal, face, package, or doorbell)", req.Type)
+	}
+	events := make([]Event, 0, len(states))
+	for _, state := range states {
+		events = append(events, p.emitEvent(Event{
+			Type:     req.Type,
+			State:    state,
+			CameraID: cam.ID(),
+			Data:     map[string]interface{}{"test": true},
+		}))
+	}
+	return events, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventQueue_Since(t *testing.T) {
+	q := newEventQueue()
+	q.push(Event{Type: EventMotion, State: EventStart, CameraID: "cam1"})
+	q.push(Event{Type: EventMotion, State: EventStart, CameraID: "cam2"})
+	ev := q.push(Event{Type: EventMotion, State: EventEnd, CameraID: "cam1"})
+
+	if ev.Seq != 3 || ev.ID != "cam1-3" {
+		t.Errorf("Expected seq 3 and ID cam1-3, got %d %q", ev.Seq, ev.ID)
+	}
+
+	if got := q.since(0, "", 0); len(got) != 3 {
+		t.Errorf("Expected 3 events, got %d", len(got))
+	}
+	if got := q.since(1, "", 0); len(got) != 2 || got[0].Seq != 2 {
+		t.Errorf("Expected events after seq 1, got %+v", got)
+	}
+	if got := q.since(0, "cam1", 0); len(got) != 2 || got[1].Seq != 3 {
+		t.Errorf("Expected cam1 events, got %+v", got)
+	}
+	if got := q.since(0, "", 1); len(got) != 1 || got[0].Seq != 1 {
+		t.Errorf("Expected the oldest event only, got %+v", got)
+	}
+}
+
+func TestEventQueue_Bounded(t *testing.T) {
+	q := newEventQueue()
+	for i := 0; i < maxQueuedEvents+10; i++ {
+		q.push(Event{Type: EventMotion, CameraID: "cam1"})
+	}
+
+	events := q.since(0, "", 0)
+	if len(events) != maxQueuedEvents {
+		t.Fatalf("Expected %d events, got %d", maxQueuedEvents, len(events))
+	}
+	if events[0].Seq != 11 {
+		t.Errorf("Expected the oldest events to be dropped, first seq is %d", events[0].Seq)
+	}
+	if q.lastSeq() != maxQueuedEvents+10 {
+		t.Errorf("Unexpected last seq %d", q.lastSeq())
+	}
+}
+
+func TestPlugin_EmitEvent(t *testing.T) {
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+
+	plugin.emitEvent(Event{Type: EventPerson, State: EventStart, CameraID: "cam1"})
+
+	var msg struct {
+		Method string `json:"method"`
+		Params Event  `json:"params"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &msg); err != nil {
+		t.Fatalf("Invalid notification %q: %v", out.String(), err)
+	}
+	if msg.Method != "event" || msg.Params.Type != EventPerson || msg.Params.Seq != 1 {
+		t.Errorf("Unexpected notification: %+v", msg)
+	}
+	if msg.Params.Timestamp.IsZero() {
+		t.Error("Expected the timestamp to be set")
+	}
+}
+
+func TestPlugin_GetEvents(t *testing.T) {
+	plugin := NewPlugin()
+	for i := 0; i < 3; i++ {
+		plugin.emitEvent(Event{Type: EventMotion, CameraID: "cam1"})
+	}
+
+	result := plugin.GetEvents(0, "", 2)
+	if len(result.Events) != 2 || result.Last != 2 {
+		t.Errorf("Expected 2 events up to seq 2, got %d up to %d", len(result.Events), result.Last)
+	}
+
+	result = plugin.GetEvents(result.Last, "", 0)
+	if len(result.Events) != 1 || result.Last != 3 {
+		t.Errorf("Expected the remaining event, got %d up to %d", len(result.Events), result.Last)
+	}
+
+	result = plugin.GetEvents(result.Last, "", 0)
+	if len(result.Events) != 0 || result.Last != 3 {
+		t.Errorf("Expected no new events, got %d up to %d", len(result.Events), result.Last)
+	}
+}
+
+func TestPlugin_WaitEvents(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.emitEvent(Event{Type: EventMotion, CameraID: "cam1"})
+
+	// Queued events are returned without waiting
+	start := time.Now()
+	if result := plugin.WaitEvents(context.Background(), 0, "", 0, time.Minute); len(result.Events) != 1 {
+		t.Errorf("Expected the queued event, got %+v", result)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected no wait with queued events")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		plugin.emitEvent(Event{Type: EventMotion, CameraID: "cam2"})
+		plugin.emitEvent(Event{Type: EventPerson, CameraID: "cam1"})
+	}()
+	result := plugin.WaitEvents(context.Background(), 1, "cam1", 0, 5*time.Second)
+	if len(result.Events) != 1 || result.Events[0].Type != EventPerson || result.Last != 3 {
+		t.Errorf("Expected the person event of cam1, got %+v", result)
+	}
+
+	start = time.Now()
+	result = plugin.WaitEvents(context.Background(), result.Last, "", 0, 50*time.Millisecond)
+	if len(result.Events) != 0 || result.Last != 3 || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("Expected an empty result after the wait, got %+v", result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := plugin.WaitEvents(ctx, 3, "", 0, time.Minute); len(result.Events) != 0 {
+		t.Errorf("Expected no events after cancel, got %+v", result)
+	}
+}
+
+func TestPlugin_EmitTestEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte{0xFF, 0xD8, 0xFF, 0xE0})
+	}))
+	defer server.Close()
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam1"] = NewCamera("cam1", "Door", "Reolink Video Doorbell", "localhost", 0, client)
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "emit_test_event", Params: []byte(`{"camera_id": "cam1", "type": "person"}`)})
+	if resp.Error != nil {
+		t.Fatalf("emit_test_event failed: %s", resp.Error.Message)
+	}
+	events := resp.Result.([]Event)
+	if len(events) != 2 || events[0].State != EventStart || events[1].State != EventEnd || events[0].Data["test"] != true {
+		t.Errorf("Expected a test start and end, got %+v", events)
+	}
+
+	events, err := plugin.EmitTestEvent(context.Background(), TestEventRequest{CameraID: "cam1", Type: EventDoorbell})
+	if err != nil {
+		t.Fatalf("EmitTestEvent failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Data["test"] != true || events[0].Data["snapshot"] == nil {
+		t.Errorf("Expected a test doorbell press with a snapshot, got %+v", events)
+	}
+
+	// Test events stay out of the summary
+	summary, err := plugin.GetEventSummary("cam1", 0)
+	if err != nil || len(summary.Cameras) != 0 {
+		t.Errorf("Expected no counted events, got %+v, %v", summary, err)
+	}
+
+	errTests := []struct {
+		req  TestEventRequest
+		want string
+	}{
+		{TestEventRequest{CameraID: "cam1", Type: "smoke"}, "invalid event type"},
+		{TestEventRequest{CameraID: "cam1", Type: EventMotion, State: "ongoing"}, "invalid event state"},
+		{TestEventRequest{CameraID: "cam1", Type: EventDoorbell, State: EventEnd}, "no end"},
+		{TestEventRequest{CameraID: "missing", Type: EventMotion}, "not found"},
+	}
+	for _, tt := range errTests {
+		if _, err := plugin.EmitTestEvent(context.Background(), tt.req); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected %q error, got %v", tt.req, tt.want, err)
+		}
+	}
+}
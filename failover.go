@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// enableFailover lets client move between the addresses of its device. The
+// stream URLs of the device's cameras follow the active address, so the
+// cameras are re-announced with camera.updated after a move.
+func (p *Plugin) enableFailover(client *reolink.Client, fallbackHosts []string) {
+	if len(fallbackHosts) == 0 {
+		return
+	}
+	client.SetFallbackHosts(fallbackHosts)
+	client.SetFailoverHandler(func(from, to string) {
+		goGuarded(p.ctx, "failover of "+client.Host(), func() {
+			for _, cam := range p.camerasOf(client) {
+				p.notifyCameraUpdated(cam)
+			}
+		})
+	})
+}
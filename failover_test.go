@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestParseConfig_FallbackHosts(t *testing.T) {
+	p := NewPlugin()
+	err := p.parseConfig(map[string]interface{}{
+		"devices": []interface{}{
+			map[string]interface{}{"host": "192.168.1.100", "username": "admin", "fallback_hosts": []interface{}{"100.64.0.10", "192.168.1.100"}},
+			map[string]interface{}{"host": "100.64.0.10", "username": "admin"},
+			map[string]interface{}{"host": "192.168.1.101", "username": "admin", "fallback_hosts": []interface{}{"192.168.1.100"}},
+		},
+	})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 3 {
+		t.Fatalf("Expected 3 config errors, got %v", err)
+	}
+	for i, field := range []string{"devices[0].fallback_hosts[1]", "devices[1].host", "devices[2].fallback_hosts[0]"} {
+		if cfgErr.Errors[i].Field != field {
+			t.Errorf("Expected an error for %s, got %+v", field, cfgErr.Errors[i])
+		}
+	}
+}
+
+func TestPlugin_HostFailover(t *testing.T) {
+	lanListener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("Cannot listen on 127.0.0.2: %v", err)
+	}
+	port := lanListener.Addr().(*net.TCPAddr).Port
+	vpnListener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.3", strconv.Itoa(port)))
+	if err != nil {
+		lanListener.Close()
+		t.Skipf("Cannot listen on 127.0.0.3: %v", err)
+	}
+	lan := httptest.NewUnstartedServer(reolinksim.New(reolinksim.Camera{Password: "secret"}))
+	lan.Listener = lanListener
+	lan.Start()
+	defer lan.Close()
+	vpn := httptest.NewUnstartedServer(reolinksim.New(reolinksim.Camera{Password: "secret"}))
+	vpn.Listener = vpnListener
+	vpn.Start()
+	defer vpn.Close()
+	cameraID := "127.0.0.2_ch0"
+
+	var out syncBuffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	err = plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"devices": []interface{}{
+			map[string]interface{}{
+				"host": "127.0.0.2", "port": float64(port), "username": "admin", "password": "secret",
+				"fallback_hosts": []interface{}{"127.0.0.3"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	if cam := plugin.GetCamera(cameraID); cam == nil || cam.ActiveHost != "127.0.0.2" {
+		t.Fatalf("Expected the camera at 127.0.0.2, got %+v", cam)
+	}
+
+	lan.Close()
+	if _, err := plugin.GetStreamProfiles(context.Background(), cameraID); err != nil {
+		t.Fatalf("Expected the request to fail over, got %v", err)
+	}
+	cam := plugin.GetCamera(cameraID)
+	if cam.Host != "127.0.0.2" || cam.ActiveHost != "127.0.0.3" || !strings.Contains(cam.MainStream, "127.0.0.3") {
+		t.Errorf("Expected the camera to keep its host and stream from 127.0.0.3, got %+v", cam)
+	}
+	health, _ := plugin.GetDeviceHealth("127.0.0.2")
+	if len(health) != 1 || health[0].ActiveHost != "127.0.0.3" {
+		t.Errorf("Expected the active address in the device health, got %+v", health)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var updated bool
+		for _, msg := range readLifecycle(t, bytes.NewBufferString(out.String())) {
+			if msg.Method == NotifyCameraUpdated && msg.Params.Camera.ActiveHost == "127.0.0.3" {
+				updated = true
+			}
+		}
+		if updated {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected camera.updated with the active address")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// UpgradeFirmware upgrades a camera's device using a completed incoming transfer
+func (p *Plugin) UpgradeFirmware(ctx context.Context, cameraID, transferID string) error {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return err
+	}
+
+	name, data, err := p.transfers.Take(transferID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Upgrading firmware on camera %s with %s (%d bytes)", cameraID, name, len(data))
+	return p.workerFor(cam.client).do(longRunning(ctx), func(ctx context.Context) error {
+		return cam.client.UpgradeFirmware(ctx, name, data)
+	})
+}
+
+// PlanFirmwareUpgrade checks a firmware upgrade like UpgradeFirmware and
+// reports what it would do. The transfer is kept for the real upgrade.
+func (p *Plugin) PlanFirmwareUpgrade(cameraID, transferID string) (*DryRunResult, error) {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, data, err := p.transfers.Peek(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if err := reolink.ValidateFirmware(name, data); err != nil {
+		return nil, err
+	}
+
+	current := "unknown"
+	if info := cam.GetDeviceInfo(); info != nil && info.FirmwareVersion != "" {
+		current = info.FirmwareVersion
+	}
+	plan := newDryRunResult(cameraID)
+	plan.Changes = append(plan.Changes,
+		fmt.Sprintf("UpgradePrepare: %s", filepath.Base(name)),
+		fmt.Sprintf("Upgrade: firmware %s -> %s (%d bytes); the device reboots", current, filepath.Base(name), len(data)))
+	return plan, nil
+}
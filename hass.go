@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultHADiscoveryPrefix is Home Assistant's default MQTT discovery prefix
+const defaultHADiscoveryPrefix = "homeassistant"
+
+// haDoorbellOffDelay turns a doorbell press, which has no end, back off
+const haDoorbellOffDelay = 5
+
+// unsafeHAChars are the characters not allowed in Home Assistant node and
+// object IDs
+var unsafeHAChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// HADiscoveryRequest is a get_ha_discovery request
+type HADiscoveryRequest struct {
+	CameraID string `json:"camera_id,omitempty"` // all cameras if empty
+
+	// DiscoveryPrefix is Home Assistant's discovery prefix, "homeassistant"
+	// by default; BaseTopic is the root of the state and command topics, the
+	// plugin ID by default
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+	BaseTopic       string `json:"base_topic,omitempty"`
+}
+
+// HADiscoveryMessage is a retained MQTT message announcing a Home Assistant
+// entity
+type HADiscoveryMessage struct {
+	Topic   string                 `json:"topic"`
+	Payload map[string]interface{} `json:"payload"` // published as JSON
+}
+
+// GetHADiscovery returns the Home Assistant MQTT discovery messages of the
+// cameras, for the host's MQTT bridge to publish retained. Each camera is a
+// device with an availability topic, a camera entity for snapshots, binary
+// sensors for motion, its AI detection types and doorbell presses, and a
+// siren switch and a light where it has them. The states live under
+// <base_topic>/<camera_id>/: "online"/"offline" on availability, "ON"/"OFF"
+// on each event type for event starts and ends, JPEG bytes on snapshot, and
+// "ON"/"OFF" on siren and light, whose commands arrive on siren/set and
+// light/set.
+func (p *Plugin) GetHADiscovery(req HADiscoveryRequest) ([]HADiscoveryMessage, error) {
+	prefix, base := req.DiscoveryPrefix, req.BaseTopic
+	if prefix == "" {
+		prefix = defaultHADiscoveryPrefix
+	}
+	if base == "" {
+		base = p.namespace().PluginID
+	}
+	for _, topic := range []string{prefix, base} {
+		if strings.ContainsAny(topic, "+#") || strings.HasPrefix(topic, "/") || strings.HasSuffix(topic, "/") {
+			return nil, fmt.Errorf("invalid topic %q: must not contain wildcards or start or end with /", topic)
+		}
+	}
+
+	var cameras []*Camera
+	if req.CameraID != "" {
+		cam, err := p.lookupCamera(req.CameraID)
+		if err != nil {
+			return nil, err
+		}
+		cameras = append(cameras, cam)
+	} else {
+		p.mu.RLock()
+		for _, cam := range p.cameras {
+			cameras = append(cameras, cam)
+		}
+		p.mu.RUnlock()
+		sort.Slice(cameras, func(i, j int) bool { return cameras[i].ID() < cameras[j].ID() })
+	}
+
+	messages := []HADiscoveryMessage{}
+	for _, cam := range cameras {
+		messages = append(messages, p.haCameraDiscovery(cam, prefix, base)...)
+	}
+	return messages, nil
+}
+
+// haCameraDiscovery returns the discovery messages of one camera's entities
+func (p *Plugin) haCameraDiscovery(cam *Camera, prefix, base string) []HADiscoveryMessage {
+	pluginID := p.namespace().PluginID
+	node := unsafeHAChars.ReplaceAllString(pluginID+"_"+cam.ID(), "_")
+	topic := base + "/" + cam.ID()
+
+	device := map[string]interface{}{
+		"identifiers":  []string{node},
+		"name":         cam.Name(),
+		"manufacturer": "Reolink",
+		"model":        cam.Model(),
+	}
+	if info := cam.GetDeviceInfo(); info != nil {
+		device["sw_version"] = info.FirmwareVersion
+		// The channels of an NVR share its MAC, which would merge them
+		// into one device
+		if mac := cam.MAC(); mac != "" && info.ChannelCount <= 1 {
+			device["connections"] = [][]string{{"mac", mac}}
+		}
+	}
+
+	entity := func(component, key, name string, fields map[string]interface{}) HADiscoveryMessage {
+		payload := map[string]interface{}{
+			"name":               name,
+			"unique_id":          node + "_" + key,
+			"availability_topic": topic + "/availability",
+			"device":             device,
+		}
+		for k, v := range fields {
+			payload[k] = v
+		}
+		return HADiscoveryMessage{
+			Topic:   fmt.Sprintf("%s/%s/%s/%s/config", prefix, component, node, key),
+			Payload: payload,
+		}
+	}
+	binarySensor := func(eventType, name, deviceClass string) HADiscoveryMessage {
+		fields := map[string]interface{}{"state_topic": topic + "/" + eventType}
+		if deviceClass != "" {
+			fields["device_class"] = deviceClass
+		}
+		if eventType == EventDoorbell {
+			fields["off_delay"] = haDoorbellOffDelay
+		}
+		return entity("binary_sensor", eventType, name, fields)
+	}
+
+	features := cam.CapabilitySet()
+	messages := []HADiscoveryMessage{
+		entity("camera", "snapshot", "Snapshot", map[string]interface{}{"topic": topic + "/snapshot"}),
+		binarySensor(EventMotion, "Motion", "motion"),
+	}
+	for _, ai := range features.AI {
+		messages = append(messages, binarySensor(ai, strings.ToUpper(ai[:1])+ai[1:], "occupancy"))
+	}
+	if features.Doorbell {
+		messages = append(messages, binarySensor(EventDoorbell, "Visitor", ""))
+	}
+	// The siren cannot be sounded on demand, only armed to sound on
+	// detections, so it is a switch over configure_siren
+	if features.Siren {
+		messages = append(messages, entity("switch", "siren", "Siren", map[string]interface{}{
+			"command_topic": topic + "/siren/set",
+			"state_topic":   topic + "/siren",
+			"icon":          "mdi:alarm-light",
+		}))
+	}
+	if features.Light {
+		messages = append(messages, entity("light", "light", "Light", map[string]interface{}{
+			"command_topic": topic + "/light/set",
+			"state_topic":   topic + "/light",
+		}))
+	}
+	return messages
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestPlugin_GetHADiscovery(t *testing.T) {
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	plugin := NewPlugin()
+	drive := NewCamera("192.168.1.100_ch0", "Drive", "RLC-823A", "192.168.1.100", 0, client)
+	drive.SetAbility(&reolink.Ability{AudioAlarm: true, Floodlight: true})
+	plugin.cameras[drive.ID()] = drive
+	plugin.cameras["cam_2"] = NewCamera("cam_2", "Shed", "E1", "192.168.1.101", 0, client)
+
+	messages, err := plugin.GetHADiscovery(HADiscoveryRequest{})
+	if err != nil {
+		t.Fatalf("GetHADiscovery failed: %v", err)
+	}
+	byTopic := make(map[string]map[string]interface{})
+	for _, msg := range messages {
+		byTopic[msg.Topic] = msg.Payload
+	}
+
+	node := "reolink_192_168_1_100_ch0"
+	for _, topic := range []string{
+		"homeassistant/camera/" + node + "/snapshot/config",
+		"homeassistant/binary_sensor/" + node + "/motion/config",
+		"homeassistant/binary_sensor/" + node + "/person/config",
+		"homeassistant/switch/" + node + "/siren/config",
+		"homeassistant/light/" + node + "/light/config",
+		"homeassistant/camera/reolink_cam_2/snapshot/config",
+	} {
+		if byTopic[topic] == nil {
+			t.Errorf("Missing discovery message %s", topic)
+		}
+	}
+	if byTopic["homeassistant/switch/reolink_cam_2/siren/config"] != nil {
+		t.Error("Expected no siren for a camera without one")
+	}
+
+	person := byTopic["homeassistant/binary_sensor/"+node+"/person/config"]
+	if person["state_topic"] != "reolink/192.168.1.100_ch0/person" || person["unique_id"] != node+"_person" ||
+		person["availability_topic"] != "reolink/192.168.1.100_ch0/availability" {
+		t.Errorf("Unexpected person sensor %v", person)
+	}
+	if device := person["device"].(map[string]interface{}); device["name"] != "Drive" || device["model"] != "RLC-823A" {
+		t.Errorf("Unexpected device %v", device)
+	}
+	if _, err := json.Marshal(messages); err != nil {
+		t.Errorf("Payloads do not encode: %v", err)
+	}
+
+	messages, err = plugin.GetHADiscovery(HADiscoveryRequest{CameraID: "cam_2", DiscoveryPrefix: "ha", BaseTopic: "nvr/reolink"})
+	if err != nil || len(messages) == 0 {
+		t.Fatalf("Expected messages for cam_2, got %v, %v", messages, err)
+	}
+	for _, msg := range messages {
+		if !strings.HasPrefix(msg.Topic, "ha/") || !strings.Contains(msg.Topic, "/reolink_cam_2/") {
+			t.Errorf("Unexpected topic %s", msg.Topic)
+		}
+	}
+
+	if _, err := plugin.GetHADiscovery(HADiscoveryRequest{BaseTopic: "reolink/#"}); err == nil {
+		t.Error("Expected an error for a wildcard topic")
+	}
+}
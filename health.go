@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// DeviceHealth is the health of one configured device (camera or NVR)
+type DeviceHealth struct {
+	Host            string `json:"host"`
+	ActiveHost      string `json:"active_host,omitempty"` // the address in use, for devices with fallback_hosts
+	Model           string `json:"model,omitempty"`
+	State           string `json:"state"` // "healthy", "degraded", "unhealthy" or "disconnected"
+	Channels        int    `json:"channels"`
+	ChannelsOnline  int    `json:"channels_online"`
+	LastSeen        string `json:"last_seen,omitempty"`
+	LastError       string `json:"last_error,omitempty"`
+	LastErrorAt     string `json:"last_error_at,omitempty"`
+	AuthMode        string `json:"auth_mode,omitempty"`         // "token" or "basic"
+	TokenAgeSeconds int    `json:"token_age_seconds,omitempty"` // age of the current session token
+	Breaker         string `json:"breaker,omitempty"`           // "closed", "open" or "half_open"
+
+	// Firmware and the version-dependent API features it was found to support
+	Firmware    string            `json:"firmware,omitempty"`
+	APIFeatures []reolink.Feature `json:"api_features,omitempty"`
+}
+
+// deviceHealthLocked builds per-device health entries sorted by host.
+// Callers must hold p.mu.
+func (p *Plugin) deviceHealthLocked() []DeviceHealth {
+	byClient := make(map[*reolink.Client]*DeviceHealth)
+	var result []*DeviceHealth
+	seenHosts := make(map[string]bool)
+
+	for _, cam := range p.cameras {
+		dh, ok := byClient[cam.client]
+		if !ok {
+			dh = &DeviceHealth{Host: cam.Host(), Model: cam.Model()}
+			if cam.client != nil {
+				h := cam.client.Health()
+				dh.AuthMode = h.AuthMode
+				dh.TokenAgeSeconds = int(h.TokenAge.Seconds())
+				dh.LastError = h.LastError
+				dh.Breaker = h.Breaker
+				if len(cam.client.Hosts()) > 1 {
+					dh.ActiveHost = cam.client.ActiveHost()
+				}
+				if !h.LastErrorAt.IsZero() {
+					dh.LastErrorAt = h.LastErrorAt.Format(time.RFC3339)
+				}
+				if v, known := cam.client.FirmwareVersion(); known {
+					dh.Firmware = v.String()
+					dh.APIFeatures = cam.client.Features()
+				}
+			}
+			byClient[cam.client] = dh
+			result = append(result, dh)
+			seenHosts[cam.Host()] = true
+		}
+
+		dh.Channels++
+		if cam.IsOnline() {
+			dh.ChannelsOnline++
+		}
+		if seen := cam.LastSeen().Format(time.RFC3339); seen > dh.LastSeen {
+			dh.LastSeen = seen
+		}
+	}
+
+	// Configured devices that never connected have no cameras
+	for _, device := range p.devices {
+		if seenHosts[device.Host] {
+			continue
+		}
+		seenHosts[device.Host] = true
+		result = append(result, &DeviceHealth{
+			Host:      device.Host,
+			LastError: p.deviceErrors[device.Host],
+		})
+	}
+
+	devices := make([]DeviceHealth, 0, len(result))
+	for _, dh := range result {
+		switch {
+		case dh.Channels == 0:
+			dh.State = "disconnected"
+		case dh.ChannelsOnline == dh.Channels && dh.Breaker != reolink.BreakerOpen:
+			dh.State = "healthy"
+		case dh.ChannelsOnline == 0:
+			dh.State = "unhealthy"
+		default:
+			dh.State = "degraded"
+		}
+		devices = append(devices, *dh)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Host < devices[j].Host })
+	return devices
+}
+
+// GetDeviceHealth returns the health of every device, or only of host if given
+func (p *Plugin) GetDeviceHealth(host string) ([]DeviceHealth, error) {
+	p.mu.RLock()
+	devices := p.deviceHealthLocked()
+	p.mu.RUnlock()
+
+	if host == "" {
+		return devices, nil
+	}
+	for _, dh := range devices {
+		if dh.Host == host {
+			return []DeviceHealth{dh}, nil
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", host)
+}
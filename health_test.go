@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_GetDeviceHealth(t *testing.T) {
+	plugin := NewPlugin()
+
+	ctx := context.Background()
+	nvr := newTestClient(newTokenDevice(t, 3600, map[string]interface{}{
+		"GetEnc": &apiErrorDetail{RspCode: -9, Detail: "not support"},
+	}, nil))
+	if err := nvr.Login(ctx); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if _, err := nvr.GetEncoderConfig(ctx, 0); err == nil {
+		t.Fatal("Expected GetEnc to fail")
+	}
+
+	ch0 := NewCamera("nvr_ch0", "NVR Ch1", "RLN8-410", "192.168.1.10", 0, nvr)
+	ch1 := NewCamera("nvr_ch1", "NVR Ch2", "RLN8-410", "192.168.1.10", 1, nvr)
+	ch1.online = false
+	plugin.cameras["nvr_ch0"] = ch0
+	plugin.cameras["nvr_ch1"] = ch1
+
+	cam := newTestClient(newFakeDevice(t, nil))
+	if err := cam.Login(ctx); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	plugin.cameras["cam"] = NewCamera("cam", "Front", "RLC-810A", "192.168.1.20", 0, cam)
+
+	plugin.devices = []DeviceConfig{{Host: "192.168.1.30"}}
+	plugin.deviceErrors["192.168.1.30"] = "login failed"
+
+	devices, err := plugin.GetDeviceHealth("")
+	if err != nil {
+		t.Fatalf("GetDeviceHealth failed: %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("Expected 3 devices, got %d", len(devices))
+	}
+
+	nvrHealth := devices[0]
+	if nvrHealth.Host != "192.168.1.10" || nvrHealth.Channels != 2 || nvrHealth.ChannelsOnline != 1 {
+		t.Errorf("Unexpected NVR health: %+v", nvrHealth)
+	}
+	if nvrHealth.State != "degraded" || !strings.Contains(nvrHealth.LastError, "not support") {
+		t.Errorf("Unexpected NVR state/error: %+v", nvrHealth)
+	}
+	if nvrHealth.AuthMode != "token" || nvrHealth.Breaker != reolink.BreakerClosed {
+		t.Errorf("Unexpected NVR session info: %+v", nvrHealth)
+	}
+
+	if devices[1].State != "healthy" || devices[1].AuthMode != "basic" {
+		t.Errorf("Unexpected camera health: %+v", devices[1])
+	}
+	if devices[2].State != "disconnected" || devices[2].LastError != "login failed" {
+		t.Errorf("Unexpected disconnected device health: %+v", devices[2])
+	}
+
+	single, err := plugin.GetDeviceHealth("192.168.1.20")
+	if err != nil || len(single) != 1 {
+		t.Errorf("Expected single device, got %v, %v", single, err)
+	}
+	if _, err := plugin.GetDeviceHealth("10.0.0.1"); err == nil {
+		t.Error("Expected error for unknown host")
+	}
+}
+
+func TestPlugin_GetDeviceHealth_Features(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{FirmwareVersion: "v2.0.0.1441_19032101", Password: "secret"}))
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := reolink.NewClient(host, port, "admin", "secret")
+	plugin := NewPlugin()
+	plugin.cameras["cam"] = NewCamera("cam", "Front", "RLC-410", host, 0, client)
+
+	devices, _ := plugin.GetDeviceHealth(host)
+	if len(devices) != 1 || devices[0].Firmware != "" {
+		t.Fatalf("Expected no firmware before the device info is read, got %+v", devices)
+	}
+
+	if _, err := client.GetDeviceInfo(context.Background()); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	devices, _ = plugin.GetDeviceHealth(host)
+	if devices[0].Firmware != "v2.0.0.1441" || len(devices[0].APIFeatures) != 0 {
+		t.Errorf("Expected v2 firmware without versioned features, got %+v", devices[0])
+	}
+}
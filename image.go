@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Orientation is how the camera's ISP rotates and mirrors the image, which
+// viewers and analytics need to interpret the stream
+type Orientation struct {
+	Rotation int  `json:"rotation"` // degrees; 90 and 270 swap width and height
+	Mirror   bool `json:"mirror"`
+}
+
+// Orientation returns the last known image orientation, or nil if the image
+// settings have not been read yet
+func (c *Camera) Orientation() *Orientation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.orientation
+}
+
+// updateOrientation caches the orientation from ISP settings and reports
+// whether it changed
+func (c *Camera) updateOrientation(settings *reolink.ImageSettings) bool {
+	if settings == nil || (settings.Rotation == nil && settings.Mirror == nil) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o := Orientation{}
+	if c.orientation != nil {
+		o = *c.orientation
+	}
+	if settings.Rotation != nil {
+		o.Rotation = *settings.Rotation
+	}
+	if settings.Mirror != nil {
+		o.Mirror = *settings.Mirror
+	}
+	changed := c.orientation == nil || *c.orientation != o
+	c.orientation = &o
+	return changed
+}
+
+// GetImageSettings returns the ISP settings of a camera
+func (p *Plugin) GetImageSettings(ctx context.Context, cameraID string) (*reolink.ImageSettings, error) {
+	var settings *reolink.ImageSettings
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		settings, err = cam.client.GetImageSettings(ctx, cam.Channel())
+		if err == nil {
+			cam.updateOrientation(settings)
+		}
+		return err
+	})
+	return settings, err
+}
+
+// SetImageSettings applies ISP settings to a camera and returns the resulting
+// settings. A rotation or mirroring change re-reads the encoder settings,
+// whose resolution follows the rotation, and is announced with
+// camera.updated so hosts pick up the new stream metadata right away.
+func (p *Plugin) SetImageSettings(ctx context.Context, cameraID string, settings reolink.ImageSettings) (*reolink.ImageSettings, error) {
+	var updated *reolink.ImageSettings
+	var reoriented *Camera
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		if err := cam.client.SetImageSettings(ctx, cam.Channel(), settings); err != nil {
+			return err
+		}
+		log.Printf("Updated image settings for camera %s", cameraID)
+
+		var err error
+		updated, err = cam.client.GetImageSettings(ctx, cam.Channel())
+		if err != nil {
+			return err
+		}
+		if cam.updateOrientation(updated) && (settings.Rotation != nil || settings.Mirror != nil) {
+			if _, err := cam.refreshEncoderConfig(ctx); err != nil {
+				log.Printf("Failed to refresh encoder config of camera %s: %v", cameraID, err)
+			}
+			reoriented = cam
+		}
+		return nil
+	})
+	if reoriented != nil {
+		p.notifyCameraUpdated(reoriented)
+	}
+	return updated, err
+}
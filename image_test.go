@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// newISPDevice starts a fake device that keeps its ISP rotation and mirroring
+// and, like corridor mode, swaps the main stream dimensions when rotated by
+// 90 or 270 degrees
+func newISPDevice(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	isp := map[string]interface{}{"channel": float64(0), "rotation": float64(0), "mirroring": float64(0)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		cmd := r.URL.Query().Get("cmd")
+		if len(cmds) > 0 {
+			cmd = cmds[0].Cmd
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		var value interface{}
+		switch cmd {
+		case "SetIsp":
+			params, _ := cmds[0].Param["Isp"].(map[string]interface{})
+			for k, v := range params {
+				isp[k] = v
+			}
+		case "GetIsp":
+			value = map[string]interface{}{"Isp": isp}
+		case "GetEnc":
+			width, height := float64(2560), float64(1440)
+			if rotation := isp["rotation"].(float64); rotation >= 2 {
+				width, height = height, width
+			}
+			value = map[string]interface{}{"Enc": map[string]interface{}{
+				"mainStream": map[string]interface{}{"width": width, "height": height},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: cmd, Code: 0, Value: value}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPlugin_SetImageSettings_Orientation(t *testing.T) {
+	client := newTestClient(newISPDevice(t))
+	client.UseURLAuth()
+
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	cam := NewCamera("cam_1", "Hall", "RLC-510A", "localhost", 0, client)
+	plugin.cameras["cam_1"] = cam
+
+	if _, err := plugin.GetImageSettings(context.Background(), "cam_1"); err != nil {
+		t.Fatalf("GetImageSettings failed: %v", err)
+	}
+	if o := cam.Orientation(); o == nil || o.Rotation != 0 || o.Mirror {
+		t.Errorf("Expected an upright orientation, got %+v", o)
+	}
+
+	rotation, mirror := 90, true
+	settings, err := plugin.SetImageSettings(context.Background(), "cam_1", reolink.ImageSettings{Rotation: &rotation, Mirror: &mirror})
+	if err != nil {
+		t.Fatalf("SetImageSettings failed: %v", err)
+	}
+	if settings.Rotation == nil || *settings.Rotation != 90 {
+		t.Errorf("Expected rotation 90, got %v", settings.Rotation)
+	}
+
+	msgs := readLifecycle(t, &out)
+	if len(msgs) != 1 || msgs[0].Method != NotifyCameraUpdated {
+		t.Fatalf("Expected one camera.updated notification, got %+v", msgs)
+	}
+	updated := msgs[0].Params.Camera
+	if updated.Orientation == nil || updated.Orientation.Rotation != 90 || !updated.Orientation.Mirror {
+		t.Errorf("Expected the new orientation in the notification, got %+v", updated.Orientation)
+	}
+	if updated.Encoder == nil || updated.Encoder.MainStream.Width != 1440 || updated.Encoder.MainStream.Height != 2560 {
+		t.Errorf("Expected the rotated resolution in the notification, got %+v", updated.Encoder)
+	}
+
+	// Other ISP settings leave the stream metadata alone
+	off := false
+	if _, err := plugin.SetImageSettings(context.Background(), "cam_1", reolink.ImageSettings{NoiseReduction: &off}); err != nil {
+		t.Fatalf("SetImageSettings failed: %v", err)
+	}
+	if msgs := readLifecycle(t, &out); len(msgs) != 0 {
+		t.Errorf("Expected no notification, got %+v", msgs)
+	}
+}
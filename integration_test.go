@@ -0,0 +1,284 @@
+//go:build integration
+
+package main_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// pluginBinary is the plugin built once for all integration tests
+var pluginBinary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "reolink-plugin-integration")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	pluginBinary = filepath.Join(dir, "reolink-plugin")
+	build := exec.Command("go", "build", "-o", pluginBinary, ".")
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to build the plugin:", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// rpcMessage is a response or notification written by the plugin
+type rpcMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// pluginProcess is the plugin binary driven over stdin and stdout
+type pluginProcess struct {
+	t      *testing.T
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	nextID int
+
+	mu            sync.Mutex
+	responses     map[int]chan rpcMessage
+	notifications []rpcMessage
+}
+
+func startPlugin(t *testing.T) *pluginProcess {
+	t.Helper()
+	cmd := exec.Command(pluginBinary)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start the plugin: %v", err)
+	}
+
+	p := &pluginProcess{t: t, cmd: cmd, stdin: stdin, responses: make(map[int]chan rpcMessage)}
+	go p.read(stdout)
+	t.Cleanup(p.stop)
+	return p
+}
+
+// read dispatches every line of stdout, failing on anything but JSON-RPC
+func (p *pluginProcess) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			p.t.Errorf("Plugin wrote a line that is not JSON-RPC: %q", scanner.Text())
+			continue
+		}
+		p.mu.Lock()
+		if msg.ID == nil {
+			p.notifications = append(p.notifications, msg)
+		} else if ch, ok := p.responses[*msg.ID]; ok {
+			ch <- msg
+		} else {
+			p.t.Errorf("Response to unknown request: %s", scanner.Text())
+		}
+		p.mu.Unlock()
+	}
+}
+
+// call sends a request and returns its result, failing the test on an error
+func (p *pluginProcess) call(method string, params interface{}, result interface{}) {
+	p.t.Helper()
+	if msg := p.request(method, params); msg.Error != nil {
+		p.t.Fatalf("%s failed: %d %s", method, msg.Error.Code, msg.Error.Message)
+	} else if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			p.t.Fatalf("Unexpected %s result %s: %v", method, msg.Result, err)
+		}
+	}
+}
+
+// request sends a request and waits for its response
+func (p *pluginProcess) request(method string, params interface{}) rpcMessage {
+	p.t.Helper()
+	p.nextID++
+	id := p.nextID
+	ch := make(chan rpcMessage, 1)
+	p.mu.Lock()
+	p.responses[id] = ch
+	p.mu.Unlock()
+
+	line, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		p.t.Fatalf("Failed to send %s: %v", method, err)
+	}
+
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(20 * time.Second):
+		p.t.Fatalf("Timed out waiting for the response to %s", method)
+		return rpcMessage{}
+	}
+}
+
+// notified reports whether a notification of method has been received
+func (p *pluginProcess) notified(method string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, msg := range p.notifications {
+		if msg.Method == method {
+			return true
+		}
+	}
+	return false
+}
+
+// stop closes stdin, which makes the plugin shut down, and waits for it
+func (p *pluginProcess) stop() {
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		_ = p.cmd.Process.Kill()
+		p.t.Error("Plugin did not exit after stdin was closed")
+	}
+}
+
+func simAddress(t *testing.T, sim *reolinksim.Server) (string, int) {
+	t.Helper()
+	server := httptest.NewServer(sim)
+	t.Cleanup(server.Close)
+	hostPort := strings.TrimPrefix(server.URL, "http://")
+	idx := strings.LastIndex(hostPort, ":")
+	port, _ := strconv.Atoi(hostPort[idx+1:])
+	return hostPort[:idx], port
+}
+
+// TestIntegration_Camera adds a simulated PTZ camera through the plugin
+// binary and exercises probing, snapshots and PTZ over JSON-RPC
+func TestIntegration_Camera(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", PTZ: true, AI: true, TokenOnly: true})
+	host, port := simAddress(t, sim)
+	plugin := startPlugin(t)
+
+	plugin.call("initialize", map[string]interface{}{"event_poll_interval_ms": 0}, nil)
+
+	var probe struct {
+		Model        string   `json:"model"`
+		Capabilities []string `json:"capabilities"`
+	}
+	plugin.call("probe_camera", map[string]interface{}{"host": host, "port": port, "username": "admin", "password": "secret"}, &probe)
+	if probe.Model != reolinksim.DefaultCamera.Model {
+		t.Errorf("Expected model %s, got %+v", reolinksim.DefaultCamera.Model, probe)
+	}
+
+	var cam struct {
+		ID           string   `json:"id"`
+		Model        string   `json:"model"`
+		Capabilities []string `json:"capabilities"`
+		MainStream   string   `json:"main_stream"`
+	}
+	plugin.call("add_camera", map[string]interface{}{"host": host, "port": port, "username": "admin", "password": "secret", "protocol": "rtsp"}, &cam)
+	if cam.ID != host+"_ch0" || !strings.HasPrefix(cam.MainStream, "rtsp://") {
+		t.Errorf("Unexpected camera %+v", cam)
+	}
+	if !plugin.notified("camera.added") {
+		t.Error("Expected a camera.added notification")
+	}
+
+	var snapshot string
+	plugin.call("get_snapshot", map[string]interface{}{"camera_id": cam.ID}, &snapshot)
+	if data, err := base64.StdEncoding.DecodeString(snapshot); err != nil || len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		t.Errorf("Expected a base64 JPEG snapshot, got %d characters (%v)", len(snapshot), err)
+	}
+
+	plugin.call("ptz_control", map[string]interface{}{"camera_id": cam.ID, "command": map[string]interface{}{"action": "pan", "direction": 1}}, nil)
+	if cmds := sim.PTZCommands(); len(cmds) != 1 || cmds[0].Op != "Right" {
+		t.Errorf("Unexpected PTZ commands: %+v", cmds)
+	}
+
+	// Protocol errors come back as JSON-RPC errors rather than breaking the stream
+	if msg := plugin.request("get_camera", map[string]interface{}{"camera_id": "missing"}); msg.Error == nil {
+		t.Error("Expected an error for an unknown camera")
+	}
+	if msg := plugin.request("no_such_method", nil); msg.Error == nil || msg.Error.Code != -32601 {
+		t.Errorf("Expected method not found, got %+v", msg.Error)
+	}
+
+	plugin.call("remove_camera", map[string]interface{}{"camera_id": cam.ID}, nil)
+	var cameras []json.RawMessage
+	plugin.call("list_cameras", nil, &cameras)
+	if len(cameras) != 0 {
+		t.Errorf("Expected no cameras after remove_camera, got %d", len(cameras))
+	}
+
+	plugin.call("shutdown", nil, nil)
+}
+
+// TestIntegration_NVR configures a multi-channel NVR at initialize and reads
+// events of one of its channels through get_events
+func TestIntegration_NVR(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "secret", LegacyEvents: true})
+	host, port := simAddress(t, sim)
+	plugin := startPlugin(t)
+
+	var report struct {
+		Connected int `json:"connected"`
+		Devices   []struct {
+			Cameras []string `json:"cameras"`
+		} `json:"devices"`
+	}
+	plugin.call("initialize", map[string]interface{}{
+		"event_poll_interval_ms": 20,
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": port, "username": "admin", "password": "secret"},
+		},
+	}, &report)
+	if report.Connected != 1 || len(report.Devices) != 1 || len(report.Devices[0].Cameras) != 2 {
+		t.Fatalf("Expected the NVR with 2 cameras, got %+v", report)
+	}
+
+	sim.SetMotion(1, true)
+	var result struct {
+		Events []struct {
+			Type     string `json:"type"`
+			CameraID string `json:"camera_id"`
+		} `json:"events"`
+	}
+	plugin.call("get_events", map[string]interface{}{"camera_id": host + "_ch1", "wait_ms": 5000}, &result)
+	if len(result.Events) == 0 || result.Events[0].Type != "motion" {
+		t.Errorf("Expected a motion event, got %+v", result)
+	}
+}
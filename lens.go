@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Lens layouts, reported in the lens capability
+const (
+	LensLayoutDual    = "dual"    // Duo: two sensors side by side
+	LensLayoutFisheye = "fisheye" // Fisheye: one 360° sensor
+)
+
+// Fisheye view modes: the raw circle, or one of the dewarped layouts
+const (
+	ViewModeFisheye        = "fisheye"
+	ViewModePanorama       = "panorama"
+	ViewModeDoublePanorama = "double_panorama"
+	ViewModeQuad           = "quad"
+)
+
+// Fisheye mounts, which decide how the image is dewarped
+const (
+	MountCeiling = "ceiling"
+	MountWall    = "wall"
+	MountDesk    = "desk"
+)
+
+var (
+	fisheyeViewModes = []string{ViewModeFisheye, ViewModePanorama, ViewModeDoublePanorama, ViewModeQuad}
+	fisheyeMounts    = []string{MountCeiling, MountWall, MountDesk}
+)
+
+// LensCapability describes a multi-sensor or fisheye lens, so the host can
+// render a stitched or dewarping viewer
+type LensCapability struct {
+	Layout    string   `json:"layout"`               // "dual" or "fisheye"
+	Sensors   int      `json:"sensors"`              // image sensors behind the stream
+	ViewModes []string `json:"view_modes,omitempty"` // fisheye only
+	Mounts    []string `json:"mounts,omitempty"`     // fisheye only
+}
+
+// LensMode is the viewer configuration of a Duo or Fisheye camera
+type LensMode struct {
+	// Stitch blends the two Duo sensors into one panorama instead of
+	// showing them side by side
+	Stitch bool `json:"stitch"`
+
+	// ViewMode and Mount select how a fisheye image is dewarped
+	ViewMode string `json:"view_mode,omitempty"`
+	Mount    string `json:"mount,omitempty"`
+}
+
+// lensLayout returns the lens layout of a model, or "" for a single lens
+func lensLayout(model string) string {
+	switch layout := reolink.LookupModel(model).Lens; layout {
+	case reolink.LensDual, reolink.LensFisheye:
+		return layout
+	}
+	return ""
+}
+
+// lensCapability returns the lens capability of a model, nil for a single lens
+func lensCapability(model string) *LensCapability {
+	switch lensLayout(model) {
+	case LensLayoutDual:
+		return &LensCapability{Layout: LensLayoutDual, Sensors: 2}
+	case LensLayoutFisheye:
+		return &LensCapability{
+			Layout:    LensLayoutFisheye,
+			Sensors:   1,
+			ViewModes: fisheyeViewModes,
+			Mounts:    fisheyeMounts,
+		}
+	}
+	return nil
+}
+
+// defaultLensMode returns the lens mode of a newly added camera: Duo streams
+// are stitched and fisheye images are shown as a ceiling-mounted circle
+func defaultLensMode(model string) *LensMode {
+	switch lensLayout(model) {
+	case LensLayoutDual:
+		return &LensMode{Stitch: true}
+	case LensLayoutFisheye:
+		return &LensMode{ViewMode: ViewModeFisheye, Mount: MountCeiling}
+	}
+	return nil
+}
+
+// LensMode returns the camera's lens mode, nil for single-lens cameras
+func (c *Camera) LensMode() *LensMode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lensMode == nil {
+		return nil
+	}
+	mode := *c.lensMode
+	return &mode
+}
+
+// SetLensMode sets the camera's lens mode
+func (c *Camera) SetLensMode(mode *LensMode) {
+	c.mu.Lock()
+	c.lensMode = mode
+	c.mu.Unlock()
+}
+
+// SetLensMode changes how a Duo camera's sensors are stitched or how a
+// fisheye camera is dewarped. Reolink leaves both to the viewer, so the mode
+// is stored by the plugin and reported to the host rather than sent to the
+// device. Unset fields keep their current value.
+func (p *Plugin) SetLensMode(cameraID string, stitch *bool, viewMode, mount string) (*LensMode, error) {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := cam.LensMode()
+	if mode == nil {
+		return nil, fmt.Errorf("camera %s has a single lens", cameraID)
+	}
+
+	switch lensLayout(cam.Model()) {
+	case LensLayoutDual:
+		if viewMode != "" || mount != "" {
+			return nil, fmt.Errorf("view_mode and mount apply to fisheye cameras only")
+		}
+		if stitch != nil {
+			mode.Stitch = *stitch
+		}
+	case LensLayoutFisheye:
+		if stitch != nil {
+			return nil, fmt.Errorf("stitch applies to Duo cameras only")
+		}
+		if viewMode != "" {
+			if !contains(fisheyeViewModes, viewMode) {
+				return nil, fmt.Errorf("invalid view_mode %q (expected one of %s)", viewMode, strings.Join(fisheyeViewModes, ", "))
+			}
+			mode.ViewMode = viewMode
+		}
+		if mount != "" {
+			if !contains(fisheyeMounts, mount) {
+				return nil, fmt.Errorf("invalid mount %q (expected one of %s)", mount, strings.Join(fisheyeMounts, ", "))
+			}
+			mode.Mount = mount
+		}
+	}
+
+	cam.SetLensMode(mode)
+	p.mu.Lock()
+	p.state.LensModes[cameraID] = *mode
+	p.mu.Unlock()
+
+	p.saveState()
+	log.Printf("Updated camera %s lens mode: %+v", cameraID, *mode)
+	p.notifyCameraUpdated(cam)
+	return cam.LensMode(), nil
+}
+
+// restoreLensMode applies the stored lens mode of a newly added camera,
+// which otherwise keeps its model's default
+func (p *Plugin) restoreLensMode(cam *Camera) {
+	p.mu.RLock()
+	stored, ok := p.state.LensModes[cam.ID()]
+	p.mu.RUnlock()
+	if ok && cam.LensMode() != nil {
+		cam.SetLensMode(&stored)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestCamera_LensCapability(t *testing.T) {
+	tests := []struct {
+		model  string
+		layout string
+	}{
+		{"Reolink Duo 2 PoE", LensLayoutDual},
+		{"FE-P", LensLayoutFisheye},
+		{"Reolink Fisheye Series P520", LensLayoutFisheye},
+		{"RLC-810A", ""},
+	}
+	for _, tt := range tests {
+		set := NewCamera("cam", "Cam", tt.model, "localhost", 0, nil).CapabilitySet()
+		if tt.layout == "" {
+			if set.Lens != nil {
+				t.Errorf("%s: expected no lens capability, got %+v", tt.model, set.Lens)
+			}
+			continue
+		}
+		if set.Lens == nil || set.Lens.Layout != tt.layout {
+			t.Errorf("%s: expected %s layout, got %+v", tt.model, tt.layout, set.Lens)
+		}
+	}
+}
+
+func TestPlugin_SetLensMode(t *testing.T) {
+	dir := t.TempDir()
+	plugin := NewPlugin()
+	plugin.store = newStateStore(dir)
+	client := reolink.NewClient("localhost", 80, "admin", "secret")
+	plugin.cameras["duo"] = NewCamera("duo", "Yard", "Reolink Duo 2 PoE", "localhost", 0, client)
+	plugin.cameras["fe"] = NewCamera("fe", "Hall", "FE-P", "localhost", 0, client)
+	plugin.cameras["rlc"] = NewCamera("rlc", "Gate", "RLC-810A", "localhost", 0, client)
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "set_lens_mode",
+		Params: []byte(`{"camera_id": "duo", "stitch": false}`)})
+	if resp.Error != nil {
+		t.Fatalf("set_lens_mode failed: %s", resp.Error.Message)
+	}
+	if mode := resp.Result.(*LensMode); mode.Stitch {
+		t.Errorf("Expected stitching to be off, got %+v", mode)
+	}
+
+	mode, err := plugin.SetLensMode("fe", nil, ViewModePanorama, "")
+	if err != nil {
+		t.Fatalf("SetLensMode failed: %v", err)
+	}
+	if mode.ViewMode != ViewModePanorama || mode.Mount != MountCeiling {
+		t.Errorf("Expected a ceiling panorama, got %+v", mode)
+	}
+
+	errTests := []struct {
+		camera, viewMode, mount, want string
+	}{
+		{"fe", "cylinder", "", "invalid view_mode"},
+		{"fe", "", "floor", "invalid mount"},
+		{"duo", ViewModeQuad, "", "fisheye cameras only"},
+		{"rlc", ViewModeQuad, "", "single lens"},
+	}
+	for _, tt := range errTests {
+		if _, err := plugin.SetLensMode(tt.camera, nil, tt.viewMode, tt.mount); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %s/%s: expected %q error, got %v", tt.camera, tt.viewMode, tt.mount, tt.want, err)
+		}
+	}
+
+	// Modes survive a restart and are reported on the camera
+	state, err := newStateStore(dir).Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	restarted := NewPlugin()
+	restarted.state = state
+	cam := NewCamera("duo", "Yard", "Reolink Duo 2 PoE", "localhost", 0, client)
+	restarted.restoreLensMode(cam)
+	if lens := restarted.newPluginCamera(cam).Lens; lens == nil || lens.Stitch {
+		t.Errorf("Expected the stored lens mode after a restart, got %+v", lens)
+	}
+}
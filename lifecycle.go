@@ -0,0 +1,63 @@
+package main
+
+import (
+	"time"
+)
+
+// Camera lifecycle notification methods, sent so the host's inventory stays
+// in sync without polling list_cameras
+const (
+	NotifyCameraAdded   = "camera.added"
+	NotifyCameraRemoved = "camera.removed"
+	NotifyCameraOnline  = "camera.online"
+	NotifyCameraOffline = "camera.offline"
+	NotifyCameraUpdated = "camera.updated"
+)
+
+// CameraLifecycle is the payload of the camera lifecycle notifications
+type CameraLifecycle struct {
+	CameraID  string        `json:"camera_id"`
+	Timestamp time.Time     `json:"timestamp"`
+	LastSeen  string        `json:"last_seen,omitempty"` // online/offline only
+	Camera    *PluginCamera `json:"camera,omitempty"`    // camera.added and camera.updated only
+}
+
+// notifyCameraAdded announces a newly added (or re-created) camera with its full details
+func (p *Plugin) notifyCameraAdded(cam *Camera) {
+	_ = p.notify(NotifyCameraAdded, CameraLifecycle{
+		CameraID:  cam.ID(),
+		Timestamp: time.Now(),
+		Camera:    p.newPluginCamera(cam),
+	})
+}
+
+// notifyCameraUpdated announces a change in a camera's details, such as its
+// stream settings
+func (p *Plugin) notifyCameraUpdated(cam *Camera) {
+	_ = p.notify(NotifyCameraUpdated, CameraLifecycle{
+		CameraID:  cam.ID(),
+		Timestamp: time.Now(),
+		Camera:    p.newPluginCamera(cam),
+	})
+}
+
+// notifyCameraRemoved announces that a camera is no longer managed by the plugin
+func (p *Plugin) notifyCameraRemoved(cameraID string) {
+	_ = p.notify(NotifyCameraRemoved, CameraLifecycle{
+		CameraID:  cameraID,
+		Timestamp: time.Now(),
+	})
+}
+
+// notifyCameraStatus announces a camera going online or offline
+func (p *Plugin) notifyCameraStatus(cam *Camera, online bool) {
+	method := NotifyCameraOffline
+	if online {
+		method = NotifyCameraOnline
+	}
+	_ = p.notify(method, CameraLifecycle{
+		CameraID:  cam.ID(),
+		Timestamp: time.Now(),
+		LastSeen:  cam.LastSeen().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// lifecycleMessage is a decoded lifecycle notification
+type lifecycleMessage struct {
+	Method string          `json:"method"`
+	Params CameraLifecycle `json:"params"`
+}
+
+// readLifecycle decodes the notifications written to out
+func readLifecycle(t *testing.T, out *bytes.Buffer) []lifecycleMessage {
+	t.Helper()
+	var msgs []lifecycleMessage
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var msg lifecycleMessage
+		if err := dec.Decode(&msg); err != nil {
+			t.Fatalf("Invalid notification: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestPlugin_RemoveCamera_Notifies(t *testing.T) {
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Front", "RLC-810A", "localhost", 0, nil)
+
+	if err := plugin.RemoveCamera(context.Background(), "cam_1"); err != nil {
+		t.Fatalf("RemoveCamera failed: %v", err)
+	}
+
+	msgs := readLifecycle(t, &out)
+	if len(msgs) != 1 || msgs[0].Method != NotifyCameraRemoved || msgs[0].Params.CameraID != "cam_1" {
+		t.Errorf("Expected one camera.removed notification, got %+v", msgs)
+	}
+}
+
+func TestPlugin_AddDeviceCameras_Notifies(t *testing.T) {
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	device := DeviceConfig{Host: "192.168.1.100", Protocol: "rtmp"}
+	plugin.addDeviceCameras(device, client, &reolink.DeviceInfo{Name: "Front", Model: "RLC-810A", ChannelCount: 1}, nil)
+
+	msgs := readLifecycle(t, &out)
+	if len(msgs) != 1 || msgs[0].Method != NotifyCameraAdded {
+		t.Fatalf("Expected one camera.added notification, got %+v", msgs)
+	}
+	cam := msgs[0].Params.Camera
+	if cam == nil || cam.ID != "192.168.1.100_ch0" || cam.Protocol != "rtmp" {
+		t.Errorf("Expected the added camera's details, got %+v", cam)
+	}
+}
+
+func TestPlugin_CheckConnectivity_NotifiesOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	client := newTestClient(server)
+	client.UseURLAuth()
+	client.SetRetryPolicy(reolink.RetryPolicy{MaxAttempts: 1})
+	server.Close()
+
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	cam := NewCamera("cam_1", "Front", "RLC-810A", "localhost", 0, client)
+	cam.lastSeen = time.Now().Add(-time.Hour)
+	plugin.cameras["cam_1"] = cam
+
+	plugin.checkConnectivity(context.Background(), time.Minute)
+	plugin.checkConnectivity(context.Background(), time.Minute)
+
+	msgs := readLifecycle(t, &out)
+	if len(msgs) != 1 || msgs[0].Method != NotifyCameraOffline || msgs[0].Params.LastSeen == "" {
+		t.Errorf("Expected a single camera.offline notification, got %+v", msgs)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// parentCheckInterval is how often the plugin checks that its host is alive
+const parentCheckInterval = 5 * time.Second
+
+// touchHost records that a message arrived from the host
+func (p *Plugin) touchHost() {
+	p.lastHostMessage.Store(time.Now().UnixNano())
+}
+
+// watchHost returns a channel that receives a reason once the host is gone:
+// the parent process exited without closing stdin, so the plugin was
+// reparented, or no message arrived within the heartbeat timeout.
+func (p *Plugin) watchHost(ctx context.Context, interval time.Duration, getppid func() int) <-chan string {
+	gone := make(chan string, 1)
+	parent := getppid()
+	p.touchHost()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if ppid := getppid(); ppid != parent {
+				gone <- fmt.Sprintf("parent process %d exited (now %d)", parent, ppid)
+				return
+			}
+			timeout := time.Duration(p.heartbeatTimeout.Load())
+			if timeout <= 0 {
+				continue
+			}
+			if quiet := time.Since(time.Unix(0, p.lastHostMessage.Load())); quiet > timeout {
+				gone <- fmt.Sprintf("no message from host for %s", quiet.Round(time.Second))
+				return
+			}
+		}
+	}()
+	return gone
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchHost_ParentExit(t *testing.T) {
+	var ppid atomic.Int64
+	ppid.Store(100)
+	getppid := func() int { return int(ppid.Load()) }
+
+	plugin := NewPlugin()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gone := plugin.watchHost(ctx, 10*time.Millisecond, getppid)
+
+	select {
+	case reason := <-gone:
+		t.Fatalf("Unexpected exit while the parent is alive: %s", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Orphaned processes are reparented, typically to init
+	ppid.Store(1)
+	select {
+	case reason := <-gone:
+		if !strings.Contains(reason, "parent process 100") {
+			t.Errorf("Unexpected reason: %s", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the parent exit to be detected")
+	}
+}
+
+func TestWatchHost_Heartbeat(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.heartbeatTimeout.Store(int64(100 * time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gone := plugin.watchHost(ctx, 10*time.Millisecond, func() int { return 100 })
+
+	// Messages from the host keep the plugin alive
+	deadline := time.Now().Add(250 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		plugin.touchHost()
+		select {
+		case reason := <-gone:
+			t.Fatalf("Unexpected exit while the host is active: %s", reason)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	select {
+	case reason := <-gone:
+		if !strings.Contains(reason, "no message from host") {
+			t.Errorf("Unexpected reason: %s", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the heartbeat deadline to expire")
+	}
+}
+
+func TestWatchHost_Stop(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.heartbeatTimeout.Store(int64(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	gone := plugin.watchHost(ctx, 10*time.Millisecond, func() int { return 100 })
+	cancel()
+
+	// Cancelling may race with the first tick, so only require that the
+	// watcher does not report after it has stopped
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-gone:
+	default:
+	}
+	select {
+	case reason := <-gone:
+		t.Errorf("Unexpected report after cancel: %s", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
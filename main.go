@@ -3,47 +3,87 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
 )
 
 func main() {
+	// Diagnostic subcommands talk to a device directly and exit
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	pprofFlag := flag.String("pprof", "", "serve net/http/pprof on this localhost address, e.g. localhost:6060")
+	interactive := flag.Bool("interactive", false, "read commands from a terminal and pretty-print responses")
+	methodPrefix := flag.String("method-prefix", "", "prefix the host adds to method names, e.g. reolink.")
+	restFlag := flag.String("rest", "", "serve the REST gateway on this address, e.g. localhost:8080")
+	restToken := flag.String("rest-token", "", "bearer token the REST gateway requires; needed for non-localhost addresses")
+	flag.Parse()
+
 	log.SetOutput(os.Stderr)
 	log.Println("Reolink plugin starting...")
 
 	plugin := NewPlugin()
+	plugin.SetOutput(os.Stdout)
+	plugin.methodPrefix = *methodPrefix
 
-	// Read JSON-RPC requests from stdin, write responses to stdout
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
-
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+	if *pprofFlag != "" {
+		if err := plugin.startPprof(*pprofFlag); err != nil {
+			log.Printf("Failed to start pprof: %v", err)
 		}
+	}
 
-		var req JSONRPCRequest
-		if err := json.Unmarshal(line, &req); err != nil {
-			log.Printf("Failed to parse request: %v", err)
-			continue
+	if *restFlag != "" {
+		if err := plugin.startREST(*restFlag, *restToken); err != nil {
+			log.Printf("Failed to start REST gateway: %v", err)
 		}
+	}
 
-		resp := plugin.HandleRequest(req)
-		respBytes, _ := json.Marshal(resp)
-		fmt.Println(string(respBytes))
+	if *interactive {
+		if err := runREPL(plugin, os.Stdin, os.Stdout); err != nil {
+			log.Printf("Interactive mode error: %v", err)
+		}
+		_ = plugin.Shutdown(context.Background())
+		return
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("Scanner error: %v", err)
+	// Exit if the host dies without closing stdin, rather than polling forever
+	go func() {
+		reason := <-plugin.watchHost(context.Background(), parentCheckInterval, os.Getppid)
+		log.Printf("Host is gone (%s), shutting down", reason)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = plugin.Shutdown(ctx)
+		os.Exit(0)
+	}()
+
+	// Read JSON-RPC requests from stdin, write responses to stdout
+	if err := plugin.serve(os.Stdin); err != nil {
+		log.Printf("Failed to read requests: %v", err)
 	}
 
+	// A host using the REST gateway may not keep stdin open, so keep serving
+	// it until the process is told to stop
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	plugin.waitREST(sigCtx)
+	stop()
+
 	log.Println("Reolink plugin shutting down...")
 }
 
@@ -57,11 +97,28 @@ type JSONRPCRequest struct {
 
 type JSONRPCResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
-	ID      interface{}     `json:"id,omitempty"`
+	ID      interface{}     `json:"id"`
 	Result  interface{}     `json:"result,omitempty"`
 	Error   *JSONRPCError   `json:"error,omitempty"`
 }
 
+// MarshalJSON writes exactly one of "result" and "error", keeping a null
+// result on success as the JSON-RPC spec requires
+func (r JSONRPCResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string        `json:"jsonrpc"`
+			ID      interface{}   `json:"id"`
+			Error   *JSONRPCError `json:"error"`
+		}{r.JSONRPC, r.ID, r.Error})
+	}
+	return json.Marshal(struct {
+		JSONRPC string      `json:"jsonrpc"`
+		ID      interface{} `json:"id"`
+		Result  interface{} `json:"result"`
+	}{r.JSONRPC, r.ID, r.Result})
+}
+
 type JSONRPCError struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
@@ -83,15 +140,151 @@ type Plugin struct {
 	settingsProtocol string // "hls", "rtsp", "rtmp"
 	probeResult      *ProbeResultSettings
 	selectedChannels []int
+
+	// Persisted state (zoom presets); store is nil unless state_dir is
+	// configured, and encrypted if state_key is
+	state *pluginState
+	store *stateStore
+
+	// Output for responses and notifications, shared by chunked transfers
+	out       io.Writer
+	outMu     sync.Mutex
+	transfers *transferManager
+
+	// deviceErrors holds the last connection error per configured host
+	deviceErrors map[string]string
+
+	// locale is the language of the error details in JSON-RPC error data
+	locale string
+
+	// Event polling; eventInterval of 0 disables polling
+	events        *eventQueue
+	analytics     *eventAnalytics
+	eventInterval time.Duration
+
+	// One worker per device serializes its requests and periodic tasks
+	workers      map[*reolink.Client]*deviceWorker
+	offlineAfter time.Duration
+
+	// Encoder settings refresh; metadataInterval of 0 disables it
+	metadataInterval time.Duration
+
+	// Running timelapses by camera ID
+	timelapses map[string]*timelapseJob
+
+	// Snapshot sinks of the plugin config; stopSinks cancels them all
+	snapshotSinks []*snapshotSink
+	stopSinks     context.CancelFunc
+
+	// Webhooks of the plugin config; stopWebhooks cancels them all
+	webhooks     []*webhook
+	stopWebhooks context.CancelFunc
+
+	// Subnets searched for devices that moved to another address; trackedAt
+	// holds the last automatic search for each device, and trackMu lets one
+	// search run at a time. A device answering there is recognized by the
+	// MAC in arpTable before it is logged in to.
+	discoverySubnets []string
+	trackedAt        map[*reolink.Client]time.Time
+	trackMu          sync.Mutex
+	arpTable         string
+
+	// Snapshot bursts captured on events, for get_event_media
+	bursts eventBursts
+
+	// Pending alarm output releases by device and port
+	alarmPulses map[alarmPulseKey]*alarmPulse
+
+	// tracer exports request spans; nil unless tracing is configured
+	tracer *tracer
+
+	// pprof serves profiles on localhost when enabled
+	pprof *http.Server
+
+	// rest is the REST gateway, when enabled
+	rest *restGateway
+
+	// scrypted serves the Scrypted-style adapter on localhost when enabled
+	scrypted *http.Server
+
+	// onvif turns forwarded ONVIF notifications into events
+	onvif onvifBridge
+
+	// maxSnapshotSize is applied to every device client; 0 uses the default
+	maxSnapshotSize int64
+
+	// methodPrefix is stripped from incoming method names, e.g. "reolink."
+	methodPrefix string
+
+	// ns is the plugin ID and camera ID prefix of the plugin config; nil
+	// until configured
+	ns atomic.Pointer[namespace]
+
+	// allowRawCommands enables raw_command, which passes API commands to
+	// devices unchecked
+	allowRawCommands bool
+
+	// profiles are the named provisioning profiles of the plugin config
+	profiles map[string]ProvisionProfile
+
+	// dryRunAll turns every operation with a dry_run option into a dry run
+	dryRunAll bool
+
+	// readOnly refuses requests that change cameras, except PTZ moves if
+	// readOnlyAllowPTZ is set
+	readOnly         bool
+	readOnlyAllowPTZ bool
+
+	// Host liveness: unix nanoseconds of the last message, and how long the
+	// host may stay silent before the plugin exits (0 disables the deadline)
+	lastHostMessage  atomic.Int64
+	heartbeatTimeout atomic.Int64
 }
 
 type DeviceConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port,omitempty"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Channels []int  `json:"channels,omitempty"`
-	Name     string `json:"name,omitempty"`
+	Host     string                 `json:"host"`
+	Port     int                    `json:"port,omitempty"`
+	Username string                 `json:"username"`
+	Password string                 `json:"password"`
+	Channels []int                  `json:"channels,omitempty"`
+	Name     string                 `json:"name,omitempty"`
+	Protocol string                 `json:"protocol,omitempty"`
+	Retry    *reolink.RetryConfig   `json:"retry,omitempty"`
+	Timeouts *reolink.TimeoutConfig `json:"timeouts,omitempty"`
+	TLS      *reolink.TLSConfig     `json:"tls,omitempty"`
+
+	// FallbackHosts are other addresses of the device, e.g. the VPN address
+	// of a camera with a second network interface. When the active address
+	// stops answering, requests move to the first of Host and these that does.
+	FallbackHosts []string `json:"fallback_hosts,omitempty"`
+
+	// Proxy is an http://, https:// or socks5:// URL the device's API is
+	// reached through, e.g. a jump host or a Tailscale SOCKS endpoint
+	Proxy string `json:"proxy,omitempty"`
+
+	// PowerSaving stops event polling, metadata refresh and frequent health
+	// checks for the device's cameras; unset enables it for battery models
+	PowerSaving *bool `json:"power_saving,omitempty"`
+
+	// MaxConcurrent caps the requests in flight to the device; 0 uses the default
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+
+	// StreamUsername is a guest account created on the device for stream
+	// URLs, so a leaked URL does not expose the admin credentials. Without
+	// StreamPassword a random password is set on every connect.
+	StreamUsername string `json:"stream_username,omitempty"`
+	StreamPassword string `json:"stream_password,omitempty"`
+
+	// CapabilityOverrides force capabilities of the device's cameras on or
+	// off when detection gets a model wrong, e.g. {"ai_detection": false}
+	CapabilityOverrides map[string]bool `json:"capability_overrides,omitempty"`
+
+	// StreamTemplates replace the built-in stream URLs, for firmware with
+	// nonstandard paths
+	StreamTemplates *reolink.StreamTemplates `json:"stream_templates,omitempty"`
+
+	// Snapshot sets the quality and size of the device's snapshots
+	Snapshot *reolink.SnapshotOptions `json:"snapshot,omitempty"`
 }
 
 type CameraConfig struct {
@@ -102,7 +295,16 @@ type CameraConfig struct {
 	Channel  int                    `json:"channel,omitempty"`
 	Name     string                 `json:"name,omitempty"`
 	Protocol string                 `json:"protocol,omitempty"` // "hls" (default), "rtsp", or "rtmp"
+	Replace  bool                   `json:"replace,omitempty"`  // re-create the camera if it already exists
 	Extra    map[string]interface{} `json:"extra,omitempty"`
+
+	// StreamUsername and StreamPassword provision a guest account for
+	// stream URLs, as in DeviceConfig
+	StreamUsername string `json:"stream_username,omitempty"`
+	StreamPassword string `json:"stream_password,omitempty"`
+
+	// CapabilityOverrides force capabilities on or off, as in DeviceConfig
+	CapabilityOverrides map[string]bool `json:"capability_overrides,omitempty"`
 }
 
 type PluginCamera struct {
@@ -118,19 +320,60 @@ type PluginCamera struct {
 	Online       bool     `json:"online"`
 	LastSeen     string   `json:"last_seen"`
 	Protocol     string   `json:"protocol"` // "hls", "rtsp", or "rtmp"
+
+	// ActiveHost is the address the device is reached at, for devices with
+	// fallback_hosts; the stream URLs use it
+	ActiveHost string `json:"active_host,omitempty"`
+
+	// Inventory data of the device the camera belongs to, so the host can
+	// deduplicate devices added under different addresses
+	Serial          string `json:"serial,omitempty"`
+	MAC             string `json:"mac,omitempty"`
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+
+	// Network is the device's addressing (DHCP or static, gateway, DNS),
+	// telling devices apart that sit behind the same address on other subnets
+	Network *reolink.LocalLink `json:"network,omitempty"`
+
+	// Features is the structured, versioned form of Capabilities
+	Features *CapabilitySet `json:"features"`
+
+	// Methods tells, for the camera methods only some cameras support,
+	// whether this one does; omitted until its abilities have been read
+	Methods map[string]bool `json:"methods,omitempty"`
+
+	// APIVersion is the HTTP API revision reported by the firmware, if any
+	APIVersion int `json:"api_version,omitempty"`
+
+	// PowerSaving is set for cameras spared periodic polling, usually battery models
+	PowerSaving bool `json:"power_saving,omitempty"`
+
+	// Lens is the stitch or dewarp mode of Duo and Fisheye cameras
+	Lens *LensMode `json:"lens,omitempty"`
+
+	// Orientation is the image rotation and mirroring, once read or set
+	Orientation *Orientation `json:"orientation,omitempty"`
+
+	// Encoder holds the stream resolutions and bitrates last read from the
+	// device; refreshed periodically and after encoder changes
+	Encoder *reolink.EncoderConfig `json:"encoder,omitempty"`
+
+	// AlreadyExists is set by add_camera when the camera was already added
+	AlreadyExists bool `json:"already_exists,omitempty"`
 }
 
 type DiscoveredCamera struct {
-	ID              string   `json:"id"`
-	Name            string   `json:"name"`
-	Model           string   `json:"model"`
-	Manufacturer    string   `json:"manufacturer"`
-	Host            string   `json:"host"`
-	Port            int      `json:"port"`
-	Channels        int      `json:"channels"`
-	Capabilities    []string `json:"capabilities"`
-	FirmwareVersion string   `json:"firmware_version,omitempty"`
-	Serial          string   `json:"serial,omitempty"`
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Model           string            `json:"model"`
+	Manufacturer    string            `json:"manufacturer"`
+	Host            string            `json:"host"`
+	Port            int               `json:"port"`
+	Channels        int               `json:"channels"`
+	Capabilities    []string          `json:"capabilities"`
+	FirmwareVersion string            `json:"firmware_version,omitempty"`
+	Serial          string            `json:"serial,omitempty"`
+	Storage         []reolink.HddInfo `json:"storage,omitempty"`
 }
 
 type HealthStatus struct {
@@ -181,44 +424,104 @@ type ChannelInfoSettings struct {
 }
 
 type PTZCommand struct {
-	Action    string  `json:"action"`
-	Direction float64 `json:"direction,omitempty"`
-	Speed     float64 `json:"speed,omitempty"`
-	Preset    string  `json:"preset,omitempty"`
+	Action    string   `json:"action"`
+	Direction float64  `json:"direction,omitempty"`
+	Speed     float64  `json:"speed,omitempty"`
+	Preset    string   `json:"preset,omitempty"`
+	Area      *PTZArea `json:"area,omitempty"` // For "area_zoom"
+}
+
+// PTZArea is a rectangle in normalized image coordinates (0..1, origin top-left)
+type PTZArea struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
 }
 
 func NewPlugin() *Plugin {
 	return &Plugin{
-		cameras: make(map[string]*Camera),
+		cameras:      make(map[string]*Camera),
+		state:        newPluginState(),
+		transfers:    newTransferManager(),
+		deviceErrors: make(map[string]string),
+		events:       newEventQueue(),
+		analytics:    newEventAnalytics(),
+		workers:      make(map[*reolink.Client]*deviceWorker),
+		timelapses:   make(map[string]*timelapseJob),
+		alarmPulses:  make(map[alarmPulseKey]*alarmPulse),
+		trackedAt:    make(map[*reolink.Client]time.Time),
+		arpTable:     "/proc/net/arp",
 	}
 }
 
-func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
+// HandleRequest handles one request. A panic in a handler is logged and
+// answered with an internal error instead of crashing the plugin.
+func (p *Plugin) HandleRequest(req JSONRPCRequest) (resp JSONRPCResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			ref := logPanic("request "+req.Method, r)
+			resp = JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   &JSONRPCError{Code: -32603, Message: "Internal error (log ref " + ref + ")"},
+			}
+		}
+	}()
+	return p.handleRequest(req)
+}
+
+func (p *Plugin) handleRequest(req JSONRPCRequest) JSONRPCResponse {
 	resp := JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
 	}
+	requested := req.Method
+	req.Method = canonicalMethod(req.Method, p.methodPrefix)
 
 	ctx := context.Background()
 	if p.ctx != nil {
 		ctx = p.ctx
 	}
 
+	// The plugin context is derived from the initialize context, so that
+	// request is not traced
+	if req.Method != "initialize" {
+		var reqSpan *span
+		ctx, reqSpan = p.tracer.startRequestSpan(ctx, req)
+		defer func() {
+			if resp.Error != nil {
+				reqSpan.End(errors.New(resp.Error.Message))
+			} else {
+				reqSpan.End(nil)
+			}
+		}()
+	}
+
+	if err := p.checkReadOnly(req.Method, req.Params); err != nil {
+		resp.Error = p.internalError(err)
+		resp.Error.Message = req.Method + " " + resp.Error.Message
+		return resp
+	}
+
 	switch req.Method {
 	case "initialize":
 		var config map[string]interface{}
 		if req.Params != nil {
 			_ = json.Unmarshal(req.Params, &config)
 		}
-		if err := p.Initialize(ctx, config); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+		var cfgErr *ConfigError
+		if err := p.Initialize(ctx, config); errors.As(err, &cfgErr) {
+			resp.Error = &JSONRPCError{Code: -32602, Message: err.Error(), Data: cfgErr.Errors}
+		} else if err != nil {
+			resp.Error = p.internalError(err)
 		} else {
-			resp.Result = map[string]interface{}{"status": "ok"}
+			resp.Result = p.InitializeReport()
 		}
 
 	case "shutdown":
 		if err := p.Shutdown(ctx); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = map[string]interface{}{"status": "ok"}
 		}
@@ -226,10 +529,26 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 	case "health":
 		resp.Result = p.Health()
 
+	case "get_device_health":
+		var params struct {
+			Host string `json:"host"`
+		}
+		if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if devices, err := p.GetDeviceHealth(params.Host); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = devices
+		}
+
 	case "discover_cameras":
-		cameras, err := p.DiscoverCameras(ctx)
-		if err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+		var params struct {
+			Subnets []string `json:"subnets"`
+		}
+		if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if cameras, err := p.DiscoverCameras(ctx, params.Subnets...); err != nil {
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = cameras
 		}
@@ -241,7 +560,7 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		} else {
 			cam, err := p.AddCamera(ctx, config)
 			if err != nil {
-				resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+				resp.Error = p.internalError(err)
 			} else {
 				resp.Result = cam
 			}
@@ -254,7 +573,7 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
 		} else if err := p.RemoveCamera(ctx, params.CameraID); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = map[string]interface{}{"status": "ok"}
 		}
@@ -271,7 +590,7 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		} else if cam := p.GetCamera(params.CameraID); cam != nil {
 			resp.Result = cam
 		} else {
-			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found"}
+			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found", Data: p.errorData(errCameraNotFound)}
 		}
 
 	case "update_camera":
@@ -281,13 +600,25 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		}
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params: " + err.Error()}
-		} else if err := p.UpdateCamera(params.CameraID, params.Settings); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+		} else if err := p.UpdateCamera(ctx, params.CameraID, params.Settings); err != nil {
+			resp.Error = p.internalError(err)
 		} else {
 			// Return updated camera info
 			resp.Result = p.GetCamera(params.CameraID)
 		}
 
+	case "sync_channel_names":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if renamed, err := p.SyncChannelNames(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"renamed": renamed}
+		}
+
 	case "ptz_control":
 		var params struct {
 			CameraID string     `json:"camera_id"`
@@ -296,7 +627,7 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
 		} else if err := p.PTZControl(ctx, params.CameraID, params.Command); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = map[string]interface{}{"status": "ok"}
 		}
@@ -304,15 +635,32 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 	case "get_snapshot":
 		var params struct {
 			CameraID string `json:"camera_id"`
+			Path     string `json:"path"` // optional directory to write the JPEG into
 		}
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if params.Path != "" {
+			if file, err := p.SaveSnapshot(ctx, params.CameraID, params.Path); err != nil {
+				resp.Error = p.internalError(err)
+			} else {
+				resp.Result = file
+			}
 		} else if data, err := p.GetSnapshot(ctx, params.CameraID); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = data // base64 encoded
 		}
 
+	case "set_snapshot_options":
+		var params SnapshotOptionsRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if opts, err := p.SetSnapshotOptions(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = opts
+		}
+
 	case "probe_camera":
 		var params struct {
 			Host     string `json:"host"`
@@ -325,12 +673,22 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		} else {
 			result, err := p.ProbeCamera(ctx, params.Host, params.Port, params.Username, params.Password)
 			if err != nil {
-				resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+				resp.Error = p.internalError(err)
 			} else {
 				resp.Result = result
 			}
 		}
 
+	case "classify_model":
+		var params ClassifyModelRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := ClassifyModel(params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
+
 	case "get_capabilities":
 		var params struct {
 			CameraID string `json:"camera_id"`
@@ -340,7 +698,31 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		} else if caps := p.GetCapabilities(params.CameraID); caps != nil {
 			resp.Result = caps
 		} else {
-			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found"}
+			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found", Data: p.errorData(errCameraNotFound)}
+		}
+
+	case "get_ability":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if ability, err := p.GetAbility(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = ability
+		}
+
+	case "refresh_camera":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if caps, err := p.RefreshCamera(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = caps
 		}
 
 	case "get_ptz_presets":
@@ -352,12 +734,62 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		} else {
 			presets, err := p.GetPTZPresets(ctx, params.CameraID)
 			if err != nil {
-				resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+				resp.Error = p.internalError(err)
 			} else {
 				resp.Result = presets
 			}
 		}
 
+	case "save_zoom_preset":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			Name     string `json:"name"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if preset, err := p.SaveZoomPreset(ctx, params.CameraID, params.Name); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = preset
+		}
+
+	case "get_zoom_focus":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if status, err := p.GetZoomFocus(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = status
+		}
+
+	case "autofocus":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if status, err := p.Autofocus(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = status
+		}
+
+	case "delete_zoom_preset":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			Name     string `json:"name"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.DeleteZoomPreset(params.CameraID, params.Name); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
 	case "get_protocols":
 		var params struct {
 			CameraID string `json:"camera_id"`
@@ -367,7 +799,46 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		} else if protocols := p.GetProtocols(params.CameraID); protocols != nil {
 			resp.Result = protocols
 		} else {
-			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found"}
+			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found", Data: p.errorData(errCameraNotFound)}
+		}
+
+	case "get_stream_profiles":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if profiles, err := p.GetStreamProfiles(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = profiles
+		}
+
+	case "get_audio_stream":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if audio, err := p.GetAudioStream(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = audio
+		}
+
+	case "set_lens_mode":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			Stitch   *bool  `json:"stitch"`
+			ViewMode string `json:"view_mode"`
+			Mount    string `json:"mount"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if mode, err := p.SetLensMode(params.CameraID, params.Stitch, params.ViewMode, params.Mount); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = mode
 		}
 
 	case "set_protocol":
@@ -378,7 +849,7 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
 		} else if err := p.SetProtocol(params.CameraID, params.Protocol); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = p.GetCamera(params.CameraID)
 		}
@@ -389,57 +860,784 @@ func (p *Plugin) HandleRequest(req JSONRPCRequest) JSONRPCResponse {
 		}
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
-		} else if info := p.GetDeviceInfo(params.CameraID); info != nil {
+		} else if info := p.GetDeviceInfo(ctx, params.CameraID); info != nil {
 			resp.Result = info
 		} else {
-			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found"}
+			resp.Error = &JSONRPCError{Code: -32603, Message: "Camera not found", Data: p.errorData(errCameraNotFound)}
 		}
 
-	case "get_settings":
-		resp.Result = p.GetSettings()
+	case "list_users":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if users, err := p.ListUsers(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = users
+		}
 
-	case "put_setting":
+	case "add_user", "modify_user":
 		var params struct {
-			Key   string      `json:"key"`
-			Value interface{} `json:"value"`
+			CameraID string `json:"camera_id"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Level    string `json:"level"`
 		}
 		if err := json.Unmarshal(req.Params, &params); err != nil {
-			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params: " + err.Error()}
-		} else if err := p.PutSetting(ctx, params.Key, params.Value); err != nil {
-			resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.SaveUser(ctx, params.CameraID, req.Method == "add_user", params.Username, params.Password, params.Level); err != nil {
+			resp.Error = p.internalError(err)
 		} else {
 			resp.Result = map[string]interface{}{"status": "ok"}
 		}
 
-	default:
-		resp.Error = &JSONRPCError{Code: -32601, Message: "Method not found: " + req.Method}
-	}
+	case "delete_user":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			Username string `json:"username"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.DeleteUser(ctx, params.CameraID, params.Username); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
 
-	return resp
-}
+	case "rotate_password":
+		var params struct {
+			CameraID    string `json:"camera_id"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.RotatePassword(ctx, params.CameraID, params.NewPassword); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
 
-func (p *Plugin) Initialize(ctx context.Context, config map[string]interface{}) error {
-	p.ctx, p.cancel = context.WithCancel(ctx)
+	case "configure_network":
+		var params NetworkRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if p.dryRun(params.DryRun) {
+			if plan, err := p.PlanNetworkChange(params); err != nil {
+				resp.Error = p.internalError(err)
+			} else {
+				resp.Result = plan
+			}
+		} else if result, err := p.ConfigureNetwork(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
 
-	if err := p.parseConfig(config); err != nil {
-		return err
-	}
+	case "scan_wifi":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if networks, err := p.ScanWifi(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = networks
+		}
 
-	// Connect to configured devices
-	for _, device := range p.devices {
-		if err := p.connectDevice(device); err != nil {
-			log.Printf("Failed to connect to device %s: %v", device.Host, err)
+	case "get_wifi":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if status, err := p.GetWifi(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = status
 		}
-	}
 
-	log.Printf("Plugin initialized with %d devices", len(p.devices))
-	return nil
-}
+	case "set_wifi":
+		var params WifiRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.SetWifi(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
 
-// GetSettings returns the declarative settings UI for the plugin
-func (p *Plugin) GetSettings() []Setting {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	case "list_sessions":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if sessions, err := p.ListSessions(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = sessions
+		}
+
+	case "disconnect_session":
+		var params struct {
+			CameraID  string `json:"camera_id"`
+			Username  string `json:"username"`
+			SessionID int    `json:"session_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.DisconnectSession(ctx, params.CameraID, params.Username, params.SessionID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "get_light":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if light, err := p.GetLight(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = light
+		}
+
+	case "set_light":
+		var params struct {
+			CameraID   string `json:"camera_id"`
+			On         *bool  `json:"on"`
+			Brightness int    `json:"brightness"`
+			Duration   int    `json:"duration"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.SetLight(ctx, params.CameraID, params.On, params.Brightness, params.Duration); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "set_light_schedule":
+		var params struct {
+			CameraID string                `json:"camera_id"`
+			Mode     string                `json:"mode"`
+			Schedule reolink.LightSchedule `json:"schedule"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.SetLightSchedule(ctx, params.CameraID, params.Mode, params.Schedule); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "get_encoder_settings":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if settings, err := p.GetEncoderSettings(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = settings
+		}
+
+	case "set_encoder_settings":
+		var params EncoderSettingsRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if p.dryRun(params.DryRun) {
+			if plan, err := p.PlanEncoderSettings(ctx, params); err != nil {
+				resp.Error = p.internalError(err)
+			} else {
+				resp.Result = plan
+			}
+		} else if settings, err := p.SetEncoderSettings(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = settings
+		}
+
+	case "provision_camera":
+		var params ProvisionRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.ProvisionCamera(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
+
+	case "get_image_settings":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if settings, err := p.GetImageSettings(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = settings
+		}
+
+	case "set_image_settings":
+		var params struct {
+			CameraID string                `json:"camera_id"`
+			Settings reolink.ImageSettings `json:"settings"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if settings, err := p.SetImageSettings(ctx, params.CameraID, params.Settings); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = settings
+		}
+
+	case "configure_privacy_mask":
+		var params PrivacyMaskRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if mask, err := p.ConfigurePrivacyMask(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = mask
+		}
+
+	case "configure_siren":
+		var params SirenRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if cfg, err := p.ConfigureSiren(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = cfg
+		}
+
+	case "get_alarm_inputs":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if inputs, err := p.GetAlarmInputs(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = inputs
+		}
+
+	case "alarm_output":
+		var params AlarmOutputRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if outputs, err := p.AlarmOutput(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = outputs
+		}
+
+	case "list_audio_clips":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if clips, err := p.ListAudioClips(ctx, params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = clips
+		}
+
+	case "upload_audio_clip":
+		var params struct {
+			CameraID   string `json:"camera_id"`
+			Name       string `json:"name"`
+			Data       string `json:"data"`        // base64 encoded WAV or MP3
+			TransferID string `json:"transfer_id"` // alternative to data: completed chunked transfer
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.UploadAudioClip(ctx, params.CameraID, params.Name, params.Data, params.TransferID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "talk":
+		var params struct {
+			CameraID   string `json:"camera_id"`
+			Data       string `json:"data"`        // base64 encoded 16-bit PCM mono WAV
+			TransferID string `json:"transfer_id"` // alternative to data: completed chunked transfer
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.Talk(ctx, params.CameraID, params.Data, params.TransferID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
+
+	case "select_audio_clip":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			ID       int    `json:"id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.SelectAudioClip(ctx, params.CameraID, params.ID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "transfer.begin":
+		var params TransferBegin
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.transfers.Begin(params.Name, params.Size, params.SHA256); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: err.Error()}
+		} else {
+			resp.Result = result
+		}
+
+	case "transfer.chunk":
+		var params TransferChunk
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.transfers.Chunk(params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: err.Error()}
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "transfer.end":
+		var params TransferEnd
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.transfers.End(params.TransferID, params.SHA256); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: err.Error()}
+		} else {
+			resp.Result = result
+		}
+
+	case "download_clip":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			Source   string `json:"source"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if result, err := p.DownloadClip(ctx, params.CameraID, params.Source); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
+
+	case "upgrade_firmware":
+		var params struct {
+			CameraID   string `json:"camera_id"`
+			TransferID string `json:"transfer_id"`
+			DryRun     bool   `json:"dry_run"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if p.dryRun(params.DryRun) {
+			if plan, err := p.PlanFirmwareUpgrade(params.CameraID, params.TransferID); err != nil {
+				resp.Error = p.internalError(err)
+			} else {
+				resp.Result = plan
+			}
+		} else if err := p.UpgradeFirmware(ctx, params.CameraID, params.TransferID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "upgrading"}
+		}
+
+	case "raw_command":
+		var params struct {
+			CameraID string               `json:"camera_id"`
+			Commands []reolink.RawCommand `json:"commands"`
+			DryRun   bool                 `json:"dry_run"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if p.dryRun(params.DryRun) {
+			if plan, err := p.PlanRawCommand(params.CameraID, params.Commands); err != nil {
+				resp.Error = p.internalError(err)
+			} else {
+				resp.Result = plan
+			}
+		} else if result, err := p.RawCommand(ctx, params.CameraID, params.Commands); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = result
+		}
+
+	case "get_events":
+		var params struct {
+			Since    uint64 `json:"since"`
+			CameraID string `json:"camera_id"`
+			Limit    int    `json:"limit"`
+			WaitMs   int    `json:"wait_ms"`
+		}
+		if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil || params.WaitMs < 0 {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else {
+			wait := time.Duration(params.WaitMs) * time.Millisecond
+			resp.Result = p.WaitEvents(ctx, params.Since, params.CameraID, params.Limit, wait)
+		}
+
+	case "get_event_media":
+		var params struct {
+			EventID string `json:"event_id"`
+			WaitMs  int    `json:"wait_ms"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.WaitMs < 0 {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if media, err := p.GetEventMedia(ctx, params.EventID, time.Duration(params.WaitMs)*time.Millisecond); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = media
+		}
+
+	case "emit_test_event":
+		var params TestEventRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if events, err := p.EmitTestEvent(ctx, params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = events
+		}
+
+	case "onvif_notify":
+		var params ONVIFNotifyRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.XML == "" {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if events, err := p.HandleONVIFNotify(params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = events
+		}
+
+	case "get_event_summary":
+		var params struct {
+			CameraID string `json:"camera_id"`
+			Hours    int    `json:"hours"`
+		}
+		if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if summary, err := p.GetEventSummary(params.CameraID, params.Hours); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = summary
+		}
+
+	case "start_timelapse":
+		var cfg TimelapseConfig
+		if err := json.Unmarshal(req.Params, &cfg); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if status, err := p.StartTimelapse(cfg); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = status
+		}
+
+	case "stop_timelapse":
+		var params struct {
+			CameraID string `json:"camera_id"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if err := p.StopTimelapse(params.CameraID); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	case "list_timelapses":
+		resp.Result = p.ListTimelapses()
+
+	case "list_snapshot_sinks":
+		resp.Result = p.ListSnapshotSinks()
+
+	case "list_webhooks":
+		resp.Result = p.ListWebhooks()
+
+	case "get_ha_discovery":
+		var params HADiscoveryRequest
+		if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params"}
+		} else if messages, err := p.GetHADiscovery(params); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = messages
+		}
+
+	case "get_settings":
+		resp.Result = p.GetSettings()
+
+	case "put_setting":
+		var params struct {
+			Key   string      `json:"key"`
+			Value interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &JSONRPCError{Code: -32602, Message: "Invalid params: " + err.Error()}
+		} else if err := p.PutSetting(ctx, params.Key, params.Value); err != nil {
+			resp.Error = p.internalError(err)
+		} else {
+			resp.Result = map[string]interface{}{"status": "ok"}
+		}
+
+	default:
+		resp.Error = &JSONRPCError{Code: -32601, Message: "Method not found: " + requested}
+	}
+
+	return resp
+}
+
+func (p *Plugin) Initialize(ctx context.Context, config map[string]interface{}) error {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+
+	if err := p.parseConfig(config); err != nil {
+		return err
+	}
+
+	if stateDir, ok := config["state_dir"].(string); ok && stateDir != "" {
+		store := newStateStore(stateDir)
+		if key, ok := config["state_key"].(string); ok && key != "" {
+			var err error
+			if store, err = newEncryptedStateStore(stateDir, key); err != nil {
+				cfgErr := &ConfigError{}
+				cfgErr.add("state_key", "%v", err)
+				return cfgErr
+			}
+		}
+		state, err := store.Load()
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.store = store
+		p.state = state
+		p.mu.Unlock()
+	}
+
+	if v, ok := config["heartbeat_timeout_ms"].(float64); ok && v >= 0 {
+		p.heartbeatTimeout.Store(int64(time.Duration(v) * time.Millisecond))
+	}
+
+	if prefix, ok := config["method_prefix"].(string); ok {
+		p.methodPrefix = prefix
+	}
+
+	locale, err := parseLocale(config)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.locale = locale
+	p.mu.Unlock()
+
+	ns, err := parseNamespace(config)
+	if err != nil {
+		return err
+	}
+	p.ns.Store(&ns)
+
+	overrides, err := parseModelOverrides(config)
+	if err != nil {
+		return err
+	}
+	if err := reolink.SetModelOverrides(overrides); err != nil {
+		return err
+	}
+
+	profiles, err := parseProvisionProfiles(config)
+	if err != nil {
+		return err
+	}
+
+	sinks, err := parseSnapshotSinks(config)
+	if err != nil {
+		return err
+	}
+
+	burst, err := parseEventBurst(config)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := parseWebhooks(config)
+	if err != nil {
+		return err
+	}
+
+	subnets, err := parseDiscoverySubnets(config)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.profiles = profiles
+	p.discoverySubnets = subnets
+	p.mu.Unlock()
+
+	if allow, ok := config["allow_raw_commands"].(bool); ok {
+		p.mu.Lock()
+		p.allowRawCommands = allow
+		p.mu.Unlock()
+	}
+
+	if readOnly, ok := config["read_only"].(bool); ok {
+		allowPTZ, _ := config["read_only_allow_ptz"].(bool)
+		p.mu.Lock()
+		p.readOnly = readOnly
+		p.readOnlyAllowPTZ = allowPTZ
+		p.mu.Unlock()
+		if readOnly {
+			log.Printf("Read-only mode: requests that change cameras are refused (PTZ allowed: %v)", allowPTZ)
+		}
+	}
+
+	if dryRun, ok := config["dry_run"].(bool); ok {
+		p.mu.Lock()
+		p.dryRunAll = dryRun
+		p.mu.Unlock()
+		if dryRun {
+			log.Printf("Dry run mode: encoder changes, firmware upgrades and raw commands are not sent")
+		}
+	}
+
+	if addr, ok := config["pprof_addr"].(string); ok && addr != "" {
+		if err := p.startPprof(addr); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := config["onvif_bridge_addr"].(string); ok && addr != "" {
+		token, _ := config["onvif_bridge_token"].(string)
+		if err := p.startONVIFBridge(addr, token); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := config["rest_addr"].(string); ok && addr != "" {
+		token, _ := config["rest_token"].(string)
+		if err := p.startREST(addr, token); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := config["scrypted_addr"].(string); ok && addr != "" {
+		if err := p.startScrypted(addr); err != nil {
+			return err
+		}
+	}
+
+	if tc := parseTracingConfig(config); tc != nil {
+		p.tracer = newTracer(*tc)
+		goGuarded(p.ctx, "trace exporter", func() { p.tracer.run(p.ctx) })
+		log.Printf("Exporting traces to %s", p.tracer.endpoint)
+	}
+
+	p.eventInterval = defaultEventPollInterval
+	if v, ok := config["event_poll_interval_ms"].(float64); ok {
+		p.eventInterval = time.Duration(v) * time.Millisecond
+	}
+
+	if v, ok := config["max_snapshot_bytes"].(float64); ok && v > 0 {
+		p.maxSnapshotSize = int64(v)
+	}
+
+	p.offlineAfter = defaultOfflineAfter
+	if v, ok := config["offline_after_ms"].(float64); ok && v > 0 {
+		p.offlineAfter = time.Duration(v) * time.Millisecond
+	}
+
+	p.metadataInterval = defaultMetadataRefreshInterval
+	if v, ok := config["metadata_refresh_interval_ms"].(float64); ok {
+		p.metadataInterval = time.Duration(v) * time.Millisecond
+	}
+
+	// Connect to configured devices
+	for _, device := range p.devices {
+		err := p.connectDevice(device)
+		p.mu.Lock()
+		if err != nil {
+			p.deviceErrors[device.Host] = err.Error()
+		} else {
+			delete(p.deviceErrors, device.Host)
+		}
+		p.mu.Unlock()
+		if err != nil {
+			log.Printf("Failed to connect to device %s: %v", device.Host, err)
+		}
+	}
+
+	p.resumeTimelapses()
+	p.startSnapshotSinks(sinks)
+	p.configureEventBursts(burst)
+	p.startWebhooks(webhooks)
+
+	log.Printf("Plugin initialized with %d devices", len(p.devices))
+	return nil
+}
+
+// InitializeResult reports the outcome of connecting each configured device
+type InitializeResult struct {
+	Status    string                `json:"status"` // always "ok"; see Devices for failures
+	PluginID  string                `json:"plugin_id"`
+	Connected int                   `json:"connected"`
+	Failed    int                   `json:"failed"`
+	Devices   []DeviceConnectResult `json:"devices"`
+}
+
+// DeviceConnectResult is the connection outcome for one configured device
+type DeviceConnectResult struct {
+	Host    string   `json:"host"`
+	Name    string   `json:"name,omitempty"`
+	Status  string   `json:"status"` // "connected" or "failed"
+	Error   string   `json:"error,omitempty"`
+	Cameras []string `json:"cameras,omitempty"`
+}
+
+// InitializeReport summarizes the connection state of every configured device
+func (p *Plugin) InitializeReport() InitializeResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := InitializeResult{Status: "ok", PluginID: p.namespace().PluginID, Devices: []DeviceConnectResult{}}
+	for _, device := range p.devices {
+		dr := DeviceConnectResult{Host: device.Host, Name: device.Name}
+		if errMsg, failed := p.deviceErrors[device.Host]; failed {
+			dr.Status = "failed"
+			dr.Error = errMsg
+			result.Failed++
+		} else {
+			dr.Status = "connected"
+			for id, cam := range p.cameras {
+				if cam.Host() == device.Host {
+					dr.Cameras = append(dr.Cameras, id)
+				}
+			}
+			sort.Strings(dr.Cameras)
+			result.Connected++
+		}
+		result.Devices = append(result.Devices, dr)
+	}
+	return result
+}
+
+// GetSettings returns the declarative settings UI for the plugin
+func (p *Plugin) GetSettings() []Setting {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
 	settings := []Setting{
 		// Connection group
@@ -626,7 +1824,7 @@ func (p *Plugin) doProbe(ctx context.Context) error {
 
 	log.Printf("Probing device at %s...", host)
 
-	client := NewClient(host, 0, username, password)
+	client := reolink.NewClient(host, 0, username, password)
 
 	// Use the existing ProbeCamera method which gets all info
 	probeResult, err := client.ProbeCamera(ctx)
@@ -728,97 +1926,224 @@ func (p *Plugin) doAddSelectedCameras(ctx context.Context) error {
 	return nil
 }
 
-func (p *Plugin) parseConfig(config map[string]interface{}) error {
-	p.devices = nil
-
-	if config == nil {
-		return nil
+func (p *Plugin) connectDevice(device DeviceConfig) error {
+	client, info, ability, err := p.openDevice(device)
+	if err != nil {
+		return err
 	}
+	p.addDeviceCameras(device, client, info, ability)
+	return nil
+}
 
-	// Look for "devices" array
-	if devicesRaw, ok := config["devices"]; ok {
-		if devicesList, ok := devicesRaw.([]interface{}); ok {
-			for _, d := range devicesList {
-				if deviceMap, ok := d.(map[string]interface{}); ok {
-					device := DeviceConfig{}
-					if host, ok := deviceMap["host"].(string); ok {
-						device.Host = host
-					}
-					if port, ok := deviceMap["port"].(float64); ok {
-						device.Port = int(port)
-					}
-					if user, ok := deviceMap["username"].(string); ok {
-						device.Username = user
-					}
-					if pass, ok := deviceMap["password"].(string); ok {
-						device.Password = pass
-					}
-					if name, ok := deviceMap["name"].(string); ok {
-						device.Name = name
-					}
-					if device.Host != "" {
-						p.devices = append(p.devices, device)
-					}
-				}
-			}
+// openDevice logs in to a device and fetches the information needed to add its cameras
+func (p *Plugin) openDevice(device DeviceConfig) (*reolink.Client, *reolink.DeviceInfo, *reolink.Ability, error) {
+	client := reolink.NewClient(device.Host, device.Port, device.Username, device.Password)
+	client.SetRetryPolicy(device.Retry.Policy())
+	timeouts := device.Timeouts.Timeouts()
+	client.SetTimeouts(timeouts)
+	if p.maxSnapshotSize > 0 {
+		client.SetMaxSnapshotSize(p.maxSnapshotSize)
+	}
+	if device.MaxConcurrent > 0 {
+		client.SetMaxConcurrent(device.MaxConcurrent)
+	}
+	if device.TLS != nil {
+		client.SetTLS(*device.TLS)
+	}
+	if device.StreamTemplates != nil {
+		client.SetStreamTemplates(*device.StreamTemplates)
+	}
+	if device.Snapshot != nil {
+		client.SetSnapshotOptions(*device.Snapshot)
+	}
+	if device.Proxy != "" {
+		if err := client.SetProxy(device.Proxy); err != nil {
+			return nil, nil, nil, err
 		}
 	}
+	p.enableFailover(client, device.FallbackHosts)
 
-	return nil
+	info, ability, err := p.loginDevice(client, device)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return client, info, ability, nil
 }
 
-func (p *Plugin) connectDevice(device DeviceConfig) error {
-	client := NewClient(device.Host, device.Port, device.Username, device.Password)
-
-	ctx, cancel := context.WithTimeout(p.ctx, 10*time.Second)
+// loginDevice logs a configured client in to its device and fetches the
+// information needed to add its cameras
+func (p *Plugin) loginDevice(client *reolink.Client, device DeviceConfig) (*reolink.DeviceInfo, *reolink.Ability, error) {
+	timeouts := client.GetTimeouts()
+
+	// Login plus GetDevInfo, GetAbility and GetLocalLink, GetUser and
+	// AddUser or ModifyUser for a stream account, and GetNetPort for TLS
+	// streams
+	requests := 3
+	if device.StreamUsername != "" {
+		requests += 2
+	}
+	if device.TLS != nil && device.TLS.Streams {
+		requests++
+	}
+	// Each address of the device may take a login timeout to give up on
+	logins := time.Duration(len(client.Hosts()))
+	ctx, cancel := context.WithTimeout(p.ctx, logins*timeouts.Login+time.Duration(requests)*timeouts.Request)
 	defer cancel()
 
 	if err := client.Login(ctx); err != nil {
-		return fmt.Errorf("login failed: %w", err)
+		return nil, nil, fmt.Errorf("login failed: %w", err)
 	}
 
 	info, err := client.GetDeviceInfo(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get device info: %w", err)
+		return nil, nil, fmt.Errorf("failed to get device info: %w", err)
 	}
 
 	log.Printf("Connected to %s (%s) with %d channels", info.Name, info.Model, info.ChannelCount)
 
+	if err := p.provisionStreamUser(ctx, client, device); err != nil {
+		return nil, nil, fmt.Errorf("failed to provision stream account: %w", err)
+	}
+
 	ability, _ := client.GetAbility(ctx, 0)
 
+	if _, err := client.GetLocalLink(ctx); err != nil {
+		log.Printf("Failed to get MAC address for %s: %v", device.Host, err)
+	}
+
+	// The TLS stream ports are only known from GetNetPort
+	if device.TLS != nil && device.TLS.Streams {
+		if _, err := client.GetNetPort(ctx); err != nil {
+			log.Printf("Failed to get stream ports for %s: %v", device.Host, err)
+		}
+	}
+
+	hub := client.IsHomeHubModel(info.Model)
+	if info.ChannelCount > 1 || client.IsNVRModel(info.Model) || hub {
+		if _, err := client.GetHddInfo(ctx); err != nil {
+			log.Printf("Failed to get storage info for %s: %v", device.Host, err)
+		}
+	}
+
+	if info.ChannelCount > 1 || hub {
+		if _, err := client.GetChannelStatus(ctx); err != nil {
+			log.Printf("Failed to get channel names for %s: %v", device.Host, err)
+		}
+	}
+
+	return info, ability, nil
+}
+
+// addDeviceCameras registers a camera for each configured channel of an opened device
+func (p *Plugin) addDeviceCameras(device DeviceConfig, client *reolink.Client, info *reolink.DeviceInfo, ability *reolink.Ability) {
+	// A Home Hub's channels are the battery cameras paired to it, so only
+	// channels with a camera are added, each with the camera's own model
+	hub := client.IsHomeHubModel(info.Model)
+	models := make(map[int]string)
+	for _, cs := range client.GetCachedChannelStatus() {
+		if hub && cs.Model != "" {
+			models[cs.Channel] = cs.Model
+		}
+	}
+
 	channels := device.Channels
 	if len(channels) == 0 {
 		for i := 0; i < info.ChannelCount; i++ {
+			if _, paired := models[i]; hub && len(models) > 0 && !paired {
+				continue
+			}
 			channels = append(channels, i)
 		}
 	}
 
 	for _, ch := range channels {
-		cameraID := fmt.Sprintf("%s_ch%d", device.Host, ch)
+		cameraID := p.cameraID(device.Host, ch)
 		cameraName := info.Name
 		if device.Name != "" {
 			cameraName = device.Name
 		}
-		if info.ChannelCount > 1 {
+		if info.ChannelCount > 1 || hub {
 			cameraName = fmt.Sprintf("%s Ch%d", cameraName, ch+1)
+			if name := client.GetCachedChannelNames()[ch]; name != "" {
+				cameraName = name
+			}
+		}
+		model := info.Model
+		if m, ok := models[ch]; ok {
+			model = m
 		}
 
-		cam := NewCamera(cameraID, cameraName, info.Model, device.Host, ch, client)
+		cam := NewCamera(cameraID, cameraName, model, device.Host, ch, client)
 		if ability != nil {
 			cam.SetAbility(ability)
 		}
+		if device.Protocol != "" {
+			cam.SetProtocol(device.Protocol)
+		}
+		if len(device.CapabilityOverrides) > 0 {
+			cam.SetCapabilityOverrides(device.CapabilityOverrides)
+		}
+		cam.SetPowerSaving(powerSavingDefault(device, cam))
+		p.restoreLensMode(cam)
 
 		p.mu.Lock()
+		replaced := p.cameras[cameraID]
 		p.cameras[cameraID] = cam
 		p.mu.Unlock()
 
 		log.Printf("Added camera: %s", cameraID)
+		p.notifyCameraAdded(cam)
+		if replaced != nil && replaced.client != client {
+			p.releaseClient(p.ctx, replaced.client)
+		}
 	}
 
-	return nil
+	p.startWorker(client)
 }
 
 func (p *Plugin) Shutdown(ctx context.Context) error {
+	// Log out of every device so sessions do not linger. This runs before
+	// cancel because ctx is usually the plugin context.
+	p.mu.RLock()
+	clients := make(map[*reolink.Client]bool)
+	for _, cam := range p.cameras {
+		if cam.client != nil {
+			clients[cam.client] = true
+		}
+	}
+	p.mu.RUnlock()
+
+	p.releaseAlarmPulses(ctx)
+
+	// Let workers finish their current command before logging out
+	for client := range clients {
+		select {
+		case <-p.stopWorker(client):
+		case <-ctx.Done():
+		}
+	}
+
+	for client := range clients {
+		logoutCtx, cancel := context.WithTimeout(ctx, client.GetTimeouts().Request)
+		if err := client.Close(logoutCtx); err != nil {
+			log.Printf("Failed to log out of %s: %v", client.Host(), err)
+		}
+		cancel()
+	}
+
+	if p.tracer != nil {
+		flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if err := p.tracer.flush(flushCtx); err != nil {
+			log.Printf("Failed to export traces: %v", err)
+		}
+		cancel()
+	}
+
+	p.stopPprof()
+	p.stopONVIFBridge()
+	p.stopREST()
+	p.stopScrypted()
+
 	if p.cancel != nil {
 		p.cancel()
 	}
@@ -832,11 +2157,21 @@ func (p *Plugin) Health() HealthStatus {
 
 	online := 0
 	total := len(p.cameras)
+	performance := make(map[string]*reolink.Performance)
+	storage := make(map[string][]reolink.HddInfo)
 
 	for _, cam := range p.cameras {
 		if cam.IsOnline() {
 			online++
 		}
+		if cam.client != nil {
+			if perf := cam.client.GetCachedPerformance(); perf != nil {
+				performance[cam.Host()] = perf
+			}
+			if disks := cam.client.GetCachedHddInfo(); disks != nil {
+				storage[cam.Host()] = disks
+			}
+		}
 	}
 
 	state := "healthy"
@@ -858,11 +2193,37 @@ func (p *Plugin) Health() HealthStatus {
 		Details: map[string]interface{}{
 			"cameras_online": online,
 			"cameras_total":  total,
+			"performance":    performance,
+			"storage":        storage,
+			"devices":        p.deviceHealthLocked(),
 		},
 	}
 }
 
-func (p *Plugin) DiscoverCameras(ctx context.Context) ([]DiscoveredCamera, error) {
+// DiscoverCameras lists the managed cameras. With discovery_subnets or
+// subnets, those are scanned first: devices whose cameras are all offline
+// and that answer there under another address are moved to it, and the
+// other devices answering are listed with their address as ID.
+func (p *Plugin) DiscoverCameras(ctx context.Context, subnets ...string) ([]DiscoveredCamera, error) {
+	for _, cidr := range subnets {
+		if _, err := subnetHosts(cidr); err != nil {
+			return nil, err
+		}
+	}
+	scan := append([]string{}, subnets...)
+	p.mu.RLock()
+	for _, cidr := range p.discoverySubnets {
+		if !contains(scan, cidr) {
+			scan = append(scan, cidr)
+		}
+	}
+	p.mu.RUnlock()
+
+	var unmanaged []DiscoveredCamera
+	if len(scan) > 0 {
+		unmanaged = p.trackDevices(ctx, scan)
+	}
+
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
@@ -875,92 +2236,192 @@ func (p *Plugin) DiscoverCameras(ctx context.Context) ([]DiscoveredCamera, error
 			Manufacturer: "Reolink",
 			Host:         cam.Host(),
 			Capabilities: cam.Capabilities(),
+			Storage:      cam.Storage(),
 		})
 	}
 
-	return discovered, nil
+	return append(discovered, unmanaged...), nil
 }
 
+// AddCamera connects a camera. If the camera already exists (same host and
+// channel, or same device serial and channel) the existing camera is returned
+// with AlreadyExists set, unless cfg.Replace is set.
 func (p *Plugin) AddCamera(ctx context.Context, cfg CameraConfig) (*PluginCamera, error) {
+	cameraID := p.cameraID(cfg.Host, cfg.Channel)
+	if unknown := validateCapabilityOverrides(cfg.CapabilityOverrides); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown capabilities in capability_overrides: %s", strings.Join(unknown, ", "))
+	}
+
+	if !cfg.Replace {
+		if existing := p.GetCamera(cameraID); existing != nil {
+			existing.AlreadyExists = true
+			return existing, nil
+		}
+	}
+
 	device := DeviceConfig{
 		Host:     cfg.Host,
 		Port:     cfg.Port,
 		Username: cfg.Username,
 		Password: cfg.Password,
 		Name:     cfg.Name,
+		Protocol: cfg.Protocol,
+
+		StreamUsername: cfg.StreamUsername,
+		StreamPassword: cfg.StreamPassword,
+
+		CapabilityOverrides: cfg.CapabilityOverrides,
 	}
 
 	if cfg.Channel > 0 {
 		device.Channels = []int{cfg.Channel}
 	}
 
-	if err := p.connectDevice(device); err != nil {
+	client, info, ability, err := p.openDevice(device)
+	if err != nil {
 		return nil, err
 	}
 
-	cameraID := fmt.Sprintf("%s_ch%d", cfg.Host, cfg.Channel)
-
-	// Apply protocol setting if specified
-	if cfg.Protocol != "" {
-		p.mu.RLock()
-		if cam, ok := p.cameras[cameraID]; ok {
-			cam.SetProtocol(cfg.Protocol)
+	// The same device may already be added under another address
+	if dup := p.findBySerial(info.Serial, cfg.Channel); dup != "" {
+		if !cfg.Replace {
+			existing := p.GetCamera(dup)
+			existing.AlreadyExists = true
+			return existing, nil
 		}
-		p.mu.RUnlock()
+		p.mu.Lock()
+		replaced := p.cameras[dup]
+		delete(p.cameras, dup)
+		p.mu.Unlock()
+		log.Printf("Replacing camera %s with %s", dup, cameraID)
+		p.notifyCameraRemoved(dup)
+		p.releaseClient(ctx, replaced.client)
 	}
 
+	p.addDeviceCameras(device, client, info, ability)
+
 	return p.GetCamera(cameraID), nil
 }
 
+// findBySerial returns the ID of the camera on the given device serial and
+// channel, or "" if there is none
+func (p *Plugin) findBySerial(serial string, channel int) string {
+	if serial == "" {
+		return ""
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for id, cam := range p.cameras {
+		if cam.Channel() != channel || cam.client == nil {
+			continue
+		}
+		if info := cam.client.GetCachedDeviceInfo(); info != nil && info.Serial == serial {
+			return id
+		}
+	}
+	return ""
+}
+
 func (p *Plugin) RemoveCamera(ctx context.Context, id string) error {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if _, ok := p.cameras[id]; !ok {
-		return fmt.Errorf("camera not found: %s", id)
+	cam, ok := p.cameras[id]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("%w: %s", errCameraNotFound, id)
 	}
 
 	delete(p.cameras, id)
+	p.stopTimelapseLocked(id)
+	_, hadTimelapse := p.state.Timelapses[id]
+	delete(p.state.Timelapses, id)
+	_, hadLensMode := p.state.LensModes[id]
+	delete(p.state.LensModes, id)
+	_, hadPresets := p.state.ZoomPresets[id]
+	delete(p.state.ZoomPresets, id)
+	for _, sink := range p.snapshotSinks {
+		if sink.config.CameraID == id {
+			sink.reset()
+		}
+	}
+	p.mu.Unlock()
+
+	if hadTimelapse || hadLensMode || hadPresets {
+		p.saveState()
+	}
+	p.analytics.forget(id)
+	p.bursts.forget(id)
 	log.Printf("Removed camera: %s", id)
+	p.notifyCameraRemoved(id)
+	p.releaseClient(ctx, cam.client)
 	return nil
 }
 
+// releaseClient logs out and closes a client once no camera uses it anymore,
+// e.g. after the last channel of an NVR is removed
+func (p *Plugin) releaseClient(ctx context.Context, client *reolink.Client) {
+	if client == nil {
+		return
+	}
+
+	p.mu.RLock()
+	for _, cam := range p.cameras {
+		if cam.client == client {
+			p.mu.RUnlock()
+			return
+		}
+	}
+	p.mu.RUnlock()
+
+	// Logging out under a running command, or before the worker's token
+	// refresh logs in again, would leave a session behind. On the worker
+	// itself nothing else runs, and it exits once the command returns.
+	done := p.stopWorker(client)
+	if w, _ := ctx.Value(workerContextKey{}).(*deviceWorker); w == nil || w.client != client {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Printf("Not logging out of %s: its worker did not stop in time", client.Host())
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, client.GetTimeouts().Request)
+	defer cancel()
+
+	if err := client.Close(ctx); err != nil {
+		log.Printf("Failed to log out of %s: %v", client.Host(), err)
+		return
+	}
+	log.Printf("Closed session for %s", client.Host())
+}
+
 func (p *Plugin) ListCameras() []PluginCamera {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	cameras := make([]PluginCamera, 0, len(p.cameras))
 	for _, cam := range p.cameras {
-		cameras = append(cameras, PluginCamera{
-			ID:           cam.ID(),
-			PluginID:     "reolink",
-			Name:         cam.Name(),
-			Model:        cam.Model(),
-			Host:         cam.Host(),
-			MainStream:   cam.StreamURL("main"),
-			SubStream:    cam.StreamURL("sub"),
-			SnapshotURL:  cam.SnapshotURL(),
-			Capabilities: cam.Capabilities(),
-			Online:       cam.IsOnline(),
-			LastSeen:     cam.LastSeen().Format(time.RFC3339),
-			Protocol:     cam.Protocol(),
-		})
+		cameras = append(cameras, *p.newPluginCamera(cam))
 	}
 	return cameras
 }
 
 func (p *Plugin) GetCamera(id string) *PluginCamera {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	cam, ok := p.cameras[id]
-	if !ok {
+	cam, err := p.lookupCamera(id)
+	if err != nil {
 		return nil
 	}
 
-	return &PluginCamera{
+	return p.newPluginCamera(cam)
+}
+
+// newPluginCamera builds the RPC representation of a camera
+func (p *Plugin) newPluginCamera(cam *Camera) *PluginCamera {
+	pc := &PluginCamera{
 		ID:           cam.ID(),
-		PluginID:     "reolink",
+		PluginID:     p.namespace().PluginID,
 		Name:         cam.Name(),
 		Model:        cam.Model(),
 		Host:         cam.Host(),
@@ -971,17 +2432,31 @@ func (p *Plugin) GetCamera(id string) *PluginCamera {
 		Online:       cam.IsOnline(),
 		LastSeen:     cam.LastSeen().Format(time.RFC3339),
 		Protocol:     cam.Protocol(),
+		Features:     cam.CapabilitySet(),
+		MAC:          cam.MAC(),
+		Encoder:      cam.EncoderConfig(),
+		PowerSaving:  cam.PowerSaving(),
+		Lens:         cam.LensMode(),
+		Orientation:  cam.Orientation(),
+		Methods:      cam.SupportedMethods(),
+		APIVersion:   cam.APIVersion(),
+		Network:      cam.Network(),
+	}
+	if info := cam.GetDeviceInfo(); info != nil {
+		pc.Serial = info.Serial
+		pc.FirmwareVersion = info.FirmwareVersion
 	}
+	if cam.client != nil && len(cam.client.Hosts()) > 1 {
+		pc.ActiveHost = cam.client.ActiveHost()
+	}
+	return pc
 }
 
 // UpdateCamera updates camera settings (like protocol)
-func (p *Plugin) UpdateCamera(id string, settings map[string]interface{}) error {
-	p.mu.RLock()
-	cam, ok := p.cameras[id]
-	p.mu.RUnlock()
-
-	if !ok {
-		return fmt.Errorf("camera not found: %s", id)
+func (p *Plugin) UpdateCamera(ctx context.Context, id string, settings map[string]interface{}) error {
+	cam, err := p.lookupCamera(id)
+	if err != nil {
+		return err
 	}
 
 	if protocol, ok := settings["protocol"].(string); ok {
@@ -989,38 +2464,48 @@ func (p *Plugin) UpdateCamera(id string, settings map[string]interface{}) error
 		log.Printf("Updated camera %s protocol to %s", id, protocol)
 	}
 
+	if on, ok := settings["power_saving"].(bool); ok {
+		cam.SetPowerSaving(on)
+		log.Printf("Updated camera %s power saving to %v", id, on)
+	}
+
+	if name, ok := settings["name"].(string); ok {
+		push, _ := settings["push_name"].(bool)
+		if err := p.RenameCamera(ctx, id, name, push); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (p *Plugin) PTZControl(ctx context.Context, cameraID string, cmd PTZCommand) error {
-	p.mu.RLock()
-	cam, ok := p.cameras[cameraID]
-	p.mu.RUnlock()
-
-	if !ok {
-		return fmt.Errorf("camera not found: %s", cameraID)
-	}
+	// Live control jumps ahead of queued snapshots and metadata reads
+	ctx = reolink.WithPriority(ctx, reolink.PriorityControl)
 
-	return cam.PTZControl(ctx, cmd)
+	return p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		// Zoom presets are stored by the plugin rather than on the camera
+		if cmd.Action == "preset" && strings.HasPrefix(cmd.Preset, zoomPresetPrefix) {
+			return p.recallZoomPreset(ctx, cam, cmd.Preset)
+		}
+		return cam.PTZControl(ctx, cmd)
+	})
 }
 
 func (p *Plugin) GetSnapshot(ctx context.Context, cameraID string) (string, error) {
-	p.mu.RLock()
-	cam, ok := p.cameras[cameraID]
-	p.mu.RUnlock()
-
-	if !ok {
-		return "", fmt.Errorf("camera not found: %s", cameraID)
-	}
-
-	return cam.GetSnapshot(ctx)
+	var snap string
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		snap, err = cam.GetSnapshot(ctx)
+		return err
+	})
+	return snap, err
 }
 
-func (p *Plugin) ProbeCamera(ctx context.Context, host string, port int, username, password string) (*CameraProbeResult, error) {
+func (p *Plugin) ProbeCamera(ctx context.Context, host string, port int, username, password string) (*reolink.CameraProbeResult, error) {
 	if port == 0 {
 		port = 80
 	}
-	client := NewClient(host, port, username, password)
+	client := reolink.NewClient(host, port, username, password)
 	return client.ProbeCamera(ctx)
 }
 
@@ -1038,6 +2523,8 @@ type CameraCapabilities struct {
 	AITypes         []string `json:"ai_types,omitempty"`
 	Protocols       []string `json:"protocols"`
 	CurrentProtocol string   `json:"current_protocol"`
+
+	Features *CapabilitySet `json:"features"`
 }
 
 // ProtocolOption represents an available streaming protocol
@@ -1056,22 +2543,20 @@ type PTZPreset struct {
 
 // RPCDeviceInfo represents detailed device information for RPC responses
 type RPCDeviceInfo struct {
-	Model           string `json:"model"`
-	Manufacturer    string `json:"manufacturer"`
-	Serial          string `json:"serial,omitempty"`
-	FirmwareVersion string `json:"firmware_version,omitempty"`
-	HardwareVersion string `json:"hardware_version,omitempty"`
-	ChannelCount    int    `json:"channel_count"`
-	DeviceType      string `json:"device_type,omitempty"`
+	Model           string               `json:"model"`
+	Manufacturer    string               `json:"manufacturer"`
+	Serial          string               `json:"serial,omitempty"`
+	FirmwareVersion string               `json:"firmware_version,omitempty"`
+	HardwareVersion string               `json:"hardware_version,omitempty"`
+	ChannelCount    int                  `json:"channel_count"`
+	DeviceType      string               `json:"device_type,omitempty"`
+	Performance     *reolink.Performance `json:"performance,omitempty"`
 }
 
 // GetCapabilities returns detailed capabilities for a camera
 func (p *Plugin) GetCapabilities(cameraID string) *CameraCapabilities {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	cam, ok := p.cameras[cameraID]
-	if !ok {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
 		return nil
 	}
 
@@ -1081,10 +2566,10 @@ func (p *Plugin) GetCapabilities(cameraID string) *CameraCapabilities {
 	hasTwoWay := contains(caps, "two_way_audio")
 	hasAI := contains(caps, "ai_detection")
 
-	// Determine AI types based on capabilities
+	features := cam.CapabilitySet()
 	var aiTypes []string
 	if hasAI {
-		aiTypes = []string{"person", "vehicle", "animal"}
+		aiTypes = features.AI
 	}
 
 	return &CameraCapabilities{
@@ -1100,22 +2585,30 @@ func (p *Plugin) GetCapabilities(cameraID string) *CameraCapabilities {
 		AITypes:         aiTypes,
 		Protocols:       []string{"rtsp", "rtmp", "hls"},
 		CurrentProtocol: cam.Protocol(),
+		Features:        features,
 	}
 }
 
 // GetPTZPresets returns available PTZ presets for a camera
 func (p *Plugin) GetPTZPresets(ctx context.Context, cameraID string) ([]PTZPreset, error) {
-	p.mu.RLock()
-	cam, ok := p.cameras[cameraID]
-	p.mu.RUnlock()
-
-	if !ok {
-		return nil, fmt.Errorf("camera not found: %s", cameraID)
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
 	}
 
+	zoomPresets := p.zoomPresets(cameraID)
+
 	// Get presets from camera
-	presets, err := cam.GetPTZPresets(ctx)
+	var presets []CameraPreset
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) (err error) {
+		presets, err = cam.GetPTZPresets(ctx)
+		return err
+	})
 	if err != nil {
+		// Cameras without native presets may still have plugin-stored zoom presets
+		if len(zoomPresets) > 0 {
+			return zoomPresets, nil
+		}
 		return nil, err
 	}
 
@@ -1124,20 +2617,17 @@ func (p *Plugin) GetPTZPresets(ctx context.Context, cameraID string) ([]PTZPrese
 		result = append(result, PTZPreset(preset))
 	}
 
-	return result, nil
+	return append(result, zoomPresets...), nil
 }
 
 // GetProtocols returns available streaming protocols for a camera
 func (p *Plugin) GetProtocols(cameraID string) []ProtocolOption {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	cam, ok := p.cameras[cameraID]
-	if !ok {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
 		return nil
 	}
 
-	return []ProtocolOption{
+	options := []ProtocolOption{
 		{
 			ID:          "rtsp",
 			Name:        "RTSP",
@@ -1157,22 +2647,44 @@ func (p *Plugin) GetProtocols(cameraID string) []ProtocolOption {
 			StreamURL:   cam.StreamURLForProtocol("main", "hls"),
 		},
 	}
+	// TLS variants are offered where the device advertises them
+	if cam.client.SecureStreamPort("rtsp") > 0 {
+		options = append(options, ProtocolOption{
+			ID:          "rtsps",
+			Name:        "RTSPS",
+			Description: "RTSP over TLS - encrypted",
+			StreamURL:   cam.StreamURLForProtocol("main", "rtsps"),
+		})
+	}
+	if cam.client.SecureStreamPort("rtmp") > 0 {
+		options = append(options, ProtocolOption{
+			ID:          "rtmps",
+			Name:        "RTMPS",
+			Description: "RTMP over TLS - encrypted",
+			StreamURL:   cam.StreamURLForProtocol("main", "rtmps"),
+		})
+	}
+	return options
 }
 
 // SetProtocol changes the streaming protocol for a camera
 func (p *Plugin) SetProtocol(cameraID string, protocol string) error {
-	p.mu.RLock()
-	cam, ok := p.cameras[cameraID]
-	p.mu.RUnlock()
-
-	if !ok {
-		return fmt.Errorf("camera not found: %s", cameraID)
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return err
 	}
 
 	// Validate protocol
 	validProtocols := map[string]bool{"rtsp": true, "rtmp": true, "hls": true}
-	if !validProtocols[protocol] {
-		return fmt.Errorf("invalid protocol: %s (must be rtsp, rtmp, or hls)", protocol)
+	switch protocol {
+	case "rtsps", "rtmps":
+		if cam.client.SecureStreamPort(protocol) == 0 {
+			return fmt.Errorf("camera %s does not offer %s", cameraID, protocol)
+		}
+	default:
+		if !validProtocols[protocol] {
+			return fmt.Errorf("invalid protocol: %s (must be rtsp, rtmp, hls, or rtsps/rtmps where offered)", protocol)
+		}
 	}
 
 	cam.SetProtocol(protocol)
@@ -1180,16 +2692,23 @@ func (p *Plugin) SetProtocol(cameraID string, protocol string) error {
 	return nil
 }
 
-// GetDeviceInfo returns detailed device information for a camera
-func (p *Plugin) GetDeviceInfo(cameraID string) *RPCDeviceInfo {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	cam, ok := p.cameras[cameraID]
-	if !ok {
+// GetDeviceInfo returns detailed device information for a camera,
+// including freshly fetched performance stats when the device answers
+func (p *Plugin) GetDeviceInfo(ctx context.Context, cameraID string) *RPCDeviceInfo {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
 		return nil
 	}
 
+	var perf *reolink.Performance
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) (err error) {
+		perf, err = cam.GetPerformance(ctx)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to get performance for camera %s: %v", cameraID, err)
+	}
+
 	info := cam.GetDeviceInfo()
 	if info == nil {
 		return &RPCDeviceInfo{
@@ -1197,6 +2716,7 @@ func (p *Plugin) GetDeviceInfo(cameraID string) *RPCDeviceInfo {
 			Manufacturer: "Reolink",
 			ChannelCount: 1,
 			DeviceType:   cam.DeviceType(),
+			Performance:  perf,
 		}
 	}
 
@@ -1208,7 +2728,139 @@ func (p *Plugin) GetDeviceInfo(cameraID string) *RPCDeviceInfo {
 		HardwareVersion: info.HardwareVersion,
 		ChannelCount:    info.ChannelCount,
 		DeviceType:      cam.DeviceType(),
+		Performance:     perf,
+	}
+}
+
+// errCameraNotFound is returned for a camera ID the plugin does not manage
+var errCameraNotFound = errors.New("camera not found")
+
+// lookupCamera returns the camera with the given ID or a not-found error
+func (p *Plugin) lookupCamera(cameraID string) (*Camera, error) {
+	p.mu.RLock()
+	cam, ok := p.cameras[cameraID]
+	p.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errCameraNotFound, cameraID)
+	}
+	return cam, nil
+}
+
+// ListUsers returns the user accounts configured on a camera's device
+func (p *Plugin) ListUsers(ctx context.Context, cameraID string) ([]reolink.CameraUser, error) {
+	var users []reolink.CameraUser
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		users, err = cam.client.GetUsers(ctx)
+		return err
+	})
+	return users, err
+}
+
+// SaveUser creates (create=true) or modifies a user account on a camera's device
+func (p *Plugin) SaveUser(ctx context.Context, cameraID string, create bool, username, password, level string) error {
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		if create {
+			return cam.client.AddUser(ctx, username, password, level)
+		}
+		return cam.client.ModifyUser(ctx, username, password, level)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Saved user %s on camera %s", username, cameraID)
+	return nil
+}
+
+// DeleteUser removes a user account from a camera's device
+func (p *Plugin) DeleteUser(ctx context.Context, cameraID, username string) error {
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		return cam.client.DeleteUser(ctx, username)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Deleted user %s on camera %s", username, cameraID)
+	return nil
+}
+
+// ListSessions returns the sessions currently logged into a camera's device
+func (p *Plugin) ListSessions(ctx context.Context, cameraID string) ([]reolink.Session, error) {
+	var sessions []reolink.Session
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		sessions, err = cam.client.GetSessions(ctx)
+		return err
+	})
+	return sessions, err
+}
+
+// DisconnectSession kicks a session off a camera's device
+func (p *Plugin) DisconnectSession(ctx context.Context, cameraID, username string, sessionID int) error {
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		return cam.client.DisconnectSession(ctx, username, sessionID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Disconnected session %d (%s) on camera %s", sessionID, username, cameraID)
+	return nil
+}
+
+// GetLight returns the white LED settings of a camera
+func (p *Plugin) GetLight(ctx context.Context, cameraID string) (*reolink.WhiteLedConfig, error) {
+	var cfg *reolink.WhiteLedConfig
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		cfg, err = cam.client.GetWhiteLed(ctx, cam.Channel())
+		return err
+	})
+	return cfg, err
+}
+
+// SetLight updates a camera's white LED. A nil on leaves the state unchanged;
+// zero brightness (percent) or duration (seconds) leave those settings unchanged.
+func (p *Plugin) SetLight(ctx context.Context, cameraID string, on *bool, brightness, duration int) error {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return err
 	}
+
+	if on == nil && brightness == 0 && duration == 0 {
+		return fmt.Errorf("nothing to set: on, brightness or duration is required")
+	}
+	ctx = reolink.WithPriority(ctx, reolink.PriorityControl)
+
+	if brightness != 0 || duration != 0 {
+		if ability := cam.Ability(); ability != nil && !ability.Floodlight {
+			return fmt.Errorf("camera %s does not support floodlight brightness control", cameraID)
+		}
+	}
+	return p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+		if brightness != 0 || duration != 0 {
+			if err := cam.client.SetWhiteLedLevel(ctx, cam.Channel(), brightness, duration); err != nil {
+				return err
+			}
+		}
+		if on != nil {
+			return cam.client.SetWhiteLedState(ctx, cam.Channel(), *on)
+		}
+		return nil
+	})
+}
+
+// SetLightSchedule configures when a camera's white LED turns on automatically
+func (p *Plugin) SetLightSchedule(ctx context.Context, cameraID, mode string, schedule reolink.LightSchedule) error {
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		return cam.client.SetWhiteLedSchedule(ctx, cam.Channel(), mode, schedule)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Set camera %s light mode to %s", cameraID, mode)
+	return nil
 }
 
 // Helper function to check if a slice contains a string
@@ -3,8 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
 )
 
 func TestNewPlugin(t *testing.T) {
@@ -74,7 +82,7 @@ func TestPlugin_Health_WithOnlineCameras(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock cameras
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam1 := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	cam1.online = true
 	cam2 := NewCamera("cam_2", "Back Yard", "RLC-810A", "localhost", 0, client)
@@ -102,7 +110,7 @@ func TestPlugin_Health_WithOfflineCameras(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock cameras
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam1 := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	cam1.online = false
 	cam2 := NewCamera("cam_2", "Back Yard", "RLC-810A", "localhost", 0, client)
@@ -122,7 +130,7 @@ func TestPlugin_Health_Degraded(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock cameras - one online, one offline
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam1 := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	cam1.online = true
 	cam2 := NewCamera("cam_2", "Back Yard", "RLC-810A", "localhost", 0, client)
@@ -142,7 +150,7 @@ func TestPlugin_DiscoverCameras(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock cameras
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	plugin.cameras["cam_1"] = cam
 
@@ -169,7 +177,7 @@ func TestPlugin_ListCameras(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock cameras
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	plugin.cameras["cam_1"] = cam
 
@@ -192,7 +200,7 @@ func TestPlugin_GetCamera_Found(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock camera
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	plugin.cameras["cam_1"] = cam
 
@@ -206,6 +214,42 @@ func TestPlugin_GetCamera_Found(t *testing.T) {
 	}
 }
 
+func TestPlugin_GetCamera_Inventory(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetDevInfo": map[string]interface{}{
+			"DevInfo": map[string]interface{}{"model": "RLC-810A", "serial": "00000001", "firmVer": "v3.1.0.2368"},
+		},
+		"GetLocalLink": map[string]interface{}{
+			"LocalLink": map[string]interface{}{"mac": "EC:71:DB:12:34:56", "type": "DHCP"},
+		},
+	})
+
+	client := newTestClient(server)
+	if _, err := client.GetDeviceInfo(context.Background()); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	if _, err := client.GetLocalLink(context.Background()); err != nil {
+		t.Fatalf("GetLocalLink failed: %v", err)
+	}
+
+	plugin := NewPlugin()
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
+
+	result := plugin.GetCamera("cam_1")
+	if result.Serial != "00000001" || result.FirmwareVersion != "v3.1.0.2368" {
+		t.Errorf("Expected serial and firmware from device info, got %+v", result)
+	}
+	if result.MAC != "ec:71:db:12:34:56" {
+		t.Errorf("Expected normalized MAC, got '%s'", result.MAC)
+	}
+	if result.Network == nil || result.Network.Type != "DHCP" || result.Network.MAC != result.MAC {
+		t.Errorf("Expected the network settings, got %+v", result.Network)
+	}
+	if cameras := plugin.ListCameras(); len(cameras) != 1 || cameras[0].MAC != result.MAC {
+		t.Errorf("Expected ListCameras to carry the MAC, got %+v", cameras)
+	}
+}
+
 func TestPlugin_GetCamera_NotFound(t *testing.T) {
 	plugin := NewPlugin()
 
@@ -220,7 +264,7 @@ func TestPlugin_RemoveCamera_Found(t *testing.T) {
 	plugin := NewPlugin()
 
 	// Add mock camera
-	client := NewClient("localhost", 80, "admin", "password")
+	client := reolink.NewClient("localhost", 80, "admin", "password")
 	cam := NewCamera("cam_1", "Front Door", "RLC-810A", "localhost", 0, client)
 	plugin.cameras["cam_1"] = cam
 
@@ -236,6 +280,30 @@ func TestPlugin_RemoveCamera_Found(t *testing.T) {
 	}
 }
 
+func TestPlugin_RemoveCamera_ForgetsState(t *testing.T) {
+	plugin := NewPlugin()
+	client := reolink.NewClient("localhost", 80, "admin", "password")
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Front Door", "RLC-811A", "localhost", 0, client)
+	plugin.state.ZoomPresets["cam_1"] = map[string]reolink.ZoomPosition{"door": {Zoom: 10}}
+	plugin.configureEventBursts(&EventBurstConfig{Count: 1, IntervalMs: 1000})
+	plugin.bursts.pre["cam_1"] = []burstFrame{{capturedAt: time.Now()}}
+	sink := &snapshotSink{config: SnapshotSinkConfig{CameraID: "cam_1"}, published: 3}
+	plugin.snapshotSinks = []*snapshotSink{sink}
+
+	if err := plugin.RemoveCamera(context.Background(), "cam_1"); err != nil {
+		t.Fatalf("RemoveCamera failed: %v", err)
+	}
+	if _, ok := plugin.state.ZoomPresets["cam_1"]; ok {
+		t.Error("Expected the zoom presets to be removed")
+	}
+	if _, ok := plugin.bursts.pre["cam_1"]; ok {
+		t.Error("Expected the pre-event snapshots to be removed")
+	}
+	if status := sink.status(); status.Published != 0 {
+		t.Errorf("Expected the sink counters to be reset, got %+v", status)
+	}
+}
+
 func TestPlugin_RemoveCamera_NotFound(t *testing.T) {
 	plugin := NewPlugin()
 
@@ -677,3 +745,304 @@ func TestHealthStatus(t *testing.T) {
 		t.Errorf("Expected state 'healthy', got '%s'", status.State)
 	}
 }
+
+func TestPlugin_ParseConfig_RetryAndTimeouts(t *testing.T) {
+	plugin := NewPlugin()
+
+	config := map[string]interface{}{
+		"devices": []interface{}{
+			map[string]interface{}{
+				"host":     "192.168.1.100",
+				"username": "admin",
+				"password": "password",
+				"retry":    map[string]interface{}{"max_attempts": float64(5)},
+				"timeouts": map[string]interface{}{"request_ms": float64(30000)},
+			},
+		},
+	}
+
+	if err := plugin.parseConfig(config); err != nil {
+		t.Fatalf("parseConfig failed: %v", err)
+	}
+
+	device := plugin.devices[0]
+	if device.Retry == nil || device.Retry.MaxAttempts != 5 {
+		t.Errorf("Expected retry max_attempts 5, got %+v", device.Retry)
+	}
+	if device.Timeouts == nil || device.Timeouts.RequestMs != 30000 {
+		t.Errorf("Expected request_ms 30000, got %+v", device.Timeouts)
+	}
+}
+
+func TestPlugin_InitializeReport(t *testing.T) {
+	plugin := NewPlugin()
+	ctx := context.Background()
+
+	config := map[string]interface{}{
+		"devices": []interface{}{
+			map[string]interface{}{
+				"host":     "127.0.0.1",
+				"port":     float64(1), // nothing listens here
+				"username": "admin",
+				"password": "password",
+				"name":     "Garage",
+				"retry":    map[string]interface{}{"max_attempts": float64(1)},
+			},
+		},
+	}
+	if err := plugin.Initialize(ctx, config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer func() { _ = plugin.Shutdown(ctx) }()
+
+	report := plugin.InitializeReport()
+	if report.Status != "ok" || report.Failed != 1 || report.Connected != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Devices) != 1 {
+		t.Fatalf("Expected 1 device, got %d", len(report.Devices))
+	}
+	dev := report.Devices[0]
+	if dev.Host != "127.0.0.1" || dev.Name != "Garage" || dev.Status != "failed" || dev.Error == "" {
+		t.Errorf("Unexpected device result: %+v", dev)
+	}
+}
+
+// newFakeDevice starts a server that answers every API command with code 0
+// and the value registered for that command in values, if any
+func newFakeDevice(t *testing.T, values map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		if cmd == "" {
+			var cmds []apiCommand
+			_ = json.NewDecoder(r.Body).Decode(&cmds)
+			if len(cmds) > 0 {
+				cmd = cmds[0].Cmd
+			}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: cmd, Code: 0, Value: values[cmd]}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// apiCommand and apiResponse are the wire format of the Reolink API, as seen
+// by fake devices
+type apiCommand struct {
+	Cmd    string                 `json:"cmd"`
+	Action int                    `json:"action"`
+	Param  map[string]interface{} `json:"param"`
+}
+
+type apiResponse struct {
+	Cmd   string          `json:"cmd"`
+	Code  int             `json:"code"`
+	Value interface{}     `json:"value"`
+	Range interface{}     `json:"range,omitempty"`
+	Error *apiErrorDetail `json:"error,omitempty"`
+}
+
+type apiErrorDetail struct {
+	RspCode int    `json:"rspCode"`
+	Detail  string `json:"detail"`
+}
+
+// newTestClient returns a client for a test server
+func newTestClient(server *httptest.Server) *reolink.Client {
+	host, port := serverHostPort(server)
+	return reolink.NewClient(host, port, "admin", "password")
+}
+
+// newTokenDevice starts a server that rejects credentials in the URL, like
+// newer firmware, and issues numbered session tokens with the given lease.
+// Other commands are answered like newFakeDevice does, except that a command
+// whose value is an *apiErrorDetail fails with it. If seen is set, it is
+// called with each command and the token it was sent with.
+func newTokenDevice(t *testing.T, lease int, values map[string]interface{}, seen func(cmd, token string)) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("user") != "" {
+			_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: r.URL.Query().Get("cmd"), Code: 1, Error: &apiErrorDetail{RspCode: -6, Detail: "please login first"}}})
+			return
+		}
+		var cmds []apiCommand
+		if err := json.NewDecoder(r.Body).Decode(&cmds); err != nil || len(cmds) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if seen != nil {
+			seen(cmds[0].Cmd, r.URL.Query().Get("token"))
+		}
+		resp := apiResponse{Cmd: cmds[0].Cmd, Value: values[cmds[0].Cmd]}
+		if detail, ok := resp.Value.(*apiErrorDetail); ok {
+			resp = apiResponse{Cmd: resp.Cmd, Code: 1, Error: detail}
+		}
+		if resp.Cmd == "Login" {
+			logins++
+			token := map[string]interface{}{"name": fmt.Sprintf("session%d", logins), "leaseTime": lease}
+			resp.Value = map[string]interface{}{"Token": token}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serverHostPort splits a test server URL into host and port
+func serverHostPort(server *httptest.Server) (string, int) {
+	hostPort := strings.TrimPrefix(server.URL, "http://")
+	idx := strings.LastIndex(hostPort, ":")
+	port, _ := strconv.Atoi(hostPort[idx+1:])
+	return hostPort[:idx], port
+}
+
+func TestPlugin_AddCamera_AlreadyExists(t *testing.T) {
+	plugin := NewPlugin()
+	client := reolink.NewClient("192.168.1.100", 80, "admin", "password")
+	plugin.cameras["192.168.1.100_ch0"] = NewCamera("192.168.1.100_ch0", "Front", "RLC-810A", "192.168.1.100", 0, client)
+
+	// No connection is attempted for a known host and channel
+	cam, err := plugin.AddCamera(context.Background(), CameraConfig{Host: "192.168.1.100", Username: "admin"})
+	if err != nil {
+		t.Fatalf("AddCamera failed: %v", err)
+	}
+	if !cam.AlreadyExists || cam.ID != "192.168.1.100_ch0" {
+		t.Errorf("Expected existing camera, got %+v", cam)
+	}
+}
+
+func TestPlugin_AddCamera_DuplicateSerial(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetDevInfo": map[string]interface{}{
+			"DevInfo": map[string]interface{}{"model": "RLC-810A", "name": "Front", "serial": "SN123", "channelNum": float64(1)},
+		},
+	})
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.ctx = context.Background()
+
+	// The same device was added earlier under its hostname
+	known := newTestClient(server)
+	if _, err := known.GetDeviceInfo(context.Background()); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	plugin.cameras["front.local_ch0"] = NewCamera("front.local_ch0", "Front", "RLC-810A", "front.local", 0, known)
+
+	cfg := CameraConfig{Host: host, Port: port, Username: "admin", Password: "password"}
+	cam, err := plugin.AddCamera(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("AddCamera failed: %v", err)
+	}
+	if !cam.AlreadyExists || cam.ID != "front.local_ch0" {
+		t.Errorf("Expected existing camera front.local_ch0, got %+v", cam)
+	}
+
+	cfg.Replace = true
+	cam, err = plugin.AddCamera(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("AddCamera with replace failed: %v", err)
+	}
+	if cam.AlreadyExists || cam.Host != host {
+		t.Errorf("Expected replaced camera on %s, got %+v", host, cam)
+	}
+	if plugin.GetCamera("front.local_ch0") != nil {
+		t.Error("Expected old camera to be removed")
+	}
+}
+
+func TestPlugin_RemoveCamera_LogsOutAfterLastChannel(t *testing.T) {
+	var logouts []string
+	server := newTokenDevice(t, 3600, nil, func(cmd, token string) {
+		if cmd == "Logout" {
+			logouts = append(logouts, token)
+		}
+	})
+
+	host, _ := serverHostPort(server)
+	client := newTestClient(server)
+	if err := client.Login(context.Background()); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	plugin := NewPlugin()
+	plugin.cameras["nvr_ch0"] = NewCamera("nvr_ch0", "Ch1", "RLN8-410", host, 0, client)
+	plugin.cameras["nvr_ch1"] = NewCamera("nvr_ch1", "Ch2", "RLN8-410", host, 1, client)
+	ctx := context.Background()
+
+	if err := plugin.RemoveCamera(ctx, "nvr_ch0"); err != nil {
+		t.Fatalf("RemoveCamera failed: %v", err)
+	}
+	if len(logouts) != 0 {
+		t.Fatal("Expected session to stay open while another channel uses it")
+	}
+
+	if err := plugin.RemoveCamera(ctx, "nvr_ch1"); err != nil {
+		t.Fatalf("RemoveCamera failed: %v", err)
+	}
+	if len(logouts) != 1 || logouts[0] != "session1" {
+		t.Errorf("Expected one logout with the session token, got %v", logouts)
+	}
+	if client.Health().TokenAge != 0 {
+		t.Error("Expected token to be cleared after logout")
+	}
+}
+
+func TestPlugin_HandleRequest_ListUsers_NotFound(t *testing.T) {
+	plugin := NewPlugin()
+
+	req := JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "list_users",
+		Params:  json.RawMessage(`{"camera_id":"missing"}`),
+	}
+
+	resp := plugin.HandleRequest(req)
+	if resp.Error == nil {
+		t.Fatal("Expected error for unknown camera")
+	}
+	if resp.Error.Code != -32603 {
+		t.Errorf("Expected error code -32603, got %d", resp.Error.Code)
+	}
+}
+
+func TestPlugin_SecureStreamProtocols(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetNetPort": map[string]interface{}{
+			"NetPort": map[string]interface{}{"rtspPort": float64(554), "rtspsPort": float64(322)},
+		},
+	})
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam1"] = NewCamera("cam1", "Front", "RLC-823A", "cam", 0, client)
+
+	if err := plugin.SetProtocol("cam1", "rtsps"); err == nil {
+		t.Error("Expected rtsps to be refused before the device advertises it")
+	}
+	if _, err := client.GetNetPort(context.Background()); err != nil {
+		t.Fatalf("GetNetPort failed: %v", err)
+	}
+
+	var ids []string
+	for _, opt := range plugin.GetProtocols("cam1") {
+		ids = append(ids, opt.ID)
+	}
+	if !containsString(ids, "rtsps") || containsString(ids, "rtmps") {
+		t.Errorf("Expected rtsps to be offered without rtmps, got %v", ids)
+	}
+	if err := plugin.SetProtocol("cam1", "rtsps"); err != nil {
+		t.Fatalf("SetProtocol failed: %v", err)
+	}
+	if url := plugin.GetCamera("cam1").MainStream; !strings.HasPrefix(url, "rtsps://") {
+		t.Errorf("Expected an RTSPS main stream, got %s", url)
+	}
+}
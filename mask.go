@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// PrivacyMaskRequest is one configure_privacy_mask operation
+type PrivacyMaskRequest struct {
+	CameraID string            `json:"camera_id"`
+	Action   string            `json:"action"`          // "list", "add", "delete" or "clear"
+	Zone     *reolink.MaskZone `json:"zone,omitempty"`  // For "add"
+	Index    *int              `json:"index,omitempty"` // For "delete", as returned by "list"
+}
+
+// ConfigurePrivacyMask lists, adds or deletes the rectangular privacy zones of
+// a camera and returns the resulting mask. The mask is enabled while it has
+// zones. On NVRs the zones belong to the camera's channel.
+func (p *Plugin) ConfigurePrivacyMask(ctx context.Context, req PrivacyMaskRequest) (*reolink.PrivacyMask, error) {
+	switch req.Action {
+	case "list", "clear":
+	case "add":
+		if req.Zone == nil {
+			return nil, fmt.Errorf("zone is required for add")
+		}
+		if err := req.Zone.Validate(); err != nil {
+			return nil, err
+		}
+	case "delete":
+		if req.Index == nil {
+			return nil, fmt.Errorf("index is required for delete")
+		}
+	default:
+		return nil, fmt.Errorf("unknown privacy mask action: %s (must be list, add, delete, or clear)", req.Action)
+	}
+
+	var mask *reolink.PrivacyMask
+	err := p.onCamera(ctx, req.CameraID, func(ctx context.Context, cam *Camera) (err error) {
+		mask, err = cam.client.GetPrivacyMask(ctx, cam.Channel())
+		if err != nil || req.Action == "list" {
+			return err
+		}
+
+		switch req.Action {
+		case "add":
+			if len(mask.Zones) >= reolink.MaxMaskZones {
+				return fmt.Errorf("camera %s already has %d privacy zones", req.CameraID, reolink.MaxMaskZones)
+			}
+			mask.Zones = append(mask.Zones, *req.Zone)
+		case "delete":
+			i := *req.Index
+			if i < 0 || i >= len(mask.Zones) {
+				return fmt.Errorf("privacy zone not found: %d", i)
+			}
+			mask.Zones = append(mask.Zones[:i], mask.Zones[i+1:]...)
+		case "clear":
+			mask.Zones = []reolink.MaskZone{}
+		}
+		mask.Enabled = len(mask.Zones) > 0
+
+		if err := cam.client.SetPrivacyMask(ctx, cam.Channel(), *mask); err != nil {
+			return err
+		}
+		log.Printf("Updated privacy mask of camera %s: %d zones", req.CameraID, len(mask.Zones))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mask, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_ConfigurePrivacyMask(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "password"}))
+	defer server.Close()
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["nvr_ch0"] = NewCamera("nvr_ch0", "Yard", "RLN8-410", "localhost", 0, client)
+	plugin.cameras["nvr_ch1"] = NewCamera("nvr_ch1", "Street", "RLN8-410", "localhost", 1, client)
+
+	call := func(params string) (*reolink.PrivacyMask, *JSONRPCError) {
+		resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "configure_privacy_mask", Params: []byte(params)})
+		mask, _ := resp.Result.(*reolink.PrivacyMask)
+		return mask, resp.Error
+	}
+
+	for _, zone := range []string{`{"x": 0, "y": 0, "width": 0.5, "height": 0.5}`, `{"x": 0.5, "y": 0.5, "width": 0.25, "height": 0.25}`} {
+		if _, rpcErr := call(`{"camera_id": "nvr_ch1", "action": "add", "zone": ` + zone + `}`); rpcErr != nil {
+			t.Fatalf("add failed: %s", rpcErr.Message)
+		}
+	}
+
+	mask, rpcErr := call(`{"camera_id": "nvr_ch1", "action": "list"}`)
+	if rpcErr != nil {
+		t.Fatalf("list failed: %s", rpcErr.Message)
+	}
+	if !mask.Enabled || len(mask.Zones) != 2 || mask.Zones[1] != (reolink.MaskZone{X: 0.5, Y: 0.5, Width: 0.25, Height: 0.25}) {
+		t.Errorf("Unexpected mask: %+v", mask)
+	}
+
+	// Zones belong to the camera's NVR channel
+	if mask, _ := call(`{"camera_id": "nvr_ch0", "action": "list"}`); mask == nil || mask.Enabled || len(mask.Zones) != 0 {
+		t.Errorf("Expected channel 0 to have no zones, got %+v", mask)
+	}
+
+	mask, rpcErr = call(`{"camera_id": "nvr_ch1", "action": "delete", "index": 0}`)
+	if rpcErr != nil {
+		t.Fatalf("delete failed: %s", rpcErr.Message)
+	}
+	if len(mask.Zones) != 1 || mask.Zones[0].X != 0.5 {
+		t.Errorf("Expected the second zone to remain, got %+v", mask.Zones)
+	}
+	if mask, _ := call(`{"camera_id": "nvr_ch1", "action": "clear"}`); mask == nil || mask.Enabled {
+		t.Errorf("Expected clearing to disable the mask, got %+v", mask)
+	}
+
+	errTests := []struct {
+		params, want string
+	}{
+		{`{"camera_id": "nvr_ch1", "action": "add", "zone": {"x": 0.9, "y": 0, "width": 0.5, "height": 0.5}}`, "within 0..1"},
+		{`{"camera_id": "nvr_ch1", "action": "delete", "index": 3}`, "not found"},
+		{`{"camera_id": "nvr_ch1", "action": "rename"}`, "unknown privacy mask action"},
+	}
+	for _, tt := range errTests {
+		if _, rpcErr := call(tt.params); rpcErr == nil || !strings.Contains(rpcErr.Message, tt.want) {
+			t.Errorf("%s: expected %q error, got %v", tt.params, tt.want, rpcErr)
+		}
+	}
+}
+
+func TestPlugin_ConfigurePrivacyMask_Full(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Model: "RLC-810A", Password: "password"}))
+	defer server.Close()
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam"] = NewCamera("cam", "Yard", "RLC-810A", "localhost", 0, client)
+
+	zone := reolink.MaskZone{Width: 0.1, Height: 0.1}
+	for i := 0; i < reolink.MaxMaskZones; i++ {
+		if _, err := plugin.ConfigurePrivacyMask(context.Background(), PrivacyMaskRequest{CameraID: "cam", Action: "add", Zone: &zone}); err != nil {
+			t.Fatalf("add %d failed: %v", i, err)
+		}
+	}
+	if _, err := plugin.ConfigurePrivacyMask(context.Background(), PrivacyMaskRequest{CameraID: "cam", Action: "add", Zone: &zone}); err == nil {
+		t.Error("Expected an error once all zones are used")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// methodRequirement says whether a camera method can work on a camera, from
+// its capabilities and the inventory of its device
+type methodRequirement func(features *CapabilitySet, info *reolink.DeviceInfo) bool
+
+func needsPTZ(f *CapabilitySet, _ *reolink.DeviceInfo) bool { return f.PTZ != nil }
+
+func needsLight(f *CapabilitySet, _ *reolink.DeviceInfo) bool { return f.Light }
+
+func needsSiren(f *CapabilitySet, _ *reolink.DeviceInfo) bool { return f.Siren }
+
+func needsSpeaker(f *CapabilitySet, _ *reolink.DeviceInfo) bool { return f.Siren || f.Audio.Out }
+
+// cameraMethods are the camera methods that only some cameras support. The
+// others work on every camera, so they are not listed.
+var cameraMethods = map[string]methodRequirement{
+	"ptz_control":        needsPTZ,
+	"get_ptz_presets":    needsPTZ,
+	"get_light":          needsLight,
+	"set_light":          needsLight,
+	"set_light_schedule": needsLight,
+	"configure_siren":    needsSiren,
+	"list_audio_clips":   needsSpeaker,
+	"upload_audio_clip":  needsSpeaker,
+	"select_audio_clip":  needsSpeaker,
+	"talk": func(f *CapabilitySet, _ *reolink.DeviceInfo) bool {
+		return f.Audio.Out
+	},
+	"set_lens_mode": func(f *CapabilitySet, _ *reolink.DeviceInfo) bool {
+		return f.Lens != nil
+	},
+	"get_alarm_inputs": func(_ *CapabilitySet, info *reolink.DeviceInfo) bool {
+		return info.AlarmInputs > 0
+	},
+	"alarm_output": func(_ *CapabilitySet, info *reolink.DeviceInfo) bool {
+		return info.AlarmOutputs > 0
+	},
+}
+
+// SupportedMethods reports for each method in cameraMethods whether it can
+// work on the camera, so the host can hide controls that would only fail.
+// It returns nil until the camera's abilities have been read.
+func (c *Camera) SupportedMethods() map[string]bool {
+	if c.client == nil || c.Ability() == nil {
+		return nil
+	}
+	info := c.client.GetCachedDeviceInfo()
+	if info == nil {
+		return nil
+	}
+
+	features := c.CapabilitySet()
+	methods := make(map[string]bool, len(cameraMethods))
+	for method, supported := range cameraMethods {
+		methods[method] = supported(features, info)
+	}
+	return methods
+}
+
+// APIVersion returns the HTTP API revision reported by the camera's
+// firmware, or 0 if unknown
+func (c *Camera) APIVersion() int {
+	if ability := c.Ability(); ability != nil {
+		return ability.APIVersion
+	}
+	return 0
+}
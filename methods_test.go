@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestCamera_SupportedMethods(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLC-510A", Password: "secret", APIVersion: 3})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	nvrSim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "secret", PTZ: true, AlarmOutputs: 1})
+	nvrServer := httptest.NewServer(nvrSim)
+	defer nvrServer.Close()
+	_, nvrPort := serverHostPort(nvrServer)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+			map[string]interface{}{"host": "localhost", "port": float64(nvrPort), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	cam := plugin.GetCamera(host + "_ch0")
+	if cam == nil || cam.APIVersion != 3 {
+		t.Fatalf("Expected API version 3, got %+v", cam)
+	}
+	want := map[string]bool{
+		"ptz_control": false, "get_ptz_presets": false, "get_light": false, "set_lens_mode": false,
+		"configure_siren": true, "talk": true, "upload_audio_clip": true, "alarm_output": false,
+	}
+	for method, supported := range want {
+		if got, ok := cam.Methods[method]; !ok || got != supported {
+			t.Errorf("methods[%s] = %v (listed %v), want %v", method, got, ok, supported)
+		}
+	}
+	if len(cam.Methods) != len(cameraMethods) {
+		t.Errorf("Expected %d methods, got %v", len(cameraMethods), cam.Methods)
+	}
+	if _, listed := cam.Methods["get_snapshot"]; listed {
+		t.Error("Methods every camera supports should not be listed")
+	}
+
+	nvrCam := plugin.GetCamera("localhost_ch1")
+	if nvrCam == nil || !nvrCam.Methods["ptz_control"] || !nvrCam.Methods["alarm_output"] || nvrCam.APIVersion != 0 {
+		t.Errorf("Unexpected NVR camera: %+v", nvrCam)
+	}
+
+	// Cameras whose abilities are unknown list no methods rather than
+	// claiming everything is unsupported
+	if methods := NewCamera("10.0.0.9_ch0", "Cam", "RLC-510A", "10.0.0.9", 0, nil).SupportedMethods(); methods != nil {
+		t.Errorf("Expected no methods without a client, got %v", methods)
+	}
+}
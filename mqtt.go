@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mqttDialTimeout bounds connecting to the broker and waiting for CONNACK
+const mqttDialTimeout = 10 * time.Second
+
+// MQTT 3.1.1 control packet types, shifted into the fixed header
+const (
+	mqttConnect    = 1 << 4
+	mqttConnack    = 2 << 4
+	mqttPublish    = 3 << 4
+	mqttDisconnect = 14 << 4
+)
+
+// MQTTConfig is the broker and topic a snapshot sink publishes to
+type MQTTConfig struct {
+	Broker   string `json:"broker"` // tcp://, mqtt://, ssl:// or mqtts:// URL
+	Topic    string `json:"topic"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Retain   bool   `json:"retain,omitempty"` // keep the last image on the broker for new subscribers
+}
+
+// validate checks the broker URL and topic
+func (cfg *MQTTConfig) validate() error {
+	if _, _, err := cfg.address(); err != nil {
+		return err
+	}
+	if cfg.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if strings.ContainsAny(cfg.Topic, "+#") {
+		return fmt.Errorf("topic must not contain wildcards: %s", cfg.Topic)
+	}
+	if cfg.Password != "" && cfg.Username == "" {
+		return fmt.Errorf("password requires username")
+	}
+	return nil
+}
+
+// address returns the host:port of the broker and whether it uses TLS
+func (cfg *MQTTConfig) address() (string, bool, error) {
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("invalid broker: %q", cfg.Broker)
+	}
+	var secure bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("invalid broker scheme %q (must be tcp, mqtt, ssl or mqtts)", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// mqttPublisher publishes QoS 0 messages to one broker topic. It connects on
+// the first publish and reconnects after a failed one.
+type mqttPublisher struct {
+	config    MQTTConfig
+	keepAlive time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newMQTTPublisher returns a publisher that keeps its connection alive for
+// keepAlive between publishes
+func newMQTTPublisher(cfg MQTTConfig, keepAlive time.Duration) *mqttPublisher {
+	return &mqttPublisher{config: cfg, keepAlive: keepAlive}
+}
+
+// Publish sends payload to the topic, retrying once on a new connection if
+// the broker dropped the old one
+func (m *mqttPublisher) Publish(ctx context.Context, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	packet := mqttPublishPacket(m.config.Topic, payload, m.config.Retain)
+	for attempt := 0; ; attempt++ {
+		reused := m.conn != nil
+		if !reused {
+			conn, err := m.connect(ctx)
+			if err != nil {
+				return err
+			}
+			m.conn = conn
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = m.conn.SetWriteDeadline(deadline)
+		}
+		_, err := m.conn.Write(packet)
+		if err == nil {
+			return nil
+		}
+		m.conn.Close()
+		m.conn = nil
+		if !reused || attempt > 0 {
+			return fmt.Errorf("failed to publish to %s: %w", m.config.Broker, err)
+		}
+	}
+}
+
+// Close disconnects from the broker
+func (m *mqttPublisher) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn != nil {
+		_ = m.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		_, _ = m.conn.Write([]byte{mqttDisconnect, 0})
+		m.conn.Close()
+		m.conn = nil
+	}
+}
+
+// connect dials the broker and completes the CONNECT/CONNACK handshake
+func (m *mqttPublisher) connect(ctx context.Context) (net.Conn, error) {
+	addr, secure, err := m.config.address()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mqttDialTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	if secure {
+		host, _, _ := net.SplitHostPort(addr)
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", m.config.Broker, err)
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if err := m.handshake(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", m.config.Broker, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (m *mqttPublisher) handshake(conn net.Conn) error {
+	keepAlive := min(int(m.keepAlive/time.Second), 0xFFFF)
+
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	flags := byte(0x02) // clean session
+	if m.config.Username != "" {
+		flags |= 0x80
+	}
+	if m.config.Password != "" {
+		flags |= 0x40
+	}
+	body = append(body, 4, flags) // protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive))
+	body = appendMQTTString(body, m.config.ClientID)
+	if m.config.Username != "" {
+		body = appendMQTTString(body, m.config.Username)
+	}
+	if m.config.Password != "" {
+		body = appendMQTTString(body, m.config.Password)
+	}
+
+	if _, err := conn.Write(mqttPacket(mqttConnect, body)); err != nil {
+		return err
+	}
+
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		return fmt.Errorf("no CONNACK: %w", err)
+	}
+	if ack[0] != mqttConnack || ack[1] != 2 {
+		return errors.New("unexpected reply to CONNECT")
+	}
+	if code := ack[3]; code != 0 {
+		return fmt.Errorf("connection refused: %s", mqttConnackReason(code))
+	}
+	return nil
+}
+
+// mqttConnackReason describes a CONNACK return code
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+// mqttPublishPacket encodes a QoS 0 PUBLISH packet
+func mqttPublishPacket(topic string, payload []byte, retain bool) []byte {
+	header := byte(mqttPublish)
+	if retain {
+		header |= 0x01
+	}
+	body := appendMQTTString(make([]byte, 0, 2+len(topic)+len(payload)), topic)
+	return mqttPacket(header, append(body, payload...))
+}
+
+// mqttPacket prefixes body with the fixed header and its remaining length
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts MQTT connections and reports the packets it receives
+type fakeBroker struct {
+	listener net.Listener
+	packets  chan []byte // header byte followed by the body
+	refuse   byte        // CONNACK return code
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{listener: l, packets: make(chan []byte, 16)}
+	t.Cleanup(func() { l.Close() })
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) url() string { return "tcp://" + b.listener.Addr().String() }
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var header [1]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return
+		}
+		length, multiplier := 0, 1
+		for {
+			var digit [1]byte
+			if _, err := io.ReadFull(conn, digit[:]); err != nil {
+				return
+			}
+			length += int(digit[0]&0x7F) * multiplier
+			multiplier *= 128
+			if digit[0]&0x80 == 0 {
+				break
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		b.packets <- append(header[:], body...)
+
+		if header[0] == mqttConnect {
+			_, _ = conn.Write([]byte{mqttConnack, 2, 0, b.refuse})
+			if b.refuse != 0 {
+				return
+			}
+		}
+	}
+}
+
+func (b *fakeBroker) next(t *testing.T) []byte {
+	t.Helper()
+	select {
+	case packet := <-b.packets:
+		return packet
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a packet")
+		return nil
+	}
+}
+
+func TestMQTTConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     MQTTConfig
+		wantErr bool
+	}{
+		{"valid", MQTTConfig{Broker: "tcp://broker:1883", Topic: "cams/front"}, false},
+		{"tls", MQTTConfig{Broker: "mqtts://broker", Topic: "cams/front", Username: "nvr", Password: "secret"}, false},
+		{"no host", MQTTConfig{Broker: "broker", Topic: "cams/front"}, true},
+		{"bad scheme", MQTTConfig{Broker: "http://broker", Topic: "cams/front"}, true},
+		{"no topic", MQTTConfig{Broker: "tcp://broker"}, true},
+		{"wildcard", MQTTConfig{Broker: "tcp://broker", Topic: "cams/#"}, true},
+		{"password without username", MQTTConfig{Broker: "tcp://broker", Topic: "cams/front", Password: "secret"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMQTTPublisher_Publish(t *testing.T) {
+	broker := newFakeBroker(t)
+	pub := newMQTTPublisher(MQTTConfig{
+		Broker: broker.url(), Topic: "cams/front", Username: "nvr", Password: "secret",
+		ClientID: "reolink-front", Retain: true,
+	}, time.Minute)
+	defer pub.Close()
+
+	payload := bytes.Repeat([]byte{0xFF, 0xD8}, 200) // needs a two-byte remaining length
+	if err := pub.Publish(context.Background(), payload); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	connect := broker.next(t)
+	if connect[0] != mqttConnect || string(connect[3:7]) != "MQTT" || connect[8] != 0xC2 {
+		t.Errorf("Unexpected CONNECT % x", connect[:9])
+	}
+	if keepAlive := binary.BigEndian.Uint16(connect[9:11]); keepAlive != 60 {
+		t.Errorf("Expected a keep alive of 60s, got %d", keepAlive)
+	}
+	if !bytes.Contains(connect, []byte("reolink-front")) || !bytes.Contains(connect, []byte("secret")) {
+		t.Error("Expected the client ID and credentials in CONNECT")
+	}
+
+	publish := broker.next(t)
+	if publish[0] != mqttPublish|0x01 {
+		t.Errorf("Expected a retained QoS 0 PUBLISH, got header %#x", publish[0])
+	}
+	if topicLen := binary.BigEndian.Uint16(publish[1:3]); string(publish[3:3+topicLen]) != "cams/front" {
+		t.Errorf("Unexpected topic in % x", publish[:16])
+	}
+	if !bytes.Equal(publish[3+len("cams/front"):], payload) {
+		t.Error("Unexpected payload")
+	}
+
+	// The connection is reused
+	if err := pub.Publish(context.Background(), []byte{1}); err != nil {
+		t.Fatalf("Second publish failed: %v", err)
+	}
+	if packet := broker.next(t); packet[0]&0xF0 != mqttPublish {
+		t.Errorf("Expected a PUBLISH without a new CONNECT, got header %#x", packet[0])
+	}
+}
+
+func TestMQTTPublisher_Refused(t *testing.T) {
+	broker := newFakeBroker(t)
+	broker.refuse = 4
+	pub := newMQTTPublisher(MQTTConfig{Broker: broker.url(), Topic: "cams/front"}, time.Minute)
+
+	err := pub.Publish(context.Background(), []byte{1})
+	if err == nil || !strings.Contains(err.Error(), "bad username or password") {
+		t.Errorf("Expected a refused connection, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+)
+
+// defaultPluginID is the plugin ID reported to the host unless configured
+// otherwise
+const defaultPluginID = "reolink"
+
+// namespace identifies a plugin instance to the host, so several instances
+// (e.g. one per site) can run side by side without their IDs colliding
+type namespace struct {
+	PluginID     string
+	CameraPrefix string // prepended to every camera ID
+}
+
+// namespace returns the configured namespace of the plugin
+func (p *Plugin) namespace() namespace {
+	if ns := p.ns.Load(); ns != nil {
+		return *ns
+	}
+	return namespace{PluginID: defaultPluginID}
+}
+
+// cameraID returns the ID of a device channel's camera
+func (p *Plugin) cameraID(host string, channel int) string {
+	return fmt.Sprintf("%s%s_ch%d", p.namespace().CameraPrefix, host, channel)
+}
+
+// validIDChars reports whether s only has characters that are safe in IDs
+// the host may put in URLs and file names
+func validIDChars(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestParseNamespace(t *testing.T) {
+	tests := []struct {
+		config  map[string]interface{}
+		want    namespace
+		wantErr bool
+	}{
+		{map[string]interface{}{}, namespace{PluginID: "reolink"}, false},
+		{map[string]interface{}{"plugin_id": ""}, namespace{PluginID: "reolink"}, false},
+		{map[string]interface{}{"plugin_id": "reolink-garage", "camera_id_prefix": "garage_"}, namespace{PluginID: "reolink-garage", CameraPrefix: "garage_"}, false},
+		{map[string]interface{}{"plugin_id": "reolink/garage"}, namespace{}, true},
+		{map[string]interface{}{"plugin_id": 1.0}, namespace{}, true},
+		{map[string]interface{}{"camera_id_prefix": "site a "}, namespace{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseNamespace(tt.config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseNamespace(%v) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseNamespace(%v) = %+v, want %+v", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestPlugin_Namespace(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	// Two instances managing the same address keep apart
+	ids := make(map[string]bool)
+	for _, site := range []string{"north", "south"} {
+		plugin := NewPlugin()
+		plugin.SetOutput(io.Discard)
+		err := plugin.Initialize(context.Background(), map[string]interface{}{
+			"event_poll_interval_ms": float64(0),
+			"plugin_id":              "reolink-" + site,
+			"camera_id_prefix":       site + "_",
+			"devices": []interface{}{
+				map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		defer plugin.Shutdown(context.Background())
+
+		if report := plugin.InitializeReport(); report.PluginID != "reolink-"+site {
+			t.Errorf("Expected plugin ID reolink-%s, got %+v", site, report)
+		}
+		cameras := plugin.ListCameras()
+		if len(cameras) != 1 || cameras[0].ID != site+"_"+host+"_ch0" || cameras[0].PluginID != "reolink-"+site {
+			t.Fatalf("Unexpected cameras: %+v", cameras)
+		}
+		ids[cameras[0].ID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected distinct camera IDs, got %v", ids)
+	}
+
+	if id := NewPlugin().cameraID("10.0.0.2", 3); id != "10.0.0.2_ch3" {
+		t.Errorf("Expected unprefixed IDs by default, got %s", id)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Network change timing. Cameras restart their network stack when the
+// addressing changes, which takes some seconds and longer over WiFi.
+const (
+	defaultNetworkWait   = 90 * time.Second
+	maxNetworkWait       = 5 * time.Minute
+	networkRetryInterval = 2 * time.Second
+	addressProbeTimeout  = 2 * time.Second
+)
+
+// NetworkRequest is a configure_network request
+type NetworkRequest struct {
+	CameraID string   `json:"camera_id"`
+	Mode     string   `json:"mode"` // "static" or "dhcp"
+	IP       string   `json:"ip,omitempty"`
+	Mask     string   `json:"mask,omitempty"`
+	Gateway  string   `json:"gateway,omitempty"`
+	DNS      []string `json:"dns,omitempty"` // up to 2 servers; from DHCP if empty in dhcp mode
+
+	// Host is the address the device answers at after switching to DHCP,
+	// e.g. its reserved lease; its current address if empty
+	Host string `json:"host,omitempty"`
+
+	WaitMs int  `json:"wait_ms,omitempty"` // how long the device may take to come back, 90000 by default
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// NetworkChange is the result of configure_network
+type NetworkChange struct {
+	PreviousHost string             `json:"previous_host"`
+	Host         string             `json:"host"`
+	Network      *reolink.LocalLink `json:"network,omitempty"`
+	Cameras      []string           `json:"cameras"` // cameras of the device, re-announced with camera.updated
+}
+
+// link validates the request and returns the settings to send
+func (req *NetworkRequest) link() (reolink.LocalLink, error) {
+	var link reolink.LocalLink
+	if len(req.DNS) > 2 {
+		return link, fmt.Errorf("at most 2 DNS servers can be set")
+	}
+	for _, server := range req.DNS {
+		if net.ParseIP(server).To4() == nil {
+			return link, fmt.Errorf("invalid DNS server %q: must be an IPv4 address", server)
+		}
+	}
+	link.DNS = req.DNS
+	link.DNSAuto = len(req.DNS) == 0
+
+	switch req.Mode {
+	case "dhcp":
+		if req.IP != "" || req.Mask != "" || req.Gateway != "" {
+			return link, fmt.Errorf("ip, mask and gateway are only used in static mode")
+		}
+		link.Type = "DHCP"
+		return link, nil
+	case "static":
+	default:
+		return link, fmt.Errorf("invalid mode: %q (must be static or dhcp)", req.Mode)
+	}
+
+	if req.Host != "" {
+		return link, fmt.Errorf("host is only used in dhcp mode; the device answers at ip")
+	}
+	if len(req.DNS) == 0 {
+		return link, fmt.Errorf("static mode requires dns")
+	}
+	ip := net.ParseIP(req.IP).To4()
+	if ip == nil {
+		return link, fmt.Errorf("invalid ip %q: must be an IPv4 address", req.IP)
+	}
+	maskIP := net.ParseIP(req.Mask).To4()
+	if maskIP == nil {
+		return link, fmt.Errorf("invalid mask %q: must be an IPv4 netmask", req.Mask)
+	}
+	mask := net.IPMask(maskIP)
+	if ones, bits := mask.Size(); bits == 0 || ones < 8 || ones > 30 {
+		return link, fmt.Errorf("invalid mask %s: must be a contiguous netmask from /8 to /30", req.Mask)
+	}
+	subnet := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	broadcast := make(net.IP, len(ip))
+	for i := range ip {
+		broadcast[i] = subnet.IP[i] | ^mask[i]
+	}
+	if ip.Equal(subnet.IP) || ip.Equal(broadcast) {
+		return link, fmt.Errorf("ip %s is the network or broadcast address of %s", req.IP, subnet)
+	}
+	gateway := net.ParseIP(req.Gateway).To4()
+	if gateway == nil {
+		return link, fmt.Errorf("invalid gateway %q: must be an IPv4 address", req.Gateway)
+	}
+	if !subnet.Contains(gateway) || gateway.Equal(ip) {
+		return link, fmt.Errorf("gateway %s must be another address in %s", req.Gateway, subnet)
+	}
+
+	link.Type = "Static"
+	link.IP, link.Mask, link.Gateway = ip.String(), maskIP.String(), gateway.String()
+	return link, nil
+}
+
+// target returns the address the device answers at after the change
+func (req *NetworkRequest) target(link reolink.LocalLink, current string) string {
+	switch {
+	case link.Type == "Static":
+		return link.IP
+	case req.Host != "":
+		return req.Host
+	}
+	return current
+}
+
+// reconnectWait returns how long a device may take to come back after a
+// network change, from the wait_ms of a request
+func reconnectWait(waitMs int) (time.Duration, error) {
+	if waitMs < 0 {
+		return 0, fmt.Errorf("wait_ms must not be negative")
+	}
+	if waitMs == 0 {
+		return defaultNetworkWait, nil
+	}
+	return min(time.Duration(waitMs)*time.Millisecond, maxNetworkWait), nil
+}
+
+// describeLink formats network settings, e.g. "static 192.168.1.20/24 via
+// 192.168.1.1, DNS 192.168.1.1"
+func describeLink(link reolink.LocalLink) string {
+	desc := "DHCP"
+	if link.Type == "Static" {
+		ones, _ := net.IPMask(net.ParseIP(link.Mask).To4()).Size()
+		desc = fmt.Sprintf("static %s/%d via %s", link.IP, ones, link.Gateway)
+	}
+	if link.DNSAuto {
+		return desc + ", DNS from DHCP"
+	}
+	return desc + ", DNS " + strings.Join(link.DNS, ", ")
+}
+
+// refusedChange reports whether err from a change that moves a device off its
+// address is final. The device drops its connections as the change takes
+// effect, so the reply may never arrive: only a refusal by the device is
+// final, and any other error means it should be looked for at its new address.
+func refusedChange(err error) bool {
+	var apiErr *reolink.APIError
+	return errors.As(err, &apiErr)
+}
+
+// checkAddressFree refuses an address something already answers at, as
+// moving the device there would leave both unreachable
+func checkAddressFree(host string, port int) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), addressProbeTimeout)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+	return fmt.Errorf("address %s is already in use: port %d answers", host, port)
+}
+
+// PlanNetworkChange checks a configure_network request and the new address
+// without changing the device
+func (p *Plugin) PlanNetworkChange(req NetworkRequest) (*DryRunResult, error) {
+	cam, err := p.lookupCamera(req.CameraID)
+	if err != nil {
+		return nil, err
+	}
+	link, err := req.link()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reconnectWait(req.WaitMs); err != nil {
+		return nil, err
+	}
+
+	client := cam.client
+	host := req.target(link, client.Host())
+	if host != client.Host() {
+		if err := checkAddressFree(host, client.Port()); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := newDryRunResult(req.CameraID)
+	plan.Changes = append(plan.Changes, fmt.Sprintf("set the network of %s to %s", client.Host(), describeLink(link)))
+	plan.Changes = append(plan.Changes, fmt.Sprintf("reconnect to the device at %s", host))
+	for _, c := range p.camerasOf(client) {
+		plan.Changes = append(plan.Changes, fmt.Sprintf("move camera %s to %s", c.ID(), host))
+	}
+	return plan, nil
+}
+
+// ConfigureNetwork switches a camera's device between DHCP and static
+// addressing (SetLocalLink). A static address something already answers at
+// is refused. After the change the device is reconnected at its new address,
+// checked to be the same device by serial, and its cameras are moved there
+// under their IDs and announced with camera.updated; the stored device
+// config follows, and the host must save the new address in its own config.
+//
+// If the device does not come back within the wait it cannot be rolled back
+// from here, and has to be found with discover_cameras.
+func (p *Plugin) ConfigureNetwork(ctx context.Context, req NetworkRequest) (*NetworkChange, error) {
+	cam, err := p.lookupCamera(req.CameraID)
+	if err != nil {
+		return nil, err
+	}
+	link, err := req.link()
+	if err != nil {
+		return nil, err
+	}
+	wait, err := reconnectWait(req.WaitMs)
+	if err != nil {
+		return nil, err
+	}
+
+	old := cam.client
+	previous := old.Host()
+	host := req.target(link, previous)
+	if host != previous {
+		if err := checkAddressFree(host, old.Port()); err != nil {
+			return nil, err
+		}
+	}
+	var serial string
+	if info := old.GetCachedDeviceInfo(); info != nil {
+		serial = info.Serial
+	}
+
+	err = p.workerFor(old).do(longRunning(ctx), func(ctx context.Context) error {
+		return old.SetLocalLink(ctx, link)
+	})
+	if refusedChange(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("No answer to SetLocalLink from %s (%v), looking for it at %s", previous, err, host)
+	}
+	log.Printf("Set the network of %s to %s", previous, describeLink(link))
+
+	device := p.deviceConfigOf(old)
+	device.Host = host
+	client, err := p.reconnectDevice(ctx, old, device, serial, wait)
+	if err != nil {
+		return nil, fmt.Errorf("device did not come back at %s (find it with discover_cameras): %w", host, err)
+	}
+
+	result := &NetworkChange{PreviousHost: previous, Host: host, Network: client.GetCachedLocalLink(), Cameras: []string{}}
+	for _, c := range p.relocateDevice(old, client) {
+		result.Cameras = append(result.Cameras, c.ID())
+		p.notifyCameraUpdated(c)
+	}
+	log.Printf("Moved %d cameras from %s to %s", len(result.Cameras), previous, host)
+
+	// Logging out at the old address may have to time out
+	goGuarded(p.ctx, "release of "+previous, func() { p.releaseClient(p.ctx, old) })
+	return result, nil
+}
+
+// deviceConfigOf returns the stored config of a client's device; cameras
+// added with add_camera have none, and keep their settings in the client
+func (p *Plugin) deviceConfigOf(client *reolink.Client) DeviceConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, device := range p.devices {
+		if device.Host == client.Host() {
+			return device
+		}
+	}
+	return DeviceConfig{Host: client.Host(), Port: client.Port()}
+}
+
+// reconnectDevice logs in to a device at device.Host with the settings of
+// old until it answers as the device with the given serial or wait elapses
+func (p *Plugin) reconnectDevice(ctx context.Context, old *reolink.Client, device DeviceConfig, serial string, wait time.Duration) (*reolink.Client, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		client := old.WithHost(device.Host)
+		info, _, err := p.loginDevice(client, device)
+		if err == nil {
+			if serial == "" || info.Serial == serial {
+				return client, nil
+			}
+			_ = client.Close(ctx)
+			return nil, fmt.Errorf("another device (serial %s) answers at %s", info.Serial, device.Host)
+		}
+		if time.Now().Add(networkRetryInterval).After(deadline) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(networkRetryInterval):
+		}
+	}
+}
+
+// relocateDevice moves the cameras of old to client, keeping their IDs,
+// points the stored device config at the new address and returns the moved
+// cameras
+func (p *Plugin) relocateDevice(old, client *reolink.Client) []*Camera {
+	p.mu.Lock()
+	var moved []*Camera
+	for id, cam := range p.cameras {
+		if cam.client == old {
+			p.cameras[id] = cam.relocated(client.Host(), client)
+			moved = append(moved, p.cameras[id])
+		}
+	}
+	var fallbackHosts []string
+	for i := range p.devices {
+		if p.devices[i].Host == old.Host() {
+			p.devices[i].Host = client.Host()
+			fallbackHosts = p.devices[i].FallbackHosts
+		}
+	}
+	p.mu.Unlock()
+
+	p.enableFailover(client, fallbackHosts)
+	p.startWorker(client)
+	return moved
+}
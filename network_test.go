@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestNetworkRequest_Link(t *testing.T) {
+	static := func(ip, mask, gateway string, dns ...string) NetworkRequest {
+		return NetworkRequest{Mode: "static", IP: ip, Mask: mask, Gateway: gateway, DNS: dns}
+	}
+	tests := []struct {
+		req     NetworkRequest
+		want    string
+		wantErr bool
+	}{
+		{static("192.168.1.20", "255.255.255.0", "192.168.1.1", "192.168.1.1"), "static 192.168.1.20/24 via 192.168.1.1, DNS 192.168.1.1", false},
+		{NetworkRequest{Mode: "dhcp"}, "DHCP, DNS from DHCP", false},
+		{NetworkRequest{Mode: "dhcp", DNS: []string{"1.1.1.1", "8.8.8.8"}}, "DHCP, DNS 1.1.1.1, 8.8.8.8", false},
+		{NetworkRequest{Mode: "auto"}, "", true},
+		{NetworkRequest{Mode: "dhcp", IP: "192.168.1.20"}, "", true},
+		{static("192.168.1.20", "255.255.255.0", "192.168.1.1"), "", true},
+		{static("192.168.1.20", "255.0.255.0", "192.168.1.1", "192.168.1.1"), "", true},
+		{static("192.168.1.20", "255.255.255.0", "192.168.2.1", "192.168.1.1"), "", true},
+		{static("192.168.1.20", "255.255.255.0", "192.168.1.20", "192.168.1.1"), "", true},
+		{static("192.168.1.255", "255.255.255.0", "192.168.1.1", "192.168.1.1"), "", true},
+		{static("fe80::1", "255.255.255.0", "192.168.1.1", "192.168.1.1"), "", true},
+		{static("192.168.1.20", "255.255.255.0", "192.168.1.1", "1.1.1.1", "8.8.8.8", "9.9.9.9"), "", true},
+		{NetworkRequest{Mode: "static", IP: "192.168.1.20", Mask: "255.255.255.0", Gateway: "192.168.1.1", DNS: []string{"1.1.1.1"}, Host: "cam.lan"}, "", true},
+	}
+	for _, tt := range tests {
+		link, err := tt.req.link()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("link(%+v) error = %v, wantErr %v", tt.req, err, tt.wantErr)
+			continue
+		}
+		if err == nil && describeLink(link) != tt.want {
+			t.Errorf("link(%+v) = %q, want %q", tt.req, describeLink(link), tt.want)
+		}
+	}
+}
+
+func TestPlugin_ConfigureNetwork(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+	cameraID := host + "_ch0"
+
+	var out syncBuffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	request := func(ip string, dryRun bool) JSONRPCResponse {
+		params := `{"camera_id": "` + cameraID + `", "mode": "static", "ip": "` + ip + `", "mask": "255.0.0.0", "gateway": "127.0.0.1", "dns": ["127.0.0.1"], "wait_ms": 10000, "dry_run": ` + strconv.FormatBool(dryRun) + `}`
+		return plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "configure_network", Params: []byte(params)})
+	}
+
+	// An address something answers at is refused
+	taken, err := net.Listen("tcp", net.JoinHostPort("127.0.0.3", strconv.Itoa(port)))
+	if err != nil {
+		t.Skipf("Cannot listen on 127.0.0.3: %v", err)
+	}
+	defer taken.Close()
+	if resp := request("127.0.0.3", false); resp.Error == nil {
+		t.Error("Expected an address in use to be refused")
+	}
+	if mode, _ := sim.LocalLink(); mode != "DHCP" {
+		t.Fatalf("Expected the device to be left alone, got %s", mode)
+	}
+
+	resp := request("127.0.0.2", true)
+	if resp.Error != nil {
+		t.Fatalf("Dry run failed: %+v", resp.Error)
+	}
+	if plan := resp.Result.(*DryRunResult); len(plan.Changes) != 3 {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if mode, _ := sim.LocalLink(); mode != "DHCP" {
+		t.Fatalf("Expected the dry run to leave the device alone, got %s", mode)
+	}
+
+	// The device shows up at its new address once it has applied the change
+	moved := httptest.NewUnstartedServer(sim)
+	defer moved.Close()
+	go func() {
+		for {
+			if mode, ip := sim.LocalLink(); mode == "Static" && ip == "127.0.0.2" {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.2", strconv.Itoa(port)))
+		if err != nil {
+			t.Errorf("Listen failed: %v", err)
+			return
+		}
+		moved.Listener = l
+		moved.Start()
+	}()
+
+	resp = request("127.0.0.2", false)
+	if resp.Error != nil {
+		t.Fatalf("configure_network failed: %+v", resp.Error)
+	}
+	result := resp.Result.(*NetworkChange)
+	if result.PreviousHost != host || result.Host != "127.0.0.2" || len(result.Cameras) != 1 || result.Cameras[0] != cameraID {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.Network == nil || result.Network.Type != "Static" || result.Network.Gateway != "127.0.0.1" {
+		t.Errorf("Expected the new network settings, got %+v", result.Network)
+	}
+
+	cam := plugin.GetCamera(cameraID)
+	if cam == nil || cam.Host != "127.0.0.2" {
+		t.Fatalf("Expected the camera to keep its ID at the new address, got %+v", cam)
+	}
+	if plugin.devices[0].Host != "127.0.0.2" {
+		t.Errorf("Expected the stored device config to follow, got %s", plugin.devices[0].Host)
+	}
+	var updated bool
+	for _, msg := range readLifecycle(t, bytes.NewBufferString(out.String())) {
+		if msg.Method == NotifyCameraUpdated && msg.Params.Camera.Host == "127.0.0.2" {
+			updated = true
+		}
+	}
+	if !updated {
+		t.Error("Expected camera.updated with the new address")
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxONVIFNotifySize is the largest Notify message accepted by the bridge
+const maxONVIFNotifySize = 1 << 20
+
+// onvifTopicEvents maps the last segment of ONVIF event topics, lower case,
+// to event types. Reolink uses the RuleEngine/MyRuleDetector topics; the
+// others are the names used by common ONVIF devices and listeners.
+var onvifTopicEvents = map[string]string{
+	"motion":        EventMotion, // RuleEngine/CellMotionDetector/Motion
+	"motionalarm":   EventMotion, // VideoSource/MotionAlarm
+	"motiondetect":  EventMotion,
+	"peopledetect":  EventPerson,
+	"persondetect":  EventPerson,
+	"humandetect":   EventPerson,
+	"vehicledetect": EventVehicle,
+	"dogcatdetect":  EventAnimal,
+	"animaldetect":  EventAnimal,
+	"petdetect":     EventAnimal,
+	"facedetect":    EventFace,
+	"packagedetect": EventPackage,
+	"visitor":       EventDoorbell,
+	"doorbell":      EventDoorbell,
+}
+
+// ONVIFNotifyRequest is an onvif_notify request: a WS-BaseNotification
+// Notify message forwarded by an ONVIF event listener
+type ONVIFNotifyRequest struct {
+	XML string `json:"xml"`
+
+	// Source is the IP or host of the camera that sent the notifications.
+	// If empty it is taken from each message's ProducerReference.
+	Source string `json:"source,omitempty"`
+	// Serial identifies the camera by device serial instead of address
+	Serial string `json:"serial,omitempty"`
+	// Channel selects the camera of a multi-channel device; the lowest
+	// channel is used if unset
+	Channel *int `json:"channel,omitempty"`
+}
+
+// onvifMessage is one NotificationMessage of a Notify, matched by local
+// names so any namespace prefixes are accepted
+type onvifMessage struct {
+	Topic    string `xml:"Topic"`
+	Producer string `xml:"ProducerReference>Address"`
+	Message  struct {
+		UtcTime string      `xml:"UtcTime,attr"`
+		Data    []onvifItem `xml:"Data>SimpleItem"`
+	} `xml:"Message>Message"`
+}
+
+type onvifItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+// parseONVIFNotify returns the NotificationMessages of a Notify message,
+// with or without its SOAP envelope
+func parseONVIFNotify(r io.Reader) ([]onvifMessage, error) {
+	dec := xml.NewDecoder(r)
+	var messages []onvifMessage
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid ONVIF notification: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "NotificationMessage" {
+			continue
+		}
+		var msg onvifMessage
+		if err := dec.DecodeElement(&msg, &start); err != nil {
+			return nil, fmt.Errorf("invalid ONVIF notification: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no NotificationMessage in ONVIF notification")
+	}
+	return messages, nil
+}
+
+// eventType returns the plugin event type of the message's topic
+func (m *onvifMessage) eventType() (string, bool) {
+	topic := strings.TrimSpace(m.Topic)
+	if i := strings.LastIndex(topic, "/"); i >= 0 {
+		topic = topic[i+1:]
+	}
+	if i := strings.LastIndex(topic, ":"); i >= 0 {
+		topic = topic[i+1:]
+	}
+	typ, ok := onvifTopicEvents[strings.ToLower(topic)]
+	return typ, ok
+}
+
+// active returns the boolean state carried in the message's data, e.g.
+// IsMotion="true"
+func (m *onvifMessage) active() (bool, bool) {
+	for _, item := range m.Message.Data {
+		switch strings.ToLower(strings.TrimSpace(item.Value)) {
+		case "true", "1":
+			return true, true
+		case "false", "0":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// producerHost returns the host of the message's ProducerReference address
+func (m *onvifMessage) producerHost() string {
+	u, err := url.Parse(strings.TrimSpace(m.Producer))
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// onvifBridge converts ONVIF notifications into plugin events. It keeps the
+// last state per camera and event type, since devices repeat states (such as
+// the initial property state) that are not changes.
+type onvifBridge struct {
+	mu     sync.Mutex
+	states map[string]bool
+	server *http.Server
+	token  string // accepted from senders other than loopback and the cameras
+}
+
+// changed records state for key and reports whether it differs from the last
+func (b *onvifBridge) changed(key string, state bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.states == nil {
+		b.states = make(map[string]bool)
+	}
+	prev, seen := b.states[key]
+	b.states[key] = state
+	return (!seen && state) || (seen && prev != state)
+}
+
+// HandleONVIFNotify converts the NotificationMessages of an ONVIF Notify
+// into events of the managed camera they came from, and returns the events
+// emitted. Messages with unknown topics or from unknown cameras are skipped.
+func (p *Plugin) HandleONVIFNotify(req ONVIFNotifyRequest) ([]Event, error) {
+	messages, err := parseONVIFNotify(strings.NewReader(req.XML))
+	if err != nil {
+		return nil, err
+	}
+	return p.bridgeONVIF(messages, req, "")
+}
+
+// bridgeONVIF emits the events of parsed messages. A message's camera is
+// identified by req, else its ProducerReference, else the fallback host.
+func (p *Plugin) bridgeONVIF(messages []onvifMessage, req ONVIFNotifyRequest, fallback string) ([]Event, error) {
+	events := []Event{}
+	var unmatched []string
+	for _, msg := range messages {
+		typ, ok := msg.eventType()
+		if !ok {
+			continue
+		}
+		active, ok := msg.active()
+		if !ok {
+			continue
+		}
+
+		source := req.Source
+		if source == "" {
+			source = msg.producerHost()
+		}
+		if source == "" {
+			source = fallback
+		}
+		cam := p.onvifCamera(source, req.Serial, req.Channel)
+		if cam == nil {
+			if req.Serial != "" {
+				source = req.Serial
+			}
+			unmatched = append(unmatched, source)
+			continue
+		}
+
+		// A doorbell press is instantaneous; only the press is an event
+		if typ == EventDoorbell && !active {
+			p.onvif.changed(cam.ID()+"/"+typ, false)
+			continue
+		}
+		if !p.onvif.changed(cam.ID()+"/"+typ, active) {
+			continue
+		}
+
+		ev := Event{
+			Type:     typ,
+			State:    EventStart,
+			CameraID: cam.ID(),
+			Data:     map[string]interface{}{"source": "onvif", "topic": strings.TrimSpace(msg.Topic)},
+		}
+		if !active {
+			ev.State = EventEnd
+		}
+		if t, err := time.Parse(time.RFC3339, msg.Message.UtcTime); err == nil {
+			ev.Timestamp = t
+		}
+		events = append(events, p.emitEvent(ev))
+	}
+
+	if len(events) == 0 && len(unmatched) > 0 {
+		return nil, fmt.Errorf("no managed camera for ONVIF source %q", unmatched[0])
+	}
+	return events, nil
+}
+
+// onvifCamera finds the managed camera by device serial, else by host. Of a
+// multi-channel device, the given channel or else the lowest is used.
+func (p *Plugin) onvifCamera(host, serial string, channel *int) *Camera {
+	p.mu.RLock()
+	var matches []*Camera
+	for _, cam := range p.cameras {
+		if serial != "" {
+			if cam.client == nil {
+				continue
+			}
+			if info := cam.client.GetCachedDeviceInfo(); info == nil || info.Serial != serial {
+				continue
+			}
+		} else if host == "" || !sameHost(cam.Host(), host) {
+			continue
+		}
+		if channel != nil && cam.Channel() != *channel {
+			continue
+		}
+		matches = append(matches, cam)
+	}
+	p.mu.RUnlock()
+
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Channel() < matches[j].Channel() })
+	return matches[0]
+}
+
+// sameHost compares hosts, treating different spellings of an IP as equal
+func sameHost(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipB != nil && ipA.Equal(ipB)
+}
+
+// onvifBridgeAddr validates an ONVIF bridge listen address; a bare ":port"
+// binds to 127.0.0.1. Other addresses only accept cameras and senders with
+// the token, so they are refused without one.
+func onvifBridgeAddr(addr, token string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid ONVIF bridge address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if _, ok := loopbackHost(host); !ok && token == "" {
+		return "", fmt.Errorf("ONVIF bridge address %q must be on localhost unless onvif_bridge_token is set", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// startONVIFBridge accepts ONVIF Notify messages posted to addr, e.g. by
+// subscriptions of cameras or an existing event listener, until Shutdown.
+// A managed camera is identified by its address. Other senders, such as a
+// listener forwarding for the cameras, must be on loopback or pass token as
+// a bearer token; their messages are matched by ProducerReference, else by
+// the sender's address.
+func (p *Plugin) startONVIFBridge(addr, token string) error {
+	addr, err := onvifBridgeAddr(addr, token)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for ONVIF notifications: %w", err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(p.serveONVIFNotify), ReadHeaderTimeout: 10 * time.Second}
+	p.onvif.mu.Lock()
+	if p.onvif.server != nil {
+		p.onvif.mu.Unlock()
+		listener.Close()
+		return nil
+	}
+	p.onvif.server = srv
+	p.onvif.token = token
+	p.onvif.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("ONVIF bridge stopped: %v", err)
+		}
+	}()
+	log.Printf("Accepting ONVIF notifications on http://%s/", listener.Addr())
+	return nil
+}
+
+// stopONVIFBridge closes the ONVIF bridge listener, if running
+func (p *Plugin) stopONVIFBridge() {
+	p.onvif.mu.Lock()
+	srv := p.onvif.server
+	p.onvif.server = nil
+	p.onvif.mu.Unlock()
+
+	if srv != nil {
+		_ = srv.Close()
+	}
+}
+
+func (p *Plugin) serveONVIFNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxONVIFNotifySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	messages, err := parseONVIFNotify(bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sender, _, _ := net.SplitHostPort(r.RemoteAddr)
+	var req ONVIFNotifyRequest
+	switch {
+	case p.onvifCamera(sender, "", nil) != nil:
+		// A camera only speaks for itself, whatever its messages say
+		req.Source = sender
+	case !p.onvifTrusted(r, sender):
+		log.Printf("Refused ONVIF notification from %s: not a managed camera", r.RemoteAddr)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if _, err := p.bridgeONVIF(messages, req, sender); err != nil {
+		log.Printf("Dropped ONVIF notification from %s: %v", r.RemoteAddr, err)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// onvifTrusted reports whether a sender other than a managed camera may
+// post notifications: it passes the bridge's token, or it is on loopback,
+// names a loopback Host and posts XML. A browser on the machine cannot meet
+// the last two from a DNS rebinding page or a cross-site form.
+func (p *Plugin) onvifTrusted(r *http.Request, sender string) bool {
+	p.onvif.mu.Lock()
+	token := p.onvif.token
+	p.onvif.mu.Unlock()
+	if token != "" {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			return true
+		}
+	}
+	_, loopback := loopbackHost(sender)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	xmlBody := mediaType == "application/soap+xml" || mediaType == "application/xml" || mediaType == "text/xml"
+	return loopback && sender != "" && restLoopbackHost(r.Host) && xmlBody
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// onvifNotify builds a SOAP Notify message as sent by Reolink cameras
+func onvifNotify(producer string, messages ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:wsa5="http://www.w3.org/2005/08/addressing" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tns1="http://www.onvif.org/ver10/topics">
+<SOAP-ENV:Body><wsnt:Notify>`)
+	for _, m := range messages {
+		b.WriteString(`<wsnt:NotificationMessage>`)
+		if producer != "" {
+			fmt.Fprintf(&b, `<wsnt:ProducerReference><wsa5:Address>http://%s:8000/onvif/event_service</wsa5:Address></wsnt:ProducerReference>`, producer)
+		}
+		b.WriteString(m)
+		b.WriteString(`</wsnt:NotificationMessage>`)
+	}
+	b.WriteString(`</wsnt:Notify></SOAP-ENV:Body></SOAP-ENV:Envelope>`)
+	return b.String()
+}
+
+func onvifMessageXML(topic, item, value string) string {
+	return fmt.Sprintf(`<wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">%s</wsnt:Topic>
+<wsnt:Message><tt:Message UtcTime="2026-01-02T15:04:05Z" PropertyOperation="Changed">
+<tt:Source><tt:SimpleItem Name="Source" Value="000"/></tt:Source>
+<tt:Data><tt:SimpleItem Name="%s" Value="%s"/></tt:Data>
+</tt:Message></wsnt:Message>`, topic, item, value)
+}
+
+func TestPlugin_HandleONVIFNotify(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.cameras["10.0.0.5_ch1"] = NewCamera("10.0.0.5_ch1", "Gate", "RLN8-410", "10.0.0.5", 1, nil)
+	plugin.cameras["10.0.0.5_ch0"] = NewCamera("10.0.0.5_ch0", "Yard", "RLN8-410", "10.0.0.5", 0, nil)
+
+	events, err := plugin.HandleONVIFNotify(ONVIFNotifyRequest{XML: onvifNotify("10.0.0.5",
+		onvifMessageXML("tns1:RuleEngine/CellMotionDetector/Motion", "IsMotion", "true"),
+		onvifMessageXML("tns1:RuleEngine/MyRuleDetector/PeopleDetect", "State", "true"),
+		onvifMessageXML("tns1:RuleEngine/MyRuleDetector/Unknown", "State", "true"),
+	)})
+	if err != nil {
+		t.Fatalf("HandleONVIFNotify failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected motion and person events, got %+v", events)
+	}
+	if events[0].Type != EventMotion || events[0].State != EventStart || events[0].CameraID != "10.0.0.5_ch0" {
+		t.Errorf("Unexpected motion event %+v", events[0])
+	}
+	if events[1].Type != EventPerson || events[1].Data["source"] != "onvif" || !events[1].Timestamp.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected person event %+v", events[1])
+	}
+
+	// Repeated states are not changes; an explicit channel picks the camera
+	channel := 1
+	events, err = plugin.HandleONVIFNotify(ONVIFNotifyRequest{Source: "10.0.0.5", XML: onvifNotify("",
+		onvifMessageXML("tns1:RuleEngine/CellMotionDetector/Motion", "IsMotion", "true"),
+	)})
+	if err != nil || len(events) != 0 {
+		t.Errorf("Expected a repeated state to be dropped, got %+v, %v", events, err)
+	}
+	events, err = plugin.HandleONVIFNotify(ONVIFNotifyRequest{Source: "10.0.0.5", Channel: &channel, XML: onvifNotify("",
+		onvifMessageXML("tns1:RuleEngine/CellMotionDetector/Motion", "IsMotion", "true"),
+	)})
+	if err != nil || len(events) != 1 || events[0].CameraID != "10.0.0.5_ch1" {
+		t.Errorf("Expected motion on channel 1, got %+v, %v", events, err)
+	}
+
+	events, err = plugin.HandleONVIFNotify(ONVIFNotifyRequest{XML: onvifNotify("10.0.0.5",
+		onvifMessageXML("tns1:RuleEngine/CellMotionDetector/Motion", "IsMotion", "false"),
+	)})
+	if err != nil || len(events) != 1 || events[0].State != EventEnd {
+		t.Errorf("Expected motion to end, got %+v, %v", events, err)
+	}
+
+	if _, err := plugin.HandleONVIFNotify(ONVIFNotifyRequest{XML: onvifNotify("10.9.9.9",
+		onvifMessageXML("tns1:RuleEngine/CellMotionDetector/Motion", "IsMotion", "true"),
+	)}); err == nil || !strings.Contains(err.Error(), "10.9.9.9") {
+		t.Errorf("Expected an unknown camera error, got %v", err)
+	}
+	if _, err := plugin.HandleONVIFNotify(ONVIFNotifyRequest{XML: "<Notify/>"}); err == nil {
+		t.Error("Expected an error without notification messages")
+	}
+}
+
+func TestPlugin_ServeONVIFNotify(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.cameras["127.0.0.1_ch0"] = NewCamera("127.0.0.1_ch0", "Door", "Reolink Video Doorbell PoE", "127.0.0.1", 0, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(plugin.serveONVIFNotify))
+	defer server.Close()
+
+	// Without a producer reference the sender is the camera
+	body := onvifNotify("", onvifMessageXML("tns1:RuleEngine/MyRuleDetector/Visitor", "State", "true"))
+	resp, err := http.Post(server.URL, "application/soap+xml", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	events := plugin.GetEvents(0, "", 0).Events
+	if len(events) != 1 || events[0].Type != EventDoorbell || events[0].CameraID != "127.0.0.1_ch0" {
+		t.Errorf("Expected a doorbell press, got %+v", events)
+	}
+
+	resp, err = http.Post(server.URL, "application/soap+xml", strings.NewReader("not xml"))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid body, got %d", resp.StatusCode)
+	}
+}
+
+func TestONVIFBridgeAddr(t *testing.T) {
+	tests := []struct {
+		addr, token, want string
+		wantErr           bool
+	}{
+		{":8089", "", "127.0.0.1:8089", false},
+		{"localhost:8089", "", "localhost:8089", false},
+		{"[::1]:8089", "", "[::1]:8089", false},
+		{"0.0.0.0:8089", "", "", true},
+		{"192.168.1.10:8089", "", "", true},
+		{"0.0.0.0:8089", "secret", "0.0.0.0:8089", false},
+		{"8089", "secret", "", true},
+	}
+	for _, tt := range tests {
+		got, err := onvifBridgeAddr(tt.addr, tt.token)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("onvifBridgeAddr(%q, %q) error = %v, wantErr %v", tt.addr, tt.token, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("onvifBridgeAddr(%q, %q) = %q, want %q", tt.addr, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestPlugin_ServeONVIFNotify_Senders(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.cameras["192.0.2.10_ch0"] = NewCamera("192.0.2.10_ch0", "Gate", "RLC-810A", "192.0.2.10", 0, nil)
+	plugin.cameras["192.0.2.11_ch0"] = NewCamera("192.0.2.11_ch0", "Door", "RLC-810A", "192.0.2.11", 0, nil)
+	plugin.onvif.token = "secret"
+
+	type sender struct{ remote, target, host, contentType, auth string }
+	post := func(s sender, producer string) int {
+		t.Helper()
+		body := onvifNotify(producer, onvifMessageXML("tns1:RuleEngine/CellMotionDetector/Motion", "IsMotion", "true"))
+		req := httptest.NewRequest(http.MethodPost, s.target, strings.NewReader(body))
+		req.RemoteAddr = s.remote
+		if s.host != "" {
+			req.Host = s.host
+		}
+		req.Header.Set("Content-Type", s.contentType)
+		if s.auth != "" {
+			req.Header.Set("Authorization", s.auth)
+		}
+		rec := httptest.NewRecorder()
+		plugin.serveONVIFNotify(rec, req)
+		// The next message is a new motion start again
+		plugin.onvif.changed("192.0.2.10_ch0/"+EventMotion, false)
+		plugin.onvif.changed("192.0.2.11_ch0/"+EventMotion, false)
+		return rec.Code
+	}
+	lastCamera := func() string {
+		events := plugin.GetEvents(0, "", 0).Events
+		if len(events) == 0 {
+			return ""
+		}
+		return events[len(events)-1].CameraID
+	}
+
+	// A camera cannot raise events for another one
+	camera := sender{remote: "192.0.2.10:40000", target: "/", contentType: "application/soap+xml"}
+	if code := post(camera, "192.0.2.11"); code != http.StatusOK || lastCamera() != "192.0.2.10_ch0" {
+		t.Errorf("Expected motion on the sending camera (%d), got %q", code, lastCamera())
+	}
+
+	// Other senders need the token as a bearer token, or to be local
+	refused := map[string]sender{
+		"an unknown sender":             {remote: "192.0.2.99:40000", target: "/", contentType: "application/soap+xml"},
+		"a wrong token":                 {remote: "192.0.2.99:40000", target: "/", contentType: "application/soap+xml", auth: "Bearer wrong"},
+		"a token in the query":          {remote: "192.0.2.99:40000", target: "/?token=secret", contentType: "application/soap+xml"},
+		"a foreign Host on loopback":    {remote: "127.0.0.1:40000", target: "/", host: "attacker.example:8089", contentType: "application/soap+xml"},
+		"a text/plain post on loopback": {remote: "127.0.0.1:40000", target: "/", host: "127.0.0.1:8089", contentType: "text/plain"},
+	}
+	for name, s := range refused {
+		if code := post(s, "192.0.2.11"); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s, got %d", name, code)
+		}
+	}
+	if lastCamera() != "192.0.2.10_ch0" {
+		t.Errorf("Expected no events from refused senders, got one on %q", lastCamera())
+	}
+
+	accepted := map[string]sender{
+		"a bearer token":     {remote: "192.0.2.99:40000", target: "/", contentType: "application/soap+xml", auth: "Bearer secret"},
+		"a local listener":   {remote: "127.0.0.1:40000", target: "/", host: "localhost:8089", contentType: "application/soap+xml; charset=utf-8"},
+		"a local XML sender": {remote: "[::1]:40000", target: "/", host: "[::1]:8089", contentType: "text/xml"},
+	}
+	for name, s := range accepted {
+		plugin.events = newEventQueue()
+		if code := post(s, "192.0.2.11"); code != http.StatusOK || lastCamera() != "192.0.2.11_ch0" {
+			t.Errorf("Expected motion on the named camera from %s (%d), got %q", name, code, lastCamera())
+		}
+	}
+}
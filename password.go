@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// PasswordRotation is the result of rotate_password
+type PasswordRotation struct {
+	Host     string   `json:"host"`
+	Username string   `json:"username"`
+	Cameras  []string `json:"cameras"` // cameras of the device, whose stream URLs changed
+}
+
+// RotatePassword changes the password of the account the plugin uses on a
+// camera's device (ModifyUser), logs in again and checks that the camera's
+// stream still opens, restoring the old password if either fails. On
+// success the stored device config is updated and the device's cameras are
+// announced with camera.updated, as their stream URLs carry the password. The
+// host must save the new password in its own config.
+func (p *Plugin) RotatePassword(ctx context.Context, cameraID, password string) (*PasswordRotation, error) {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	client := cam.client
+	err = p.workerFor(client).do(ctx, func(ctx context.Context) error {
+		return client.RotatePassword(ctx, password, func(ctx context.Context) error {
+			return client.CheckStream(ctx, cam.Channel())
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	for i := range p.devices {
+		if p.devices[i].Host == client.Host() {
+			p.devices[i].Password = password
+		}
+	}
+	p.mu.Unlock()
+	log.Printf("Rotated the password of %s on %s", client.Username(), client.Host())
+
+	result := &PasswordRotation{Host: client.Host(), Username: client.Username(), Cameras: []string{}}
+	for _, c := range p.camerasOf(client) {
+		result.Cameras = append(result.Cameras, c.ID())
+		p.notifyCameraUpdated(c)
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_RotatePassword(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", RTSPPort: l.Addr().(*net.TCPAddr).Port})
+	go sim.ServeRTSP(l)
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	host, port := serverHostPort(server)
+	client := reolink.NewClient(host, port, "admin", "secret")
+	if _, err := client.GetNetPort(context.Background()); err != nil {
+		t.Fatalf("GetNetPort failed: %v", err)
+	}
+
+	plugin := NewPlugin()
+	plugin.devices = []DeviceConfig{{Host: host, Port: port, Username: "admin", Password: "secret"}}
+	plugin.cameras["cam"] = NewCamera("cam", "Drive", "RLC-811A", host, 0, client)
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "rotate_password",
+		Params: []byte(`{"camera_id": "cam", "new_password": "n3w-secret"}`)})
+	if resp.Error != nil {
+		t.Fatalf("rotate_password failed: %s", resp.Error.Message)
+	}
+	result := resp.Result.(*PasswordRotation)
+	if result.Username != "admin" || len(result.Cameras) != 1 || result.Cameras[0] != "cam" {
+		t.Errorf("Unexpected rotation result: %+v", result)
+	}
+	if sim.Password() != "n3w-secret" {
+		t.Errorf("Expected the device password to change, got %q", sim.Password())
+	}
+	if plugin.devices[0].Password != "n3w-secret" {
+		t.Errorf("Expected the stored device config to be updated, got %q", plugin.devices[0].Password)
+	}
+
+	// Reusing the current password is refused without touching the device
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "rotate_password",
+		Params: []byte(`{"camera_id": "cam", "new_password": "n3w-secret"}`)})
+	if resp.Error == nil {
+		t.Error("Expected an error for an unchanged password")
+	}
+
+	resp = plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 3, Method: "rotate_password",
+		Params: []byte(`{"camera_id": "missing", "new_password": "x"}`)})
+	if resp.Error == nil {
+		t.Error("Expected an error for an unknown camera")
+	}
+}
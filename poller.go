@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// defaultEventPollInterval is how often cameras are polled for event state
+const defaultEventPollInterval = time.Second
+
+// detectionEvents are the event types with a start and an end, in the order
+// their changes are emitted
+var detectionEvents = []string{EventMotion, EventPerson, EventVehicle, EventAnimal, EventFace, EventPackage}
+
+// eventActive reports whether a detection event type is active in s
+func eventActive(s reolink.EventState, eventType string) bool {
+	switch eventType {
+	case EventMotion:
+		return s.Motion
+	case EventPerson:
+		return s.Person
+	case EventVehicle:
+		return s.Vehicle
+	case EventAnimal:
+		return s.Animal
+	case EventFace:
+		return s.Face
+	case EventPackage:
+		return s.Package
+	}
+	return false
+}
+
+// pollDeviceEvents polls every camera of a device once and emits events for
+// state changes since the last poll, tracked in states by camera ID.
+// Power-saving cameras are not polled, as that would keep them awake.
+func (p *Plugin) pollDeviceEvents(ctx context.Context, client *reolink.Client, states map[string]reolink.EventState) {
+	for _, cam := range p.camerasOf(client) {
+		if cam.PowerSaving() {
+			continue
+		}
+		state, err := client.GetEventState(ctx, cam.Channel())
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, reolink.ErrCircuitOpen) {
+				log.Printf("Event poll failed for %s: %v", cam.ID(), err)
+			}
+			continue
+		}
+
+		if state.FaceSupported || state.PackageSupported {
+			cam.enableAIDetection(state.FaceSupported, state.PackageSupported)
+		}
+
+		prev := states[cam.ID()]
+		states[cam.ID()] = *state
+		p.emitStateChanges(ctx, cam, prev, *state)
+	}
+}
+
+// emitStateChanges emits start/end events for every event type that changed
+func (p *Plugin) emitStateChanges(ctx context.Context, cam *Camera, prev, cur reolink.EventState) {
+	for _, eventType := range detectionEvents {
+		active := eventActive(cur, eventType)
+		if active == eventActive(prev, eventType) {
+			continue
+		}
+		state := EventEnd
+		if active {
+			state = EventStart
+		}
+		p.emitEvent(Event{Type: eventType, State: state, CameraID: cam.ID()})
+	}
+
+	if cur.Visitor && !prev.Visitor {
+		p.emitEvent(p.visitorEvent(ctx, cam, time.Now()))
+	}
+}
+
+// visitorEvent builds a doorbell press event with everything the host needs
+// to show an "answer" UI: press time, a snapshot and two-way audio support
+func (p *Plugin) visitorEvent(ctx context.Context, cam *Camera, pressedAt time.Time) Event {
+	twoWayAudio := false
+	if ability := cam.Ability(); ability != nil {
+		twoWayAudio = ability.TwoWayAudio
+	}
+
+	data := map[string]interface{}{
+		"pressed_at":    pressedAt.UTC().Format(time.RFC3339Nano),
+		"two_way_audio": twoWayAudio,
+	}
+
+	snapCtx, cancel := context.WithTimeout(ctx, cam.client.GetTimeouts().Snapshot)
+	defer cancel()
+	if snap, err := cam.client.GetSnapshot(snapCtx, cam.Channel()); err == nil {
+		data["snapshot"] = base64.StdEncoding.EncodeToString(snap)
+	} else {
+		log.Printf("Failed to capture visitor snapshot for %s: %v", cam.ID(), err)
+	}
+
+	return Event{
+		Type:      EventDoorbell,
+		State:     EventStart,
+		CameraID:  cam.ID(),
+		Timestamp: pressedAt,
+		Data:      data,
+	}
+}
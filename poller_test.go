@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestPlugin_EmitStateChanges(t *testing.T) {
+	plugin := NewPlugin()
+	cam := NewCamera("cam1", "Front", "RLC-810A", "localhost", 0, reolink.NewClient("localhost", 80, "admin", "password"))
+
+	plugin.emitStateChanges(context.Background(), cam, reolink.EventState{}, reolink.EventState{Motion: true, Person: true})
+	plugin.emitStateChanges(context.Background(), cam, reolink.EventState{Motion: true, Person: true}, reolink.EventState{Motion: true})
+
+	events := plugin.GetEvents(0, "", 0).Events
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", events)
+	}
+	expected := []struct{ typ, state string }{
+		{EventMotion, EventStart},
+		{EventPerson, EventStart},
+		{EventPerson, EventEnd},
+	}
+	for i, want := range expected {
+		if events[i].Type != want.typ || events[i].State != want.state {
+			t.Errorf("Event %d: expected %s %s, got %s %s", i, want.typ, want.state, events[i].Type, events[i].State)
+		}
+	}
+}
+
+func TestPlugin_VisitorEvent(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jpeg)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam1", "Door", "Reolink Video Doorbell", "localhost", 0, client)
+	cam.SetAbility(&reolink.Ability{TwoWayAudio: true})
+
+	// A held button only produces one event
+	plugin.emitStateChanges(context.Background(), cam, reolink.EventState{}, reolink.EventState{Visitor: true})
+	plugin.emitStateChanges(context.Background(), cam, reolink.EventState{Visitor: true}, reolink.EventState{Visitor: true})
+	plugin.emitStateChanges(context.Background(), cam, reolink.EventState{Visitor: true}, reolink.EventState{})
+
+	events := plugin.GetEvents(0, "", 0).Events
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %+v", events)
+	}
+	ev := events[0]
+	if ev.Type != EventDoorbell || ev.CameraID != "cam1" {
+		t.Errorf("Unexpected event %+v", ev)
+	}
+	if ev.Data["two_way_audio"] != true {
+		t.Error("Expected two_way_audio to be true")
+	}
+	if ev.Data["snapshot"] != base64.StdEncoding.EncodeToString(jpeg) {
+		t.Errorf("Unexpected snapshot %v", ev.Data["snapshot"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, ev.Data["pressed_at"].(string)); err != nil {
+		t.Errorf("Invalid pressed_at: %v", err)
+	}
+}
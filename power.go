@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+)
+
+// powerSavingOfflineAfter is the silence window of devices whose cameras are
+// all power-saving. The watchdog probes after half of it, so a sleeping
+// battery camera is woken at most twice an hour.
+const powerSavingOfflineAfter = time.Hour
+
+// PowerSaving reports whether the camera is spared periodic traffic
+func (c *Camera) PowerSaving() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.powerSaving
+}
+
+// SetPowerSaving enables or disables the power-saving policy of the camera
+func (c *Camera) SetPowerSaving(on bool) {
+	c.mu.Lock()
+	c.powerSaving = on
+	c.mu.Unlock()
+}
+
+// powerSavingDefault decides the policy of a newly added camera: the device's
+// power_saving setting if given, otherwise on for battery models
+func powerSavingDefault(device DeviceConfig, cam *Camera) bool {
+	if device.PowerSaving != nil {
+		return *device.PowerSaving
+	}
+	return cam.DeviceType() == "battery"
+}
+
+// allPowerSaving reports whether every camera of a device is power-saving,
+// in which case nothing may poll the device on a schedule
+func allPowerSaving(cameras []*Camera) bool {
+	for _, cam := range cameras {
+		if !cam.PowerSaving() {
+			return false
+		}
+	}
+	return len(cameras) > 0
+}
+
+// offlineWindow returns the silence window of a device's cameras, stretched
+// for power-saving devices so connectivity checks rarely wake them
+func (p *Plugin) offlineWindow(cameras []*Camera) time.Duration {
+	if allPowerSaving(cameras) && p.offlineAfter < powerSavingOfflineAfter {
+		return powerSavingOfflineAfter
+	}
+	return p.offlineAfter
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPowerSavingDefault(t *testing.T) {
+	off := false
+	tests := []struct {
+		model  string
+		device DeviceConfig
+		want   bool
+	}{
+		{"Argus 3 Pro", DeviceConfig{}, true},
+		{"RLC-810A", DeviceConfig{}, false},
+		{"Argus 3 Pro", DeviceConfig{PowerSaving: &off}, false},
+		{"RLN8-410", DeviceConfig{}, false},
+	}
+	for _, tt := range tests {
+		cam := NewCamera("cam", "Cam", tt.model, "192.168.1.100", 0, nil)
+		if got := powerSavingDefault(tt.device, cam); got != tt.want {
+			t.Errorf("powerSavingDefault(%s, %+v) = %v, want %v", tt.model, tt.device, got, tt.want)
+		}
+	}
+}
+
+func TestPlugin_PowerSaving_SkipsPolling(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "Argus 3 Pro", Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := reolink.NewClient(host, port, "admin", "secret")
+	plugin := NewPlugin()
+	cam := NewCamera("cam", "Garden", "Argus 3 Pro", host, 0, client)
+	cam.SetPowerSaving(true)
+	plugin.cameras["cam"] = cam
+
+	ctx := context.Background()
+	plugin.pollDeviceEvents(ctx, client, make(map[string]reolink.EventState))
+	plugin.refreshEncoderConfigs(ctx, client)
+	if n := sim.CommandCount("GetEvents") + sim.CommandCount("GetMdState") + sim.CommandCount("GetEnc"); n != 0 {
+		t.Errorf("Expected a power-saving camera not to be polled, got %d requests", n)
+	}
+
+	plugin.offlineAfter = time.Minute
+	if window := plugin.offlineWindow([]*Camera{cam}); window != powerSavingOfflineAfter {
+		t.Errorf("Expected the power-saving silence window, got %s", window)
+	}
+
+	// Turned off by the host, the camera is polled like any other
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "update_camera",
+		Params: []byte(`{"camera_id": "cam", "settings": {"power_saving": false}}`)})
+	if resp.Error != nil {
+		t.Fatalf("update_camera failed: %s", resp.Error.Message)
+	}
+	if plugin.GetCamera("cam").PowerSaving {
+		t.Error("Expected power saving to be off")
+	}
+	plugin.pollDeviceEvents(ctx, client, make(map[string]reolink.EventState))
+	if sim.CommandCount("GetEvents") != 1 {
+		t.Errorf("Expected one GetEvents poll, got %d", sim.CommandCount("GetEvents"))
+	}
+	if window := plugin.offlineWindow([]*Camera{cam}); window != time.Minute {
+		t.Errorf("Expected the configured silence window, got %s", window)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	// Time zones are resolved without relying on the host's zoneinfo
+	_ "time/tzdata"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// ProvisionProfile is a bundle of settings applied by provision_camera.
+// Unset fields are left unchanged. NTP, time zone, push, email and RTSP are
+// device settings, shared by all channels of an NVR.
+type ProvisionProfile struct {
+	NTP        *reolink.NTPConfig   `json:"ntp,omitempty"`
+	Timezone   string               `json:"timezone,omitempty"` // IANA name, e.g. "Europe/Berlin"
+	OSD        *reolink.OSDSettings `json:"osd,omitempty"`
+	MainStream *EncoderProfile      `json:"main_stream,omitempty"`
+	SubStream  *EncoderProfile      `json:"sub_stream,omitempty"`
+	Push       *bool                `json:"push,omitempty"`  // push notifications to the Reolink apps
+	Email      *bool                `json:"email,omitempty"` // alarm emails
+	RTSP       *bool                `json:"rtsp,omitempty"`  // the device's RTSP server
+}
+
+// EncoderProfile is the stream part of a provisioning profile
+type EncoderProfile struct {
+	Width     int `json:"width,omitempty"`
+	Height    int `json:"height,omitempty"`
+	FrameRate int `json:"frame_rate,omitempty"`
+	BitRate   int `json:"bit_rate,omitempty"` // kbps
+}
+
+// ProvisionRequest applies a named profile from the plugin config and/or
+// inline settings, which override the profile field by field
+type ProvisionRequest struct {
+	CameraID string            `json:"camera_id"`
+	Name     string            `json:"name,omitempty"` // set on the device and in the plugin
+	Profile  string            `json:"profile,omitempty"`
+	Settings *ProvisionProfile `json:"settings,omitempty"`
+}
+
+// ProvisionResult reports each step of provision_camera. Steps run in order
+// and a failed step does not stop the ones after it.
+type ProvisionResult struct {
+	CameraID string          `json:"camera_id"`
+	Steps    []ProvisionStep `json:"steps"`
+	Failed   int             `json:"failed"`
+}
+
+// ProvisionStep is the outcome of one setting of a profile
+type ProvisionStep struct {
+	Step   string `json:"step"`   // "name", "ntp", "timezone", "osd", "main_stream", ...
+	Status string `json:"status"` // "ok" or "failed"
+	Error  string `json:"error,omitempty"`
+}
+
+// parseProvisionProfiles reads the "provisioning_profiles" section of the
+// plugin config: profile names mapped to settings
+func parseProvisionProfiles(config map[string]interface{}) (map[string]ProvisionProfile, error) {
+	raw, ok := config["provisioning_profiles"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	cfgErr := &ConfigError{}
+	entries, ok := raw.(map[string]interface{})
+	if !ok {
+		cfgErr.add("provisioning_profiles", "must be an object")
+		return nil, cfgErr
+	}
+
+	profiles := make(map[string]ProvisionProfile, len(entries))
+	for name, entry := range entries {
+		field := "provisioning_profiles." + name
+		var profile ProvisionProfile
+		data, err := json.Marshal(entry)
+		if err == nil {
+			err = json.Unmarshal(data, &profile)
+		}
+		if err != nil {
+			cfgErr.add(field, "invalid profile: %v", err)
+			continue
+		}
+		if err := profile.validate(); err != nil {
+			cfgErr.add(field, "%v", err)
+			continue
+		}
+		profiles[name] = profile
+	}
+	if len(cfgErr.Errors) > 0 {
+		sort.Slice(cfgErr.Errors, func(i, j int) bool { return cfgErr.Errors[i].Field < cfgErr.Errors[j].Field })
+		return nil, cfgErr
+	}
+	return profiles, nil
+}
+
+// validate checks the settings that can be checked without the device
+func (pp *ProvisionProfile) validate() error {
+	if pp.Timezone != "" {
+		if _, err := time.LoadLocation(pp.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone: %s", pp.Timezone)
+		}
+	}
+	for _, s := range []*EncoderProfile{pp.MainStream, pp.SubStream} {
+		if s != nil && (s.Width == 0) != (s.Height == 0) {
+			return fmt.Errorf("width and height must be set together")
+		}
+	}
+	return nil
+}
+
+// merge returns pp with the set fields of o applied over it
+func (pp ProvisionProfile) merge(o *ProvisionProfile) ProvisionProfile {
+	if o == nil {
+		return pp
+	}
+	if o.NTP != nil {
+		pp.NTP = o.NTP
+	}
+	if o.Timezone != "" {
+		pp.Timezone = o.Timezone
+	}
+	if o.OSD != nil {
+		pp.OSD = o.OSD
+	}
+	if o.MainStream != nil {
+		pp.MainStream = o.MainStream
+	}
+	if o.SubStream != nil {
+		pp.SubStream = o.SubStream
+	}
+	if o.Push != nil {
+		pp.Push = o.Push
+	}
+	if o.Email != nil {
+		pp.Email = o.Email
+	}
+	if o.RTSP != nil {
+		pp.RTSP = o.RTSP
+	}
+	return pp
+}
+
+// ProvisionCamera applies a provisioning profile to a camera, so a batch of
+// new cameras can be commissioned with one call each
+func (p *Plugin) ProvisionCamera(ctx context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	cam, err := p.lookupCamera(req.CameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile ProvisionProfile
+	if req.Profile != "" {
+		p.mu.RLock()
+		named, ok := p.profiles[req.Profile]
+		p.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("provisioning profile not found: %s", req.Profile)
+		}
+		profile = named
+	}
+	profile = profile.merge(req.Settings)
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+
+	result := &ProvisionResult{CameraID: req.CameraID, Steps: []ProvisionStep{}}
+	step := func(name string, fn func(ctx context.Context) error) {
+		s := ProvisionStep{Step: name, Status: "ok"}
+		if err := fn(ctx); err != nil {
+			s.Status = "failed"
+			s.Error = err.Error()
+			result.Failed++
+			log.Printf("Provisioning %s of camera %s failed: %v", name, req.CameraID, err)
+		}
+		result.Steps = append(result.Steps, s)
+	}
+	onDevice := func(fn func(ctx context.Context) error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			return p.workerFor(cam.client).do(ctx, fn)
+		}
+	}
+
+	if req.Name != "" {
+		step("name", func(ctx context.Context) error {
+			return p.RenameCamera(ctx, req.CameraID, req.Name, true)
+		})
+	}
+	if profile.NTP != nil {
+		step("ntp", onDevice(func(ctx context.Context) error {
+			return cam.client.SetNTP(ctx, *profile.NTP)
+		}))
+	}
+	if profile.Timezone != "" {
+		step("timezone", onDevice(func(ctx context.Context) error {
+			loc, _ := time.LoadLocation(profile.Timezone)
+			return cam.client.SetTimezone(ctx, loc)
+		}))
+	}
+	if profile.OSD != nil {
+		step("osd", onDevice(func(ctx context.Context) error {
+			return cam.client.SetOSD(ctx, cam.Channel(), *profile.OSD)
+		}))
+	}
+	for _, s := range []struct {
+		stream  string
+		profile *EncoderProfile
+	}{{"main", profile.MainStream}, {"sub", profile.SubStream}} {
+		if s.profile == nil {
+			continue
+		}
+		enc := EncoderSettingsRequest{CameraID: req.CameraID, Stream: s.stream,
+			Width: s.profile.Width, Height: s.profile.Height, FrameRate: s.profile.FrameRate, BitRate: s.profile.BitRate}
+		step(s.stream+"_stream", func(ctx context.Context) error {
+			_, err := p.SetEncoderSettings(ctx, enc)
+			return err
+		})
+	}
+	if profile.Push != nil {
+		step("push", onDevice(func(ctx context.Context) error {
+			return cam.client.SetPushEnabled(ctx, cam.Channel(), *profile.Push)
+		}))
+	}
+	if profile.Email != nil {
+		step("email", onDevice(func(ctx context.Context) error {
+			return cam.client.SetEmailEnabled(ctx, cam.Channel(), *profile.Email)
+		}))
+	}
+	if profile.RTSP != nil {
+		step("rtsp", onDevice(func(ctx context.Context) error {
+			return cam.client.SetRTSPEnabled(ctx, *profile.RTSP)
+		}))
+	}
+
+	if len(result.Steps) == 0 {
+		return nil, fmt.Errorf("no provisioning settings to apply")
+	}
+	log.Printf("Provisioned camera %s: %d of %d steps succeeded", req.CameraID, len(result.Steps)-result.Failed, len(result.Steps))
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPlugin_ProvisionCamera(t *testing.T) {
+	var mu sync.Mutex
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		mu.Lock()
+		received = append(received, cmds[0])
+		mu.Unlock()
+		resp := apiResponse{Cmd: cmds[0].Cmd, Code: 0}
+		if cmds[0].Cmd == "GetEnc" {
+			resp.Code = 1
+			resp.Error = &apiErrorDetail{RspCode: -4, Detail: "param error"}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"provisioning_profiles": map[string]interface{}{
+			"site": map[string]interface{}{
+				"ntp":         map[string]interface{}{"enabled": true, "server": "ntp.site.lan"},
+				"timezone":    "Europe/Berlin",
+				"osd":         map[string]interface{}{"show_time": true, "time_position": "upper_left"},
+				"main_stream": map[string]interface{}{"frame_rate": float64(15)},
+				"push":        false,
+				"email":       false,
+				"rtsp":        true,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Camera1", "RLC-811A", "localhost", 0, client)
+
+	// Inline settings override the profile
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "provision_camera", Params: []byte(
+		`{"camera_id": "cam_1", "name": "Gate", "profile": "site", "settings": {"email": true}}`)})
+	if resp.Error != nil {
+		t.Fatalf("provision_camera failed: %s", resp.Error.Message)
+	}
+	result := resp.Result.(*ProvisionResult)
+
+	var steps []string
+	for _, s := range result.Steps {
+		steps = append(steps, s.Step+":"+s.Status)
+	}
+	want := []string{"name:ok", "ntp:ok", "timezone:ok", "osd:ok", "main_stream:failed", "push:ok", "email:ok", "rtsp:ok"}
+	if !reflect.DeepEqual(steps, want) || result.Failed != 1 {
+		t.Errorf("Expected steps %v, got %v (failed %d)", want, steps, result.Failed)
+	}
+	if plugin.GetCamera("cam_1").Name != "Gate" {
+		t.Error("Expected the camera to be renamed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	params := make(map[string]map[string]interface{})
+	for _, cmd := range received {
+		params[cmd.Cmd] = cmd.Param
+	}
+	if ntp, _ := params["SetNtp"]["Ntp"].(map[string]interface{}); ntp["server"] != "ntp.site.lan" || ntp["enable"] != float64(1) {
+		t.Errorf("Unexpected SetNtp params: %v", params["SetNtp"])
+	}
+	if tm, _ := params["SetTime"]["Time"].(map[string]interface{}); tm["timeZone"] != float64(-3600) {
+		t.Errorf("Unexpected SetTime params: %v", params["SetTime"])
+	}
+	if email, _ := params["SetEmailV20"]["Email"].(map[string]interface{}); email["enable"] != float64(1) {
+		t.Errorf("Expected the inline email setting, got %v", params["SetEmailV20"])
+	}
+	if port, _ := params["SetNetPort"]["NetPort"].(map[string]interface{}); port["rtspEnable"] != float64(1) {
+		t.Errorf("Unexpected SetNetPort params: %v", params["SetNetPort"])
+	}
+}
+
+func TestPlugin_ProvisionCamera_Invalid(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Camera1", "RLC-811A", "localhost", 0, nil)
+	ctx := context.Background()
+
+	if _, err := plugin.ProvisionCamera(ctx, ProvisionRequest{CameraID: "cam_1", Profile: "missing"}); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+	if _, err := plugin.ProvisionCamera(ctx, ProvisionRequest{CameraID: "cam_1", Settings: &ProvisionProfile{Timezone: "Mars/Olympus"}}); err == nil {
+		t.Error("Expected an error for an unknown timezone")
+	}
+	if _, err := plugin.ProvisionCamera(ctx, ProvisionRequest{CameraID: "cam_1"}); err == nil {
+		t.Error("Expected an error without settings")
+	}
+
+	err := plugin.Initialize(ctx, map[string]interface{}{
+		"provisioning_profiles": map[string]interface{}{"bad": map[string]interface{}{"push": "no"}},
+	})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Errors[0].Field != "provisioning_profiles.bad" {
+		t.Errorf("Expected a config error for the bad profile, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// errRawCommandsDisabled is returned by raw_command unless the plugin config
+// sets allow_raw_commands
+var errRawCommandsDisabled = errors.New("raw_command is disabled; set allow_raw_commands in the plugin config")
+
+// RawCommand sends Reolink API commands to a camera's device as given and
+// returns the device's responses unparsed. The commands are not checked, so
+// they can change any setting the account is allowed to.
+func (p *Plugin) RawCommand(ctx context.Context, cameraID string, commands []reolink.RawCommand) ([]json.RawMessage, error) {
+	if err := p.checkRawCommands(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.Cmd
+	}
+	log.Printf("Sending raw command %s to camera %s", strings.Join(names, ","), cameraID)
+
+	var resp []json.RawMessage
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		resp, err = cam.client.Do(ctx, commands)
+		if err != nil {
+			return err
+		}
+		// The streams shown to the host must follow encoder changes
+		for _, cmd := range commands {
+			if cmd.Cmd == "SetEnc" {
+				p.refreshEncoderConfigs(ctx, cam.client)
+				break
+			}
+		}
+		return nil
+	})
+	return resp, err
+}
+
+// PlanRawCommand reports the commands RawCommand would send without sending
+// them, so a batch can be reviewed first
+func (p *Plugin) PlanRawCommand(cameraID string, commands []reolink.RawCommand) (*DryRunResult, error) {
+	if err := p.checkRawCommands(); err != nil {
+		return nil, err
+	}
+	if _, err := p.lookupCamera(cameraID); err != nil {
+		return nil, err
+	}
+
+	if len(commands) == 0 {
+		return nil, errors.New("no commands")
+	}
+
+	plan := newDryRunResult(cameraID)
+	for i, cmd := range commands {
+		if cmd.Cmd == "" {
+			return nil, fmt.Errorf("command %d has no cmd", i)
+		}
+		if cmd.Param == nil {
+			cmd.Param = map[string]interface{}{}
+		}
+		param, err := json.Marshal(cmd.Param)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, fmt.Sprintf("%s (action %d): %s", cmd.Cmd, cmd.Action, param))
+	}
+	return plan, nil
+}
+
+func (p *Plugin) checkRawCommands() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.allowRawCommands {
+		return errRawCommandsDisabled
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_RawCommand(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", TokenOnly: true})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	for _, allow := range []bool{false, true} {
+		plugin := NewPlugin()
+		plugin.SetOutput(io.Discard)
+		err := plugin.Initialize(context.Background(), map[string]interface{}{
+			"event_poll_interval_ms": float64(0),
+			"allow_raw_commands":     allow,
+			"devices": []interface{}{
+				map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		defer plugin.Shutdown(context.Background())
+
+		params := json.RawMessage(`{"camera_id": "` + host + `_ch0", "commands": [{"cmd": "GetDevInfo"}, {"cmd": "GetAutoFocus"}]}`)
+		resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "raw_command", Params: params})
+		if !allow {
+			if resp.Error == nil || !strings.Contains(resp.Error.Message, "allow_raw_commands") {
+				t.Errorf("Expected raw_command to be disabled, got %+v", resp)
+			}
+			continue
+		}
+		if resp.Error != nil {
+			t.Fatalf("raw_command failed: %s", resp.Error.Message)
+		}
+
+		data, _ := json.Marshal(resp.Result)
+		var results []struct {
+			Cmd   string `json:"cmd"`
+			Code  int    `json:"code"`
+			Value struct {
+				DevInfo struct {
+					Serial string `json:"serial"`
+				} `json:"DevInfo"`
+			} `json:"value"`
+		}
+		if err := json.Unmarshal(data, &results); err != nil || len(results) != 2 {
+			t.Fatalf("Expected 2 raw responses, got %s", data)
+		}
+		if results[0].Code != 0 || results[0].Value.DevInfo.Serial != reolinksim.DefaultCamera.Serial {
+			t.Errorf("Unexpected GetDevInfo response: %+v", results[0])
+		}
+		// Commands the simulator does not know are rejected in their response
+		if results[1].Cmd != "GetAutoFocus" || results[1].Code == 0 {
+			t.Errorf("Expected GetAutoFocus to be rejected, got %+v", results[1])
+		}
+	}
+}
+
+func TestPlugin_RawCommand_RefreshesEncoder(t *testing.T) {
+	server, _ := newEncoderDevice(t, 2560)
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	plugin.allowRawCommands = true
+	cam := NewCamera("cam1", "Front", "RLC-823A", "cam", 0, client)
+	plugin.cameras["cam1"] = cam
+
+	commands := []reolink.RawCommand{{Cmd: "SetEnc", Param: map[string]interface{}{
+		"Enc": map[string]interface{}{"channel": 0, "mainStream": map[string]interface{}{"width": 3840}},
+	}}}
+	if _, err := plugin.RawCommand(context.Background(), "cam1", commands); err != nil {
+		t.Fatalf("RawCommand failed: %v", err)
+	}
+	if enc := cam.EncoderConfig(); enc == nil || enc.MainStream.Width != 3840 {
+		t.Errorf("Expected the encoder config to be re-read after SetEnc, got %+v", enc)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// errReadOnly is returned for requests that would change a camera while the
+// plugin config sets read_only
+var errReadOnly = errors.New("refused in read-only mode")
+
+// cameraWrites are the methods that change camera settings or make a camera
+// act. Methods that also have a reading variant decide per request.
+var cameraWrites = map[string]func(params json.RawMessage) bool{
+	"add_user":               alwaysWrites,
+	"modify_user":            alwaysWrites,
+	"delete_user":            alwaysWrites,
+	"rotate_password":        alwaysWrites,
+	"configure_network":      alwaysWrites,
+	"set_wifi":               alwaysWrites,
+	"disconnect_session":     alwaysWrites,
+	"set_light":              alwaysWrites,
+	"set_light_schedule":     alwaysWrites,
+	"set_encoder_settings":   alwaysWrites,
+	"set_image_settings":     alwaysWrites,
+	"upload_audio_clip":      alwaysWrites,
+	"select_audio_clip":      alwaysWrites,
+	"talk":                   alwaysWrites,
+	"upgrade_firmware":       alwaysWrites,
+	"provision_camera":       alwaysWrites,
+	"update_camera":          pushesName,
+	"configure_privacy_mask": func(params json.RawMessage) bool { return actionOtherThan(params, "list") },
+	"configure_siren":        changesSiren,
+	"alarm_output":           func(params json.RawMessage) bool { return actionOtherThan(params, "status") },
+	"raw_command":            sendsSetCommand,
+	"set_snapshot_options":   setsTimestamp,
+}
+
+// ptzMethods move the camera or its lens; read_only_allow_ptz permits them
+var ptzMethods = []string{"ptz_control", "autofocus"}
+
+// dryRunMethods have a dry_run option, which never reaches the camera
+var dryRunMethods = []string{"set_encoder_settings", "upgrade_firmware", "raw_command", "configure_network"}
+
+// checkReadOnly refuses a request that would change a camera while the
+// plugin is in read-only mode. Settings that only live in the plugin, such as
+// display names and zoom presets, can still be changed.
+func (p *Plugin) checkReadOnly(method string, params json.RawMessage) error {
+	p.mu.RLock()
+	readOnly, allowPTZ := p.readOnly, p.readOnlyAllowPTZ
+	p.mu.RUnlock()
+	if !readOnly {
+		return nil
+	}
+
+	if contains(ptzMethods, method) {
+		if allowPTZ {
+			return nil
+		}
+		return errReadOnly
+	}
+	writes, ok := cameraWrites[method]
+	if !ok || !writes(params) {
+		return nil
+	}
+	if contains(dryRunMethods, method) {
+		var req struct {
+			DryRun bool `json:"dry_run"`
+		}
+		_ = json.Unmarshal(params, &req)
+		if p.dryRun(req.DryRun) {
+			return nil
+		}
+	}
+	return errReadOnly
+}
+
+func alwaysWrites(json.RawMessage) bool { return true }
+
+// actionOtherThan reports whether a request's action is not the given
+// reading one. Unreadable params count as writing.
+func actionOtherThan(params json.RawMessage, read string) bool {
+	var req struct {
+		Action string `json:"action"`
+	}
+	return json.Unmarshal(params, &req) != nil || req.Action != read
+}
+
+// pushesName reports whether update_camera renames the channel on the device
+func pushesName(params json.RawMessage) bool {
+	var req struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if json.Unmarshal(params, &req) != nil {
+		return true
+	}
+	push, _ := req.Settings["push_name"].(bool)
+	return push
+}
+
+// setsTimestamp reports whether set_snapshot_options changes the OSD of the
+// camera; snapshot quality and size only live in the plugin
+func setsTimestamp(params json.RawMessage) bool {
+	var req map[string]json.RawMessage
+	if json.Unmarshal(params, &req) != nil {
+		return true
+	}
+	_, ok := req["timestamp"]
+	return ok
+}
+
+// changesSiren reports whether configure_siren sets anything rather than
+// only reading the linkage
+func changesSiren(params json.RawMessage) bool {
+	var req map[string]json.RawMessage
+	if json.Unmarshal(params, &req) != nil {
+		return true
+	}
+	for _, field := range []string{"enabled", "triggers", "schedule"} {
+		if _, ok := req[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sendsSetCommand reports whether raw_command sends anything but Get commands
+func sendsSetCommand(params json.RawMessage) bool {
+	var req struct {
+		Commands []struct {
+			Cmd string `json:"cmd"`
+		} `json:"commands"`
+	}
+	if json.Unmarshal(params, &req) != nil {
+		return true
+	}
+	for _, cmd := range req.Commands {
+		if !strings.HasPrefix(cmd.Cmd, "Get") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPlugin_ReadOnly(t *testing.T) {
+	tests := []struct {
+		method, params string
+		refused        bool
+	}{
+		{"set_image_settings", `{"camera_id": "cam_1", "mirror": true}`, true},
+		{"add_user", `{"camera_id": "cam_1", "username": "guest"}`, true},
+		{"upgrade_firmware", `{"camera_id": "cam_1", "transfer_id": "x"}`, true},
+		{"upgrade_firmware", `{"camera_id": "cam_1", "transfer_id": "x", "dry_run": true}`, false},
+		{"configure_privacy_mask", `{"camera_id": "cam_1", "action": "clear"}`, true},
+		{"configure_privacy_mask", `{"camera_id": "cam_1", "action": "list"}`, false},
+		{"configure_siren", `{"camera_id": "cam_1", "enabled": false}`, true},
+		{"configure_siren", `{"camera_id": "cam_1"}`, false},
+		{"alarm_output", `{"camera_id": "cam_1", "action": "trigger"}`, true},
+		{"alarm_output", `{"camera_id": "cam_1", "action": "status"}`, false},
+		{"raw_command", `{"camera_id": "cam_1", "commands": [{"cmd": "GetTime"}, {"cmd": "Reboot"}]}`, true},
+		{"raw_command", `{"camera_id": "cam_1", "commands": [{"cmd": "GetTime"}]}`, false},
+		{"update_camera", `{"camera_id": "cam_1", "settings": {"name": "Gate", "push_name": true}}`, true},
+		{"update_camera", `{"camera_id": "cam_1", "settings": {"name": "Gate"}}`, false},
+		{"ptz_control", `{"camera_id": "cam_1", "action": "left"}`, true},
+		{"get_snapshot", `{"camera_id": "cam_1"}`, false},
+		{"set_snapshot_options", `{"camera_id": "cam_1", "timestamp": false}`, true},
+		{"set_snapshot_options", `{"camera_id": "cam_1", "quality": 80}`, false},
+	}
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	if err := plugin.Initialize(context.Background(), map[string]interface{}{"read_only": true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	for _, tt := range tests {
+		resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: tt.method, Params: []byte(tt.params)})
+		refused := resp.Error != nil && strings.Contains(resp.Error.Message, "read-only")
+		if refused != tt.refused {
+			t.Errorf("%s %s: expected refused=%v, got %+v", tt.method, tt.params, tt.refused, resp.Error)
+		}
+	}
+}
+
+func TestPlugin_ReadOnly_AllowPTZ(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	if err := plugin.Initialize(context.Background(), map[string]interface{}{"read_only": true, "read_only_allow_ptz": true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	if err := plugin.checkReadOnly("ptz_control", []byte(`{"camera_id": "cam_1"}`)); err != nil {
+		t.Errorf("Expected PTZ to be allowed, got %v", err)
+	}
+	if err := plugin.checkReadOnly("set_light", []byte(`{"camera_id": "cam_1"}`)); err != errReadOnly {
+		t.Errorf("Expected set_light to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// panicRestartDelay is how long a guarded loop waits before restarting after a panic
+const panicRestartDelay = time.Second
+
+// logPanic logs a recovered panic with its stack and returns a short
+// reference, so an error reported to the host can be matched to the log
+func logPanic(where string, r interface{}) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	ref := hex.EncodeToString(b)
+	log.Printf("Panic in %s (ref %s): %v\n%s", where, ref, r, debug.Stack())
+	return ref
+}
+
+// runGuarded calls fn and reports whether it panicked, logging the panic
+// instead of letting it crash the plugin
+func runGuarded(where string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(where, r)
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// goGuarded runs fn in a goroutine and restarts it after a panic until ctx
+// is done, so a malformed device response cannot stop a background loop. The
+// returned channel is closed when the goroutine exits.
+func goGuarded(ctx context.Context, where string, fn func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for runGuarded(where, fn) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(panicRestartDelay):
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandleRequest_RecoversPanic(t *testing.T) {
+	plugin := NewPlugin()
+	// A camera without a client makes the snapshot handler dereference nil
+	plugin.cameras["cam1"] = NewCamera("cam1", "Front", "RLC-810A", "localhost", 0, nil)
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 7, Method: "get_snapshot", Params: []byte(`{"camera_id": "cam1"}`)})
+	if resp.Error == nil || resp.Error.Code != -32603 || !strings.Contains(resp.Error.Message, "log ref") {
+		t.Fatalf("Expected an internal error with a log reference, got %+v", resp.Error)
+	}
+	if resp.ID != 7 {
+		t.Errorf("Expected the request ID to be kept, got %v", resp.ID)
+	}
+
+	// The plugin keeps serving requests
+	if resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 8, Method: "health"}); resp.Error != nil {
+		t.Errorf("Unexpected error after a panic: %+v", resp.Error)
+	}
+}
+
+func TestGoGuarded_RestartsAfterPanic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	done := make(chan struct{})
+	goGuarded(ctx, "test loop", func() {
+		if runs.Add(1) == 1 {
+			panic("malformed response")
+		}
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the loop to be restarted after the panic")
+	}
+	if runs.Load() != 2 {
+		t.Errorf("Expected 2 runs, got %d", runs.Load())
+	}
+}
+
+func TestGoGuarded_StopsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var runs atomic.Int32
+	goGuarded(ctx, "test loop", func() {
+		runs.Add(1)
+		panic("malformed response")
+	})
+
+	time.Sleep(panicRestartDelay + 200*time.Millisecond)
+	if runs.Load() != 1 {
+		t.Errorf("Expected no restart after the context is done, got %d runs", runs.Load())
+	}
+}
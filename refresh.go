@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Refresh re-reads the camera's abilities, encoder settings and the device's
+// service ports. Abilities can change after a firmware update, so the values
+// cached at connect time may be stale.
+func (c *Camera) Refresh(ctx context.Context) error {
+	if c.client == nil {
+		return fmt.Errorf("camera %s has no client", c.id)
+	}
+
+	ability, err := c.client.GetAbility(ctx, c.channel)
+	if err != nil {
+		return fmt.Errorf("failed to get abilities: %w", err)
+	}
+	c.SetAbility(ability)
+
+	enc, err := c.client.GetEncoderConfig(ctx, c.channel)
+	if err != nil {
+		return fmt.Errorf("failed to get encoder config: %w", err)
+	}
+	c.SetEncoderConfig(enc)
+
+	if err := c.probeAI(ctx); err != nil {
+		log.Printf("Failed to probe AI detection of camera %s: %v", c.id, err)
+	}
+
+	// Older firmware does not implement GetNetPort; keep the default ports
+	if _, err := c.client.GetNetPort(ctx); err != nil {
+		log.Printf("Failed to get network ports for camera %s: %v", c.id, err)
+	}
+
+	return nil
+}
+
+// probeAI asks the camera which AI detections it supports, so models the
+// model database does not know are classified correctly
+func (c *Camera) probeAI(ctx context.Context) error {
+	support, err := c.client.GetAISupport(ctx, c.channel)
+	if err != nil {
+		return err
+	}
+	c.SetAISupport(support)
+	return nil
+}
+
+// probeAISupport asks the cameras of a device which AI detections they
+// support, announcing the cameras whose capabilities changed. Power-saving
+// cameras are not woken up for it; the model database decides for them
+// until an explicit refresh.
+func (p *Plugin) probeAISupport(ctx context.Context, client *reolink.Client) {
+	for _, cam := range p.camerasOf(client) {
+		if cam.PowerSaving() {
+			continue
+		}
+		before := cam.Capabilities()
+		if err := cam.probeAI(ctx); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to probe AI detection of camera %s: %v", cam.ID(), err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(before, cam.Capabilities()) {
+			log.Printf("Probed AI detection of camera %s: %v", cam.ID(), cam.Capabilities())
+			p.notifyCameraUpdated(cam)
+		}
+	}
+}
+
+// defaultMetadataRefreshInterval is how often each worker re-reads the encoder
+// settings of its cameras, which users can change in the Reolink app
+const defaultMetadataRefreshInterval = 10 * time.Minute
+
+// refreshEncoderConfig re-reads a camera's encoder settings and reports
+// whether they differ from the cached ones
+func (c *Camera) refreshEncoderConfig(ctx context.Context) (bool, error) {
+	enc, err := c.client.GetEncoderConfig(ctx, c.channel)
+	if err != nil {
+		return false, err
+	}
+	changed := !reflect.DeepEqual(c.EncoderConfig(), enc)
+	c.SetEncoderConfig(enc)
+	return changed, nil
+}
+
+// refreshEncoderConfigs re-reads the encoder settings of every camera of a
+// device, announcing the cameras whose streams changed. Offline and
+// power-saving cameras are skipped; the settings are read again once they
+// are back or on an explicit refresh.
+func (p *Plugin) refreshEncoderConfigs(ctx context.Context, client *reolink.Client) {
+	for _, cam := range p.camerasOf(client) {
+		if !cam.IsOnline() || cam.PowerSaving() {
+			continue
+		}
+		hadConfig := cam.EncoderConfig() != nil
+		changed, err := cam.refreshEncoderConfig(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to refresh encoder config of camera %s: %v", cam.ID(), err)
+			}
+			continue
+		}
+		if changed && hadConfig {
+			log.Printf("Encoder config of camera %s changed", cam.ID())
+			p.notifyCameraUpdated(cam)
+		}
+	}
+}
+
+// refreshPerformance re-reads the load statistics of a device that Health
+// reports. Devices whose cameras are all offline or power-saving are skipped.
+func (p *Plugin) refreshPerformance(ctx context.Context, client *reolink.Client, cameras []*Camera) {
+	for _, cam := range cameras {
+		if cam.IsOnline() && !cam.PowerSaving() {
+			var apiErr *reolink.APIError
+			// Devices without GetPerformance refuse it on every tick
+			if _, err := client.GetPerformance(ctx); err != nil && ctx.Err() == nil && !errors.As(err, &apiErr) {
+				log.Printf("Failed to refresh performance of %s: %v", client.Host(), err)
+			}
+			return
+		}
+	}
+}
+
+// refreshStorage re-reads the disks or SD cards of a device that reported
+// storage, for their free space and status in Health and discover_cameras.
+// Devices whose cameras are all offline or power-saving are skipped.
+func (p *Plugin) refreshStorage(ctx context.Context, client *reolink.Client) {
+	if client.GetCachedHddInfo() == nil {
+		return
+	}
+	for _, cam := range p.camerasOf(client) {
+		if cam.IsOnline() && !cam.PowerSaving() {
+			if _, err := client.GetHddInfo(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to refresh storage info of %s: %v", client.Host(), err)
+			}
+			return
+		}
+	}
+}
+
+// RefreshCamera re-probes a camera's capabilities and returns the updated set
+func (p *Plugin) RefreshCamera(ctx context.Context, cameraID string) (*CameraCapabilities, error) {
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) error {
+		if err := cam.Refresh(ctx); err != nil {
+			return err
+		}
+		log.Printf("Refreshed capabilities of camera %s: %v", cameraID, cam.Capabilities())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetCapabilities(cameraID), nil
+}
+
+// GetAbility reads the complete ability report of a camera's device, with the
+// summary fields for the camera's channel, and updates the cached abilities
+func (p *Plugin) GetAbility(ctx context.Context, cameraID string) (*reolink.Ability, error) {
+	var ability *reolink.Ability
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		ability, err = cam.client.GetAbility(ctx, cam.Channel())
+		if err != nil {
+			return err
+		}
+		cam.SetAbility(ability)
+		return nil
+	})
+	return ability, err
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_RefreshCamera(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetAbility": map[string]interface{}{
+			"Ability": map[string]interface{}{
+				"ptz":  map[string]interface{}{"ver": float64(1)},
+				"talk": map[string]interface{}{"ver": float64(1)},
+			},
+		},
+		"GetEnc": map[string]interface{}{
+			"Enc": map[string]interface{}{
+				"mainStream": map[string]interface{}{"width": float64(3840), "height": float64(2160)},
+			},
+		},
+		"GetNetPort": map[string]interface{}{
+			"NetPort": map[string]interface{}{"rtspPort": float64(8554), "rtmpPort": float64(1936)},
+		},
+	})
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam1", "Front", "RLC-823A", "cam", 0, client)
+	cam.SetAbility(&reolink.Ability{})
+	plugin.cameras["cam1"] = cam
+
+	caps, err := plugin.RefreshCamera(context.Background(), "cam1")
+	if err != nil {
+		t.Fatalf("RefreshCamera failed: %v", err)
+	}
+	if !caps.HasPTZ || !caps.HasTwoWayAudio {
+		t.Errorf("Expected refreshed PTZ and two-way audio, got %+v", caps)
+	}
+	if cam.encConfig == nil || cam.encConfig.MainStream.Width != 3840 {
+		t.Errorf("Expected refreshed encoder config, got %+v", cam.encConfig)
+	}
+
+	host, _ := serverHostPort(server)
+	want := "rtsp://admin:password@" + host + ":8554/h264Preview_01_main"
+	if url := client.RTSPStreamURL(0, "main"); url != want {
+		t.Errorf("Expected %s, got %s", want, url)
+	}
+}
+
+func TestPlugin_RefreshCamera_NotFound(t *testing.T) {
+	plugin := NewPlugin()
+	if _, err := plugin.RefreshCamera(context.Background(), "missing"); err == nil {
+		t.Error("Expected error for unknown camera")
+	}
+}
+
+func TestPlugin_GetAbility(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetAbility": map[string]interface{}{
+			"Ability": map[string]interface{}{
+				"talk":            map[string]interface{}{"permit": float64(6), "ver": float64(1)},
+				"scheduleVersion": map[string]interface{}{"permit": float64(0), "ver": float64(1)},
+				"abilityChn": []interface{}{
+					map[string]interface{}{"supportAiFace": map[string]interface{}{"permit": float64(4), "ver": float64(0)}},
+					map[string]interface{}{"supportAiFace": map[string]interface{}{"permit": float64(4), "ver": float64(1)}},
+				},
+			},
+		},
+	})
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	cam := NewCamera("nvr_ch1", "Yard", "RLN8-410", "nvr", 1, client)
+	plugin.cameras["nvr_ch1"] = cam
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "get_ability", Params: []byte(`{"camera_id": "nvr_ch1"}`)})
+	if resp.Error != nil {
+		t.Fatalf("get_ability failed: %s", resp.Error.Message)
+	}
+	ability := resp.Result.(*reolink.Ability)
+	if talk := ability.Device["talk"]; talk.Ver != 1 || talk.Permit != 6 {
+		t.Errorf("Expected the talk entry, got %+v", talk)
+	}
+	if _, ok := ability.Device["scheduleVersion"]; !ok {
+		t.Error("Expected entries the plugin does not use to be included")
+	}
+	if len(ability.Channels) != 2 || ability.Channels[1]["supportAiFace"].Ver != 1 {
+		t.Errorf("Expected both channels' entries, got %+v", ability.Channels)
+	}
+	// The summary follows the camera's channel and is cached
+	if !ability.FaceDetection || cam.Ability() == nil || !cam.Ability().FaceDetection {
+		t.Errorf("Expected face detection on channel 1 to be cached, got %+v", cam.Ability())
+	}
+}
+
+// newEncoderDevice fakes a device whose main stream width is read by GetEnc
+// and changed by SetEnc, or directly through the returned setter
+func newEncoderDevice(t *testing.T, width int) (*httptest.Server, func(int)) {
+	t.Helper()
+	var mu sync.Mutex
+	setWidth := func(w int) {
+		mu.Lock()
+		width = w
+		mu.Unlock()
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		cmd := r.URL.Query().Get("cmd")
+		if len(cmds) > 0 {
+			cmd = cmds[0].Cmd
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		var value interface{}
+		switch cmd {
+		case "GetEnc":
+			value = map[string]interface{}{"Enc": map[string]interface{}{
+				"mainStream": map[string]interface{}{"width": float64(width), "height": float64(width * 9 / 16)},
+			}}
+		case "SetEnc":
+			enc, _ := cmds[0].Param["Enc"].(map[string]interface{})
+			main, _ := enc["mainStream"].(map[string]interface{})
+			if v, ok := main["width"].(float64); ok {
+				width = int(v)
+			}
+			value = map[string]interface{}{"rspCode": float64(200)}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: cmd, Code: 0, Value: value}})
+	}))
+	t.Cleanup(server.Close)
+	return server, setWidth
+}
+
+func TestPlugin_RefreshEncoderConfigs(t *testing.T) {
+	server, setWidth := newEncoderDevice(t, 2560)
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	cam := NewCamera("cam1", "Front", "RLC-823A", "cam", 0, client)
+	plugin.cameras["cam1"] = cam
+
+	// The first read only fills the cache
+	plugin.refreshEncoderConfigs(context.Background(), client)
+	if enc := plugin.GetCamera("cam1").Encoder; enc == nil || enc.MainStream.Width != 2560 {
+		t.Fatalf("Expected the encoder config to be shown, got %+v", enc)
+	}
+	plugin.refreshEncoderConfigs(context.Background(), client)
+	if msgs := readLifecycle(t, &out); len(msgs) != 0 {
+		t.Fatalf("Expected no notification without a change, got %+v", msgs)
+	}
+
+	// A resolution changed in the Reolink app is picked up and announced
+	setWidth(3840)
+	plugin.refreshEncoderConfigs(context.Background(), client)
+	msgs := readLifecycle(t, &out)
+	if len(msgs) != 1 || msgs[0].Method != NotifyCameraUpdated {
+		t.Fatalf("Expected one camera.updated notification, got %+v", msgs)
+	}
+	if enc := msgs[0].Params.Camera.Encoder; enc == nil || enc.MainStream.Width != 3840 {
+		t.Errorf("Expected the new encoder config, got %+v", enc)
+	}
+}
+
+func TestPlugin_HealthRefreshesPerformance(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Password: "secret"}))
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"offline_after_ms":       float64(4000),
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	// Without any get_device_info, the health tick reads the load
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		perf := plugin.Health().Details["performance"].(map[string]*reolink.Performance)
+		if p := perf[host]; p != nil {
+			if p.CPUUsed != 35 {
+				t.Errorf("Expected the device load, got %+v", p)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the performance in health")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPlugin_MetadataRefreshesStorage(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms":       float64(0),
+		"metadata_refresh_interval_ms": float64(50),
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	// Read once on connect, then again on the metadata tick
+	deadline := time.Now().Add(5 * time.Second)
+	for sim.CommandCount("GetHddInfo") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the storage info to be read again, got %d reads", sim.CommandCount("GetHddInfo"))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
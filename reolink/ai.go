@@ -0,0 +1,70 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+)
+
+// AISupport is the AI detection a channel supports, as reported by the
+// device rather than guessed from its model
+type AISupport struct {
+	Person  bool `json:"person"`
+	Vehicle bool `json:"vehicle"`
+	Animal  bool `json:"animal"`
+	Face    bool `json:"face"`
+	Package bool `json:"package"`
+}
+
+// Any reports whether person, vehicle or animal detection is supported
+func (s AISupport) Any() bool {
+	return s.Person || s.Vehicle || s.Animal
+}
+
+// GetAISupport asks a channel which AI detections it supports. It reads the
+// support flags of GetAiState, falling back to the detection types of
+// GetAiCfg. A device rejecting both has no AI detection.
+func (c *Client) GetAISupport(ctx context.Context, channel int) (*AISupport, error) {
+	value, err := c.execCommand(ctx, "GetAiState", map[string]interface{}{
+		"channel": channel,
+	})
+	if err == nil {
+		return parseAISupport(value, func(v interface{}) bool {
+			// Firmware without support flags only lists what it detects
+			state, ok := v.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if _, flagged := state["support"]; !flagged {
+				return true
+			}
+			return aiSupported(state)
+		}), nil
+	}
+	if !errors.Is(err, ErrNotSupported) {
+		return nil, err
+	}
+
+	value, err = c.execCommand(ctx, "GetAiCfg", map[string]interface{}{
+		"channel": channel,
+	})
+	if errors.Is(err, ErrNotSupported) {
+		return &AISupport{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	types, _ := value["AiDetectType"].(map[string]interface{})
+	return parseAISupport(types, func(v interface{}) bool { return v != nil }), nil
+}
+
+// parseAISupport reads the AI kinds of a GetAiState or AiDetectType object,
+// keyed as "people", "vehicle", "dog_cat", "face" and "package"
+func parseAISupport(value map[string]interface{}, supported func(interface{}) bool) *AISupport {
+	return &AISupport{
+		Person:  supported(value["people"]),
+		Vehicle: supported(value["vehicle"]),
+		Animal:  supported(value["dog_cat"]),
+		Face:    supported(value["face"]),
+		Package: supported(value["package"]),
+	}
+}
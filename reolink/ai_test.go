@@ -0,0 +1,67 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newAITestServer answers GetAiState and GetAiCfg with the given values, or
+// rejects them as unsupported if nil
+func newAITestServer(aiState, aiCfg map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		resp := apiResponse{Cmd: cmds[0].Cmd, Code: 0}
+		value := aiState
+		if cmds[0].Cmd == "GetAiCfg" {
+			value = aiCfg
+		}
+		if value == nil {
+			resp.Code = 1
+			resp.Error = &apiErrorDetail{RspCode: -9, Detail: "not support"}
+		} else {
+			resp.Value = value
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+}
+
+func TestClient_GetAISupport(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  map[string]interface{}
+		cfg    map[string]interface{}
+		expect AISupport
+	}{
+		{"state", map[string]interface{}{
+			"people":  map[string]interface{}{"alarm_state": 0, "support": 1},
+			"vehicle": map[string]interface{}{"alarm_state": 0, "support": 0},
+			"face":    map[string]interface{}{"alarm_state": 0, "support": 1},
+		}, nil, AISupport{Person: true, Face: true}},
+		{"state without flags", map[string]interface{}{
+			"people":  map[string]interface{}{"alarm_state": 0},
+			"dog_cat": map[string]interface{}{"alarm_state": 0},
+		}, nil, AISupport{Person: true, Animal: true}},
+		{"config", nil, map[string]interface{}{
+			"AiDetectType": map[string]interface{}{"people": 1, "vehicle": 0},
+		}, AISupport{Person: true, Vehicle: true}},
+		{"none", nil, nil, AISupport{}},
+	}
+	for _, tt := range tests {
+		server := newAITestServer(tt.state, tt.cfg)
+		client := newTestClient(server)
+		client.useBasicAuth = true
+
+		support, err := client.GetAISupport(context.Background(), 0)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: GetAISupport failed: %v", tt.name, err)
+		}
+		if *support != tt.expect {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expect, *support)
+		}
+	}
+}
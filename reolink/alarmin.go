@@ -0,0 +1,32 @@
+package reolink
+
+import "context"
+
+// AlarmInput is the state of a wired alarm input, such as a PIR sensor or
+// door contact connected to an NVR
+type AlarmInput struct {
+	Port   int  `json:"port"`
+	Active bool `json:"active"`
+}
+
+// GetAlarmInputs retrieves the state of the device's alarm inputs. Ports are
+// numbered from 0; DeviceInfo.AlarmInputs reports how many exist.
+func (c *Client) GetAlarmInputs(ctx context.Context) ([]AlarmInput, error) {
+	value, err := c.execCommand(ctx, "GetIOInput", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list, _ := value["IOInput"].([]interface{})
+	inputs := make([]AlarmInput, 0, len(list))
+	for _, item := range list {
+		input, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		port, _ := input["port"].(float64)
+		status, _ := input["status"].(float64)
+		inputs = append(inputs, AlarmInput{Port: int(port), Active: status == 1})
+	}
+	return inputs, nil
+}
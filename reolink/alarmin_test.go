@@ -0,0 +1,38 @@
+package reolink
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestClient_GetAlarmInputs(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "secret", AlarmInputs: 2})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	ctx := context.Background()
+
+	info, err := client.GetDeviceInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	if info.AlarmInputs != 2 || info.AlarmOutputs != 0 {
+		t.Errorf("Expected 2 alarm inputs and no outputs, got %+v", info)
+	}
+
+	sim.SetAlarmInput(1, true)
+	inputs, err := client.GetAlarmInputs(ctx)
+	if err != nil {
+		t.Fatalf("GetAlarmInputs failed: %v", err)
+	}
+	want := []AlarmInput{{Port: 0}, {Port: 1, Active: true}}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("Expected %+v, got %+v", want, inputs)
+	}
+}
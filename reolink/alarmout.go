@@ -0,0 +1,49 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+)
+
+// AlarmOutput is the state of an alarm output relay
+type AlarmOutput struct {
+	Port   int  `json:"port"`
+	Active bool `json:"active"`
+}
+
+// GetAlarmOutputs retrieves the state of the device's alarm output relays.
+// Ports are numbered from 0; DeviceInfo.AlarmOutputs reports how many exist.
+func (c *Client) GetAlarmOutputs(ctx context.Context) ([]AlarmOutput, error) {
+	value, err := c.execCommand(ctx, "GetIOOutput", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list, _ := value["IOOutput"].([]interface{})
+	outputs := make([]AlarmOutput, 0, len(list))
+	for _, item := range list {
+		output, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		port, _ := output["port"].(float64)
+		status, _ := output["status"].(float64)
+		outputs = append(outputs, AlarmOutput{Port: int(port), Active: status == 1})
+	}
+	return outputs, nil
+}
+
+// SetAlarmOutput energizes (active) or releases an alarm output relay
+func (c *Client) SetAlarmOutput(ctx context.Context, port int, active bool) error {
+	if port < 0 {
+		return fmt.Errorf("invalid alarm output port: %d", port)
+	}
+	status := 0
+	if active {
+		status = 1
+	}
+	_, err := c.execCommand(ctx, "SetIOOutput", map[string]interface{}{
+		"IOOutput": map[string]interface{}{"port": port, "status": status},
+	})
+	return err
+}
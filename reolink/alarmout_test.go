@@ -0,0 +1,62 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestClient_AlarmOutputs(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Channels: 2, Password: "secret", AlarmOutputs: 2})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	ctx := context.Background()
+
+	info, err := client.GetDeviceInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	if info.AlarmOutputs != 2 {
+		t.Errorf("Expected 2 alarm outputs, got %d", info.AlarmOutputs)
+	}
+
+	if err := client.SetAlarmOutput(ctx, 1, true); err != nil {
+		t.Fatalf("SetAlarmOutput failed: %v", err)
+	}
+	if !sim.AlarmOutput(1) || sim.AlarmOutput(0) {
+		t.Error("Expected only port 1 to be active")
+	}
+	outputs, err := client.GetAlarmOutputs(ctx)
+	if err != nil {
+		t.Fatalf("GetAlarmOutputs failed: %v", err)
+	}
+	want := []AlarmOutput{{Port: 0}, {Port: 1, Active: true}}
+	if !reflect.DeepEqual(outputs, want) {
+		t.Errorf("Expected %+v, got %+v", want, outputs)
+	}
+
+	if err := client.SetAlarmOutput(ctx, 1, false); err != nil || sim.AlarmOutput(1) {
+		t.Errorf("Expected port 1 to be released, got %v", err)
+	}
+	if err := client.SetAlarmOutput(ctx, -1, true); err == nil {
+		t.Error("Expected an error for a negative port")
+	}
+}
+
+func TestClient_AlarmOutputs_NotSupported(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Password: "secret"}))
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	if _, err := client.GetAlarmOutputs(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
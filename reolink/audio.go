@@ -0,0 +1,109 @@
+package reolink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// maxAudioClipSize is the largest audio clip accepted for upload
+const maxAudioClipSize = 1 << 20
+
+// AudioClip is an audio file installed on the camera for the siren/audio alarm
+type AudioClip struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Selected bool   `json:"selected"`
+}
+
+// GetAudioClips lists the audio clips installed on a channel
+func (c *Client) GetAudioClips(ctx context.Context, channel int) ([]AudioClip, error) {
+	value, err := c.execCommand(ctx, "GetAudioFileList", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, audioClipError(err)
+	}
+
+	clips := []AudioClip{}
+	list, ok := value["AudioFileList"].([]interface{})
+	if !ok {
+		return clips, nil
+	}
+
+	for _, item := range list {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		clip := AudioClip{}
+		if v, ok := data["id"].(float64); ok {
+			clip.ID = int(v)
+		}
+		if v, ok := data["fileName"].(string); ok {
+			clip.Name = v
+		}
+		if v, ok := data["isSelected"].(float64); ok {
+			clip.Selected = v == 1
+		}
+		clips = append(clips, clip)
+	}
+
+	return clips, nil
+}
+
+// SelectAudioClip makes the given clip the one played by the audio alarm
+func (c *Client) SelectAudioClip(ctx context.Context, channel, id int) error {
+	_, err := c.execCommand(ctx, "SetAudioFile", map[string]interface{}{
+		"AudioFile": map[string]interface{}{
+			"channel": channel,
+			"id":      id,
+		},
+	})
+	return audioClipError(err)
+}
+
+// UploadAudioClip uploads a WAV or MP3 file to the camera
+func (c *Client) UploadAudioClip(ctx context.Context, channel int, name string, data []byte) error {
+	if err := validateAudioClip(name, data); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("channel=%d", channel)
+	return audioClipError(c.uploadFile(ctx, "UploadAudioFile", query, name, data))
+}
+
+// validateAudioClip checks size and format of an audio clip before upload
+func validateAudioClip(name string, data []byte) error {
+	if name == "" {
+		return fmt.Errorf("clip name is required")
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("clip data is empty")
+	}
+	if len(data) > maxAudioClipSize {
+		return fmt.Errorf("clip is too large: %d bytes (max %d)", len(data), maxAudioClipSize)
+	}
+
+	isWAV := len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+	isMP3 := bytes.HasPrefix(data, []byte("ID3")) || (len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0)
+	if !isWAV && !isMP3 {
+		return fmt.Errorf("unsupported audio format: clip must be WAV or MP3")
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != ".wav" && ext != ".mp3" {
+		return fmt.Errorf("clip name must end in .wav or .mp3")
+	}
+	return nil
+}
+
+// audioClipError explains the common case of firmware without custom audio support
+func audioClipError(err error) error {
+	if errors.Is(err, ErrNotSupported) {
+		return fmt.Errorf("custom audio clips require newer firmware: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testWAV = append([]byte("RIFF\x24\x00\x00\x00WAVEfmt "), make([]byte, 32)...)
+
+func TestValidateAudioClip(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"alarm.wav", testWAV, false},
+		{"alarm.mp3", []byte("ID3\x03\x00\x00\x00"), false},
+		{"", testWAV, true},
+		{"alarm.wav", nil, true},
+		{"alarm.wav", []byte("not audio"), true},
+		{"alarm.txt", testWAV, true},
+		{"alarm.wav", make([]byte, maxAudioClipSize+1), true},
+	}
+
+	for _, tt := range tests {
+		err := validateAudioClip(tt.name, tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateAudioClip(%q, %d bytes) error = %v, wantErr %v", tt.name, len(tt.data), err, tt.wantErr)
+		}
+	}
+}
+
+func TestClient_UploadAudioClip(t *testing.T) {
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cmd") != "UploadAudioFile" {
+			t.Errorf("Unexpected cmd: %s", r.URL.Query().Get("cmd"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Missing file: %v", err)
+		} else {
+			uploaded, _ = io.ReadAll(file)
+			if header.Filename != "alarm.wav" {
+				t.Errorf("Unexpected filename: %s", header.Filename)
+			}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "UploadAudioFile", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if err := client.UploadAudioClip(context.Background(), 0, "alarm.wav", testWAV); err != nil {
+		t.Fatalf("UploadAudioClip failed: %v", err)
+	}
+	if len(uploaded) != len(testWAV) {
+		t.Errorf("Expected %d bytes uploaded, got %d", len(testWAV), len(uploaded))
+	}
+}
+
+func TestClient_GetAudioClips_NotSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:   "GetAudioFileList",
+			Code:  1,
+			Error: &apiErrorDetail{RspCode: -9, Detail: "not support"},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	_, err := client.GetAudioClips(context.Background(), 0)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,625 @@
+package reolink
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backchannelRequire is the RTSP option tag that asks an ONVIF Profile T
+// device to include its audio backchannel in the SDP
+const backchannelRequire = "www.onvif.org/ver20/backchannel"
+
+// backchannelPacketDuration is the audio sent per RTP packet
+const backchannelPacketDuration = 20 * time.Millisecond
+
+// backchannelLead is how far ahead of real time audio may be sent; devices
+// buffer little, so writing faster than playback drops audio
+const backchannelLead = 100 * time.Millisecond
+
+// ErrNoBackchannel is returned by OpenBackchannel when the device's RTSP
+// server offers no audio backchannel
+var ErrNoBackchannel = fmt.Errorf("no RTSP audio backchannel: %w", ErrNotSupported)
+
+// Backchannel is an open RTSP audio backchannel (ONVIF Profile T) to a
+// channel's speaker. Audio written to it is played by the camera.
+type Backchannel struct {
+	conn    *rtspConn
+	url     string
+	session string
+
+	codec       string // RTP encoding name, e.g. "PCMU"
+	clockRate   int
+	payloadType uint8
+	interleaved uint8
+
+	mu      sync.Mutex
+	seq     uint16
+	ssrc    uint32
+	samples uint32 // RTP timestamp of the next packet
+	start   time.Time
+	closed  bool
+}
+
+// OpenBackchannel negotiates the RTSP audio backchannel of a channel. It
+// fails with ErrNoBackchannel when the device does not offer one, which is
+// the case on most Reolink firmware without ONVIF Profile T support.
+func (c *Client) OpenBackchannel(ctx context.Context, channel int) (*Backchannel, error) {
+	ctx, span := startSpan(ctx, "reolink RTSP backchannel")
+	span.SetAttribute("net.peer.name", c.ActiveHost())
+	b, err := c.openBackchannel(ctx, channel)
+	span.End(err)
+	return b, err
+}
+
+func (c *Client) openBackchannel(ctx context.Context, channel int) (*Backchannel, error) {
+	conn, streamURL, err := c.dialRTSP(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := negotiateBackchannel(conn, streamURL)
+	if err != nil {
+		conn.nc.Close()
+		return nil, err
+	}
+	_ = conn.nc.SetDeadline(time.Time{})
+	return b, nil
+}
+
+// CheckStream checks that the main RTSP stream of a channel can be opened
+// with the client's credentials, by asking the device to describe it
+func (c *Client) CheckStream(ctx context.Context, channel int) error {
+	conn, streamURL, err := c.dialRTSP(ctx, channel)
+	if err != nil {
+		return err
+	}
+	defer conn.nc.Close()
+
+	resp, err := conn.request("DESCRIBE", streamURL, textproto.MIMEHeader{"Accept": {"application/sdp"}})
+	if err != nil {
+		return err
+	}
+	if resp.status != 200 {
+		return fmt.Errorf("RTSP DESCRIBE failed: %d %s", resp.status, resp.reason)
+	}
+	return nil
+}
+
+// dialRTSP connects to the device's RTSP server with a deadline from ctx and
+// returns the connection with the URL of the channel's main stream
+func (c *Client) dialRTSP(ctx context.Context, channel int) (*rtspConn, string, error) {
+	addr := net.JoinHostPort(c.ActiveHost(), strconv.Itoa(c.rtspPort()))
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("RTSP connect failed: %w", err)
+	}
+	c.mu.RLock()
+	conn := newRTSPConn(nc, c.username, c.password)
+	c.mu.RUnlock()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = nc.SetDeadline(deadline)
+	} else {
+		_ = nc.SetDeadline(time.Now().Add(c.GetTimeouts().Request))
+	}
+	return conn, fmt.Sprintf("rtsp://%s/%s", addr, c.rtspPath(channel, "main")), nil
+}
+
+// negotiateBackchannel runs DESCRIBE, SETUP and PLAY for the backchannel track
+func negotiateBackchannel(conn *rtspConn, streamURL string) (*Backchannel, error) {
+	require := textproto.MIMEHeader{"Require": {backchannelRequire}}
+
+	resp, err := conn.request("DESCRIBE", streamURL, textproto.MIMEHeader{
+		"Accept":  {"application/sdp"},
+		"Require": {backchannelRequire},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.status == 551 { // Option not supported
+		return nil, ErrNoBackchannel
+	}
+	if resp.status != 200 {
+		return nil, fmt.Errorf("RTSP DESCRIBE failed: %d %s", resp.status, resp.reason)
+	}
+
+	media, ok := findBackchannel(string(resp.body))
+	if !ok {
+		return nil, ErrNoBackchannel
+	}
+	switch media.codec {
+	case "PCMU", "PCMA":
+	default:
+		return nil, fmt.Errorf("unsupported backchannel codec %s: %w", media.codec, ErrNotSupported)
+	}
+
+	base := streamURL
+	if cb := resp.header.Get("Content-Base"); cb != "" {
+		base = cb
+	}
+	b := &Backchannel{
+		conn:        conn,
+		url:         streamURL,
+		codec:       media.codec,
+		clockRate:   media.clockRate,
+		payloadType: media.payloadType,
+	}
+
+	setup := textproto.MIMEHeader{
+		"Transport": {"RTP/AVP/TCP;unicast;interleaved=0-1"},
+		"Require":   {backchannelRequire},
+	}
+	resp, err = conn.request("SETUP", resolveControl(base, media.control), setup)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status != 200 {
+		return nil, fmt.Errorf("RTSP SETUP failed: %d %s", resp.status, resp.reason)
+	}
+	b.session, _, _ = strings.Cut(resp.header.Get("Session"), ";")
+	if ch, ok := interleavedChannel(resp.header.Get("Transport")); ok {
+		b.interleaved = ch
+	}
+
+	require.Set("Session", b.session)
+	resp, err = conn.request("PLAY", base, require)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status != 200 {
+		return nil, fmt.Errorf("RTSP PLAY failed: %d %s", resp.status, resp.reason)
+	}
+
+	var id [6]byte
+	_, _ = rand.Read(id[:])
+	b.ssrc = binary.BigEndian.Uint32(id[:4])
+	b.seq = binary.BigEndian.Uint16(id[4:])
+	return b, nil
+}
+
+// Codec returns the RTP encoding of the backchannel, "PCMU" or "PCMA"
+func (b *Backchannel) Codec() string {
+	return b.codec
+}
+
+// SampleRate returns the sample rate the backchannel expects
+func (b *Backchannel) SampleRate() int {
+	return b.clockRate
+}
+
+// WritePCM encodes 16-bit PCM samples at the backchannel's sample rate with
+// its codec and sends them, paced at playback speed
+func (b *Backchannel) WritePCM(samples []int16) error {
+	encode := linearToULaw
+	if b.codec == "PCMA" {
+		encode = linearToALaw
+	}
+	data := make([]byte, len(samples))
+	for i, s := range samples {
+		data[i] = encode(s)
+	}
+	_, err := b.Write(data)
+	return err
+}
+
+// Write sends audio already encoded with the backchannel's codec, one byte
+// per sample, paced at playback speed
+func (b *Backchannel) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, errors.New("backchannel closed")
+	}
+	if b.start.IsZero() {
+		b.start = time.Now()
+	}
+
+	perPacket := b.clockRate * int(backchannelPacketDuration) / int(time.Second)
+	written := 0
+	for written < len(data) {
+		n := len(data) - written
+		if n > perPacket {
+			n = perPacket
+		}
+		// Do not get further ahead of playback than the device buffers
+		playAt := b.start.Add(time.Duration(b.samples) * time.Second / time.Duration(b.clockRate))
+		if wait := time.Until(playAt) - backchannelLead; wait > 0 {
+			time.Sleep(wait)
+		}
+		if err := b.sendPacket(data[written : written+n]); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// sendPacket writes one RTP packet, framed for RTSP interleaved transport
+func (b *Backchannel) sendPacket(payload []byte) error {
+	pkt := make([]byte, 4+12+len(payload))
+	pkt[0] = '$'
+	pkt[1] = b.interleaved
+	binary.BigEndian.PutUint16(pkt[2:], uint16(12+len(payload)))
+	rtp := pkt[4:]
+	rtp[0] = 0x80 // version 2
+	rtp[1] = b.payloadType & 0x7f
+	binary.BigEndian.PutUint16(rtp[2:], b.seq)
+	binary.BigEndian.PutUint32(rtp[4:], b.samples)
+	binary.BigEndian.PutUint32(rtp[8:], b.ssrc)
+	copy(rtp[12:], payload)
+
+	_ = b.conn.nc.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := b.conn.nc.Write(pkt); err != nil {
+		return fmt.Errorf("backchannel write failed: %w", err)
+	}
+	b.seq++
+	b.samples += uint32(len(payload))
+	return nil
+}
+
+// Close waits for the sent audio to play, tears the session down and closes
+// the connection
+func (b *Backchannel) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	if !b.start.IsZero() {
+		playEnd := b.start.Add(time.Duration(b.samples) * time.Second / time.Duration(b.clockRate))
+		if wait := time.Until(playEnd); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	_ = b.conn.nc.SetDeadline(time.Now().Add(2 * time.Second))
+	_, err := b.conn.request("TEARDOWN", b.url, textproto.MIMEHeader{"Session": {b.session}})
+	if cerr := b.conn.nc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// backchannelMedia is the SDP description of a backchannel track
+type backchannelMedia struct {
+	control     string
+	payloadType uint8
+	codec       string
+	clockRate   int
+}
+
+// findBackchannel returns the first audio track of an SDP marked sendonly,
+// which ONVIF uses for the client-to-device direction
+func findBackchannel(sdp string) (backchannelMedia, bool) {
+	var cur backchannelMedia
+	var inAudio, sendonly, found bool
+	var result backchannelMedia
+
+	finish := func() {
+		if inAudio && sendonly && !found {
+			result, found = cur, true
+		}
+	}
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "m="):
+			finish()
+			fields := strings.Fields(line[2:])
+			inAudio = len(fields) >= 4 && fields[0] == "audio"
+			sendonly = false
+			cur = backchannelMedia{}
+			if inAudio {
+				pt, _ := strconv.Atoi(fields[3])
+				cur.payloadType = uint8(pt)
+				// Static payload types need no rtpmap
+				switch pt {
+				case 0:
+					cur.codec, cur.clockRate = "PCMU", 8000
+				case 8:
+					cur.codec, cur.clockRate = "PCMA", 8000
+				}
+			}
+		case line == "a=sendonly":
+			sendonly = true
+		case strings.HasPrefix(line, "a=control:"):
+			cur.control = strings.TrimPrefix(line, "a=control:")
+		case strings.HasPrefix(line, "a=rtpmap:"):
+			ptStr, enc, _ := strings.Cut(strings.TrimPrefix(line, "a=rtpmap:"), " ")
+			if pt, err := strconv.Atoi(ptStr); err != nil || uint8(pt) != cur.payloadType {
+				continue
+			}
+			name, rate, _ := strings.Cut(enc, "/")
+			rate, _, _ = strings.Cut(rate, "/")
+			cur.codec = strings.ToUpper(name)
+			cur.clockRate, _ = strconv.Atoi(rate)
+		}
+	}
+	finish()
+	if found && result.clockRate == 0 {
+		result.clockRate = 8000
+	}
+	return result, found
+}
+
+// resolveControl resolves a track's control attribute against the base URL
+func resolveControl(base, control string) string {
+	if control == "" || control == "*" {
+		return base
+	}
+	if strings.HasPrefix(control, "rtsp://") {
+		return control
+	}
+	return strings.TrimSuffix(base, "/") + "/" + control
+}
+
+// interleavedChannel returns the first channel of "interleaved=a-b" in a
+// Transport header
+func interleavedChannel(transport string) (uint8, bool) {
+	for _, part := range strings.Split(transport, ";") {
+		if v, ok := strings.CutPrefix(part, "interleaved="); ok {
+			first, _, _ := strings.Cut(v, "-")
+			n, err := strconv.Atoi(first)
+			if err == nil && n >= 0 && n < 256 {
+				return uint8(n), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// rtspConn is a minimal RTSP client connection with Basic and Digest auth
+type rtspConn struct {
+	nc       net.Conn
+	r        *bufio.Reader
+	cseq     int
+	username string
+	password string
+
+	// Digest challenge from the last 401, reused for later requests
+	realm, nonce, qop, opaque string
+	basic                     bool
+	nc1                       int
+}
+
+type rtspResponse struct {
+	status int
+	reason string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func newRTSPConn(nc net.Conn, username, password string) *rtspConn {
+	return &rtspConn{nc: nc, r: bufio.NewReader(nc), username: username, password: password}
+}
+
+// request sends a request and reads its response, answering one auth challenge
+func (c *rtspConn) request(method, uri string, header textproto.MIMEHeader) (*rtspResponse, error) {
+	resp, err := c.roundTrip(method, uri, header)
+	if err != nil || resp.status != 401 {
+		return resp, err
+	}
+	if !c.parseChallenge(resp.header.Values("WWW-Authenticate")) {
+		return resp, fmt.Errorf("RTSP %s: unsupported authentication challenge", method)
+	}
+	resp, err = c.roundTrip(method, uri, header)
+	if err == nil && resp.status == 401 {
+		return nil, fmt.Errorf("RTSP %s: %w", method, ErrAuthFailed)
+	}
+	return resp, err
+}
+
+func (c *rtspConn) roundTrip(method, uri string, header textproto.MIMEHeader) (*rtspResponse, error) {
+	c.cseq++
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s RTSP/1.0\r\nCSeq: %d\r\nUser-Agent: reolink-plugin\r\n", method, uri, c.cseq)
+	if auth := c.authorization(method, uri); auth != "" {
+		fmt.Fprintf(&sb, "Authorization: %s\r\n", auth)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&sb, "%s: %s\r\n", k, v)
+		}
+	}
+	sb.WriteString("\r\n")
+	if _, err := io.WriteString(c.nc, sb.String()); err != nil {
+		return nil, fmt.Errorf("RTSP %s failed: %w", method, err)
+	}
+
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, fmt.Errorf("RTSP %s failed: %w", method, err)
+	}
+	return resp, nil
+}
+
+// readResponse reads the next response, skipping interleaved packets such
+// as RTCP reports that may arrive in between
+func (c *rtspConn) readResponse() (*rtspResponse, error) {
+	for {
+		b, err := c.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '$' {
+			break
+		}
+		var hdr [4]byte
+		if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+			return nil, err
+		}
+		if _, err := c.r.Discard(int(binary.BigEndian.Uint16(hdr[2:]))); err != nil {
+			return nil, err
+		}
+	}
+
+	tp := textproto.NewReader(c.r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	proto, rest, _ := strings.Cut(line, " ")
+	if !strings.HasPrefix(proto, "RTSP/") {
+		return nil, fmt.Errorf("malformed RTSP status line %q", line)
+	}
+	code, reason, _ := strings.Cut(rest, " ")
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return nil, fmt.Errorf("malformed RTSP status line %q", line)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	resp := &rtspResponse{status: status, reason: reason, header: header}
+	if n, _ := strconv.Atoi(header.Get("Content-Length")); n > 0 {
+		resp.body = make([]byte, n)
+		if _, err := io.ReadFull(c.r, resp.body); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// parseChallenge records a Digest (preferred) or Basic challenge
+func (c *rtspConn) parseChallenge(challenges []string) bool {
+	for _, ch := range challenges {
+		scheme, params, _ := strings.Cut(ch, " ")
+		if strings.EqualFold(scheme, "Digest") {
+			p := parseAuthParams(params)
+			c.realm, c.nonce, c.opaque = p["realm"], p["nonce"], p["opaque"]
+			c.qop = ""
+			for _, q := range strings.Split(p["qop"], ",") {
+				if strings.TrimSpace(q) == "auth" {
+					c.qop = "auth"
+				}
+			}
+			c.basic = false
+			return c.nonce != ""
+		}
+	}
+	for _, ch := range challenges {
+		if scheme, _, _ := strings.Cut(ch, " "); strings.EqualFold(scheme, "Basic") {
+			c.basic = true
+			return true
+		}
+	}
+	return false
+}
+
+// authorization returns the Authorization header for a request, if challenged
+func (c *rtspConn) authorization(method, uri string) string {
+	if c.basic {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+	}
+	if c.nonce == "" {
+		return ""
+	}
+	ha1 := md5Hex(c.username + ":" + c.realm + ":" + c.password)
+	ha2 := md5Hex(method + ":" + uri)
+	auth := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, c.username, c.realm, c.nonce, uri)
+	if c.qop == "auth" {
+		c.nc1++
+		var cn [8]byte
+		_, _ = rand.Read(cn[:])
+		cnonce := hex.EncodeToString(cn[:])
+		nc := fmt.Sprintf("%08x", c.nc1)
+		resp := md5Hex(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+		auth += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s", response="%s"`, nc, cnonce, resp)
+	} else {
+		auth += fmt.Sprintf(`, response="%s"`, md5Hex(ha1+":"+c.nonce+":"+ha2))
+	}
+	if c.opaque != "" {
+		auth += fmt.Sprintf(`, opaque="%s"`, c.opaque)
+	}
+	return auth
+}
+
+// parseAuthParams parses the comma separated key="value" list of a challenge
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, s = rest[1:], ""
+			} else {
+				value, s = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// linearToULaw encodes a 16-bit PCM sample as G.711 µ-law
+func linearToULaw(sample int16) byte {
+	const bias, clip = 0x84, 32635
+	s := int(sample)
+	sign := 0
+	if s < 0 {
+		s, sign = -s, 0x80
+	}
+	if s > clip {
+		s = clip
+	}
+	s += bias
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> (exponent + 3)) & 0x0f
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// linearToALaw encodes a 16-bit PCM sample as G.711 A-law
+func linearToALaw(sample int16) byte {
+	s := int(sample) >> 3 // 13-bit magnitude
+	sign := 0x80
+	if s < 0 {
+		s, sign = -s-1, 0
+	}
+	if s > 0xfff {
+		s = 0xfff
+	}
+	var b int
+	if s < 32 {
+		b = s >> 1
+	} else {
+		exponent := 1
+		for v := s >> 5; v > 1; v >>= 1 {
+			exponent++
+		}
+		b = exponent<<4 | (s>>exponent)&0x0f
+	}
+	return byte((b | sign) ^ 0x55)
+}
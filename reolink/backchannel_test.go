@@ -0,0 +1,111 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// newBackchannelDevice starts a simulated device with an RTSP server and
+// returns a client that has read its RTSP port
+func newBackchannelDevice(t *testing.T, backchannel bool) (*reolinksim.Server, *Client) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", Backchannel: backchannel, RTSPPort: l.Addr().(*net.TCPAddr).Port})
+	go sim.ServeRTSP(l)
+	server := httptest.NewServer(sim)
+	t.Cleanup(server.Close)
+
+	host, port := serverHostPort(server)
+	client := NewClient(host, port, "admin", "secret")
+	if _, err := client.GetNetPort(context.Background()); err != nil {
+		t.Fatalf("GetNetPort failed: %v", err)
+	}
+	return sim, client
+}
+
+func TestClient_OpenBackchannel(t *testing.T) {
+	sim, client := newBackchannelDevice(t, true)
+
+	b, err := client.OpenBackchannel(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("OpenBackchannel failed: %v", err)
+	}
+	if b.Codec() != "PCMU" || b.SampleRate() != 8000 {
+		t.Errorf("Expected PCMU at 8 kHz, got %s at %d", b.Codec(), b.SampleRate())
+	}
+
+	// 100ms of silence and full scale, split into 20ms packets
+	samples := make([]int16, 800)
+	for i := 400; i < len(samples); i++ {
+		samples[i] = 32767
+	}
+	if err := b.WritePCM(samples); err != nil {
+		t.Fatalf("WritePCM failed: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	audio := sim.BackchannelAudio()
+	if len(audio) != 800 || audio[0] != 0xFF || audio[799] != 0x80 {
+		t.Errorf("Expected 800 µ-law samples, got %d", len(audio))
+	}
+	if n := sim.CommandCount("RTSP TEARDOWN"); n != 1 {
+		t.Errorf("Expected one TEARDOWN, got %d", n)
+	}
+	if _, err := b.Write([]byte{0}); err == nil {
+		t.Error("Expected writes after Close to fail")
+	}
+}
+
+func TestClient_OpenBackchannel_NotOffered(t *testing.T) {
+	_, client := newBackchannelDevice(t, false)
+
+	_, err := client.OpenBackchannel(context.Background(), 0)
+	if !errors.Is(err, ErrNoBackchannel) || !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNoBackchannel, got %v", err)
+	}
+}
+
+func TestFindBackchannel(t *testing.T) {
+	sdp := "v=0\r\n" +
+		"m=video 0 RTP/AVP 96\r\na=control:track1\r\na=recvonly\r\n" +
+		"m=audio 0 RTP/AVP 97\r\na=control:track2\r\na=rtpmap:97 PCMA/8000/1\r\na=sendonly\r\n"
+
+	media, ok := findBackchannel(sdp)
+	if !ok || media.codec != "PCMA" || media.payloadType != 97 || media.clockRate != 8000 || media.control != "track2" {
+		t.Errorf("Unexpected backchannel media: %+v, %v", media, ok)
+	}
+	if _, ok := findBackchannel("v=0\r\nm=audio 0 RTP/AVP 0\r\na=recvonly\r\n"); ok {
+		t.Error("Expected receive-only audio not to be a backchannel")
+	}
+}
+
+func TestG711(t *testing.T) {
+	tests := []struct {
+		sample     int16
+		ulaw, alaw byte
+	}{
+		{0, 0xFF, 0xD5},
+		{32767, 0x80, 0xAA},
+		{-32768, 0x00, 0x2A},
+	}
+	for _, tt := range tests {
+		if got := linearToULaw(tt.sample); got != tt.ulaw {
+			t.Errorf("linearToULaw(%d) = %#x, want %#x", tt.sample, got, tt.ulaw)
+		}
+		if got := linearToALaw(tt.sample); got != tt.alaw {
+			t.Errorf("linearToALaw(%d) = %#x, want %#x", tt.sample, got, tt.alaw)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package reolink
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker()
+
+	for i := 0; i < breakerThreshold-1; i++ {
+		b.Failure()
+	}
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Fatal("Expected breaker to stay closed below threshold")
+	}
+
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected open breaker, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Error("Expected requests to be rejected while open")
+	}
+}
+
+func TestCircuitBreaker_HalfOpenTrial(t *testing.T) {
+	b := newCircuitBreaker()
+	for i := 0; i < breakerThreshold; i++ {
+		b.Failure()
+	}
+	b.openedAt = time.Now().Add(-breakerCooldown)
+
+	if !b.Allow() {
+		t.Fatal("Expected a trial request after cooldown")
+	}
+	if b.Allow() {
+		t.Error("Expected only one trial request while half-open")
+	}
+
+	// A failed trial reopens the breaker immediately
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Errorf("Expected open breaker after failed trial, got %s", b.State())
+	}
+
+	b.openedAt = time.Now().Add(-breakerCooldown)
+	b.Allow()
+	b.Success()
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Error("Expected closed breaker after successful trial")
+	}
+}
+
+func TestCircuitBreaker_Nil(t *testing.T) {
+	var b *circuitBreaker
+	if !b.Allow() || b.State() != BreakerClosed {
+		t.Error("Expected nil breaker to allow all requests")
+	}
+	b.Failure()
+	b.Success()
+}
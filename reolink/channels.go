@@ -0,0 +1,117 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+)
+
+// ChannelStatus is a channel of an NVR or Home Hub
+type ChannelStatus struct {
+	Channel int    `json:"channel"`
+	Name    string `json:"name,omitempty"`
+	Online  bool   `json:"online"`
+	Model   string `json:"model,omitempty"` // of the camera on the channel; empty if none is attached
+}
+
+// GetChannelStatus retrieves the channels of an NVR or Home Hub with the
+// name, online state and model of the camera on each
+func (c *Client) GetChannelStatus(ctx context.Context) ([]ChannelStatus, error) {
+	value, err := c.execCommand(ctx, "GetChannelstatus", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []ChannelStatus{}
+	names := make(map[int]string)
+	status, _ := value["status"].([]interface{})
+	for _, item := range status {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ch, ok := data["channel"].(float64)
+		if !ok {
+			continue
+		}
+		cs := ChannelStatus{Channel: int(ch)}
+		cs.Name, _ = data["name"].(string)
+		cs.Model, _ = data["typeInfo"].(string)
+		if v, ok := data["online"].(float64); ok {
+			cs.Online = v == 1
+		}
+		if cs.Name != "" {
+			names[cs.Channel] = cs.Name
+		}
+		channels = append(channels, cs)
+	}
+
+	c.mu.Lock()
+	c.cachedChannelNames = names
+	c.cachedChannelStatus = channels
+	c.mu.Unlock()
+
+	return channels, nil
+}
+
+// GetCachedChannelStatus returns the channels from the last GetChannelStatus
+// or GetChannelNames call
+func (c *Client) GetCachedChannelStatus() []ChannelStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedChannelStatus
+}
+
+// GetChannelNames retrieves the per-channel names configured on an NVR,
+// keyed by channel number. Channels without a name are omitted.
+func (c *Client) GetChannelNames(ctx context.Context) (map[int]string, error) {
+	if _, err := c.GetChannelStatus(ctx); err != nil {
+		return nil, err
+	}
+	return c.GetCachedChannelNames(), nil
+}
+
+// GetCachedChannelNames returns the channel names from the last GetChannelNames call
+func (c *Client) GetCachedChannelNames() map[int]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedChannelNames
+}
+
+// SetChannelName changes a channel's name on the device. The name is part of
+// the OSD settings, so the current OSD config is read and written back.
+func (c *Client) SetChannelName(ctx context.Context, channel int, name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	value, err := c.execCommand(ctx, "GetOsd", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return err
+	}
+
+	osd, ok := value["Osd"].(map[string]interface{})
+	if !ok {
+		osd = map[string]interface{}{}
+	}
+	osdChannel, ok := osd["osdChannel"].(map[string]interface{})
+	if !ok {
+		osdChannel = map[string]interface{}{"enable": 1}
+	}
+	osdChannel["name"] = name
+	osd["osdChannel"] = osdChannel
+	osd["channel"] = channel
+
+	_, err = c.execCommand(ctx, "SetOsd", map[string]interface{}{"Osd": osd})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.cachedChannelNames != nil {
+		c.cachedChannelNames[channel] = name
+	}
+	c.mu.Unlock()
+	return nil
+}
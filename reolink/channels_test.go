@@ -0,0 +1,81 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_SetChannelName_PreservesOsd(t *testing.T) {
+	var setOsd map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		switch cmds[0].Cmd {
+		case "GetOsd":
+			_ = json.NewEncoder(w).Encode([]apiResponse{{
+				Cmd:  "GetOsd",
+				Code: 0,
+				Value: map[string]interface{}{
+					"Osd": map[string]interface{}{
+						"channel":    float64(2),
+						"osdChannel": map[string]interface{}{"enable": float64(1), "name": "Camera3", "pos": "Lower Right"},
+						"osdTime":    map[string]interface{}{"enable": float64(1), "pos": "Top Center"},
+					},
+				},
+			}})
+		case "SetOsd":
+			setOsd, _ = cmds[0].Param["Osd"].(map[string]interface{})
+			_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetOsd", Code: 0}})
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if err := client.SetChannelName(context.Background(), 2, "Back Gate"); err != nil {
+		t.Fatalf("SetChannelName failed: %v", err)
+	}
+
+	osdChannel, _ := setOsd["osdChannel"].(map[string]interface{})
+	if osdChannel["name"] != "Back Gate" || osdChannel["pos"] != "Lower Right" {
+		t.Errorf("Unexpected osdChannel: %v", osdChannel)
+	}
+	if _, ok := setOsd["osdTime"]; !ok {
+		t.Error("Expected other OSD settings to be preserved")
+	}
+}
+
+func TestClient_GetChannelStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "GetChannelstatus", Code: 0, Value: map[string]interface{}{
+			"count": float64(2),
+			"status": []interface{}{
+				map[string]interface{}{"channel": float64(0), "name": "Garden", "online": float64(1), "typeInfo": "Argus 4 Pro"},
+				map[string]interface{}{"channel": float64(1), "name": "", "online": float64(0), "typeInfo": ""},
+			},
+		}}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	status, err := client.GetChannelStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetChannelStatus failed: %v", err)
+	}
+	want := []ChannelStatus{{Channel: 0, Name: "Garden", Online: true, Model: "Argus 4 Pro"}, {Channel: 1}}
+	if len(status) != 2 || status[0] != want[0] || status[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, status)
+	}
+	if names := client.GetCachedChannelNames(); len(names) != 1 || names[0] != "Garden" {
+		t.Errorf("Expected the channel names to be cached, got %v", names)
+	}
+	if !client.IsHomeHubModel("Reolink Home Hub Pro") || client.IsHomeHubModel("RLN8-410") {
+		t.Error("Unexpected Home Hub model detection")
+	}
+}
@@ -0,0 +1,2061 @@
+package reolink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client is an HTTP client for the Reolink API
+type Client struct {
+	host     string
+	port     int
+	username string
+	password string
+
+	// fallbackHosts are other addresses of the device, tried in order when
+	// activeHost stops answering; activeHost is host if empty
+	fallbackHosts []string
+	activeHost    string
+	onFailover    func(from, to string)
+	failoverMu    sync.Mutex // serializes switches of activeHost
+
+	// streamUser and streamPassword, if set, are embedded in stream URLs
+	// instead of the login account
+	streamUser     string
+	streamPassword string
+
+	// streamTemplates replace the built-in stream URLs where set
+	streamTemplates StreamTemplates
+
+	token         string
+	tokenExp      time.Time
+	useBasicAuth  bool // If true, use URL-based auth instead of token
+	tokenOnly     bool // If true, never send the credentials in the URL
+	useHTTPS      bool // If true, use HTTPS even when the port is not 443
+	secureStreams bool // Prefer RTSPS/RTMPS stream URLs where advertised
+	legacyEvents  bool // GetEvents unsupported; poll GetMdState/GetAiState instead
+
+	// Cached device info
+	cachedDevInfo       *DeviceInfo
+	cachedPerformance   *Performance
+	cachedHddInfo       []HddInfo
+	cachedChannelNames  map[int]string
+	cachedChannelStatus []ChannelStatus
+	cachedNetPort       *NetPort
+	cachedLocalLink     *LocalLink
+
+	retry    RetryPolicy
+	timeouts Timeouts
+
+	// maxSnapshotSize caps snapshot downloads so a misbehaving camera cannot
+	// exhaust memory
+	maxSnapshotSize int64
+
+	// snapshotOptions are passed to every Snap request
+	snapshotOptions SnapshotOptions
+
+	// limiter bounds the number of requests in flight to the device
+	limiter *requestLimiter
+
+	// lastSeen is the time of the last successful exchange with the device
+	lastSeen time.Time
+
+	// Diagnostics for device health reporting
+	lastError   string
+	lastErrorAt time.Time
+	tokenIssued time.Time
+	breaker     *circuitBreaker
+
+	http *http.Client
+	mu   sync.RWMutex
+}
+
+// NewClient creates a new Reolink API client
+func NewClient(host string, port int, username, password string) *Client {
+	if port == 0 {
+		port = 80
+	}
+	// Create HTTP client that accepts self-signed certs for HTTPS
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	return &Client{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		retry:    DefaultRetryPolicy,
+		timeouts: DefaultTimeouts,
+		breaker:  newCircuitBreaker(),
+
+		maxSnapshotSize: DefaultMaxSnapshotSize,
+		limiter:         newRequestLimiter(DefaultMaxConcurrent),
+		http: &http.Client{
+			Timeout:   DefaultTimeouts.Request,
+			Transport: tr,
+		},
+	}
+}
+
+// WithHost returns a client for the same device at another address, with
+// the credentials and settings of c but no session, cached state or fallback
+// addresses. The HTTP transport, and with it the TLS and proxy settings, is
+// shared.
+func (c *Client) WithHost(host string) *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Client{
+		host:     host,
+		port:     c.port,
+		username: c.username,
+		password: c.password,
+
+		streamUser:      c.streamUser,
+		streamPassword:  c.streamPassword,
+		streamTemplates: c.streamTemplates,
+		useHTTPS:        c.useHTTPS,
+		secureStreams:   c.secureStreams,
+		legacyEvents:    c.legacyEvents,
+		tokenOnly:       c.tokenOnly,
+
+		retry:           c.retry,
+		timeouts:        c.timeouts,
+		breaker:         newCircuitBreaker(),
+		maxSnapshotSize: c.maxSnapshotSize,
+		snapshotOptions: c.snapshotOptions,
+		limiter:         newRequestLimiter(c.limiter.max),
+		http:            c.http,
+	}
+}
+
+// DefaultMaxSnapshotSize is the snapshot size limit unless max_snapshot_bytes is configured
+const DefaultMaxSnapshotSize = 10 << 20
+
+// Timeouts holds the per-operation timeouts used by a Client
+type Timeouts struct {
+	Request  time.Duration // Single API request
+	Login    time.Duration // Complete login sequence, including HTTPS fallback
+	Snapshot time.Duration // Snapshot download
+}
+
+// DefaultTimeouts is used by clients unless overridden per device
+var DefaultTimeouts = Timeouts{
+	Request:  10 * time.Second,
+	Login:    20 * time.Second,
+	Snapshot: 10 * time.Second,
+}
+
+// TimeoutConfig is the per-device timeout configuration from the plugin config
+type TimeoutConfig struct {
+	RequestMs  int `json:"request_ms,omitempty"`
+	LoginMs    int `json:"login_ms,omitempty"`
+	SnapshotMs int `json:"snapshot_ms,omitempty"`
+}
+
+// Timeouts converts the config into Timeouts, filling unset fields from the default
+func (tc *TimeoutConfig) Timeouts() Timeouts {
+	t := DefaultTimeouts
+	if tc == nil {
+		return t
+	}
+	if tc.RequestMs > 0 {
+		t.Request = time.Duration(tc.RequestMs) * time.Millisecond
+	}
+	if tc.LoginMs > 0 {
+		t.Login = time.Duration(tc.LoginMs) * time.Millisecond
+	}
+	if tc.SnapshotMs > 0 {
+		t.Snapshot = time.Duration(tc.SnapshotMs) * time.Millisecond
+	}
+	return t
+}
+
+// SetTimeouts sets the request, login and snapshot timeouts
+func (c *Client) SetTimeouts(t Timeouts) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeouts = t
+	c.http.Timeout = t.Request
+}
+
+// GetTimeouts returns the timeouts currently in use
+func (c *Client) GetTimeouts() Timeouts {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.timeouts
+}
+
+// SetRetryPolicy sets how transient request failures are retried
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.mu.Lock()
+	c.retry = policy
+	c.mu.Unlock()
+}
+
+// TLSConfig controls how a device is reached over HTTPS
+type TLSConfig struct {
+	HTTPS  bool `json:"https,omitempty"`  // Use HTTPS even on ports other than 443
+	Verify bool `json:"verify,omitempty"` // Verify the certificate; Reolink devices ship self-signed ones
+
+	// Streams prefers RTSPS and RTMPS stream URLs where the device
+	// advertises them in GetNetPort
+	Streams bool `json:"streams,omitempty"`
+}
+
+// SetTLS configures HTTPS use and certificate verification
+func (c *Client) SetTLS(cfg TLSConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.useHTTPS = cfg.HTTPS
+	c.secureStreams = cfg.Streams
+	if tr, ok := c.http.Transport.(*http.Transport); ok {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: !cfg.Verify}
+	}
+}
+
+// ParseProxyURL parses a proxy address such as "http://jump:3128" or
+// "socks5://100.64.0.1:1080". The scheme must be http, https, socks5 or
+// socks5h, and credentials may be given as user:password@.
+func ParseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, want http, https, socks5 or socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", s)
+	}
+	return u, nil
+}
+
+// SetProxy sends all API requests through an HTTP or SOCKS5 proxy, for
+// devices only reachable through a jump host. An empty address connects
+// directly. Stream URLs are not affected.
+func (c *Client) SetProxy(proxy string) error {
+	var proxyFunc func(*http.Request) (*url.URL, error)
+	if proxy != "" {
+		u, err := ParseProxyURL(proxy)
+		if err != nil {
+			return err
+		}
+		proxyFunc = http.ProxyURL(u)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tr, ok := c.http.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("proxy not supported by a custom transport")
+	}
+	tr.Proxy = proxyFunc
+	return nil
+}
+
+func (c *Client) baseURL() string {
+	host := c.ActiveHost()
+	// Use HTTPS for port 443 or when configured, otherwise HTTP
+	if c.port == 443 {
+		return fmt.Sprintf("https://%s", host)
+	}
+	if c.useHTTPS {
+		return fmt.Sprintf("https://%s:%d", host, c.port)
+	}
+	return fmt.Sprintf("http://%s:%d", host, c.port)
+}
+
+func (c *Client) baseURLHTTPS() string {
+	host := c.ActiveHost()
+	if c.port == 443 || c.port == 80 {
+		return fmt.Sprintf("https://%s", host)
+	}
+	return fmt.Sprintf("https://%s:%d", host, c.port)
+}
+
+func (c *Client) apiURL() string {
+	return c.baseURL() + "/api.cgi"
+}
+
+// Login authenticates and obtains a session token. A device with fallback
+// addresses is tried at the active one first, then at the others in order.
+func (c *Client) Login(ctx context.Context) error {
+	hosts := c.failoverOrder()
+	var err error
+	for i, host := range hosts {
+		if i > 0 {
+			log.Printf("%s did not answer at %s (%v), trying %s", c.host, hosts[i-1], err, host)
+		}
+		c.setActiveHost(host)
+		if err = c.login(ctx); err == nil || !unreachable(ctx, err) {
+			if i > 0 {
+				c.movedTo(hosts[0], host)
+			}
+			return err
+		}
+	}
+	c.setActiveHost(hosts[0])
+	return err
+}
+
+// login authenticates at the active address.
+// Uses two-stage approach like Scrypted: try basic auth first, then token-based login
+func (c *Client) login(ctx context.Context) error {
+	// First, try basic auth by testing GetDevInfo with credentials in URL
+	// This works on some older firmware and avoids token management
+	log.Printf("Attempting login to %s:%d as user '%s'", c.ActiveHost(), c.port, c.username)
+
+	ctx, cancel := context.WithTimeout(ctx, c.GetTimeouts().Login)
+	defer cancel()
+
+	// Once the firmware version is known only its login style is tried
+	version, known := c.FirmwareVersion()
+	c.mu.RLock()
+	tokenOnly := c.tokenOnly
+	c.mu.RUnlock()
+	if tokenOnly && known && !version.Supports(FeatureTokenLogin) {
+		return fmt.Errorf("login refused (firmware %s only supports URL credentials)", version)
+	}
+	if !tokenOnly && (!known || !version.Supports(FeatureTokenLogin)) {
+		if err := c.tryBasicAuth(ctx); err == nil {
+			log.Printf("Basic auth succeeded for %s", c.host)
+			return nil
+		} else if known {
+			return fmt.Errorf("login failed (firmware %s only supports URL credentials): %w", version, err)
+		} else {
+			log.Printf("Basic auth failed for %s: %v, trying token-based login", c.host, err)
+		}
+	}
+
+	// Fall back to token-based Login API
+	cmd := []apiCommand{{
+		Cmd:    "Login",
+		Action: 0,
+		Param: map[string]interface{}{
+			"User": map[string]interface{}{
+				"userName": c.username,
+				"password": c.password,
+			},
+		},
+	}}
+
+	// Try HTTP first
+	log.Printf("Trying HTTP login to %s", c.baseURL()+"/api.cgi")
+	resp, err := c.doRequestURL(ctx, c.baseURL()+"/api.cgi", cmd)
+	if err != nil {
+		// If HTTP fails, try HTTPS
+		log.Printf("HTTP login failed for %s, trying HTTPS: %v", c.host, err)
+		resp, err = c.doRequestURL(ctx, c.baseURLHTTPS()+"/api.cgi", cmd)
+		if err != nil {
+			return fmt.Errorf("login request failed (tried HTTP and HTTPS): %w", err)
+		}
+	}
+
+	if len(resp) == 0 {
+		return fmt.Errorf("empty login response")
+	}
+
+	loginResp := resp[0]
+	log.Printf("Login response for %s: cmd=%s code=%d", c.host, loginResp.Cmd, loginResp.Code)
+
+	if loginResp.Code != 0 {
+		return fmt.Errorf("login failed: %w", newAPIError(loginResp))
+	}
+
+	value, ok := loginResp.Value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid login response format")
+	}
+
+	token, ok := value["Token"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("missing token in response")
+	}
+
+	tokenName, ok := token["name"].(string)
+	if !ok {
+		return fmt.Errorf("missing token name")
+	}
+
+	leaseTime := 3600
+	if lt, ok := token["leaseTime"].(float64); ok {
+		leaseTime = int(lt)
+	}
+
+	c.mu.Lock()
+	c.token = tokenName
+	c.tokenExp = time.Now().Add(time.Duration(leaseTime-60) * time.Second)
+	c.tokenIssued = time.Now()
+	c.mu.Unlock()
+
+	log.Printf("Token-based login succeeded for %s, token expires in %d seconds", c.host, leaseTime)
+	return nil
+}
+
+// tryBasicAuth attempts to access the API with credentials in the URL (like older firmware)
+func (c *Client) tryBasicAuth(ctx context.Context) error {
+	// Try with credentials in URL query string
+	authURL := fmt.Sprintf("%s/api.cgi?cmd=GetDevInfo&user=%s&password=%s",
+		c.baseURL(), url.QueryEscape(c.username), url.QueryEscape(c.password))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", authURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Try HTTPS
+		authURL = fmt.Sprintf("%s/api.cgi?cmd=GetDevInfo&user=%s&password=%s",
+			c.baseURLHTTPS(), url.QueryEscape(c.username), url.QueryEscape(c.password))
+		req, err = http.NewRequestWithContext(ctx, "GET", authURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err = c.http.Do(req)
+		if err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var responses []apiResponse
+	if err := json.Unmarshal(body, &responses); err != nil {
+		return err
+	}
+
+	if err := checkResponse(responses, "GetDevInfo"); err != nil {
+		return err
+	}
+
+	// Basic auth worked, set a flag to use URL-based auth instead of token
+	c.mu.Lock()
+	c.useBasicAuth = true
+	c.mu.Unlock()
+
+	return nil
+}
+
+// UseURLAuth makes the client send its credentials with every request
+// instead of logging in for a session token, as older firmware expects.
+// Login detects such firmware by itself.
+func (c *Client) UseURLAuth() {
+	c.mu.Lock()
+	c.useBasicAuth = true
+	c.mu.Unlock()
+}
+
+// UseTokenLogin makes Login only use the Login command, so the credentials
+// are never sent in a URL to a device not yet known to be the right one
+func (c *Client) UseTokenLogin() {
+	c.mu.Lock()
+	c.tokenOnly = true
+	c.mu.Unlock()
+}
+
+func (c *Client) ensureToken(ctx context.Context) error {
+	c.mu.RLock()
+	useBasic := c.useBasicAuth
+	needLogin := !useBasic && (c.token == "" || time.Now().After(c.tokenExp))
+	c.mu.RUnlock()
+
+	if needLogin {
+		return c.Login(ctx)
+	}
+	return nil
+}
+
+// TokenExpiresWithin reports whether the session token is held and expires
+// within d. It is false for devices using URL authentication.
+func (c *Client) TokenExpiresWithin(d time.Duration) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.useBasicAuth && c.token != "" && time.Until(c.tokenExp) < d
+}
+
+// Logout ends the current session so it does not linger on the device until
+// its lease expires. It is a no-op when no session token is held.
+func (c *Client) Logout(ctx context.Context) error {
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+
+	if token == "" {
+		return nil
+	}
+
+	cmd := []apiCommand{{Cmd: "Logout", Action: 0, Param: map[string]interface{}{}}}
+	resp, err := c.doRequestURL(ctx, c.apiURL()+"?token="+url.QueryEscape(token), cmd)
+	c.invalidateToken()
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp, "Logout")
+}
+
+// Close logs out and releases idle connections. The client can still be used
+// afterwards; it will log in again on the next request.
+func (c *Client) Close(ctx context.Context) error {
+	err := c.Logout(ctx)
+	c.http.CloseIdleConnections()
+	return err
+}
+
+// CloseIdleConnections closes kept-alive connections to the device, which
+// would otherwise hold a battery camera's radio awake between requests
+func (c *Client) CloseIdleConnections() {
+	c.http.CloseIdleConnections()
+}
+
+// GetDeviceInfo retrieves basic device information
+func (c *Client) GetDeviceInfo(ctx context.Context) (*DeviceInfo, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	cmd := []apiCommand{{
+		Cmd:    "GetDevInfo",
+		Action: 0,
+		Param:  map[string]interface{}{},
+	}}
+
+	resp, err := c.doRequest(ctx, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "GetDevInfo"); err != nil {
+		return nil, err
+	}
+
+	value, ok := resp[0].Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid device info format")
+	}
+
+	devInfo, ok := value["DevInfo"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing DevInfo")
+	}
+
+	info := &DeviceInfo{}
+	if v, ok := devInfo["model"].(string); ok {
+		info.Model = v
+	}
+	if v, ok := devInfo["name"].(string); ok {
+		info.Name = v
+	}
+	if v, ok := devInfo["serial"].(string); ok {
+		info.Serial = v
+	}
+	if v, ok := devInfo["firmVer"].(string); ok {
+		info.FirmwareVersion = v
+	}
+	if v, ok := devInfo["hwVer"].(string); ok {
+		info.HardwareVersion = v
+	}
+	if v, ok := devInfo["channelNum"].(float64); ok {
+		info.ChannelCount = int(v)
+	}
+	if info.ChannelCount == 0 {
+		info.ChannelCount = 1
+	}
+	if v, ok := devInfo["IOOutputNum"].(float64); ok {
+		info.AlarmOutputs = int(v)
+	}
+	if v, ok := devInfo["IOInputNum"].(float64); ok {
+		info.AlarmInputs = int(v)
+	}
+
+	// Cache the device info
+	c.mu.Lock()
+	c.cachedDevInfo = info
+	c.mu.Unlock()
+
+	return info, nil
+}
+
+// GetCachedDeviceInfo returns cached device info without making an API call
+func (c *Client) GetCachedDeviceInfo() *DeviceInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedDevInfo
+}
+
+// GetLocalLink retrieves the device's wired network settings, including its MAC address
+func (c *Client) GetLocalLink(ctx context.Context) (*LocalLink, error) {
+	value, err := c.execCommand(ctx, "GetLocalLink", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	link := &LocalLink{}
+	if data, ok := value["LocalLink"].(map[string]interface{}); ok {
+		if v, ok := data["mac"].(string); ok {
+			link.MAC = strings.ToLower(v)
+		}
+		if v, ok := data["type"].(string); ok {
+			link.Type = v
+		}
+		if v, ok := data["activeLink"].(string); ok {
+			link.ActiveLink = v
+		}
+		if static, ok := data["static"].(map[string]interface{}); ok {
+			if v, ok := static["ip"].(string); ok {
+				link.IP = v
+			}
+			if v, ok := static["mask"].(string); ok {
+				link.Mask = v
+			}
+			if v, ok := static["gateway"].(string); ok {
+				link.Gateway = v
+			}
+		}
+		if dns, ok := data["dns"].(map[string]interface{}); ok {
+			if v, ok := dns["auto"].(float64); ok {
+				link.DNSAuto = v == 1
+			}
+			for _, key := range []string{"dns1", "dns2"} {
+				if v, ok := dns[key].(string); ok && v != "" && v != "0.0.0.0" {
+					link.DNS = append(link.DNS, v)
+				}
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.cachedLocalLink = link
+	c.mu.Unlock()
+
+	return link, nil
+}
+
+// GetCachedLocalLink returns the last fetched network settings without making an API call
+func (c *Client) GetCachedLocalLink() *LocalLink {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedLocalLink
+}
+
+// SetLocalLink changes the device's wired network settings. Static mode
+// uses the link's IP, Mask, Gateway and DNS; DHCP mode only the DNS servers,
+// and only if DNSAuto is off. The device applies the change right away, so
+// the response may be lost when its address changes: a network error does
+// not mean the change was refused.
+func (c *Client) SetLocalLink(ctx context.Context, link LocalLink) error {
+	param := map[string]interface{}{"type": link.Type}
+	if link.Type == "Static" {
+		param["static"] = map[string]interface{}{"ip": link.IP, "mask": link.Mask, "gateway": link.Gateway}
+	}
+	dns := map[string]interface{}{"auto": 0, "dns1": "", "dns2": ""}
+	if link.DNSAuto {
+		dns["auto"] = 1
+	}
+	for i, server := range link.DNS {
+		if i < 2 {
+			dns[fmt.Sprintf("dns%d", i+1)] = server
+		}
+	}
+	param["dns"] = dns
+
+	if _, err := c.execCommand(ctx, "SetLocalLink", map[string]interface{}{"LocalLink": param}); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.cachedLocalLink = nil
+	c.mu.Unlock()
+	return nil
+}
+
+// GetPerformance retrieves CPU, encoder and network load statistics
+func (c *Client) GetPerformance(ctx context.Context) (*Performance, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	cmd := []apiCommand{{
+		Cmd:    "GetPerformance",
+		Action: 0,
+		Param:  map[string]interface{}{},
+	}}
+
+	resp, err := c.doRequest(ctx, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "GetPerformance"); err != nil {
+		return nil, err
+	}
+
+	perf := &Performance{UpdatedAt: time.Now()}
+	if value, ok := resp[0].Value.(map[string]interface{}); ok {
+		if data, ok := value["Performance"].(map[string]interface{}); ok {
+			if v, ok := data["cpuUsed"].(float64); ok {
+				perf.CPUUsed = int(v)
+			}
+			if v, ok := data["codecRate"].(float64); ok {
+				perf.CodecRate = int(v)
+			}
+			if v, ok := data["netThroughput"].(float64); ok {
+				perf.NetThroughput = int(v)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.cachedPerformance = perf
+	c.mu.Unlock()
+
+	return perf, nil
+}
+
+// GetCachedPerformance returns the last fetched performance stats without making an API call
+func (c *Client) GetCachedPerformance() *Performance {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedPerformance
+}
+
+// GetHddInfo retrieves capacity and status of the storage devices (NVR disks or SD cards)
+func (c *Client) GetHddInfo(ctx context.Context) ([]HddInfo, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	cmd := []apiCommand{{
+		Cmd:    "GetHddInfo",
+		Action: 0,
+		Param:  map[string]interface{}{},
+	}}
+
+	resp, err := c.doRequest(ctx, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "GetHddInfo"); err != nil {
+		return nil, err
+	}
+
+	disks := []HddInfo{}
+	if value, ok := resp[0].Value.(map[string]interface{}); ok {
+		if list, ok := value["HddInfo"].([]interface{}); ok {
+			for _, item := range list {
+				if data, ok := item.(map[string]interface{}); ok {
+					disks = append(disks, parseHddInfo(data))
+				}
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.cachedHddInfo = disks
+	c.mu.Unlock()
+
+	return disks, nil
+}
+
+// parseHddInfo reads one GetHddInfo entry. The device reports capacity and
+// free space in MB.
+func parseHddInfo(data map[string]interface{}) HddInfo {
+	hdd := HddInfo{Type: "HDD"}
+	if v, ok := data["id"].(float64); ok {
+		hdd.ID = int(v)
+	}
+	if v, ok := data["capacity"].(float64); ok {
+		hdd.CapacityGB = int(v) / 1024
+	}
+	if v, ok := data["size"].(float64); ok {
+		hdd.FreeGB = int(v) / 1024
+	}
+	if v, ok := data["storageType"].(float64); ok && v == 2 {
+		hdd.Type = "SD"
+	}
+
+	mounted, _ := data["mount"].(float64)
+	formatted, _ := data["format"].(float64)
+	switch {
+	case formatted == 0:
+		hdd.Status = "unformatted"
+	case mounted == 0:
+		hdd.Status = "unmounted"
+	default:
+		hdd.Status = "ok"
+	}
+	return hdd
+}
+
+// GetCachedHddInfo returns the last fetched storage info without making an API call
+func (c *Client) GetCachedHddInfo() []HddInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedHddInfo
+}
+
+// GetAbility retrieves camera capabilities
+func (c *Client) GetAbility(ctx context.Context, channel int) (*Ability, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	cmd := []apiCommand{{
+		Cmd:    "GetAbility",
+		Action: 0,
+		Param: map[string]interface{}{
+			"User": map[string]interface{}{
+				"userName": c.username,
+			},
+		},
+	}}
+
+	resp, err := c.doRequest(ctx, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "GetAbility"); err != nil {
+		return nil, err
+	}
+
+	ability := &Ability{}
+	value, ok := resp[0].Value.(map[string]interface{})
+	if !ok {
+		return ability, nil
+	}
+
+	abilityData, ok := value["Ability"].(map[string]interface{})
+	if !ok {
+		return ability, nil
+	}
+
+	if ptz, ok := abilityData["ptz"].(map[string]interface{}); ok {
+		if ver, ok := ptz["ver"].(float64); ok && ver > 0 {
+			ability.PTZ = true
+		}
+	}
+	if pt, ok := abilityData["pt"].(map[string]interface{}); ok {
+		if ver, ok := pt["ver"].(float64); ok && ver > 0 {
+			ability.PanTilt = true
+		}
+	}
+	if audio, ok := abilityData["supportAudioAlarm"].(map[string]interface{}); ok {
+		if ver, ok := audio["ver"].(float64); ok && ver > 0 {
+			ability.AudioAlarm = true
+		}
+	}
+	if talk, ok := abilityData["talk"].(map[string]interface{}); ok {
+		if ver, ok := talk["ver"].(float64); ok && ver > 0 {
+			ability.TwoWayAudio = true
+		}
+	}
+
+	chnData := channelAbility(abilityData, channel)
+	ability.Floodlight = abilitySupported(chnData, "floodLight") || abilitySupported(chnData, "supportFLswitch")
+	ability.FaceDetection = abilitySupported(chnData, "supportAiFace")
+	ability.PackageDetection = abilitySupported(chnData, "supportAiPackage")
+	// White-label and newer doorbells do not say so in their model name
+	for _, key := range doorbellAbilities {
+		ability.Doorbell = ability.Doorbell || abilitySupported(chnData, key)
+	}
+
+	ability.Device = abilityEntries(abilityData)
+	// Firmware reports the revision of its HTTP API as an ability entry
+	for _, key := range []string{"apiVersion", "apiVer"} {
+		if entry := ability.Device[key]; entry.Supported() {
+			ability.APIVersion = entry.Ver
+			break
+		}
+	}
+	if chnList, ok := abilityData["abilityChn"].([]interface{}); ok {
+		ability.Channels = make([]map[string]AbilityEntry, len(chnList))
+		for i, chn := range chnList {
+			chnData, _ := chn.(map[string]interface{})
+			ability.Channels[i] = abilityEntries(chnData)
+		}
+	}
+
+	return ability, nil
+}
+
+// doorbellAbilities are the channel abilities only doorbells report: the
+// visitor button, quick-reply messages and the button light
+var doorbellAbilities = []string{"supportVisitor", "supportQuickReplyPlay", "supportDoorbellLight"}
+
+// channelAbility returns the per-channel ability map from abilityChn, or nil
+func channelAbility(abilityData map[string]interface{}, channel int) map[string]interface{} {
+	chnList, ok := abilityData["abilityChn"].([]interface{})
+	if !ok || channel < 0 || channel >= len(chnList) {
+		return nil
+	}
+	chnData, _ := chnList[channel].(map[string]interface{})
+	return chnData
+}
+
+// abilityEntries collects the {ver, permit} entries of an ability map
+func abilityEntries(data map[string]interface{}) map[string]AbilityEntry {
+	entries := make(map[string]AbilityEntry)
+	for key, v := range data {
+		entry, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ver, ok := entry["ver"].(float64)
+		if !ok {
+			continue
+		}
+		permit, _ := entry["permit"].(float64)
+		entries[key] = AbilityEntry{Ver: int(ver), Permit: int(permit)}
+	}
+	return entries
+}
+
+// abilitySupported reports whether an ability entry has a non-zero version
+func abilitySupported(data map[string]interface{}, key string) bool {
+	entry, ok := data[key].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	ver, ok := entry["ver"].(float64)
+	return ok && ver > 0
+}
+
+// GetEncoderConfig retrieves video encoder settings
+func (c *Client) GetEncoderConfig(ctx context.Context, channel int) (*EncoderConfig, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	cmd := []apiCommand{{
+		Cmd:    "GetEnc",
+		Action: 0,
+		Param: map[string]interface{}{
+			"channel": channel,
+		},
+	}}
+
+	resp, err := c.doRequest(ctx, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "GetEnc"); err != nil {
+		return nil, err
+	}
+
+	cfg := &EncoderConfig{}
+	value, ok := resp[0].Value.(map[string]interface{})
+	if !ok {
+		return cfg, nil
+	}
+
+	if enc, ok := value["Enc"].(map[string]interface{}); ok {
+		if v, ok := enc["audio"].(float64); ok {
+			cfg.Audio = v == 1
+		}
+		if main, ok := enc["mainStream"].(map[string]interface{}); ok {
+			cfg.MainStream = parseStreamConfig(main)
+		}
+		if sub, ok := enc["subStream"].(map[string]interface{}); ok {
+			cfg.SubStream = parseStreamConfig(sub)
+		}
+		if ext, ok := enc["extStream"].(map[string]interface{}); ok {
+			extCfg := parseStreamConfig(ext)
+			cfg.ExtStream = &extCfg
+		}
+	}
+
+	return cfg, nil
+}
+
+func parseStreamConfig(data map[string]interface{}) StreamConfig {
+	cfg := StreamConfig{}
+	if v, ok := data["width"].(float64); ok {
+		cfg.Width = int(v)
+	}
+	if v, ok := data["height"].(float64); ok {
+		cfg.Height = int(v)
+	}
+	if v, ok := data["frameRate"].(float64); ok {
+		cfg.FrameRate = int(v)
+	}
+	if v, ok := data["bitRate"].(float64); ok {
+		cfg.BitRate = int(v)
+	}
+	if v, ok := data["video"].(map[string]interface{}); ok {
+		if codec, ok := v["videoType"].(string); ok {
+			cfg.Codec = codec
+		}
+	}
+	return cfg
+}
+
+// PTZControl sends a PTZ command
+func (c *Client) PTZControl(ctx context.Context, channel int, cmd PTZCmd) error {
+	if err := c.ensureToken(ctx); err != nil {
+		return err
+	}
+
+	apiCmd := []apiCommand{{
+		Cmd:    "PtzCtrl",
+		Action: 0,
+		Param: map[string]interface{}{
+			"channel": channel,
+			"op":      cmd.Operation,
+			"speed":   cmd.Speed,
+		},
+	}}
+
+	if cmd.Preset != "" {
+		apiCmd[0].Param["id"] = cmd.Preset
+	}
+
+	resp, err := c.doRequest(ctx, apiCmd, true)
+	if err != nil {
+		return err
+	}
+
+	if len(resp) > 0 && resp[0].Code != 0 {
+		return fmt.Errorf("PTZ command failed: %w", newAPIError(resp[0]))
+	}
+
+	return nil
+}
+
+// PTZAreaZoom centers and zooms on a rectangle given in main stream pixel coordinates
+func (c *Client) PTZAreaZoom(ctx context.Context, channel, x, y, width, height int) error {
+	_, err := c.execCommand(ctx, "PtzCtrl", map[string]interface{}{
+		"channel": channel,
+		"op":      "3DPosition",
+		"rect": map[string]interface{}{
+			"x":      x,
+			"y":      y,
+			"width":  width,
+			"height": height,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("PTZ area zoom failed: %w", err)
+	}
+	return nil
+}
+
+// ReolinkPTZPreset represents a PTZ preset position from Reolink API
+type ReolinkPTZPreset struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enable"`
+}
+
+// GetPTZPresets retrieves the list of PTZ presets for a channel
+func (c *Client) GetPTZPresets(ctx context.Context, channel int) ([]ReolinkPTZPreset, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	cmd := []apiCommand{{
+		Cmd:    "GetPtzPreset",
+		Action: 0,
+		Param: map[string]interface{}{
+			"channel": channel,
+		},
+	}}
+
+	resp, err := c.doRequest(ctx, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "GetPtzPreset"); err != nil {
+		return nil, err
+	}
+
+	value, ok := resp[0].Value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	presetList, ok := value["PtzPreset"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var presets []ReolinkPTZPreset
+	for _, p := range presetList {
+		preset, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ptz := ReolinkPTZPreset{}
+		if id, ok := preset["id"].(float64); ok {
+			ptz.ID = int(id)
+		}
+		if name, ok := preset["name"].(string); ok {
+			ptz.Name = name
+		}
+		if enable, ok := preset["enable"].(float64); ok {
+			ptz.Enabled = enable == 1
+		}
+		// Only include enabled presets with names
+		if ptz.Enabled && ptz.Name != "" {
+			presets = append(presets, ptz)
+		}
+	}
+
+	return presets, nil
+}
+
+// GetSnapshot captures a JPEG snapshot
+func (c *Client) GetSnapshot(ctx context.Context, channel int) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := c.StreamSnapshot(ctx, channel, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StreamSnapshot captures a JPEG snapshot and copies it to w as it is
+// received. It fails if the camera answers with something other than an image
+// or the image is larger than the maximum snapshot size.
+func (c *Client) StreamSnapshot(ctx context.Context, channel int, w io.Writer) (n int64, err error) {
+	ctx, span := startSpan(ctx, "reolink Snap")
+	span.SetAttribute("net.peer.name", c.ActiveHost())
+	defer func() { span.End(err) }()
+
+	if err := c.ensureToken(ctx); err != nil {
+		return 0, err
+	}
+
+	snapURL := fmt.Sprintf("%s/cgi-bin/api.cgi?cmd=Snap&channel=%d&%s%s",
+		c.baseURL(), channel, c.authQuery(), c.SnapshotOptions().query())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", snapURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	snapHTTP := *c.http
+	snapHTTP.Timeout = c.timeouts.Snapshot
+	maxSize := c.maxSnapshotSize
+	c.mu.RUnlock()
+
+	release, err := c.acquireSlot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	resp, err := snapHTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("snapshot failed: %s", resp.Status)
+	}
+	c.markSeen()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		// Errors such as a rejected token come back as JSON with status 200
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiResp []apiResponse
+		if json.Unmarshal(body, &apiResp) == nil && len(apiResp) > 0 && apiResp[0].Code != 0 {
+			return 0, newAPIError(apiResp[0])
+		}
+		return 0, fmt.Errorf("snapshot failed: unexpected content type %q", ct)
+	}
+	if resp.ContentLength > maxSize {
+		return 0, fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrSnapshotTooLarge, resp.ContentLength, maxSize)
+	}
+
+	// Read one byte past the limit to detect oversized bodies without a Content-Length
+	n, err = io.Copy(w, io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return n, err
+	}
+	if n > maxSize {
+		return n, fmt.Errorf("%w: exceeds the %d byte limit", ErrSnapshotTooLarge, maxSize)
+	}
+	return n, nil
+}
+
+// SnapshotOptions are the Snap parameters of a device's snapshots, so stored
+// images have a consistent size. Zero values leave the camera's defaults;
+// firmware without a parameter ignores it.
+type SnapshotOptions struct {
+	Quality int `json:"quality,omitempty"` // JPEG quality, 1-100
+	Width   int `json:"width,omitempty"`
+	Height  int `json:"height,omitempty"`
+}
+
+// Validate checks the ranges of the options
+func (o SnapshotOptions) Validate() error {
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100, got %d", o.Quality)
+	}
+	if o.Width < 0 || o.Height < 0 || (o.Width == 0) != (o.Height == 0) {
+		return fmt.Errorf("width and height must be set together")
+	}
+	return nil
+}
+
+// query returns the options as Snap query parameters, each with a leading &
+func (o SnapshotOptions) query() string {
+	var q string
+	if o.Quality > 0 {
+		q += fmt.Sprintf("&quality=%d", o.Quality)
+	}
+	if o.Width > 0 {
+		q += fmt.Sprintf("&width=%d&height=%d", o.Width, o.Height)
+	}
+	return q
+}
+
+// SetSnapshotOptions sets the Snap parameters of the device's snapshots
+func (c *Client) SetSnapshotOptions(opts SnapshotOptions) {
+	c.mu.Lock()
+	c.snapshotOptions = opts
+	c.mu.Unlock()
+}
+
+// SnapshotOptions returns the Snap parameters of the device's snapshots
+func (c *Client) SnapshotOptions() SnapshotOptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshotOptions
+}
+
+// SetMaxSnapshotSize sets the largest snapshot, in bytes, the client accepts
+func (c *Client) SetMaxSnapshotSize(size int64) {
+	if size <= 0 {
+		size = DefaultMaxSnapshotSize
+	}
+	c.mu.Lock()
+	c.maxSnapshotSize = size
+	c.mu.Unlock()
+}
+
+// ProbeCamera fully probes a camera and returns all detected information
+func (c *Client) ProbeCamera(ctx context.Context) (*CameraProbeResult, error) {
+	result := &CameraProbeResult{
+		Host:     c.host,
+		Port:     c.port,
+		Channels: []ChannelInfo{},
+	}
+
+	devInfo, err := c.GetDeviceInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device info: %w", err)
+	}
+
+	result.Model = devInfo.Model
+	result.Name = devInfo.Name
+	result.Serial = devInfo.Serial
+	result.FirmwareVersion = devInfo.FirmwareVersion
+	result.ChannelCount = devInfo.ChannelCount
+
+	result.DeviceType = c.detectDeviceType(devInfo.Model)
+	result.IsDoorbell = c.isDoorbellModel(devInfo.Model)
+	result.IsHub = c.IsHomeHubModel(devInfo.Model)
+	result.IsNVR = devInfo.ChannelCount > 1 || c.IsNVRModel(devInfo.Model) || result.IsHub
+	result.IsBattery = c.isBatteryModel(devInfo.Model)
+
+	ability, err := c.GetAbility(ctx, 0)
+	if err == nil {
+		result.HasPTZ = ability.PTZ || ability.PanTilt
+		result.HasTwoWayAudio = ability.TwoWayAudio
+		result.HasAudioAlarm = ability.AudioAlarm
+		if ability.Doorbell && !result.IsNVR {
+			result.IsDoorbell = true
+			result.DeviceType = "doorbell"
+		}
+	}
+
+	result.HasAIDetection = c.hasAIDetection(devInfo.Model)
+	if support, err := c.GetAISupport(ctx, 0); err == nil {
+		result.HasAIDetection = support.Any()
+	}
+
+	if result.IsNVR {
+		if disks, err := c.GetHddInfo(ctx); err == nil {
+			result.Storage = disks
+		}
+	}
+
+	if link, err := c.GetLocalLink(ctx); err == nil {
+		result.MAC = link.MAC
+		result.Network = link
+	}
+
+	channels := make([]ChannelInfo, 0, result.ChannelCount)
+	for ch := 0; ch < result.ChannelCount; ch++ {
+		channels = append(channels, ChannelInfo{Channel: ch})
+	}
+	// A hub has a channel for every camera it could pair, so only the
+	// channels with a camera are probed
+	if result.IsHub {
+		if status, err := c.GetChannelStatus(ctx); err == nil {
+			channels = channels[:0]
+			for _, cs := range status {
+				if cs.Model != "" {
+					channels = append(channels, ChannelInfo{Channel: cs.Channel, Name: cs.Name, Model: cs.Model})
+				}
+			}
+		}
+	}
+
+	for _, chInfo := range channels {
+		ch := chInfo.Channel
+
+		encCfg, err := c.GetEncoderConfig(ctx, ch)
+		if err == nil {
+			chInfo.MainStream = encCfg.MainStream
+			chInfo.SubStream = encCfg.SubStream
+			chInfo.Codec = encCfg.MainStream.Codec
+		}
+
+		chInfo.RTMPMain = c.RTMPStreamURL(ch, "main")
+		chInfo.RTMPSub = c.RTMPStreamURL(ch, "sub")
+		chInfo.RTSPMain = c.RTSPStreamURL(ch, "main")
+		chInfo.RTSPSub = c.RTSPStreamURL(ch, "sub")
+
+		result.Channels = append(result.Channels, chInfo)
+	}
+
+	return result, nil
+}
+
+func (c *Client) RTMPStreamURL(channel int, stream string) string {
+	streamID := fmt.Sprintf("channel%d_%s.bcs", channel, stream)
+	if tmpl := c.StreamTemplates().RTMP; tmpl != "" {
+		return c.expandStreamTemplate(tmpl, c.rtmpPort(), channel, stream)
+	}
+	username, password := c.StreamCredentials()
+	return fmt.Sprintf("rtmp://%s:%d/bcs/%s?user=%s&password=%s",
+		c.ActiveHost(), c.rtmpPort(), streamID, url.QueryEscape(username), url.QueryEscape(password))
+}
+
+func (c *Client) RTSPStreamURL(channel int, stream string) string {
+	// Stream suffix: "main" for main stream, "sub" for sub stream
+	streamSuffix := "main"
+	if stream == "sub" {
+		streamSuffix = "sub"
+	}
+	if tmpl := c.StreamTemplates().RTSP; tmpl != "" {
+		return c.expandStreamTemplate(tmpl, c.rtspPort(), channel, streamSuffix)
+	}
+	username, password := c.StreamCredentials()
+	return fmt.Sprintf("rtsp://%s:%s@%s:%d/%s",
+		url.QueryEscape(username), url.QueryEscape(password), c.ActiveHost(), c.rtspPort(), c.rtspPath(channel, streamSuffix))
+}
+
+// RTSPSStreamURL returns the RTSP-over-TLS URL of a channel's stream, on the
+// RTSPS port advertised by the device
+func (c *Client) RTSPSStreamURL(channel int, stream string) string {
+	if stream != "sub" {
+		stream = "main"
+	}
+	username, password := c.StreamCredentials()
+	return fmt.Sprintf("rtsps://%s:%s@%s:%d/%s",
+		url.QueryEscape(username), url.QueryEscape(password), c.ActiveHost(), c.SecureStreamPort("rtsp"), c.rtspPath(channel, stream))
+}
+
+// RTMPSStreamURL returns the RTMP-over-TLS URL of a channel's stream, on the
+// RTMPS port advertised by the device
+func (c *Client) RTMPSStreamURL(channel int, stream string) string {
+	username, password := c.StreamCredentials()
+	return fmt.Sprintf("rtmps://%s:%d/bcs/channel%d_%s.bcs?user=%s&password=%s",
+		c.ActiveHost(), c.SecureStreamPort("rtmp"), channel, stream, url.QueryEscape(username), url.QueryEscape(password))
+}
+
+// rtspPath returns the RTSP path of a channel's stream. The h264 path works
+// everywhere but cannot serve H.265 on newer firmware. The model database
+// may fix the prefix for a model.
+func (c *Client) rtspPath(channel int, stream string) string {
+	prefix := "h264Preview"
+	if v, known := c.FirmwareVersion(); known && v.Supports(FeatureRTSPPreview) {
+		prefix = "Preview"
+	}
+	if info := c.GetCachedDeviceInfo(); info != nil {
+		if p := LookupModel(info.Model).RTSPPrefix; p != "" {
+			prefix = p
+		}
+	}
+	return fmt.Sprintf("%s_%02d_%s", prefix, channel+1, stream)
+}
+
+// HLSStreamURL returns an HTTP-FLV URL for the given channel and stream
+// This is more reliable than RTSP for many Reolink cameras
+func (c *Client) HLSStreamURL(channel int, stream string) string {
+	// Use FLV format which is well-supported by ffmpeg and go2rtc
+	if tmpl := c.StreamTemplates().HLS; tmpl != "" {
+		return c.expandStreamTemplate(tmpl, c.rtmpPort(), channel, stream)
+	}
+	username, password := c.StreamCredentials()
+	return fmt.Sprintf("http://%s/flv?port=%d&app=bcs&stream=channel%d_%s.bcs&user=%s&password=%s",
+		c.ActiveHost(), c.rtmpPort(), channel, stream, url.QueryEscape(username), url.QueryEscape(password))
+}
+
+// StreamURL returns the stream URL for the specified protocol
+// Supported protocols: "hls" (default), "rtsp", "rtmp", and "rtsps" and
+// "rtmps" on devices advertising them. With TLS streams preferred, "rtsp"
+// and "rtmp" return the TLS variant where the device has one.
+func (c *Client) StreamURL(channel int, stream string, protocol string) string {
+	c.mu.RLock()
+	secure := c.secureStreams
+	c.mu.RUnlock()
+
+	switch protocol {
+	case "rtsp", "rtsps":
+		if (secure || protocol == "rtsps") && c.SecureStreamPort("rtsp") > 0 {
+			return c.RTSPSStreamURL(channel, stream)
+		}
+		return c.RTSPStreamURL(channel, stream)
+	case "rtmp", "rtmps":
+		if (secure || protocol == "rtmps") && c.SecureStreamPort("rtmp") > 0 {
+			return c.RTMPSStreamURL(channel, stream)
+		}
+		return c.RTMPStreamURL(channel, stream)
+	case "hls", "http", "flv", "":
+		// Default to HLS (HTTP-FLV) which is more reliable
+		return c.HLSStreamURL(channel, stream)
+	default:
+		return c.HLSStreamURL(channel, stream)
+	}
+}
+
+// detectDeviceType returns the device type of a model from the model database
+func (c *Client) detectDeviceType(model string) string {
+	return LookupModel(model).Type
+}
+
+func (c *Client) isDoorbellModel(model string) bool {
+	return LookupModel(model).Doorbell
+}
+
+// IsHomeHubModel reports whether model names a Reolink Home Hub, which
+// serves the battery cameras paired to it as channels, like an NVR
+func (c *Client) IsHomeHubModel(model string) bool {
+	return LookupModel(model).Hub
+}
+
+// IsNVRModel reports whether model names a Reolink NVR
+func (c *Client) IsNVRModel(model string) bool {
+	return LookupModel(model).NVR
+}
+
+func (c *Client) isBatteryModel(model string) bool {
+	return LookupModel(model).Battery
+}
+
+func (c *Client) hasAIDetection(model string) bool {
+	return LookupModel(model).AI
+}
+
+// execCommand sends a single authenticated command and returns its value object
+func (c *Client) execCommand(ctx context.Context, cmd string, param map[string]interface{}) (map[string]interface{}, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkCommandSupported(cmd); err != nil {
+		return nil, err
+	}
+
+	if param == nil {
+		param = map[string]interface{}{}
+	}
+	resp, err := c.doRequest(ctx, []apiCommand{{Cmd: cmd, Action: 0, Param: param}}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, cmd); err != nil {
+		return nil, err
+	}
+
+	value, _ := resp[0].Value.(map[string]interface{})
+	return value, nil
+}
+
+// execCommandRange sends a single command with action 1, which makes the camera
+// also report the allowed value ranges, and returns the value and range objects
+func (c *Client) execCommandRange(ctx context.Context, cmd string, param map[string]interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.doRequest(ctx, []apiCommand{{Cmd: cmd, Action: 1, Param: param}}, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkResponse(resp, cmd); err != nil {
+		return nil, nil, err
+	}
+
+	value, _ := resp[0].Value.(map[string]interface{})
+	rng, _ := resp[0].Range.(map[string]interface{})
+	return value, rng, nil
+}
+
+// authQuery returns the URL query used to authenticate API requests
+func (c *Client) authQuery() string {
+	c.mu.RLock()
+	token := c.token
+	useBasic := c.useBasicAuth
+	c.mu.RUnlock()
+
+	if useBasic {
+		// Use URL-based credentials instead of token
+		return "user=" + url.QueryEscape(c.username) + "&password=" + url.QueryEscape(c.password)
+	} else if token != "" {
+		return "token=" + url.QueryEscape(token)
+	}
+	return ""
+}
+
+// uploadFile POSTs a file as multipart form data to an upload command
+// such as UploadAudioFile or Upgrade. query holds extra URL parameters.
+func (c *Client) uploadFile(ctx context.Context, cmd, query, name string, data []byte) error {
+	if err := c.ensureToken(ctx); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	uploadURL := c.apiURL() + "?cmd=" + url.QueryEscape(cmd)
+	if query != "" {
+		uploadURL += "&" + query
+	}
+	if auth := c.authQuery(); auth != "" {
+		uploadURL += "&" + auth
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	// Uploads can take much longer than a normal API call; rely on ctx instead
+	c.mu.RLock()
+	uploadHTTP := *c.http
+	c.mu.RUnlock()
+	uploadHTTP.Timeout = 0
+
+	resp, err := uploadHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	c.markSeen()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var responses []apiResponse
+	if err := json.Unmarshal(respBody, &responses); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return checkResponse(responses, cmd)
+}
+
+func (c *Client) doRequest(ctx context.Context, commands []apiCommand, useToken bool) (resp []apiResponse, err error) {
+	ctx, span := startSpan(ctx, "reolink "+commandNames(commands))
+	span.SetAttribute("net.peer.name", c.ActiveHost())
+	defer func() {
+		if err == nil && len(resp) > 0 && resp[0].Code != 0 {
+			span.End(newAPIError(resp[0]))
+		} else {
+			span.End(err)
+		}
+	}()
+
+	release, err := c.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err = c.doRequestOnce(ctx, commands, useToken)
+	if err != nil || !useToken || !hasTokenError(resp) {
+		return resp, err
+	}
+
+	c.mu.RLock()
+	useBasic := c.useBasicAuth
+	c.mu.RUnlock()
+	if useBasic {
+		return resp, nil
+	}
+
+	// The session expired or was dropped by the device; log in again and
+	// retry the commands once
+	log.Printf("Session token for %s rejected, logging in again", c.host)
+	c.invalidateToken()
+	if err := c.Login(ctx); err != nil {
+		return nil, fmt.Errorf("re-login failed: %w", err)
+	}
+	return c.doRequestOnce(ctx, commands, useToken)
+}
+
+// commandNames joins the command names of a request for span names
+func commandNames(commands []apiCommand) string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.Cmd
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *Client) doRequestOnce(ctx context.Context, commands []apiCommand, useToken bool) ([]apiResponse, error) {
+	send := func() ([]apiResponse, error) {
+		reqURL := c.apiURL()
+		if useToken {
+			if auth := c.authQuery(); auth != "" {
+				reqURL += "?" + auth
+			}
+		}
+		return c.doRequestURL(ctx, reqURL, commands)
+	}
+
+	c.mu.RLock()
+	policy := c.retry
+	breaker := c.breaker
+	multiHost := len(c.fallbackHosts) > 0
+	c.mu.RUnlock()
+
+	if !breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	retryable, moveOn := isTransientError, func(err error) bool { return unreachable(ctx, err) }
+	if !readOnly(commands) {
+		retryable = neverSent
+		moveOn = func(err error) bool { return unreachable(ctx, err) && neverSent(err) }
+	}
+
+	host := c.ActiveHost()
+	resp, err := withRetry(ctx, policy, retryable, send)
+	if multiHost && moveOn(err) {
+		err = c.failover(ctx, host, func() (err error) {
+			resp, err = send()
+			return err
+		})
+	}
+	if err != nil {
+		if isTransientError(err) || errors.Is(err, context.DeadlineExceeded) {
+			breaker.Failure()
+		} else {
+			breaker.Release()
+		}
+		c.recordError(err)
+		return nil, err
+	}
+
+	// Any parsed response, even an API error, shows the device is reachable
+	breaker.Success()
+	c.markSeen()
+	for _, r := range resp {
+		if r.Code != 0 {
+			c.recordError(newAPIError(r))
+		}
+	}
+	return resp, nil
+}
+
+// recordError remembers the most recent failure for health reporting
+func (c *Client) recordError(err error) {
+	c.mu.Lock()
+	c.lastError = err.Error()
+	c.lastErrorAt = time.Now()
+	c.mu.Unlock()
+}
+
+// Health is a snapshot of a client's connection diagnostics
+type Health struct {
+	AuthMode    string
+	TokenAge    time.Duration // zero when no token is held
+	LastError   string
+	LastErrorAt time.Time
+	Breaker     string
+}
+
+// Health returns the client's connection diagnostics
+func (c *Client) Health() Health {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	h := Health{
+		AuthMode:    "token",
+		LastError:   c.lastError,
+		LastErrorAt: c.lastErrorAt,
+		Breaker:     c.breaker.State(),
+	}
+	if c.useBasicAuth {
+		h.AuthMode = "basic"
+	} else if c.token != "" && !c.tokenIssued.IsZero() {
+		h.TokenAge = time.Since(c.tokenIssued)
+	}
+	return h
+}
+
+// invalidateToken discards the current session token
+func (c *Client) invalidateToken() {
+	c.mu.Lock()
+	c.token = ""
+	c.tokenExp = time.Time{}
+	c.mu.Unlock()
+}
+
+// hasTokenError reports whether any response failed because the session
+// token is invalid or expired
+func hasTokenError(resp []apiResponse) bool {
+	for _, r := range resp {
+		if r.Code != 0 && errors.Is(newAPIError(r), ErrTokenInvalid) {
+			return true
+		}
+	}
+	return false
+}
+
+// markSeen records a successful exchange with the device
+func (c *Client) markSeen() {
+	c.mu.Lock()
+	c.lastSeen = time.Now()
+	c.mu.Unlock()
+}
+
+// Host returns the device host the client talks to
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Port returns the device's HTTP port
+func (c *Client) Port() int {
+	return c.port
+}
+
+// Username returns the account the client logs in with
+func (c *Client) Username() string {
+	return c.username
+}
+
+// SetStreamCredentials sets the account embedded in stream URLs, e.g. a
+// guest account so a leaked URL cannot be used to manage the device. An
+// empty username uses the login account.
+func (c *Client) SetStreamCredentials(username, password string) {
+	c.mu.Lock()
+	c.streamUser, c.streamPassword = username, password
+	c.mu.Unlock()
+}
+
+// StreamCredentials returns the account embedded in stream URLs
+func (c *Client) StreamCredentials() (username, password string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.streamUser != "" {
+		return c.streamUser, c.streamPassword
+	}
+	return c.username, c.password
+}
+
+// Ping checks whether the host answers the Reolink API. Any well-formed API
+// response, even "please login first", identifies a device, so no
+// credentials are needed.
+func (c *Client) Ping(ctx context.Context) error {
+	cmd := []apiCommand{{Cmd: "GetDevInfo", Action: 0, Param: map[string]interface{}{}}}
+	_, err := c.doRequestURL(ctx, c.apiURL(), cmd)
+	return err
+}
+
+// LastSeen returns the time of the last successful exchange with the device
+func (c *Client) LastSeen() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastSeen
+}
+
+func (c *Client) doRequestURL(ctx context.Context, reqURL string, commands []apiCommand) ([]apiResponse, error) {
+	body, err := json.Marshal(commands)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []apiResponse
+	if err := json.Unmarshal(respBody, &responses); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return responses, nil
+}
+
+// API types
+type apiCommand struct {
+	Cmd    string                 `json:"cmd"`
+	Action int                    `json:"action"`
+	Param  map[string]interface{} `json:"param"`
+}
+
+type apiResponse struct {
+	Cmd   string          `json:"cmd"`
+	Code  int             `json:"code"`
+	Value interface{}     `json:"value"`
+	Range interface{}     `json:"range,omitempty"`
+	Error *apiErrorDetail `json:"error,omitempty"`
+
+	// raw is the response as received, for raw command passthrough
+	raw json.RawMessage
+}
+
+func (r *apiResponse) UnmarshalJSON(data []byte) error {
+	type fields apiResponse
+	if err := json.Unmarshal(data, (*fields)(r)); err != nil {
+		return err
+	}
+	r.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type apiErrorDetail struct {
+	RspCode int    `json:"rspCode"`
+	Detail  string `json:"detail"`
+}
+
+type DeviceInfo struct {
+	Model           string `json:"model"`
+	Name            string `json:"name"`
+	Serial          string `json:"serial"`
+	FirmwareVersion string `json:"firmware_version"`
+	HardwareVersion string `json:"hardware_version"`
+	ChannelCount    int    `json:"channel_count"`
+	AlarmOutputs    int    `json:"alarm_outputs,omitempty"` // relay outputs, mostly on NVRs
+	AlarmInputs     int    `json:"alarm_inputs,omitempty"`  // wired sensor inputs
+}
+
+// LocalLink holds the wired network settings reported by GetLocalLink. The
+// address, mask and gateway are the static settings, which most firmware
+// fills with the current lease in DHCP mode.
+type LocalLink struct {
+	MAC        string   `json:"mac"`
+	Type       string   `json:"type"` // "DHCP" or "Static"
+	IP         string   `json:"ip,omitempty"`
+	Mask       string   `json:"mask,omitempty"`
+	Gateway    string   `json:"gateway,omitempty"`
+	DNS        []string `json:"dns,omitempty"`
+	DNSAuto    bool     `json:"dns_auto"`              // DNS servers come from DHCP
+	ActiveLink string   `json:"active_link,omitempty"` // "LAN" or "WiFi"
+}
+
+// Performance holds device load statistics reported by GetPerformance
+type Performance struct {
+	CPUUsed       int       `json:"cpu_used"`       // CPU usage in percent
+	CodecRate     int       `json:"codec_rate"`     // Total encoder bitrate in kbps
+	NetThroughput int       `json:"net_throughput"` // Network throughput in kbps
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// HddInfo describes a storage device reported by GetHddInfo
+type HddInfo struct {
+	ID         int    `json:"id"`
+	Type       string `json:"type"` // "HDD" or "SD"
+	CapacityGB int    `json:"capacity_gb"`
+	FreeGB     int    `json:"free_gb"`
+	Status     string `json:"status"` // "ok", "unformatted" or "unmounted"
+}
+
+type Ability struct {
+	PTZ              bool `json:"ptz"`
+	PanTilt          bool `json:"pan_tilt"`
+	AudioAlarm       bool `json:"audio_alarm"`
+	TwoWayAudio      bool `json:"two_way_audio"`
+	Floodlight       bool `json:"floodlight"`
+	FaceDetection    bool `json:"face_detection"`
+	PackageDetection bool `json:"package_detection"`
+	Doorbell         bool `json:"doorbell"` // visitor button, whatever the model name
+
+	// APIVersion is the HTTP API revision the firmware reports, 0 if it
+	// reports none
+	APIVersion int `json:"api_version,omitempty"`
+
+	// The complete GetAbility response: the device entries by name, and the
+	// entries of each channel from abilityChn
+	Device   map[string]AbilityEntry   `json:"device,omitempty"`
+	Channels []map[string]AbilityEntry `json:"channels,omitempty"`
+}
+
+// IsDoorbellChannel reports whether a channel has doorbell abilities. The
+// Doorbell field only describes the channel GetAbility was asked for.
+func (a *Ability) IsDoorbellChannel(channel int) bool {
+	if channel < 0 || channel >= len(a.Channels) {
+		return a.Doorbell
+	}
+	for _, key := range doorbellAbilities {
+		if a.Channels[channel][key].Supported() {
+			return true
+		}
+	}
+	return false
+}
+
+// Ability permission bits
+const (
+	PermitRead  = 1
+	PermitWrite = 2
+	PermitExec  = 4
+)
+
+// AbilityEntry is one capability reported by GetAbility. Ver is 0 when the
+// capability is not supported, and otherwise tells which variant of its
+// commands the firmware implements. Permit is a mask of Permit* bits for the
+// logged-in account.
+type AbilityEntry struct {
+	Ver    int `json:"ver"`
+	Permit int `json:"permit"`
+}
+
+// Supported reports whether the capability is available
+func (e AbilityEntry) Supported() bool {
+	return e.Ver > 0
+}
+
+type EncoderConfig struct {
+	MainStream StreamConfig  `json:"main_stream"`
+	SubStream  StreamConfig  `json:"sub_stream"`
+	ExtStream  *StreamConfig `json:"ext_stream,omitempty"` // only on cameras with a third stream
+	Audio      bool          `json:"audio"`                // audio is recorded and muxed into every stream
+}
+
+// Audio format of Reolink streams. GetEnc only reports whether audio is on;
+// the format is the same on every model.
+const (
+	AudioCodec      = "aac"
+	AudioSampleRate = 16000
+	AudioChannels   = 1
+)
+
+type StreamConfig struct {
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	FrameRate int    `json:"frame_rate"`
+	BitRate   int    `json:"bit_rate"`
+	Codec     string `json:"codec"`
+}
+
+type PTZCmd struct {
+	Operation string `json:"operation"`
+	Speed     int    `json:"speed"`
+	Preset    string `json:"preset"`
+}
+
+type CameraProbeResult struct {
+	Host            string        `json:"host"`
+	Port            int           `json:"port"`
+	Model           string        `json:"model"`
+	Name            string        `json:"name"`
+	Serial          string        `json:"serial"`
+	FirmwareVersion string        `json:"firmware_version"`
+	DeviceType      string        `json:"device_type"`
+	IsDoorbell      bool          `json:"is_doorbell"`
+	IsNVR           bool          `json:"is_nvr"`
+	IsHub           bool          `json:"is_hub"`
+	IsBattery       bool          `json:"is_battery"`
+	HasPTZ          bool          `json:"has_ptz"`
+	HasTwoWayAudio  bool          `json:"has_two_way_audio"`
+	HasAudioAlarm   bool          `json:"has_audio_alarm"`
+	HasAIDetection  bool          `json:"has_ai_detection"`
+	ChannelCount    int           `json:"channel_count"`
+	Channels        []ChannelInfo `json:"channels"`
+	Storage         []HddInfo     `json:"storage,omitempty"`
+
+	// MAC and Network identify the device across addresses and subnets;
+	// empty if the firmware lacks GetLocalLink
+	MAC     string     `json:"mac,omitempty"`
+	Network *LocalLink `json:"network,omitempty"`
+}
+
+type ChannelInfo struct {
+	Channel    int          `json:"channel"`
+	Name       string       `json:"name,omitempty"`
+	Model      string       `json:"model,omitempty"` // of the camera on a Home Hub channel
+	Codec      string       `json:"codec"`
+	MainStream StreamConfig `json:"main_stream"`
+	SubStream  StreamConfig `json:"sub_stream"`
+	RTMPMain   string       `json:"rtmp_main"`
+	RTMPSub    string       `json:"rtmp_sub"`
+	RTSPMain   string       `json:"rtsp_main"`
+	RTSPSub    string       `json:"rtsp_sub"`
+}
+
+// Utility for snapshots
+func encodeBase64(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
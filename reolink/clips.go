@@ -0,0 +1,60 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DownloadClip opens a recording stored on the device for download. source is
+// the file name as reported by the device's recording search. The caller must
+// close the returned reader; size is -1 if the device does not report it.
+func (c *Client) DownloadClip(ctx context.Context, source string) (io.ReadCloser, int64, error) {
+	if source == "" {
+		return nil, 0, fmt.Errorf("source file is required")
+	}
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	downloadURL := fmt.Sprintf("%s?cmd=Download&source=%s&output=%s",
+		c.apiURL(), url.QueryEscape(source), url.QueryEscape(path.Base(source)))
+	if auth := c.authQuery(); auth != "" {
+		downloadURL += "&" + auth
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Clip downloads can take minutes; rely on ctx instead of the request timeout
+	c.mu.RLock()
+	downloadHTTP := *c.http
+	c.mu.RUnlock()
+	downloadHTTP.Timeout = 0
+
+	resp, err := downloadHTTP.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	c.markSeen()
+
+	// Errors come back as a JSON body instead of the video
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") ||
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "text/") {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("download failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
@@ -0,0 +1,53 @@
+package reolink
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_DownloadClip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("cmd") != "Download" || q.Get("source") != "Mp4Record/2024-01-01/RecM01.mp4" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		if q.Get("output") != "RecM01.mp4" {
+			t.Errorf("Unexpected output: %s", q.Get("output"))
+		}
+		w.Header().Set("Content-Type", "video/mp4")
+		_, _ = w.Write([]byte("mp4data"))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	body, size, err := client.DownloadClip(context.Background(), "Mp4Record/2024-01-01/RecM01.mp4")
+	if err != nil {
+		t.Fatalf("DownloadClip failed: %v", err)
+	}
+	defer body.Close()
+
+	data, _ := io.ReadAll(body)
+	if string(data) != "mp4data" || size != 7 {
+		t.Errorf("Unexpected download: %q (%d bytes)", data, size)
+	}
+}
+
+func TestClient_DownloadClip_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"cmd":"Download","code":1}]`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if _, _, err := client.DownloadClip(context.Background(), "missing.mp4"); err == nil {
+		t.Error("Expected error for JSON error body")
+	}
+}
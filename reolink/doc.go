@@ -0,0 +1,12 @@
+// Package reolink is a client for the HTTP API (api.cgi) of Reolink cameras
+// and NVRs.
+//
+// A Client manages the session of one device: it logs in with a token, or
+// with credentials in the URL on older firmware, and logs in again when the
+// token expires. Requests are limited per device, retried on transient
+// failures and stopped by a circuit breaker while the device is not
+// answering. Live control requests made with WithPriority(ctx,
+// PriorityControl) go ahead of queued bulk requests such as snapshots.
+//
+// Device requests can be traced by passing a context from WithSpanStarter.
+package reolink
@@ -0,0 +1,192 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Binning modes mapped to the values used by GetIsp/SetIsp. Binning merges
+// 2x2 sensor pixels, trading resolution for low-light performance.
+var binningModes = map[string]int{
+	"off":  0,
+	"on":   1,
+	"auto": 2, // on at night only
+}
+
+// StreamOption is one resolution a stream can be set to, with the frame rates
+// and bitrates the device allows at that resolution
+type StreamOption struct {
+	Width      int   `json:"width"`
+	Height     int   `json:"height"`
+	FrameRates []int `json:"frame_rates"`
+	BitRates   []int `json:"bit_rates"` // kbps
+}
+
+// EncoderOptions are the stream settings a channel accepts, as reported in
+// the GetEnc range
+type EncoderOptions struct {
+	MainStream []StreamOption `json:"main_stream"`
+	SubStream  []StreamOption `json:"sub_stream"`
+}
+
+// GetEncoderOptions retrieves the resolutions, frame rates and bitrates each
+// stream of a channel can be set to
+func (c *Client) GetEncoderOptions(ctx context.Context, channel int) (*EncoderOptions, error) {
+	_, rng, err := c.execCommandRange(ctx, "GetEnc", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &EncoderOptions{}
+	entries, _ := rng["Enc"].([]interface{})
+	for _, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		if main, ok := entry["mainStream"].(map[string]interface{}); ok {
+			opts.MainStream = addStreamOption(opts.MainStream, main)
+		}
+		if sub, ok := entry["subStream"].(map[string]interface{}); ok {
+			opts.SubStream = addStreamOption(opts.SubStream, sub)
+		}
+	}
+
+	// Largest resolution first
+	for _, list := range [][]StreamOption{opts.MainStream, opts.SubStream} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Width*list[i].Height > list[j].Width*list[j].Height })
+	}
+	return opts, nil
+}
+
+// addStreamOption parses one stream of a GetEnc range entry, which lists a
+// resolution as "W*H" along with its frame rates and bitrates
+func addStreamOption(list []StreamOption, data map[string]interface{}) []StreamOption {
+	size, _ := data["size"].(string)
+	w, h, ok := strings.Cut(size, "*")
+	if !ok {
+		return list
+	}
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if err1 != nil || err2 != nil {
+		return list
+	}
+
+	for _, existing := range list {
+		if existing.Width == width && existing.Height == height {
+			return list
+		}
+	}
+	return append(list, StreamOption{
+		Width:      width,
+		Height:     height,
+		FrameRates: parseIntList(data["frameRate"]),
+		BitRates:   parseIntList(data["bitRate"]),
+	})
+}
+
+func parseIntList(v interface{}) []int {
+	values, _ := v.([]interface{})
+	result := make([]int, 0, len(values))
+	for _, value := range values {
+		if n, ok := value.(float64); ok {
+			result = append(result, int(n))
+		}
+	}
+	return result
+}
+
+// Validate checks stream settings against the options, taking the binning
+// mode into account: with binning on, the main stream is limited to half the
+// sensor's width and height. Zero fields are left unchanged and not checked.
+func (o *EncoderOptions) Validate(stream string, cfg StreamConfig, binning string) error {
+	var options []StreamOption
+	switch stream {
+	case "main":
+		options = o.MainStream
+	case "sub":
+		options = o.SubStream
+	default:
+		return fmt.Errorf("invalid stream: %s (must be main or sub)", stream)
+	}
+	if len(options) == 0 {
+		return fmt.Errorf("device reports no %s stream options", stream)
+	}
+	if cfg.Width == 0 && cfg.Height == 0 {
+		if cfg.FrameRate != 0 || cfg.BitRate != 0 {
+			return fmt.Errorf("width and height are required to check frame_rate and bit_rate")
+		}
+		return nil
+	}
+
+	var option *StreamOption
+	sizes := make([]string, 0, len(options))
+	for i := range options {
+		sizes = append(sizes, fmt.Sprintf("%dx%d", options[i].Width, options[i].Height))
+		if options[i].Width == cfg.Width && options[i].Height == cfg.Height {
+			option = &options[i]
+		}
+	}
+	if option == nil {
+		return fmt.Errorf("%s stream does not support %dx%d (supported: %s)", stream, cfg.Width, cfg.Height, strings.Join(sizes, ", "))
+	}
+
+	if stream == "main" && binning == "on" {
+		maxWidth, maxHeight := options[0].Width/2, options[0].Height/2
+		if cfg.Width > maxWidth || cfg.Height > maxHeight {
+			return fmt.Errorf("binning limits the main stream to %dx%d; turn binning off for %dx%d", maxWidth, maxHeight, cfg.Width, cfg.Height)
+		}
+	}
+	if cfg.FrameRate != 0 && !containsInt(option.FrameRates, cfg.FrameRate) {
+		return fmt.Errorf("frame rate %d is not supported at %dx%d (supported: %v)", cfg.FrameRate, cfg.Width, cfg.Height, option.FrameRates)
+	}
+	if cfg.BitRate != 0 && !containsInt(option.BitRates, cfg.BitRate) {
+		return fmt.Errorf("bitrate %d is not supported at %dx%d (supported: %v)", cfg.BitRate, cfg.Width, cfg.Height, option.BitRates)
+	}
+	return nil
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStreamConfig changes the resolution, frame rate and/or bitrate of the
+// main or sub stream. Zero fields are left unchanged; the codec cannot be set.
+func (c *Client) SetStreamConfig(ctx context.Context, channel int, stream string, cfg StreamConfig) error {
+	var key string
+	switch stream {
+	case "main":
+		key = "mainStream"
+	case "sub":
+		key = "subStream"
+	default:
+		return fmt.Errorf("invalid stream: %s (must be main or sub)", stream)
+	}
+
+	params := map[string]interface{}{}
+	if cfg.Width > 0 && cfg.Height > 0 {
+		params["size"] = fmt.Sprintf("%d*%d", cfg.Width, cfg.Height)
+	}
+	if cfg.FrameRate > 0 {
+		params["frameRate"] = cfg.FrameRate
+	}
+	if cfg.BitRate > 0 {
+		params["bitRate"] = cfg.BitRate
+	}
+	if len(params) == 0 {
+		return fmt.Errorf("no stream settings to apply")
+	}
+
+	_, err := c.execCommand(ctx, "SetEnc", map[string]interface{}{
+		"Enc": map[string]interface{}{"channel": channel, key: params},
+	})
+	return err
+}
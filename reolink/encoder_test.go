@@ -0,0 +1,140 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// encRange is the GetEnc range of a 12MP camera
+var encRange = map[string]interface{}{
+	"Enc": []interface{}{
+		map[string]interface{}{
+			"mainStream": map[string]interface{}{
+				"size":      "4512*2512",
+				"frameRate": []interface{}{float64(20), float64(15)},
+				"bitRate":   []interface{}{float64(6144), float64(8192)},
+			},
+			"subStream": map[string]interface{}{
+				"size":      "640*352",
+				"frameRate": []interface{}{float64(15), float64(10)},
+				"bitRate":   []interface{}{float64(256), float64(512)},
+			},
+		},
+		map[string]interface{}{
+			"mainStream": map[string]interface{}{
+				"size":      "2256*1256",
+				"frameRate": []interface{}{float64(30), float64(25), float64(20)},
+				"bitRate":   []interface{}{float64(2048), float64(4096)},
+			},
+			"subStream": map[string]interface{}{
+				"size":      "640*352",
+				"frameRate": []interface{}{float64(15)},
+				"bitRate":   []interface{}{float64(256)},
+			},
+		},
+	},
+}
+
+func TestClient_GetEncoderOptions(t *testing.T) {
+	var action int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		action = cmds[0].Action
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "GetEnc", Code: 0, Value: map[string]interface{}{}, Range: encRange}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	opts, err := client.GetEncoderOptions(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("GetEncoderOptions failed: %v", err)
+	}
+	if action != 1 {
+		t.Errorf("Expected GetEnc with action 1, got %d", action)
+	}
+	if len(opts.MainStream) != 2 || opts.MainStream[0].Width != 4512 || opts.MainStream[1].Height != 1256 {
+		t.Errorf("Unexpected main stream options: %+v", opts.MainStream)
+	}
+	if len(opts.MainStream[1].FrameRates) != 3 || opts.MainStream[1].FrameRates[0] != 30 {
+		t.Errorf("Unexpected frame rates: %v", opts.MainStream[1].FrameRates)
+	}
+	if len(opts.SubStream) != 1 {
+		t.Errorf("Expected duplicate sub stream sizes to be merged, got %+v", opts.SubStream)
+	}
+}
+
+func TestEncoderOptions_Validate(t *testing.T) {
+	opts := &EncoderOptions{
+		MainStream: []StreamOption{
+			{Width: 4512, Height: 2512, FrameRates: []int{20, 15}, BitRates: []int{6144, 8192}},
+			{Width: 2256, Height: 1256, FrameRates: []int{30, 25, 20}, BitRates: []int{2048, 4096}},
+		},
+		SubStream: []StreamOption{{Width: 640, Height: 352, FrameRates: []int{15}, BitRates: []int{256}}},
+	}
+
+	tests := []struct {
+		stream  string
+		cfg     StreamConfig
+		binning string
+		wantErr string
+	}{
+		{"main", StreamConfig{Width: 4512, Height: 2512, FrameRate: 20}, "off", ""},
+		{"main", StreamConfig{Width: 2256, Height: 1256, FrameRate: 30, BitRate: 4096}, "on", ""},
+		{"main", StreamConfig{Width: 4512, Height: 2512}, "auto", ""},
+		{"main", StreamConfig{Width: 4512, Height: 2512}, "on", "binning limits"},
+		{"main", StreamConfig{Width: 4512, Height: 2512, FrameRate: 30}, "off", "frame rate 30"},
+		{"main", StreamConfig{Width: 2256, Height: 1256, BitRate: 8192}, "", "bitrate 8192"},
+		{"main", StreamConfig{Width: 1920, Height: 1080}, "", "does not support 1920x1080"},
+		{"main", StreamConfig{FrameRate: 20}, "", "width and height are required"},
+		{"sub", StreamConfig{Width: 640, Height: 352, FrameRate: 15}, "on", ""},
+		{"ext", StreamConfig{Width: 640, Height: 352}, "", "invalid stream"},
+	}
+	for _, tt := range tests {
+		err := opts.Validate(tt.stream, tt.cfg, tt.binning)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s %+v binning=%s: unexpected error %v", tt.stream, tt.cfg, tt.binning, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s %+v binning=%s: expected %q error, got %v", tt.stream, tt.cfg, tt.binning, tt.wantErr, err)
+		}
+	}
+}
+
+func TestClient_SetStreamConfig(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetEnc", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if err := client.SetStreamConfig(context.Background(), 1, "main", StreamConfig{Width: 2256, Height: 1256, FrameRate: 25}); err != nil {
+		t.Fatalf("SetStreamConfig failed: %v", err)
+	}
+
+	enc, _ := received[0].Param["Enc"].(map[string]interface{})
+	main, _ := enc["mainStream"].(map[string]interface{})
+	if enc["channel"] != float64(1) || main["size"] != "2256*1256" || main["frameRate"] != float64(25) {
+		t.Errorf("Unexpected SetEnc params: %v", enc)
+	}
+	if _, ok := main["bitRate"]; ok {
+		t.Error("Expected an unset bitrate to be left out")
+	}
+
+	if err := client.SetStreamConfig(context.Background(), 0, "sub", StreamConfig{}); err == nil {
+		t.Error("Expected error for empty settings")
+	}
+}
@@ -0,0 +1,210 @@
+package reolink
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors for classes of Reolink API failures.
+// Use errors.Is to branch on the class of an error returned by the Client.
+var (
+	ErrAuthFailed       = errors.New("authentication failed")
+	ErrAccountLocked    = errors.New("account locked")
+	ErrTokenInvalid     = errors.New("session token invalid or expired")
+	ErrMaxSessions      = errors.New("maximum number of sessions reached")
+	ErrNotSupported     = errors.New("command not supported")
+	ErrDeviceBusy       = errors.New("device busy")
+	ErrInvalidParameter = errors.New("invalid parameter")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrDeviceFailure    = errors.New("device internal failure")
+
+	// ErrCircuitOpen is returned without contacting the device while its
+	// circuit breaker is open after repeated connection failures
+	ErrCircuitOpen = errors.New("device unreachable, circuit breaker open")
+
+	// ErrSnapshotTooLarge is returned when a snapshot exceeds the configured size limit
+	ErrSnapshotTooLarge = errors.New("snapshot too large")
+)
+
+// APIError is returned when the camera answers a command with a non-zero code
+type APIError struct {
+	Cmd     string // Command that failed, e.g. "GetDevInfo"
+	Code    int    // Top-level response code
+	RspCode int    // Detailed rspCode from the error object (usually negative)
+	Detail  string // Detail text reported by the camera, if any
+}
+
+func (e *APIError) Error() string {
+	msg := reolinkErrorMessage(e.code())
+	if e.Detail != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Detail)
+	}
+	if e.Cmd == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s failed: %s", e.Cmd, msg)
+}
+
+// Unwrap returns the sentinel error for the error class, if known
+func (e *APIError) Unwrap() error {
+	return reolinkErrorClass(e.code())
+}
+
+// code returns the most specific error code available
+func (e *APIError) code() int {
+	if e.RspCode != 0 {
+		return e.RspCode
+	}
+	return e.Code
+}
+
+// newAPIError builds an APIError from a failed API response
+func newAPIError(resp apiResponse) *APIError {
+	apiErr := &APIError{
+		Cmd:  resp.Cmd,
+		Code: resp.Code,
+	}
+	if resp.Error != nil {
+		apiErr.RspCode = resp.Error.RspCode
+		apiErr.Detail = resp.Error.Detail
+	}
+	return apiErr
+}
+
+// checkResponse returns an error if the response is empty or the first command failed
+func checkResponse(resp []apiResponse, cmd string) error {
+	if len(resp) == 0 {
+		return fmt.Errorf("%s failed: empty response", cmd)
+	}
+	if resp[0].Code != 0 {
+		apiErr := newAPIError(resp[0])
+		if apiErr.Cmd == "" {
+			apiErr.Cmd = cmd
+		}
+		return apiErr
+	}
+	return nil
+}
+
+// reolinkErrorMessage translates Reolink API error codes to human-readable messages.
+// Positive codes are the legacy top-level codes, negative codes are rspCode values.
+func reolinkErrorMessage(code int) string {
+	switch code {
+	case 1:
+		return "invalid credentials - check username and password"
+	case 2:
+		return "account is locked - too many failed login attempts"
+	case 3:
+		return "session expired - please try again"
+	case 4:
+		return "command not supported on this device"
+	case 5:
+		return "device is busy - try again later"
+	case 6:
+		return "parameter error - invalid request"
+	case 7:
+		return "permission denied - account may not have admin access"
+	case -1:
+		return "missing parameters"
+	case -2:
+		return "device out of memory"
+	case -3:
+		return "check error"
+	case -4:
+		return "parameter error - invalid request"
+	case -5:
+		return "maximum number of sessions reached - log out other clients"
+	case -6:
+		return "token invalid - login required"
+	case -7:
+		return "login failed - check username and password"
+	case -8:
+		return "operation timed out on device"
+	case -9:
+		return "command not supported on this device"
+	case -10:
+		return "protocol error"
+	case -11:
+		return "failed to read operation"
+	case -12:
+		return "failed to get configuration"
+	case -13:
+		return "failed to set configuration"
+	case -14:
+		return "failed to allocate memory on device"
+	case -15:
+		return "failed to create socket"
+	case -16:
+		return "failed to send data"
+	case -17:
+		return "failed to receive data"
+	case -18:
+		return "failed to open file"
+	case -19:
+		return "failed to read file"
+	case -20:
+		return "failed to write file"
+	case -21:
+		return "token error - login required"
+	case -22:
+		return "string exceeds maximum length"
+	case -23:
+		return "missing parameters"
+	case -24:
+		return "command error"
+	case -25:
+		return "internal device error"
+	case -26:
+		return "buffer full - device is busy, try again later"
+	case -27:
+		return "invalid user"
+	case -28:
+		return "user already exists"
+	case -29:
+		return "maximum number of users reached"
+	case -30:
+		return "firmware version is identical to the current one"
+	case -31:
+		return "another user is already upgrading the device"
+	case -32:
+		return "IP address conflicts with another device"
+	case -48:
+		return "failed to capture picture"
+	case -100, -101, -102, -103, -104, -105, -107:
+		return "test or upgrade failed"
+	case -480:
+		return "account locked - too many failed login attempts"
+	case -501, -502, -503:
+		return "login failed - check username and password"
+	case -505:
+		return "command not supported on this device"
+	default:
+		return fmt.Sprintf("unknown error (code %d)", code)
+	}
+}
+
+// reolinkErrorClass maps an error code to its sentinel error, or nil if unclassified
+func reolinkErrorClass(code int) error {
+	switch code {
+	case 1, -7, -27, -501, -502, -503:
+		return ErrAuthFailed
+	case 2, -480:
+		return ErrAccountLocked
+	case 3, -6, -21:
+		return ErrTokenInvalid
+	case -5:
+		return ErrMaxSessions
+	case 4, -9, -24, -505:
+		return ErrNotSupported
+	case 5, -8, -26, -31:
+		return ErrDeviceBusy
+	case 6, -1, -4, -22, -23, -28, -29, -30, -32:
+		return ErrInvalidParameter
+	case 7:
+		return ErrPermissionDenied
+	case -2, -3, -10, -11, -12, -13, -14, -15, -16, -17, -18, -19, -20, -25, -48:
+		return ErrDeviceFailure
+	default:
+		return nil
+	}
+}
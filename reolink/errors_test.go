@@ -0,0 +1,99 @@
+package reolink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestReolinkErrorMessage(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected string
+	}{
+		{1, "invalid credentials - check username and password"},
+		{-6, "token invalid - login required"},
+		{-26, "buffer full - device is busy, try again later"},
+		{-505, "command not supported on this device"},
+		{-9999, "unknown error (code -9999)"},
+	}
+
+	for _, tt := range tests {
+		if msg := reolinkErrorMessage(tt.code); msg != tt.expected {
+			t.Errorf("reolinkErrorMessage(%d) = %q, expected %q", tt.code, msg, tt.expected)
+		}
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		code     int
+		rspCode  int
+		expected error
+	}{
+		{1, -7, ErrAuthFailed},
+		{1, -6, ErrTokenInvalid},
+		{1, -5, ErrMaxSessions},
+		{1, -9, ErrNotSupported},
+		{1, -505, ErrNotSupported},
+		{1, -26, ErrDeviceBusy},
+		{1, -4, ErrInvalidParameter},
+		{7, 0, ErrPermissionDenied},
+		{2, 0, ErrAccountLocked},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &APIError{Cmd: "GetDevInfo", Code: tt.code, RspCode: tt.rspCode})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("code=%d rspCode=%d: expected errors.Is(%v)", tt.code, tt.rspCode, tt.expected)
+		}
+	}
+}
+
+func TestAPIError_Unclassified(t *testing.T) {
+	err := &APIError{Cmd: "GetDevInfo", Code: 1, RspCode: -9999}
+	if errors.Unwrap(err) != nil {
+		t.Error("Expected no sentinel for unknown code")
+	}
+	if err.Error() != "GetDevInfo failed: unknown error (code -9999)" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+}
+
+func TestNewAPIError_ParsesErrorObject(t *testing.T) {
+	raw := `[{"cmd":"GetEnc","code":1,"error":{"detail":"not support","rspCode":-9}}]`
+
+	var resp []apiResponse
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	err := checkResponse(resp, "GetEnc")
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %T", err)
+	}
+	if apiErr.RspCode != -9 {
+		t.Errorf("Expected rspCode -9, got %d", apiErr.RspCode)
+	}
+	if apiErr.Detail != "not support" {
+		t.Errorf("Expected detail 'not support', got '%s'", apiErr.Detail)
+	}
+	if !errors.Is(err, ErrNotSupported) {
+		t.Error("Expected ErrNotSupported")
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	if err := checkResponse(nil, "GetDevInfo"); err == nil {
+		t.Error("Expected error for empty response")
+	}
+	if err := checkResponse([]apiResponse{{Cmd: "GetDevInfo", Code: 0}}, "GetDevInfo"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+)
+
+// EventState is the current alarm state of a channel
+type EventState struct {
+	Motion  bool
+	Person  bool
+	Vehicle bool
+	Animal  bool
+	Face    bool
+	Package bool
+	Visitor bool // doorbell button pressed
+
+	// Set when the AI state reports face or package detection as supported,
+	// which some firmware does without advertising it in GetAbility
+	FaceSupported    bool
+	PackageSupported bool
+}
+
+// GetEventState retrieves the motion, AI and visitor alarm state of a channel.
+// It uses GetEvents, falling back to GetMdState and GetAiState on firmware
+// without it (which cannot report visitor presses).
+func (c *Client) GetEventState(ctx context.Context, channel int) (*EventState, error) {
+	c.mu.RLock()
+	legacy := c.legacyEvents
+	c.mu.RUnlock()
+
+	if !legacy {
+		value, err := c.execCommand(ctx, "GetEvents", map[string]interface{}{
+			"channel": channel,
+		})
+		if err == nil {
+			state := &EventState{}
+			if md, ok := value["md"].(map[string]interface{}); ok {
+				state.Motion = alarmActive(md)
+			}
+			if ai, ok := value["ai"].(map[string]interface{}); ok {
+				parseAiState(ai, state)
+			}
+			if visitor, ok := value["visitor"].(map[string]interface{}); ok {
+				state.Visitor = alarmActive(visitor)
+			}
+			return state, nil
+		}
+		if !errors.Is(err, ErrNotSupported) {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.legacyEvents = true
+		c.mu.Unlock()
+	}
+
+	state := &EventState{}
+	value, err := c.execCommand(ctx, "GetMdState", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := value["state"].(float64); ok {
+		state.Motion = v == 1
+	}
+
+	// AI state is optional; cameras without AI reject the command
+	if ai, err := c.execCommand(ctx, "GetAiState", map[string]interface{}{
+		"channel": channel,
+	}); err == nil {
+		parseAiState(ai, state)
+	}
+
+	return state, nil
+}
+
+// parseAiState fills the AI detection states from a GetAiState/GetEvents "ai" object
+func parseAiState(ai map[string]interface{}, state *EventState) {
+	if v, ok := ai["people"].(map[string]interface{}); ok {
+		state.Person = alarmActive(v)
+	}
+	if v, ok := ai["vehicle"].(map[string]interface{}); ok {
+		state.Vehicle = alarmActive(v)
+	}
+	if v, ok := ai["dog_cat"].(map[string]interface{}); ok {
+		state.Animal = alarmActive(v)
+	}
+	if v, ok := ai["face"].(map[string]interface{}); ok {
+		state.Face = alarmActive(v)
+		state.FaceSupported = aiSupported(v)
+	}
+	if v, ok := ai["package"].(map[string]interface{}); ok {
+		state.Package = alarmActive(v)
+		state.PackageSupported = aiSupported(v)
+	}
+}
+
+// aiSupported reads the support flag of an AI state object
+func aiSupported(data map[string]interface{}) bool {
+	v, ok := data["support"].(float64)
+	return ok && v == 1
+}
+
+// alarmActive reads the alarm_state flag of an event object
+func alarmActive(data map[string]interface{}) bool {
+	v, ok := data["alarm_state"].(float64)
+	return ok && v == 1
+}
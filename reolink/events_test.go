@@ -0,0 +1,85 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_GetEventState(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetEvents": map[string]interface{}{
+			"md":      map[string]interface{}{"alarm_state": 1},
+			"ai":      map[string]interface{}{"people": map[string]interface{}{"alarm_state": 1}, "vehicle": map[string]interface{}{"alarm_state": 0}},
+			"visitor": map[string]interface{}{"alarm_state": 1},
+		},
+	})
+	client := newTestClient(server)
+	client.token = "test-token"
+	client.tokenExp = time.Now().Add(time.Hour)
+
+	state, err := client.GetEventState(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("GetEventState failed: %v", err)
+	}
+	want := EventState{Motion: true, Person: true, Visitor: true}
+	if *state != want {
+		t.Errorf("Expected %+v, got %+v", want, *state)
+	}
+}
+
+func TestParseAiState_FacePackage(t *testing.T) {
+	var state EventState
+	parseAiState(map[string]interface{}{
+		"face":    map[string]interface{}{"alarm_state": float64(1), "support": float64(1)},
+		"package": map[string]interface{}{"alarm_state": float64(0), "support": float64(1)},
+	}, &state)
+
+	if !state.Face || state.Package {
+		t.Errorf("Expected face active and package inactive, got %+v", state)
+	}
+	if !state.FaceSupported || !state.PackageSupported {
+		t.Errorf("Expected face and package support, got %+v", state)
+	}
+}
+
+func TestClient_GetEventState_LegacyFallback(t *testing.T) {
+	var getEvents int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		resp := apiResponse{Cmd: cmds[0].Cmd}
+		switch cmds[0].Cmd {
+		case "GetEvents":
+			getEvents++
+			resp.Code = 1
+			resp.Error = &apiErrorDetail{RspCode: -9, Detail: "not support"}
+		case "GetMdState":
+			resp.Value = map[string]interface{}{"state": 1}
+		case "GetAiState":
+			resp.Value = map[string]interface{}{"dog_cat": map[string]interface{}{"alarm_state": 1}}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.token = "test-token"
+	client.tokenExp = time.Now().Add(time.Hour)
+
+	for i := 0; i < 2; i++ {
+		state, err := client.GetEventState(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("GetEventState failed: %v", err)
+		}
+		if !state.Motion || !state.Animal || state.Person {
+			t.Errorf("Unexpected state %+v", *state)
+		}
+	}
+	if getEvents != 1 {
+		t.Errorf("Expected GetEvents to be tried once, got %d", getEvents)
+	}
+}
@@ -0,0 +1,134 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"syscall"
+)
+
+// SetFallbackHosts sets other addresses of the device, e.g. its VPN address
+// next to its LAN one. When the active address stops answering, requests
+// move to the first of the others that does.
+func (c *Client) SetFallbackHosts(hosts []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallbackHosts = nil
+	for _, host := range hosts {
+		if host != "" && host != c.host && !containsString(c.fallbackHosts, host) {
+			c.fallbackHosts = append(c.fallbackHosts, host)
+		}
+	}
+}
+
+// Hosts returns every address of the device in the order they are tried,
+// the configured host first
+func (c *Client) Hosts() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string{c.host}, c.fallbackHosts...)
+}
+
+// ActiveHost returns the address requests and stream URLs currently use.
+// Host keeps returning the configured host, which identifies the device.
+func (c *Client) ActiveHost() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.activeHost != "" {
+		return c.activeHost
+	}
+	return c.host
+}
+
+// SetFailoverHandler sets a function called after the client moved to
+// another address, e.g. to republish stream URLs
+func (c *Client) SetFailoverHandler(fn func(from, to string)) {
+	c.mu.Lock()
+	c.onFailover = fn
+	c.mu.Unlock()
+}
+
+func (c *Client) setActiveHost(host string) {
+	c.mu.Lock()
+	c.activeHost = host
+	c.mu.Unlock()
+}
+
+// failoverOrder returns the addresses to try when logging in: the active one
+// first, so a working fallback is kept, then the others in configured order
+func (c *Client) failoverOrder() []string {
+	active := c.ActiveHost()
+	order := []string{active}
+	for _, host := range c.Hosts() {
+		if host != active {
+			order = append(order, host)
+		}
+	}
+	return order
+}
+
+// failover retries a request at the device's other addresses, in order,
+// after failed stopped answering, and keeps the first that answers active.
+// The device still answering with an error counts as answering. If none
+// answers, failed stays active and the last error is returned.
+func (c *Client) failover(ctx context.Context, failed string, try func() error) error {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+
+	// Another request may have moved on while this one waited
+	if c.ActiveHost() != failed {
+		return try()
+	}
+
+	var err error
+	for _, host := range c.Hosts() {
+		if host == failed {
+			continue
+		}
+		c.setActiveHost(host)
+		if err = try(); err == nil || !unreachable(ctx, err) {
+			c.movedTo(failed, host)
+			return err
+		}
+	}
+	c.setActiveHost(failed)
+	return err
+}
+
+// movedTo reports a switch of the active address
+func (c *Client) movedTo(from, to string) {
+	log.Printf("%s did not answer at %s, switched to %s", c.host, from, to)
+	c.mu.RLock()
+	fn := c.onFailover
+	c.mu.RUnlock()
+	if fn != nil {
+		fn(from, to)
+	}
+}
+
+// unreachable reports whether err means an address did not answer at all,
+// rather than the device answering with an error. A timeout only counts
+// while ctx, the caller's deadline, is still live.
+func unreachable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if isTransientError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
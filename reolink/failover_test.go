@@ -0,0 +1,102 @@
+package reolink
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// startSimAt serves a simulated device at host:port
+func startSimAt(t *testing.T, host string, port int) *httptest.Server {
+	t.Helper()
+	l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		t.Skipf("Cannot listen on %s: %v", host, err)
+	}
+	server := httptest.NewUnstartedServer(reolinksim.New(reolinksim.Camera{Password: "secret"}))
+	server.Listener = l
+	server.Start()
+	return server
+}
+
+func TestClient_Failover(t *testing.T) {
+	lan := startSimAt(t, "127.0.0.2", 0)
+	defer lan.Close()
+	_, port := serverHostPort(lan)
+	vpn := startSimAt(t, "127.0.0.3", port)
+	defer vpn.Close()
+
+	client := NewClient("127.0.0.2", port, "admin", "secret")
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	client.SetFallbackHosts([]string{"127.0.0.3", "127.0.0.2", ""})
+	if hosts := client.Hosts(); !reflect.DeepEqual(hosts, []string{"127.0.0.2", "127.0.0.3"}) {
+		t.Errorf("Unexpected hosts %v", hosts)
+	}
+	var moves []string
+	client.SetFailoverHandler(func(from, to string) { moves = append(moves, from+">"+to) })
+
+	ctx := context.Background()
+	if err := client.Login(ctx); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if client.ActiveHost() != "127.0.0.2" {
+		t.Errorf("Expected the first address active, got %s", client.ActiveHost())
+	}
+
+	// The LAN interface goes away; requests move to the VPN address, where
+	// the session of the LAN address is renewed
+	lan.Close()
+	if _, err := client.GetAbility(ctx, 0); err != nil {
+		t.Fatalf("GetAbility failed after failover: %v", err)
+	}
+	if client.ActiveHost() != "127.0.0.3" || client.Host() != "127.0.0.2" {
+		t.Errorf("Expected 127.0.0.3 active for 127.0.0.2, got %s for %s", client.ActiveHost(), client.Host())
+	}
+	if !reflect.DeepEqual(moves, []string{"127.0.0.2>127.0.0.3"}) {
+		t.Errorf("Unexpected moves %v", moves)
+	}
+	if url := client.RTSPStreamURL(0, "main"); !strings.Contains(url, "@127.0.0.3:") {
+		t.Errorf("Expected the stream URL at the active address, got %s", url)
+	}
+
+	// Nothing answers; the last working address stays active
+	vpn.Close()
+	if _, err := client.GetAbility(ctx, 0); err == nil {
+		t.Fatal("Expected an error with no address answering")
+	}
+	if client.ActiveHost() != "127.0.0.3" {
+		t.Errorf("Expected 127.0.0.3 to stay active, got %s", client.ActiveHost())
+	}
+}
+
+func TestClient_LoginFailover(t *testing.T) {
+	vpn := startSimAt(t, "127.0.0.3", 0)
+	defer vpn.Close()
+	_, port := serverHostPort(vpn)
+
+	// Nothing listens at the LAN address
+	client := NewClient("127.0.0.2", port, "admin", "secret")
+	client.SetFallbackHosts([]string{"127.0.0.3"})
+	if err := client.Login(context.Background()); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if client.ActiveHost() != "127.0.0.3" {
+		t.Errorf("Expected 127.0.0.3 active, got %s", client.ActiveHost())
+	}
+
+	// Wrong credentials are an answer, not a reason to try another address
+	client = NewClient("127.0.0.3", port, "admin", "wrong")
+	client.SetFallbackHosts([]string{"127.0.0.2"})
+	if err := client.Login(context.Background()); err == nil {
+		t.Fatal("Expected a login error")
+	}
+	if client.ActiveHost() != "127.0.0.3" {
+		t.Errorf("Expected 127.0.0.3 to stay active, got %s", client.ActiveHost())
+	}
+}
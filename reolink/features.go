@@ -0,0 +1,141 @@
+package reolink
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FirmwareVersion is the numeric part of a firmware version string such as
+// "v3.1.0.2368_23062700"
+type FirmwareVersion struct {
+	Major int
+	Minor int
+	Patch int
+	Build int
+}
+
+// ParseFirmwareVersion parses a firmware version as reported by GetDevInfo.
+// It fails unless at least the major and minor numbers are present.
+func ParseFirmwareVersion(s string) (FirmwareVersion, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(s)), "v")
+	s, _, _ = strings.Cut(s, "_")
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return FirmwareVersion{}, false
+	}
+	var nums [4]int
+	for i := 0; i < len(parts) && i < len(nums); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return FirmwareVersion{}, false
+		}
+		nums[i] = n
+	}
+	return FirmwareVersion{Major: nums[0], Minor: nums[1], Patch: nums[2], Build: nums[3]}, true
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than o
+func (v FirmwareVersion) Compare(o FirmwareVersion) int {
+	a := [4]int{v.Major, v.Minor, v.Patch, v.Build}
+	b := [4]int{o.Major, o.Minor, o.Patch, o.Build}
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v FirmwareVersion) String() string {
+	return fmt.Sprintf("v%d.%d.%d.%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
+// Feature is an API behavior that depends on the firmware version
+type Feature string
+
+const (
+	// FeatureTokenLogin is the Login API with session tokens. Older firmware
+	// only accepts credentials in the request URL.
+	FeatureTokenLogin Feature = "token_login"
+	// FeatureEvents is GetEvents, which reports all alarm states at once.
+	// Older firmware is polled with GetMdState and GetAiState.
+	FeatureEvents Feature = "events"
+	// FeatureAIState is GetAiState, absent before AI detection was added
+	FeatureAIState Feature = "ai_state"
+	// FeatureRTSPPreview is the "Preview_01_main" RTSP path, which also
+	// serves H.265 streams. Older firmware only knows "h264Preview_01_main".
+	FeatureRTSPPreview Feature = "rtsp_preview"
+)
+
+// featureMatrix holds the oldest firmware version implementing each feature
+var featureMatrix = map[Feature]FirmwareVersion{
+	FeatureTokenLogin:  {Major: 3},
+	FeatureEvents:      {Major: 3},
+	FeatureAIState:     {Major: 3},
+	FeatureRTSPPreview: {Major: 3},
+}
+
+// commandFeatures maps the commands that not every firmware implements to the
+// feature they belong to
+var commandFeatures = map[string]Feature{
+	"GetEvents":  FeatureEvents,
+	"GetAiState": FeatureAIState,
+}
+
+// Supports reports whether firmware v implements f. Features missing from
+// the matrix are assumed to be available everywhere.
+func (v FirmwareVersion) Supports(f Feature) bool {
+	min, ok := featureMatrix[f]
+	return !ok || v.Compare(min) >= 0
+}
+
+// FirmwareVersion returns the device's firmware version, known once the
+// device info has been read and the version string could be parsed
+func (c *Client) FirmwareVersion() (FirmwareVersion, bool) {
+	c.mu.RLock()
+	info := c.cachedDevInfo
+	c.mu.RUnlock()
+	if info == nil {
+		return FirmwareVersion{}, false
+	}
+	return ParseFirmwareVersion(info.FirmwareVersion)
+}
+
+// Supports reports whether the device implements f. Until the firmware
+// version is known every feature is assumed, and older firmware is detected
+// by its errors instead.
+func (c *Client) Supports(f Feature) bool {
+	v, ok := c.FirmwareVersion()
+	return !ok || v.Supports(f)
+}
+
+// Features returns the features of the matrix the device implements, sorted
+func (c *Client) Features() []Feature {
+	var features []Feature
+	for f := range featureMatrix {
+		if c.Supports(f) {
+			features = append(features, f)
+		}
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return features
+}
+
+// checkCommandSupported fails with ErrNotSupported for commands the device's
+// firmware is known not to implement, so they are not sent at all
+func (c *Client) checkCommandSupported(cmd string) error {
+	f, ok := commandFeatures[cmd]
+	if !ok {
+		return nil
+	}
+	if v, known := c.FirmwareVersion(); known && !v.Supports(f) {
+		return fmt.Errorf("%s is not available on firmware %s: %w", cmd, v, ErrNotSupported)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestParseFirmwareVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FirmwareVersion
+		ok   bool
+	}{
+		{"v3.1.0.2368_23062700", FirmwareVersion{3, 1, 0, 2368}, true},
+		{"v2.0.0.1441_19032101", FirmwareVersion{2, 0, 0, 1441}, true},
+		{"V3.0.0", FirmwareVersion{3, 0, 0, 0}, true},
+		{"3.0", FirmwareVersion{3, 0, 0, 0}, true},
+		{"", FirmwareVersion{}, false},
+		{"v3", FirmwareVersion{}, false},
+		{"build-abc", FirmwareVersion{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseFirmwareVersion(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseFirmwareVersion(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFirmwareVersion_Supports(t *testing.T) {
+	old := FirmwareVersion{Major: 2, Minor: 0, Patch: 0, Build: 1441}
+	current := FirmwareVersion{Major: 3, Minor: 0, Patch: 0, Build: 660}
+
+	for f := range featureMatrix {
+		if old.Supports(f) {
+			t.Errorf("Expected %s to be unavailable on %s", f, old)
+		}
+		if !current.Supports(f) {
+			t.Errorf("Expected %s to be available on %s", f, current)
+		}
+	}
+	if !old.Supports(Feature("unknown")) {
+		t.Error("Expected features outside the matrix to be assumed")
+	}
+}
+
+// TestClient_Features_OldFirmware checks that v2 firmware is only sent what it handles
+func TestClient_Features_OldFirmware(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{FirmwareVersion: "v2.0.0.1441_19032101", Password: "secret", LegacyEvents: true})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	ctx := context.Background()
+
+	if !client.Supports(FeatureEvents) {
+		t.Error("Expected features to be assumed before the firmware is known")
+	}
+	if _, err := client.GetDeviceInfo(ctx); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	if v, ok := client.FirmwareVersion(); !ok || v.Major != 2 {
+		t.Fatalf("Expected firmware v2 to be detected, got %+v, %v", v, ok)
+	}
+	if features := client.Features(); len(features) != 0 {
+		t.Errorf("Expected no versioned features, got %v", features)
+	}
+
+	if _, err := client.GetEventState(ctx, 0); err != nil {
+		t.Fatalf("GetEventState failed: %v", err)
+	}
+	if n := sim.CommandCount("GetEvents"); n != 0 {
+		t.Errorf("Expected GetEvents not to be sent, got %d", n)
+	}
+	if n := sim.CommandCount("GetAiState"); n != 0 {
+		t.Errorf("Expected GetAiState not to be sent, got %d", n)
+	}
+	if _, err := client.execCommand(ctx, "GetAiState", nil); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+
+	if url := client.RTSPStreamURL(0, "main"); !strings.HasSuffix(url, "/h264Preview_01_main") {
+		t.Errorf("Expected the h264 RTSP path, got %s", url)
+	}
+}
+
+func TestClient_Features_CurrentFirmware(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{FirmwareVersion: "v3.1.0.2368_23062700", Password: "secret", TokenOnly: true})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	ctx := context.Background()
+	if _, err := client.GetDeviceInfo(ctx); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+	if url := client.RTSPStreamURL(1, "sub"); !strings.HasSuffix(url, "/Preview_02_sub") {
+		t.Errorf("Expected the Preview RTSP path, got %s", url)
+	}
+
+	// Once the version is known, logging in again goes straight to the Login API
+	client.invalidateToken()
+	before := sim.CommandCount("GetDevInfo")
+	if err := client.Login(ctx); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if n := sim.CommandCount("GetDevInfo"); n != before {
+		t.Errorf("Expected no URL credential probe, got %d GetDevInfo requests", n-before)
+	}
+}
+
+func TestClient_UseTokenLogin(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	// The firmware would accept URL credentials, but they are not sent
+	client := NewClient(host, port, "admin", "secret")
+	client.UseTokenLogin()
+	if err := client.Login(context.Background()); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if n := sim.CommandCount("GetDevInfo"); n != 0 {
+		t.Errorf("Expected no URL credential probe, got %d GetDevInfo requests", n)
+	}
+	if n := sim.CommandCount("Login"); n != 1 {
+		t.Errorf("Expected 1 Login request, got %d", n)
+	}
+	if copied := client.WithHost("127.0.0.2"); !copied.tokenOnly {
+		t.Error("Expected copies of the client to keep token login")
+	}
+}
+
+func TestClient_Login_OldFirmwareSkipsTokenAPI(t *testing.T) {
+	// v2 firmware behind a proxy that only accepts tokens: the Login API must
+	// not be tried, since the firmware cannot handle it
+	sim := reolinksim.New(reolinksim.Camera{FirmwareVersion: "v2.0.0.1441_19032101", Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	if _, err := client.GetDeviceInfo(context.Background()); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+
+	wrong := NewClient(host, port, "admin", "wrong")
+	wrong.mu.Lock()
+	wrong.cachedDevInfo = client.GetCachedDeviceInfo()
+	wrong.mu.Unlock()
+	if err := wrong.Login(context.Background()); err == nil || !strings.Contains(err.Error(), "URL credentials") {
+		t.Errorf("Expected a URL credential login failure, got %v", err)
+	}
+	if n := sim.CommandCount("Login"); n != 0 {
+		t.Errorf("Expected the Login API not to be used, got %d", n)
+	}
+}
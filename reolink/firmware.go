@@ -0,0 +1,41 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// UpgradeFirmware uploads a firmware image and starts the upgrade. The device
+// reboots when the upgrade completes and is unreachable in the meantime.
+func (c *Client) UpgradeFirmware(ctx context.Context, name string, data []byte) error {
+	if err := ValidateFirmware(name, data); err != nil {
+		return err
+	}
+
+	_, err := c.execCommand(ctx, "UpgradePrepare", map[string]interface{}{
+		"restoreCfg": 0,
+		"fileName":   filepath.Base(name),
+	})
+	if err != nil {
+		return fmt.Errorf("upgrade rejected: %w", err)
+	}
+
+	return c.uploadFile(ctx, "Upgrade", "", name, data)
+}
+
+// ValidateFirmware checks a firmware image before it is sent to a device.
+// UpgradeFirmware does this itself.
+func ValidateFirmware(name string, data []byte) error {
+	if name == "" {
+		return fmt.Errorf("firmware file name is required")
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("firmware image is empty")
+	}
+	if ext := strings.ToLower(filepath.Ext(name)); ext != ".pak" {
+		return fmt.Errorf("firmware file must be a .pak image")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateFirmware(t *testing.T) {
+	if err := ValidateFirmware("IPC_523.pak", []byte{1}); err != nil {
+		t.Errorf("Expected valid firmware, got %v", err)
+	}
+	if err := ValidateFirmware("IPC_523.zip", []byte{1}); err == nil {
+		t.Error("Expected error for non-.pak file")
+	}
+	if err := ValidateFirmware("IPC_523.pak", nil); err == nil {
+		t.Error("Expected error for empty image")
+	}
+}
+
+func TestClient_UpgradeFirmware(t *testing.T) {
+	var cmds []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cmd := r.URL.Query().Get("cmd"); cmd != "" {
+			cmds = append(cmds, cmd)
+			_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: cmd, Code: 0}})
+			return
+		}
+		var req []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		cmds = append(cmds, req[0].Cmd)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: req[0].Cmd, Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if err := client.UpgradeFirmware(context.Background(), "IPC_523.pak", []byte("image")); err != nil {
+		t.Fatalf("UpgradeFirmware failed: %v", err)
+	}
+	if len(cmds) != 2 || cmds[0] != "UpgradePrepare" || cmds[1] != "Upgrade" {
+		t.Errorf("Unexpected command sequence: %v", cmds)
+	}
+}
@@ -0,0 +1,135 @@
+package reolink
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newReplayClient returns a client answered from testdata/fixtures/<name>.json
+func newReplayClient(t *testing.T, name string) (*Client, *ReplayTransport) {
+	t.Helper()
+	fixture, err := LoadFixture(filepath.Join("testdata", "fixtures", name+".json"))
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	replay := NewReplayTransport(fixture)
+	client := NewClient("camera.invalid", 80, "admin", "password")
+	client.http.Transport = replay
+	t.Cleanup(func() {
+		if missing := replay.Missing(); len(missing) > 0 {
+			t.Errorf("Requests missing from fixture %s:\n%s", name, strings.Join(missing, "\n"))
+		}
+	})
+	return client, replay
+}
+
+func TestFixture_Duo2PoE(t *testing.T) {
+	client, _ := newReplayClient(t, "reolink-duo-2-poe")
+	ctx := context.Background()
+
+	probe, err := client.ProbeCamera(ctx)
+	if err != nil {
+		t.Fatalf("ProbeCamera failed: %v", err)
+	}
+	if probe.DeviceType != "floodlight_camera" || probe.HasPTZ || probe.IsNVR {
+		t.Errorf("Unexpected probe result: %+v", probe)
+	}
+	if main := probe.Channels[0].MainStream; main.Width != 4608 || main.Height != 1728 || main.Codec != "h265" {
+		t.Errorf("Expected the 4608x1728 panorama stream, got %+v", main)
+	}
+
+	enc, err := client.GetEncoderConfig(ctx, 0)
+	if err != nil || enc.ExtStream == nil || enc.ExtStream.Width != 2304 {
+		t.Errorf("Expected a 2304x864 ext stream, got %+v, %v", enc, err)
+	}
+	ability, err := client.GetAbility(ctx, 0)
+	if err != nil || !ability.Floodlight {
+		t.Errorf("Expected floodlight ability, got %+v, %v", ability, err)
+	}
+
+	state, err := client.GetEventState(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetEventState failed: %v", err)
+	}
+	if !state.Motion || !state.Vehicle || state.Person || state.Visitor {
+		t.Errorf("Unexpected event state: %+v", state)
+	}
+}
+
+func TestFixture_TrackMixPoE(t *testing.T) {
+	client, _ := newReplayClient(t, "reolink-trackmix-poe")
+	ctx := context.Background()
+
+	probe, err := client.ProbeCamera(ctx)
+	if err != nil {
+		t.Fatalf("ProbeCamera failed: %v", err)
+	}
+	if probe.DeviceType != "ptz_camera" || !probe.HasPTZ {
+		t.Errorf("Unexpected probe result: %+v", probe)
+	}
+
+	// Disabled and unnamed presets are skipped
+	presets, err := client.GetPTZPresets(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetPTZPresets failed: %v", err)
+	}
+	if len(presets) != 2 || presets[0].Name != "Patio" || presets[1].ID != 1 {
+		t.Errorf("Unexpected presets: %+v", presets)
+	}
+
+	// This firmware lacks GetEvents, so state comes from GetMdState and GetAiState
+	state, err := client.GetEventState(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetEventState failed: %v", err)
+	}
+	if !state.Motion || !state.Person || !state.Animal || state.Vehicle {
+		t.Errorf("Unexpected event state: %+v", state)
+	}
+	if !client.legacyEvents {
+		t.Error("Expected the client to switch to legacy event polling")
+	}
+}
+
+func TestFixture_VideoDoorbellPoE(t *testing.T) {
+	client, _ := newReplayClient(t, "reolink-video-doorbell-poe")
+	ctx := context.Background()
+
+	probe, err := client.ProbeCamera(ctx)
+	if err != nil {
+		t.Fatalf("ProbeCamera failed: %v", err)
+	}
+	if probe.DeviceType != "doorbell" || !probe.IsDoorbell || !probe.HasTwoWayAudio {
+		t.Errorf("Unexpected probe result: %+v", probe)
+	}
+	if main := probe.Channels[0].MainStream; main.Width != 1920 || main.Height != 2560 {
+		t.Errorf("Expected the portrait main stream, got %+v", main)
+	}
+
+	state, err := client.GetEventState(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetEventState failed: %v", err)
+	}
+	if !state.Visitor || !state.Person || !state.Face || !state.FaceSupported || !state.PackageSupported {
+		t.Errorf("Unexpected event state: %+v", state)
+	}
+
+	if snap, err := client.GetSnapshot(ctx, 0); err != nil || !bytes.Equal(snap, placeholderJPEG) {
+		t.Errorf("Expected the placeholder snapshot, got %v", err)
+	}
+}
+
+func TestReplayTransport_Missing(t *testing.T) {
+	client, replay := newReplayClient(t, "reolink-duo-2-poe")
+
+	if _, err := client.GetPerformance(context.Background()); err == nil {
+		t.Error("Expected an error for a request that was not recorded")
+	}
+	if missing := replay.Missing(); len(missing) != 1 || !strings.Contains(missing[0], "GetPerformance") {
+		t.Errorf("Expected GetPerformance to be reported missing, got %v", missing)
+	}
+	// Do not fail the test from the cleanup check
+	replay.missing = nil
+}
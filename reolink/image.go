@@ -0,0 +1,125 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+)
+
+// Anti-flicker modes mapped to the values used by GetIsp/SetIsp
+var antiFlickerModes = map[string]string{
+	"off":     "Off",
+	"50hz":    "50HZ",
+	"60hz":    "60HZ",
+	"outdoor": "Outdoor",
+}
+
+// Rotations in degrees mapped to the rotation values used by GetIsp/SetIsp.
+// 180 is what the Reolink apps call "flip"; 90 and 270 are the corridor mode
+// of models with a portrait sensor readout.
+var rotations = map[int]int{0: 0, 180: 1, 90: 2, 270: 3}
+
+// ImageSettings are the ISP (image signal processor) settings of a channel.
+// Nil/empty fields are omitted when reading and left unchanged when writing.
+type ImageSettings struct {
+	NoiseReduction *bool  `json:"noise_reduction,omitempty"` // 3D noise reduction
+	AntiFlicker    string `json:"anti_flicker,omitempty"`    // "off", "50hz", "60hz" or "outdoor"
+	Rotation       *int   `json:"rotation,omitempty"`        // degrees: 0, 90, 180 (flip) or 270
+	Mirror         *bool  `json:"mirror,omitempty"`          // horizontal mirroring
+	Binning        string `json:"binning,omitempty"`         // "off", "on" or "auto"; only on models with binning
+}
+
+// GetImageSettings retrieves the ISP settings for a channel
+func (c *Client) GetImageSettings(ctx context.Context, channel int) (*ImageSettings, error) {
+	value, err := c.execCommand(ctx, "GetIsp", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &ImageSettings{}
+	isp, ok := value["Isp"].(map[string]interface{})
+	if !ok {
+		return settings, nil
+	}
+
+	if v, ok := isp["nr3d"].(float64); ok {
+		enabled := v == 1
+		settings.NoiseReduction = &enabled
+	}
+	if v, ok := isp["antiFlicker"].(string); ok {
+		for mode, apiValue := range antiFlickerModes {
+			if v == apiValue {
+				settings.AntiFlicker = mode
+			}
+		}
+	}
+	if v, ok := isp["rotation"].(float64); ok {
+		for degrees, apiValue := range rotations {
+			if int(v) == apiValue {
+				settings.Rotation = &degrees
+			}
+		}
+	}
+	if v, ok := isp["mirroring"].(float64); ok {
+		mirror := v == 1
+		settings.Mirror = &mirror
+	}
+	if v, ok := isp["binningMode"].(float64); ok {
+		for mode, apiValue := range binningModes {
+			if int(v) == apiValue {
+				settings.Binning = mode
+			}
+		}
+	}
+
+	return settings, nil
+}
+
+// SetImageSettings applies the non-empty ISP settings to a channel
+func (c *Client) SetImageSettings(ctx context.Context, channel int, settings ImageSettings) error {
+	isp := map[string]interface{}{"channel": channel}
+
+	if settings.NoiseReduction != nil {
+		nr3d := 0
+		if *settings.NoiseReduction {
+			nr3d = 1
+		}
+		isp["nr3d"] = nr3d
+	}
+	if settings.AntiFlicker != "" {
+		apiValue, ok := antiFlickerModes[settings.AntiFlicker]
+		if !ok {
+			return fmt.Errorf("invalid anti_flicker: %s (must be off, 50hz, 60hz, or outdoor)", settings.AntiFlicker)
+		}
+		isp["antiFlicker"] = apiValue
+	}
+	if settings.Rotation != nil {
+		apiValue, ok := rotations[*settings.Rotation]
+		if !ok {
+			return fmt.Errorf("invalid rotation: %d (must be 0, 90, 180, or 270)", *settings.Rotation)
+		}
+		isp["rotation"] = apiValue
+	}
+	if settings.Mirror != nil {
+		mirroring := 0
+		if *settings.Mirror {
+			mirroring = 1
+		}
+		isp["mirroring"] = mirroring
+	}
+	if settings.Binning != "" {
+		apiValue, ok := binningModes[settings.Binning]
+		if !ok {
+			return fmt.Errorf("invalid binning: %s (must be off, on, or auto)", settings.Binning)
+		}
+		isp["binningMode"] = apiValue
+	}
+
+	if len(isp) == 1 {
+		return fmt.Errorf("no image settings to apply")
+	}
+
+	_, err := c.execCommand(ctx, "SetIsp", map[string]interface{}{"Isp": isp})
+	return err
+}
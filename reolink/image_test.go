@@ -0,0 +1,100 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetImageSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:  "GetIsp",
+			Code: 0,
+			Value: map[string]interface{}{
+				"Isp": map[string]interface{}{
+					"channel":     float64(0),
+					"antiFlicker": "60HZ",
+					"nr3d":        float64(1),
+					"rotation":    float64(1),
+					"mirroring":   float64(0),
+					"binningMode": float64(2),
+				},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	settings, err := client.GetImageSettings(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("GetImageSettings failed: %v", err)
+	}
+	if settings.AntiFlicker != "60hz" {
+		t.Errorf("Expected anti_flicker '60hz', got '%s'", settings.AntiFlicker)
+	}
+	if settings.NoiseReduction == nil || !*settings.NoiseReduction {
+		t.Error("Expected noise reduction enabled")
+	}
+	if settings.Rotation == nil || *settings.Rotation != 180 {
+		t.Errorf("Expected rotation 180, got %v", settings.Rotation)
+	}
+	if settings.Mirror == nil || *settings.Mirror {
+		t.Error("Expected mirroring disabled")
+	}
+	if settings.Binning != "auto" {
+		t.Errorf("Expected binning 'auto', got '%s'", settings.Binning)
+	}
+}
+
+func TestClient_SetImageSettings(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetIsp", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	off, on, rotation := false, true, 90
+	err := client.SetImageSettings(context.Background(), 0, ImageSettings{NoiseReduction: &off, AntiFlicker: "50hz", Rotation: &rotation, Mirror: &on})
+	if err != nil {
+		t.Fatalf("SetImageSettings failed: %v", err)
+	}
+
+	isp, _ := received[0].Param["Isp"].(map[string]interface{})
+	if isp["antiFlicker"] != "50HZ" {
+		t.Errorf("Expected antiFlicker '50HZ', got %v", isp["antiFlicker"])
+	}
+	if isp["nr3d"] != float64(0) {
+		t.Errorf("Expected nr3d 0, got %v", isp["nr3d"])
+	}
+	if isp["rotation"] != float64(2) || isp["mirroring"] != float64(1) {
+		t.Errorf("Expected rotation 2 and mirroring 1, got %v and %v", isp["rotation"], isp["mirroring"])
+	}
+}
+
+func TestClient_SetImageSettings_Invalid(t *testing.T) {
+	client := NewClient("192.168.1.100", 80, "admin", "password")
+	ctx := context.Background()
+
+	if err := client.SetImageSettings(ctx, 0, ImageSettings{AntiFlicker: "45hz"}); err == nil {
+		t.Error("Expected error for invalid anti_flicker")
+	}
+	rotation := 45
+	if err := client.SetImageSettings(ctx, 0, ImageSettings{Rotation: &rotation}); err == nil {
+		t.Error("Expected error for invalid rotation")
+	}
+	if err := client.SetImageSettings(ctx, 0, ImageSettings{Binning: "4x4"}); err == nil {
+		t.Error("Expected error for invalid binning")
+	}
+	if err := client.SetImageSettings(ctx, 0, ImageSettings{}); err == nil {
+		t.Error("Expected error for empty settings")
+	}
+}
@@ -0,0 +1,205 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+)
+
+// White LED (spotlight/floodlight) modes as used by GetWhiteLed/SetWhiteLed
+var whiteLedModes = map[string]int{
+	"off":      0, // Never turns on automatically
+	"auto":     1, // Turns on at night when motion is detected
+	"schedule": 3, // Turns on at night within the lighting schedule
+}
+
+// LightSchedule is the daily window in which the white LED may turn on
+type LightSchedule struct {
+	Start string `json:"start"` // "HH:MM"
+	End   string `json:"end"`   // "HH:MM"
+}
+
+// WhiteLedConfig is the white LED state and automation settings of a channel
+type WhiteLedConfig struct {
+	State      bool            `json:"state"`
+	Mode       string          `json:"mode"`               // "off", "auto" or "schedule"
+	Brightness int             `json:"brightness"`         // Percent
+	Duration   int             `json:"duration,omitempty"` // Seconds the light stays on after motion
+	Schedule   LightSchedule   `json:"schedule"`
+	Ranges     *WhiteLedRanges `json:"ranges,omitempty"`
+}
+
+// WhiteLedRanges are the allowed brightness and duration values reported by the device
+type WhiteLedRanges struct {
+	BrightnessMin int `json:"brightness_min"`
+	BrightnessMax int `json:"brightness_max"`
+	DurationMin   int `json:"duration_min,omitempty"`
+	DurationMax   int `json:"duration_max,omitempty"`
+}
+
+// defaultWhiteLedRanges is used when the device does not report ranges
+var defaultWhiteLedRanges = WhiteLedRanges{BrightnessMin: 0, BrightnessMax: 100}
+
+// GetWhiteLed retrieves the white LED settings for a channel
+func (c *Client) GetWhiteLed(ctx context.Context, channel int) (*WhiteLedConfig, error) {
+	value, rng, err := c.execCommandRange(ctx, "GetWhiteLed", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := parseWhiteLedRanges(rng)
+	cfg := &WhiteLedConfig{Mode: "off", Ranges: &ranges}
+	data, ok := value["WhiteLed"].(map[string]interface{})
+	if !ok {
+		return cfg, nil
+	}
+
+	if v, ok := data["state"].(float64); ok {
+		cfg.State = v == 1
+	}
+	if v, ok := data["mode"].(float64); ok {
+		for name, mode := range whiteLedModes {
+			if int(v) == mode {
+				cfg.Mode = name
+			}
+		}
+	}
+	if v, ok := data["bright"].(float64); ok {
+		cfg.Brightness = int(v)
+	}
+	if v, ok := data["duration"].(float64); ok {
+		cfg.Duration = int(v)
+	}
+	if sched, ok := data["LightingSchedule"].(map[string]interface{}); ok {
+		startHour, _ := sched["StartHour"].(float64)
+		startMin, _ := sched["StartMin"].(float64)
+		endHour, _ := sched["EndHour"].(float64)
+		endMin, _ := sched["EndMin"].(float64)
+		cfg.Schedule = LightSchedule{
+			Start: fmt.Sprintf("%02d:%02d", int(startHour), int(startMin)),
+			End:   fmt.Sprintf("%02d:%02d", int(endHour), int(endMin)),
+		}
+	}
+
+	return cfg, nil
+}
+
+// SetWhiteLedState turns the white LED on or off manually
+func (c *Client) SetWhiteLedState(ctx context.Context, channel int, on bool) error {
+	state := 0
+	if on {
+		state = 1
+	}
+	return c.setWhiteLed(ctx, channel, map[string]interface{}{"state": state})
+}
+
+// SetWhiteLedSchedule sets the automation mode and, for "schedule" mode, the daily window
+func (c *Client) SetWhiteLedSchedule(ctx context.Context, channel int, mode string, schedule LightSchedule) error {
+	modeValue, ok := whiteLedModes[mode]
+	if !ok {
+		return fmt.Errorf("invalid light mode: %s (must be off, auto, or schedule)", mode)
+	}
+
+	settings := map[string]interface{}{"mode": modeValue}
+	if mode == "schedule" {
+		startHour, startMin, err := ParseClock(schedule.Start)
+		if err != nil {
+			return fmt.Errorf("invalid schedule start: %w", err)
+		}
+		endHour, endMin, err := ParseClock(schedule.End)
+		if err != nil {
+			return fmt.Errorf("invalid schedule end: %w", err)
+		}
+		settings["LightingSchedule"] = map[string]interface{}{
+			"StartHour": startHour,
+			"StartMin":  startMin,
+			"EndHour":   endHour,
+			"EndMin":    endMin,
+		}
+	}
+
+	return c.setWhiteLed(ctx, channel, settings)
+}
+
+// SetWhiteLedLevel sets the brightness (percent) and the motion-triggered on duration
+// (seconds). Zero values are left unchanged. Values are validated against the ranges
+// reported by the device.
+func (c *Client) SetWhiteLedLevel(ctx context.Context, channel, brightness, duration int) error {
+	if brightness == 0 && duration == 0 {
+		return fmt.Errorf("brightness or duration is required")
+	}
+
+	current, err := c.GetWhiteLed(ctx, channel)
+	if err != nil {
+		return err
+	}
+	ranges := defaultWhiteLedRanges
+	if current.Ranges != nil {
+		ranges = *current.Ranges
+	}
+
+	settings := map[string]interface{}{}
+	if brightness != 0 {
+		if brightness < ranges.BrightnessMin || brightness > ranges.BrightnessMax {
+			return fmt.Errorf("brightness %d out of range (%d-%d)", brightness, ranges.BrightnessMin, ranges.BrightnessMax)
+		}
+		settings["bright"] = brightness
+	}
+	if duration != 0 {
+		if ranges.DurationMax == 0 {
+			return fmt.Errorf("auto-off duration is not supported on this device")
+		}
+		if duration < ranges.DurationMin || duration > ranges.DurationMax {
+			return fmt.Errorf("duration %d out of range (%d-%d)", duration, ranges.DurationMin, ranges.DurationMax)
+		}
+		settings["duration"] = duration
+	}
+
+	return c.setWhiteLed(ctx, channel, settings)
+}
+
+func parseWhiteLedRanges(rng map[string]interface{}) WhiteLedRanges {
+	ranges := defaultWhiteLedRanges
+	data, ok := rng["WhiteLed"].(map[string]interface{})
+	if !ok {
+		return ranges
+	}
+	if bright, ok := data["bright"].(map[string]interface{}); ok {
+		if v, ok := bright["min"].(float64); ok {
+			ranges.BrightnessMin = int(v)
+		}
+		if v, ok := bright["max"].(float64); ok {
+			ranges.BrightnessMax = int(v)
+		}
+	}
+	if duration, ok := data["duration"].(map[string]interface{}); ok {
+		if v, ok := duration["min"].(float64); ok {
+			ranges.DurationMin = int(v)
+		}
+		if v, ok := duration["max"].(float64); ok {
+			ranges.DurationMax = int(v)
+		}
+	}
+	return ranges
+}
+
+func (c *Client) setWhiteLed(ctx context.Context, channel int, settings map[string]interface{}) error {
+	settings["channel"] = channel
+	_, err := c.execCommand(ctx, "SetWhiteLed", map[string]interface{}{
+		"WhiteLed": settings,
+	})
+	return err
+}
+
+// ParseClock parses a "HH:MM" time of day
+func ParseClock(s string) (int, int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil {
+		return 0, 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("time out of range: %q", s)
+	}
+	return hour, minute, nil
+}
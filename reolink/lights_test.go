@@ -0,0 +1,185 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		input   string
+		hour    int
+		minute  int
+		wantErr bool
+	}{
+		{"18:00", 18, 0, false},
+		{"06:30", 6, 30, false},
+		{"24:00", 0, 0, true},
+		{"noon", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		hour, minute, err := ParseClock(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseClock(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if hour != tt.hour || minute != tt.minute {
+			t.Errorf("ParseClock(%q) = %d:%d, expected %d:%d", tt.input, hour, minute, tt.hour, tt.minute)
+		}
+	}
+}
+
+func TestClient_GetWhiteLed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := []apiResponse{{
+			Cmd:  "GetWhiteLed",
+			Code: 0,
+			Value: map[string]interface{}{
+				"WhiteLed": map[string]interface{}{
+					"channel": float64(0),
+					"state":   float64(0),
+					"mode":    float64(3),
+					"bright":  float64(80),
+					"LightingSchedule": map[string]interface{}{
+						"StartHour": float64(18), "StartMin": float64(30),
+						"EndHour": float64(6), "EndMin": float64(0),
+					},
+				},
+			},
+		}}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	cfg, err := client.GetWhiteLed(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("GetWhiteLed failed: %v", err)
+	}
+	if cfg.Mode != "schedule" {
+		t.Errorf("Expected mode 'schedule', got '%s'", cfg.Mode)
+	}
+	if cfg.Brightness != 80 {
+		t.Errorf("Expected brightness 80, got %d", cfg.Brightness)
+	}
+	if cfg.Schedule.Start != "18:30" || cfg.Schedule.End != "06:00" {
+		t.Errorf("Unexpected schedule: %+v", cfg.Schedule)
+	}
+}
+
+func TestClient_SetWhiteLedSchedule(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetWhiteLed", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	err := client.SetWhiteLedSchedule(context.Background(), 1, "schedule", LightSchedule{Start: "19:00", End: "05:15"})
+	if err != nil {
+		t.Fatalf("SetWhiteLedSchedule failed: %v", err)
+	}
+
+	led, _ := received[0].Param["WhiteLed"].(map[string]interface{})
+	if led["mode"] != float64(3) {
+		t.Errorf("Expected mode 3, got %v", led["mode"])
+	}
+	if led["channel"] != float64(1) {
+		t.Errorf("Expected channel 1, got %v", led["channel"])
+	}
+	sched, _ := led["LightingSchedule"].(map[string]interface{})
+	if sched["StartHour"] != float64(19) || sched["EndMin"] != float64(15) {
+		t.Errorf("Unexpected schedule: %v", sched)
+	}
+}
+
+func TestClient_SetWhiteLedSchedule_InvalidMode(t *testing.T) {
+	client := NewClient("192.168.1.100", 80, "admin", "password")
+
+	if err := client.SetWhiteLedSchedule(context.Background(), 0, "disco", LightSchedule{}); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
+
+func TestParseWhiteLedRanges(t *testing.T) {
+	if got := parseWhiteLedRanges(nil); got != defaultWhiteLedRanges {
+		t.Errorf("Expected default ranges, got %+v", got)
+	}
+
+	rng := map[string]interface{}{
+		"WhiteLed": map[string]interface{}{
+			"bright":   map[string]interface{}{"min": float64(1), "max": float64(100)},
+			"duration": map[string]interface{}{"min": float64(10), "max": float64(300)},
+		},
+	}
+	expected := WhiteLedRanges{BrightnessMin: 1, BrightnessMax: 100, DurationMin: 10, DurationMax: 300}
+	if got := parseWhiteLedRanges(rng); got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestClient_SetWhiteLedLevel_ValidatesRange(t *testing.T) {
+	var setCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		if cmds[0].Cmd == "SetWhiteLed" {
+			setCalls++
+			_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetWhiteLed", Code: 0}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:   "GetWhiteLed",
+			Code:  0,
+			Value: map[string]interface{}{"WhiteLed": map[string]interface{}{"bright": float64(50)}},
+			Range: map[string]interface{}{
+				"WhiteLed": map[string]interface{}{
+					"bright":   map[string]interface{}{"min": float64(1), "max": float64(100)},
+					"duration": map[string]interface{}{"min": float64(10), "max": float64(300)},
+				},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+	ctx := context.Background()
+
+	if err := client.SetWhiteLedLevel(ctx, 0, 150, 0); err == nil {
+		t.Error("Expected error for brightness out of range")
+	}
+	if err := client.SetWhiteLedLevel(ctx, 0, 0, 5); err == nil {
+		t.Error("Expected error for duration out of range")
+	}
+	if err := client.SetWhiteLedLevel(ctx, 0, 75, 60); err != nil {
+		t.Errorf("Expected success, got %v", err)
+	}
+	if setCalls != 1 {
+		t.Errorf("Expected 1 SetWhiteLed call, got %d", setCalls)
+	}
+}
+
+func TestChannelAbility_Floodlight(t *testing.T) {
+	abilityData := map[string]interface{}{
+		"abilityChn": []interface{}{
+			map[string]interface{}{"floodLight": map[string]interface{}{"ver": float64(1)}},
+		},
+	}
+
+	if !abilitySupported(channelAbility(abilityData, 0), "floodLight") {
+		t.Error("Expected floodLight supported on channel 0")
+	}
+	if channelAbility(abilityData, 1) != nil {
+		t.Error("Expected nil ability for missing channel")
+	}
+}
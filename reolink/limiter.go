@@ -0,0 +1,124 @@
+package reolink
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMaxConcurrent is how many requests may be in flight to one device
+// unless overridden per device. Reolink firmware answers "device busy" when
+// hit with many parallel requests.
+const DefaultMaxConcurrent = 2
+
+// Priority orders requests waiting for a device slot
+type Priority int
+
+const (
+	// PriorityBulk is used for snapshots, event polling and metadata reads
+	PriorityBulk Priority = iota
+	// PriorityControl is used for live control such as PTZ, so it stays
+	// responsive while snapshots are queued
+	PriorityControl
+
+	// NumPriorities is the number of priority levels
+	NumPriorities
+)
+
+type priorityContextKey struct{}
+
+// WithPriority returns a context whose device requests are queued at priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// PriorityFromContext returns the request priority of ctx, bulk by default
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityContextKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityBulk
+}
+
+// requestLimiter bounds the number of requests in flight to a device.
+// Excess requests wait until a slot frees up; higher priority requests are
+// served first, and requests of equal priority in arrival order.
+type requestLimiter struct {
+	mu      sync.Mutex
+	max     int
+	active  int
+	waiters [NumPriorities][]chan struct{}
+}
+
+func newRequestLimiter(n int) *requestLimiter {
+	if n <= 0 {
+		n = DefaultMaxConcurrent
+	}
+	return &requestLimiter{max: n}
+}
+
+// acquire waits for a free slot or until ctx is done
+func (l *requestLimiter) acquire(ctx context.Context, priority Priority) error {
+	l.mu.Lock()
+	if l.active < l.max {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	l.waiters[priority] = append(l.waiters[priority], ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		queue := l.waiters[priority]
+		for i, ch := range queue {
+			if ch == ready {
+				l.waiters[priority] = append(queue[:i], queue[i+1:]...)
+				l.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		l.mu.Unlock()
+		// The slot was handed over just as ctx was canceled; pass it on
+		l.release()
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire, handing it to the next waiter
+func (l *requestLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for priority := NumPriorities - 1; priority >= 0; priority-- {
+		if queue := l.waiters[priority]; len(queue) > 0 {
+			l.waiters[priority] = queue[1:]
+			close(queue[0])
+			return
+		}
+	}
+	l.active--
+}
+
+// SetMaxConcurrent sets how many requests may be in flight to the device at once
+func (c *Client) SetMaxConcurrent(n int) {
+	c.mu.Lock()
+	c.limiter = newRequestLimiter(n)
+	c.mu.Unlock()
+}
+
+// acquireSlot waits for a request slot at the priority of ctx and returns the
+// function releasing it
+func (c *Client) acquireSlot(ctx context.Context) (func(), error) {
+	c.mu.RLock()
+	limiter := c.limiter
+	c.mu.RUnlock()
+
+	if err := limiter.acquire(ctx, PriorityFromContext(ctx)); err != nil {
+		return nil, err
+	}
+	return limiter.release, nil
+}
@@ -0,0 +1,122 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiter_AcquireCanceled(t *testing.T) {
+	l := newRequestLimiter(1)
+	if err := l.acquire(context.Background(), PriorityBulk); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, PriorityBulk); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded while the slot is held, got %v", err)
+	}
+
+	l.release()
+	if err := l.acquire(context.Background(), PriorityBulk); err != nil {
+		t.Errorf("Expected the slot to be free after release, got %v", err)
+	}
+}
+
+func TestClient_MaxConcurrent(t *testing.T) {
+	var inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "GetDevInfo", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+	client.SetMaxConcurrent(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.execCommand(context.Background(), "GetDevInfo", nil); err != nil {
+				t.Errorf("Request failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestRequestLimiter_ControlFirst(t *testing.T) {
+	l := newRequestLimiter(1)
+	if err := l.acquire(context.Background(), PriorityBulk); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	wait := func(name string, priority Priority) {
+		defer wg.Done()
+		if err := l.acquire(context.Background(), priority); err != nil {
+			t.Errorf("acquire failed: %v", err)
+			return
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		l.release()
+	}
+
+	// Queue two snapshots, then a PTZ command behind them
+	for i, name := range []string{"snap1", "snap2"} {
+		wg.Add(1)
+		go wait(name, PriorityBulk)
+		waitForWaiters(t, l, PriorityBulk, i+1)
+	}
+	wg.Add(1)
+	go wait("ptz", PriorityControl)
+	waitForWaiters(t, l, PriorityControl, 1)
+
+	l.release()
+	wg.Wait()
+
+	if len(order) != 3 || order[0] != "ptz" || order[1] != "snap1" || order[2] != "snap2" {
+		t.Errorf("Expected ptz before queued snapshots in arrival order, got %v", order)
+	}
+}
+
+// waitForWaiters blocks until n requests are queued at priority
+func waitForWaiters(t *testing.T, l *requestLimiter, priority Priority, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		queued := len(l.waiters[priority])
+		l.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d queued requests", n)
+}
@@ -0,0 +1,124 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// MaxMaskZones is the number of privacy zones a channel can hold
+const MaxMaskZones = 4
+
+// Reference screen for SetMask. The device scales zones from the screen size
+// sent with them, so any size works; this is the one GetMask reports.
+const (
+	maskScreenWidth  = 640
+	maskScreenHeight = 480
+)
+
+// MaskZone is a rectangular privacy zone in normalized image coordinates
+// (0..1, origin top-left)
+type MaskZone struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Validate checks that the zone is a non-empty rectangle within the image
+func (z MaskZone) Validate() error {
+	if z.Width <= 0 || z.Height <= 0 || z.X < 0 || z.Y < 0 || z.X+z.Width > 1 || z.Y+z.Height > 1 {
+		return fmt.Errorf("zone must be a non-empty rectangle within 0..1")
+	}
+	return nil
+}
+
+// PrivacyMask is the privacy mask configuration of a channel
+type PrivacyMask struct {
+	Enabled bool       `json:"enabled"`
+	Zones   []MaskZone `json:"zones"`
+}
+
+// GetPrivacyMask retrieves the privacy zones of a channel. Unused zone slots,
+// which the device reports as empty rectangles, are left out.
+func (c *Client) GetPrivacyMask(ctx context.Context, channel int) (*PrivacyMask, error) {
+	value, err := c.execCommand(ctx, "GetMask", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mask := &PrivacyMask{Zones: []MaskZone{}}
+	data, ok := value["Mask"].(map[string]interface{})
+	if !ok {
+		return mask, nil
+	}
+
+	if v, ok := data["enable"].(float64); ok {
+		mask.Enabled = v == 1
+	}
+	areas, _ := data["area"].([]interface{})
+	for _, a := range areas {
+		area, _ := a.(map[string]interface{})
+		block, _ := area["block"].(map[string]interface{})
+		screen, _ := area["screen"].(map[string]interface{})
+		screenWidth, _ := screen["width"].(float64)
+		screenHeight, _ := screen["height"].(float64)
+		if screenWidth <= 0 || screenHeight <= 0 {
+			screenWidth, screenHeight = maskScreenWidth, maskScreenHeight
+		}
+
+		x, _ := block["x"].(float64)
+		y, _ := block["y"].(float64)
+		width, _ := block["width"].(float64)
+		height, _ := block["height"].(float64)
+		if width <= 0 || height <= 0 {
+			continue
+		}
+		mask.Zones = append(mask.Zones, MaskZone{
+			X:      x / screenWidth,
+			Y:      y / screenHeight,
+			Width:  width / screenWidth,
+			Height: height / screenHeight,
+		})
+	}
+
+	return mask, nil
+}
+
+// SetPrivacyMask replaces the privacy zones of a channel
+func (c *Client) SetPrivacyMask(ctx context.Context, channel int, mask PrivacyMask) error {
+	if len(mask.Zones) > MaxMaskZones {
+		return fmt.Errorf("too many privacy zones: %d (maximum %d)", len(mask.Zones), MaxMaskZones)
+	}
+
+	areas := make([]interface{}, 0, len(mask.Zones))
+	for i, zone := range mask.Zones {
+		if err := zone.Validate(); err != nil {
+			return fmt.Errorf("invalid privacy zone %d: %w", i, err)
+		}
+		areas = append(areas, map[string]interface{}{
+			"screen": map[string]interface{}{"width": maskScreenWidth, "height": maskScreenHeight},
+			"block": map[string]interface{}{
+				"x":      int(math.Round(zone.X * maskScreenWidth)),
+				"y":      int(math.Round(zone.Y * maskScreenHeight)),
+				"width":  int(math.Round(zone.Width * maskScreenWidth)),
+				"height": int(math.Round(zone.Height * maskScreenHeight)),
+			},
+		})
+	}
+
+	enable := 0
+	if mask.Enabled {
+		enable = 1
+	}
+	_, err := c.execCommand(ctx, "SetMask", map[string]interface{}{
+		"Mask": map[string]interface{}{
+			"channel": channel,
+			"enable":  enable,
+			"area":    areas,
+		},
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetPrivacyMask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		screen := map[string]interface{}{"width": float64(640), "height": float64(480)}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:  "GetMask",
+			Code: 0,
+			Value: map[string]interface{}{
+				"Mask": map[string]interface{}{
+					"channel": float64(2),
+					"enable":  float64(1),
+					"area": []interface{}{
+						map[string]interface{}{
+							"screen": screen,
+							"block":  map[string]interface{}{"x": float64(320), "y": float64(0), "width": float64(160), "height": float64(240)},
+						},
+						map[string]interface{}{
+							"screen": screen,
+							"block":  map[string]interface{}{"x": float64(0), "y": float64(0), "width": float64(0), "height": float64(0)},
+						},
+					},
+				},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	mask, err := client.GetPrivacyMask(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("GetPrivacyMask failed: %v", err)
+	}
+	if !mask.Enabled || len(mask.Zones) != 1 {
+		t.Fatalf("Expected one enabled zone, got %+v", mask)
+	}
+	if zone := mask.Zones[0]; zone != (MaskZone{X: 0.5, Y: 0, Width: 0.25, Height: 0.5}) {
+		t.Errorf("Unexpected zone: %+v", zone)
+	}
+}
+
+func TestClient_SetPrivacyMask(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetMask", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	mask := PrivacyMask{Enabled: true, Zones: []MaskZone{{X: 0.25, Y: 0.5, Width: 0.5, Height: 0.25}}}
+	if err := client.SetPrivacyMask(context.Background(), 1, mask); err != nil {
+		t.Fatalf("SetPrivacyMask failed: %v", err)
+	}
+
+	data, _ := received[0].Param["Mask"].(map[string]interface{})
+	if data["channel"] != float64(1) || data["enable"] != float64(1) {
+		t.Errorf("Unexpected mask params: %v", data)
+	}
+	areas, _ := data["area"].([]interface{})
+	if len(areas) != 1 {
+		t.Fatalf("Expected one area, got %v", data["area"])
+	}
+	block := areas[0].(map[string]interface{})["block"].(map[string]interface{})
+	if block["x"] != float64(160) || block["y"] != float64(240) || block["width"] != float64(320) || block["height"] != float64(120) {
+		t.Errorf("Unexpected block: %v", block)
+	}
+}
+
+func TestClient_SetPrivacyMask_Invalid(t *testing.T) {
+	client := NewClient("192.168.1.100", 80, "admin", "password")
+	ctx := context.Background()
+
+	if err := client.SetPrivacyMask(ctx, 0, PrivacyMask{Zones: []MaskZone{{X: 0.8, Width: 0.5, Height: 0.1}}}); err == nil {
+		t.Error("Expected error for a zone outside the image")
+	}
+	if err := client.SetPrivacyMask(ctx, 0, PrivacyMask{Zones: make([]MaskZone, MaxMaskZones+1)}); err == nil {
+		t.Error("Expected error for too many zones")
+	}
+}
@@ -0,0 +1,214 @@
+package reolink
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lens layouts of ModelInfo
+const (
+	LensSingle  = "single"
+	LensDual    = "dual"    // Duo: two sensors side by side
+	LensFisheye = "fisheye" // one 360° sensor
+)
+
+//go:embed models.json
+var embeddedModels []byte
+
+// ModelInfo is an entry of the model database. Every entry whose Match is
+// contained in a model name applies, in order, so later entries refine
+// earlier ones; unset fields leave what earlier entries said.
+type ModelInfo struct {
+	Match      string      `json:"match"`          // case-insensitive substring of the model name; "" matches every model
+	Type       string      `json:"type,omitempty"` // device type reported by probes, e.g. "camera" or "nvr"
+	NVR        *bool       `json:"nvr,omitempty"`  // records the channels of other cameras
+	Hub        *bool       `json:"hub,omitempty"`  // Home Hub serving paired battery cameras as channels
+	Doorbell   *bool       `json:"doorbell,omitempty"`
+	Battery    *bool       `json:"battery,omitempty"`
+	AI         *bool       `json:"ai,omitempty"`          // person/vehicle/animal detection
+	Lens       string      `json:"lens,omitempty"`        // "single", "dual" or "fisheye"
+	PTZSpeed   *SpeedRange `json:"ptz_speed,omitempty"`   // range of PtzCtrl speeds
+	RTSPPrefix string      `json:"rtsp_prefix,omitempty"` // e.g. "h264Preview"; chosen by firmware version if unset
+}
+
+// SpeedRange is the range of PTZ speeds a model accepts
+type SpeedRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// ModelCapabilities is what the model database knows about one model
+type ModelCapabilities struct {
+	Type       string     `json:"type"`
+	NVR        bool       `json:"nvr"`
+	Hub        bool       `json:"hub"`
+	Doorbell   bool       `json:"doorbell"`
+	Battery    bool       `json:"battery"`
+	AI         bool       `json:"ai"`
+	Lens       string     `json:"lens"`
+	PTZSpeed   SpeedRange `json:"ptz_speed"`
+	RTSPPrefix string     `json:"rtsp_prefix,omitempty"`
+}
+
+var (
+	modelsMu       sync.RWMutex
+	builtinModels  = mustParseModels(embeddedModels)
+	modelOverrides []ModelInfo
+)
+
+// mustParseModels parses the embedded database, which is checked by tests
+func mustParseModels(data []byte) []ModelInfo {
+	var entries []ModelInfo
+	if err := json.Unmarshal(data, &entries); err != nil {
+		panic("reolink: invalid embedded model database: " + err.Error())
+	}
+	return entries
+}
+
+// SetModelOverrides adds entries for models the embedded database does not
+// know or gets wrong. They apply after the embedded entries and replace the
+// overrides of an earlier call.
+func SetModelOverrides(entries []ModelInfo) error {
+	for i, e := range entries {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("model override %d: %w", i, err)
+		}
+	}
+	modelsMu.Lock()
+	modelOverrides = append([]ModelInfo(nil), entries...)
+	modelsMu.Unlock()
+	return nil
+}
+
+// Validate checks the fields of an entry that have a fixed set of values
+func (e ModelInfo) Validate() error {
+	if strings.TrimSpace(e.Match) == "" {
+		return fmt.Errorf("match is required")
+	}
+	switch e.Lens {
+	case "", LensSingle, LensDual, LensFisheye:
+	default:
+		return fmt.Errorf("invalid lens: %s (must be single, dual, or fisheye)", e.Lens)
+	}
+	if r := e.PTZSpeed; r != nil && (r.Min < 1 || r.Max < r.Min) {
+		return fmt.Errorf("invalid ptz_speed: %d-%d", r.Min, r.Max)
+	}
+	return nil
+}
+
+// LookupModel returns what the model database, with its overrides, says
+// about a model name as reported by GetDevInfo
+func LookupModel(model string) ModelCapabilities {
+	caps, _ := lookupModel(model, false)
+	return caps
+}
+
+// ModelMatch is an entry of the model database that applies to a model
+type ModelMatch struct {
+	ModelInfo
+	Override bool `json:"override,omitempty"` // set with SetModelOverrides
+}
+
+// ExplainModel is LookupModel that also returns the entries that applied, in
+// order, to show why a model was classified the way it was
+func ExplainModel(model string) (ModelCapabilities, []ModelMatch) {
+	return lookupModel(model, true)
+}
+
+func lookupModel(model string, explain bool) (ModelCapabilities, []ModelMatch) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	var caps ModelCapabilities
+	var matches []ModelMatch
+	for i, entries := range [2][]ModelInfo{builtinModels, modelOverrides} {
+		for _, e := range entries {
+			if !ContainsFold(model, e.Match) {
+				continue
+			}
+			if explain {
+				matches = append(matches, ModelMatch{ModelInfo: e, Override: i == 1})
+			}
+			if e.Type != "" {
+				caps.Type = e.Type
+			}
+			setBool(&caps.NVR, e.NVR)
+			setBool(&caps.Hub, e.Hub)
+			setBool(&caps.Doorbell, e.Doorbell)
+			setBool(&caps.Battery, e.Battery)
+			setBool(&caps.AI, e.AI)
+			if e.Lens != "" {
+				caps.Lens = e.Lens
+			}
+			if e.PTZSpeed != nil {
+				caps.PTZSpeed = *e.PTZSpeed
+			}
+			if e.RTSPPrefix != "" {
+				caps.RTSPPrefix = e.RTSPPrefix
+			}
+		}
+	}
+	return caps, matches
+}
+
+// ContainsFold reports whether substr is within s under Unicode simple case
+// folding, as strings.EqualFold compares, without allocating
+func ContainsFold(s, substr string) bool {
+	for i := 0; ; {
+		if hasPrefixFold(s[i:], substr) {
+			return true
+		}
+		if i == len(s) {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+}
+
+// hasPrefixFold reports whether s begins with prefix under simple case folding
+func hasPrefixFold(s, prefix string) bool {
+	for prefix != "" {
+		if s == "" {
+			return false
+		}
+		r1, n1 := utf8.DecodeRuneInString(s)
+		r2, n2 := utf8.DecodeRuneInString(prefix)
+		if !equalFoldRune(r1, r2) {
+			return false
+		}
+		s, prefix = s[n1:], prefix[n2:]
+	}
+	return true
+}
+
+// equalFoldRune reports whether two runes are equal under simple case
+// folding, following the orbit of unicode.SimpleFold as strings.EqualFold does
+func equalFoldRune(r1, r2 rune) bool {
+	if r1 == r2 {
+		return true
+	}
+	if r2 < r1 {
+		r1, r2 = r2, r1
+	}
+	if r2 < utf8.RuneSelf {
+		// ASCII, where only letters differ in case
+		return 'A' <= r1 && r1 <= 'Z' && r2 == r1+'a'-'A'
+	}
+	r := unicode.SimpleFold(r1)
+	for r != r1 && r < r2 {
+		r = unicode.SimpleFold(r)
+	}
+	return r == r2
+}
+
+func setBool(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
@@ -0,0 +1,178 @@
+package reolink
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  ModelCapabilities
+	}{
+		{"RLC-810A", ModelCapabilities{Type: "camera", AI: true, Lens: LensSingle, PTZSpeed: SpeedRange{1, 64}}},
+		{"RLC-410", ModelCapabilities{Type: "camera", Lens: LensSingle, PTZSpeed: SpeedRange{1, 64}}},
+		{"Reolink Duo 2 PoE", ModelCapabilities{Type: "floodlight_camera", AI: true, Lens: LensDual, PTZSpeed: SpeedRange{1, 64}}},
+		{"FE-P", ModelCapabilities{Type: "camera", AI: true, Lens: LensFisheye, PTZSpeed: SpeedRange{1, 64}}},
+		{"Argus 3 Pro", ModelCapabilities{Type: "battery_camera", Battery: true, AI: true, Lens: LensSingle, PTZSpeed: SpeedRange{1, 64}}},
+		{"RLN8-410", ModelCapabilities{Type: "nvr", NVR: true, AI: true, Lens: LensSingle, PTZSpeed: SpeedRange{1, 64}}},
+		{"Reolink Home Hub", ModelCapabilities{Type: "hub", Hub: true, AI: true, Lens: LensSingle, PTZSpeed: SpeedRange{1, 64}}},
+		{"Reolink Video Doorbell PoE", ModelCapabilities{Type: "doorbell", Doorbell: true, AI: true, Lens: LensSingle, PTZSpeed: SpeedRange{1, 64}}},
+	}
+	for _, tt := range tests {
+		if got := LookupModel(tt.model); got != tt.want {
+			t.Errorf("LookupModel(%q) = %+v, want %+v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestSetModelOverrides(t *testing.T) {
+	defer SetModelOverrides(nil)
+
+	off := false
+	err := SetModelOverrides([]ModelInfo{
+		{Match: "RLC-410", Lens: LensFisheye},
+		{Match: "CX810", AI: &off, PTZSpeed: &SpeedRange{Min: 1, Max: 8}, RTSPPrefix: "Preview"},
+	})
+	if err != nil {
+		t.Fatalf("SetModelOverrides failed: %v", err)
+	}
+
+	// Overrides refine the embedded entries
+	if caps := LookupModel("RLC-410W"); caps.Lens != LensFisheye || caps.AI {
+		t.Errorf("Unexpected capabilities for RLC-410W: %+v", caps)
+	}
+	caps := LookupModel("CX810")
+	if caps.AI || caps.PTZSpeed != (SpeedRange{1, 8}) || caps.RTSPPrefix != "Preview" || caps.Type != "camera" {
+		t.Errorf("Unexpected capabilities for CX810: %+v", caps)
+	}
+
+	for _, bad := range []ModelInfo{{Lens: LensDual}, {Match: "x", Lens: "triple"}, {Match: "x", PTZSpeed: &SpeedRange{Min: 5, Max: 2}}} {
+		if err := SetModelOverrides([]ModelInfo{bad}); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+	if LookupModel("CX810").RTSPPrefix != "Preview" {
+		t.Error("A rejected call should keep the previous overrides")
+	}
+}
+
+func TestExplainModel(t *testing.T) {
+	defer SetModelOverrides(nil)
+	if err := SetModelOverrides([]ModelInfo{{Match: "rln8", Lens: LensFisheye}}); err != nil {
+		t.Fatal(err)
+	}
+
+	caps, matches := ExplainModel("RLN8-410")
+	if caps != LookupModel("RLN8-410") {
+		t.Errorf("ExplainModel and LookupModel disagree: %+v", caps)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Match)
+	}
+	if strings.Join(got, ",") != ",rln,rln8" || matches[0].Override || !matches[2].Override {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+}
+
+func TestContainsFold(t *testing.T) {
+	tests := []struct {
+		s, substr string
+		want      bool
+	}{
+		{"RLC-810A", "rlc-8", true},
+		{"RLC-810A", "810a", true},
+		{"RLC-810A", "", true},
+		{"", "", true},
+		{"", "a", false},
+		{"RLC", "rlc-", false},
+		{"Reolink Home Hub", "home hub", true},
+		{"Reolink Home Hub", "home  hub", false},
+		{"E1 Zoom", "e1 zoom", true},
+		{"RLC-810A", "rlc-810b", false},
+		{"abcab", "CAB", true},
+		{"[", "{", false}, // differ by the case bit but are not letters
+		{"@", "`", false},
+		{"Überwachung", "üBER", true},
+		{"\u212a-series", "k-", true}, // Kelvin sign folds to K
+		{"ΣΊΣΥΦΟΣ", "σίσυφος", true},
+		{"\xffgo", "GO", true},
+	}
+	for _, tt := range tests {
+		if got := ContainsFold(tt.s, tt.substr); got != tt.want {
+			t.Errorf("ContainsFold(%q, %q) = %v, want %v", tt.s, tt.substr, got, tt.want)
+		}
+	}
+}
+
+func TestContainsFold_Allocs(t *testing.T) {
+	if n := testing.AllocsPerRun(100, func() { ContainsFold("Reolink Video Doorbell PoE", "DOORBELL") }); n != 0 {
+		t.Errorf("ContainsFold allocated %v times", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { LookupModel("Reolink Video Doorbell PoE") }); n != 0 {
+		t.Errorf("LookupModel allocated %v times", n)
+	}
+}
+
+// TestContainsIgnoreCase keeps the cases of the plugin's former
+// containsIgnoreCase, which ContainsFold replaced
+func TestContainsIgnoreCase(t *testing.T) {
+	tests := []struct {
+		s        string
+		substr   string
+		expected bool
+	}{
+		{"Hello World", "hello", true},
+		{"Hello World", "WORLD", true},
+		{"Hello World", "lo Wo", true},
+		{"Hello World", "xyz", false},
+		{"", "", true},
+		{"Hello", "", true},
+		{"", "hello", false},
+		{"A", "a", true},
+		{"a", "A", true},
+	}
+
+	for _, tt := range tests {
+		result := ContainsFold(tt.s, tt.substr)
+		if result != tt.expected {
+			t.Errorf("ContainsFold(%q, %q) = %v, expected %v",
+				tt.s, tt.substr, result, tt.expected)
+		}
+	}
+}
+
+// FuzzContainsFold checks ContainsFold against strings.EqualFold of every
+// substring of s
+func FuzzContainsFold(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"RLC-810A", "rlc-8"}, {"Reolink Home Hub", "HOME HUB"}, {"\u212a", "k"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφος"}, {"\xff\xfe", "\xff"}, {"\xaa", "\xbb"}, {"", ""}, {"a", "ab"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, s, substr string) {
+		// Invalid bytes decode as one U+FFFD each, as in strings.EqualFold
+		var bounds []int
+		for i := 0; i < len(s); {
+			bounds = append(bounds, i)
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		bounds = append(bounds, len(s))
+
+		want := false
+		for i, from := range bounds {
+			for _, to := range bounds[i:] {
+				if strings.EqualFold(s[from:to], substr) {
+					want = true
+				}
+			}
+		}
+		if got := ContainsFold(s, substr); got != want {
+			t.Errorf("ContainsFold(%q, %q) = %v, want %v", s, substr, got, want)
+		}
+	})
+}
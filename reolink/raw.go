@@ -0,0 +1,48 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RawCommand is a command sent to the device as given, for API features the
+// client does not wrap
+type RawCommand struct {
+	Cmd    string                 `json:"cmd"`
+	Action int                    `json:"action"`
+	Param  map[string]interface{} `json:"param"`
+}
+
+// Do sends raw commands in one request and returns the device's response to
+// each, as received. Commands the device rejects are reported in their
+// responses rather than as an error.
+func (c *Client) Do(ctx context.Context, commands []RawCommand) ([]json.RawMessage, error) {
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("no commands")
+	}
+	cmds := make([]apiCommand, len(commands))
+	for i, cmd := range commands {
+		if cmd.Cmd == "" {
+			return nil, fmt.Errorf("command %d has no cmd", i)
+		}
+		if cmd.Param == nil {
+			cmd.Param = map[string]interface{}{}
+		}
+		cmds[i] = apiCommand(cmd)
+	}
+
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(ctx, cmds, true)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]json.RawMessage, len(resp))
+	for i, r := range resp {
+		raw[i] = r.raw
+	}
+	return raw, nil
+}
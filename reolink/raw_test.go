@@ -0,0 +1,60 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Do(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_, _ = w.Write([]byte(`[{"cmd":"GetAutoFocus","code":0,"value":{"AutoFocus":{"channel":0,"disable":0}},"initial":{"AutoFocus":{"disable":1}}},` +
+			`{"cmd":"GetTime","code":1,"error":{"detail":"not support","rspCode":-9}}]`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	resp, err := client.Do(context.Background(), []RawCommand{
+		{Cmd: "GetAutoFocus", Action: 1, Param: map[string]interface{}{"channel": 0}},
+		{Cmd: "GetTime"},
+	})
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+
+	if len(received) != 2 || received[0].Action != 1 || received[0].Param["channel"] != float64(0) {
+		t.Errorf("Expected the commands to be sent as given, got %+v", received)
+	}
+	if received[1].Param == nil {
+		t.Error("Expected an empty param object for a command without params")
+	}
+
+	if len(resp) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(resp))
+	}
+	// Fields the client does not know are kept
+	var first map[string]interface{}
+	if err := json.Unmarshal(resp[0], &first); err != nil || first["initial"] == nil {
+		t.Errorf("Expected the response as received, got %s", resp[0])
+	}
+	var second apiResponse
+	if err := json.Unmarshal(resp[1], &second); err != nil || second.Error == nil || second.Error.RspCode != -9 {
+		t.Errorf("Expected the rejected command in its response, got %s", resp[1])
+	}
+}
+
+func TestClient_Do_Invalid(t *testing.T) {
+	client := NewClient("camera.invalid", 80, "admin", "password")
+	if _, err := client.Do(context.Background(), nil); err == nil {
+		t.Error("Expected an error without commands")
+	}
+	if _, err := client.Do(context.Background(), []RawCommand{{Action: 0}}); err == nil {
+		t.Error("Expected an error for a command without cmd")
+	}
+}
@@ -0,0 +1,140 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Recording is a file recorded on the device's storage
+type Recording struct {
+	Name   string    `json:"name"` // source for DownloadClip
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Size   int64     `json:"size,omitempty"`
+	Stream string    `json:"stream,omitempty"` // "main" or "sub"
+}
+
+// SearchRecordings lists the recordings of a channel's stream ("main" or
+// "sub") that overlap start to end, ordered by start time. The device keeps
+// recording times in its local time, so they are converted using its clock.
+func (c *Client) SearchRecordings(ctx context.Context, channel int, stream string, start, end time.Time) ([]Recording, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	if stream == "" {
+		stream = "main"
+	}
+
+	loc, err := c.deviceZone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches are answered per day, so longer ranges are split at midnight
+	var recordings []Recording
+	seen := make(map[string]bool)
+	for from := start.In(loc); from.Before(end); {
+		y, m, d := from.Date()
+		to := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		if to.After(end) {
+			to = end.In(loc)
+		}
+
+		value, err := c.execCommand(ctx, "Search", map[string]interface{}{
+			"Search": map[string]interface{}{
+				"channel":    channel,
+				"onlyStatus": 0,
+				"streamType": stream,
+				"StartTime":  deviceTime(from),
+				"EndTime":    deviceTime(to.Add(-time.Second)),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result, _ := value["SearchResult"].(map[string]interface{})
+		files, _ := result["File"].([]interface{})
+		for _, f := range files {
+			file, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			rec := Recording{Stream: stream}
+			rec.Name, _ = file["name"].(string)
+			rec.Start = parseDeviceTime(file["StartTime"], loc)
+			rec.End = parseDeviceTime(file["EndTime"], loc)
+			rec.Size = parseSize(file["size"])
+			if t, ok := file["type"].(string); ok && t != "" {
+				rec.Stream = t
+			}
+			if rec.Name == "" || seen[rec.Name] || rec.Start.IsZero() ||
+				!rec.End.After(start) || !rec.Start.Before(end) {
+				continue
+			}
+			seen[rec.Name] = true
+			recordings = append(recordings, rec)
+		}
+		from = to
+	}
+
+	sort.Slice(recordings, func(i, j int) bool { return recordings[i].Start.Before(recordings[j].Start) })
+	return recordings, nil
+}
+
+// deviceZone returns the device's current UTC offset as a location, derived
+// from its clock so that daylight saving time is included
+func (c *Client) deviceZone(ctx context.Context) (*time.Location, error) {
+	value, err := c.execCommand(ctx, "GetTime", nil)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	local := parseDeviceTime(value["Time"], time.UTC)
+	if local.IsZero() {
+		return nil, fmt.Errorf("device did not report its time")
+	}
+	// Offsets are whole quarter hours; the rest is clock drift
+	offset := local.Sub(now).Round(15 * time.Minute)
+	return time.FixedZone("device", int(offset.Seconds())), nil
+}
+
+// deviceTime formats t as a Reolink time object
+func deviceTime(t time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"year": t.Year(), "mon": int(t.Month()), "day": t.Day(),
+		"hour": t.Hour(), "min": t.Minute(), "sec": t.Second(),
+	}
+}
+
+// parseDeviceTime reads a Reolink time object in loc, or returns zero
+func parseDeviceTime(v interface{}, loc *time.Location) time.Time {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return time.Time{}
+	}
+	field := func(name string) int {
+		n, _ := obj[name].(float64)
+		return int(n)
+	}
+	if field("year") == 0 {
+		return time.Time{}
+	}
+	return time.Date(field("year"), time.Month(field("mon")), field("day"),
+		field("hour"), field("min"), field("sec"), 0, loc)
+}
+
+// parseSize reads a file size reported as a number or a string
+func parseSize(v interface{}) int64 {
+	switch size := v.(type) {
+	case float64:
+		return int64(size)
+	case string:
+		n, _ := strconv.ParseInt(size, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package reolink
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestClient_SearchRecordings(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*3600)
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", Location: zone})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	// Recordings on both sides of the device's midnight
+	now := time.Now().Truncate(time.Second)
+	midnight := time.Date(now.In(zone).Year(), now.In(zone).Month(), now.In(zone).Day(), 0, 0, 0, 0, zone)
+	before := reolinksim.Recording{Start: midnight.Add(-10 * time.Minute), End: midnight.Add(-9 * time.Minute), Name: "before.mp4"}
+	after := reolinksim.Recording{Start: midnight.Add(5 * time.Minute), End: midnight.Add(6 * time.Minute), Name: "after.mp4"}
+	outside := reolinksim.Recording{Start: midnight.Add(-3 * time.Hour), End: midnight.Add(-2 * time.Hour), Name: "outside.mp4"}
+	sim.AddRecording(after)
+	sim.AddRecording(before)
+	sim.AddRecording(outside)
+
+	client := NewClient(host, port, "admin", "secret")
+	recordings, err := client.SearchRecordings(context.Background(), 0, "", midnight.Add(-time.Hour), midnight.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SearchRecordings failed: %v", err)
+	}
+	if len(recordings) != 2 {
+		t.Fatalf("Expected 2 recordings, got %+v", recordings)
+	}
+	for i, want := range []reolinksim.Recording{before, after} {
+		got := recordings[i]
+		if got.Name != want.Name || !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.Stream != "main" {
+			t.Errorf("Recording %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if n := sim.CommandCount("Search"); n != 2 {
+		t.Errorf("Expected one search per day, got %d", n)
+	}
+
+	if _, err := client.SearchRecordings(context.Background(), 0, "", now, now); err == nil {
+		t.Error("Expected an error for an empty range")
+	}
+}
@@ -0,0 +1,100 @@
+package reolink
+
+import (
+	"context"
+)
+
+// Default stream ports, used until GetNetPort has been read from the device
+const (
+	defaultRTSPPort = 554
+	defaultRTMPPort = 1935
+)
+
+// NetPort holds the network service ports configured on a device
+type NetPort struct {
+	HTTP  int `json:"http"`
+	HTTPS int `json:"https"`
+	RTSP  int `json:"rtsp"`
+	RTMP  int `json:"rtmp"`
+	ONVIF int `json:"onvif"`
+	Media int `json:"media"` // Baichuan port used by the Reolink apps
+
+	// Ports of the TLS stream servers, 0 on devices without them
+	RTSPS int `json:"rtsps,omitempty"`
+	RTMPS int `json:"rtmps,omitempty"`
+}
+
+// GetNetPort retrieves the service ports configured on the device
+func (c *Client) GetNetPort(ctx context.Context) (*NetPort, error) {
+	value, err := c.execCommand(ctx, "GetNetPort", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	ports := &NetPort{}
+	if data, ok := value["NetPort"].(map[string]interface{}); ok {
+		ports.HTTP = intField(data, "httpPort")
+		ports.HTTPS = intField(data, "httpsPort")
+		ports.RTSP = intField(data, "rtspPort")
+		ports.RTMP = intField(data, "rtmpPort")
+		ports.ONVIF = intField(data, "onvifPort")
+		ports.Media = intField(data, "mediaPort")
+		if intField(data, "rtspsEnable") != 0 || data["rtspsEnable"] == nil {
+			ports.RTSPS = intField(data, "rtspsPort")
+		}
+		if intField(data, "rtmpsEnable") != 0 || data["rtmpsEnable"] == nil {
+			ports.RTMPS = intField(data, "rtmpsPort")
+		}
+	}
+
+	c.mu.Lock()
+	c.cachedNetPort = ports
+	c.mu.Unlock()
+
+	return ports, nil
+}
+
+// GetCachedNetPort returns the last fetched service ports without making an API call
+func (c *Client) GetCachedNetPort() *NetPort {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedNetPort
+}
+
+// rtspPort returns the device's RTSP port, falling back to the default
+func (c *Client) rtspPort() int {
+	if ports := c.GetCachedNetPort(); ports != nil && ports.RTSP > 0 {
+		return ports.RTSP
+	}
+	return defaultRTSPPort
+}
+
+// rtmpPort returns the device's RTMP port, falling back to the default
+func (c *Client) rtmpPort() int {
+	if ports := c.GetCachedNetPort(); ports != nil && ports.RTMP > 0 {
+		return ports.RTMP
+	}
+	return defaultRTMPPort
+}
+
+// intField returns a numeric JSON field as an int, or 0 if it is missing
+func intField(data map[string]interface{}, key string) int {
+	v, _ := data[key].(float64)
+	return int(v)
+}
+
+// SecureStreamPort returns the port of the TLS variant of a stream protocol,
+// "rtsp" or "rtmp", or 0 if the device does not advertise one in GetNetPort
+func (c *Client) SecureStreamPort(protocol string) int {
+	ports := c.GetCachedNetPort()
+	if ports == nil {
+		return 0
+	}
+	switch protocol {
+	case "rtsp", "rtsps":
+		return ports.RTSPS
+	case "rtmp", "rtmps":
+		return ports.RTMPS
+	}
+	return 0
+}
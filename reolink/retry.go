@@ -0,0 +1,156 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how transient request failures are retried
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // Backoff before the first retry, doubled on each attempt
+	MaxDelay    time.Duration // Upper bound for a single backoff
+}
+
+// DefaultRetryPolicy is used by clients unless overridden per device
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// RetryConfig is the per-device retry configuration from the plugin config
+type RetryConfig struct {
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	BaseDelayMs int `json:"base_delay_ms,omitempty"`
+	MaxDelayMs  int `json:"max_delay_ms,omitempty"`
+}
+
+// Policy converts the config into a RetryPolicy, filling unset fields from the default
+func (rc *RetryConfig) Policy() RetryPolicy {
+	policy := DefaultRetryPolicy
+	if rc == nil {
+		return policy
+	}
+	if rc.MaxAttempts > 0 {
+		policy.MaxAttempts = rc.MaxAttempts
+	}
+	if rc.BaseDelayMs > 0 {
+		policy.BaseDelay = time.Duration(rc.BaseDelayMs) * time.Millisecond
+	}
+	if rc.MaxDelayMs > 0 {
+		policy.MaxDelay = time.Duration(rc.MaxDelayMs) * time.Millisecond
+	}
+	return policy
+}
+
+// backoff returns the delay before retry number n (starting at 0) using full jitter
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << uint(n)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// httpStatusError is returned when the camera answers with a non-200 status
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("API request failed: %s", e.Status)
+}
+
+// isTransientError reports whether a request error is worth retrying
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+// neverSent reports whether a request failed before it reached the device,
+// e.g. the connection was refused, so sending it again cannot repeat it
+func neverSent(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// readOnly reports whether every command of a request only reads settings
+// or state. A write that timed out may still have run, so only reads are
+// retried after any transient error; writes only when they were never sent.
+func readOnly(commands []apiCommand) bool {
+	for _, cmd := range commands {
+		if !strings.HasPrefix(cmd.Cmd, "Get") && !strings.HasPrefix(cmd.Cmd, "Search") {
+			return false
+		}
+	}
+	return len(commands) > 0
+}
+
+// withRetry runs fn until it succeeds, fails with an error retryable does
+// not accept, or attempts are exhausted
+func withRetry[T any](ctx context.Context, policy RetryPolicy, retryable func(error) bool, fn func() (T, error)) (T, error) {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var result T
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(policy.backoff(attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return result, err
+			case <-timer.C:
+			}
+		}
+
+		result, err = fn()
+		if err == nil || !retryable(err) {
+			return result, err
+		}
+	}
+	return result, err
+}
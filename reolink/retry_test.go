@@ -0,0 +1,150 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryConfig_Policy(t *testing.T) {
+	var nilCfg *RetryConfig
+	if nilCfg.Policy() != DefaultRetryPolicy {
+		t.Error("Nil config should return the default policy")
+	}
+
+	policy := (&RetryConfig{MaxAttempts: 5, BaseDelayMs: 100}).Policy()
+	if policy.MaxAttempts != 5 {
+		t.Errorf("Expected 5 attempts, got %d", policy.MaxAttempts)
+	}
+	if policy.BaseDelay != 100*time.Millisecond {
+		t.Errorf("Expected 100ms base delay, got %v", policy.BaseDelay)
+	}
+	if policy.MaxDelay != DefaultRetryPolicy.MaxDelay {
+		t.Errorf("Expected default max delay, got %v", policy.MaxDelay)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	for n := 0; n < 10; n++ {
+		d := policy.backoff(n)
+		if d < 0 || d > policy.MaxDelay {
+			t.Errorf("backoff(%d) = %v, expected within [0, %v]", n, d, policy.MaxDelay)
+		}
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{syscall.ECONNREFUSED, true},
+		{&httpStatusError{StatusCode: 503, Status: "503 Service Unavailable"}, true},
+		{&httpStatusError{StatusCode: 401, Status: "401 Unauthorized"}, false},
+		{context.Canceled, false},
+		{errors.New("failed to parse response"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransientError(tt.err); got != tt.expected {
+			t.Errorf("isTransientError(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestClient_DoRequest_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "GetDevInfo", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	resp, err := client.doRequest(context.Background(), []apiCommand{{Cmd: "GetDevInfo"}}, false)
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if len(resp) != 1 {
+		t.Errorf("Expected 1 response, got %d", len(resp))
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestClient_DoRequest_NoRetryOnClientError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	if _, err := client.doRequest(context.Background(), []apiCommand{{Cmd: "GetDevInfo"}}, false); err == nil {
+		t.Fatal("Expected error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestClient_DoRequest_NoRetryOfSentWrites(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	// The device may have moved before answering 502, so the move is not repeated
+	if _, err := client.doRequest(context.Background(), []apiCommand{{Cmd: "PtzCtrl"}}, false); err == nil {
+		t.Fatal("Expected error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestNeverSent(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{syscall.ECONNREFUSED, true},
+		{&net.OpError{Op: "dial", Err: errors.New("i/o timeout")}, true},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, false},
+		{&httpStatusError{StatusCode: 503, Status: "503 Service Unavailable"}, false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		if got := neverSent(tt.err); got != tt.expected {
+			t.Errorf("neverSent(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+
+	if !readOnly([]apiCommand{{Cmd: "GetEnc"}, {Cmd: "Search"}}) || readOnly([]apiCommand{{Cmd: "GetEnc"}, {Cmd: "SetEnc"}}) {
+		t.Error("Expected only requests of Get and Search commands to be read-only")
+	}
+}
@@ -0,0 +1,249 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Siren triggers mapped to the schedule tables of GetAudioAlarmV20/SetAudioAlarmV20
+var sirenTriggers = map[string]string{
+	"motion":  "MD",
+	"person":  "AI_PEOPLE",
+	"vehicle": "AI_VEHICLE",
+	"animal":  "AI_DOG_CAT",
+}
+
+// sirenTriggerOrder lists the triggers in the order they are reported
+var sirenTriggerOrder = []string{"motion", "person", "vehicle", "animal"}
+
+// Schedule days in the order of Reolink's weekly tables, which start on Sunday
+var scheduleDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// scheduleHours is the length of a weekly schedule table: one flag per hour
+const scheduleHours = 7 * 24
+
+// ScheduleWindow is a daily time window, with hour resolution
+type ScheduleWindow struct {
+	Days  []string `json:"days,omitempty"` // "sun".."sat"; every day if empty
+	Start string   `json:"start"`          // "HH:00"
+	End   string   `json:"end"`            // "HH:00" or "24:00"; before Start to cross midnight
+}
+
+// SirenConfig is when a channel's siren (audio alarm) sounds on its own
+type SirenConfig struct {
+	Enabled  bool             `json:"enabled"`
+	Triggers []string         `json:"triggers"`           // "motion", "person", "vehicle" and/or "animal"
+	Schedule []ScheduleWindow `json:"schedule,omitempty"` // always armed if empty
+}
+
+// GetSirenConfig retrieves the siren linkage of a channel. Firmware without
+// the V20 API only links the siren to motion.
+func (c *Client) GetSirenConfig(ctx context.Context, channel int) (*SirenConfig, error) {
+	value, err := c.execCommand(ctx, "GetAudioAlarmV20", map[string]interface{}{
+		"channel": channel,
+	})
+	if errors.Is(err, ErrNotSupported) {
+		return c.getLegacySirenConfig(ctx, channel)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &SirenConfig{Triggers: []string{}}
+	audio, _ := value["Audio"].(map[string]interface{})
+	if v, ok := audio["enable"].(float64); ok {
+		cfg.Enabled = v == 1
+	}
+	schedule, _ := audio["schedule"].(map[string]interface{})
+	tables, _ := schedule["table"].(map[string]interface{})
+
+	var armed string
+	for _, trigger := range sirenTriggerOrder {
+		table, _ := tables[sirenTriggers[trigger]].(string)
+		if strings.Contains(table, "1") {
+			cfg.Triggers = append(cfg.Triggers, trigger)
+			if armed == "" {
+				armed = table
+			}
+		}
+	}
+	cfg.Schedule = decodeSchedule(armed)
+	return cfg, nil
+}
+
+func (c *Client) getLegacySirenConfig(ctx context.Context, channel int) (*SirenConfig, error) {
+	value, err := c.execCommand(ctx, "GetAudioAlarm", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &SirenConfig{Triggers: []string{}}
+	audio, _ := value["Audio"].(map[string]interface{})
+	schedule, _ := audio["schedule"].(map[string]interface{})
+	if v, ok := schedule["enable"].(float64); ok {
+		cfg.Enabled = v == 1
+	}
+	table, _ := schedule["table"].(string)
+	if strings.Contains(table, "1") {
+		cfg.Triggers = append(cfg.Triggers, "motion")
+	}
+	cfg.Schedule = decodeSchedule(table)
+	return cfg, nil
+}
+
+// SetSirenConfig links the siren of a channel to the given triggers within
+// the schedule. Firmware without the V20 API only accepts the motion trigger.
+func (c *Client) SetSirenConfig(ctx context.Context, channel int, cfg SirenConfig) error {
+	armed, err := encodeSchedule(cfg.Schedule)
+	if err != nil {
+		return err
+	}
+	off := strings.Repeat("0", scheduleHours)
+
+	tables := make(map[string]interface{}, len(sirenTriggers))
+	for _, key := range sirenTriggers {
+		tables[key] = off
+	}
+	for _, trigger := range cfg.Triggers {
+		key, ok := sirenTriggers[trigger]
+		if !ok {
+			return fmt.Errorf("invalid siren trigger: %s (must be motion, person, vehicle, or animal)", trigger)
+		}
+		tables[key] = armed
+	}
+
+	enable := 0
+	if cfg.Enabled {
+		enable = 1
+	}
+	_, err = c.execCommand(ctx, "SetAudioAlarmV20", map[string]interface{}{
+		"Audio": map[string]interface{}{
+			"channel":  channel,
+			"enable":   enable,
+			"schedule": map[string]interface{}{"channel": channel, "table": tables},
+		},
+	})
+	if !errors.Is(err, ErrNotSupported) {
+		return err
+	}
+
+	// Older firmware has a single motion table
+	table := off
+	for _, trigger := range cfg.Triggers {
+		if trigger != "motion" {
+			return fmt.Errorf("firmware only links the siren to motion: %w", ErrNotSupported)
+		}
+		table = armed
+	}
+	_, err = c.execCommand(ctx, "SetAudioAlarm", map[string]interface{}{
+		"Audio": map[string]interface{}{
+			"schedule": map[string]interface{}{"channel": channel, "enable": enable, "table": table},
+		},
+	})
+	return err
+}
+
+// encodeSchedule converts schedule windows into a weekly table; no windows
+// means every hour
+func encodeSchedule(windows []ScheduleWindow) (string, error) {
+	if len(windows) == 0 {
+		return strings.Repeat("1", scheduleHours), nil
+	}
+
+	table := []byte(strings.Repeat("0", scheduleHours))
+	for _, w := range windows {
+		start, err := parseScheduleHour(w.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := parseScheduleHour(w.End)
+		if err != nil {
+			return "", err
+		}
+		if start == end || start == 24 {
+			return "", fmt.Errorf("empty schedule window %s-%s", w.Start, w.End)
+		}
+		if end < start {
+			end += 24 // crosses midnight into the next day
+		}
+
+		days := w.Days
+		if len(days) == 0 {
+			days = scheduleDays
+		}
+		for _, day := range days {
+			d := indexOf(scheduleDays, day)
+			if d < 0 {
+				return "", fmt.Errorf("invalid schedule day: %s (must be sun, mon, tue, wed, thu, fri, or sat)", day)
+			}
+			for h := start; h < end; h++ {
+				table[(d*24+h)%scheduleHours] = '1'
+			}
+		}
+	}
+	return string(table), nil
+}
+
+// parseScheduleHour parses "HH:00" into an hour from 0 to 24
+func parseScheduleHour(s string) (int, error) {
+	hour, minute, ok := strings.Cut(s, ":")
+	h, err := strconv.Atoi(hour)
+	if !ok || err != nil || minute != "00" || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid schedule time %q (must be a full hour, e.g. \"22:00\")", s)
+	}
+	return h, nil
+}
+
+// decodeSchedule converts a weekly table into windows, grouping days with the
+// same hours. An always-on or empty table has no windows.
+func decodeSchedule(table string) []ScheduleWindow {
+	if len(table) != scheduleHours || !strings.Contains(table, "0") || !strings.Contains(table, "1") {
+		return nil
+	}
+
+	var windows []ScheduleWindow
+	index := make(map[[2]int]int)
+	for d, day := range scheduleDays {
+		hours := table[d*24 : d*24+24]
+		for h := 0; h < 24; h++ {
+			if hours[h] != '1' {
+				continue
+			}
+			start := h
+			for h < 24 && hours[h] == '1' {
+				h++
+			}
+			key := [2]int{start, h}
+			if i, ok := index[key]; ok {
+				windows[i].Days = append(windows[i].Days, day)
+				continue
+			}
+			index[key] = len(windows)
+			windows = append(windows, ScheduleWindow{
+				Days:  []string{day},
+				Start: fmt.Sprintf("%02d:00", start),
+				End:   fmt.Sprintf("%02d:00", h),
+			})
+		}
+	}
+	for i := range windows {
+		if len(windows[i].Days) == len(scheduleDays) {
+			windows[i].Days = nil
+		}
+	}
+	return windows
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,145 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_SetSirenConfig(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetAudioAlarmV20", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	cfg := SirenConfig{
+		Enabled:  true,
+		Triggers: []string{"person"},
+		Schedule: []ScheduleWindow{{Days: []string{"sat"}, Start: "22:00", End: "06:00"}},
+	}
+	if err := client.SetSirenConfig(context.Background(), 0, cfg); err != nil {
+		t.Fatalf("SetSirenConfig failed: %v", err)
+	}
+
+	audio, _ := received[0].Param["Audio"].(map[string]interface{})
+	schedule, _ := audio["schedule"].(map[string]interface{})
+	tables, _ := schedule["table"].(map[string]interface{})
+	if audio["enable"] != float64(1) || len(tables) != 4 {
+		t.Fatalf("Unexpected SetAudioAlarmV20 params: %v", audio)
+	}
+	people, _ := tables["AI_PEOPLE"].(string)
+	if strings.Count(people, "1") != 8 || people[6*24+22] != '1' || people[5] != '1' || people[6] != '0' {
+		t.Errorf("Expected Saturday 22:00 to Sunday 06:00, got %s", people)
+	}
+	if md, _ := tables["MD"].(string); strings.Contains(md, "1") {
+		t.Errorf("Expected motion not to trigger the siren, got %s", md)
+	}
+}
+
+func TestClient_SetSirenConfig_Invalid(t *testing.T) {
+	client := NewClient("192.168.1.100", 80, "admin", "password")
+	ctx := context.Background()
+
+	tests := []SirenConfig{
+		{Triggers: []string{"doorbell"}},
+		{Triggers: []string{"person"}, Schedule: []ScheduleWindow{{Start: "22:30", End: "06:00"}}},
+		{Triggers: []string{"person"}, Schedule: []ScheduleWindow{{Start: "08:00", End: "08:00"}}},
+		{Triggers: []string{"person"}, Schedule: []ScheduleWindow{{Days: []string{"monday"}, Start: "08:00", End: "18:00"}}},
+	}
+	for _, cfg := range tests {
+		if err := client.SetSirenConfig(ctx, 0, cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestClient_GetSirenConfig(t *testing.T) {
+	workdays := []byte(strings.Repeat("0", scheduleHours))
+	for d := 1; d <= 5; d++ {
+		for h := 8; h < 18; h++ {
+			workdays[d*24+h] = '1'
+		}
+	}
+	off := strings.Repeat("0", scheduleHours)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:  "GetAudioAlarmV20",
+			Code: 0,
+			Value: map[string]interface{}{"Audio": map[string]interface{}{
+				"enable": float64(1),
+				"schedule": map[string]interface{}{"table": map[string]interface{}{
+					"MD": off, "AI_PEOPLE": string(workdays), "AI_VEHICLE": string(workdays), "AI_DOG_CAT": off,
+				}},
+			}},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	cfg, err := client.GetSirenConfig(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("GetSirenConfig failed: %v", err)
+	}
+	want := &SirenConfig{
+		Enabled:  true,
+		Triggers: []string{"person", "vehicle"},
+		Schedule: []ScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:00"}},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Expected %+v, got %+v", want, cfg)
+	}
+}
+
+func TestClient_SirenConfig_Legacy(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		resp := apiResponse{Cmd: received[0].Cmd, Code: 0}
+		switch received[0].Cmd {
+		case "GetAudioAlarmV20", "SetAudioAlarmV20":
+			resp.Code = 1
+			resp.Error = &apiErrorDetail{RspCode: -9, Detail: "not support"}
+		case "GetAudioAlarm":
+			resp.Value = map[string]interface{}{"Audio": map[string]interface{}{
+				"schedule": map[string]interface{}{"enable": float64(1), "table": strings.Repeat("1", scheduleHours)},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+	ctx := context.Background()
+
+	cfg, err := client.GetSirenConfig(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetSirenConfig failed: %v", err)
+	}
+	if !cfg.Enabled || !reflect.DeepEqual(cfg.Triggers, []string{"motion"}) || cfg.Schedule != nil {
+		t.Errorf("Unexpected legacy config: %+v", cfg)
+	}
+
+	if err := client.SetSirenConfig(ctx, 0, SirenConfig{Enabled: true, Triggers: []string{"person"}}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected AI triggers to be unsupported, got %v", err)
+	}
+	if err := client.SetSirenConfig(ctx, 0, SirenConfig{Enabled: true, Triggers: []string{"motion"}}); err != nil {
+		t.Fatalf("Legacy SetSirenConfig failed: %v", err)
+	}
+	if received[0].Cmd != "SetAudioAlarm" {
+		t.Errorf("Expected SetAudioAlarm, got %s", received[0].Cmd)
+	}
+}
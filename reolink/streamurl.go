@@ -0,0 +1,85 @@
+package reolink
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StreamTemplates replace the built-in stream URLs of a device, so firmware
+// with nonstandard paths works without a new release. Empty templates keep
+// the built-in URL. Templates may contain these placeholders:
+//
+//	{host}        device address
+//	{port}        stream port of the protocol (RTSP, or RTMP for rtmp and hls)
+//	{api_port}    HTTP(S) API port
+//	{channel}     channel number, from 0
+//	{channel_01}  channel number from 1, two digits, as in Preview_01_main
+//	{stream}      "main", "sub" or "ext"
+//	{user}        stream account, URL-escaped
+//	{password}    stream account password, URL-escaped
+//
+// e.g. "rtsp://{user}:{password}@{host}:{port}/Preview_{channel_01}_{stream}"
+type StreamTemplates struct {
+	RTSP string `json:"rtsp,omitempty"`
+	RTMP string `json:"rtmp,omitempty"`
+	HLS  string `json:"hls,omitempty"`
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var templateFields = map[string]bool{
+	"host": true, "port": true, "api_port": true, "channel": true,
+	"channel_01": true, "stream": true, "user": true, "password": true,
+}
+
+// Validate checks that every template only uses known placeholders and
+// expands to an absolute URL
+func (t StreamTemplates) Validate() error {
+	for _, tmpl := range []struct{ name, value string }{{"rtsp", t.RTSP}, {"rtmp", t.RTMP}, {"hls", t.HLS}} {
+		if tmpl.value == "" {
+			continue
+		}
+		for _, m := range templatePlaceholder.FindAllStringSubmatch(tmpl.value, -1) {
+			if !templateFields[m[1]] {
+				return fmt.Errorf("%s: unknown placeholder {%s}", tmpl.name, m[1])
+			}
+		}
+		example := templatePlaceholder.ReplaceAllString(tmpl.value, "0")
+		if u, err := url.Parse(example); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s: not an absolute URL: %s", tmpl.name, tmpl.value)
+		}
+	}
+	return nil
+}
+
+// SetStreamTemplates sets the templates of the device's stream URLs
+func (c *Client) SetStreamTemplates(t StreamTemplates) {
+	c.mu.Lock()
+	c.streamTemplates = t
+	c.mu.Unlock()
+}
+
+// StreamTemplates returns the templates of the device's stream URLs
+func (c *Client) StreamTemplates() StreamTemplates {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.streamTemplates
+}
+
+// expandStreamTemplate fills the placeholders of a stream URL template
+func (c *Client) expandStreamTemplate(tmpl string, port, channel int, stream string) string {
+	username, password := c.StreamCredentials()
+	return strings.NewReplacer(
+		"{host}", c.ActiveHost(),
+		"{port}", strconv.Itoa(port),
+		"{api_port}", strconv.Itoa(c.port),
+		"{channel}", strconv.Itoa(channel),
+		"{channel_01}", fmt.Sprintf("%02d", channel+1),
+		"{stream}", stream,
+		"{user}", url.QueryEscape(username),
+		"{password}", url.QueryEscape(password),
+	).Replace(tmpl)
+}
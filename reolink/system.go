@@ -0,0 +1,227 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OSD positions mapped to the values used by GetOsd/SetOsd
+var osdPositions = map[string]string{
+	"upper_left":    "Upper Left",
+	"top_center":    "Top Center",
+	"upper_right":   "Upper Right",
+	"lower_left":    "Lower Left",
+	"bottom_center": "Bottom Center",
+	"lower_right":   "Lower Right",
+}
+
+// NTPConfig is the time synchronization of a device
+type NTPConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Server   string `json:"server,omitempty"`   // e.g. "pool.ntp.org"
+	Port     int    `json:"port,omitempty"`     // 123 if zero
+	Interval int    `json:"interval,omitempty"` // minutes between syncs; 1440 if zero
+}
+
+// OSDSettings are the on-screen display overlays of a channel.
+// Nil/empty fields are left unchanged.
+type OSDSettings struct {
+	ShowName     *bool  `json:"show_name,omitempty"`
+	NamePosition string `json:"name_position,omitempty"` // e.g. "lower_right"
+	ShowTime     *bool  `json:"show_time,omitempty"`
+	TimePosition string `json:"time_position,omitempty"` // e.g. "top_center"
+}
+
+// SetNTP configures time synchronization on the device
+func (c *Client) SetNTP(ctx context.Context, cfg NTPConfig) error {
+	if cfg.Enabled && cfg.Server == "" {
+		return fmt.Errorf("NTP server is required")
+	}
+	ntp := map[string]interface{}{"enable": boolInt(cfg.Enabled)}
+	if cfg.Server != "" {
+		port, interval := cfg.Port, cfg.Interval
+		if port == 0 {
+			port = 123
+		}
+		if interval == 0 {
+			interval = 1440
+		}
+		ntp["server"], ntp["port"], ntp["interval"] = cfg.Server, port, interval
+	}
+
+	_, err := c.execCommand(ctx, "SetNtp", map[string]interface{}{"Ntp": ntp})
+	return err
+}
+
+// SetTimezone sets the device's time zone, including the daylight saving
+// rules of the current year, from an IANA location such as "Europe/Berlin".
+// The current time settings are read and written back with the zone changed.
+func (c *Client) SetTimezone(ctx context.Context, loc *time.Location) error {
+	value, err := c.execCommand(ctx, "GetTime", nil)
+	if err != nil {
+		return err
+	}
+
+	timeZone, dst := timezoneSettings(loc, time.Now().In(loc).Year())
+	settings, ok := value["Time"].(map[string]interface{})
+	if !ok {
+		settings = map[string]interface{}{}
+	}
+	settings["timeZone"] = timeZone
+	current, ok := value["Dst"].(map[string]interface{})
+	if !ok {
+		current = map[string]interface{}{}
+	}
+	for k, v := range dst {
+		current[k] = v
+	}
+
+	_, err = c.execCommand(ctx, "SetTime", map[string]interface{}{
+		"Time": settings,
+		"Dst":  current,
+	})
+	return err
+}
+
+// timezoneSettings returns Reolink's timeZone, the standard time offset in
+// seconds west of UTC, and the Dst fields for loc in the given year. DST
+// rules are given as the nth (5 = last) weekday of a month, with the start
+// in standard time and the end in daylight saving time.
+func timezoneSettings(loc *time.Location, year int) (int, map[string]interface{}) {
+	type transition struct {
+		at     time.Time
+		before int // UTC offset in seconds before the transition
+		after  int
+	}
+	var transitions []transition
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	for t.Year() == year {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.Year() != year {
+			break
+		}
+		_, before := t.Zone()
+		_, after := end.Zone()
+		transitions = append(transitions, transition{at: end, before: before, after: after})
+		t = end
+	}
+
+	_, offset := t.Zone()
+	if len(transitions) != 2 {
+		return -offset, map[string]interface{}{"enable": 0}
+	}
+
+	start, end := transitions[0], transitions[1]
+	if start.after < start.before {
+		start, end = end, start
+	}
+	dst := map[string]interface{}{
+		"enable": 1,
+		"offset": (start.after - start.before + 1800) / 3600,
+	}
+	setRule := func(prefix string, tr transition) {
+		wall := tr.at.In(time.FixedZone("", tr.before))
+		week := (wall.Day()-1)/7 + 1
+		if wall.AddDate(0, 0, 7).Month() != wall.Month() {
+			week = 5
+		}
+		dst[prefix+"Mon"] = int(wall.Month())
+		dst[prefix+"Week"] = week
+		dst[prefix+"Weekday"] = int(wall.Weekday())
+		dst[prefix+"Hour"] = wall.Hour()
+		dst[prefix+"Min"] = wall.Minute()
+		dst[prefix+"Sec"] = wall.Second()
+	}
+	setRule("start", start)
+	setRule("end", end)
+	return -start.before, dst
+}
+
+// SetOSD changes the name and time overlays of a channel. The current OSD
+// config is read and written back with the changes.
+func (c *Client) SetOSD(ctx context.Context, channel int, settings OSDSettings) error {
+	for _, pos := range []string{settings.NamePosition, settings.TimePosition} {
+		if _, ok := osdPositions[pos]; pos != "" && !ok {
+			return fmt.Errorf("invalid OSD position: %s (must be upper_left, top_center, upper_right, lower_left, bottom_center, or lower_right)", pos)
+		}
+	}
+
+	value, err := c.execCommand(ctx, "GetOsd", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return err
+	}
+
+	osd, ok := value["Osd"].(map[string]interface{})
+	if !ok {
+		osd = map[string]interface{}{}
+	}
+	apply := func(key string, show *bool, pos string) {
+		overlay, ok := osd[key].(map[string]interface{})
+		if !ok {
+			overlay = map[string]interface{}{}
+		}
+		if show != nil {
+			overlay["enable"] = boolInt(*show)
+		}
+		if pos != "" {
+			overlay["pos"] = osdPositions[pos]
+		}
+		osd[key] = overlay
+	}
+	apply("osdChannel", settings.ShowName, settings.NamePosition)
+	apply("osdTime", settings.ShowTime, settings.TimePosition)
+	osd["channel"] = channel
+
+	_, err = c.execCommand(ctx, "SetOsd", map[string]interface{}{"Osd": osd})
+	return err
+}
+
+// SetPushEnabled turns push notifications to the Reolink apps on or off.
+// Firmware without the V20 API switches the channel's push schedule instead.
+func (c *Client) SetPushEnabled(ctx context.Context, channel int, enabled bool) error {
+	return c.setAlarmAction(ctx, "Push", channel, enabled)
+}
+
+// SetEmailEnabled turns alarm emails on or off. Firmware without the V20
+// API switches the channel's email schedule instead.
+func (c *Client) SetEmailEnabled(ctx context.Context, channel int, enabled bool) error {
+	return c.setAlarmAction(ctx, "Email", channel, enabled)
+}
+
+// setAlarmAction switches an alarm action such as Push or Email with its
+// V20 command, falling back to the schedule of the legacy command
+func (c *Client) setAlarmAction(ctx context.Context, action string, channel int, enabled bool) error {
+	_, err := c.execCommand(ctx, "Set"+action+"V20", map[string]interface{}{
+		action: map[string]interface{}{"enable": boolInt(enabled)},
+	})
+	if !errors.Is(err, ErrNotSupported) {
+		return err
+	}
+
+	_, err = c.execCommand(ctx, "Set"+action, map[string]interface{}{
+		action: map[string]interface{}{
+			"schedule": map[string]interface{}{"channel": channel, "enable": boolInt(enabled)},
+		},
+	})
+	return err
+}
+
+// SetRTSPEnabled turns the device's RTSP server on or off
+func (c *Client) SetRTSPEnabled(ctx context.Context, enabled bool) error {
+	_, err := c.execCommand(ctx, "SetNetPort", map[string]interface{}{
+		"NetPort": map[string]interface{}{"rtspEnable": boolInt(enabled)},
+	})
+	return err
+}
+
+// boolInt returns the 0/1 flag the Reolink API uses for booleans
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimezoneSettings(t *testing.T) {
+	tests := []struct {
+		zone     string
+		timeZone int
+		dst      map[string]interface{}
+	}{
+		{"UTC", 0, map[string]interface{}{"enable": 0}},
+		{"Asia/Tokyo", -32400, map[string]interface{}{"enable": 0}},
+		{"Europe/Berlin", -3600, map[string]interface{}{
+			"enable": 1, "offset": 1,
+			"startMon": 3, "startWeek": 5, "startWeekday": 0, "startHour": 2, "startMin": 0, "startSec": 0,
+			"endMon": 10, "endWeek": 5, "endWeekday": 0, "endHour": 3, "endMin": 0, "endSec": 0,
+		}},
+		{"America/New_York", 18000, map[string]interface{}{
+			"enable": 1, "offset": 1,
+			"startMon": 3, "startWeek": 2, "startWeekday": 0, "startHour": 2, "startMin": 0, "startSec": 0,
+			"endMon": 11, "endWeek": 1, "endWeekday": 0, "endHour": 2, "endMin": 0, "endSec": 0,
+		}},
+		// Southern hemisphere: DST ends in April and starts in October
+		{"Australia/Sydney", -36000, map[string]interface{}{
+			"enable": 1, "offset": 1,
+			"startMon": 10, "startWeek": 1, "startWeekday": 0, "startHour": 2, "startMin": 0, "startSec": 0,
+			"endMon": 4, "endWeek": 1, "endWeekday": 0, "endHour": 3, "endMin": 0, "endSec": 0,
+		}},
+	}
+	for _, tt := range tests {
+		loc, err := time.LoadLocation(tt.zone)
+		if err != nil {
+			t.Fatalf("LoadLocation(%s) failed: %v", tt.zone, err)
+		}
+		timeZone, dst := timezoneSettings(loc, 2026)
+		if timeZone != tt.timeZone {
+			t.Errorf("%s: expected timeZone %d, got %d", tt.zone, tt.timeZone, timeZone)
+		}
+		for k, v := range tt.dst {
+			if dst[k] != v {
+				t.Errorf("%s: expected %s %v, got %v", tt.zone, k, v, dst[k])
+			}
+		}
+	}
+}
+
+func TestClient_SetOSD(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmds []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		received = append(received, cmds...)
+		resp := apiResponse{Cmd: cmds[0].Cmd, Code: 0}
+		if cmds[0].Cmd == "GetOsd" {
+			resp.Value = map[string]interface{}{"Osd": map[string]interface{}{
+				"bgcolor":    float64(0),
+				"osdChannel": map[string]interface{}{"enable": float64(1), "name": "Drive", "pos": "Lower Right"},
+				"osdTime":    map[string]interface{}{"enable": float64(1), "pos": "Top Center"},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	hide := false
+	if err := client.SetOSD(context.Background(), 0, OSDSettings{ShowName: &hide, TimePosition: "upper_left"}); err != nil {
+		t.Fatalf("SetOSD failed: %v", err)
+	}
+	osd, _ := received[1].Param["Osd"].(map[string]interface{})
+	name, _ := osd["osdChannel"].(map[string]interface{})
+	clock, _ := osd["osdTime"].(map[string]interface{})
+	if name["enable"] != float64(0) || name["name"] != "Drive" || clock["pos"] != "Upper Left" || osd["bgcolor"] != float64(0) {
+		t.Errorf("Unexpected SetOsd params: %v", osd)
+	}
+
+	if err := client.SetOSD(context.Background(), 0, OSDSettings{NamePosition: "middle"}); err == nil {
+		t.Error("Expected an error for an invalid position")
+	}
+}
+
+func TestClient_SetPushEnabled_Legacy(t *testing.T) {
+	var cmds []string
+	var legacy apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received []apiCommand
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		cmds = append(cmds, received[0].Cmd)
+		resp := apiResponse{Cmd: received[0].Cmd, Code: 0}
+		if received[0].Cmd == "SetPushV20" {
+			resp.Code = 1
+			resp.Error = &apiErrorDetail{RspCode: -9, Detail: "not support"}
+		} else {
+			legacy = received[0]
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{resp})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if err := client.SetPushEnabled(context.Background(), 2, false); err != nil {
+		t.Fatalf("SetPushEnabled failed: %v", err)
+	}
+	push, _ := legacy.Param["Push"].(map[string]interface{})
+	schedule, _ := push["schedule"].(map[string]interface{})
+	if len(cmds) != 2 || legacy.Cmd != "SetPush" || schedule["enable"] != float64(0) || schedule["channel"] != float64(2) {
+		t.Errorf("Expected a legacy SetPush after SetPushV20, got %v %v", cmds, legacy.Param)
+	}
+}
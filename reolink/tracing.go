@@ -0,0 +1,37 @@
+package reolink
+
+import (
+	"context"
+)
+
+// Span is a traced device request. The client sets the peer host as an
+// attribute and ends the span with the request's error.
+type Span interface {
+	SetAttribute(key, value string)
+	End(err error)
+}
+
+// SpanStarter starts a span for a device request made with ctx, returning
+// the context to make the request with
+type SpanStarter func(ctx context.Context, name string) (context.Context, Span)
+
+type spanStarterKey struct{}
+
+// WithSpanStarter returns a context whose device requests are traced with start
+func WithSpanStarter(ctx context.Context, start SpanStarter) context.Context {
+	return context.WithValue(ctx, spanStarterKey{}, start)
+}
+
+// noSpan is used when ctx carries no span starter
+type noSpan struct{}
+
+func (noSpan) SetAttribute(key, value string) {}
+func (noSpan) End(err error)                  {}
+
+// startSpan starts a span with the starter of ctx, if any
+func startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if start, ok := ctx.Value(spanStarterKey{}).(SpanStarter); ok {
+		return start(ctx, name)
+	}
+	return ctx, noSpan{}
+}
@@ -0,0 +1,59 @@
+package reolink
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// testSpan records what the client reports on a span
+type testSpan struct {
+	mu    sync.Mutex
+	name  string
+	attrs map[string]string
+	ended bool
+	err   error
+}
+
+func (s *testSpan) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *testSpan) End(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+	s.err = err
+}
+
+func TestWithSpanStarter(t *testing.T) {
+	server := newFakeDevice(t, map[string]interface{}{
+		"GetDevInfo": map[string]interface{}{"DevInfo": map[string]interface{}{"model": "RLC-810A"}},
+	})
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	var spans []*testSpan
+	ctx := WithSpanStarter(context.Background(), func(ctx context.Context, name string) (context.Context, Span) {
+		s := &testSpan{name: name, attrs: map[string]string{}}
+		spans = append(spans, s)
+		return ctx, s
+	})
+	if _, err := client.GetDeviceInfo(ctx); err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+
+	if len(spans) != 1 || spans[0].name != "reolink GetDevInfo" {
+		t.Fatalf("Expected one GetDevInfo span, got %+v", spans)
+	}
+	if !spans[0].ended || spans[0].err != nil || spans[0].attrs["net.peer.name"] != client.Host() {
+		t.Errorf("Unexpected span %+v", spans[0])
+	}
+
+	// Requests without a starter are not traced
+	if _, err := client.GetDeviceInfo(context.Background()); err != nil || len(spans) != 1 {
+		t.Errorf("Expected no span without a starter, got %d spans, %v", len(spans), err)
+	}
+}
@@ -0,0 +1,274 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+)
+
+// User levels supported by Reolink firmware
+const (
+	UserLevelAdmin = "admin"
+	UserLevelGuest = "guest"
+)
+
+// CameraUser is a user account configured on the camera
+type CameraUser struct {
+	Username string `json:"username"`
+	Level    string `json:"level"`
+}
+
+// GetUsers lists the user accounts configured on the device
+func (c *Client) GetUsers(ctx context.Context) ([]CameraUser, error) {
+	value, err := c.execCommand(ctx, "GetUser", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []CameraUser{}
+	list, ok := value["User"].([]interface{})
+	if !ok {
+		return users, nil
+	}
+
+	for _, item := range list {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		user := CameraUser{}
+		if v, ok := data["userName"].(string); ok {
+			user.Username = v
+		}
+		if v, ok := data["level"].(string); ok {
+			user.Level = v
+		}
+		if user.Username != "" {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// AddUser creates a new user account on the device
+func (c *Client) AddUser(ctx context.Context, username, password, level string) error {
+	if username == "" || password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+	if level == "" {
+		level = UserLevelGuest
+	}
+	if err := validateUserLevel(level); err != nil {
+		return err
+	}
+
+	_, err := c.execCommand(ctx, "AddUser", map[string]interface{}{
+		"User": map[string]interface{}{
+			"userName": username,
+			"password": password,
+			"level":    level,
+		},
+	})
+	return err
+}
+
+// ModifyUser changes the password and/or level of an existing user account.
+// Empty values are left unchanged.
+func (c *Client) ModifyUser(ctx context.Context, username, password, level string) error {
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if password == "" && level == "" {
+		return fmt.Errorf("nothing to modify: password or level is required")
+	}
+
+	user := map[string]interface{}{"userName": username}
+	if password != "" {
+		user["password"] = password
+	}
+	if level != "" {
+		if err := validateUserLevel(level); err != nil {
+			return err
+		}
+		user["level"] = level
+	}
+
+	_, err := c.execCommand(ctx, "ModifyUser", map[string]interface{}{"User": user})
+	if err != nil {
+		return err
+	}
+
+	// Keep our own credentials in sync so the next login still works
+	if username == c.username && password != "" {
+		c.mu.Lock()
+		c.password = password
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// RotatePassword changes the password of the account the client logs in
+// with and logs in again with it. check, if given, then confirms the device is
+// still usable, e.g. that its streams open. If the new login or check fails,
+// the old password is restored on the device and in the client.
+func (c *Client) RotatePassword(ctx context.Context, password string, check func(ctx context.Context) error) error {
+	if password == "" {
+		return fmt.Errorf("new password is required")
+	}
+	c.mu.RLock()
+	username, old := c.username, c.password
+	c.mu.RUnlock()
+	if password == old {
+		return fmt.Errorf("new password is the same as the current one")
+	}
+
+	if err := c.ModifyUser(ctx, username, password, ""); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	c.invalidateToken()
+	err := c.Login(ctx)
+	loggedIn := err == nil
+	if loggedIn && check != nil {
+		err = check(ctx)
+	}
+	if err == nil {
+		return nil
+	}
+
+	// Roll back through the new session if there is one. Without it the
+	// device may not have applied the change, so the old password is tried.
+	var rollbackErr error
+	if loggedIn {
+		rollbackErr = c.ModifyUser(ctx, username, old, "")
+	} else {
+		c.mu.Lock()
+		c.password = old
+		c.mu.Unlock()
+	}
+	if rollbackErr == nil {
+		c.invalidateToken()
+		rollbackErr = c.Login(ctx)
+	}
+	if rollbackErr != nil {
+		return fmt.Errorf("password rotation failed (%v) and the old password could not be restored: %w", err, rollbackErr)
+	}
+	return fmt.Errorf("password rotation failed, old password restored: %w", err)
+}
+
+// EnsureGuestUser creates a guest account on the device, or sets the
+// password of an existing one. An existing admin account is refused rather
+// than demoted.
+func (c *Client) EnsureGuestUser(ctx context.Context, username, password string) error {
+	if username == "" || password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+	if username == c.Username() {
+		return fmt.Errorf("user %s is the account the client logs in with", username)
+	}
+
+	users, err := c.GetUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.Username != username {
+			continue
+		}
+		if user.Level != UserLevelGuest {
+			return fmt.Errorf("user %s already exists with level %s", username, user.Level)
+		}
+		return c.ModifyUser(ctx, username, password, "")
+	}
+	return c.AddUser(ctx, username, password, UserLevelGuest)
+}
+
+// DeleteUser removes a user account from the device
+func (c *Client) DeleteUser(ctx context.Context, username string) error {
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if username == c.username {
+		return fmt.Errorf("cannot delete the account the plugin is logged in as")
+	}
+
+	_, err := c.execCommand(ctx, "DelUser", map[string]interface{}{
+		"User": map[string]interface{}{
+			"userName": username,
+		},
+	})
+	return err
+}
+
+func validateUserLevel(level string) error {
+	switch level {
+	case UserLevelAdmin, UserLevelGuest:
+		return nil
+	default:
+		return fmt.Errorf("invalid user level: %s (must be admin or guest)", level)
+	}
+}
+
+// Session is an active login session reported by GetOnline
+type Session struct {
+	SessionID     int    `json:"session_id"`
+	Username      string `json:"username"`
+	Level         string `json:"level"`
+	IP            string `json:"ip"`
+	CanDisconnect bool   `json:"can_disconnect"`
+}
+
+// GetSessions lists the sessions currently logged into the device
+func (c *Client) GetSessions(ctx context.Context) ([]Session, error) {
+	value, err := c.execCommand(ctx, "GetOnline", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	list, ok := value["User"].([]interface{})
+	if !ok {
+		return sessions, nil
+	}
+
+	for _, item := range list {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		session := Session{}
+		if v, ok := data["sessionId"].(float64); ok {
+			session.SessionID = int(v)
+		}
+		if v, ok := data["userName"].(string); ok {
+			session.Username = v
+		}
+		if v, ok := data["level"].(string); ok {
+			session.Level = v
+		}
+		if v, ok := data["ip"].(string); ok {
+			session.IP = v
+		}
+		if v, ok := data["canbeDisconn"].(float64); ok {
+			session.CanDisconnect = v == 1
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// DisconnectSession kicks a logged-in session off the device
+func (c *Client) DisconnectSession(ctx context.Context, username string, sessionID int) error {
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+
+	_, err := c.execCommand(ctx, "Disconnect", map[string]interface{}{
+		"User": map[string]interface{}{
+			"userName":  username,
+			"sessionId": sessionID,
+		},
+	})
+	return err
+}
@@ -0,0 +1,183 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_GetUsers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := []apiResponse{{
+			Cmd:  "GetUser",
+			Code: 0,
+			Value: map[string]interface{}{
+				"User": []interface{}{
+					map[string]interface{}{"userName": "admin", "level": "admin"},
+					map[string]interface{}{"userName": "nvr", "level": "guest"},
+				},
+			},
+		}}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	users, err := client.GetUsers(context.Background())
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+	if users[1].Username != "nvr" || users[1].Level != "guest" {
+		t.Errorf("Unexpected user: %+v", users[1])
+	}
+}
+
+func TestClient_AddUser_SendsCommand(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "AddUser", Code: 0}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	if err := client.AddUser(context.Background(), "nvr", "secret", ""); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if len(received) != 1 || received[0].Cmd != "AddUser" {
+		t.Fatalf("Unexpected command: %+v", received)
+	}
+	user, _ := received[0].Param["User"].(map[string]interface{})
+	if user["level"] != UserLevelGuest {
+		t.Errorf("Expected default level guest, got %v", user["level"])
+	}
+}
+
+func TestClient_UserValidation(t *testing.T) {
+	client := NewClient("192.168.1.100", 80, "admin", "password")
+	ctx := context.Background()
+
+	if err := client.AddUser(ctx, "", "secret", ""); err == nil {
+		t.Error("Expected error for missing username")
+	}
+	if err := client.AddUser(ctx, "nvr", "secret", "superuser"); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+	if err := client.ModifyUser(ctx, "nvr", "", ""); err == nil {
+		t.Error("Expected error when nothing to modify")
+	}
+	if err := client.DeleteUser(ctx, "admin"); err == nil {
+		t.Error("Expected error when deleting own account")
+	}
+}
+
+func TestClient_GetSessions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := []apiResponse{{
+			Cmd:  "GetOnline",
+			Code: 0,
+			Value: map[string]interface{}{
+				"User": []interface{}{
+					map[string]interface{}{
+						"canbeDisconn": float64(1),
+						"ip":           "192.168.1.50",
+						"level":        "admin",
+						"sessionId":    float64(1002),
+						"userName":     "admin",
+					},
+				},
+			},
+		}}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	sessions, err := client.GetSessions(context.Background())
+	if err != nil {
+		t.Fatalf("GetSessions failed: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d", len(sessions))
+	}
+
+	expected := Session{SessionID: 1002, Username: "admin", Level: "admin", IP: "192.168.1.50", CanDisconnect: true}
+	if sessions[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, sessions[0])
+	}
+}
+
+func TestClient_RotatePassword(t *testing.T) {
+	sim, client := newBackchannelDevice(t, false)
+	ctx := context.Background()
+	check := func(ctx context.Context) error { return client.CheckStream(ctx, 0) }
+
+	if err := client.CheckStream(ctx, 0); err != nil {
+		t.Fatalf("CheckStream failed: %v", err)
+	}
+	if err := client.RotatePassword(ctx, "n3w-secret", check); err != nil {
+		t.Fatalf("RotatePassword failed: %v", err)
+	}
+	if sim.Password() != "n3w-secret" {
+		t.Errorf("Expected the device password to change, got %q", sim.Password())
+	}
+	if _, err := client.GetEncoderConfig(ctx, 0); err != nil {
+		t.Errorf("Expected the client to work with the new password, got %v", err)
+	}
+
+	// A failed check restores the old password
+	broken := errors.New("stream broken")
+	err := client.RotatePassword(ctx, "other", func(context.Context) error { return broken })
+	if !errors.Is(err, broken) || !strings.Contains(err.Error(), "old password restored") {
+		t.Errorf("Expected a rolled back rotation, got %v", err)
+	}
+	if sim.Password() != "n3w-secret" {
+		t.Errorf("Expected the password to be rolled back, got %q", sim.Password())
+	}
+	if err := client.CheckStream(ctx, 0); err != nil {
+		t.Errorf("Expected streams to work after the rollback, got %v", err)
+	}
+
+	if err := client.RotatePassword(ctx, "", nil); err == nil {
+		t.Error("Expected an error for an empty password")
+	}
+}
+
+func TestClient_EnsureGuestUser(t *testing.T) {
+	sim, client := newBackchannelDevice(t, false)
+	ctx := context.Background()
+
+	if err := client.EnsureGuestUser(ctx, "viewer", "first"); err != nil {
+		t.Fatalf("EnsureGuestUser failed: %v", err)
+	}
+	if err := client.EnsureGuestUser(ctx, "viewer", "second"); err != nil {
+		t.Fatalf("EnsureGuestUser on an existing user failed: %v", err)
+	}
+	if password, ok := sim.GuestPassword("viewer"); !ok || password != "second" {
+		t.Errorf("Expected the guest password to be updated, got %q", password)
+	}
+	if err := client.EnsureGuestUser(ctx, "admin", "x"); err == nil {
+		t.Error("Expected the login account to be refused")
+	}
+
+	client.SetStreamCredentials("viewer", "second")
+	if url := client.RTSPStreamURL(0, "main"); !strings.HasPrefix(url, "rtsp://viewer:second@") {
+		t.Errorf("Expected the stream account in the URL, got %s", url)
+	}
+	if url := client.HLSStreamURL(0, "sub"); !strings.Contains(url, "user=viewer&password=second") {
+		t.Errorf("Expected the stream account in the URL, got %s", url)
+	}
+}
@@ -0,0 +1,92 @@
+package reolink
+
+import (
+	"context"
+	"fmt"
+)
+
+// WifiNetwork is a network found by ScanWifi
+type WifiNetwork struct {
+	SSID      string `json:"ssid"`
+	Signal    int    `json:"signal"`    // signal strength in percent
+	Encrypted bool   `json:"encrypted"` // needs a password
+}
+
+// WifiStatus is the network a WiFi device is set to join
+type WifiStatus struct {
+	SSID   string `json:"ssid"`
+	Signal int    `json:"signal,omitempty"` // signal strength in percent, 0 if unknown
+}
+
+// ScanWifi lists the networks the device sees. The scan takes some seconds
+// and the device stays connected meanwhile.
+func (c *Client) ScanWifi(ctx context.Context) ([]WifiNetwork, error) {
+	value, err := c.execCommand(ctx, "ScanWifi", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, _ := value["ScanWifi"].(map[string]interface{})
+	list, _ := scan["Wifi"].([]interface{})
+	networks := make([]WifiNetwork, 0, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ssid, _ := entry["ssid"].(string)
+		if ssid == "" {
+			continue // hidden network
+		}
+		signal, _ := entry["signal"].(float64)
+		encrypt, _ := entry["encrypt"].(float64)
+		networks = append(networks, WifiNetwork{SSID: ssid, Signal: int(signal), Encrypted: encrypt != 0})
+	}
+	return networks, nil
+}
+
+// GetWifi retrieves the network the device is set to join and, if the
+// firmware reports it, the current signal strength. The stored password is
+// not returned.
+func (c *Client) GetWifi(ctx context.Context) (*WifiStatus, error) {
+	value, err := c.execCommand(ctx, "GetWifi", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &WifiStatus{}
+	if wifi, ok := value["Wifi"].(map[string]interface{}); ok {
+		status.SSID, _ = wifi["ssid"].(string)
+	}
+	if value, err := c.execCommand(ctx, "GetWifiSignal", nil); err == nil {
+		if signal, ok := value["wifiSignal"].(float64); ok {
+			status.Signal = int(signal)
+		}
+	}
+	return status, nil
+}
+
+// TestWifi has the device try to join a network without switching to it,
+// returning an error if it cannot
+func (c *Client) TestWifi(ctx context.Context, ssid, password string) error {
+	if ssid == "" {
+		return fmt.Errorf("ssid is required")
+	}
+	_, err := c.execCommand(ctx, "TestWifi", map[string]interface{}{
+		"Wifi": map[string]interface{}{"ssid": ssid, "password": password},
+	})
+	return err
+}
+
+// SetWifi points the device at another network. A device connected over
+// WiFi drops off the current network right away, so the response may be
+// lost: a network error does not mean the change was refused.
+func (c *Client) SetWifi(ctx context.Context, ssid, password string) error {
+	if ssid == "" {
+		return fmt.Errorf("ssid is required")
+	}
+	_, err := c.execCommand(ctx, "SetWifi", map[string]interface{}{
+		"Wifi": map[string]interface{}{"ssid": ssid, "password": password},
+	})
+	return err
+}
@@ -0,0 +1,66 @@
+package reolink
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestClient_Wifi(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{
+		Password:     "secret",
+		WiFiNetworks: map[string]string{"Home": "home-pass", "Guest": ""},
+		WiFiSSID:     "Home",
+	})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	ctx := context.Background()
+
+	networks, err := client.ScanWifi(ctx)
+	if err != nil {
+		t.Fatalf("ScanWifi failed: %v", err)
+	}
+	want := []WifiNetwork{{SSID: "Guest", Signal: 80}, {SSID: "Home", Signal: 70, Encrypted: true}}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("ScanWifi = %+v, want %+v", networks, want)
+	}
+
+	status, err := client.GetWifi(ctx)
+	if err != nil || status.SSID != "Home" || status.Signal != 80 {
+		t.Errorf("Unexpected WiFi status %+v, %v", status, err)
+	}
+
+	if err := client.TestWifi(ctx, "Home", "wrong"); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected a failed test for a wrong password, got %v", err)
+	}
+	if err := client.TestWifi(ctx, "Guest", ""); err != nil {
+		t.Errorf("TestWifi failed: %v", err)
+	}
+	if err := client.SetWifi(ctx, "Guest", ""); err != nil {
+		t.Fatalf("SetWifi failed: %v", err)
+	}
+	if sim.WifiSSID() != "Guest" {
+		t.Errorf("Expected the device on Guest, got %s", sim.WifiSSID())
+	}
+	if err := client.SetWifi(ctx, "", ""); err == nil {
+		t.Error("Expected an error without an SSID")
+	}
+}
+
+func TestClient_Wifi_Wired(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Password: "secret"}))
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	client := NewClient(host, port, "admin", "secret")
+	if _, err := client.ScanWifi(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package reolink
+
+import (
+	"context"
+)
+
+// ZoomPosition is an optical zoom and focus motor position
+type ZoomPosition struct {
+	Zoom  int `json:"zoom"`
+	Focus int `json:"focus"`
+}
+
+// ZoomFocusRange is the travel of the zoom and focus motors
+type ZoomFocusRange struct {
+	ZoomMin  int `json:"zoom_min"`
+	ZoomMax  int `json:"zoom_max"`
+	FocusMin int `json:"focus_min"`
+	FocusMax int `json:"focus_max"`
+}
+
+// GetZoomFocus retrieves the current zoom and focus positions for a channel
+func (c *Client) GetZoomFocus(ctx context.Context, channel int) (*ZoomPosition, error) {
+	value, err := c.execCommand(ctx, "GetZoomFocus", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseZoomPosition(value), nil
+}
+
+// GetZoomFocusRange retrieves the current zoom and focus positions together
+// with the range of each motor
+func (c *Client) GetZoomFocusRange(ctx context.Context, channel int) (*ZoomPosition, *ZoomFocusRange, error) {
+	value, rng, err := c.execCommandRange(ctx, "GetZoomFocus", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ranges := &ZoomFocusRange{}
+	if data, ok := rng["ZoomFocus"].(map[string]interface{}); ok {
+		ranges.ZoomMin, ranges.ZoomMax = parsePosRange(data["zoom"])
+		ranges.FocusMin, ranges.FocusMax = parsePosRange(data["focus"])
+	}
+	return parseZoomPosition(value), ranges, nil
+}
+
+func parseZoomPosition(value map[string]interface{}) *ZoomPosition {
+	pos := &ZoomPosition{}
+	data, ok := value["ZoomFocus"].(map[string]interface{})
+	if !ok {
+		return pos
+	}
+	if zoom, ok := data["zoom"].(map[string]interface{}); ok {
+		if v, ok := zoom["pos"].(float64); ok {
+			pos.Zoom = int(v)
+		}
+	}
+	if focus, ok := data["focus"].(map[string]interface{}); ok {
+		if v, ok := focus["pos"].(float64); ok {
+			pos.Focus = int(v)
+		}
+	}
+	return pos
+}
+
+// parsePosRange reads a motor range, reported as {"pos": {"min": 0, "max": 33}}
+func parsePosRange(v interface{}) (lo, hi int) {
+	motor, _ := v.(map[string]interface{})
+	pos, _ := motor["pos"].(map[string]interface{})
+	if v, ok := pos["min"].(float64); ok {
+		lo = int(v)
+	}
+	if v, ok := pos["max"].(float64); ok {
+		hi = int(v)
+	}
+	return lo, hi
+}
+
+// SetZoomFocus moves the zoom motor and then the focus motor to the given positions
+func (c *Client) SetZoomFocus(ctx context.Context, channel int, pos ZoomPosition) error {
+	if err := c.startZoomFocus(ctx, channel, "ZoomPos", pos.Zoom); err != nil {
+		return err
+	}
+	return c.startZoomFocus(ctx, channel, "FocusPos", pos.Focus)
+}
+
+func (c *Client) startZoomFocus(ctx context.Context, channel int, op string, pos int) error {
+	_, err := c.execCommand(ctx, "StartZoomFocus", map[string]interface{}{
+		"ZoomFocus": map[string]interface{}{
+			"channel": channel,
+			"op":      op,
+			"pos":     pos,
+		},
+	})
+	return err
+}
+
+// GetAutoFocus reports whether autofocus is enabled for a channel
+func (c *Client) GetAutoFocus(ctx context.Context, channel int) (bool, error) {
+	value, err := c.execCommand(ctx, "GetAutoFocus", map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		return false, err
+	}
+	data, _ := value["AutoFocus"].(map[string]interface{})
+	disable, _ := data["disable"].(float64)
+	return disable == 0, nil
+}
+
+// SetAutoFocus enables or disables autofocus for a channel. Enabling it makes
+// the camera refocus right away.
+func (c *Client) SetAutoFocus(ctx context.Context, channel int, enabled bool) error {
+	disable := 1
+	if enabled {
+		disable = 0
+	}
+	_, err := c.execCommand(ctx, "SetAutoFocus", map[string]interface{}{
+		"AutoFocus": map[string]interface{}{
+			"channel": channel,
+			"disable": disable,
+		},
+	})
+	return err
+}
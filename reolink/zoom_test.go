@@ -0,0 +1,74 @@
+package reolink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetZoomFocusRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:  "GetZoomFocus",
+			Code: 0,
+			Value: map[string]interface{}{
+				"ZoomFocus": map[string]interface{}{
+					"zoom":  map[string]interface{}{"pos": float64(12)},
+					"focus": map[string]interface{}{"pos": float64(140)},
+				},
+			},
+			Range: map[string]interface{}{
+				"ZoomFocus": map[string]interface{}{
+					"zoom":  map[string]interface{}{"pos": map[string]interface{}{"min": float64(0), "max": float64(33)}},
+					"focus": map[string]interface{}{"pos": map[string]interface{}{"min": float64(0), "max": float64(248)}},
+				},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	pos, ranges, err := client.GetZoomFocusRange(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("GetZoomFocusRange failed: %v", err)
+	}
+	if *pos != (ZoomPosition{Zoom: 12, Focus: 140}) {
+		t.Errorf("Unexpected position: %+v", pos)
+	}
+	if *ranges != (ZoomFocusRange{ZoomMax: 33, FocusMax: 248}) {
+		t.Errorf("Unexpected ranges: %+v", ranges)
+	}
+}
+
+func TestClient_AutoFocus(t *testing.T) {
+	var received []apiCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:   received[0].Cmd,
+			Code:  0,
+			Value: map[string]interface{}{"AutoFocus": map[string]interface{}{"channel": float64(0), "disable": float64(1)}},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.useBasicAuth = true
+
+	enabled, err := client.GetAutoFocus(context.Background(), 0)
+	if err != nil || enabled {
+		t.Errorf("Expected autofocus disabled, got %v, %v", enabled, err)
+	}
+
+	if err := client.SetAutoFocus(context.Background(), 2, true); err != nil {
+		t.Fatalf("SetAutoFocus failed: %v", err)
+	}
+	af, _ := received[0].Param["AutoFocus"].(map[string]interface{})
+	if received[0].Cmd != "SetAutoFocus" || af["channel"] != float64(2) || af["disable"] != float64(0) {
+		t.Errorf("Unexpected SetAutoFocus request: %+v", received[0])
+	}
+}
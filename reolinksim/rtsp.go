@@ -0,0 +1,161 @@
+package reolinksim
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// RTSP digest challenge of the simulator
+const (
+	rtspRealm = "BC Streaming Media"
+	rtspNonce = "0123456789abcdef"
+)
+
+// ServeRTSP answers RTSP connections on l until it is closed. Only what the
+// audio backchannel needs is implemented: DESCRIBE, SETUP, PLAY and
+// TEARDOWN with digest auth, and RTP audio received interleaved.
+func (s *Server) ServeRTSP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveRTSPConn(conn)
+	}
+}
+
+// BackchannelAudio returns the RTP payloads received on the backchannel so far
+func (s *Server) BackchannelAudio() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.talk...)
+}
+
+func (s *Server) serveRTSPConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+		if b[0] == '$' {
+			if !s.readInterleaved(r) {
+				return
+			}
+			continue
+		}
+
+		tp := textproto.NewReader(r)
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		header, err := tp.ReadMIMEHeader()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return
+		}
+		method, uri := fields[0], fields[1]
+		s.count("RTSP " + method)
+
+		status, extra, body := s.handleRTSP(method, uri, header)
+		fmt.Fprintf(conn, "RTSP/1.0 %s\r\nCSeq: %s\r\n", status, header.Get("CSeq"))
+		for _, h := range extra {
+			fmt.Fprintf(conn, "%s\r\n", h)
+		}
+		if body != "" {
+			fmt.Fprintf(conn, "Content-Type: application/sdp\r\nContent-Length: %d\r\n", len(body))
+		}
+		fmt.Fprintf(conn, "\r\n%s", body)
+
+		if method == "TEARDOWN" {
+			return
+		}
+	}
+}
+
+// handleRTSP returns the status line, extra headers and body of a response
+func (s *Server) handleRTSP(method, uri string, header textproto.MIMEHeader) (string, []string, string) {
+	if !s.rtspAuthorized(method, header.Get("Authorization")) {
+		return "401 Unauthorized", []string{fmt.Sprintf(`WWW-Authenticate: Digest realm="%s", nonce="%s"`, rtspRealm, rtspNonce)}, ""
+	}
+
+	backchannel := s.cam.Backchannel && header.Get("Require") == "www.onvif.org/ver20/backchannel"
+	switch method {
+	case "DESCRIBE":
+		if header.Get("Require") != "" && !s.cam.Backchannel {
+			return "551 Option not supported", []string{"Unsupported: " + header.Get("Require")}, ""
+		}
+		sdp := "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=Session\r\nt=0 0\r\n" +
+			"m=video 0 RTP/AVP 96\r\na=control:trackID=1\r\na=rtpmap:96 H264/90000\r\na=recvonly\r\n" +
+			"m=audio 0 RTP/AVP 97\r\na=control:trackID=2\r\na=rtpmap:97 MPEG4-GENERIC/16000\r\na=recvonly\r\n"
+		if backchannel {
+			sdp += "m=audio 0 RTP/AVP 0\r\na=control:trackID=3\r\na=rtpmap:0 PCMU/8000\r\na=sendonly\r\n"
+		}
+		return "200 OK", []string{"Content-Base: " + strings.TrimSuffix(uri, "/") + "/"}, sdp
+	case "SETUP":
+		return "200 OK", []string{"Session: 12345678;timeout=60", "Transport: RTP/AVP/TCP;unicast;interleaved=0-1"}, ""
+	case "PLAY", "TEARDOWN", "OPTIONS":
+		return "200 OK", []string{"Session: 12345678"}, ""
+	}
+	return "405 Method Not Allowed", nil, ""
+}
+
+// rtspAuthorized checks a digest Authorization header without qop
+func (s *Server) rtspAuthorized(method, auth string) bool {
+	scheme, params, _ := strings.Cut(auth, " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return false
+	}
+	p := make(map[string]string)
+	for _, part := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		p[k] = strings.Trim(v, `"`)
+	}
+	password, ok := s.GuestPassword(p["username"])
+	if p["username"] == s.cam.Username {
+		password, ok = s.Password(), true
+	}
+	ha1 := md5Hex(p["username"] + ":" + rtspRealm + ":" + password)
+	ha2 := md5Hex(method + ":" + p["uri"])
+	return ok && p["nonce"] == rtspNonce && p["response"] == md5Hex(ha1+":"+rtspNonce+":"+ha2)
+}
+
+// readInterleaved reads one interleaved RTP packet, keeping the payload
+func (s *Server) readInterleaved(r *bufio.Reader) bool {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return false
+	}
+	pkt := make([]byte, binary.BigEndian.Uint16(hdr[2:]))
+	if _, err := io.ReadFull(r, pkt); err != nil {
+		return false
+	}
+	if hdr[1] == 0 && len(pkt) > 12 {
+		s.mu.Lock()
+		s.talk = append(s.talk, pkt[12:]...)
+		s.mu.Unlock()
+	}
+	return true
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,1056 @@
+// Package reolinksim emulates the HTTP API (api.cgi) of a Reolink camera or
+// NVR, so the plugin can be run end to end without hardware.
+//
+// A Server answers login, device info, encoder, ISP binning, privacy mask,
+// siren, ability, network, PTZ, snapshot, motion/AI state, clock and
+// recording search commands with the same JSON shapes as real firmware. Tests drive it through SetMotion and
+// SetAI and inspect the PTZ commands it received.
+package reolinksim
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reolink rspCode values returned by the simulator
+const (
+	rspParamError   = -4
+	rspLoginNeeded  = -6
+	rspLoginFailed  = -7
+	rspNotSupported = -9
+)
+
+// tokenLease is the lease time of session tokens, in seconds
+const tokenLease = 3600
+
+// AI detection kinds accepted by SetAI, as named in GetAiState
+const (
+	AIPerson  = "people"
+	AIVehicle = "vehicle"
+	AIAnimal  = "dog_cat"
+	AIFace    = "face"
+	AIPackage = "package"
+)
+
+var aiKinds = []string{AIPerson, AIVehicle, AIAnimal, AIFace, AIPackage}
+
+// Camera describes the simulated device
+type Camera struct {
+	Model           string
+	Name            string
+	Serial          string
+	FirmwareVersion string
+	HardwareVersion string
+	MAC             string
+	Channels        int // 1 for a camera, more for an NVR
+
+	Username string
+	Password string
+
+	PTZ bool // report PTZ ability and accept PtzCtrl
+	AI  bool // report AI state; GetAiState is rejected otherwise
+
+	// TokenOnly rejects credentials in the URL, like newer firmware, so
+	// clients must use the Login token API
+	TokenOnly bool
+	// LegacyEvents rejects GetEvents, like older firmware, so clients must
+	// poll GetMdState and GetAiState
+	LegacyEvents bool
+
+	// Snapshot is returned by Snap. A small generated JPEG is used if empty.
+	Snapshot []byte
+
+	// RTSPPort is reported by GetNetPort; 554 if zero. Point it at the
+	// listener given to ServeRTSP.
+	RTSPPort int
+	// Backchannel offers an ONVIF audio backchannel over RTSP
+	Backchannel bool
+
+	// ChannelModels are the models of the cameras on the channels of an NVR
+	// or Home Hub (e.g. Model "Reolink Home Hub"); an empty entry is a
+	// channel without a camera. Channels beyond the list report no model.
+	ChannelModels []string
+
+	// DoorbellChannels report the visitor abilities of a doorbell, whatever
+	// their model name
+	DoorbellChannels []int
+
+	// AlarmOutputs is the number of alarm output relays, as on NVRs
+	AlarmOutputs int
+	// AlarmInputs is the number of wired alarm inputs, e.g. for PIR sensors
+	AlarmInputs int
+
+	// WiFiNetworks makes a WiFi camera: the networks it sees, by SSID to
+	// password (empty for an open network). It starts joined to WiFiSSID
+	// and reports WiFi as its active link. Wired cameras reject the WiFi
+	// commands.
+	WiFiNetworks map[string]string
+	WiFiSSID     string
+
+	// APIVersion is reported as the apiVersion ability entry if non-zero
+	APIVersion int
+
+	// Binning makes a 12MP camera with pixel binning, like the RLC-1212A:
+	// its main stream is 4512x2512 at 20 fps or binned 2256x1256 at up to
+	// 30 fps, and GetIsp and SetIsp have a binningMode
+	Binning bool
+
+	// Location is the time zone of the device clock, UTC if nil. GetTime
+	// and recording searches use its local time.
+	Location *time.Location
+}
+
+// Recording is a file on the simulated device's storage, listed by Search
+type Recording struct {
+	Channel    int
+	Start, End time.Time
+	Name       string
+}
+
+// DefaultCamera is a single-channel PTZ camera with AI detection
+var DefaultCamera = Camera{
+	Model:           "RLC-823A",
+	Name:            "Simulated Camera",
+	Serial:          "SIM00000001",
+	FirmwareVersion: "v3.1.0.2368_23062700",
+	HardwareVersion: "IPC_523SD10",
+	MAC:             "ec:71:db:00:00:01",
+	Channels:        1,
+	Username:        "admin",
+	PTZ:             true,
+	AI:              true,
+}
+
+// PTZCommand is a PtzCtrl command received by the simulator
+type PTZCommand struct {
+	Channel int
+	Op      string
+	Speed   int
+	Preset  int
+}
+
+// channelState is the simulated alarm state of one channel
+type channelState struct {
+	motion bool
+	ai     map[string]bool
+}
+
+// Server is a simulated device. It implements http.Handler.
+type Server struct {
+	cam      Camera
+	snapshot []byte
+
+	mu       sync.Mutex
+	tokens   map[string]time.Time // token to expiry
+	channels []channelState
+	ptz      []PTZCommand
+	counts   map[string]int
+	talk     []byte            // backchannel audio received over RTSP
+	outputs  []bool            // alarm output relay states
+	inputs   []bool            // alarm input states
+	password string            // changed by ModifyUser
+	guests   map[string]string // accounts added by AddUser, to password
+	records  []Recording
+
+	// wired network settings, changed by SetLocalLink
+	linkType   string
+	linkStatic map[string]interface{}
+	linkDNS    map[string]interface{}
+
+	wifiSSID string // changed by SetWifi
+
+	// per-channel settings: encoder streams, privacy mask and siren linkage
+	encoders   []encoderState
+	binning    int
+	masks      map[int]interface{}
+	audioAlarm map[int]interface{}
+}
+
+// encoderState is the stream settings of a channel, changed by SetEnc
+type encoderState struct {
+	main, sub streamState
+}
+
+type streamState struct {
+	width, height, frameRate, bitRate int
+	codec                             string
+}
+
+// New returns a simulated device. Empty fields of cam are taken from
+// DefaultCamera; the feature flags are used as given.
+func New(cam Camera) *Server {
+	if cam.Model == "" {
+		cam.Model = DefaultCamera.Model
+	}
+	if cam.Name == "" {
+		cam.Name = DefaultCamera.Name
+	}
+	if cam.Serial == "" {
+		cam.Serial = DefaultCamera.Serial
+	}
+	if cam.FirmwareVersion == "" {
+		cam.FirmwareVersion = DefaultCamera.FirmwareVersion
+	}
+	if cam.HardwareVersion == "" {
+		cam.HardwareVersion = DefaultCamera.HardwareVersion
+	}
+	if cam.MAC == "" {
+		cam.MAC = DefaultCamera.MAC
+	}
+	if cam.Channels <= 0 {
+		cam.Channels = 1
+	}
+	if cam.Username == "" {
+		cam.Username = DefaultCamera.Username
+	}
+	if cam.RTSPPort == 0 {
+		cam.RTSPPort = 554
+	}
+	if cam.Location == nil {
+		cam.Location = time.UTC
+	}
+
+	s := &Server{
+		cam:      cam,
+		snapshot: cam.Snapshot,
+		tokens:   make(map[string]time.Time),
+		channels: make([]channelState, cam.Channels),
+		counts:   make(map[string]int),
+		outputs:  make([]bool, cam.AlarmOutputs),
+		inputs:   make([]bool, cam.AlarmInputs),
+		password: cam.Password,
+		guests:   make(map[string]string),
+
+		linkType:   "DHCP",
+		linkStatic: map[string]interface{}{"ip": "192.168.1.100", "mask": "255.255.255.0", "gateway": "192.168.1.1"},
+		linkDNS:    map[string]interface{}{"auto": 1, "dns1": "192.168.1.1", "dns2": "0.0.0.0"},
+		wifiSSID:   cam.WiFiSSID,
+
+		encoders:   make([]encoderState, cam.Channels),
+		masks:      make(map[int]interface{}),
+		audioAlarm: make(map[int]interface{}),
+	}
+	for i := range s.channels {
+		s.channels[i].ai = make(map[string]bool)
+		s.encoders[i] = encoderState{
+			main: streamState{3840, 2160, 25, 6144, "h265"},
+			sub:  streamState{640, 360, 15, 256, "h264"},
+		}
+		if cam.Binning {
+			s.encoders[i] = encoderState{
+				main: streamState{4512, 2512, 20, 4096, "h265"},
+				sub:  streamState{640, 352, 15, 256, "h264"},
+			}
+		}
+	}
+	if len(s.snapshot) == 0 {
+		s.snapshot = testJPEG()
+	}
+	return s
+}
+
+// SetMotion sets the motion alarm state of a channel
+func (s *Server) SetMotion(channel int, active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if channel >= 0 && channel < len(s.channels) {
+		s.channels[channel].motion = active
+	}
+}
+
+// SetAI sets the state of an AI detection kind, such as AIPerson, on a channel
+func (s *Server) SetAI(channel int, kind string, active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if channel >= 0 && channel < len(s.channels) {
+		s.channels[channel].ai[kind] = active
+	}
+}
+
+// PTZCommands returns the PtzCtrl commands received so far
+func (s *Server) PTZCommands() []PTZCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PTZCommand(nil), s.ptz...)
+}
+
+// AlarmOutput reports whether an alarm output relay is active
+func (s *Server) AlarmOutput(port int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return port >= 0 && port < len(s.outputs) && s.outputs[port]
+}
+
+// Password returns the current password of the simulated account
+func (s *Server) Password() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.password
+}
+
+// LocalLink returns the addressing mode ("DHCP" or "Static") and static
+// address last set with SetLocalLink
+func (s *Server) LocalLink() (mode, ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, _ = s.linkStatic["ip"].(string)
+	return s.linkType, ip
+}
+
+// WifiSSID returns the network the simulated WiFi camera is set to join
+func (s *Server) WifiSSID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wifiSSID
+}
+
+// GuestPassword returns the password of an account added with AddUser
+func (s *Server) GuestPassword(username string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	password, ok := s.guests[username]
+	return password, ok
+}
+
+// SetAlarmInput sets the state of a wired alarm input, as a sensor would
+func (s *Server) SetAlarmInput(port int, active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if port >= 0 && port < len(s.inputs) {
+		s.inputs[port] = active
+	}
+}
+
+// AddRecording stores a recording to be found by Search
+func (s *Server) AddRecording(rec Recording) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+}
+
+// CommandCount returns how many times cmd was received, including Snap
+func (s *Server) CommandCount(cmd string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[cmd]
+}
+
+// request is one command of an api.cgi call
+type request struct {
+	Cmd    string                 `json:"cmd"`
+	Action int                    `json:"action"`
+	Param  map[string]interface{} `json:"param"`
+}
+
+// response is the reply to one command
+type response struct {
+	Cmd   string      `json:"cmd"`
+	Code  int         `json:"code"`
+	Value interface{} `json:"value,omitempty"`
+	Range interface{} `json:"range,omitempty"`
+	Error *rspError   `json:"error,omitempty"`
+}
+
+type rspError struct {
+	RspCode int    `json:"rspCode"`
+	Detail  string `json:"detail"`
+}
+
+// ServeHTTP answers api.cgi requests, at /api.cgi or /cgi-bin/api.cgi
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api.cgi" && r.URL.Path != "/cgi-bin/api.cgi" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+
+	var reqs []request
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil || json.Unmarshal(body, &reqs) != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else if cmd := query.Get("cmd"); cmd != "" {
+		reqs = []request{{Cmd: cmd, Param: map[string]interface{}{}}}
+	}
+	if len(reqs) == 0 {
+		http.Error(w, "missing command", http.StatusBadRequest)
+		return
+	}
+
+	authed := s.authorized(query)
+
+	// Snap returns the image itself rather than JSON
+	if reqs[0].Cmd == "Snap" {
+		s.count("Snap")
+		channel, _ := strconv.Atoi(query.Get("channel"))
+		switch {
+		case !authed:
+			s.writeJSON(w, []response{errorResponse("Snap", rspLoginNeeded, "please login first")})
+		case channel < 0 || channel >= s.cam.Channels:
+			s.writeJSON(w, []response{errorResponse("Snap", rspParamError, "param error")})
+		default:
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Length", strconv.Itoa(len(s.snapshot)))
+			_, _ = w.Write(s.snapshot)
+		}
+		return
+	}
+
+	resps := make([]response, 0, len(reqs))
+	for _, req := range reqs {
+		s.count(req.Cmd)
+		if req.Param == nil {
+			req.Param = map[string]interface{}{}
+		}
+		switch {
+		case req.Cmd == "Login":
+			resps = append(resps, s.login(req))
+		case !authed:
+			resps = append(resps, errorResponse(req.Cmd, rspLoginNeeded, "please login first"))
+		case req.Cmd == "Logout":
+			s.mu.Lock()
+			delete(s.tokens, query.Get("token"))
+			s.mu.Unlock()
+			resps = append(resps, okResponse("Logout", map[string]interface{}{"rspCode": 200}))
+		default:
+			resps = append(resps, s.handle(req))
+		}
+	}
+	s.writeJSON(w, resps)
+}
+
+// authorized checks the token or URL credentials of a request
+func (s *Server) authorized(query map[string][]string) bool {
+	get := func(key string) string {
+		if v := query[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	if token := get("token"); token != "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		exp, ok := s.tokens[token]
+		return ok && time.Now().Before(exp)
+	}
+	if s.cam.TokenOnly {
+		return false
+	}
+	return get("user") == s.cam.Username && get("password") == s.Password()
+}
+
+// login issues a session token for valid credentials
+func (s *Server) login(req request) response {
+	user, _ := req.Param["User"].(map[string]interface{})
+	name, _ := user["userName"].(string)
+	password, _ := user["password"].(string)
+	if name != s.cam.Username || password != s.Password() {
+		return errorResponse("Login", rspLoginFailed, "login failed")
+	}
+
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(tokenLease * time.Second)
+	s.mu.Unlock()
+
+	return okResponse("Login", map[string]interface{}{
+		"Token": map[string]interface{}{"name": token, "leaseTime": tokenLease},
+	})
+}
+
+// handle answers an authenticated command
+func (s *Server) handle(req request) response {
+	channel, hasChannel := intParam(req.Param, "channel")
+	if hasChannel && (channel < 0 || channel >= s.cam.Channels) {
+		return errorResponse(req.Cmd, rspParamError, "param error")
+	}
+
+	switch req.Cmd {
+	case "GetDevInfo":
+		return okResponse(req.Cmd, map[string]interface{}{"DevInfo": map[string]interface{}{
+			"model":       s.cam.Model,
+			"name":        s.cam.Name,
+			"serial":      s.cam.Serial,
+			"firmVer":     s.cam.FirmwareVersion,
+			"hwVer":       s.cam.HardwareVersion,
+			"channelNum":  s.cam.Channels,
+			"IOInputNum":  s.cam.AlarmInputs,
+			"IOOutputNum": s.cam.AlarmOutputs,
+		}})
+
+	case "GetAbility":
+		return okResponse(req.Cmd, map[string]interface{}{"Ability": s.ability()})
+
+	case "GetEnc", "SetEnc":
+		return s.encoder(req, channel)
+
+	case "GetIsp", "SetIsp":
+		return s.isp(req, channel)
+
+	case "GetMask", "SetMask":
+		return s.setting(req, channel, "Mask", s.masks, map[string]interface{}{"channel": channel, "enable": 0, "area": []interface{}{}})
+
+	case "GetAudioAlarmV20", "SetAudioAlarmV20":
+		return s.setting(req, channel, "Audio", s.audioAlarm, map[string]interface{}{
+			"channel": channel, "enable": 0, "schedule": map[string]interface{}{"channel": channel, "table": map[string]interface{}{}},
+		})
+
+	case "GetLocalLink":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		activeLink := "LAN"
+		if len(s.cam.WiFiNetworks) > 0 {
+			activeLink = "WiFi"
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"LocalLink": map[string]interface{}{
+			"activeLink": activeLink,
+			"mac":        s.cam.MAC,
+			"type":       s.linkType,
+			"static":     s.linkStatic,
+			"dns":        s.linkDNS,
+		}})
+
+	case "ScanWifi", "GetWifi", "GetWifiSignal", "TestWifi", "SetWifi":
+		return s.wifi(req)
+
+	case "SetLocalLink":
+		link, _ := req.Param["LocalLink"].(map[string]interface{})
+		linkType, _ := link["type"].(string)
+		static, _ := link["static"].(map[string]interface{})
+		dns, _ := link["dns"].(map[string]interface{})
+		if linkType != "DHCP" && (linkType != "Static" || static == nil) {
+			return errorResponse(req.Cmd, rspParamError, "param error")
+		}
+		s.mu.Lock()
+		s.linkType = linkType
+		if static != nil {
+			s.linkStatic = static
+		}
+		if dns != nil {
+			s.linkDNS = dns
+		}
+		s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+
+	case "GetNetPort":
+		return okResponse(req.Cmd, map[string]interface{}{"NetPort": map[string]interface{}{
+			"httpPort":  80,
+			"httpsPort": 443,
+			"rtspPort":  s.cam.RTSPPort,
+			"rtmpPort":  1935,
+			"onvifPort": 8000,
+			"mediaPort": 9000,
+		}})
+
+	case "GetChannelstatus":
+		status := make([]interface{}, s.cam.Channels)
+		for i := range status {
+			entry := map[string]interface{}{
+				"channel": i,
+				"name":    fmt.Sprintf("%s %d", s.cam.Name, i+1),
+				"online":  1,
+			}
+			if i < len(s.cam.ChannelModels) {
+				entry["typeInfo"] = s.cam.ChannelModels[i]
+				if s.cam.ChannelModels[i] == "" {
+					entry["online"] = 0
+				}
+			}
+			status[i] = entry
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"count": s.cam.Channels, "status": status})
+
+	case "GetPerformance":
+		return okResponse(req.Cmd, map[string]interface{}{"Performance": map[string]interface{}{
+			"cpuUsed": 35, "codecRate": 4096, "netThroughput": 2048,
+		}})
+
+	case "GetHddInfo":
+		return okResponse(req.Cmd, map[string]interface{}{"HddInfo": []interface{}{
+			map[string]interface{}{"id": 0, "capacity": 1907729, "size": 1782579, "format": 1, "mount": 1, "storageType": 1},
+		}})
+
+	case "GetMdState":
+		state := s.channelState(channel)
+		return okResponse(req.Cmd, map[string]interface{}{"state": flag(state.motion)})
+
+	case "GetAiState":
+		if !s.cam.AI {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		return okResponse(req.Cmd, s.aiState(channel))
+
+	case "GetEvents":
+		if s.cam.LegacyEvents {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		state := s.channelState(channel)
+		value := map[string]interface{}{
+			"channel": channel,
+			"md":      map[string]interface{}{"alarm_state": flag(state.motion), "support": 1},
+		}
+		if s.cam.AI {
+			value["ai"] = s.aiState(channel)
+		}
+		return okResponse(req.Cmd, value)
+
+	case "PtzCtrl":
+		if !s.cam.PTZ {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		op, _ := req.Param["op"].(string)
+		if op == "" {
+			return errorResponse(req.Cmd, rspParamError, "param error")
+		}
+		speed, _ := intParam(req.Param, "speed")
+		preset, _ := intParam(req.Param, "id")
+		s.mu.Lock()
+		s.ptz = append(s.ptz, PTZCommand{Channel: channel, Op: op, Speed: speed, Preset: preset})
+		s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+
+	case "GetUser":
+		s.mu.Lock()
+		users := []interface{}{map[string]interface{}{"userName": s.cam.Username, "level": "admin"}}
+		for name := range s.guests {
+			users = append(users, map[string]interface{}{"userName": name, "level": "guest"})
+		}
+		s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"User": users})
+
+	case "AddUser":
+		user, _ := req.Param["User"].(map[string]interface{})
+		name, _ := user["userName"].(string)
+		password, _ := user["password"].(string)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, exists := s.guests[name]; exists || name == s.cam.Username {
+			return errorResponse(req.Cmd, rspParamError, "user exist")
+		}
+		s.guests[name] = password
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+
+	case "ModifyUser":
+		user, _ := req.Param["User"].(map[string]interface{})
+		name, _ := user["userName"].(string)
+		password, _ := user["password"].(string)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, guest := s.guests[name]
+		if name != s.cam.Username && !guest {
+			return errorResponse(req.Cmd, rspParamError, "user not exist")
+		}
+		if password != "" && guest {
+			s.guests[name] = password
+		} else if password != "" {
+			s.password = password
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+
+	case "GetIOOutput":
+		if s.cam.AlarmOutputs == 0 {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		s.mu.Lock()
+		outputs := make([]interface{}, len(s.outputs))
+		for i, active := range s.outputs {
+			outputs[i] = map[string]interface{}{"port": i, "status": flag(active)}
+		}
+		s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"IOOutput": outputs})
+
+	case "GetIOInput":
+		if s.cam.AlarmInputs == 0 {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		s.mu.Lock()
+		inputs := make([]interface{}, len(s.inputs))
+		for i, active := range s.inputs {
+			inputs[i] = map[string]interface{}{"port": i, "status": flag(active)}
+		}
+		s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"IOInput": inputs})
+
+	case "SetIOOutput":
+		if s.cam.AlarmOutputs == 0 {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		output, _ := req.Param["IOOutput"].(map[string]interface{})
+		port, ok := intParam(output, "port")
+		status, _ := intParam(output, "status")
+		if !ok || port < 0 || port >= s.cam.AlarmOutputs {
+			return errorResponse(req.Cmd, rspParamError, "param error")
+		}
+		s.mu.Lock()
+		s.outputs[port] = status == 1
+		s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+
+	case "GetTime":
+		return okResponse(req.Cmd, map[string]interface{}{"Time": timeValue(time.Now().In(s.cam.Location))})
+
+	case "Search":
+		return s.search(req)
+
+	case "GetPtzPreset":
+		if !s.cam.PTZ {
+			return errorResponse(req.Cmd, rspNotSupported, "not support")
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"PtzPreset": []interface{}{
+			map[string]interface{}{"id": 0, "name": "Home", "enable": 1, "channel": channel},
+			map[string]interface{}{"id": 1, "name": "Gate", "enable": 1, "channel": channel},
+		}})
+	}
+
+	return errorResponse(req.Cmd, rspNotSupported, "not support")
+}
+
+// encoder answers GetEnc, with the stream options in its range for action
+// 1, and applies SetEnc
+func (s *Server) encoder(req request, channel int) response {
+	if req.Cmd == "SetEnc" {
+		enc, _ := req.Param["Enc"].(map[string]interface{})
+		channel, _ = intParam(enc, "channel")
+		if channel < 0 || channel >= s.cam.Channels {
+			return errorResponse(req.Cmd, rspParamError, "param error")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for key, stream := range map[string]*streamState{"mainStream": &s.encoders[channel].main, "subStream": &s.encoders[channel].sub} {
+			params, ok := enc[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if size, ok := params["size"].(string); ok {
+				if _, err := fmt.Sscanf(size, "%d*%d", &stream.width, &stream.height); err != nil {
+					return errorResponse(req.Cmd, rspParamError, "param error")
+				}
+			}
+			if fps, ok := intParam(params, "frameRate"); ok {
+				stream.frameRate = fps
+			}
+			if rate, ok := intParam(params, "bitRate"); ok {
+				stream.bitRate = rate
+			}
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+	}
+
+	s.mu.Lock()
+	state := s.encoders[channel]
+	s.mu.Unlock()
+	resp := okResponse(req.Cmd, map[string]interface{}{"Enc": map[string]interface{}{
+		"channel":    channel,
+		"audio":      1,
+		"mainStream": streamConfig(state.main.width, state.main.height, state.main.frameRate, state.main.bitRate, state.main.codec),
+		"subStream":  streamConfig(state.sub.width, state.sub.height, state.sub.frameRate, state.sub.bitRate, state.sub.codec),
+	}})
+	if req.Action == 1 {
+		resp.Range = map[string]interface{}{"Enc": s.encoderRange()}
+	}
+	return resp
+}
+
+// encoderRange lists the resolutions of the streams with their frame rates
+// and bitrates, one GetEnc range entry per main stream resolution
+func (s *Server) encoderRange() []interface{} {
+	option := func(width, height int, frameRates, bitRates []int) map[string]interface{} {
+		return map[string]interface{}{"size": fmt.Sprintf("%d*%d", width, height), "frameRate": frameRates, "bitRate": bitRates}
+	}
+	if s.cam.Binning {
+		sub := option(640, 352, []int{15}, []int{256})
+		return []interface{}{
+			map[string]interface{}{"mainStream": option(4512, 2512, []int{20}, []int{4096}), "subStream": sub},
+			map[string]interface{}{"mainStream": option(2256, 1256, []int{30, 20}, []int{4096}), "subStream": sub},
+		}
+	}
+	sub := option(640, 360, []int{15, 10}, []int{256, 512})
+	return []interface{}{
+		map[string]interface{}{"mainStream": option(3840, 2160, []int{25, 20, 15}, []int{4096, 6144, 8192}), "subStream": sub},
+		map[string]interface{}{"mainStream": option(2560, 1440, []int{25, 20, 15}, []int{3072, 4096}), "subStream": sub},
+	}
+}
+
+// isp answers GetIsp and SetIsp. Only cameras with binning have ISP
+// settings in the simulator.
+func (s *Server) isp(req request, channel int) response {
+	if !s.cam.Binning {
+		return errorResponse(req.Cmd, rspNotSupported, "not support")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Cmd == "SetIsp" {
+		isp, _ := req.Param["Isp"].(map[string]interface{})
+		if mode, ok := intParam(isp, "binningMode"); ok {
+			s.binning = mode
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+	}
+	return okResponse(req.Cmd, map[string]interface{}{"Isp": map[string]interface{}{"channel": channel, "binningMode": s.binning}})
+}
+
+// setting answers a Get command with the object last stored for the
+// channel by its Set command under key, or with def
+func (s *Server) setting(req request, channel int, key string, stored map[int]interface{}, def interface{}) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strings.HasPrefix(req.Cmd, "Set") {
+		value, ok := req.Param[key].(map[string]interface{})
+		if !ok {
+			return errorResponse(req.Cmd, rspParamError, "param error")
+		}
+		channel, _ = intParam(value, "channel")
+		if channel < 0 || channel >= s.cam.Channels {
+			return errorResponse(req.Cmd, rspParamError, "param error")
+		}
+		stored[channel] = value
+		return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+	}
+	if value, ok := stored[channel]; ok {
+		def = value
+	}
+	return okResponse(req.Cmd, map[string]interface{}{key: def})
+}
+
+// wifi answers the WiFi commands of a WiFi camera
+func (s *Server) wifi(req request) response {
+	if len(s.cam.WiFiNetworks) == 0 {
+		return errorResponse(req.Cmd, rspNotSupported, "not support")
+	}
+
+	switch req.Cmd {
+	case "ScanWifi":
+		ssids := make([]string, 0, len(s.cam.WiFiNetworks))
+		for ssid := range s.cam.WiFiNetworks {
+			ssids = append(ssids, ssid)
+		}
+		sort.Strings(ssids)
+		list := make([]interface{}, len(ssids))
+		for i, ssid := range ssids {
+			list[i] = map[string]interface{}{"ssid": ssid, "signal": 80 - 10*i, "encrypt": flag(s.cam.WiFiNetworks[ssid] != "")}
+		}
+		return okResponse(req.Cmd, map[string]interface{}{"ScanWifi": map[string]interface{}{"num": len(list), "Wifi": list}})
+
+	case "GetWifi":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return okResponse(req.Cmd, map[string]interface{}{"Wifi": map[string]interface{}{
+			"ssid": s.wifiSSID, "password": s.cam.WiFiNetworks[s.wifiSSID],
+		}})
+
+	case "GetWifiSignal":
+		return okResponse(req.Cmd, map[string]interface{}{"wifiSignal": 80})
+	}
+
+	// TestWifi and SetWifi fail for networks the camera cannot join
+	wifi, _ := req.Param["Wifi"].(map[string]interface{})
+	ssid, _ := wifi["ssid"].(string)
+	password, _ := wifi["password"].(string)
+	if want, ok := s.cam.WiFiNetworks[ssid]; !ok || password != want {
+		return errorResponse(req.Cmd, rspParamError, "connect wifi failed")
+	}
+	if req.Cmd == "SetWifi" {
+		s.mu.Lock()
+		s.wifiSSID = ssid
+		s.mu.Unlock()
+	}
+	return okResponse(req.Cmd, map[string]interface{}{"rspCode": 200})
+}
+
+// search answers a recording search with the recordings of the channel
+// overlapping the requested local time range
+func (s *Server) search(req request) response {
+	param, _ := req.Param["Search"].(map[string]interface{})
+	channel, _ := intParam(param, "channel")
+	start, okStart := parseTime(param["StartTime"], s.cam.Location)
+	end, okEnd := parseTime(param["EndTime"], s.cam.Location)
+	if channel < 0 || channel >= s.cam.Channels || !okStart || !okEnd {
+		return errorResponse(req.Cmd, rspParamError, "param error")
+	}
+
+	s.mu.Lock()
+	files := []interface{}{}
+	for _, rec := range s.records {
+		if rec.Channel != channel || rec.End.Before(start) || rec.Start.After(end) {
+			continue
+		}
+		files = append(files, map[string]interface{}{
+			"name":      rec.Name,
+			"StartTime": timeValue(rec.Start.In(s.cam.Location)),
+			"EndTime":   timeValue(rec.End.In(s.cam.Location)),
+			"size":      1024,
+			"type":      "main",
+		})
+	}
+	s.mu.Unlock()
+
+	return okResponse(req.Cmd, map[string]interface{}{"SearchResult": map[string]interface{}{
+		"channel": channel,
+		"File":    files,
+	}})
+}
+
+// ability builds the GetAbility value for the simulated features
+func (s *Server) ability() map[string]interface{} {
+	ptzVer := 0
+	if s.cam.PTZ {
+		ptzVer = 1
+	}
+	aiVer := 0
+	if s.cam.AI {
+		aiVer = 1
+	}
+
+	chn := make([]interface{}, s.cam.Channels)
+	for i := range chn {
+		chn[i] = map[string]interface{}{
+			"supportAiPeople":  map[string]interface{}{"permit": 4, "ver": aiVer},
+			"supportAiVehicle": map[string]interface{}{"permit": 4, "ver": aiVer},
+			"supportAiFace":    map[string]interface{}{"permit": 4, "ver": 0},
+			"supportAiPackage": map[string]interface{}{"permit": 4, "ver": 0},
+			"floodLight":       map[string]interface{}{"permit": 6, "ver": 0},
+		}
+	}
+	for _, i := range s.cam.DoorbellChannels {
+		if i >= 0 && i < len(chn) {
+			entry := chn[i].(map[string]interface{})
+			entry["supportVisitor"] = map[string]interface{}{"permit": 4, "ver": 1}
+			entry["supportQuickReplyPlay"] = map[string]interface{}{"permit": 6, "ver": 1}
+		}
+	}
+
+	ability := map[string]interface{}{
+		"ptz":               map[string]interface{}{"permit": 6, "ver": ptzVer},
+		"pt":                map[string]interface{}{"permit": 6, "ver": ptzVer},
+		"talk":              map[string]interface{}{"permit": 6, "ver": 1},
+		"supportAudioAlarm": map[string]interface{}{"permit": 6, "ver": 1},
+		"abilityChn":        chn,
+	}
+	if s.cam.APIVersion > 0 {
+		ability["apiVersion"] = map[string]interface{}{"permit": 0, "ver": s.cam.APIVersion}
+	}
+	return ability
+}
+
+// aiState builds the GetAiState value of a channel
+func (s *Server) aiState(channel int) map[string]interface{} {
+	state := s.channelState(channel)
+	value := map[string]interface{}{"channel": channel}
+	for _, kind := range aiKinds {
+		value[kind] = map[string]interface{}{"alarm_state": flag(state.ai[kind]), "support": 1}
+	}
+	return value
+}
+
+// channelState returns a copy of the alarm state of a channel
+func (s *Server) channelState(channel int) channelState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := channelState{motion: s.channels[channel].motion, ai: make(map[string]bool)}
+	for kind, active := range s.channels[channel].ai {
+		state.ai[kind] = active
+	}
+	return state
+}
+
+func (s *Server) count(cmd string) {
+	s.mu.Lock()
+	s.counts[cmd]++
+	s.mu.Unlock()
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, resps []response) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resps)
+}
+
+func okResponse(cmd string, value interface{}) response {
+	return response{Cmd: cmd, Code: 0, Value: value}
+}
+
+func errorResponse(cmd string, rspCode int, detail string) response {
+	return response{Cmd: cmd, Code: 1, Error: &rspError{RspCode: rspCode, Detail: detail}}
+}
+
+// timeValue formats t as a Reolink time object
+func timeValue(t time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"year": t.Year(), "mon": int(t.Month()), "day": t.Day(),
+		"hour": t.Hour(), "min": t.Minute(), "sec": t.Second(),
+	}
+}
+
+// parseTime reads a Reolink time object in loc
+func parseTime(v interface{}, loc *time.Location) (time.Time, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return time.Time{}, false
+	}
+	var fields [6]int
+	for i, name := range []string{"year", "mon", "day", "hour", "min", "sec"} {
+		if fields[i], ok = intParam(obj, name); !ok {
+			return time.Time{}, false
+		}
+	}
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc), true
+}
+
+func streamConfig(width, height, fps, bitrate int, codec string) map[string]interface{} {
+	return map[string]interface{}{
+		"width":     width,
+		"height":    height,
+		"frameRate": fps,
+		"bitRate":   bitrate,
+		"video":     map[string]interface{}{"videoType": codec},
+	}
+}
+
+// intParam reads an integer command parameter, which JSON decodes as float64
+func intParam(param map[string]interface{}, key string) (int, bool) {
+	switch v := param[key].(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func flag(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// testJPEG encodes a small gray image used as the default snapshot
+func testJPEG() []byte {
+	img := image.NewGray(image.Rect(0, 0, 64, 36))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	img.SetGray(0, 0, color.Gray{Y: 0xFF})
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+}
@@ -0,0 +1,163 @@
+package reolinksim
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// call posts one command and decodes the single response
+func call(t *testing.T, url string, cmd string, param map[string]interface{}) response {
+	t.Helper()
+	body, _ := json.Marshal([]request{{Cmd: cmd, Param: param}})
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s failed: %v", cmd, err)
+	}
+	defer resp.Body.Close()
+
+	var resps []struct {
+		Cmd   string                 `json:"cmd"`
+		Code  int                    `json:"code"`
+		Value map[string]interface{} `json:"value"`
+		Error *rspError              `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&resps); err != nil || len(resps) != 1 {
+		t.Fatalf("%s: invalid response: %v", cmd, err)
+	}
+	return response{Cmd: resps[0].Cmd, Code: resps[0].Code, Value: resps[0].Value, Error: resps[0].Error}
+}
+
+func login(t *testing.T, server *httptest.Server, user, password string) string {
+	t.Helper()
+	resp := call(t, server.URL+"/api.cgi", "Login", map[string]interface{}{
+		"User": map[string]interface{}{"userName": user, "password": password},
+	})
+	if resp.Code != 0 {
+		return ""
+	}
+	token, _ := resp.Value.(map[string]interface{})["Token"].(map[string]interface{})
+	name, _ := token["name"].(string)
+	return name
+}
+
+func TestServer_Login(t *testing.T) {
+	server := httptest.NewServer(New(Camera{Password: "secret"}))
+	defer server.Close()
+
+	if token := login(t, server, "admin", "wrong"); token != "" {
+		t.Error("Expected login with a wrong password to fail")
+	}
+	token := login(t, server, "admin", "secret")
+	if token == "" {
+		t.Fatal("Expected login to succeed")
+	}
+
+	apiURL := server.URL + "/api.cgi?token=" + token
+	info := call(t, apiURL, "GetDevInfo", nil)
+	if info.Code != 0 {
+		t.Fatalf("GetDevInfo failed: %+v", info.Error)
+	}
+	devInfo := info.Value.(map[string]interface{})["DevInfo"].(map[string]interface{})
+	if devInfo["model"] != DefaultCamera.Model || devInfo["channelNum"] != float64(1) {
+		t.Errorf("Unexpected DevInfo: %v", devInfo)
+	}
+
+	if resp := call(t, apiURL, "Logout", nil); resp.Code != 0 {
+		t.Errorf("Logout failed: %+v", resp.Error)
+	}
+	if resp := call(t, apiURL, "GetDevInfo", nil); resp.Error == nil || resp.Error.RspCode != rspLoginNeeded {
+		t.Errorf("Expected token to be invalid after logout, got %+v", resp)
+	}
+}
+
+func TestServer_URLAuth(t *testing.T) {
+	server := httptest.NewServer(New(Camera{Password: "secret"}))
+	defer server.Close()
+	tokenOnly := httptest.NewServer(New(Camera{Password: "secret", TokenOnly: true}))
+	defer tokenOnly.Close()
+
+	query := "?cmd=GetDevInfo&user=admin&password=" + url.QueryEscape("secret")
+	if resp := call(t, server.URL+"/api.cgi"+query, "GetDevInfo", nil); resp.Code != 0 {
+		t.Errorf("Expected URL credentials to be accepted, got %+v", resp.Error)
+	}
+	if resp := call(t, tokenOnly.URL+"/api.cgi"+query, "GetDevInfo", nil); resp.Code == 0 {
+		t.Error("Expected URL credentials to be rejected in token-only mode")
+	}
+}
+
+func TestServer_Snap(t *testing.T) {
+	server := httptest.NewServer(New(Camera{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/cgi-bin/api.cgi?cmd=Snap&channel=0&user=admin&password=")
+	if err != nil {
+		t.Fatalf("Snap failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Expected image/jpeg, got %q", ct)
+	}
+	if _, err := jpeg.Decode(resp.Body); err != nil {
+		t.Errorf("Expected a valid JPEG: %v", err)
+	}
+
+	// A channel the device does not have is rejected with JSON
+	resp2, err := http.Get(server.URL + "/cgi-bin/api.cgi?cmd=Snap&channel=3&user=admin&password=")
+	if err != nil {
+		t.Fatalf("Snap failed: %v", err)
+	}
+	resp2.Body.Close()
+	if ct := resp2.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON error for unknown channel, got %q", ct)
+	}
+}
+
+func TestServer_EventsAndPTZ(t *testing.T) {
+	sim := New(Camera{Channels: 2, PTZ: true, AI: true, LegacyEvents: true})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	apiURL := server.URL + "/api.cgi?user=admin&password="
+
+	if resp := call(t, apiURL, "GetEvents", map[string]interface{}{"channel": 1}); resp.Error == nil || resp.Error.RspCode != rspNotSupported {
+		t.Errorf("Expected GetEvents to be unsupported, got %+v", resp)
+	}
+
+	sim.SetMotion(1, true)
+	sim.SetAI(1, AIPerson, true)
+	md := call(t, apiURL, "GetMdState", map[string]interface{}{"channel": 1})
+	if md.Value.(map[string]interface{})["state"] != float64(1) {
+		t.Errorf("Expected motion on channel 1, got %v", md.Value)
+	}
+	md = call(t, apiURL, "GetMdState", map[string]interface{}{"channel": 0})
+	if md.Value.(map[string]interface{})["state"] != float64(0) {
+		t.Errorf("Expected no motion on channel 0, got %v", md.Value)
+	}
+	ai := call(t, apiURL, "GetAiState", map[string]interface{}{"channel": 1})
+	people := ai.Value.(map[string]interface{})["people"].(map[string]interface{})
+	if people["alarm_state"] != float64(1) {
+		t.Errorf("Expected person detected, got %v", people)
+	}
+
+	call(t, apiURL, "PtzCtrl", map[string]interface{}{"channel": 1, "op": "Left", "speed": 32})
+	call(t, apiURL, "PtzCtrl", map[string]interface{}{"channel": 1, "op": "ToPos", "speed": 32, "id": "1"})
+	cmds := sim.PTZCommands()
+	if len(cmds) != 2 || cmds[0] != (PTZCommand{Channel: 1, Op: "Left", Speed: 32}) || cmds[1].Preset != 1 {
+		t.Errorf("Unexpected PTZ commands: %+v", cmds)
+	}
+	if sim.CommandCount("PtzCtrl") != 2 {
+		t.Errorf("Expected 2 PtzCtrl commands, got %d", sim.CommandCount("PtzCtrl"))
+	}
+
+	if resp := call(t, apiURL, "GetEnc", map[string]interface{}{"channel": 2}); resp.Error == nil || resp.Error.RspCode != rspParamError {
+		t.Errorf("Expected param error for unknown channel, got %+v", resp)
+	}
+	if resp := call(t, apiURL, "Reboot", nil); resp.Error == nil || resp.Error.RspCode != rspNotSupported {
+		t.Errorf("Expected unknown command to be unsupported, got %+v", resp)
+	}
+}
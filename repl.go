@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The interactive mode (-interactive) drives the plugin by hand: each line is
+// either "<method> [params]" or a raw JSON-RPC request, and responses and
+// notifications are pretty-printed.
+
+// replTemplates holds example params for each method, shown by "template"
+var replTemplates = map[string]string{
+	"initialize":             `{"devices": [{"host": "192.168.1.100", "username": "admin", "password": ""}]}`,
+	"shutdown":               ``,
+	"health":                 ``,
+	"get_device_health":      `{"host": "192.168.1.100"}`,
+	"discover_cameras":       `{"subnets": []}`,
+	"add_camera":             `{"host": "192.168.1.100", "username": "admin", "password": "", "channel": 0}`,
+	"remove_camera":          `{"camera_id": ""}`,
+	"list_cameras":           ``,
+	"get_camera":             `{"camera_id": ""}`,
+	"update_camera":          `{"camera_id": "", "settings": {"name": ""}}`,
+	"sync_channel_names":     `{"camera_id": ""}`,
+	"ptz_control":            `{"camera_id": "", "command": {"action": "pan", "direction": 1, "speed": 0.5}}`,
+	"get_snapshot":           `{"camera_id": ""}`,
+	"set_snapshot_options":   `{"camera_id": "", "quality": 80, "timestamp": true}`,
+	"probe_camera":           `{"host": "192.168.1.100", "port": 80, "username": "admin", "password": ""}`,
+	"classify_model":         `{"model": "RLC-810A", "channels": 0}`,
+	"get_capabilities":       `{"camera_id": ""}`,
+	"refresh_camera":         `{"camera_id": ""}`,
+	"get_ability":            `{"camera_id": ""}`,
+	"get_ptz_presets":        `{"camera_id": ""}`,
+	"save_zoom_preset":       `{"camera_id": "", "name": ""}`,
+	"get_zoom_focus":         `{"camera_id": ""}`,
+	"autofocus":              `{"camera_id": ""}`,
+	"delete_zoom_preset":     `{"camera_id": "", "name": ""}`,
+	"get_protocols":          `{"camera_id": ""}`,
+	"get_stream_profiles":    `{"camera_id": ""}`,
+	"get_audio_stream":       `{"camera_id": ""}`,
+	"set_lens_mode":          `{"camera_id": "", "view_mode": "panorama"}`,
+	"set_protocol":           `{"camera_id": "", "protocol": "rtsp"}`,
+	"get_device_info":        `{"camera_id": ""}`,
+	"list_users":             `{"camera_id": ""}`,
+	"add_user":               `{"camera_id": "", "username": "", "password": "", "level": "guest"}`,
+	"modify_user":            `{"camera_id": "", "username": "", "password": "", "level": "guest"}`,
+	"delete_user":            `{"camera_id": "", "username": ""}`,
+	"rotate_password":        `{"camera_id": "", "new_password": ""}`,
+	"configure_network":      `{"camera_id": "", "mode": "static", "ip": "", "mask": "255.255.255.0", "gateway": "", "dns": [""], "dry_run": true}`,
+	"scan_wifi":              `{"camera_id": ""}`,
+	"get_wifi":               `{"camera_id": ""}`,
+	"set_wifi":               `{"camera_id": "", "ssid": "", "password": ""}`,
+	"list_sessions":          `{"camera_id": ""}`,
+	"disconnect_session":     `{"camera_id": "", "username": "", "session_id": 0}`,
+	"get_light":              `{"camera_id": ""}`,
+	"set_light":              `{"camera_id": "", "on": true, "brightness": 100}`,
+	"set_light_schedule":     `{"camera_id": "", "mode": "schedule", "schedule": {"start": "18:00", "end": "06:00"}}`,
+	"get_encoder_settings":   `{"camera_id": ""}`,
+	"set_encoder_settings":   `{"camera_id": "", "stream": "main", "width": 0, "height": 0, "binning": "auto", "dry_run": true}`,
+	"provision_camera":       `{"camera_id": "", "name": "", "profile": "", "settings": {"timezone": "", "push": false, "email": false, "rtsp": true}}`,
+	"get_image_settings":     `{"camera_id": ""}`,
+	"configure_privacy_mask": `{"camera_id": "", "action": "list"}`,
+	"set_image_settings":     `{"camera_id": "", "settings": {"anti_flicker": "50hz"}}`,
+	"get_alarm_inputs":       `{"camera_id": ""}`,
+	"alarm_output":           `{"camera_id": "", "action": "trigger", "port": 0, "duration_ms": 3000}`,
+	"configure_siren":        `{"camera_id": "", "enabled": true, "triggers": ["person"], "schedule": [{"start": "22:00", "end": "06:00"}]}`,
+	"list_audio_clips":       `{"camera_id": ""}`,
+	"upload_audio_clip":      `{"camera_id": "", "name": "", "data": ""}`,
+	"select_audio_clip":      `{"camera_id": "", "id": 0}`,
+	"talk":                   `{"camera_id": "", "data": ""}`,
+	"transfer.begin":         `{"transfer_id": "", "name": "", "size": 0}`,
+	"transfer.chunk":         `{"transfer_id": "", "seq": 0, "data": ""}`,
+	"transfer.end":           `{"transfer_id": "", "size": 0, "chunks": 0, "sha256": ""}`,
+	"download_clip":          `{"camera_id": "", "source": ""}`,
+	"upgrade_firmware":       `{"camera_id": "", "transfer_id": "", "dry_run": true}`,
+	"raw_command":            `{"camera_id": "", "commands": [{"cmd": "GetTime", "action": 0, "param": {}}]}`,
+	"emit_test_event":        `{"camera_id": "", "type": "person"}`,
+	"onvif_notify":           `{"xml": "", "source": ""}`,
+	"get_events":             `{"camera_id": "", "since": 0, "limit": 50, "wait_ms": 0}`,
+	"get_event_media":        `{"event_id": "", "wait_ms": 0}`,
+	"get_event_summary":      `{"camera_id": "", "hours": 24}`,
+	"start_timelapse":        `{"camera_id": "", "interval_ms": 60000}`,
+	"stop_timelapse":         `{"camera_id": ""}`,
+	"list_timelapses":        ``,
+	"list_snapshot_sinks":    ``,
+	"list_webhooks":          ``,
+	"get_ha_discovery":       `{"discovery_prefix": "homeassistant"}`,
+	"get_settings":           ``,
+	"put_setting":            `{"key": "host", "value": ""}`,
+}
+
+const replHelp = `Commands:
+  <method> [params]   call a method, params as JSON, e.g. get_camera {"camera_id": "cam_1"}
+  {...}               send a raw JSON-RPC request
+  methods             list the available methods
+  template <method>   print an example call to copy and edit
+  help                show this help
+  quit                exit`
+
+// prettyWriter reindents each JSON message written to it
+type prettyWriter struct {
+	w io.Writer
+}
+
+func (pw prettyWriter) Write(data []byte) (int, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+		return pw.w.Write(data)
+	}
+	buf.WriteByte('\n')
+	if _, err := pw.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// runREPL reads commands from in until EOF or "quit", writing prompts,
+// responses and notifications to out
+func runREPL(p *Plugin, in io.Reader, out io.Writer) error {
+	p.SetOutput(prettyWriter{w: out})
+
+	fmt.Fprintln(out, `Reolink plugin interactive mode. Type "help" for commands.`)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+
+	nextID := 1
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch name {
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Fprintln(out, replHelp)
+			continue
+		case "methods":
+			fmt.Fprintln(out, strings.Join(replMethods(), "\n"))
+			continue
+		case "template":
+			params, ok := replTemplates[rest]
+			if !ok {
+				fmt.Fprintf(out, "Unknown method %q\n", rest)
+				continue
+			}
+			fmt.Fprintln(out, strings.TrimSpace(rest+" "+params))
+			continue
+		}
+
+		req, err := parseREPLLine(line, nextID)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		nextID++
+
+		// Handlers expect the context set up by initialize, as sent by the host
+		if p.ctx == nil && req.Method != "initialize" {
+			fmt.Fprintln(out, `Not initialized, run "initialize {}" or "template initialize" first`)
+			continue
+		}
+
+		if err := p.writeMessage(p.HandleRequest(req)); err != nil {
+			return err
+		}
+	}
+}
+
+// parseREPLLine builds a request from a raw JSON-RPC object or a
+// "<method> [params]" line. Requests without an ID get id.
+func parseREPLLine(line string, id int) (JSONRPCRequest, error) {
+	var req JSONRPCRequest
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			return req, fmt.Errorf("invalid request: %v", err)
+		}
+		if req.Method == "" {
+			return req, fmt.Errorf("invalid request: method is required")
+		}
+	} else {
+		method, params, _ := strings.Cut(line, " ")
+		req.Method = method
+		if params = strings.TrimSpace(params); params != "" {
+			if !json.Valid([]byte(params)) {
+				return req, fmt.Errorf("invalid params: not valid JSON, see \"template %s\"", method)
+			}
+			req.Params = json.RawMessage(params)
+		}
+	}
+
+	if req.JSONRPC == "" {
+		req.JSONRPC = "2.0"
+	}
+	if req.ID == nil {
+		req.ID = id
+	}
+	// Methods decode their params unconditionally, so default to an empty object
+	if req.Params == nil {
+		req.Params = json.RawMessage("{}")
+	}
+	return req, nil
+}
+
+// replMethods returns the method names with templates, sorted
+func replMethods() []string {
+	methods := make([]string, 0, len(replTemplates))
+	for method := range replTemplates {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
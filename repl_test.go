@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseREPLLine(t *testing.T) {
+	req, err := parseREPLLine(`get_camera {"camera_id": "cam_1"}`, 7)
+	if err != nil {
+		t.Fatalf("parseREPLLine failed: %v", err)
+	}
+	if req.Method != "get_camera" || req.ID != 7 || req.JSONRPC != "2.0" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	if string(req.Params) != `{"camera_id": "cam_1"}` {
+		t.Errorf("Unexpected params: %s", req.Params)
+	}
+
+	// Methods without params get an empty object
+	req, _ = parseREPLLine("list_cameras", 1)
+	if string(req.Params) != "{}" {
+		t.Errorf("Expected empty params, got %s", req.Params)
+	}
+
+	// Raw requests keep their own ID
+	req, err = parseREPLLine(`{"jsonrpc": "2.0", "id": "abc", "method": "health"}`, 3)
+	if err != nil || req.Method != "health" || req.ID != "abc" {
+		t.Errorf("Unexpected raw request: %+v, %v", req, err)
+	}
+
+	for _, line := range []string{`get_camera {"camera_id":`, `{"id": 1}`, `{not json}`} {
+		if _, err := parseREPLLine(line, 1); err == nil {
+			t.Errorf("Expected error for %q", line)
+		}
+	}
+}
+
+func TestRunREPL(t *testing.T) {
+	input := strings.Join([]string{
+		"help",
+		"list_cameras",
+		"initialize {}",
+		"template get_camera",
+		`get_camera {"camera_id": "missing"}`,
+		"get_camera {bad",
+		"list_cameras",
+		"quit",
+		"health",
+	}, "\n")
+
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	if err := runREPL(plugin, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runREPL failed: %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"template <method>",
+		`get_camera {"camera_id": ""}`,
+		`"message": "Camera not found"`,
+		"invalid params",
+		"Not initialized",
+		"\"id\": 4,\n  \"result\": []",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	// Nothing after quit is run
+	if strings.Contains(output, `"id": 5`) {
+		t.Errorf("Expected commands after quit to be ignored, got:\n%s", output)
+	}
+}
+
+func TestREPLTemplates(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: []byte("{}")})
+	defer plugin.Shutdown(context.Background())
+
+	for _, method := range replMethods() {
+		// Templates must be valid calls of methods the plugin knows
+		req, err := parseREPLLine(strings.TrimSpace(method+" "+replTemplates[method]), 1)
+		if err != nil {
+			t.Errorf("Template for %s does not parse: %v", method, err)
+			continue
+		}
+		switch method {
+		case "initialize", "add_camera", "probe_camera", "get_device_health", "discover_cameras", "shutdown":
+			// Talk to the network or stop the plugin
+			continue
+		}
+		if resp := plugin.HandleRequest(req); resp.Error != nil && resp.Error.Code == -32601 {
+			t.Errorf("Template method %s is not handled", method)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRESTBodySize bounds request bodies of the REST gateway
+const maxRESTBodySize = 1 << 20
+
+// restGateway serves a small REST API over the JSON-RPC methods, for
+// debugging and for hosts that prefer HTTP to stdio
+type restGateway struct {
+	server *http.Server
+	done   chan struct{} // closed when the server has stopped
+}
+
+// restAddr validates a REST gateway listen address. Without a token anyone
+// who can reach the gateway controls the cameras, so only loopback addresses
+// are accepted then; a bare ":port" binds to 127.0.0.1.
+func restAddr(addr, token string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid REST address %q: %w", addr, err)
+	}
+	if token != "" {
+		return addr, nil
+	}
+	host, ok := loopbackHost(host)
+	if !ok {
+		return "", fmt.Errorf("REST address %q must be on localhost unless rest_token is set", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// startREST serves the REST gateway until Shutdown. Requests must carry
+// token as a bearer token if it is set.
+func (p *Plugin) startREST(addr, token string) error {
+	addr, err := restAddr(addr, token)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rest != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for REST requests: %w", err)
+	}
+
+	gw := &restGateway{
+		server: &http.Server{Addr: listener.Addr().String(), Handler: p.restHandler(token), ReadHeaderTimeout: 10 * time.Second},
+		done:   make(chan struct{}),
+	}
+	p.rest = gw
+	go func() {
+		defer close(gw.done)
+		if err := gw.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("REST gateway stopped: %v", err)
+		}
+	}()
+
+	log.Printf("Serving REST gateway on http://%s/", gw.server.Addr)
+	return nil
+}
+
+// stopREST closes the REST gateway, if running
+func (p *Plugin) stopREST() {
+	p.mu.Lock()
+	gw := p.rest
+	p.rest = nil
+	p.mu.Unlock()
+
+	if gw != nil {
+		_ = gw.server.Close()
+	}
+}
+
+// waitREST blocks until the REST gateway stops or ctx is done. It returns
+// at once if the gateway is not running.
+func (p *Plugin) waitREST(ctx context.Context) {
+	p.mu.RLock()
+	gw := p.rest
+	p.mu.RUnlock()
+
+	if gw == nil {
+		return
+	}
+	select {
+	case <-gw.done:
+	case <-ctx.Done():
+	}
+}
+
+// restHandler routes the REST API to the plugin's methods. Every request,
+// GETs included, must be JSON.
+func (p *Plugin) restHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /cameras", func(w http.ResponseWriter, r *http.Request) {
+		p.restCall(w, "list_cameras", nil)
+	})
+	mux.HandleFunc("GET /cameras/{id}", func(w http.ResponseWriter, r *http.Request) {
+		p.restCall(w, "get_camera", map[string]string{"camera_id": r.PathValue("id")})
+	})
+	mux.HandleFunc("POST /cameras/{id}/ptz", p.restPTZ)
+	mux.HandleFunc("GET /cameras/{id}/snapshot", p.restSnapshot)
+
+	return restGuard(mux, token, true)
+}
+
+// restGuard wraps the handler of a local HTTP server. Requests must be
+// JSON, so browsers cannot send one from another site without a CORS
+// preflight, which is not answered; GETs only if jsonGET is set. Without a
+// token they must also name a loopback Host, so a DNS rebinding page cannot
+// reach the server; with one they must carry it as a bearer token.
+func restGuard(next http.Handler, token string, jsonGET bool) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" && !restLoopbackHost(r.Host) {
+			writeRESTError(w, http.StatusForbidden, "host "+r.Host+" is not allowed", nil)
+			return
+		}
+		if r.Method != http.MethodGet || jsonGET {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeRESTError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json", nil)
+				return
+			}
+		}
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reolink-plugin"`)
+			writeRESTError(w, http.StatusUnauthorized, "unauthorized", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// restLoopbackHost reports whether a Host header, with or without a port,
+// names a loopback address
+func restLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+	}
+	if host == "" {
+		return false
+	}
+	_, ok := loopbackHost(host)
+	return ok
+}
+
+// restPTZ moves a camera; the body is a PTZ command as taken by ptz_control
+func (p *Plugin) restPTZ(w http.ResponseWriter, r *http.Request) {
+	var cmd PTZCommand
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRESTBodySize)).Decode(&cmd); err != nil {
+		writeRESTError(w, http.StatusBadRequest, "invalid PTZ command: "+err.Error(), nil)
+		return
+	}
+	p.restCall(w, "ptz_control", map[string]interface{}{"camera_id": r.PathValue("id"), "command": cmd})
+}
+
+// restSnapshot returns a camera's snapshot as a JPEG image
+func (p *Plugin) restSnapshot(w http.ResponseWriter, r *http.Request) {
+	result, ok := p.restResult(w, "get_snapshot", map[string]string{"camera_id": r.PathValue("id")})
+	if !ok {
+		return
+	}
+	encoded, _ := result.(string)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, "invalid snapshot: "+err.Error(), nil)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(data)
+}
+
+// restCall runs a method and writes its result or error as JSON
+func (p *Plugin) restCall(w http.ResponseWriter, method string, params interface{}) {
+	if result, ok := p.restResult(w, method, params); ok {
+		writeRESTJSON(w, http.StatusOK, result)
+	}
+}
+
+// restResult runs a method and returns its result. On failure it writes the
+// error and returns false.
+func (p *Plugin) restResult(w http.ResponseWriter, method string, params interface{}) (interface{}, bool) {
+	req := JSONRPCRequest{JSONRPC: "2.0", ID: "rest", Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			writeRESTError(w, http.StatusBadRequest, err.Error(), nil)
+			return nil, false
+		}
+		req.Params = data
+	}
+
+	resp := p.HandleRequest(req)
+	if resp.Error != nil {
+		writeRESTError(w, restStatus(resp.Error), resp.Error.Message, resp.Error.Data)
+		return nil, false
+	}
+	return resp.Result, true
+}
+
+// restStatus maps a JSON-RPC error to an HTTP status, using the error
+// catalog's kind where there is one
+func restStatus(rpcErr *JSONRPCError) int {
+	switch rpcErr.Code {
+	case -32601:
+		return http.StatusNotFound
+	case -32602:
+		return http.StatusBadRequest
+	}
+
+	detail, ok := rpcErr.Data.(*ErrorDetail)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	switch detail.Kind {
+	case "camera_not_found":
+		return http.StatusNotFound
+	case "invalid_parameter":
+		return http.StatusBadRequest
+	case "read_only", "raw_commands_disabled":
+		return http.StatusForbidden
+	case "timeout":
+		return http.StatusGatewayTimeout
+	default:
+		// The other kinds describe failures of the camera
+		return http.StatusBadGateway
+	}
+}
+
+// writeRESTError writes an error body with the catalog's detail, if any
+func writeRESTError(w http.ResponseWriter, status int, message string, detail interface{}) {
+	body := map[string]interface{}{"error": strings.TrimSpace(message)}
+	if detail != nil {
+		body["detail"] = detail
+	}
+	writeRESTJSON(w, status, body)
+}
+
+func writeRESTJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write REST response: %v", err)
+	}
+}
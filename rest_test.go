@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// restRequest sends a JSON request to the REST gateway
+func restRequest(method, url, body string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return http.DefaultClient.Do(req)
+}
+
+func TestRESTAddr(t *testing.T) {
+	tests := []struct {
+		addr, token, want string
+		wantErr           bool
+	}{
+		{":8080", "", "127.0.0.1:8080", false},
+		{"localhost:8080", "", "localhost:8080", false},
+		{"[::1]:8080", "", "[::1]:8080", false},
+		{"0.0.0.0:8080", "", "", true},
+		{"0.0.0.0:8080", "secret", "0.0.0.0:8080", false},
+		{":8080", "secret", ":8080", false},
+		{"8080", "secret", "", true},
+	}
+	for _, tt := range tests {
+		got, err := restAddr(tt.addr, tt.token)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("restAddr(%q, %q) error = %v, wantErr %v", tt.addr, tt.token, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("restAddr(%q, %q) = %q, want %q", tt.addr, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestREST_Gateway(t *testing.T) {
+	snapshot := []byte("\xff\xd8\xff\xe0 not really a jpeg \xff\xd9")
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLC-823A", Password: "secret", PTZ: true, Snapshot: snapshot})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"rest_addr":              "localhost:0",
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+	base := "http://" + plugin.rest.server.Addr
+	cameraID := host + "_ch0"
+
+	resp, err := restRequest(http.MethodGet, base+"/cameras", "")
+	if err != nil {
+		t.Fatalf("GET /cameras failed: %v", err)
+	}
+	var cameras []PluginCamera
+	_ = json.NewDecoder(resp.Body).Decode(&cameras)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(cameras) != 1 || cameras[0].ID != cameraID {
+		t.Fatalf("Unexpected cameras (%d): %+v", resp.StatusCode, cameras)
+	}
+
+	resp, err = restRequest(http.MethodGet, base+"/cameras/"+cameraID+"/snapshot", "")
+	if err != nil {
+		t.Fatalf("GET snapshot failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/jpeg" || !bytes.Equal(body, snapshot) {
+		t.Errorf("Unexpected snapshot (%d, %s): %q", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	resp, err = restRequest(http.MethodPost, base+"/cameras/"+cameraID+"/ptz", `{"action": "pan", "direction": 1}`)
+	if err != nil {
+		t.Fatalf("POST ptz failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for PTZ, got %d", resp.StatusCode)
+	}
+	if cmds := sim.PTZCommands(); len(cmds) != 1 || cmds[0].Op != "Right" {
+		t.Errorf("Unexpected PTZ commands: %+v", cmds)
+	}
+
+	// Unknown cameras are 404s with the catalog's detail
+	resp, err = restRequest(http.MethodGet, base+"/cameras/missing", "")
+	if err != nil {
+		t.Fatalf("GET missing camera failed: %v", err)
+	}
+	var failure struct {
+		Error  string       `json:"error"`
+		Detail *ErrorDetail `json:"detail"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&failure)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || failure.Detail == nil || failure.Detail.Kind != "camera_not_found" {
+		t.Errorf("Unexpected response for a missing camera (%d): %+v", resp.StatusCode, failure)
+	}
+
+	resp, err = restRequest(http.MethodPost, base+"/cameras/"+cameraID+"/ptz", `{"action":`)
+	if err != nil {
+		t.Fatalf("POST bad ptz failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad PTZ command, got %d", resp.StatusCode)
+	}
+
+	// Other methods are only served on stdin
+	resp, err = restRequest(http.MethodPost, base+"/rpc", `{"jsonrpc": "2.0", "id": 7, "method": "health"}`)
+	if err != nil {
+		t.Fatalf("POST /rpc failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for /rpc, got %d", resp.StatusCode)
+	}
+}
+
+func TestREST_ReadOnly(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.readOnly = true
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Yard", "RLC-823A", "localhost", 0, nil)
+
+	server := httptest.NewServer(plugin.restHandler(""))
+	defer server.Close()
+
+	resp, err := restRequest(http.MethodPost, server.URL+"/cameras/cam_1/ptz", `{"action": "pan", "direction": 1}`)
+	if err != nil {
+		t.Fatalf("POST ptz failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 in read-only mode, got %d", resp.StatusCode)
+	}
+}
+
+func TestREST_Token(t *testing.T) {
+	plugin := NewPlugin()
+	server := httptest.NewServer(plugin.restHandler("secret"))
+	defer server.Close()
+
+	for auth, want := range map[string]int{"": http.StatusUnauthorized, "Bearer wrong": http.StatusUnauthorized, "Bearer secret": http.StatusOK} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/cameras", nil)
+		req.Header.Set("Content-Type", "application/json")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /cameras failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Authorization %q: expected %d, got %d", auth, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestREST_RequestChecks(t *testing.T) {
+	plugin := NewPlugin()
+	server := httptest.NewServer(plugin.restHandler(""))
+	defer server.Close()
+
+	tests := []struct {
+		name, host, contentType string
+		want                    int
+	}{
+		{"json", "", "application/json", http.StatusOK},
+		{"json with charset", "", "application/json; charset=utf-8", http.StatusOK},
+		{"localhost", "localhost:8080", "application/json", http.StatusOK},
+		{"ipv6 loopback", "[::1]:8080", "application/json", http.StatusOK},
+		{"no content type", "", "", http.StatusUnsupportedMediaType},
+		{"form", "", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"text", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"rebound name", "attacker.example:8080", "application/json", http.StatusForbidden},
+		{"lan address", "192.168.1.10:8080", "application/json", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/cameras", nil)
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: GET /cameras failed: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, resp.StatusCode)
+		}
+	}
+
+	// With a token the gateway may be on another address
+	server = httptest.NewServer(plugin.restHandler("secret"))
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/cameras", nil)
+	req.Host = "192.168.1.10:8080"
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /cameras failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with a token on a LAN address, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// maxMessageSize is the largest JSON-RPC message read from the host
+const maxMessageSize = 10 * 1024 * 1024
+
+// errMessageTooLarge is returned for messages over the size limit
+var errMessageTooLarge = errors.New("message too large")
+
+// sizeLimitReader fails once more than n bytes are read since the last reset
+type sizeLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errMessageTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// messageReader reads JSON values from a stream. Messages may span lines;
+// after malformed or oversized input it skips ahead to the next line that
+// starts a new object.
+type messageReader struct {
+	src     *bufio.Reader
+	limit   *sizeLimitReader
+	dec     *json.Decoder
+	maxSize int64
+}
+
+func newMessageReader(r io.Reader, maxSize int64) *messageReader {
+	m := &messageReader{src: bufio.NewReader(r), maxSize: maxSize}
+	m.reset()
+	return m
+}
+
+// reset starts a new decoder at the current position of src
+func (m *messageReader) reset() {
+	m.limit = &sizeLimitReader{r: m.src}
+	m.dec = json.NewDecoder(m.limit)
+}
+
+// isParseError reports whether err from Next describes skipped input, after
+// which reading can continue
+func isParseError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr) || errors.Is(err, errMessageTooLarge) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Next returns the next raw message, io.EOF at the end of the input, a parse
+// error for skipped input, or the error from reading the input.
+func (m *messageReader) Next() (json.RawMessage, error) {
+	m.limit.n = m.maxSize
+
+	var raw json.RawMessage
+	err := m.dec.Decode(&raw)
+	if err == nil && int64(len(raw)) <= m.maxSize {
+		return raw, nil
+	}
+	if err != nil && !isParseError(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		return nil, fmt.Errorf("%w: more than %d bytes", errMessageTooLarge, m.maxSize)
+	}
+
+	// The decoder cannot continue past bad input, so drop the rest of the
+	// message and decode afresh from the next one
+	m.resync(m.dec.Buffered())
+	if errors.Is(err, errMessageTooLarge) {
+		return nil, fmt.Errorf("%w: more than %d bytes", errMessageTooLarge, m.maxSize)
+	}
+	return nil, err
+}
+
+// resync discards the message starting in the buffered bytes, up to the next
+// line starting with '{', the first line of a compact or indented message
+func (m *messageReader) resync(buffered io.Reader) {
+	m.src = bufio.NewReader(io.MultiReader(buffered, m.src))
+	defer m.reset()
+
+	// Skip whitespace left after the previous message to reach the bad one
+	for {
+		b, err := m.src.ReadByte()
+		if err != nil {
+			return
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			break
+		}
+	}
+	for {
+		// Discard the rest of the line without holding it in memory
+		for {
+			_, err := m.src.ReadSlice('\n')
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				return
+			}
+		}
+		next, err := m.src.Peek(1)
+		if err != nil || next[0] == '{' {
+			return
+		}
+	}
+}
+
+// parseRequest validates a raw message as a JSON-RPC 2.0 request. It reports
+// whether the request is a notification, which has no id member. An invalid
+// request is returned with its id where one could be read, for the error.
+func parseRequest(raw json.RawMessage) (JSONRPCRequest, bool, error) {
+	var req JSONRPCRequest
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return req, false, errors.New("request must be an object")
+	}
+
+	idRaw, hasID := fields["id"]
+	if hasID {
+		if err := json.Unmarshal(idRaw, &req.ID); err != nil {
+			return req, false, errors.New("invalid id")
+		}
+		switch req.ID.(type) {
+		case nil, string, float64:
+		default:
+			req.ID = nil
+			return req, false, errors.New("id must be a string, number or null")
+		}
+	}
+	id := req.ID
+
+	if err := json.Unmarshal(raw, &req); err != nil {
+		req.ID = id
+		return req, false, fmt.Errorf("invalid member: %v", err)
+	}
+	if req.JSONRPC != "2.0" {
+		return req, false, errors.New(`jsonrpc must be "2.0"`)
+	}
+	if req.Method == "" {
+		return req, false, errors.New("method is required")
+	}
+	if params := bytes.TrimSpace(req.Params); len(params) > 0 && params[0] != '{' && params[0] != '[' && !bytes.Equal(params, []byte("null")) {
+		return req, false, errors.New("params must be an object or array")
+	}
+	return req, !hasID, nil
+}
+
+// canonicalMethod maps a method name as sent by the host to the snake_case
+// name the plugin handles: prefix is stripped, and camelCase names such as
+// listCameras or getPTZPresets become list_cameras and get_ptz_presets.
+func canonicalMethod(method, prefix string) string {
+	if prefix != "" {
+		method = strings.TrimPrefix(method, prefix)
+	}
+
+	var b strings.Builder
+	runes := []rune(method)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a word after a lower-case letter or digit, and at the last
+			// capital of an acronym followed by a lower-case letter
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// serve reads JSON-RPC requests from in and writes the responses until in is
+// exhausted. Malformed input is answered with a -32700 parse error, invalid
+// requests with -32600, and notifications are handled without a reply.
+func (p *Plugin) serve(in io.Reader) error {
+	reader := newMessageReader(in, maxMessageSize)
+	for {
+		raw, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		p.touchHost()
+		if err != nil && !isParseError(err) {
+			return err
+		}
+		if err != nil {
+			log.Printf("Failed to parse request: %v", err)
+			p.writeErrorResponse(nil, -32700, "Parse error: "+err.Error())
+			continue
+		}
+
+		req, notification, err := parseRequest(raw)
+		if err != nil {
+			log.Printf("Invalid request %s: %v", truncate(string(raw), 200), err)
+			p.writeErrorResponse(req.ID, -32600, "Invalid Request: "+err.Error())
+			continue
+		}
+
+		// A long-polling get_events must not hold up the requests behind it
+		if p.isLongPoll(req) {
+			go p.respond(req, notification)
+			continue
+		}
+		p.respond(req, notification)
+	}
+}
+
+// respond handles a request and writes its response, unless it is a
+// notification
+func (p *Plugin) respond(req JSONRPCRequest, notification bool) {
+	resp := p.HandleRequest(req)
+	if notification {
+		if resp.Error != nil {
+			log.Printf("Notification %s failed: %s", req.Method, resp.Error.Message)
+		}
+		return
+	}
+	if err := p.writeMessage(resp); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// isLongPoll reports whether req is a get_events or get_event_media request
+// that waits for events or snapshots
+func (p *Plugin) isLongPoll(req JSONRPCRequest) bool {
+	switch canonicalMethod(req.Method, p.methodPrefix) {
+	case "get_events", "get_event_media":
+	default:
+		return false
+	}
+	var params struct {
+		WaitMs int `json:"wait_ms"`
+	}
+	return json.Unmarshal(req.Params, &params) == nil && params.WaitMs > 0
+}
+
+// writeErrorResponse reports an error for a message that could not be handled
+func (p *Plugin) writeErrorResponse(id interface{}, code int, message string) {
+	err := p.writeMessage(JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &JSONRPCError{Code: code, Message: message},
+	})
+	if err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// truncate shortens s to at most n bytes for logging
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveLines runs input through serve and decodes each response line
+func serveLines(t *testing.T, input string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	if err := plugin.serve(strings.NewReader(input)); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	var resps []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Invalid response line %q: %v", line, err)
+		}
+		resps = append(resps, resp)
+	}
+	return resps
+}
+
+func errorCode(resp map[string]interface{}) float64 {
+	e, _ := resp["error"].(map[string]interface{})
+	code, _ := e["code"].(float64)
+	return code
+}
+
+func TestServe_MultiLine(t *testing.T) {
+	input := `{"jsonrpc": "2.0", "id": 1, "method": "health"}
+{
+  "jsonrpc": "2.0",
+  "id": 2,
+  "method": "health",
+  "params": {}
+}
+{"jsonrpc": "2.0", "id": 3, "method": "health"} {"jsonrpc": "2.0", "id": 4, "method": "health"}
+
+`
+	resps := serveLines(t, input)
+	if len(resps) != 4 {
+		t.Fatalf("Expected 4 responses, got %d: %v", len(resps), resps)
+	}
+	for i, resp := range resps {
+		if resp["id"] != float64(i+1) || resp["error"] != nil {
+			t.Errorf("Unexpected response %d: %v", i, resp)
+		}
+	}
+}
+
+func TestServe_ParseErrors(t *testing.T) {
+	// Lines not starting an object are skipped while recovering from bad input
+	input := `{"jsonrpc": "2.0", "id": 1, "method": "health"}
+42
+{"jsonrpc": "2.0", "id": oops}
+{
+  "jsonrpc": "2.0",
+  "id": ,
+  "method": "health"
+}
+  "dropped": true
+{"jsonrpc": "2.0", "id": 2}
+{"jsonrpc": "2.0", "id": 3, "method": "health"}
+{"jsonrpc": "2.0", "id": 4, "method":`
+
+	resps := serveLines(t, input)
+	want := []struct {
+		id   interface{}
+		code float64
+	}{
+		{float64(1), 0},
+		{nil, -32600},
+		{nil, -32700},
+		{nil, -32700},
+		{float64(2), -32600},
+		{float64(3), 0},
+		{nil, -32700},
+	}
+	if len(resps) != len(want) {
+		t.Fatalf("Expected %d responses, got %d: %v", len(want), len(resps), resps)
+	}
+	for i, w := range want {
+		if resps[i]["id"] != w.id || errorCode(resps[i]) != w.code {
+			t.Errorf("Response %d: expected id %v code %v, got %v", i, w.id, w.code, resps[i])
+		}
+		if _, ok := resps[i]["id"]; !ok {
+			t.Errorf("Response %d has no id member", i)
+		}
+	}
+}
+
+func TestServe_Compliance(t *testing.T) {
+	input := strings.Join([]string{
+		`{"jsonrpc": "2.0", "method": "health"}`,
+		`{"jsonrpc": "2.0", "method": "no_such_method"}`,
+		`{"jsonrpc": "2.0", "id": null, "method": "health"}`,
+		`{"jsonrpc": "1.0", "id": "a", "method": "health"}`,
+		`{"id": "b", "method": "health"}`,
+		`{"jsonrpc": "2.0", "id": {"x": 1}, "method": "health"}`,
+		`{"jsonrpc": "2.0", "id": "c", "method": "health", "params": 5}`,
+		`{"jsonrpc": "2.0", "id": "d", "method": 7}`,
+		`{"jsonrpc": "2.0", "method": ""}`,
+		`{"jsonrpc": "2.0", "id": "e", "method": "health"}`,
+	}, "\n")
+
+	resps := serveLines(t, input)
+	want := []struct {
+		id   interface{}
+		code float64
+	}{
+		{nil, 0},
+		{"a", -32600},
+		{"b", -32600},
+		{nil, -32600},
+		{"c", -32600},
+		{"d", -32600},
+		{nil, -32600},
+		{"e", 0},
+	}
+	if len(resps) != len(want) {
+		t.Fatalf("Expected %d responses, got %d: %v", len(want), len(resps), resps)
+	}
+	for i, w := range want {
+		if resps[i]["id"] != w.id || errorCode(resps[i]) != w.code {
+			t.Errorf("Response %d: expected id %v code %v, got %v", i, w.id, w.code, resps[i])
+		}
+		if _, ok := resps[i]["id"]; !ok {
+			t.Errorf("Response %d has no id member", i)
+		}
+		_, hasResult := resps[i]["result"]
+		if hasResult == (w.code != 0) {
+			t.Errorf("Response %d must have exactly one of result and error: %v", i, resps[i])
+		}
+	}
+}
+
+func TestServe_LongPoll(t *testing.T) {
+	var out syncBuffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+
+	in, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- plugin.serve(in) }()
+
+	_, _ = io.WriteString(w, `{"jsonrpc": "2.0", "id": 1, "method": "get_events", "params": {"wait_ms": 5000}}
+{"jsonrpc": "2.0", "id": 2, "method": "health"}
+`)
+
+	// The health check is answered while get_events waits
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), `"id":2`) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := out.String(); !strings.Contains(got, `"id":2`) || strings.Contains(got, `"id":1`) {
+		t.Fatalf("Expected only the health response, got %q", got)
+	}
+
+	plugin.emitEvent(Event{Type: EventMotion, CameraID: "cam1"})
+	deadline = time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), `"id":1`) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(out.String(), `"id":1,"result":{"events":[{"id":"cam1-1"`) {
+		t.Errorf("Expected get_events to return the event, got %q", out.String())
+	}
+
+	w.Close()
+	if err := <-done; err != nil {
+		t.Errorf("serve failed: %v", err)
+	}
+}
+
+func TestJSONRPCResponse_MarshalJSON(t *testing.T) {
+	data, _ := json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: 1})
+	if string(data) != `{"jsonrpc":"2.0","id":1,"result":null}` {
+		t.Errorf("Unexpected success response: %s", data)
+	}
+	data, _ = json.Marshal(JSONRPCResponse{JSONRPC: "2.0", Result: "ignored", Error: &JSONRPCError{Code: -32601, Message: "Method not found"}})
+	if string(data) != `{"jsonrpc":"2.0","id":null,"error":{"code":-32601,"message":"Method not found"}}` {
+		t.Errorf("Unexpected error response: %s", data)
+	}
+}
+
+func TestMessageReader_Oversized(t *testing.T) {
+	big := `{"jsonrpc": "2.0", "id": 1, "method": "health", "params": {"pad": "` + strings.Repeat("x", 4096) + `"}}`
+	input := big + "\n" + `{"jsonrpc": "2.0", "id": 2, "method": "health"}` + "\n"
+	reader := newMessageReader(strings.NewReader(input), 1024)
+
+	if _, err := reader.Next(); !errors.Is(err, errMessageTooLarge) {
+		t.Fatalf("Expected errMessageTooLarge, got %v", err)
+	}
+	raw, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Expected the next message after an oversized one, got %v", err)
+	}
+	if !strings.Contains(string(raw), `"id": 2`) {
+		t.Errorf("Unexpected message: %s", raw)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestServe_ReadError(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	if err := plugin.serve(failingReader{}); err == nil || isParseError(err) {
+		t.Errorf("Expected the read error to be returned, got %v", err)
+	}
+}
+
+func TestCanonicalMethod(t *testing.T) {
+	tests := []struct {
+		method, prefix, want string
+	}{
+		{"list_cameras", "", "list_cameras"},
+		{"listCameras", "", "list_cameras"},
+		{"ListCameras", "", "list_cameras"},
+		{"getPTZPresets", "", "get_ptz_presets"},
+		{"ptzControl", "", "ptz_control"},
+		{"transfer.begin", "", "transfer.begin"},
+		{"reolink.getSnapshot", "reolink.", "get_snapshot"},
+		{"reolink/health", "reolink/", "health"},
+		{"health", "reolink.", "health"},
+	}
+	for _, tt := range tests {
+		if got := canonicalMethod(tt.method, tt.prefix); got != tt.want {
+			t.Errorf("canonicalMethod(%q, %q) = %q, want %q", tt.method, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestHandleRequest_MethodAliases(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.methodPrefix = "reolink."
+
+	for _, method := range []string{"health", "reolink.health", "reolink.Health", "listCameras"} {
+		if resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: method}); resp.Error != nil {
+			t.Errorf("%s: unexpected error %v", method, resp.Error.Message)
+		}
+	}
+
+	// Errors name the method as the host sent it
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "reolink.noSuchMethod"})
+	if resp.Error == nil || resp.Error.Message != "Method not found: reolink.noSuchMethod" {
+		t.Errorf("Unexpected response: %+v", resp.Error)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Scrypted device types and interfaces the adapter reports
+const (
+	scryptedTypeCamera   = "Camera"
+	scryptedTypeDoorbell = "Doorbell"
+
+	scryptedCamera         = "Camera"
+	scryptedVideoCamera    = "VideoCamera"
+	scryptedMotionSensor   = "MotionSensor"
+	scryptedOnline         = "Online"
+	scryptedObjectDetector = "ObjectDetector"
+	scryptedPanTiltZoom    = "PanTiltZoom"
+	scryptedBinarySensor   = "BinarySensor"
+	scryptedOnOff          = "OnOff"
+)
+
+// scryptedMethods maps the interface methods the adapter answers to the
+// interface that provides them
+var scryptedMethods = map[string]string{
+	"takePicture":           scryptedCamera,
+	"getVideoStreamOptions": scryptedVideoCamera,
+	"getVideoStream":        scryptedVideoCamera,
+	"getObjectTypes":        scryptedObjectDetector,
+	"ptzCommand":            scryptedPanTiltZoom,
+	"turnOn":                scryptedOnOff,
+	"turnOff":               scryptedOnOff,
+}
+
+// ScryptedDevice is a camera as Scrypted describes its devices: a type, the
+// interfaces it implements and their state properties
+type ScryptedDevice struct {
+	ID         string             `json:"id"`
+	NativeID   string             `json:"nativeId"`
+	ProviderID string             `json:"providerId"`
+	Name       string             `json:"name"`
+	Type       string             `json:"type"`
+	Interfaces []string           `json:"interfaces"`
+	Info       ScryptedDeviceInfo `json:"info"`
+
+	Online          bool                     `json:"online"`
+	MotionDetected  bool                     `json:"motionDetected"`
+	BinaryState     *bool                    `json:"binaryState,omitempty"` // doorbell pressed
+	PTZCapabilities *ScryptedPTZCapabilities `json:"ptzCapabilities,omitempty"`
+}
+
+// ScryptedDeviceInfo is the info property of a Scrypted device
+type ScryptedDeviceInfo struct {
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Firmware     string `json:"firmware,omitempty"`
+	MAC          string `json:"mac,omitempty"`
+	IP           string `json:"ip,omitempty"`
+}
+
+// ScryptedPTZCapabilities is the ptzCapabilities property of PanTiltZoom
+type ScryptedPTZCapabilities struct {
+	Pan  bool `json:"pan"`
+	Tilt bool `json:"tilt"`
+	Zoom bool `json:"zoom"`
+}
+
+// ScryptedStreamOptions is a stream as returned by getVideoStreamOptions
+type ScryptedStreamOptions struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Container string               `json:"container"`
+	Video     *ScryptedVideoFormat `json:"video,omitempty"`
+	Audio     *ScryptedAudioFormat `json:"audio,omitempty"`
+}
+
+// ScryptedVideoFormat is the video part of ScryptedStreamOptions
+type ScryptedVideoFormat struct {
+	Codec  string `json:"codec,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	FPS    int    `json:"fps,omitempty"`
+}
+
+// ScryptedAudioFormat is the audio part of ScryptedStreamOptions
+type ScryptedAudioFormat struct {
+	Codec      string `json:"codec"`
+	SampleRate int    `json:"sampleRate"`
+}
+
+// ScryptedPTZCommand is the argument of ptzCommand: pan, tilt and zoom
+// between -1 and 1, or a preset
+type ScryptedPTZCommand struct {
+	Pan    float64 `json:"pan,omitempty"`
+	Tilt   float64 `json:"tilt,omitempty"`
+	Zoom   float64 `json:"zoom,omitempty"`
+	Preset string  `json:"preset,omitempty"`
+}
+
+// scryptedAddr validates a Scrypted adapter listen address. The adapter has
+// no authentication, so only loopback addresses are accepted; a bare ":port"
+// binds to 127.0.0.1.
+func scryptedAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid Scrypted address %q: %w", addr, err)
+	}
+	host, ok := loopbackHost(host)
+	if !ok {
+		return "", fmt.Errorf("Scrypted address %q must be on localhost", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// startScrypted serves the Scrypted-style adapter until Shutdown, so users
+// moving from Scrypted can compare what both report for their cameras
+func (p *Plugin) startScrypted(addr string) error {
+	addr, err := scryptedAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.scrypted != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for Scrypted requests: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices", p.scryptedDevices)
+	mux.HandleFunc("GET /devices/{id}", p.scryptedDevice)
+	mux.HandleFunc("POST /devices/{id}/{method}", p.scryptedCall)
+
+	// getVideoStream answers stream URLs with the device credentials, so
+	// the adapter is guarded like the REST gateway
+	handler := restGuard(mux, "", false)
+	p.scrypted = &http.Server{Addr: listener.Addr().String(), Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Scrypted adapter stopped: %v", err)
+		}
+	}(p.scrypted)
+
+	log.Printf("Serving Scrypted adapter on http://%s/devices", p.scrypted.Addr)
+	return nil
+}
+
+// stopScrypted closes the Scrypted adapter, if running
+func (p *Plugin) stopScrypted() {
+	p.mu.Lock()
+	srv := p.scrypted
+	p.scrypted = nil
+	p.mu.Unlock()
+
+	if srv != nil {
+		_ = srv.Close()
+	}
+}
+
+// scryptedDevices lists the cameras as Scrypted devices, ordered by ID
+func (p *Plugin) scryptedDevices(w http.ResponseWriter, r *http.Request) {
+	cameras := p.ListCameras()
+	sort.Slice(cameras, func(i, j int) bool { return cameras[i].ID < cameras[j].ID })
+	devices := make([]*ScryptedDevice, 0, len(cameras))
+	for i := range cameras {
+		devices = append(devices, p.scryptedDeviceOf(&cameras[i]))
+	}
+	writeRESTJSON(w, http.StatusOK, devices)
+}
+
+func (p *Plugin) scryptedDevice(w http.ResponseWriter, r *http.Request) {
+	cam, ok := p.scryptedCamera(w, r)
+	if ok {
+		writeRESTJSON(w, http.StatusOK, p.scryptedDeviceOf(cam))
+	}
+}
+
+// scryptedCamera looks up the camera of a request. On failure it writes the
+// error and returns false.
+func (p *Plugin) scryptedCamera(w http.ResponseWriter, r *http.Request) (*PluginCamera, bool) {
+	result, ok := p.restResult(w, "get_camera", map[string]string{"camera_id": r.PathValue("id")})
+	if !ok {
+		return nil, false
+	}
+	return result.(*PluginCamera), true
+}
+
+// scryptedDeviceOf describes a camera as a Scrypted device. Motion and
+// doorbell state come from the latest queued events.
+func (p *Plugin) scryptedDeviceOf(cam *PluginCamera) *ScryptedDevice {
+	device := &ScryptedDevice{
+		ID:         cam.ID,
+		NativeID:   cam.ID,
+		ProviderID: cam.PluginID,
+		Name:       cam.Name,
+		Type:       scryptedTypeCamera,
+		Interfaces: []string{scryptedCamera, scryptedVideoCamera, scryptedMotionSensor, scryptedOnline},
+		Info: ScryptedDeviceInfo{
+			Manufacturer: "Reolink",
+			Model:        cam.Model,
+			SerialNumber: cam.Serial,
+			Firmware:     cam.FirmwareVersion,
+			MAC:          cam.MAC,
+			IP:           cam.Host,
+		},
+		Online:         cam.Online,
+		MotionDetected: p.eventActive(cam.ID, EventMotion),
+	}
+
+	features := cam.Features
+	if len(features.AI) > 0 {
+		device.Interfaces = append(device.Interfaces, scryptedObjectDetector)
+	}
+	if features.PTZ != nil {
+		device.Interfaces = append(device.Interfaces, scryptedPanTiltZoom)
+		device.PTZCapabilities = &ScryptedPTZCapabilities{Pan: features.PTZ.Pan, Tilt: features.PTZ.Tilt, Zoom: features.PTZ.Zoom}
+	}
+	if features.Doorbell {
+		device.Type = scryptedTypeDoorbell
+		device.Interfaces = append(device.Interfaces, scryptedBinarySensor)
+		pressed := p.eventActive(cam.ID, EventDoorbell)
+		device.BinaryState = &pressed
+	}
+	if features.Light {
+		device.Interfaces = append(device.Interfaces, scryptedOnOff)
+	}
+	return device
+}
+
+// eventActive reports whether the latest queued event of a type on a camera
+// is a start
+func (p *Plugin) eventActive(cameraID, eventType string) bool {
+	events := p.events.since(0, cameraID, 0)
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == eventType {
+			return events[i].State == EventStart
+		}
+	}
+	return false
+}
+
+// scryptedCall runs an interface method of a device. The body is the JSON
+// array of its arguments, as Scrypted passes them; the answer is
+// {"result": ...}, or the JPEG itself for takePicture.
+func (p *Plugin) scryptedCall(w http.ResponseWriter, r *http.Request) {
+	cam, ok := p.scryptedCamera(w, r)
+	if !ok {
+		return
+	}
+	method := r.PathValue("method")
+	iface, known := scryptedMethods[method]
+	if !known {
+		writeRESTError(w, http.StatusNotFound, "unknown method "+method, nil)
+		return
+	}
+	if !contains(p.scryptedDeviceOf(cam).Interfaces, iface) {
+		writeRESTError(w, http.StatusNotFound, fmt.Sprintf("%s does not implement %s", cam.ID, iface), nil)
+		return
+	}
+
+	var args []json.RawMessage
+	if body, _ := io.ReadAll(io.LimitReader(r.Body, maxRESTBodySize)); len(body) > 0 {
+		if err := json.Unmarshal(body, &args); err != nil {
+			writeRESTError(w, http.StatusBadRequest, "arguments must be a JSON array: "+err.Error(), nil)
+			return
+		}
+	}
+
+	switch method {
+	case "takePicture":
+		p.restSnapshot(w, r)
+
+	case "getVideoStreamOptions":
+		writeRESTJSON(w, http.StatusOK, map[string]interface{}{"result": scryptedStreamOptions(cam)})
+
+	case "getVideoStream":
+		var options struct {
+			ID string `json:"id"`
+		}
+		if len(args) > 0 && json.Unmarshal(args[0], &options) != nil {
+			writeRESTError(w, http.StatusBadRequest, "invalid stream options", nil)
+			return
+		}
+		if options.ID == "" {
+			options.ID = "main"
+		}
+		for _, stream := range scryptedStreamOptions(cam) {
+			if stream.ID == options.ID {
+				// The camera's own stream URLs follow its protocol, HLS by
+				// default, so the RTSP ones are built here
+				device, err := p.lookupCamera(cam.ID)
+				if err != nil {
+					writeRESTError(w, http.StatusNotFound, err.Error(), nil)
+					return
+				}
+				writeRESTJSON(w, http.StatusOK, map[string]interface{}{"result": map[string]interface{}{
+					"url": device.StreamURLForProtocol(stream.ID, stream.Container), "container": stream.Container, "mediaStreamOptions": stream,
+				}})
+				return
+			}
+		}
+		writeRESTError(w, http.StatusBadRequest, "unknown stream "+options.ID, nil)
+
+	case "getObjectTypes":
+		writeRESTJSON(w, http.StatusOK, map[string]interface{}{"result": map[string]interface{}{"classes": cam.Features.AI}})
+
+	case "ptzCommand":
+		var cmd ScryptedPTZCommand
+		if len(args) == 0 || json.Unmarshal(args[0], &cmd) != nil {
+			writeRESTError(w, http.StatusBadRequest, "invalid PTZ command", nil)
+			return
+		}
+		p.scryptedResult(w, "ptz_control", map[string]interface{}{"camera_id": cam.ID, "command": cmd.plugin()})
+
+	case "turnOn", "turnOff":
+		p.scryptedResult(w, "set_light", map[string]interface{}{"camera_id": cam.ID, "on": method == "turnOn"})
+	}
+}
+
+// scryptedResult runs a method and answers {"result": null}, as Scrypted
+// does for methods without a return value
+func (p *Plugin) scryptedResult(w http.ResponseWriter, method string, params interface{}) {
+	if _, ok := p.restResult(w, method, params); ok {
+		writeRESTJSON(w, http.StatusOK, map[string]interface{}{"result": nil})
+	}
+}
+
+// plugin converts the command to the plugin's PTZ command. Continuous
+// movement is along the first non-zero axis; all zero stops.
+func (cmd ScryptedPTZCommand) plugin() PTZCommand {
+	switch {
+	case cmd.Preset != "":
+		return PTZCommand{Action: "preset", Preset: cmd.Preset}
+	case cmd.Pan != 0:
+		return PTZCommand{Action: "pan", Direction: cmd.Pan}
+	case cmd.Tilt != 0:
+		return PTZCommand{Action: "tilt", Direction: cmd.Tilt}
+	case cmd.Zoom != 0:
+		return PTZCommand{Action: "zoom", Direction: cmd.Zoom}
+	default:
+		return PTZCommand{Action: "stop"}
+	}
+}
+
+// scryptedStreamOptions describes the RTSP streams of a camera, with the
+// encoder settings last read from it
+func scryptedStreamOptions(cam *PluginCamera) []ScryptedStreamOptions {
+	main := ScryptedStreamOptions{ID: "main", Name: "Main Stream", Container: "rtsp"}
+	sub := ScryptedStreamOptions{ID: "sub", Name: "Sub Stream", Container: "rtsp"}
+	if enc := cam.Encoder; enc != nil {
+		main.Video = scryptedVideo(enc.MainStream)
+		sub.Video = scryptedVideo(enc.SubStream)
+		if enc.Audio {
+			audio := &ScryptedAudioFormat{Codec: reolink.AudioCodec, SampleRate: reolink.AudioSampleRate}
+			main.Audio, sub.Audio = audio, audio
+		}
+	}
+	return []ScryptedStreamOptions{main, sub}
+}
+
+func scryptedVideo(cfg reolink.StreamConfig) *ScryptedVideoFormat {
+	return &ScryptedVideoFormat{Codec: cfg.Codec, Width: cfg.Width, Height: cfg.Height, FPS: cfg.FrameRate}
+}
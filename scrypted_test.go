@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestScryptedAddr(t *testing.T) {
+	if addr, err := scryptedAddr(":8091"); err != nil || addr != "127.0.0.1:8091" {
+		t.Errorf("Expected 127.0.0.1:8091, got %q, %v", addr, err)
+	}
+	if _, err := scryptedAddr("0.0.0.0:8091"); err == nil {
+		t.Error("Expected an error for a non-loopback address")
+	}
+}
+
+func TestScrypted_Adapter(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "RLC-823A", Password: "secret", PTZ: true, AI: true})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"scrypted_addr":          "localhost:0",
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+	base := "http://" + plugin.scrypted.Addr
+	cameraID := host + "_ch0"
+
+	plugin.emitEvent(Event{Type: EventMotion, State: EventStart, CameraID: cameraID})
+
+	resp, err := http.Get(base + "/devices")
+	if err != nil {
+		t.Fatalf("GET /devices failed: %v", err)
+	}
+	var devices []ScryptedDevice
+	_ = json.NewDecoder(resp.Body).Decode(&devices)
+	resp.Body.Close()
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 device, got %+v", devices)
+	}
+	device := devices[0]
+	if device.ID != cameraID || device.Type != scryptedTypeCamera || !device.Online || !device.MotionDetected ||
+		device.Info.Manufacturer != "Reolink" || device.PTZCapabilities == nil {
+		t.Errorf("Unexpected device %+v", device)
+	}
+	for _, iface := range []string{scryptedCamera, scryptedVideoCamera, scryptedObjectDetector, scryptedPanTiltZoom} {
+		if !contains(device.Interfaces, iface) {
+			t.Errorf("Expected interface %s, got %v", iface, device.Interfaces)
+		}
+	}
+
+	call := func(method, args string) (int, map[string]json.RawMessage) {
+		t.Helper()
+		resp, err := http.Post(base+"/devices/"+cameraID+"/"+method, "application/json", strings.NewReader(args))
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+		defer resp.Body.Close()
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	status, body := call("getVideoStream", `[{"id": "sub"}]`)
+	var stream struct {
+		URL       string `json:"url"`
+		Container string `json:"container"`
+	}
+	_ = json.Unmarshal(body["result"], &stream)
+	if status != http.StatusOK || stream.Container != "rtsp" ||
+		!strings.HasPrefix(stream.URL, "rtsp://") || !strings.Contains(stream.URL, "_sub") {
+		t.Errorf("Unexpected stream (%d): %s", status, body["result"])
+	}
+
+	if status, _ := call("ptzCommand", `[{"tilt": -0.5}]`); status != http.StatusOK {
+		t.Errorf("Expected 200 for ptzCommand, got %d", status)
+	}
+	if cmds := sim.PTZCommands(); len(cmds) != 1 || cmds[0].Op != "Down" {
+		t.Errorf("Unexpected PTZ commands: %+v", cmds)
+	}
+
+	// The camera has no spotlight
+	if status, _ := call("turnOn", ""); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for turnOn, got %d", status)
+	}
+	if status, _ := call("ptzCommand", `{"pan": 1}`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for arguments that are not an array, got %d", status)
+	}
+
+	resp, err = http.Post(base+"/devices/"+cameraID+"/takePicture", "application/json", nil)
+	if err != nil {
+		t.Fatalf("takePicture failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/jpeg" {
+		t.Errorf("Unexpected takePicture response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// A page on another site can send text/plain without a preflight
+	resp, err = http.Post(base+"/devices/"+cameraID+"/ptzCommand", "text/plain", strings.NewReader(`[{"pan": 1}]`))
+	if err != nil {
+		t.Fatalf("ptzCommand failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType || len(sim.PTZCommands()) != 1 {
+		t.Errorf("Expected 415 and no PTZ command for a text/plain body, got %d and %+v", resp.StatusCode, sim.PTZCommands())
+	}
+
+	// A DNS rebinding page reaches the adapter under its own name
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, _ := http.NewRequest(method, base+"/devices/"+cameraID+"/getVideoStream", strings.NewReader(`[{"id": "main"}]`))
+		if method == http.MethodGet {
+			req, _ = http.NewRequest(method, base+"/devices", nil)
+		}
+		req.Host = "attacker.example:8091"
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s with a foreign Host failed: %v", method, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden || strings.Contains(string(body), "secret") {
+			t.Errorf("Expected 403 for %s with a foreign Host, got %d: %s", method, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// TestPlugin_Simulator runs the plugin end to end against simulated devices
+func TestPlugin_Simulator(t *testing.T) {
+	camSim := reolinksim.New(reolinksim.Camera{Password: "secret", PTZ: true, AI: true})
+	camServer := httptest.NewServer(camSim)
+	defer camServer.Close()
+
+	nvrSim := reolinksim.New(reolinksim.Camera{Model: "RLN8-410", Name: "NVR", Channels: 2, Password: "secret", TokenOnly: true, LegacyEvents: true})
+	nvrServer := httptest.NewServer(nvrSim)
+	defer nvrServer.Close()
+
+	camHost, camPort := serverHostPort(camServer)
+	_, nvrPort := serverHostPort(nvrServer)
+	// Devices are keyed by host, so reach the NVR through a different loopback name
+	nvrHost := "localhost"
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(20),
+		"devices": []interface{}{
+			map[string]interface{}{"host": camHost, "port": float64(camPort), "username": "admin", "password": "secret"},
+			map[string]interface{}{"host": nvrHost, "port": float64(nvrPort), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	report := plugin.InitializeReport()
+	if report.Connected != 2 || report.Failed != 0 {
+		t.Fatalf("Expected both devices to connect, got %+v", report)
+	}
+	cameras := plugin.ListCameras()
+	if len(cameras) != 3 {
+		t.Fatalf("Expected 3 cameras, got %d", len(cameras))
+	}
+
+	camID := camHost + "_ch0"
+	got := plugin.GetCamera(camID)
+	if got == nil {
+		t.Fatalf("Camera %s not found", camID)
+	}
+	if got.Model != reolinksim.DefaultCamera.Model || got.MAC != reolinksim.DefaultCamera.MAC || !containsString(got.Capabilities, "ptz") {
+		t.Errorf("Unexpected camera: %+v", got)
+	}
+
+	ctx := context.Background()
+	snap, err := plugin.GetSnapshot(ctx, nvrHost+"_ch1")
+	if err != nil || len(snap) == 0 {
+		t.Errorf("GetSnapshot failed: %v", err)
+	}
+	if nvrSim.CommandCount("Login") == 0 {
+		t.Error("Expected the token-only NVR to use the Login API")
+	}
+
+	if err := plugin.PTZControl(ctx, camID, PTZCommand{Action: "pan", Direction: -1}); err != nil {
+		t.Fatalf("PTZControl failed: %v", err)
+	}
+	if cmds := camSim.PTZCommands(); len(cmds) != 1 || cmds[0].Op != "Left" {
+		t.Errorf("Unexpected PTZ commands: %+v", cmds)
+	}
+
+	// Motion on the NVR is polled through the legacy commands
+	nvrSim.SetMotion(1, true)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := plugin.GetEvents(0, nvrHost+"_ch1", 0)
+		if len(result.Events) > 0 {
+			if ev := result.Events[0]; ev.Type != EventMotion || ev.State != EventStart {
+				t.Errorf("Unexpected event: %+v", ev)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			data, _ := json.Marshal(result)
+			t.Fatalf("Expected a motion event, got %s", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The NVR rejects the AI commands, so its cameras lose the AI detection
+	// the model database gives them once probed
+	for containsString(plugin.GetCamera(nvrHost+"_ch1").Capabilities, "ai_detection") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the NVR camera to be probed without AI detection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !containsString(plugin.GetCamera(camID).Capabilities, "ai_detection") {
+		t.Error("Expected AI detection on the camera reporting AI state")
+	}
+}
+
+// TestPlugin_HomeHub adds the battery cameras paired to a Home Hub
+func TestPlugin_HomeHub(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Model: "Reolink Home Hub", Name: "Hub", Channels: 4, Password: "secret",
+		ChannelModels: []string{"Argus 4 Pro", "", "Reolink Video Doorbell Battery", ""}})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	plugin.ctx = context.Background()
+	if _, err := plugin.AddCamera(context.Background(), CameraConfig{Host: host, Port: port, Username: "admin", Password: "secret"}); err != nil {
+		t.Fatalf("AddCamera failed: %v", err)
+	}
+
+	cameras := plugin.ListCameras()
+	if len(cameras) != 2 {
+		t.Fatalf("Expected the 2 paired cameras, got %d", len(cameras))
+	}
+	garden := plugin.GetCamera(host + "_ch0")
+	door := plugin.GetCamera(host + "_ch2")
+	if garden == nil || door == nil {
+		t.Fatalf("Expected cameras on channels 0 and 2, got %+v", cameras)
+	}
+	if garden.Model != "Argus 4 Pro" || garden.Name != "Hub 1" || plugin.cameras[garden.ID].DeviceType() != "battery" {
+		t.Errorf("Unexpected hub camera: %+v", garden)
+	}
+	if plugin.cameras[door.ID].DeviceType() != "doorbell" {
+		t.Errorf("Expected a doorbell, got %+v", door)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
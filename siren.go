@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// SirenRequest reads or changes when a camera's siren sounds on its own.
+// Omitted fields are left unchanged; a request with none only reads.
+type SirenRequest struct {
+	CameraID string                   `json:"camera_id"`
+	Enabled  *bool                    `json:"enabled,omitempty"`
+	Triggers []string                 `json:"triggers,omitempty"` // "motion", "person", "vehicle", "animal"
+	Schedule []reolink.ScheduleWindow `json:"schedule,omitempty"` // [] arms the siren at all times
+}
+
+// ConfigureSiren reads or updates the alarm linkage of a camera's siren:
+// which detections sound it and during which hours. This is separate from
+// the audio clip the siren plays, which select_audio_clip chooses.
+func (p *Plugin) ConfigureSiren(ctx context.Context, req SirenRequest) (*reolink.SirenConfig, error) {
+	var cfg *reolink.SirenConfig
+	err := p.onCamera(ctx, req.CameraID, func(ctx context.Context, cam *Camera) (err error) {
+		if ability := cam.Ability(); ability != nil && !ability.AudioAlarm {
+			return fmt.Errorf("camera %s has no siren", req.CameraID)
+		}
+
+		cfg, err = cam.client.GetSirenConfig(ctx, cam.Channel())
+		if err != nil {
+			return fmt.Errorf("failed to read siren settings: %w", err)
+		}
+		if req.Enabled == nil && req.Triggers == nil && req.Schedule == nil {
+			return nil
+		}
+
+		if req.Enabled != nil {
+			cfg.Enabled = *req.Enabled
+		}
+		if req.Triggers != nil {
+			cfg.Triggers = req.Triggers
+		}
+		if req.Schedule != nil {
+			cfg.Schedule = req.Schedule
+		}
+		if err := cam.client.SetSirenConfig(ctx, cam.Channel(), *cfg); err != nil {
+			return err
+		}
+		log.Printf("Updated siren of camera %s: enabled=%v triggers=%v", req.CameraID, cfg.Enabled, cfg.Triggers)
+
+		cfg, err = cam.client.GetSirenConfig(ctx, cam.Channel())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_ConfigureSiren(t *testing.T) {
+	server := httptest.NewServer(reolinksim.New(reolinksim.Camera{Model: "RLC-811A", Password: "password"}))
+	defer server.Close()
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam_1", "Drive", "RLC-811A", "localhost", 0, client)
+	cam.SetAbility(&reolink.Ability{AudioAlarm: true})
+	plugin.cameras["cam_1"] = cam
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "configure_siren", Params: []byte(
+		`{"camera_id": "cam_1", "enabled": true, "triggers": ["person"], "schedule": [{"start": "22:00", "end": "06:00"}]}`)})
+	if resp.Error != nil {
+		t.Fatalf("configure_siren failed: %s", resp.Error.Message)
+	}
+	cfg := resp.Result.(*reolink.SirenConfig)
+	if !cfg.Enabled || !reflect.DeepEqual(cfg.Triggers, []string{"person"}) || len(cfg.Schedule) != 2 {
+		t.Errorf("Unexpected siren config: %+v", cfg)
+	}
+
+	// Omitted fields are kept; an empty schedule arms the siren at all times
+	cfg, err := plugin.ConfigureSiren(context.Background(), SirenRequest{CameraID: "cam_1", Schedule: []reolink.ScheduleWindow{}})
+	if err != nil {
+		t.Fatalf("ConfigureSiren failed: %v", err)
+	}
+	if !cfg.Enabled || !reflect.DeepEqual(cfg.Triggers, []string{"person"}) || cfg.Schedule != nil {
+		t.Errorf("Expected an always-armed person trigger, got %+v", cfg)
+	}
+
+	cam.SetAbility(&reolink.Ability{})
+	if _, err := plugin.ConfigureSiren(context.Background(), SirenRequest{CameraID: "cam_1"}); err == nil || !strings.Contains(err.Error(), "no siren") {
+		t.Errorf("Expected a missing siren error, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// unsafeFileChars matches characters not allowed in generated file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SnapshotFile describes a snapshot written to disk
+type SnapshotFile struct {
+	Path     string `json:"path"`
+	Filename string `json:"filename"`
+	Size     int    `json:"size"`
+}
+
+// SaveSnapshot writes a JPEG snapshot of a camera into dir and returns the file
+// written. dir must be an existing absolute directory chosen by the host.
+func (p *Plugin) SaveSnapshot(ctx context.Context, cameraID, dir string) (*SnapshotFile, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("path must be absolute: %s", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", dir)
+	}
+
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s-%s.jpg",
+		unsafeFileChars.ReplaceAllString(cameraID, "_"),
+		time.Now().UTC().Format("20060102T150405.000Z"))
+	path := filepath.Join(dir, filename)
+
+	// Stream straight to disk; a failed or oversized snapshot leaves no file
+	var size int64
+	var snapErr error
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+		return writeFileAtomicFrom(path, 0o644, func(w io.Writer) error {
+			size, snapErr = cam.client.StreamSnapshot(ctx, cam.Channel(), w)
+			return snapErr
+		})
+	})
+	if snapErr != nil {
+		return nil, snapErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SnapshotFile{Path: path, Filename: filename, Size: int(size)}, nil
+}
+
+// SnapshotOptionsRequest changes the snapshots of a camera's device. Quality
+// and size replace the current ones when any of them is set.
+type SnapshotOptionsRequest struct {
+	CameraID string `json:"camera_id"`
+	reolink.SnapshotOptions
+
+	// Timestamp shows or hides the OSD time on the camera's images. It is
+	// burned into the video, so streams and recordings change with it.
+	Timestamp *bool `json:"timestamp,omitempty"`
+}
+
+// SetSnapshotOptions sets the quality and size of the snapshots of a
+// camera's device, and the timestamp overlay of the camera, so stored alert
+// images look the same. It returns the device's snapshot options.
+func (p *Plugin) SetSnapshotOptions(ctx context.Context, req SnapshotOptionsRequest) (*reolink.SnapshotOptions, error) {
+	if err := req.SnapshotOptions.Validate(); err != nil {
+		return nil, err
+	}
+	cam, err := p.lookupCamera(req.CameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Timestamp != nil {
+		err := p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+			return cam.client.SetOSD(ctx, cam.Channel(), reolink.OSDSettings{ShowTime: req.Timestamp})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to set timestamp overlay: %w", err)
+		}
+	}
+	if req.SnapshotOptions != (reolink.SnapshotOptions{}) {
+		cam.client.SetSnapshotOptions(req.SnapshotOptions)
+		log.Printf("Set snapshot options of %s: %+v", cam.client.Host(), req.SnapshotOptions)
+	}
+
+	opts := cam.client.SnapshotOptions()
+	return &opts, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the target directory and
+// renames it into place, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicFrom(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFrom is writeFileAtomic with the contents produced by write
+func writeFileAtomicFrom(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	tmpName := tmp.Name()
+
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
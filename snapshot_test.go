@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestPlugin_SaveSnapshot(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 'j', 'p', 'e', 'g'}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cmd") != "Snap" {
+			t.Errorf("Unexpected cmd: %s", r.URL.Query().Get("cmd"))
+		}
+		_, _ = w.Write(jpeg)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam/1"] = NewCamera("cam/1", "Front", "RLC-810A", "localhost", 0, client)
+
+	dir := t.TempDir()
+	file, err := plugin.SaveSnapshot(context.Background(), "cam/1", dir)
+	if err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	if filepath.Dir(file.Path) != dir {
+		t.Errorf("Expected file in %s, got %s", dir, file.Path)
+	}
+	if file.Size != len(jpeg) {
+		t.Errorf("Expected size %d, got %d", len(jpeg), file.Size)
+	}
+	data, err := os.ReadFile(file.Path)
+	if err != nil || !bytes.Equal(data, jpeg) {
+		t.Errorf("Unexpected file contents: %v", err)
+	}
+
+	// Only the snapshot itself should remain, no temporary files
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != file.Filename {
+		t.Errorf("Unexpected directory contents: %v", entries)
+	}
+}
+
+func TestPlugin_SaveSnapshot_InvalidPath(t *testing.T) {
+	plugin := NewPlugin()
+	ctx := context.Background()
+
+	if _, err := plugin.SaveSnapshot(ctx, "cam_1", "relative/dir"); err == nil {
+		t.Error("Expected error for relative path")
+	}
+	if _, err := plugin.SaveSnapshot(ctx, "cam_1", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestPlugin_SaveSnapshot_TooLarge(t *testing.T) {
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, bytes.Repeat([]byte{0}, 2048)...)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(jpeg)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+	client.SetMaxSnapshotSize(1024)
+
+	plugin := NewPlugin()
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Front", "RLC-810A", "localhost", 0, client)
+	dir := t.TempDir()
+	if _, err := plugin.SaveSnapshot(context.Background(), "cam_1", dir); !errors.Is(err, reolink.ErrSnapshotTooLarge) {
+		t.Errorf("Expected ErrSnapshotTooLarge from SaveSnapshot, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files after a failed snapshot, got %v", entries)
+	}
+}
+
+func TestPlugin_SetSnapshotOptions(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 'j', 'p', 'e', 'g'}
+	var snapQuery string
+	var osd map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cmd") == "Snap" {
+			snapQuery = r.URL.RawQuery
+			_, _ = w.Write(jpeg)
+			return
+		}
+		var cmds []map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		cmd, _ := cmds[0]["cmd"].(string)
+		if cmd == "SetOsd" {
+			param, _ := cmds[0]["param"].(map[string]interface{})
+			osd, _ = param["Osd"].(map[string]interface{})
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{{"cmd": cmd, "code": 0, "value": map[string]interface{}{}}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam1"] = NewCamera("cam1", "Front", "RLC-810A", "localhost", 0, client)
+	ctx := context.Background()
+
+	on := true
+	opts, err := plugin.SetSnapshotOptions(ctx, SnapshotOptionsRequest{CameraID: "cam1",
+		SnapshotOptions: reolink.SnapshotOptions{Quality: 80, Width: 1280, Height: 720}, Timestamp: &on})
+	if err != nil {
+		t.Fatalf("SetSnapshotOptions failed: %v", err)
+	}
+	if opts.Quality != 80 || opts.Width != 1280 {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+	clock, _ := osd["osdTime"].(map[string]interface{})
+	if clock["enable"] != float64(1) {
+		t.Errorf("Expected the time overlay to be enabled, got %v", osd)
+	}
+
+	if _, err := plugin.GetSnapshot(ctx, "cam1"); err != nil {
+		t.Fatalf("GetSnapshot failed: %v", err)
+	}
+	if !strings.Contains(snapQuery, "quality=80") || !strings.Contains(snapQuery, "width=1280&height=720") {
+		t.Errorf("Expected the snapshot options in the Snap query, got %s", snapQuery)
+	}
+
+	if _, err := plugin.SetSnapshotOptions(ctx, SnapshotOptionsRequest{CameraID: "cam1",
+		SnapshotOptions: reolink.SnapshotOptions{Quality: 101}}); err == nil {
+		t.Error("Expected an error for quality 101")
+	}
+}
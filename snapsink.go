@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minSnapshotSinkInterval is the shortest allowed time between published snapshots
+const minSnapshotSinkInterval = time.Second
+
+// SnapshotSinkConfig publishes the latest snapshot of a camera on an
+// interval, to a file that is replaced each time and/or an MQTT topic
+type SnapshotSinkConfig struct {
+	CameraID   string `json:"camera_id"`
+	IntervalMs int    `json:"interval_ms"`
+
+	// Dir is an absolute directory the snapshot is written to as Filename,
+	// "<camera_id>.jpg" by default
+	Dir      string `json:"dir,omitempty"`
+	Filename string `json:"filename,omitempty"`
+
+	// MQTT publishes the JPEG bytes as the message payload
+	MQTT *MQTTConfig `json:"mqtt,omitempty"`
+}
+
+// validate checks the config and fills in the default file name and MQTT
+// client ID
+func (cfg *SnapshotSinkConfig) validate() error {
+	if cfg.CameraID == "" {
+		return fmt.Errorf("camera_id is required")
+	}
+	if time.Duration(cfg.IntervalMs)*time.Millisecond < minSnapshotSinkInterval {
+		return fmt.Errorf("interval_ms must be at least %d", minSnapshotSinkInterval.Milliseconds())
+	}
+	if cfg.Dir == "" && cfg.MQTT == nil {
+		return fmt.Errorf("dir or mqtt is required")
+	}
+
+	if cfg.Dir != "" {
+		if !filepath.IsAbs(cfg.Dir) {
+			return fmt.Errorf("dir must be absolute: %s", cfg.Dir)
+		}
+		info, err := os.Stat(cfg.Dir)
+		if err != nil {
+			return fmt.Errorf("invalid dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("dir is not a directory: %s", cfg.Dir)
+		}
+		if cfg.Filename == "" {
+			cfg.Filename = unsafeFileChars.ReplaceAllString(cfg.CameraID, "_") + ".jpg"
+		}
+		if strings.ContainsAny(cfg.Filename, `/\`) || strings.HasPrefix(cfg.Filename, ".") {
+			return fmt.Errorf("invalid filename: %s", cfg.Filename)
+		}
+	}
+
+	if cfg.MQTT != nil {
+		if err := cfg.MQTT.validate(); err != nil {
+			return fmt.Errorf("invalid mqtt: %w", err)
+		}
+		if cfg.MQTT.ClientID == "" {
+			cfg.MQTT.ClientID = "reolink-" + unsafeFileChars.ReplaceAllString(cfg.CameraID, "_")
+		}
+	}
+	return nil
+}
+
+// SnapshotSinkStatus is a configured snapshot sink and its progress
+type SnapshotSinkStatus struct {
+	SnapshotSinkConfig
+	Published   int       `json:"published"`
+	LastPublish time.Time `json:"last_publish"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// snapshotSink is a running snapshot publisher
+type snapshotSink struct {
+	config SnapshotSinkConfig
+	mqtt   *mqttPublisher // nil without MQTT
+
+	mu          sync.Mutex
+	published   int
+	lastPublish time.Time
+	lastError   string
+}
+
+func (s *snapshotSink) status() SnapshotSinkStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := SnapshotSinkStatus{
+		SnapshotSinkConfig: s.config,
+		Published:          s.published,
+		LastPublish:        s.lastPublish,
+		LastError:          s.lastError,
+	}
+	if m := s.config.MQTT; m != nil {
+		// Keep the broker password out of the host's logs
+		mqtt := *m
+		mqtt.Password = ""
+		status.MQTT = &mqtt
+	}
+	return status
+}
+
+// reset clears the counters of a sink whose camera was removed; the sink
+// keeps trying in case the camera is added again
+func (s *snapshotSink) reset() {
+	s.mu.Lock()
+	s.published = 0
+	s.lastPublish = time.Time{}
+	s.lastError = ""
+	s.mu.Unlock()
+}
+
+// startSnapshotSinks replaces the running snapshot sinks with configs. Sinks
+// of cameras that are not connected yet keep trying on every interval.
+func (p *Plugin) startSnapshotSinks(configs []SnapshotSinkConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopSinks != nil {
+		p.stopSinks()
+		p.stopSinks = nil
+	}
+	p.snapshotSinks = nil
+	if len(configs) == 0 {
+		return
+	}
+
+	parent := p.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	p.stopSinks = cancel
+
+	for _, cfg := range configs {
+		sink := &snapshotSink{config: cfg}
+		if cfg.MQTT != nil {
+			// Outlive the interval so the broker does not drop idle connections
+			sink.mqtt = newMQTTPublisher(*cfg.MQTT, 2*time.Duration(cfg.IntervalMs)*time.Millisecond)
+		}
+		if _, ok := p.cameras[cfg.CameraID]; !ok {
+			log.Printf("Snapshot sink for %s waits for the camera to connect", cfg.CameraID)
+		}
+		p.snapshotSinks = append(p.snapshotSinks, sink)
+		goGuarded(ctx, "snapshot sink for "+cfg.CameraID, func() { p.runSnapshotSink(ctx, sink) })
+	}
+	log.Printf("Started %d snapshot sinks", len(configs))
+}
+
+// ListSnapshotSinks returns the configured snapshot sinks in config order
+func (p *Plugin) ListSnapshotSinks() []SnapshotSinkStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	result := make([]SnapshotSinkStatus, 0, len(p.snapshotSinks))
+	for _, sink := range p.snapshotSinks {
+		result = append(result, sink.status())
+	}
+	return result
+}
+
+// runSnapshotSink publishes a snapshot every interval until ctx is canceled
+func (p *Plugin) runSnapshotSink(ctx context.Context, sink *snapshotSink) {
+	if sink.mqtt != nil {
+		defer sink.mqtt.Close()
+	}
+
+	ticker := time.NewTicker(time.Duration(sink.config.IntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.publishSnapshot(ctx, sink)
+		}
+	}
+}
+
+// publishSnapshot takes one snapshot and delivers it to the sink's file and
+// topic
+func (p *Plugin) publishSnapshot(ctx context.Context, sink *snapshotSink) {
+	err := p.deliverSnapshot(ctx, sink)
+
+	sink.mu.Lock()
+	if err != nil {
+		sink.lastError = err.Error()
+	} else {
+		sink.published++
+		sink.lastPublish = time.Now().UTC()
+		sink.lastError = ""
+	}
+	sink.mu.Unlock()
+
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Snapshot sink failed for %s: %v", sink.config.CameraID, err)
+	}
+}
+
+func (p *Plugin) deliverSnapshot(ctx context.Context, sink *snapshotSink) error {
+	cam, err := p.lookupCamera(sink.config.CameraID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cam.client.GetTimeouts().Snapshot)
+	defer cancel()
+
+	var data []byte
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) (err error) {
+		data, err = cam.client.GetSnapshot(ctx, cam.Channel())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if sink.config.Dir != "" {
+		if err := writeFileAtomic(filepath.Join(sink.config.Dir, sink.config.Filename), data, 0o644); err != nil {
+			return err
+		}
+	}
+	if sink.mqtt != nil {
+		if err := sink.mqtt.Publish(ctx, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
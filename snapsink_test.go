@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlugin_SnapshotSinks(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jpeg)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam1"] = NewCamera("cam1", "Weather", "RLC-810A", "localhost", 0, client)
+
+	broker := newFakeBroker(t)
+	dir := t.TempDir()
+	sinks := []SnapshotSinkConfig{
+		{CameraID: "cam1", IntervalMs: 1000, Dir: dir, MQTT: &MQTTConfig{Broker: broker.url(), Topic: "weather/cam1", Username: "nvr", Password: "secret"}},
+		{CameraID: "missing", IntervalMs: 1000, Dir: dir},
+	}
+	for i := range sinks {
+		if err := sinks[i].validate(); err != nil {
+			t.Fatalf("validate failed: %v", err)
+		}
+	}
+	plugin.startSnapshotSinks(sinks)
+	defer plugin.startSnapshotSinks(nil)
+
+	if connect := broker.next(t); connect[0] != mqttConnect {
+		t.Fatalf("Expected CONNECT, got header %#x", connect[0])
+	}
+	if publish := broker.next(t); !bytes.HasSuffix(publish, jpeg) {
+		t.Errorf("Expected the snapshot as payload, got % x", publish)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if list := plugin.ListSnapshotSinks(); list[0].Published > 0 && list[1].LastError != "" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "cam1.jpg")); err != nil || !bytes.Equal(data, jpeg) {
+		t.Errorf("Unexpected snapshot file contents: %v", err)
+	}
+
+	list := plugin.ListSnapshotSinks()
+	if len(list) != 2 || list[0].Published < 1 || list[0].LastError != "" {
+		t.Errorf("Unexpected sink status %+v", list[0])
+	}
+	if list[0].MQTT.Password != "" {
+		t.Error("Expected the broker password to be left out of the status")
+	}
+	if list[1].Published != 0 || list[1].LastError == "" {
+		t.Errorf("Expected the sink of an unknown camera to fail, got %+v", list[1])
+	}
+	if sinks[0].MQTT.Password != "secret" {
+		t.Error("Expected the config to be left unchanged")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// stateFileName is the name of the state file inside the state directory
+const stateFileName = "reolink-state.json"
+
+// pluginState is the plugin data persisted across restarts
+type pluginState struct {
+	// ZoomPresets maps camera ID to preset name to stored position
+	ZoomPresets map[string]map[string]reolink.ZoomPosition `json:"zoom_presets,omitempty"`
+
+	// Timelapses maps camera ID to its timelapse schedule
+	Timelapses map[string]TimelapseConfig `json:"timelapses,omitempty"`
+
+	// LensModes maps camera ID to the lens mode of Duo and Fisheye cameras
+	LensModes map[string]LensMode `json:"lens_modes,omitempty"`
+}
+
+func newPluginState() *pluginState {
+	return &pluginState{
+		ZoomPresets: make(map[string]map[string]reolink.ZoomPosition),
+		Timelapses:  make(map[string]TimelapseConfig),
+		LensModes:   make(map[string]LensMode),
+	}
+}
+
+// stateCipher names the encryption of state files written with a state_key
+const stateCipher = "aes-256-gcm"
+
+// encryptedState is the on-disk form of state encrypted with a state_key
+type encryptedState struct {
+	Encrypted string `json:"encrypted"` // stateCipher
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// stateStore persists plugin state as a JSON file, encrypted if a key is set
+type stateStore struct {
+	path string
+	aead cipher.AEAD // nil for plaintext
+	mu   sync.Mutex
+}
+
+func newStateStore(dir string) *stateStore {
+	return &stateStore{path: filepath.Join(dir, stateFileName)}
+}
+
+// newEncryptedStateStore returns a store that encrypts the state file with
+// AES-256-GCM. key is the base64 encoding of 32 random bytes.
+func newEncryptedStateStore(dir, key string) (*stateStore, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("must be 32 bytes in base64")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newStateStore(dir)
+	s.aead = aead
+	return s, nil
+}
+
+// Load reads the state file, returning empty state if it does not exist yet
+func (s *stateStore) Load() (*pluginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := newPluginState()
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var envelope encryptedState
+	encrypted := json.Unmarshal(data, &envelope) == nil && envelope.Encrypted != ""
+	if encrypted {
+		if data, err = s.decrypt(envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	// Plaintext state from before a key was set is encrypted right away
+	if !encrypted && s.aead != nil {
+		if err := s.writeLocked(data); err != nil {
+			return nil, err
+		}
+		log.Printf("Encrypted state file %s", s.path)
+	}
+	if state.ZoomPresets == nil {
+		state.ZoomPresets = make(map[string]map[string]reolink.ZoomPosition)
+	}
+	if state.Timelapses == nil {
+		state.Timelapses = make(map[string]TimelapseConfig)
+	}
+	if state.LensModes == nil {
+		state.LensModes = make(map[string]LensMode)
+	}
+	return state, nil
+}
+
+// Save writes the state file atomically
+func (s *stateStore) Save(state *pluginState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+func (s *stateStore) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeLocked(data)
+}
+
+func (s *stateStore) writeLocked(data []byte) error {
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		envelope := encryptedState{Encrypted: stateCipher, Nonce: nonce, Data: s.aead.Seal(nil, nonce, data, nil)}
+		var err error
+		if data, err = json.Marshal(envelope); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	return writeFileAtomic(s.path, data, 0o600)
+}
+
+// decrypt opens an encrypted state file
+func (s *stateStore) decrypt(envelope encryptedState) ([]byte, error) {
+	if s.aead == nil {
+		return nil, fmt.Errorf("state file %s is encrypted; set state_key", s.path)
+	}
+	if envelope.Encrypted != stateCipher {
+		return nil, fmt.Errorf("unsupported state encryption: %s", envelope.Encrypted)
+	}
+	if len(envelope.Nonce) != s.aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt state: invalid nonce")
+	}
+	data, err := s.aead.Open(nil, envelope.Nonce, envelope.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt state (wrong state_key?): %w", err)
+	}
+	return data, nil
+}
+
+// saveState persists the plugin state if a state directory is configured.
+// Callers must not hold p.mu.
+func (p *Plugin) saveState() {
+	p.mu.RLock()
+	store := p.store
+	data, err := json.MarshalIndent(p.state, "", "  ")
+	p.mu.RUnlock()
+
+	if store == nil {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to encode state: %v", err)
+		return
+	}
+
+	if err := store.write(data); err != nil {
+		log.Printf("Failed to save state: %v", err)
+	}
+}
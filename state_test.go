@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestStateStore_LoadMissing(t *testing.T) {
+	store := newStateStore(t.TempDir())
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if state.ZoomPresets == nil || len(state.ZoomPresets) != 0 {
+		t.Error("Expected empty zoom presets")
+	}
+}
+
+func TestStateStore_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	store := newStateStore(dir)
+
+	state := newPluginState()
+	state.ZoomPresets["cam_1"] = map[string]reolink.ZoomPosition{"door": {Zoom: 12, Focus: 240}}
+	if err := store.Save(state); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, stateFileName))
+	if err != nil {
+		t.Fatalf("State file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := newStateStore(dir).Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.ZoomPresets["cam_1"]["door"] != (reolink.ZoomPosition{Zoom: 12, Focus: 240}) {
+		t.Errorf("Unexpected zoom presets: %+v", loaded.ZoomPresets)
+	}
+}
+
+func TestStateStore_LoadCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, stateFileName), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newStateStore(dir).Load(); err == nil {
+		t.Error("Expected error for corrupt state file")
+	}
+}
+
+func TestStateStore_Encrypted(t *testing.T) {
+	dir := t.TempDir()
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+
+	// Plaintext state from before the key was set is encrypted on load
+	state := newPluginState()
+	state.ZoomPresets["cam_1"] = map[string]reolink.ZoomPosition{"door": {Zoom: 12, Focus: 240}}
+	if err := newStateStore(dir).Save(state); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	store, err := newEncryptedStateStore(dir, key)
+	if err != nil {
+		t.Fatalf("newEncryptedStateStore failed: %v", err)
+	}
+	if _, err := store.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, stateFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("cam_1")) || !bytes.Contains(data, []byte(stateCipher)) {
+		t.Errorf("Expected an encrypted state file, got %s", data)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.ZoomPresets["cam_1"]["door"] != (reolink.ZoomPosition{Zoom: 12, Focus: 240}) {
+		t.Errorf("Unexpected zoom presets: %+v", loaded.ZoomPresets)
+	}
+
+	if _, err := newStateStore(dir).Load(); err == nil || !strings.Contains(err.Error(), "set state_key") {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+	other, _ := newEncryptedStateStore(dir, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32)))
+	if _, err := other.Load(); err == nil || !strings.Contains(err.Error(), "wrong state_key") {
+		t.Errorf("Expected a wrong key error, got %v", err)
+	}
+	if _, err := newEncryptedStateStore(dir, "c2hvcnQ="); err == nil {
+		t.Error("Expected an error for a short key")
+	}
+}
+
+func TestPlugin_HandleRequest_Initialize_InvalidStateKey(t *testing.T) {
+	plugin := NewPlugin()
+	defer func() { _ = plugin.Shutdown(context.Background()) }()
+
+	params, _ := json.Marshal(map[string]interface{}{"state_dir": t.TempDir(), "state_key": "c2hvcnQ="})
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: params})
+
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("Expected an invalid params error, got %+v", resp.Error)
+	}
+	fields, ok := resp.Error.Data.([]FieldError)
+	if !ok || len(fields) != 1 || fields[0].Field != "state_key" {
+		t.Errorf("Expected a state_key field error, got %+v", resp.Error.Data)
+	}
+}
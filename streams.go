@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// StreamProfile is one stream variant a camera can serve
+type StreamProfile struct {
+	Stream    string `json:"stream"`   // "main", "sub" or "ext"
+	Protocol  string `json:"protocol"` // "rtsp", "rtmp" or "flv"
+	URL       string `json:"url"`
+	Codec     string `json:"codec,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	FrameRate int    `json:"frame_rate,omitempty"`
+	BitRate   int    `json:"bit_rate,omitempty"` // kbps
+
+	// Orientation is the image rotation and mirroring, once known
+	Orientation *Orientation `json:"orientation,omitempty"`
+}
+
+// streamProtocols lists the protocols each stream is served over. Reolink
+// RTSP only exposes the main and sub streams.
+var streamProtocols = map[string][]string{
+	"main": {"rtsp", "rtmp", "flv"},
+	"sub":  {"rtsp", "rtmp", "flv"},
+	"ext":  {"rtmp", "flv"},
+}
+
+// StreamProfiles returns every stream variant of the camera, using GetEnc for
+// the encoding parameters
+func (c *Camera) StreamProfiles(ctx context.Context) ([]StreamProfile, error) {
+	enc, err := c.client.GetEncoderConfig(ctx, c.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoder config: %w", err)
+	}
+	c.SetEncoderConfig(enc)
+
+	streams := []struct {
+		name string
+		cfg  reolink.StreamConfig
+	}{
+		{"main", enc.MainStream},
+		{"sub", enc.SubStream},
+	}
+	if enc.ExtStream != nil {
+		streams = append(streams, struct {
+			name string
+			cfg  reolink.StreamConfig
+		}{"ext", *enc.ExtStream})
+	}
+
+	orientation := c.Orientation()
+	var profiles []StreamProfile
+	for _, s := range streams {
+		for _, protocol := range streamProtocols[s.name] {
+			profiles = append(profiles, StreamProfile{
+				Stream:      s.name,
+				Protocol:    protocol,
+				URL:         c.client.StreamURL(c.channel, s.name, protocol),
+				Codec:       s.cfg.Codec,
+				Width:       s.cfg.Width,
+				Height:      s.cfg.Height,
+				FrameRate:   s.cfg.FrameRate,
+				BitRate:     s.cfg.BitRate,
+				Orientation: orientation,
+			})
+		}
+	}
+	return profiles, nil
+}
+
+// GetStreamProfiles returns every stream variant of a camera
+func (p *Plugin) GetStreamProfiles(ctx context.Context, cameraID string) ([]StreamProfile, error) {
+	var profiles []StreamProfile
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		profiles, err = cam.StreamProfiles(ctx)
+		return err
+	})
+	return profiles, err
+}
+
+// AudioStream describes the audio track of a camera's streams
+type AudioStream struct {
+	Enabled    bool   `json:"enabled"` // audio recording is on in the encoder settings
+	Codec      string `json:"codec,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"` // Hz
+	Channels   int    `json:"channels,omitempty"`
+	MuxedRTSP  bool   `json:"muxed_rtsp"`    // the RTSP streams carry the audio track
+	URL        string `json:"url,omitempty"` // audio-only stream, when enabled
+}
+
+// AudioStream returns the audio track details of the camera, using GetEnc to
+// see whether audio is recorded. Reolink has no separate audio stream, so the
+// audio-only URL selects the audio track of the main RTSP stream in go2rtc's
+// "#media=audio" form.
+func (c *Camera) AudioStream(ctx context.Context) (*AudioStream, error) {
+	enc, err := c.client.GetEncoderConfig(ctx, c.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoder config: %w", err)
+	}
+	c.SetEncoderConfig(enc)
+
+	if !enc.Audio {
+		return &AudioStream{}, nil
+	}
+	return &AudioStream{
+		Enabled:    true,
+		Codec:      reolink.AudioCodec,
+		SampleRate: reolink.AudioSampleRate,
+		Channels:   reolink.AudioChannels,
+		MuxedRTSP:  true,
+		URL:        c.client.RTSPStreamURL(c.channel, "main") + "#media=audio",
+	}, nil
+}
+
+// GetAudioStream returns the audio track details of a camera
+func (p *Plugin) GetAudioStream(ctx context.Context, cameraID string) (*AudioStream, error) {
+	var audio *AudioStream
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		audio, err = cam.AudioStream(ctx)
+		return err
+	})
+	return audio, err
+}
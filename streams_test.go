@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCamera_StreamProfiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:  "GetEnc",
+			Code: 0,
+			Value: map[string]interface{}{
+				"Enc": map[string]interface{}{
+					"mainStream": map[string]interface{}{
+						"width": float64(3840), "height": float64(2160),
+						"frameRate": float64(25), "bitRate": float64(6144),
+						"video": map[string]interface{}{"videoType": "h265"},
+					},
+					"subStream": map[string]interface{}{
+						"width": float64(640), "height": float64(360),
+						"frameRate": float64(15), "bitRate": float64(256),
+					},
+					"extStream": map[string]interface{}{
+						"width": float64(1280), "height": float64(720),
+					},
+				},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+	cam := NewCamera("cam_1", "Front", "RLC-811A", "localhost", 0, client)
+
+	profiles, err := cam.StreamProfiles(context.Background())
+	if err != nil {
+		t.Fatalf("StreamProfiles failed: %v", err)
+	}
+
+	// main and sub over 3 protocols, ext over rtmp/flv only
+	if len(profiles) != 8 {
+		t.Fatalf("Expected 8 profiles, got %d", len(profiles))
+	}
+
+	main := profiles[0]
+	if main.Stream != "main" || main.Protocol != "rtsp" || main.Codec != "h265" || main.Width != 3840 {
+		t.Errorf("Unexpected main profile: %+v", main)
+	}
+	if !strings.HasPrefix(main.URL, "rtsp://") {
+		t.Errorf("Expected RTSP URL, got %s", main.URL)
+	}
+
+	for _, p := range profiles {
+		if p.Stream == "ext" && p.Protocol == "rtsp" {
+			t.Error("ext stream should not be offered over RTSP")
+		}
+		if p.Stream == "ext" && !strings.Contains(p.URL, "channel0_ext.bcs") {
+			t.Errorf("Unexpected ext URL: %s", p.URL)
+		}
+	}
+}
+
+func TestPlugin_GetAudioStream(t *testing.T) {
+	for _, audio := range []float64{1, 0} {
+		server := newFakeDevice(t, map[string]interface{}{
+			"GetEnc": map[string]interface{}{"Enc": map[string]interface{}{
+				"audio":      audio,
+				"mainStream": map[string]interface{}{"width": float64(2560), "height": float64(1440)},
+			}},
+		})
+		client := newTestClient(server)
+		client.UseURLAuth()
+
+		plugin := NewPlugin()
+		plugin.cameras["cam_1"] = NewCamera("cam_1", "Front", "RLC-510A", "localhost", 0, client)
+
+		resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "get_audio_stream", Params: []byte(`{"camera_id": "cam_1"}`)})
+		if resp.Error != nil {
+			t.Fatalf("get_audio_stream failed: %s", resp.Error.Message)
+		}
+		stream := resp.Result.(*AudioStream)
+
+		if audio == 0 {
+			if stream.Enabled || stream.URL != "" || stream.MuxedRTSP {
+				t.Errorf("Expected no audio when it is off in the encoder, got %+v", stream)
+			}
+			continue
+		}
+		if !stream.Enabled || stream.Codec != "aac" || stream.SampleRate != 16000 || !stream.MuxedRTSP {
+			t.Errorf("Unexpected audio stream: %+v", stream)
+		}
+		if !strings.HasPrefix(stream.URL, "rtsp://") || !strings.HasSuffix(stream.URL, "_01_main#media=audio") {
+			t.Errorf("Expected an audio-only RTSP URL, got %s", stream.URL)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// streamPasswordChars avoids characters some firmware rejects in passwords
+const streamPasswordChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// provisionStreamUser creates the device's stream account as a guest user
+// and puts it in the client's stream URLs, so the login (admin) account is
+// only used for management. Without a configured password a random one is
+// set on every connect. In read-only mode the device is left alone and a
+// configured password is used as is.
+func (p *Plugin) provisionStreamUser(ctx context.Context, client *reolink.Client, device DeviceConfig) error {
+	if device.StreamUsername == "" {
+		return nil
+	}
+
+	p.mu.RLock()
+	readOnly := p.readOnly
+	p.mu.RUnlock()
+
+	password := device.StreamPassword
+	if readOnly {
+		if password == "" {
+			log.Printf("Not creating stream account %s on %s in read-only mode", device.StreamUsername, device.Host)
+			return nil
+		}
+		client.SetStreamCredentials(device.StreamUsername, password)
+		return nil
+	}
+
+	if password == "" {
+		password = newStreamPassword()
+	}
+	if err := client.EnsureGuestUser(ctx, device.StreamUsername, password); err != nil {
+		return err
+	}
+	client.SetStreamCredentials(device.StreamUsername, password)
+	log.Printf("Using guest account %s for streams of %s", device.StreamUsername, device.Host)
+	return nil
+}
+
+// newStreamPassword returns a random 16 character password
+func newStreamPassword() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = streamPasswordChars[int(b[i])%len(streamPasswordChars)]
+	}
+	return string(b)
+}
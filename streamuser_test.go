@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+func TestPlugin_AddCamera_StreamUser(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	plugin.ctx = context.Background()
+
+	cam, err := plugin.AddCamera(context.Background(), CameraConfig{
+		Host: host, Port: port, Username: "admin", Password: "secret", Protocol: "rtsp", StreamUsername: "nvr",
+	})
+	if err != nil {
+		t.Fatalf("AddCamera failed: %v", err)
+	}
+
+	password, ok := sim.GuestPassword("nvr")
+	if !ok || len(password) != 16 {
+		t.Fatalf("Expected a guest account with a generated password, got %q", password)
+	}
+	if !strings.HasPrefix(cam.MainStream, "rtsp://nvr:"+password+"@") {
+		t.Errorf("Expected the guest account in the stream URL, got %s", cam.MainStream)
+	}
+	if strings.Contains(cam.SubStream, "secret") {
+		t.Errorf("Expected no admin password in the stream URL, got %s", cam.SubStream)
+	}
+}
+
+func TestPlugin_StreamUser_ReadOnly(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret"})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	plugin.ctx = context.Background()
+	plugin.readOnly = true
+
+	// A configured account is used without touching the device
+	cam, err := plugin.AddCamera(context.Background(), CameraConfig{
+		Host: host, Port: port, Username: "admin", Password: "secret", Protocol: "rtsp",
+		StreamUsername: "nvr", StreamPassword: "viewonly",
+	})
+	if err != nil {
+		t.Fatalf("AddCamera failed: %v", err)
+	}
+	if _, ok := sim.GuestPassword("nvr"); ok {
+		t.Error("Expected no account to be created in read-only mode")
+	}
+	if !strings.HasPrefix(cam.MainStream, "rtsp://nvr:viewonly@") {
+		t.Errorf("Expected the configured account in the stream URL, got %s", cam.MainStream)
+	}
+}
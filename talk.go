@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Talkback paths, reported by talk
+const (
+	TalkPathRTSPBackchannel = "rtsp_backchannel"
+)
+
+// TalkResult describes audio played through a camera's speaker
+type TalkResult struct {
+	Path       string `json:"path"`  // talkback path used, e.g. "rtsp_backchannel"
+	Codec      string `json:"codec"` // codec negotiated with the camera
+	DurationMs int    `json:"duration_ms"`
+}
+
+// Talk plays a WAV clip (16-bit PCM, mono) through a camera's speaker, from
+// base64 data or a completed chunked transfer. The talkback path is chosen
+// from the camera's abilities; the RTSP backchannel is the only one so far.
+// The audio is streamed in real time over its own connection, so it does not
+// hold up the device worker.
+func (p *Plugin) Talk(ctx context.Context, cameraID, data, transferID string) (*TalkResult, error) {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+	if ability := cam.Ability(); ability != nil && !ability.TwoWayAudio {
+		return nil, fmt.Errorf("camera %s has no speaker for two-way audio", cameraID)
+	}
+
+	var raw []byte
+	if transferID != "" {
+		_, raw, err = p.transfers.Take(transferID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audio data: %w", err)
+		}
+	}
+	samples, rate, err := decodeWAV(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := cam.client.OpenBackchannel(ctx, cam.Channel())
+	if err != nil {
+		if errors.Is(err, reolink.ErrNoBackchannel) {
+			return nil, fmt.Errorf("camera %s offers no talkback path: %w", cameraID, err)
+		}
+		return nil, err
+	}
+	samples = resample(samples, rate, b.SampleRate())
+	err = b.WritePCM(samples)
+	if cerr := b.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(len(samples)) * time.Second / time.Duration(b.SampleRate())
+	log.Printf("Played %s of audio on camera %s over the RTSP backchannel", duration, cameraID)
+	return &TalkResult{
+		Path:       TalkPathRTSPBackchannel,
+		Codec:      b.Codec(),
+		DurationMs: int(duration.Milliseconds()),
+	}, nil
+}
+
+// decodeWAV returns the samples and sample rate of a 16-bit PCM mono WAV file
+func decodeWAV(data []byte) ([]int16, int, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("audio must be a WAV file")
+	}
+
+	var rate int
+	var pcm []byte
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8:]
+		if size > len(body) {
+			size = len(body)
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, fmt.Errorf("invalid WAV format chunk")
+			}
+			format := binary.LittleEndian.Uint16(body[0:])
+			channels := binary.LittleEndian.Uint16(body[2:])
+			bits := binary.LittleEndian.Uint16(body[14:])
+			if format != 1 || channels != 1 || bits != 16 {
+				return nil, 0, fmt.Errorf("audio must be 16-bit PCM mono, got format %d with %d channels of %d bits", format, channels, bits)
+			}
+			rate = int(binary.LittleEndian.Uint32(body[4:]))
+		case "data":
+			pcm = body[:size]
+		}
+		pos += 8 + size + size%2
+	}
+	if rate == 0 || pcm == nil {
+		return nil, 0, fmt.Errorf("invalid WAV file: missing format or data")
+	}
+
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(pcm[2*i:]))
+	}
+	return samples, rate, nil
+}
+
+// resample converts samples between rates by linear interpolation, which is
+// good enough for speech
+func resample(samples []int16, from, to int) []int16 {
+	if from == to || len(samples) == 0 {
+		return samples
+	}
+	out := make([]int16, int(int64(len(samples))*int64(to)/int64(from)))
+	for i := range out {
+		pos := float64(i) * float64(from) / float64(to)
+		j := int(pos)
+		if j+1 >= len(samples) {
+			out[i] = samples[len(samples)-1]
+			continue
+		}
+		frac := pos - float64(j)
+		out[i] = int16(float64(samples[j])*(1-frac) + float64(samples[j+1])*frac)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// testWAV returns a 16-bit PCM mono WAV file of the given samples
+func testWAV(rate int, samples []int16) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+2*len(samples)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{16})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{uint32(rate), uint32(2 * rate)})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(2*len(samples)))
+	_ = binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+func TestPlugin_Talk(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	sim := reolinksim.New(reolinksim.Camera{Password: "secret", Backchannel: true, RTSPPort: l.Addr().(*net.TCPAddr).Port})
+	go sim.ServeRTSP(l)
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	host, port := serverHostPort(server)
+	client := reolink.NewClient(host, port, "admin", "secret")
+	if _, err := client.GetNetPort(context.Background()); err != nil {
+		t.Fatalf("GetNetPort failed: %v", err)
+	}
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam", "Door", "Reolink Video Doorbell", host, 0, client)
+	cam.SetAbility(&reolink.Ability{TwoWayAudio: true})
+	plugin.cameras["cam"] = cam
+
+	// 100ms at 16 kHz, resampled to the backchannel's 8 kHz
+	data := base64.StdEncoding.EncodeToString(testWAV(16000, make([]int16, 1600)))
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "talk",
+		Params: []byte(`{"camera_id": "cam", "data": "` + data + `"}`)})
+	if resp.Error != nil {
+		t.Fatalf("talk failed: %s", resp.Error.Message)
+	}
+	result := resp.Result.(*TalkResult)
+	if result.Path != TalkPathRTSPBackchannel || result.Codec != "PCMU" || result.DurationMs != 100 {
+		t.Errorf("Unexpected talk result: %+v", result)
+	}
+	if n := len(sim.BackchannelAudio()); n != 800 {
+		t.Errorf("Expected 800 samples played, got %d", n)
+	}
+
+	// Cameras without a speaker are not tried
+	cam.SetAbility(&reolink.Ability{})
+	if _, err := plugin.Talk(context.Background(), "cam", data, ""); err == nil || !strings.Contains(err.Error(), "no speaker") {
+		t.Errorf("Expected a missing speaker error, got %v", err)
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	samples, rate, err := decodeWAV(testWAV(8000, []int16{1, -2, 3}))
+	if err != nil || rate != 8000 || len(samples) != 3 || samples[1] != -2 {
+		t.Errorf("Unexpected decode: %v, %d, %v", samples, rate, err)
+	}
+
+	stereo := testWAV(8000, []int16{1, 2})
+	binary.LittleEndian.PutUint16(stereo[22:], 2)
+	if _, _, err := decodeWAV(stereo); err == nil {
+		t.Error("Expected stereo audio to be rejected")
+	}
+	if _, _, err := decodeWAV([]byte("ID3 not a wav file")); err == nil {
+		t.Error("Expected non-WAV data to be rejected")
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// minTimelapseInterval is the shortest allowed time between timelapse frames
+const minTimelapseInterval = time.Second
+
+// TimelapseConfig schedules periodic snapshots of a camera
+type TimelapseConfig struct {
+	CameraID   string `json:"camera_id"`
+	IntervalMs int    `json:"interval_ms"`
+
+	// Optional daily capture window in local "HH:MM" time. A window
+	// whose end is before its start spans midnight.
+	WindowStart string `json:"window_start,omitempty"`
+	WindowEnd   string `json:"window_end,omitempty"`
+
+	// Dir is an absolute directory frames are written to. If empty, frames
+	// are sent to the host as timelapse.frame notifications.
+	Dir string `json:"dir,omitempty"`
+}
+
+// validate checks the config and normalizes the window times
+func (cfg *TimelapseConfig) validate() error {
+	if time.Duration(cfg.IntervalMs)*time.Millisecond < minTimelapseInterval {
+		return fmt.Errorf("interval_ms must be at least %d", minTimelapseInterval.Milliseconds())
+	}
+
+	if cfg.WindowStart != "" || cfg.WindowEnd != "" {
+		start, err := clockMinutes(cfg.WindowStart)
+		if err != nil {
+			return fmt.Errorf("invalid window_start: %w", err)
+		}
+		end, err := clockMinutes(cfg.WindowEnd)
+		if err != nil {
+			return fmt.Errorf("invalid window_end: %w", err)
+		}
+		if start == end {
+			return fmt.Errorf("window_start and window_end must differ")
+		}
+	}
+
+	if cfg.Dir != "" {
+		if !filepath.IsAbs(cfg.Dir) {
+			return fmt.Errorf("dir must be absolute: %s", cfg.Dir)
+		}
+		info, err := os.Stat(cfg.Dir)
+		if err != nil {
+			return fmt.Errorf("invalid dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("dir is not a directory: %s", cfg.Dir)
+		}
+	}
+	return nil
+}
+
+// inWindow reports whether t falls within the capture window, if any
+func (cfg *TimelapseConfig) inWindow(t time.Time) bool {
+	if cfg.WindowStart == "" {
+		return true
+	}
+	start, _ := clockMinutes(cfg.WindowStart)
+	end, _ := clockMinutes(cfg.WindowEnd)
+
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// clockMinutes parses "HH:MM" into minutes since midnight
+func clockMinutes(s string) (int, error) {
+	hour, minute, err := reolink.ParseClock(s)
+	if err != nil {
+		return 0, err
+	}
+	return hour*60 + minute, nil
+}
+
+// TimelapseStatus is a running timelapse and its progress
+type TimelapseStatus struct {
+	TimelapseConfig
+	Frames    int       `json:"frames"`
+	LastFrame time.Time `json:"last_frame"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// TimelapseFrame is the payload of a timelapse.frame notification. Image is
+// set when frames are delivered inline, Path/Filename/Size when written to Dir.
+type TimelapseFrame struct {
+	CameraID   string    `json:"camera_id"`
+	CapturedAt time.Time `json:"captured_at"`
+	Image      string    `json:"image,omitempty"` // base64 JPEG
+	Path       string    `json:"path,omitempty"`
+	Filename   string    `json:"filename,omitempty"`
+	Size       int       `json:"size,omitempty"`
+}
+
+// timelapseJob is a running timelapse schedule
+type timelapseJob struct {
+	config TimelapseConfig
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	frames    int
+	lastFrame time.Time
+	lastError string
+}
+
+func (j *timelapseJob) status() TimelapseStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return TimelapseStatus{
+		TimelapseConfig: j.config,
+		Frames:          j.frames,
+		LastFrame:       j.lastFrame,
+		LastError:       j.lastError,
+	}
+}
+
+// StartTimelapse starts (or replaces) the timelapse schedule of a camera.
+// Schedules are persisted and resumed on the next initialize.
+func (p *Plugin) StartTimelapse(cfg TimelapseConfig) (*TimelapseStatus, error) {
+	if _, err := p.lookupCamera(cfg.CameraID); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	job := p.startTimelapseLocked(cfg)
+	p.state.Timelapses[cfg.CameraID] = cfg
+	p.mu.Unlock()
+
+	p.saveState()
+	log.Printf("Started timelapse for camera %s every %dms", cfg.CameraID, cfg.IntervalMs)
+
+	status := job.status()
+	return &status, nil
+}
+
+// StopTimelapse stops a camera's timelapse and forgets its schedule
+func (p *Plugin) StopTimelapse(cameraID string) error {
+	p.mu.Lock()
+	_, running := p.timelapses[cameraID]
+	_, saved := p.state.Timelapses[cameraID]
+	if !running && !saved {
+		p.mu.Unlock()
+		return fmt.Errorf("no timelapse for camera: %s", cameraID)
+	}
+	p.stopTimelapseLocked(cameraID)
+	delete(p.state.Timelapses, cameraID)
+	p.mu.Unlock()
+
+	p.saveState()
+	log.Printf("Stopped timelapse for camera %s", cameraID)
+	return nil
+}
+
+// ListTimelapses returns the running timelapses ordered by camera ID
+func (p *Plugin) ListTimelapses() []TimelapseStatus {
+	p.mu.RLock()
+	result := make([]TimelapseStatus, 0, len(p.timelapses))
+	for _, job := range p.timelapses {
+		result = append(result, job.status())
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CameraID < result[j].CameraID })
+	return result
+}
+
+// resumeTimelapses starts the persisted timelapse schedules of known cameras
+func (p *Plugin) resumeTimelapses() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for cameraID, cfg := range p.state.Timelapses {
+		if _, ok := p.cameras[cameraID]; !ok {
+			log.Printf("Not resuming timelapse for unknown camera %s", cameraID)
+			continue
+		}
+		p.startTimelapseLocked(cfg)
+	}
+}
+
+func (p *Plugin) startTimelapseLocked(cfg TimelapseConfig) *timelapseJob {
+	p.stopTimelapseLocked(cfg.CameraID)
+
+	parent := p.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	job := &timelapseJob{config: cfg, cancel: cancel}
+	p.timelapses[cfg.CameraID] = job
+	goGuarded(ctx, "timelapse for "+cfg.CameraID, func() { p.runTimelapse(ctx, job) })
+	return job
+}
+
+func (p *Plugin) stopTimelapseLocked(cameraID string) {
+	if job, ok := p.timelapses[cameraID]; ok {
+		job.cancel()
+		delete(p.timelapses, cameraID)
+	}
+}
+
+// runTimelapse captures a frame every interval within the window until ctx is canceled
+func (p *Plugin) runTimelapse(ctx context.Context, job *timelapseJob) {
+	ticker := time.NewTicker(time.Duration(job.config.IntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !job.config.inWindow(now) {
+				continue
+			}
+			p.captureTimelapseFrame(ctx, job)
+		}
+	}
+}
+
+// captureTimelapseFrame takes one snapshot and delivers it to the sink
+// directory or the host
+func (p *Plugin) captureTimelapseFrame(ctx context.Context, job *timelapseJob) {
+	cameraID := job.config.CameraID
+	frame, err := p.timelapseFrame(ctx, cameraID, job.config.Dir)
+
+	job.mu.Lock()
+	if err != nil {
+		job.lastError = err.Error()
+	} else {
+		job.frames++
+		job.lastFrame = frame.CapturedAt
+		job.lastError = ""
+	}
+	job.mu.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Timelapse capture failed for %s: %v", cameraID, err)
+		}
+		return
+	}
+	_ = p.notify("timelapse.frame", frame)
+}
+
+func (p *Plugin) timelapseFrame(ctx context.Context, cameraID, dir string) (*TimelapseFrame, error) {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cam.client.GetTimeouts().Snapshot)
+	defer cancel()
+
+	frame := &TimelapseFrame{CameraID: cameraID, CapturedAt: time.Now().UTC()}
+	if dir != "" {
+		file, err := p.SaveSnapshot(ctx, cameraID, dir)
+		if err != nil {
+			return nil, err
+		}
+		frame.Path = file.Path
+		frame.Filename = file.Filename
+		frame.Size = file.Size
+		return frame, nil
+	}
+
+	var data []byte
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) (err error) {
+		data, err = cam.client.GetSnapshot(ctx, cam.Channel())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	frame.Image = base64.StdEncoding.EncodeToString(data)
+	return frame, nil
+}
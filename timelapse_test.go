@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimelapseConfig_Validate(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		cfg     TimelapseConfig
+		wantErr bool
+	}{
+		{"valid", TimelapseConfig{IntervalMs: 60000}, false},
+		{"window and dir", TimelapseConfig{IntervalMs: 1000, WindowStart: "07:00", WindowEnd: "19:30", Dir: dir}, false},
+		{"interval too short", TimelapseConfig{IntervalMs: 500}, true},
+		{"window without end", TimelapseConfig{IntervalMs: 1000, WindowStart: "07:00"}, true},
+		{"invalid window", TimelapseConfig{IntervalMs: 1000, WindowStart: "25:00", WindowEnd: "07:00"}, true},
+		{"empty window", TimelapseConfig{IntervalMs: 1000, WindowStart: "07:00", WindowEnd: "07:00"}, true},
+		{"relative dir", TimelapseConfig{IntervalMs: 1000, Dir: "frames"}, true},
+		{"missing dir", TimelapseConfig{IntervalMs: 1000, Dir: dir + "/missing"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTimelapseConfig_InWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 2, hour, minute, 0, 0, time.Local)
+	}
+
+	day := TimelapseConfig{WindowStart: "07:00", WindowEnd: "19:00"}
+	if !day.inWindow(at(7, 0)) || !day.inWindow(at(18, 59)) {
+		t.Error("Expected times within the day window to match")
+	}
+	if day.inWindow(at(19, 0)) || day.inWindow(at(6, 59)) {
+		t.Error("Expected times outside the day window not to match")
+	}
+
+	night := TimelapseConfig{WindowStart: "22:00", WindowEnd: "06:00"}
+	if !night.inWindow(at(23, 0)) || !night.inWindow(at(5, 0)) {
+		t.Error("Expected times within the overnight window to match")
+	}
+	if night.inWindow(at(12, 0)) {
+		t.Error("Expected noon to be outside the overnight window")
+	}
+
+	if !(&TimelapseConfig{}).inWindow(at(3, 0)) {
+		t.Error("Expected no window to always match")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestPlugin_Timelapse(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jpeg)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	var out syncBuffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	plugin.cameras["cam1"] = NewCamera("cam1", "Site", "RLC-810A", "localhost", 0, client)
+
+	if _, err := plugin.StartTimelapse(TimelapseConfig{CameraID: "missing", IntervalMs: 1000}); err == nil {
+		t.Error("Expected error for unknown camera")
+	}
+
+	dir := t.TempDir()
+	status, err := plugin.StartTimelapse(TimelapseConfig{CameraID: "cam1", IntervalMs: 1000, Dir: dir})
+	if err != nil {
+		t.Fatalf("StartTimelapse failed: %v", err)
+	}
+	if status.CameraID != "cam1" || status.Frames != 0 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if _, ok := plugin.state.Timelapses["cam1"]; !ok {
+		t.Error("Expected the schedule to be persisted")
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !strings.Contains(out.String(), "timelapse.frame") && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	var msg struct {
+		Method string         `json:"method"`
+		Params TimelapseFrame `json:"params"`
+	}
+	line, _, _ := strings.Cut(out.String(), "\n")
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		t.Fatalf("Invalid notification %q: %v", out.String(), err)
+	}
+	if msg.Method != "timelapse.frame" || msg.Params.Path == "" || msg.Params.Size != len(jpeg) {
+		t.Errorf("Unexpected notification %+v", msg)
+	}
+	if data, err := os.ReadFile(msg.Params.Path); err != nil || !bytes.Equal(data, jpeg) {
+		t.Errorf("Unexpected frame file contents: %v", err)
+	}
+
+	list := plugin.ListTimelapses()
+	if len(list) != 1 || list[0].Frames < 1 {
+		t.Errorf("Unexpected timelapse list %+v", list)
+	}
+
+	if err := plugin.StopTimelapse("cam1"); err != nil {
+		t.Fatalf("StopTimelapse failed: %v", err)
+	}
+	if len(plugin.ListTimelapses()) != 0 || len(plugin.state.Timelapses) != 0 {
+		t.Error("Expected the timelapse to be removed")
+	}
+	if err := plugin.StopTimelapse("cam1"); err == nil {
+		t.Error("Expected error when stopping a stopped timelapse")
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Tracing follows the OpenTelemetry data model: every JSON-RPC request is a
+// server span, and the device API calls made while handling it are client
+// spans below it. Finished spans are batched and exported to an OTLP/HTTP
+// collector as JSON, so no SDK is needed.
+//
+// Spans are only recorded below a request span. Background work such as event
+// polling has no parent span and is not traced.
+
+const (
+	defaultServiceName = "reolink-plugin"
+
+	traceFlushInterval = 5 * time.Second
+	traceMaxBatch      = 512
+	traceMaxPending    = 4096 // spans are dropped beyond this if the collector is down
+)
+
+// OTLP span kinds
+const (
+	spanKindServer = 2
+	spanKindClient = 3
+)
+
+// TracingConfig is the "tracing" section of the plugin config
+type TracingConfig struct {
+	OTLPEndpoint string `json:"otlp_endpoint"` // e.g. http://otel-collector:4318
+	ServiceName  string `json:"service_name,omitempty"`
+}
+
+// parseTracingConfig reads the tracing config, falling back to the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT environment variable. It returns nil if no
+// endpoint is configured.
+func parseTracingConfig(config map[string]interface{}) *TracingConfig {
+	tc := &TracingConfig{}
+	if data, ok := config["tracing"].(map[string]interface{}); ok {
+		if v, ok := data["otlp_endpoint"].(string); ok {
+			tc.OTLPEndpoint = v
+		}
+		if v, ok := data["service_name"].(string); ok {
+			tc.ServiceName = v
+		}
+	}
+	if tc.OTLPEndpoint == "" {
+		tc.OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if tc.OTLPEndpoint == "" {
+		return nil
+	}
+	if tc.ServiceName == "" {
+		tc.ServiceName = defaultServiceName
+	}
+	return tc
+}
+
+// tracer collects finished spans and exports them in batches
+type tracer struct {
+	endpoint string // full URL of the OTLP traces endpoint
+	service  string
+	http     *http.Client
+
+	mu      sync.Mutex
+	pending []*span
+	flushCh chan struct{}
+}
+
+func newTracer(cfg TracingConfig) *tracer {
+	return &tracer{
+		endpoint: strings.TrimSuffix(cfg.OTLPEndpoint, "/") + "/v1/traces",
+		service:  cfg.ServiceName,
+		http:     &http.Client{Timeout: 10 * time.Second},
+		flushCh:  make(chan struct{}, 1),
+	}
+}
+
+// run exports pending spans periodically until ctx is canceled
+func (t *tracer) run(ctx context.Context) {
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-t.flushCh:
+		}
+		if err := t.flush(ctx); err != nil {
+			log.Printf("Failed to export traces: %v", err)
+		}
+	}
+}
+
+// record queues a finished span for export
+func (t *tracer) record(s *span) {
+	t.mu.Lock()
+	if len(t.pending) < traceMaxPending {
+		t.pending = append(t.pending, s)
+	}
+	full := len(t.pending) >= traceMaxBatch
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush exports all pending spans
+func (t *tracer) flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > traceMaxBatch {
+			n = traceMaxBatch
+		}
+		if err := t.export(ctx, spans[:n]); err != nil {
+			return err
+		}
+		spans = spans[n:]
+	}
+	return nil
+}
+
+// export sends one batch of spans to the collector
+func (t *tracer) export(ctx context.Context, spans []*span) error {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		otlpSpans = append(otlpSpans, s.otlp())
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes(map[string]string{"service.name": t.service}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": defaultServiceName},
+				"spans": otlpSpans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// span is a single timed operation. A nil *span is valid and records nothing,
+// so callers do not need to check whether tracing is enabled.
+type span struct {
+	tracer   *tracer
+	name     string
+	kind     int
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte // zero for root spans
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      string
+}
+
+type spanContextKey struct{}
+
+// spanFromContext returns the current span of ctx, or nil
+func spanFromContext(ctx context.Context) *span {
+	s, _ := ctx.Value(spanContextKey{}).(*span)
+	return s
+}
+
+// startRequestSpan starts the root span of a JSON-RPC request. If the request
+// params carry a W3C "traceparent" or a "trace_id", the span joins that trace.
+func (t *tracer) startRequestSpan(ctx context.Context, req JSONRPCRequest) (context.Context, *span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	s := &span{
+		tracer: t,
+		name:   req.Method,
+		kind:   spanKindServer,
+		start:  time.Now(),
+		attrs:  map[string]string{"rpc.system": "jsonrpc", "rpc.method": req.Method},
+	}
+
+	var params struct {
+		TraceParent string `json:"traceparent"`
+		TraceID     string `json:"trace_id"`
+		CameraID    string `json:"camera_id"`
+	}
+	if len(req.Params) > 0 {
+		_ = json.Unmarshal(req.Params, &params)
+	}
+	if !parseTraceParent(params.TraceParent, &s.traceID, &s.parentID) {
+		if !decodeHexID(params.TraceID, s.traceID[:]) {
+			_, _ = rand.Read(s.traceID[:])
+		}
+	}
+	_, _ = rand.Read(s.spanID[:])
+	if params.CameraID != "" {
+		s.attrs["camera.id"] = params.CameraID
+	}
+
+	ctx = reolink.WithSpanStarter(ctx, startClientSpan)
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// startClientSpan starts the span of a device request made by the client
+func startClientSpan(ctx context.Context, name string) (context.Context, reolink.Span) {
+	return startSpan(ctx, name, spanKindClient)
+}
+
+// startSpan starts a child of the span in ctx. It returns a nil span when ctx
+// has none.
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *span) {
+	parent := spanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	s := &span{
+		tracer:   parent.tracer,
+		name:     name,
+		kind:     kind,
+		traceID:  parent.traceID,
+		parentID: parent.spanID,
+		start:    time.Now(),
+		attrs:    map[string]string{},
+	}
+	_, _ = rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// SetAttribute records a key/value pair on the span
+func (s *span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// End finishes the span, marking it failed if err is non-nil, and queues it for export
+func (s *span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.tracer.record(s)
+}
+
+// otlp converts the span to its OTLP/JSON form
+func (s *span) otlp() map[string]interface{} {
+	out := map[string]interface{}{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        otlpAttributes(s.attrs),
+	}
+	if s.parentID != [8]byte{} {
+		out["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != "" {
+		out["status"] = map[string]interface{}{"code": 2, "message": s.err}
+	}
+	return out
+}
+
+func otlpAttributes(attrs map[string]string) []interface{} {
+	list := make([]interface{}, 0, len(attrs))
+	for k, v := range attrs {
+		list = append(list, map[string]interface{}{
+			"key":   k,
+			"value": map[string]interface{}{"stringValue": v},
+		})
+	}
+	return list
+}
+
+// parseTraceParent parses a W3C traceparent header value
+// ("00-<trace id>-<parent span id>-<flags>")
+func parseTraceParent(value string, traceID *[16]byte, parentID *[8]byte) bool {
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return false
+	}
+	var tid [16]byte
+	var pid [8]byte
+	if !decodeHexID(parts[1], tid[:]) || !decodeHexID(parts[2], pid[:]) {
+		return false
+	}
+	*traceID = tid
+	*parentID = pid
+	return true
+}
+
+// decodeHexID decodes a non-zero hex ID of exactly len(dst) bytes
+func decodeHexID(value string, dst []byte) bool {
+	if len(value) != 2*len(dst) {
+		return false
+	}
+	if _, err := hex.Decode(dst, []byte(strings.ToLower(value))); err != nil {
+		return false
+	}
+	for _, b := range dst {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// otlpSpan is the subset of an exported OTLP/JSON span checked by the tests
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+}
+
+// newFakeCollector returns an OTLP/HTTP collector that records received spans
+func newFakeCollector(t *testing.T) (*httptest.Server, func() []otlpSpan) {
+	t.Helper()
+	var mu sync.Mutex
+	var spans []otlpSpan
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		for _, rs := range body.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []otlpSpan {
+		mu.Lock()
+		defer mu.Unlock()
+		return spans
+	}
+}
+
+func TestPlugin_HandleRequest_Traced(t *testing.T) {
+	collector, received := newFakeCollector(t)
+	device := newFakeDevice(t, map[string]interface{}{
+		"GetAbility": map[string]interface{}{"Ability": map[string]interface{}{}},
+		"GetEnc":     map[string]interface{}{"Enc": map[string]interface{}{}},
+	})
+
+	client := newTestClient(device)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.tracer = newTracer(TracingConfig{OTLPEndpoint: collector.URL, ServiceName: "test"})
+	plugin.cameras["cam1"] = NewCamera("cam1", "Front", "RLC-810A", "cam", 0, client)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	params, _ := json.Marshal(map[string]string{
+		"camera_id":   "cam1",
+		"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01",
+	})
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "refresh_camera", Params: params})
+	if resp.Error != nil {
+		t.Fatalf("refresh_camera failed: %v", resp.Error.Message)
+	}
+
+	if err := plugin.tracer.flush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	spans := received()
+	var root *otlpSpan
+	for i := range spans {
+		if spans[i].Name == "refresh_camera" {
+			root = &spans[i]
+		}
+	}
+	if root == nil {
+		t.Fatalf("Expected a refresh_camera span, got %+v", spans)
+	}
+	if root.TraceID != traceID || root.ParentSpanID != "00f067aa0ba902b7" || root.Kind != spanKindServer {
+		t.Errorf("Expected the request span to join the caller's trace, got %+v", root)
+	}
+
+	var children int
+	for _, s := range spans {
+		if s.ParentSpanID == root.SpanID {
+			children++
+			if s.TraceID != traceID || s.Kind != spanKindClient {
+				t.Errorf("Unexpected device call span %+v", s)
+			}
+		}
+	}
+	if children < 2 {
+		t.Errorf("Expected spans for GetAbility and GetEnc, got %d", children)
+	}
+}
+
+func TestPlugin_HandleRequest_NotTracedByDefault(t *testing.T) {
+	plugin := NewPlugin()
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "list_cameras"})
+	if resp.Error != nil {
+		t.Fatalf("list_cameras failed: %v", resp.Error.Message)
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	var traceID [16]byte
+	var parentID [8]byte
+
+	if !parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", &traceID, &parentID) {
+		t.Fatal("Expected a valid traceparent to parse")
+	}
+	if traceID[0] != 0x4b || parentID[7] != 0xb7 {
+		t.Errorf("Unexpected IDs %x %x", traceID, parentID)
+	}
+
+	invalid := []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	for _, v := range invalid {
+		if parseTraceParent(v, &traceID, &parentID) {
+			t.Errorf("Expected %q to be rejected", v)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// Address tracking. A device on a DHCP lease may come back at another
+// address after a restart; it is looked for in the discovery subnets and
+// recognized by its MAC in the ARP table before any credentials are sent
+// there, then by serial once logged in.
+const (
+	trackInterval     = 5 * time.Minute // between automatic searches for a device
+	trackProbeTimeout = time.Second
+	trackConcurrency  = 64
+)
+
+// lostDevice is a device whose cameras are all offline, with what
+// recognizes it at another address
+type lostDevice struct {
+	client *reolink.Client
+	serial string
+	mac    string
+}
+
+// lostDevices returns the devices whose cameras are all offline and that
+// can be recognized by MAC
+func (p *Plugin) lostDevices() []lostDevice {
+	p.mu.RLock()
+	online := make(map[*reolink.Client]bool)
+	var clients []*reolink.Client
+	for _, cam := range p.cameras {
+		if _, seen := online[cam.client]; !seen {
+			clients = append(clients, cam.client)
+		}
+		online[cam.client] = online[cam.client] || cam.IsOnline()
+	}
+	p.mu.RUnlock()
+
+	var lost []lostDevice
+	for _, client := range clients {
+		if online[client] {
+			continue
+		}
+		device := lostDevice{client: client}
+		if info := client.GetCachedDeviceInfo(); info != nil {
+			device.serial = info.Serial
+		}
+		if link := client.GetCachedLocalLink(); link != nil {
+			device.mac = link.MAC
+		}
+		if device.mac != "" {
+			lost = append(lost, device)
+		}
+	}
+	return lost
+}
+
+// managedHosts returns every address a managed device may answer at, and a
+// client of the managed devices for each of their ports
+func (p *Plugin) managedHosts() (map[string]bool, []*reolink.Client) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	hosts := make(map[string]bool)
+	ports := make(map[int]bool)
+	var probes []*reolink.Client
+	for _, cam := range p.cameras {
+		for _, host := range cam.client.Hosts() {
+			hosts[host] = true
+		}
+		if !ports[cam.client.Port()] {
+			ports[cam.client.Port()] = true
+			probes = append(probes, cam.client)
+		}
+	}
+	return hosts, probes
+}
+
+// scanSubnets returns the addresses in subnets, other than skip, answering
+// the Reolink API on the port of one of the probes, each of which is a
+// client to copy the connection settings of
+func scanSubnets(ctx context.Context, subnets []string, probes []*reolink.Client, skip map[string]bool) []DiscoveredCamera {
+	var hosts []string
+	for _, cidr := range subnets {
+		list, _ := subnetHosts(cidr)
+		for _, host := range list {
+			if !skip[host] && !contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	found := []DiscoveredCamera{}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, trackConcurrency)
+	for _, host := range hosts {
+		for _, probe := range probes {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(client *reolink.Client) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				// The probe shares the HTTP client of the device it copies,
+				// so only the context bounds it
+				client.SetRetryPolicy(reolink.RetryPolicy{MaxAttempts: 1})
+				probeCtx, cancel := context.WithTimeout(ctx, trackProbeTimeout)
+				defer cancel()
+				if err := client.Ping(probeCtx); err != nil {
+					return
+				}
+				mu.Lock()
+				found = append(found, DiscoveredCamera{
+					ID:           client.Host(),
+					Manufacturer: "Reolink",
+					Host:         client.Host(),
+					Port:         client.Port(),
+					Capabilities: []string{},
+				})
+				mu.Unlock()
+			}(probe.WithHost(host))
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Host != found[j].Host {
+			return found[i].Host < found[j].Host
+		}
+		return found[i].Port < found[j].Port
+	})
+	return found
+}
+
+// trackDevices scans subnets for lost devices and moves the cameras of each
+// one found to its new address. It returns the devices answering there that
+// are not managed.
+func (p *Plugin) trackDevices(ctx context.Context, subnets []string) []DiscoveredCamera {
+	p.trackMu.Lock()
+	defer p.trackMu.Unlock()
+
+	lost := p.lostDevices()
+	// Devices keep their port across addresses, and new ones likely use the
+	// port of the others or the default
+	skip, probes := p.managedHosts()
+	hasDefault := false
+	for _, probe := range probes {
+		hasDefault = hasDefault || probe.Port() == 80
+	}
+	if !hasDefault {
+		probes = append(probes, reolink.NewClient("", 80, "", ""))
+	}
+
+	unmanaged := []DiscoveredCamera{}
+	for _, found := range scanSubnets(ctx, subnets, probes, skip) {
+		moved := false
+		for i, device := range lost {
+			if device.client.Port() == found.Port && p.trackDevice(ctx, device, found.Host) {
+				lost = append(lost[:i], lost[i+1:]...)
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			unmanaged = append(unmanaged, found)
+		}
+	}
+	return unmanaged
+}
+
+// trackDevice checks whether the lost device answers at host and if so
+// moves its cameras there under their IDs and announces them with
+// camera.online and camera.updated. The stored device config follows; the
+// host must save the new address in its own config.
+func (p *Plugin) trackDevice(ctx context.Context, device lostDevice, host string) bool {
+	old := device.client
+	if !device.answersAt(p.arpTable, host) {
+		return false
+	}
+
+	// Only the Login API is used, in case another device took over the MAC
+	client := old.WithHost(host)
+	client.UseTokenLogin()
+	config := p.deviceConfigOf(old)
+	config.Host = host
+	info, _, err := p.loginDevice(client, config)
+	if err != nil {
+		log.Printf("Found %s at %s but could not connect: %v", old.Host(), host, err)
+		return false
+	}
+	if device.serial != "" && info.Serial != device.serial {
+		log.Printf("Found the MAC of %s at %s, but serial %s answers there", old.Host(), host, info.Serial)
+		_ = client.Close(ctx)
+		return false
+	}
+	// The cameras may have been removed, or come back, meanwhile
+	if lost := p.lostDevices(); !containsLost(lost, old) {
+		_ = client.Close(ctx)
+		return false
+	}
+
+	previous := old.Host()
+	moved := p.relocateDevice(old, client)
+	for _, cam := range moved {
+		p.notifyCameraStatus(cam, true)
+		p.notifyCameraUpdated(cam)
+	}
+	log.Printf("Device %s moved to %s, moved %d cameras", previous, host, len(moved))
+
+	p.mu.Lock()
+	delete(p.trackedAt, old)
+	p.mu.Unlock()
+	goGuarded(p.ctx, "release of "+previous, func() { p.releaseClient(p.ctx, old) })
+	return true
+}
+
+// answersAt reports whether the ARP table at path has the MAC of the device
+// for host, which the scan has just reached
+func (d lostDevice) answersAt(path, host string) bool {
+	mac := neighborMAC(path, host)
+	return mac != "" && strings.EqualFold(mac, d.mac)
+}
+
+// neighborMAC returns the hardware address of host in the ARP table at
+// path, in the format of /proc/net/arp, or "" if it is not there
+func neighborMAC(path, host string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		// IP address, HW type, flags, HW address, mask, device; flags 0x0
+		// is an incomplete entry
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[0] == host && fields[2] != "0x0" {
+			return fields[3]
+		}
+	}
+	return ""
+}
+
+func containsLost(lost []lostDevice, client *reolink.Client) bool {
+	for _, device := range lost {
+		if device.client == client {
+			return true
+		}
+	}
+	return false
+}
+
+// trackLost starts a search for a device whose cameras are all offline in
+// the discovery subnets, at most every trackInterval
+func (p *Plugin) trackLost(client *reolink.Client, cameras []*Camera) {
+	for _, cam := range cameras {
+		if cam.IsOnline() {
+			return
+		}
+	}
+	p.mu.Lock()
+	subnets := p.discoverySubnets
+	if len(subnets) == 0 || len(cameras) == 0 || time.Since(p.trackedAt[client]) < trackInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.trackedAt[client] = time.Now()
+	p.mu.Unlock()
+
+	log.Printf("Looking for %s in %s", client.Host(), strings.Join(subnets, ", "))
+	goGuarded(p.ctx, "address tracking of "+client.Host(), func() { p.trackDevices(p.ctx, subnets) })
+}
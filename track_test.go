@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// startTrackSim serves a simulated device with the given serial at host:port
+func startTrackSim(t *testing.T, host string, port int, serial string) *httptest.Server {
+	t.Helper()
+	server, _ := startTrackSimCamera(t, host, port, reolinksim.Camera{Password: "secret", Serial: serial})
+	return server
+}
+
+func startTrackSimCamera(t *testing.T, host string, port int, cam reolinksim.Camera) (*httptest.Server, *reolinksim.Server) {
+	t.Helper()
+	l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		t.Skipf("Cannot listen on %s: %v", host, err)
+	}
+	sim := reolinksim.New(cam)
+	server := httptest.NewUnstartedServer(sim)
+	server.Listener = l
+	server.Start()
+	return server, sim
+}
+
+// writeARPTable writes an ARP table in the format of /proc/net/arp with the
+// given MAC for each host
+func writeARPTable(t *testing.T, macs map[string]string) string {
+	t.Helper()
+	table := "IP address       HW type     Flags       HW address            Mask     Device\n"
+	for host, mac := range macs {
+		table += host + "        0x1         0x2         " + mac + "     *        eth0\n"
+	}
+	path := filepath.Join(t.TempDir(), "arp")
+	if err := os.WriteFile(path, []byte(table), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNeighborMAC(t *testing.T) {
+	path := writeARPTable(t, map[string]string{"192.168.1.20": "ec:71:db:00:00:01"})
+	data, _ := os.ReadFile(path)
+	incomplete := "192.168.1.21     0x1         0x0         00:00:00:00:00:00     *        eth0\n"
+	if err := os.WriteFile(path, append(data, incomplete...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if mac := neighborMAC(path, "192.168.1.20"); mac != "ec:71:db:00:00:01" {
+		t.Errorf("Expected the MAC of 192.168.1.20, got %q", mac)
+	}
+	if mac := neighborMAC(path, "192.168.1.21"); mac != "" {
+		t.Errorf("Expected no MAC for an incomplete entry, got %q", mac)
+	}
+	if mac := neighborMAC(path, "192.168.1.2"); mac != "" {
+		t.Errorf("Expected no MAC for a host not in the table, got %q", mac)
+	}
+	if mac := neighborMAC(filepath.Join(t.TempDir(), "missing"), "192.168.1.20"); mac != "" {
+		t.Errorf("Expected no MAC without a table, got %q", mac)
+	}
+}
+
+func TestParseDiscoverySubnets(t *testing.T) {
+	subnets, err := parseDiscoverySubnets(map[string]interface{}{
+		"discovery_subnets": []interface{}{"192.168.1.0/24", "10.0.0.8/30"},
+	})
+	if err != nil || len(subnets) != 2 {
+		t.Fatalf("Expected 2 subnets, got %v, %v", subnets, err)
+	}
+
+	_, err = parseDiscoverySubnets(map[string]interface{}{
+		"discovery_subnets": []interface{}{"192.168.1.0/24", "192.168.1.0/24", "10.0.0.0/8", 5, ""},
+	})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 4 {
+		t.Fatalf("Expected 4 config errors, got %v", err)
+	}
+	for i, field := range []string{"discovery_subnets[1]", "discovery_subnets[2]", "discovery_subnets[3]", "discovery_subnets[4]"} {
+		if cfgErr.Errors[i].Field != field {
+			t.Errorf("Expected an error for %s, got %+v", field, cfgErr.Errors[i])
+		}
+	}
+}
+
+func TestPlugin_TrackMovedDevice(t *testing.T) {
+	before := startTrackSim(t, "127.0.0.2", 0, "SN-MOVED")
+	_, port := serverHostPort(before)
+	cameraID := "127.0.0.2_ch0"
+
+	var out syncBuffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	plugin.arpTable = writeARPTable(t, map[string]string{"127.0.0.3": reolinksim.DefaultCamera.MAC})
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"offline_after_ms":       float64(200),
+		"discovery_subnets":      []interface{}{"127.0.0.3/32"},
+		"devices": []interface{}{
+			map[string]interface{}{"host": "127.0.0.2", "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		before.Close()
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	// The device gets a new lease after a restart. Credentials may only be
+	// sent with the Login API there.
+	before.Close()
+	after, _ := startTrackSimCamera(t, "127.0.0.3", port, reolinksim.Camera{Password: "secret", Serial: "SN-MOVED", TokenOnly: true})
+	defer after.Close()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if cam := plugin.GetCamera(cameraID); cam != nil && cam.Host == "127.0.0.3" {
+			if !strings.Contains(cam.MainStream, "127.0.0.3") {
+				t.Errorf("Expected the stream URL at the new address, got %s", cam.MainStream)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the camera to move to 127.0.0.3, got %+v", plugin.GetCamera(cameraID))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	var updated bool
+	for _, msg := range readLifecycle(t, bytes.NewBufferString(out.String())) {
+		if msg.Method == NotifyCameraUpdated && msg.Params.Camera.Host == "127.0.0.3" {
+			updated = true
+		}
+	}
+	if !updated {
+		t.Error("Expected camera.updated with the new address")
+	}
+	if cam, _ := plugin.lookupCamera(cameraID); plugin.deviceConfigOf(cam.client).Username != "admin" {
+		t.Error("Expected the stored device config to follow the new address")
+	}
+}
+
+func TestPlugin_TrackIgnoresOtherMAC(t *testing.T) {
+	before := startTrackSim(t, "127.0.0.2", 0, "SN-MOVED")
+	_, port := serverHostPort(before)
+
+	plugin := NewPlugin()
+	plugin.SetOutput(&syncBuffer{})
+	plugin.arpTable = writeARPTable(t, map[string]string{"127.0.0.3": "ec:71:db:00:00:99"})
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"offline_after_ms":       float64(200),
+		"discovery_subnets":      []interface{}{"127.0.0.3/32"},
+		"devices": []interface{}{
+			map[string]interface{}{"host": "127.0.0.2", "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		before.Close()
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+	before.Close()
+
+	// Another device answers at the address the scan finds
+	other, sim := startTrackSimCamera(t, "127.0.0.3", port, reolinksim.Camera{Password: "secret", Serial: "SN-OTHER", MAC: "ec:71:db:00:00:99"})
+	defer other.Close()
+
+	unmanaged := plugin.trackDevices(context.Background(), []string{"127.0.0.3/32"})
+	if len(unmanaged) != 1 || unmanaged[0].Host != "127.0.0.3" {
+		t.Errorf("Expected the other device to be listed, got %+v", unmanaged)
+	}
+	if cam := plugin.GetCamera("127.0.0.2_ch0"); cam == nil || cam.Host != "127.0.0.2" {
+		t.Errorf("Expected the camera to stay at 127.0.0.2, got %+v", cam)
+	}
+	if n := sim.CommandCount("Login"); n != 0 {
+		t.Errorf("Expected no login to the other device, got %d", n)
+	}
+}
+
+func TestPlugin_DiscoverCamerasSubnets(t *testing.T) {
+	managed := startTrackSim(t, "127.0.0.2", 0, "SN-MANAGED")
+	defer managed.Close()
+	_, port := serverHostPort(managed)
+	other := startTrackSim(t, "127.0.0.3", port, "SN-OTHER")
+	defer other.Close()
+
+	plugin := NewPlugin()
+	plugin.SetOutput(&syncBuffer{})
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"devices": []interface{}{
+			map[string]interface{}{"host": "127.0.0.2", "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	if _, err := plugin.DiscoverCameras(context.Background(), "not-a-subnet"); err == nil {
+		t.Error("Expected an error for an invalid subnet")
+	}
+
+	// The managed device is online, so the other one is only listed
+	discovered, err := plugin.DiscoverCameras(context.Background(), "127.0.0.2/31")
+	if err != nil {
+		t.Fatalf("DiscoverCameras failed: %v", err)
+	}
+	if len(discovered) != 2 || discovered[0].ID != "127.0.0.2_ch0" ||
+		discovered[1].ID != "127.0.0.3" || discovered[1].Port != port {
+		t.Errorf("Unexpected discovery %+v", discovered)
+	}
+}
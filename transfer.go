@@ -0,0 +1,346 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chunked binary transfers.
+//
+// Large binaries do not travel inside a single JSON-RPC line. Instead they are
+// split into base64 chunks framed by transfer.begin / transfer.chunk /
+// transfer.end messages:
+//
+//   - Outgoing (plugin to host, e.g. download_clip): the plugin emits the three
+//     messages as JSON-RPC notifications before returning the response.
+//   - Incoming (host to plugin, e.g. upgrade_firmware): the host calls the three
+//     methods, then passes the transfer_id to the consuming method.
+//
+// Every chunk carries a CRC-32 of its decoded bytes and the whole transfer is
+// verified with SHA-256.
+const (
+	transferChunkSize    = 256 * 1024        // raw bytes per outgoing chunk
+	maxTransferChunkSize = 1024 * 1024       // largest incoming chunk accepted
+	maxTransferSize      = 64 * 1024 * 1024  // largest incoming transfer accepted
+	maxPendingTransfers  = 128 * 1024 * 1024 // total declared size of incoming transfers held at once
+	transferIdleTimeout  = 5 * time.Minute   // incoming transfers expire after this
+)
+
+// JSONRPCNotification is a JSON-RPC message without an ID
+type JSONRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// TransferBegin starts a chunked transfer
+type TransferBegin struct {
+	TransferID  string `json:"transfer_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"` // -1 if unknown for outgoing transfers
+	SHA256      string `json:"sha256,omitempty"`
+	ChunkSize   int    `json:"chunk_size,omitempty"`
+}
+
+// TransferChunk carries one base64-encoded piece of a transfer
+type TransferChunk struct {
+	TransferID string `json:"transfer_id"`
+	Seq        int    `json:"seq"`
+	Data       string `json:"data"`
+	CRC32      string `json:"crc32,omitempty"` // hex CRC-32 (IEEE) of the decoded bytes
+}
+
+// TransferEnd completes a transfer
+type TransferEnd struct {
+	TransferID string `json:"transfer_id"`
+	Size       int64  `json:"size"`
+	Chunks     int    `json:"chunks"`
+	SHA256     string `json:"sha256"`
+}
+
+// SetOutput sets where responses and notifications are written
+func (p *Plugin) SetOutput(w io.Writer) {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	p.out = w
+}
+
+// writeMessage writes one JSON-RPC message as a single line
+func (p *Plugin) writeMessage(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+
+	if p.out == nil {
+		return fmt.Errorf("no output configured")
+	}
+	_, err = p.out.Write(append(data, '\n'))
+	return err
+}
+
+// notify sends a JSON-RPC notification to the host
+func (p *Plugin) notify(method string, params interface{}) error {
+	return p.writeMessage(JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// sendTransfer streams r to the host as transfer notifications. size may be
+// -1 when unknown. The returned summary matches the transfer.end notification.
+func (p *Plugin) sendTransfer(name, contentType string, size int64, r io.Reader) (*TransferEnd, error) {
+	id := newTransferID()
+
+	err := p.notify("transfer.begin", TransferBegin{
+		TransferID:  id,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		ChunkSize:   transferChunkSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transfer: %w", err)
+	}
+
+	sum := sha256.New()
+	buf := make([]byte, transferChunkSize)
+	end := &TransferEnd{TransferID: id}
+
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			sum.Write(chunk)
+			err := p.notify("transfer.chunk", TransferChunk{
+				TransferID: id,
+				Seq:        end.Chunks,
+				Data:       base64.StdEncoding.EncodeToString(chunk),
+				CRC32:      fmt.Sprintf("%08x", crc32.ChecksumIEEE(chunk)),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to send chunk: %w", err)
+			}
+			end.Chunks++
+			end.Size += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			// Tell the host to discard what it received so far
+			_ = p.notify("transfer.abort", map[string]interface{}{
+				"transfer_id": id,
+				"error":       readErr.Error(),
+			})
+			return nil, readErr
+		}
+	}
+
+	end.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	if err := p.notify("transfer.end", end); err != nil {
+		return nil, fmt.Errorf("failed to finish transfer: %w", err)
+	}
+	return end, nil
+}
+
+// incomingTransfer is a host-to-plugin transfer being assembled
+type incomingTransfer struct {
+	name     string
+	size     int64
+	expected string // expected SHA-256 from transfer.begin, if given
+	data     []byte
+	sum      hash.Hash
+	nextSeq  int
+	complete bool
+	updated  time.Time
+}
+
+// transferManager tracks incoming transfers until they are consumed
+type transferManager struct {
+	mu        sync.Mutex
+	transfers map[string]*incomingTransfer
+}
+
+func newTransferManager() *transferManager {
+	return &transferManager{transfers: make(map[string]*incomingTransfer)}
+}
+
+// Begin registers a new incoming transfer and returns its ID
+func (m *transferManager) Begin(name string, size int64, sha string) (*TransferBegin, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("size is required")
+	}
+	if size > maxTransferSize {
+		return nil, fmt.Errorf("transfer too large: %d bytes (max %d)", size, maxTransferSize)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expireLocked()
+
+	pending := size
+	for _, t := range m.transfers {
+		pending += t.size
+	}
+	if pending > maxPendingTransfers {
+		return nil, fmt.Errorf("too many pending transfers: %d bytes declared (max %d)", pending, maxPendingTransfers)
+	}
+
+	// The buffer grows as chunks arrive, so a declared size costs nothing
+	// until the data is actually sent.
+	id := newTransferID()
+	m.transfers[id] = &incomingTransfer{
+		name:     name,
+		size:     size,
+		expected: strings.ToLower(sha),
+		sum:      sha256.New(),
+		updated:  time.Now(),
+	}
+
+	return &TransferBegin{
+		TransferID: id,
+		Name:       name,
+		Size:       size,
+		SHA256:     sha,
+		ChunkSize:  maxTransferChunkSize,
+	}, nil
+}
+
+// Chunk appends a chunk to an incoming transfer. Chunks must arrive in order.
+func (m *transferManager) Chunk(chunk TransferChunk) error {
+	data, err := base64.StdEncoding.DecodeString(chunk.Data)
+	if err != nil {
+		return fmt.Errorf("invalid chunk data: %w", err)
+	}
+	if len(data) > maxTransferChunkSize {
+		return fmt.Errorf("chunk too large: %d bytes (max %d)", len(data), maxTransferChunkSize)
+	}
+	if chunk.CRC32 != "" {
+		if got := fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)); !strings.EqualFold(got, chunk.CRC32) {
+			return fmt.Errorf("chunk %d checksum mismatch: expected %s, got %s", chunk.Seq, chunk.CRC32, got)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.transfers[chunk.TransferID]
+	if !ok {
+		return fmt.Errorf("transfer not found: %s", chunk.TransferID)
+	}
+	if t.complete {
+		return fmt.Errorf("transfer already completed: %s", chunk.TransferID)
+	}
+	if chunk.Seq != t.nextSeq {
+		return fmt.Errorf("unexpected chunk %d (expected %d)", chunk.Seq, t.nextSeq)
+	}
+	if int64(len(t.data)+len(data)) > t.size {
+		return fmt.Errorf("transfer exceeds declared size of %d bytes", t.size)
+	}
+
+	t.data = append(t.data, data...)
+	t.sum.Write(data)
+	t.nextSeq++
+	t.updated = time.Now()
+	return nil
+}
+
+// End verifies an incoming transfer. sha overrides the checksum given at begin.
+// On mismatch the transfer is discarded.
+func (m *transferManager) End(id, sha string) (*TransferEnd, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.transfers[id]
+	if !ok {
+		return nil, fmt.Errorf("transfer not found: %s", id)
+	}
+
+	if sha != "" {
+		t.expected = strings.ToLower(sha)
+	}
+	if t.expected == "" {
+		return nil, fmt.Errorf("sha256 is required")
+	}
+
+	if int64(len(t.data)) != t.size {
+		delete(m.transfers, id)
+		return nil, fmt.Errorf("transfer incomplete: received %d of %d bytes", len(t.data), t.size)
+	}
+	got := hex.EncodeToString(t.sum.Sum(nil))
+	if got != t.expected {
+		delete(m.transfers, id)
+		return nil, fmt.Errorf("checksum mismatch: expected %s, got %s", t.expected, got)
+	}
+
+	t.complete = true
+	t.updated = time.Now()
+	return &TransferEnd{TransferID: id, Size: t.size, Chunks: t.nextSeq, SHA256: got}, nil
+}
+
+// Take removes a completed transfer and returns its name and data
+func (m *transferManager) Take(id string) (string, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.completedLocked(id)
+	if err != nil {
+		return "", nil, err
+	}
+	delete(m.transfers, id)
+	return t.name, t.data, nil
+}
+
+// Peek returns the name and data of a completed transfer without removing it
+func (m *transferManager) Peek(id string) (string, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.completedLocked(id)
+	if err != nil {
+		return "", nil, err
+	}
+	return t.name, t.data, nil
+}
+
+func (m *transferManager) completedLocked(id string) (*incomingTransfer, error) {
+	t, ok := m.transfers[id]
+	if !ok {
+		return nil, fmt.Errorf("transfer not found: %s", id)
+	}
+	if !t.complete {
+		return nil, fmt.Errorf("transfer not completed: %s", id)
+	}
+	return t, nil
+}
+
+// expireLocked drops transfers idle for longer than transferIdleTimeout
+func (m *transferManager) expireLocked() {
+	for id, t := range m.transfers {
+		if time.Since(t.updated) > transferIdleTimeout {
+			delete(m.transfers, id)
+		}
+	}
+}
+
+func newTransferID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
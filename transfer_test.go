@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPlugin_SendTransfer_RoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPlugin()
+	p.SetOutput(&out)
+
+	payload := bytes.Repeat([]byte("0123456789"), transferChunkSize/4) // 2.5 chunks
+	result, err := p.sendTransfer("clip.mp4", "video/mp4", int64(len(payload)), bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("sendTransfer failed: %v", err)
+	}
+	if result.Chunks != 3 {
+		t.Errorf("Expected 3 chunks, got %d", result.Chunks)
+	}
+
+	// Feed the emitted notifications back into a transfer manager
+	m := newTransferManager()
+	var id string
+	scanner := bufio.NewScanner(&out)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Invalid notification: %v", err)
+		}
+
+		switch msg.Method {
+		case "transfer.begin":
+			var begin TransferBegin
+			_ = json.Unmarshal(msg.Params, &begin)
+			started, err := m.Begin(begin.Name, begin.Size, "")
+			if err != nil {
+				t.Fatalf("Begin failed: %v", err)
+			}
+			id = started.TransferID
+		case "transfer.chunk":
+			var chunk TransferChunk
+			_ = json.Unmarshal(msg.Params, &chunk)
+			chunk.TransferID = id
+			if err := m.Chunk(chunk); err != nil {
+				t.Fatalf("Chunk failed: %v", err)
+			}
+		case "transfer.end":
+			var end TransferEnd
+			_ = json.Unmarshal(msg.Params, &end)
+			if _, err := m.End(id, end.SHA256); err != nil {
+				t.Fatalf("End failed: %v", err)
+			}
+		default:
+			t.Fatalf("Unexpected method: %s", msg.Method)
+		}
+	}
+
+	name, data, err := m.Take(id)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if name != "clip.mp4" || !bytes.Equal(data, payload) {
+		t.Errorf("Round trip mismatch: name=%s, %d bytes", name, len(data))
+	}
+}
+
+func TestPlugin_SendTransfer_NoOutput(t *testing.T) {
+	p := NewPlugin()
+	if _, err := p.sendTransfer("clip.mp4", "", 3, strings.NewReader("abc")); err == nil {
+		t.Error("Expected error without output")
+	}
+}
+
+func TestTransferManager_Validation(t *testing.T) {
+	payload := []byte("firmware image")
+	sum := sha256.Sum256(payload)
+	sha := hex.EncodeToString(sum[:])
+	encoded := base64.StdEncoding.EncodeToString(payload)
+
+	m := newTransferManager()
+
+	if _, err := m.Begin("fw.pak", 0, sha); err == nil {
+		t.Error("Expected error for missing size")
+	}
+	if _, err := m.Begin("fw.pak", maxTransferSize+1, sha); err == nil {
+		t.Error("Expected error for oversized transfer")
+	}
+
+	begin, err := m.Begin("fw.pak", int64(len(payload)), sha)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	id := begin.TransferID
+
+	if err := m.Chunk(TransferChunk{TransferID: id, Seq: 1, Data: encoded}); err == nil {
+		t.Error("Expected error for out-of-order chunk")
+	}
+	if err := m.Chunk(TransferChunk{TransferID: id, Seq: 0, Data: encoded, CRC32: "deadbeef"}); err == nil {
+		t.Error("Expected error for CRC mismatch")
+	}
+	if _, _, err := m.Take(id); err == nil {
+		t.Error("Expected error taking an incomplete transfer")
+	}
+	if err := m.Chunk(TransferChunk{TransferID: id, Seq: 0, Data: encoded}); err != nil {
+		t.Fatalf("Chunk failed: %v", err)
+	}
+	if _, err := m.End(id, ""); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+	if _, data, err := m.Peek(id); err != nil || !bytes.Equal(data, payload) {
+		t.Errorf("Peek returned %q, %v", data, err)
+	}
+	if _, data, err := m.Take(id); err != nil || !bytes.Equal(data, payload) {
+		t.Errorf("Take returned %q, %v", data, err)
+	}
+	if _, _, err := m.Take(id); err == nil {
+		t.Error("Expected transfer to be consumed")
+	}
+}
+
+func TestTransferManager_PendingLimit(t *testing.T) {
+	m := newTransferManager()
+
+	for i := 0; i < maxPendingTransfers/maxTransferSize; i++ {
+		if _, err := m.Begin("fw.pak", maxTransferSize, ""); err != nil {
+			t.Fatalf("Begin %d failed: %v", i, err)
+		}
+	}
+	if _, err := m.Begin("fw.pak", 1, ""); err == nil {
+		t.Error("Expected error once the pending limit is reached")
+	}
+
+	for id, tr := range m.transfers {
+		if cap(tr.data) != 0 {
+			t.Errorf("Transfer %s preallocated %d bytes", id, cap(tr.data))
+		}
+	}
+}
+
+func TestTransferManager_ChecksumMismatch(t *testing.T) {
+	m := newTransferManager()
+	begin, _ := m.Begin("clip.wav", 3, strings.Repeat("0", 64))
+	_ = m.Chunk(TransferChunk{TransferID: begin.TransferID, Seq: 0, Data: base64.StdEncoding.EncodeToString([]byte("abc"))})
+
+	if _, err := m.End(begin.TransferID, ""); err == nil {
+		t.Error("Expected checksum mismatch")
+	}
+	if _, err := m.End(begin.TransferID, ""); err == nil {
+		t.Error("Expected failed transfer to be discarded")
+	}
+}
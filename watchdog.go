@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// defaultOfflineAfter is how long a device may stay silent before its cameras
+// are marked offline
+const defaultOfflineAfter = 2 * time.Minute
+
+// checkConnectivity checks every device at once; device workers normally
+// check their own device periodically
+func (p *Plugin) checkConnectivity(ctx context.Context, offlineAfter time.Duration) {
+	p.mu.RLock()
+	byClient := make(map[*reolink.Client][]*Camera)
+	for _, cam := range p.cameras {
+		byClient[cam.client] = append(byClient[cam.client], cam)
+	}
+	p.mu.RUnlock()
+
+	for client, cameras := range byClient {
+		p.checkDeviceConnectivity(ctx, client, cameras, offlineAfter)
+	}
+}
+
+// checkDeviceConnectivity probes a device that has been quiet for half the
+// silence window, then marks its cameras online or offline based on when
+// they were last seen
+func (p *Plugin) checkDeviceConnectivity(ctx context.Context, client *reolink.Client, cameras []*Camera, offlineAfter time.Duration) {
+	if client != nil && len(cameras) > 0 && time.Since(cameras[0].LastSeen()) >= offlineAfter/2 {
+		probeCtx, cancel := context.WithTimeout(ctx, client.GetTimeouts().Request)
+		if _, err := client.GetDeviceInfo(probeCtx); err != nil {
+			log.Printf("Connectivity check failed for %s: %v", cameras[0].Host(), err)
+		}
+		cancel()
+	}
+
+	for _, cam := range cameras {
+		online := time.Since(cam.LastSeen()) <= offlineAfter
+		if !cam.setOnline(online) {
+			continue
+		}
+		if online {
+			log.Printf("Camera %s is back online", cam.ID())
+		} else {
+			log.Printf("Camera %s marked offline (not seen since %s)", cam.ID(), cam.LastSeen().Format(time.RFC3339))
+			cam.beginOutage(cam.LastSeen())
+		}
+		p.notifyCameraStatus(cam, online)
+		if online {
+			p.backfillLater(ctx, cam)
+		}
+	}
+
+	// A device that went away may have come back at another address
+	if client != nil {
+		p.trackLost(client, cameras)
+	}
+}
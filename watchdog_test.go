@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+func TestPlugin_CheckConnectivity_Reachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:   "GetDevInfo",
+			Code:  0,
+			Value: map[string]interface{}{"DevInfo": map[string]interface{}{"model": "RLC-810A"}},
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam_1", "Front", "RLC-810A", "localhost", 0, client)
+	cam.online = false
+	cam.lastSeen = time.Now().Add(-time.Hour)
+	plugin.cameras["cam_1"] = cam
+
+	plugin.checkConnectivity(context.Background(), time.Minute)
+
+	if !cam.IsOnline() {
+		t.Error("Expected camera to be back online after successful probe")
+	}
+	if time.Since(cam.LastSeen()) > time.Minute {
+		t.Errorf("Expected LastSeen to be updated, got %v", cam.LastSeen())
+	}
+}
+
+func TestPlugin_CheckConnectivity_Silent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	client := newTestClient(server)
+	client.UseURLAuth()
+	client.SetRetryPolicy(reolink.RetryPolicy{MaxAttempts: 1})
+	server.Close()
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam_1", "Front", "RLC-810A", "localhost", 0, client)
+	cam.lastSeen = time.Now().Add(-time.Hour)
+	plugin.cameras["cam_1"] = cam
+
+	plugin.checkConnectivity(context.Background(), time.Minute)
+
+	if cam.IsOnline() {
+		t.Error("Expected camera to be marked offline")
+	}
+}
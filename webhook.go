@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Webhook delivery limits. Events wait in a short queue per webhook, so a
+// slow endpoint delays only its own deliveries and drops the overflow.
+const (
+	defaultWebhookTimeout = 5 * time.Second
+	maxWebhookTimeout     = time.Minute
+	webhookQueueSize      = 100
+	webhookAttempts       = 3
+	webhookRetryDelay     = time.Second
+)
+
+// webhookSignatureHeader carries the HMAC-SHA256 of the body for webhooks
+// with a secret, as "sha256=<hex>"
+const webhookSignatureHeader = "X-Reolink-Signature"
+
+// WebhookConfig posts camera events as JSON to an HTTP endpoint, such as a
+// Node-RED flow or a home automation hook
+type WebhookConfig struct {
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`    // e.g. Authorization
+	TimeoutMs int               `json:"timeout_ms,omitempty"` // per attempt, 5000 by default
+
+	// Secret signs every body in the X-Reolink-Signature header
+	Secret string `json:"secret,omitempty"`
+
+	// EventTypes and Cameras limit the events that are posted; all events
+	// are if empty
+	EventTypes []string `json:"event_types,omitempty"`
+	Cameras    []string `json:"cameras,omitempty"`
+}
+
+// validate checks the config and fills in the default timeout
+func (cfg *WebhookConfig) validate() error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http:// or https:// URL: %q", cfg.URL)
+	}
+	if cfg.TimeoutMs == 0 {
+		cfg.TimeoutMs = int(defaultWebhookTimeout.Milliseconds())
+	}
+	if cfg.TimeoutMs < 0 || time.Duration(cfg.TimeoutMs)*time.Millisecond > maxWebhookTimeout {
+		return fmt.Errorf("timeout_ms must be between 1 and %d", maxWebhookTimeout.Milliseconds())
+	}
+	return nil
+}
+
+// matches reports whether ev is posted to the webhook
+func (cfg *WebhookConfig) matches(ev Event) bool {
+	if len(cfg.EventTypes) > 0 && !contains(cfg.EventTypes, ev.Type) {
+		return false
+	}
+	return len(cfg.Cameras) == 0 || contains(cfg.Cameras, ev.CameraID)
+}
+
+// WebhookPayload is the JSON body posted for an event: the event as sent in
+// "event" notifications, plus the plugin ID to tell instances apart
+type WebhookPayload struct {
+	PluginID string `json:"plugin_id"`
+	Event
+}
+
+// WebhookStatus is a configured webhook and its deliveries
+type WebhookStatus struct {
+	WebhookConfig
+	Delivered    int       `json:"delivered"`
+	Failed       int       `json:"failed"`  // events given up on after all attempts
+	Dropped      int       `json:"dropped"` // events not queued because the endpoint fell behind
+	LastDelivery time.Time `json:"last_delivery"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// webhook is a running event forwarder
+type webhook struct {
+	config WebhookConfig
+	client *http.Client
+	queue  chan WebhookPayload
+
+	mu           sync.Mutex
+	delivered    int
+	failed       int
+	dropped      int
+	lastDelivery time.Time
+	lastError    string
+}
+
+func (w *webhook) status() WebhookStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := WebhookStatus{
+		WebhookConfig: w.config,
+		Delivered:     w.delivered,
+		Failed:        w.failed,
+		Dropped:       w.dropped,
+		LastDelivery:  w.lastDelivery,
+		LastError:     w.lastError,
+	}
+	// Keep credentials out of the host's logs
+	status.Secret = ""
+	if len(w.config.Headers) > 0 {
+		status.Headers = make(map[string]string, len(w.config.Headers))
+		for name := range w.config.Headers {
+			status.Headers[name] = "***"
+		}
+	}
+	return status
+}
+
+// startWebhooks replaces the running webhooks with configs
+func (p *Plugin) startWebhooks(configs []WebhookConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopWebhooks != nil {
+		p.stopWebhooks()
+		p.stopWebhooks = nil
+	}
+	p.webhooks = nil
+	if len(configs) == 0 {
+		return
+	}
+
+	parent := p.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	p.stopWebhooks = cancel
+
+	for _, cfg := range configs {
+		hook := &webhook{
+			config: cfg,
+			client: &http.Client{Timeout: time.Duration(cfg.TimeoutMs) * time.Millisecond},
+			queue:  make(chan WebhookPayload, webhookQueueSize),
+		}
+		p.webhooks = append(p.webhooks, hook)
+		goGuarded(ctx, "webhook "+cfg.URL, func() { p.runWebhook(ctx, hook) })
+	}
+	log.Printf("Forwarding events to %d webhooks", len(configs))
+}
+
+// ListWebhooks returns the configured webhooks in config order
+func (p *Plugin) ListWebhooks() []WebhookStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	result := make([]WebhookStatus, 0, len(p.webhooks))
+	for _, hook := range p.webhooks {
+		result = append(result, hook.status())
+	}
+	return result
+}
+
+// forwardEvent queues ev for the webhooks it matches without waiting for
+// delivery
+func (p *Plugin) forwardEvent(ev Event) {
+	p.mu.RLock()
+	hooks := p.webhooks
+	p.mu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload := WebhookPayload{PluginID: p.namespace().PluginID, Event: ev}
+	for _, hook := range hooks {
+		if !hook.config.matches(ev) {
+			continue
+		}
+		select {
+		case hook.queue <- payload:
+		default:
+			hook.mu.Lock()
+			hook.dropped++
+			hook.mu.Unlock()
+		}
+	}
+}
+
+// runWebhook posts queued events in order until ctx is canceled
+func (p *Plugin) runWebhook(ctx context.Context, hook *webhook) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-hook.queue:
+			err := hook.deliver(ctx, payload)
+
+			hook.mu.Lock()
+			if err != nil {
+				hook.failed++
+				hook.lastError = err.Error()
+			} else {
+				hook.delivered++
+				hook.lastDelivery = time.Now().UTC()
+				hook.lastError = ""
+			}
+			hook.mu.Unlock()
+
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Webhook %s failed for event %s: %v", hook.config.URL, payload.ID, err)
+			}
+		}
+	}
+}
+
+// deliver posts a payload, retrying network failures and server errors
+func (w *webhook) deliver(ctx context.Context, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * webhookRetryDelay):
+			}
+		}
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (w *webhook) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reolink-plugin")
+	for name, value := range w.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if w.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.config.Secret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("endpoint answered %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
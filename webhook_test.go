@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	_, err := parseWebhooks(map[string]interface{}{
+		"webhooks": []interface{}{
+			map[string]interface{}{"url": "http://nodered.lan:1880/reolink"},
+			map[string]interface{}{"url": "ftp://nodered.lan/reolink"},
+			map[string]interface{}{"url": "http://nodered.lan", "timeout_ms": float64(120000)},
+			map[string]interface{}{"url": "http://nodered.lan", "headers": "Authorization"},
+		},
+	})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 3 {
+		t.Fatalf("Expected 3 config errors, got %v", err)
+	}
+	for i, field := range []string{"webhooks[1]", "webhooks[2]", "webhooks[3].headers"} {
+		if cfgErr.Errors[i].Field != field {
+			t.Errorf("Expected an error for %s, got %+v", field, cfgErr.Errors[i])
+		}
+	}
+
+	hooks, err := parseWebhooks(map[string]interface{}{"webhooks": []interface{}{
+		map[string]interface{}{"url": "https://automation.example.com/hooks"},
+	}})
+	if err != nil || len(hooks) != 1 || hooks[0].TimeoutMs != 5000 {
+		t.Errorf("Expected the default timeout, got %+v, %v", hooks, err)
+	}
+}
+
+func TestPlugin_Webhooks(t *testing.T) {
+	received := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	var rejected atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rejected.Add(1)
+		http.Error(w, "no", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"webhooks": []interface{}{
+			map[string]interface{}{
+				"url": server.URL, "secret": "s3cret", "event_types": []interface{}{EventPerson},
+				"headers": map[string]interface{}{"Authorization": "Bearer abc"},
+			},
+			map[string]interface{}{"url": failing.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+
+	plugin.emitEvent(Event{Type: EventMotion, State: EventStart, CameraID: "cam_1"})
+	ev := plugin.emitEvent(Event{Type: EventPerson, State: EventStart, CameraID: "cam_1"})
+
+	var req *http.Request
+	var body []byte
+	select {
+	case req = <-received:
+		body = <-bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("No webhook delivery")
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Invalid payload: %v", err)
+	}
+	if payload.PluginID != "reolink" || payload.ID != ev.ID || payload.Type != EventPerson || payload.CameraID != "cam_1" {
+		t.Errorf("Unexpected payload: %s", body)
+	}
+	if req.Header.Get("Authorization") != "Bearer abc" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers: %v", req.Header)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if got := req.Header.Get(webhookSignatureHeader); got != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("Unexpected signature %q", got)
+	}
+
+	// Client errors are not retried
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses := plugin.ListWebhooks()
+		if statuses[1].Failed == 2 {
+			if statuses[1].LastError == "" || rejected.Load() != 2 {
+				t.Errorf("Expected one attempt per event, got %d: %+v", rejected.Load(), statuses[1])
+			}
+			if statuses[0].Delivered != 1 || statuses[0].Secret != "" || statuses[0].Headers["Authorization"] != "***" {
+				t.Errorf("Unexpected status %+v", statuses[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 failed deliveries, got %+v", statuses)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case <-received:
+		t.Error("Expected the motion event to be filtered out")
+	default:
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// WiFi switch stages reported in wifi.progress notifications
+const (
+	WifiTesting      = "testing"      // the device tries to join the network
+	WifiApplying     = "applying"     // the device is pointed at the network
+	WifiReconnecting = "reconnecting" // waiting for the device to answer again
+	WifiDone         = "done"
+	WifiFailed       = "failed"
+)
+
+// WifiProgress is sent as a wifi.progress notification at each stage of
+// set_wifi
+type WifiProgress struct {
+	CameraID string `json:"camera_id"`
+	SSID     string `json:"ssid"`
+	Stage    string `json:"stage"`
+	Error    string `json:"error,omitempty"` // why the switch failed
+}
+
+// WifiRequest is a set_wifi request
+type WifiRequest struct {
+	CameraID string `json:"camera_id"`
+	SSID     string `json:"ssid"`
+	Password string `json:"password"`
+
+	// Host is the address the device answers at on the new network, e.g.
+	// its reserved lease; its current address if empty
+	Host string `json:"host,omitempty"`
+
+	WaitMs int `json:"wait_ms,omitempty"` // how long the device may take to come back, 90000 by default
+}
+
+// WifiChange is the result of set_wifi
+type WifiChange struct {
+	SSID         string   `json:"ssid"`
+	PreviousSSID string   `json:"previous_ssid,omitempty"`
+	PreviousHost string   `json:"previous_host"`
+	Host         string   `json:"host"`
+	Cameras      []string `json:"cameras"` // cameras moved to the new address and re-announced with camera.updated
+}
+
+// ScanWifi lists the WiFi networks a camera's device sees
+func (p *Plugin) ScanWifi(ctx context.Context, cameraID string) ([]reolink.WifiNetwork, error) {
+	var networks []reolink.WifiNetwork
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		networks, err = cam.client.ScanWifi(ctx)
+		return err
+	})
+	return networks, err
+}
+
+// GetWifi returns the WiFi network a camera's device is set to join
+func (p *Plugin) GetWifi(ctx context.Context, cameraID string) (*reolink.WifiStatus, error) {
+	var status *reolink.WifiStatus
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		status, err = cam.client.GetWifi(ctx)
+		return err
+	})
+	return status, err
+}
+
+// SetWifi points a camera's device at another WiFi network, e.g. after the
+// home network changed. The device first has to join the network in a test
+// (TestWifi, skipped on firmware without it), so wrong credentials leave it
+// connected. A device connected over WiFi is then reconnected on the new
+// network and its cameras are moved there like configure_network does. Each
+// stage is reported in a wifi.progress notification.
+func (p *Plugin) SetWifi(ctx context.Context, req WifiRequest) (*WifiChange, error) {
+	cam, err := p.lookupCamera(req.CameraID)
+	if err != nil {
+		return nil, err
+	}
+	if req.SSID == "" {
+		return nil, fmt.Errorf("ssid is required")
+	}
+	wait, err := reconnectWait(req.WaitMs)
+	if err != nil {
+		return nil, err
+	}
+
+	old := cam.client
+	result := &WifiChange{SSID: req.SSID, PreviousHost: old.Host(), Host: old.Host(), Cameras: []string{}}
+	fail := func(err error) (*WifiChange, error) {
+		p.wifiProgress(req, WifiFailed, err)
+		return nil, err
+	}
+
+	// Joining a network to test it takes a while
+	p.wifiProgress(req, WifiTesting, nil)
+	err = p.workerFor(old).do(longRunning(ctx), func(ctx context.Context) error {
+		if status, err := old.GetWifi(ctx); err == nil {
+			result.PreviousSSID = status.SSID
+		}
+		err := old.TestWifi(ctx, req.SSID, req.Password)
+		if errors.Is(err, reolink.ErrNotSupported) {
+			log.Printf("%s cannot test WiFi networks, switching to %s untested", old.Host(), req.SSID)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fail(fmt.Errorf("device cannot join %s: %w", req.SSID, err))
+	}
+
+	p.wifiProgress(req, WifiApplying, nil)
+	err = p.workerFor(old).do(longRunning(ctx), func(ctx context.Context) error {
+		return old.SetWifi(ctx, req.SSID, req.Password)
+	})
+	if refusedChange(err) {
+		return fail(err)
+	}
+	if err != nil {
+		log.Printf("No answer to SetWifi from %s (%v)", old.Host(), err)
+	}
+	log.Printf("Pointed %s at WiFi network %s", old.Host(), req.SSID)
+
+	// A wired device stays where it is
+	if link := old.GetCachedLocalLink(); link == nil || link.ActiveLink != "WiFi" {
+		if err != nil {
+			return fail(err)
+		}
+		p.wifiProgress(req, WifiDone, nil)
+		return result, nil
+	}
+
+	p.wifiProgress(req, WifiReconnecting, nil)
+	var serial string
+	if info := old.GetCachedDeviceInfo(); info != nil {
+		serial = info.Serial
+	}
+	device := p.deviceConfigOf(old)
+	if req.Host != "" {
+		device.Host = req.Host
+	}
+	client, err := p.reconnectDevice(ctx, old, device, serial, wait)
+	if err != nil {
+		return fail(fmt.Errorf("device did not come back at %s (find it with discover_cameras): %w", device.Host, err))
+	}
+	if status, err := client.GetWifi(ctx); err == nil && status.SSID != req.SSID {
+		_ = client.Close(ctx)
+		return fail(fmt.Errorf("device is on %s instead of %s", status.SSID, req.SSID))
+	}
+
+	result.Host = client.Host()
+	for _, c := range p.relocateDevice(old, client) {
+		result.Cameras = append(result.Cameras, c.ID())
+		p.notifyCameraUpdated(c)
+	}
+	goGuarded(p.ctx, "release of "+result.PreviousHost, func() { p.releaseClient(p.ctx, old) })
+
+	p.wifiProgress(req, WifiDone, nil)
+	return result, nil
+}
+
+func (p *Plugin) wifiProgress(req WifiRequest, stage string, err error) {
+	progress := WifiProgress{CameraID: req.CameraID, SSID: req.SSID, Stage: stage}
+	if err != nil {
+		progress.Error = err.Error()
+	}
+	_ = p.notify("wifi.progress", progress)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolinksim"
+)
+
+// wifiStages returns the stages of the wifi.progress notifications written so far
+func wifiStages(t *testing.T, out *syncBuffer) []string {
+	t.Helper()
+	var stages []string
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for dec.More() {
+		var msg struct {
+			Method string       `json:"method"`
+			Params WifiProgress `json:"params"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			t.Fatalf("Invalid notification: %v", err)
+		}
+		if msg.Method == "wifi.progress" {
+			stages = append(stages, msg.Params.Stage)
+		}
+	}
+	return stages
+}
+
+func TestPlugin_SetWifi(t *testing.T) {
+	sim := reolinksim.New(reolinksim.Camera{
+		Password:     "secret",
+		WiFiNetworks: map[string]string{"Home": "old-pass", "Home-5G": "new-pass"},
+		WiFiSSID:     "Home",
+	})
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	host, port := serverHostPort(server)
+	cameraID := host + "_ch0"
+
+	var out syncBuffer
+	plugin := NewPlugin()
+	plugin.SetOutput(&out)
+	err := plugin.Initialize(context.Background(), map[string]interface{}{
+		"event_poll_interval_ms": float64(0),
+		"devices": []interface{}{
+			map[string]interface{}{"host": host, "port": float64(port), "username": "admin", "password": "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer plugin.Shutdown(context.Background())
+	ctx := context.Background()
+
+	networks, err := plugin.ScanWifi(ctx, cameraID)
+	if err != nil || len(networks) != 2 {
+		t.Fatalf("Expected 2 networks, got %+v, %v", networks, err)
+	}
+	if status, err := plugin.GetWifi(ctx, cameraID); err != nil || status.SSID != "Home" {
+		t.Errorf("Expected the device on Home, got %+v, %v", status, err)
+	}
+
+	// A network the device cannot join is refused before switching
+	if _, err := plugin.SetWifi(ctx, WifiRequest{CameraID: cameraID, SSID: "Home-5G", Password: "wrong"}); err == nil {
+		t.Fatal("Expected an error for a wrong password")
+	}
+	if sim.WifiSSID() != "Home" {
+		t.Errorf("Expected the device to stay on Home, got %s", sim.WifiSSID())
+	}
+	if stages := strings.Join(wifiStages(t, &out), ","); stages != "testing,failed" {
+		t.Errorf("Unexpected stages %s", stages)
+	}
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "set_wifi",
+		Params: []byte(`{"camera_id": "` + cameraID + `", "ssid": "Home-5G", "password": "new-pass", "wait_ms": 10000}`)})
+	if resp.Error != nil {
+		t.Fatalf("set_wifi failed: %+v", resp.Error)
+	}
+	result := resp.Result.(*WifiChange)
+	if result.PreviousSSID != "Home" || result.Host != host || len(result.Cameras) != 1 || result.Cameras[0] != cameraID {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if sim.WifiSSID() != "Home-5G" {
+		t.Errorf("Expected the device on Home-5G, got %s", sim.WifiSSID())
+	}
+	if stages := strings.Join(wifiStages(t, &out), ","); stages != "testing,failed,testing,applying,reconnecting,done" {
+		t.Errorf("Unexpected stages %s", stages)
+	}
+	if cam := plugin.GetCamera(cameraID); cam == nil || !cam.Online {
+		t.Errorf("Expected the camera online after the switch, got %+v", cam)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// tokenRefreshInterval is how often a worker checks whether its session token
+// is about to expire, so requests do not pay for the login
+const tokenRefreshInterval = time.Minute
+
+// errWorkerStopped is returned for commands sent to a device that was removed
+var errWorkerStopped = errors.New("device worker stopped")
+
+// deviceCommand is one unit of device access queued on a worker
+type deviceCommand struct {
+	ctx    context.Context
+	fn     func(ctx context.Context) error
+	result chan commandResult
+}
+
+type commandResult struct {
+	err      error
+	panicked interface{}
+}
+
+type workerContextKey struct{}
+
+type longRunningKey struct{}
+
+// longRunning marks the commands queued with ctx as taking up to minutes,
+// e.g. firmware uploads, so they run beside the worker's other commands
+func longRunning(ctx context.Context) context.Context {
+	return context.WithValue(ctx, longRunningKey{}, true)
+}
+
+// deviceWorker owns the access to one device. Requests are queued as commands
+// and run one at a time on the worker's goroutine, interleaved with its
+// periodic tasks: event polling, connectivity checks, token and encoder
+// settings refresh. Live control commands go ahead of queued bulk ones.
+// Long-running commands run one at a time on a second goroutine, so control
+// commands are not held up behind them; their requests share the device's
+// request slots, where control requests go first.
+// Power-saving cameras are left out of the periodic tasks where possible.
+type deviceWorker struct {
+	p      *Plugin
+	client *reolink.Client
+	queues [reolink.NumPriorities]chan deviceCommand
+	long   chan deviceCommand
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   <-chan struct{}
+
+	// Last known event state per camera ID and alarm input states of the
+	// device, only used on the worker goroutine
+	states map[string]reolink.EventState
+	inputs []bool
+}
+
+// startWorker starts the worker of a device unless one already runs or the
+// plugin is not initialized
+func (p *Plugin) startWorker(client *reolink.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ctx == nil {
+		return
+	}
+	if _, running := p.workers[client]; running {
+		return
+	}
+
+	w := &deviceWorker{p: p, client: client, states: make(map[string]reolink.EventState)}
+	for i := range w.queues {
+		w.queues[i] = make(chan deviceCommand)
+	}
+	w.long = make(chan deviceCommand)
+	w.ctx, w.cancel = context.WithCancel(p.ctx)
+	p.workers[client] = w
+
+	eventInterval, healthInterval, metadataInterval := p.eventInterval, p.offlineAfter/4, p.metadataInterval
+	if healthInterval < time.Second {
+		healthInterval = time.Second
+	}
+	loop := goGuarded(w.ctx, "device worker for "+client.Host(), func() {
+		w.run(eventInterval, healthInterval, metadataInterval)
+	})
+	long := goGuarded(w.ctx, "long-running commands for "+client.Host(), w.runLong)
+	done := make(chan struct{})
+	go func() {
+		<-loop
+		<-long
+		close(done)
+	}()
+	w.done = done
+}
+
+// stopWorker stops the worker of a device, if any, and returns a channel
+// closed once it has exited
+func (p *Plugin) stopWorker(client *reolink.Client) <-chan struct{} {
+	p.mu.Lock()
+	w, ok := p.workers[client]
+	delete(p.workers, client)
+	p.mu.Unlock()
+
+	if !ok {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	w.cancel()
+	return w.done
+}
+
+// workerFor returns the worker of a device, or nil if it has none
+func (p *Plugin) workerFor(client *reolink.Client) *deviceWorker {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.workers[client]
+}
+
+// onCamera runs fn for a camera on its device's worker and returns its error
+func (p *Plugin) onCamera(ctx context.Context, cameraID string, fn func(ctx context.Context, cam *Camera) error) error {
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return err
+	}
+	return p.workerFor(cam.client).do(ctx, func(ctx context.Context) error {
+		return fn(ctx, cam)
+	})
+}
+
+// do queues fn at the priority of ctx, or with the long-running commands,
+// and waits for it to run. Without a worker, or when called from the worker
+// itself, fn runs directly. A panic in fn is raised again in the caller.
+func (w *deviceWorker) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if w == nil || ctx.Value(workerContextKey{}) == w {
+		return fn(ctx)
+	}
+
+	queue := w.queues[reolink.PriorityFromContext(ctx)]
+	if long, _ := ctx.Value(longRunningKey{}).(bool); long {
+		queue = w.long
+	}
+	cmd := deviceCommand{ctx: ctx, fn: fn, result: make(chan commandResult, 1)}
+	select {
+	case queue <- cmd:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.ctx.Done():
+		return errWorkerStopped
+	}
+
+	select {
+	case res := <-cmd.result:
+		if res.panicked != nil {
+			panic(res.panicked)
+		}
+		return res.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run serves commands and periodic tasks until the worker is stopped
+func (w *deviceWorker) run(eventInterval, healthInterval, metadataInterval time.Duration) {
+	var eventTick <-chan time.Time
+	if eventInterval > 0 {
+		ticker := time.NewTicker(eventInterval)
+		defer ticker.Stop()
+		eventTick = ticker.C
+	}
+	var metadataTick <-chan time.Time
+	if metadataInterval > 0 {
+		ticker := time.NewTicker(metadataInterval)
+		defer ticker.Stop()
+		metadataTick = ticker.C
+		// Read the stream settings once up front so the host sees them
+		// without waiting a full interval
+		w.p.refreshEncoderConfigs(w.ctx, w.client)
+	}
+	w.p.probeAISupport(w.ctx, w.client)
+	healthTicker := time.NewTicker(healthInterval)
+	defer healthTicker.Stop()
+	tokenTicker := time.NewTicker(tokenRefreshInterval)
+	defer tokenTicker.Stop()
+
+	for {
+		// Queued control commands go ahead of everything else
+		select {
+		case cmd := <-w.queues[reolink.PriorityControl]:
+			w.exec(cmd)
+			w.release()
+			continue
+		default:
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case cmd := <-w.queues[reolink.PriorityControl]:
+			w.exec(cmd)
+			w.release()
+		case cmd := <-w.queues[reolink.PriorityBulk]:
+			w.exec(cmd)
+			w.release()
+		case <-eventTick:
+			w.p.pollDeviceEvents(w.ctx, w.client, w.states)
+			w.inputs = w.p.pollAlarmInputs(w.ctx, w.client, w.inputs)
+		case <-healthTicker.C:
+			cameras := w.p.camerasOf(w.client)
+			w.p.checkDeviceConnectivity(w.ctx, w.client, cameras, w.p.offlineWindow(cameras))
+			w.p.refreshPerformance(w.ctx, w.client, cameras)
+			w.release()
+		case <-tokenTicker.C:
+			// A power-saving device logs in again when it is next used
+			if !allPowerSaving(w.p.camerasOf(w.client)) {
+				w.refreshToken()
+			}
+		case <-metadataTick:
+			w.p.refreshEncoderConfigs(w.ctx, w.client)
+			w.p.refreshStorage(w.ctx, w.client)
+		}
+	}
+}
+
+// runLong serves the long-running commands, one at a time, until the worker
+// is stopped
+func (w *deviceWorker) runLong() {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case cmd := <-w.long:
+			w.exec(cmd)
+			w.release()
+		}
+	}
+}
+
+// exec runs a command on the worker goroutine, handing a panic back to the caller
+func (w *deviceWorker) exec(cmd deviceCommand) {
+	var res commandResult
+	defer func() {
+		if r := recover(); r != nil {
+			res.panicked = fmt.Sprintf("%v [on device worker for %s]\n%s", r, w.client.Host(), debug.Stack())
+		}
+		cmd.result <- res
+	}()
+
+	if err := cmd.ctx.Err(); err != nil {
+		res.err = err
+		return
+	}
+	res.err = cmd.fn(context.WithValue(cmd.ctx, workerContextKey{}, w))
+}
+
+// release drops the kept-alive connections of a power-saving device after
+// each use, so the camera can go back to sleep
+func (w *deviceWorker) release() {
+	if allPowerSaving(w.p.camerasOf(w.client)) {
+		w.client.CloseIdleConnections()
+	}
+}
+
+// refreshToken logs in again shortly before the session token expires
+func (w *deviceWorker) refreshToken() {
+	if !w.client.TokenExpiresWithin(2 * tokenRefreshInterval) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(w.ctx, w.client.GetTimeouts().Login)
+	defer cancel()
+	if err := w.client.Login(ctx); err != nil && w.ctx.Err() == nil {
+		log.Printf("Token refresh failed for %s: %v", w.client.Host(), err)
+	}
+}
+
+// camerasOf returns the cameras served by a client
+func (p *Plugin) camerasOf(client *reolink.Client) []*Camera {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var cameras []*Camera
+	for _, cam := range p.cameras {
+		if cam.client == client {
+			cameras = append(cameras, cam)
+		}
+	}
+	return cameras
+}
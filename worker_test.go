@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// newWorkerPlugin returns an initialized plugin with one camera and its worker
+func newWorkerPlugin(t *testing.T) (*Plugin, *deviceWorker) {
+	t.Helper()
+	plugin := NewPlugin()
+	plugin.SetOutput(io.Discard)
+	if err := plugin.Initialize(context.Background(), map[string]interface{}{"event_poll_interval_ms": float64(0)}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { plugin.cancel() })
+
+	client := reolink.NewClient("camera.invalid", 80, "admin", "password")
+	plugin.cameras["cam1"] = NewCamera("cam1", "Front", "RLC-810A", "camera.invalid", 0, client)
+	plugin.startWorker(client)
+	w := plugin.workerFor(client)
+	if w == nil {
+		t.Fatal("Expected a worker for the client")
+	}
+	return plugin, w
+}
+
+func TestDeviceWorker_Serializes(t *testing.T) {
+	plugin, _ := newWorkerPlugin(t)
+
+	var active, maxActive atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := plugin.onCamera(context.Background(), "cam1", func(ctx context.Context, cam *Camera) error {
+				n := active.Add(1)
+				for {
+					m := maxActive.Load()
+					if n <= m || maxActive.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				active.Add(-1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("onCamera failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxActive.Load() != 1 {
+		t.Errorf("Expected commands to run one at a time, got %d at once", maxActive.Load())
+	}
+	if err := plugin.onCamera(context.Background(), "missing", nil); err == nil || !strings.Contains(err.Error(), "camera not found") {
+		t.Errorf("Expected camera not found, got %v", err)
+	}
+}
+
+func TestDeviceWorker_ControlFirst(t *testing.T) {
+	_, w := newWorkerPlugin(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go w.do(ctx, func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	var mu sync.Mutex
+	var order []string
+	run := func(ctx context.Context, name string, wg *sync.WaitGroup) {
+		defer wg.Done()
+		_ = w.do(ctx, func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go run(ctx, "bulk", &wg)
+	time.Sleep(20 * time.Millisecond)
+	go run(reolink.WithPriority(ctx, reolink.PriorityControl), "control", &wg)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != "control" {
+		t.Errorf("Expected the control command to run first, got %v", order)
+	}
+}
+
+func TestDeviceWorker_PanicAndNesting(t *testing.T) {
+	_, w := newWorkerPlugin(t)
+	ctx := context.Background()
+
+	// Commands queued from a running command run inline instead of deadlocking
+	done := make(chan error, 1)
+	go func() {
+		done <- w.do(ctx, func(ctx context.Context) error {
+			return w.do(ctx, func(ctx context.Context) error { return errors.New("inner") })
+		})
+	}()
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "inner" {
+			t.Errorf("Expected the inner error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Nested command deadlocked")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil || !strings.Contains(r.(string), "bad response") {
+				t.Errorf("Expected the panic to reach the caller, got %v", r)
+			}
+		}()
+		_ = w.do(ctx, func(ctx context.Context) error { panic("bad response") })
+	}()
+
+	// The worker survives the panic
+	if err := w.do(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Worker stopped after a panic: %v", err)
+	}
+}
+
+func TestDeviceWorker_Stop(t *testing.T) {
+	plugin, w := newWorkerPlugin(t)
+
+	select {
+	case <-plugin.stopWorker(w.client):
+	case <-time.After(5 * time.Second):
+		t.Fatal("Worker did not stop")
+	}
+	if err := w.do(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, errWorkerStopped) {
+		t.Errorf("Expected errWorkerStopped, got %v", err)
+	}
+	if plugin.workerFor(w.client) != nil {
+		t.Error("Expected the worker to be removed")
+	}
+}
+
+func TestDeviceWorker_RefreshToken(t *testing.T) {
+	for _, tt := range []struct {
+		lease  int
+		logins int
+	}{
+		{lease: 3600, logins: 1}, // a token far from expiry is kept
+		{lease: 120, logins: 2},  // an expiring token is refreshed
+	} {
+		var logins atomic.Int32
+		client := newTestClient(newTokenDevice(t, tt.lease, nil, func(cmd, token string) {
+			if cmd == "Login" {
+				logins.Add(1)
+			}
+		}))
+		if err := client.Login(context.Background()); err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		w := &deviceWorker{client: client, ctx: context.Background()}
+		w.refreshToken()
+		if n := int(logins.Load()); n != tt.logins {
+			t.Errorf("Lease %ds: expected %d logins, got %d", tt.lease, tt.logins, n)
+		}
+	}
+}
+
+func TestPlugin_ReleaseClient_WaitsForWorker(t *testing.T) {
+	plugin, _ := newWorkerPlugin(t)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = plugin.onCamera(context.Background(), "cam1", func(ctx context.Context, cam *Camera) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	removed := make(chan struct{})
+	go func() {
+		_ = plugin.RemoveCamera(context.Background(), "cam1")
+		close(removed)
+	}()
+
+	// The session is not closed under the running command
+	select {
+	case <-removed:
+		t.Fatal("Expected the removal to wait for the running command")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-removed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Removal did not finish after the command")
+	}
+}
+
+func TestDeviceWorker_LongRunning(t *testing.T) {
+	plugin, w := newWorkerPlugin(t)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	upload := make(chan error, 1)
+	go func() {
+		upload <- w.do(longRunning(context.Background()), func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// A PTZ move is not held up by the running upload
+	ctx, cancel := context.WithTimeout(reolink.WithPriority(context.Background(), reolink.PriorityControl), 2*time.Second)
+	defer cancel()
+	if err := plugin.onCamera(ctx, "cam1", func(ctx context.Context, cam *Camera) error { return nil }); err != nil {
+		t.Fatalf("Expected the control command to run beside the upload, got %v", err)
+	}
+
+	close(release)
+	if err := <-upload; err != nil {
+		t.Errorf("Long-running command failed: %v", err)
+	}
+}
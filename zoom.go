@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// zoomPresetPrefix marks plugin-stored zoom presets in preset IDs
+const zoomPresetPrefix = "zoom:"
+
+// SaveZoomPreset stores the camera's current zoom/focus position under a name
+func (p *Plugin) SaveZoomPreset(ctx context.Context, cameraID, name string) (*PTZPreset, error) {
+	if name == "" {
+		return nil, fmt.Errorf("preset name is required")
+	}
+
+	cam, err := p.lookupCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos *reolink.ZoomPosition
+	err = p.workerFor(cam.client).do(ctx, func(ctx context.Context) (err error) {
+		pos, err = cam.client.GetZoomFocus(ctx, cam.Channel())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zoom position: %w", err)
+	}
+
+	p.mu.Lock()
+	presets, ok := p.state.ZoomPresets[cameraID]
+	if !ok {
+		presets = make(map[string]reolink.ZoomPosition)
+		p.state.ZoomPresets[cameraID] = presets
+	}
+	presets[name] = *pos
+	p.mu.Unlock()
+
+	p.saveState()
+	log.Printf("Saved zoom preset %q for camera %s (zoom=%d focus=%d)", name, cameraID, pos.Zoom, pos.Focus)
+
+	return &PTZPreset{ID: zoomPresetPrefix + name, Name: name}, nil
+}
+
+// DeleteZoomPreset removes a stored zoom preset
+func (p *Plugin) DeleteZoomPreset(cameraID, name string) error {
+	name = strings.TrimPrefix(name, zoomPresetPrefix)
+
+	p.mu.Lock()
+	presets := p.state.ZoomPresets[cameraID]
+	if _, ok := presets[name]; !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("zoom preset not found: %s", name)
+	}
+	delete(presets, name)
+	if len(presets) == 0 {
+		delete(p.state.ZoomPresets, cameraID)
+	}
+	p.mu.Unlock()
+
+	p.saveState()
+	return nil
+}
+
+// zoomPresets returns the stored zoom presets for a camera sorted by name
+func (p *Plugin) zoomPresets(cameraID string) []PTZPreset {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var result []PTZPreset
+	for name := range p.state.ZoomPresets[cameraID] {
+		result = append(result, PTZPreset{ID: zoomPresetPrefix + name, Name: name})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// recallZoomPreset moves the camera to a stored zoom preset
+func (p *Plugin) recallZoomPreset(ctx context.Context, cam *Camera, presetID string) error {
+	name := strings.TrimPrefix(presetID, zoomPresetPrefix)
+
+	p.mu.RLock()
+	pos, ok := p.state.ZoomPresets[cam.ID()][name]
+	p.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("zoom preset not found: %s", name)
+	}
+	return cam.client.SetZoomFocus(ctx, cam.Channel(), pos)
+}
+
+// ZoomFocusStatus is the live zoom and focus state of a camera, for zoom and
+// focus sliders
+type ZoomFocusStatus struct {
+	reolink.ZoomPosition
+	reolink.ZoomFocusRange
+
+	// Autofocus is nil on cameras that do not report it
+	Autofocus *bool `json:"autofocus,omitempty"`
+}
+
+// GetZoomFocus returns the current zoom and focus positions of a camera with
+// the range of each motor. Hosts poll it to keep a zoom slider in sync with
+// moves made elsewhere.
+func (p *Plugin) GetZoomFocus(ctx context.Context, cameraID string) (*ZoomFocusStatus, error) {
+	var status *ZoomFocusStatus
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		status, err = cam.zoomFocusStatus(ctx)
+		return err
+	})
+	return status, err
+}
+
+// Autofocus makes a camera refocus by enabling its autofocus, and returns the
+// resulting zoom and focus state
+func (p *Plugin) Autofocus(ctx context.Context, cameraID string) (*ZoomFocusStatus, error) {
+	var status *ZoomFocusStatus
+	err := p.onCamera(ctx, cameraID, func(ctx context.Context, cam *Camera) (err error) {
+		if err := cam.client.SetAutoFocus(ctx, cam.Channel(), true); err != nil {
+			return fmt.Errorf("failed to trigger autofocus: %w", err)
+		}
+		log.Printf("Triggered autofocus on camera %s", cameraID)
+		status, err = cam.zoomFocusStatus(ctx)
+		return err
+	})
+	return status, err
+}
+
+func (c *Camera) zoomFocusStatus(ctx context.Context) (*ZoomFocusStatus, error) {
+	if ability := c.Ability(); ability != nil && !ability.PTZ {
+		return nil, fmt.Errorf("camera %s has no optical zoom", c.id)
+	}
+
+	pos, ranges, err := c.client.GetZoomFocusRange(ctx, c.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zoom position: %w", err)
+	}
+	status := &ZoomFocusStatus{ZoomPosition: *pos, ZoomFocusRange: *ranges}
+
+	// Older firmware has no autofocus API; leave the state unknown
+	if enabled, err := c.client.GetAutoFocus(ctx, c.channel); err == nil {
+		status.Autofocus = &enabled
+	} else if !errors.Is(err, reolink.ErrNotSupported) {
+		return nil, fmt.Errorf("failed to read autofocus: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Spatial-NVR/reolink-plugin/reolink"
+)
+
+// zoomTestServer emulates GetZoomFocus/StartZoomFocus and records requested positions
+type zoomTestServer struct {
+	mu        sync.Mutex
+	pos       reolink.ZoomPosition
+	autofocus bool
+}
+
+func (z *zoomTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var cmds []apiCommand
+	_ = json.NewDecoder(r.Body).Decode(&cmds)
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	switch cmds[0].Cmd {
+	case "GetZoomFocus":
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:  "GetZoomFocus",
+			Code: 0,
+			Value: map[string]interface{}{
+				"ZoomFocus": map[string]interface{}{
+					"channel": float64(0),
+					"zoom":    map[string]interface{}{"pos": float64(z.pos.Zoom)},
+					"focus":   map[string]interface{}{"pos": float64(z.pos.Focus)},
+				},
+			},
+			Range: map[string]interface{}{
+				"ZoomFocus": map[string]interface{}{
+					"zoom":  map[string]interface{}{"pos": map[string]interface{}{"min": float64(0), "max": float64(33)}},
+					"focus": map[string]interface{}{"pos": map[string]interface{}{"min": float64(0), "max": float64(248)}},
+				},
+			},
+		}})
+	case "StartZoomFocus":
+		zf, _ := cmds[0].Param["ZoomFocus"].(map[string]interface{})
+		pos, _ := zf["pos"].(float64)
+		if zf["op"] == "ZoomPos" {
+			z.pos.Zoom = int(pos)
+		} else {
+			z.pos.Focus = int(pos)
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "StartZoomFocus", Code: 0}})
+	case "GetAutoFocus":
+		disable := 1
+		if z.autofocus {
+			disable = 0
+		}
+		_ = json.NewEncoder(w).Encode([]apiResponse{{
+			Cmd:   "GetAutoFocus",
+			Code:  0,
+			Value: map[string]interface{}{"AutoFocus": map[string]interface{}{"disable": float64(disable)}},
+		}})
+	case "SetAutoFocus":
+		af, _ := cmds[0].Param["AutoFocus"].(map[string]interface{})
+		z.autofocus = af["disable"] == float64(0)
+		z.pos.Focus = 180 // refocused
+		_ = json.NewEncoder(w).Encode([]apiResponse{{Cmd: "SetAutoFocus", Code: 0}})
+	}
+}
+
+func TestPlugin_ZoomPresets_SaveAndRecall(t *testing.T) {
+	zs := &zoomTestServer{pos: reolink.ZoomPosition{Zoom: 20, Focus: 300}}
+	server := httptest.NewServer(zs)
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	plugin.cameras["cam_1"] = NewCamera("cam_1", "Gate", "RLC-823A", "localhost", 0, client)
+	ctx := context.Background()
+
+	preset, err := plugin.SaveZoomPreset(ctx, "cam_1", "gate")
+	if err != nil {
+		t.Fatalf("SaveZoomPreset failed: %v", err)
+	}
+	if preset.ID != "zoom:gate" {
+		t.Errorf("Expected ID 'zoom:gate', got '%s'", preset.ID)
+	}
+
+	zs.mu.Lock()
+	zs.pos = reolink.ZoomPosition{}
+	zs.mu.Unlock()
+
+	if err := plugin.PTZControl(ctx, "cam_1", PTZCommand{Action: "preset", Preset: "zoom:gate"}); err != nil {
+		t.Fatalf("Recall failed: %v", err)
+	}
+
+	zs.mu.Lock()
+	defer zs.mu.Unlock()
+	if zs.pos != (reolink.ZoomPosition{Zoom: 20, Focus: 300}) {
+		t.Errorf("Expected camera at zoom 20 focus 300, got %+v", zs.pos)
+	}
+}
+
+func TestPlugin_ZoomPresets_Delete(t *testing.T) {
+	plugin := NewPlugin()
+	plugin.state.ZoomPresets["cam_1"] = map[string]reolink.ZoomPosition{"gate": {Zoom: 1}}
+
+	if len(plugin.zoomPresets("cam_1")) != 1 {
+		t.Fatal("Expected 1 zoom preset")
+	}
+	if err := plugin.DeleteZoomPreset("cam_1", "zoom:gate"); err != nil {
+		t.Fatalf("DeleteZoomPreset failed: %v", err)
+	}
+	if len(plugin.zoomPresets("cam_1")) != 0 {
+		t.Error("Expected zoom preset to be deleted")
+	}
+	if err := plugin.DeleteZoomPreset("cam_1", "gate"); err == nil {
+		t.Error("Expected error deleting missing preset")
+	}
+}
+
+func TestPlugin_GetZoomFocus_Autofocus(t *testing.T) {
+	zs := &zoomTestServer{pos: reolink.ZoomPosition{Zoom: 12, Focus: 140}}
+	server := httptest.NewServer(zs)
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.UseURLAuth()
+
+	plugin := NewPlugin()
+	cam := NewCamera("cam_1", "Gate", "RLC-823A", "localhost", 0, client)
+	cam.SetAbility(&reolink.Ability{PTZ: true})
+	plugin.cameras["cam_1"] = cam
+
+	resp := plugin.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "get_zoom_focus", Params: []byte(`{"camera_id": "cam_1"}`)})
+	if resp.Error != nil {
+		t.Fatalf("get_zoom_focus failed: %s", resp.Error.Message)
+	}
+	status := resp.Result.(*ZoomFocusStatus)
+	if status.Zoom != 12 || status.Focus != 140 || status.ZoomMax != 33 || status.FocusMax != 248 {
+		t.Errorf("Unexpected zoom status: %+v", status)
+	}
+	if status.Autofocus == nil || *status.Autofocus {
+		t.Errorf("Expected autofocus off, got %v", status.Autofocus)
+	}
+
+	status, err := plugin.Autofocus(context.Background(), "cam_1")
+	if err != nil {
+		t.Fatalf("Autofocus failed: %v", err)
+	}
+	if status.Autofocus == nil || !*status.Autofocus || status.Focus != 180 {
+		t.Errorf("Expected autofocus on at the new focus, got %+v", status)
+	}
+
+	// Cameras without an optical zoom are not asked
+	cam.SetAbility(&reolink.Ability{PanTilt: true})
+	if _, err := plugin.GetZoomFocus(context.Background(), "cam_1"); err == nil {
+		t.Error("Expected an error for a camera without optical zoom")
+	}
+}